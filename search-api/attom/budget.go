@@ -0,0 +1,164 @@
+package attom
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// EndpointClass groups provider endpoints that share a request budget.
+type EndpointClass string
+
+const (
+	ClassSearch EndpointClass = "search"
+	ClassPhotos EndpointClass = "photos"
+	ClassDetail EndpointClass = "detail"
+)
+
+// classOf maps a request path to its endpoint class.
+func classOf(path string) EndpointClass {
+	switch {
+	case strings.Contains(path, "/search/"):
+		return ClassSearch
+	case strings.Contains(path, "/photos"):
+		return ClassPhotos
+	default:
+		return ClassDetail
+	}
+}
+
+// Priority tells the client whether a request serves a caller waiting on
+// the response or background work such as bulk hydration.
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota
+	PriorityBulk
+)
+
+type priorityKey struct{}
+
+// WithPriority tags provider calls made with ctx. Untagged calls are
+// interactive.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityOf(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+type admissionKey struct{}
+
+// WithAdmission makes provider calls made with ctx ask admit, such as a
+// per-consumer budget check, once the client's own quota has admitted them.
+// An error from admit refuses the call; it is returned in an AdmissionError
+// and not retried.
+func WithAdmission(ctx context.Context, admit func(context.Context) error) context.Context {
+	return context.WithValue(ctx, admissionKey{}, admit)
+}
+
+// AdmissionError is a provider call refused by a WithAdmission check.
+type AdmissionError struct{ Err error }
+
+func (e *AdmissionError) Error() string { return "attom: call refused: " + e.Err.Error() }
+func (e *AdmissionError) Unwrap() error { return e.Err }
+
+func admit(ctx context.Context) error {
+	if fn, _ := ctx.Value(admissionKey{}).(func(context.Context) error); fn != nil {
+		if err := fn(ctx); err != nil {
+			return &AdmissionError{Err: err}
+		}
+	}
+	return nil
+}
+
+// Budget is one endpoint class's share of the daily quota.
+type Budget struct {
+	// Daily caps the class's requests per UTC day; 0 leaves it bounded only
+	// by the shared daily limit.
+	Daily int
+	// Rank orders classes as the shared quota runs low. Rank 0 may spend the
+	// whole quota; each rank above it stops Reserve requests earlier. Bulk
+	// requests count one rank lower than their class.
+	Rank int
+}
+
+// Budgets configures per-class request budgets. Classes missing from the
+// map get rank 0 and no cap.
+type Budgets struct {
+	Classes map[EndpointClass]Budget
+	// Reserve is the number of requests held back per rank; <= 0 defaults to
+	// 5% of the daily limit.
+	Reserve int
+}
+
+// DefaultBudgets keeps interactive search ahead of detail lookups and photo
+// fetches, with no per-class caps.
+func DefaultBudgets() Budgets {
+	return Budgets{Classes: map[EndpointClass]Budget{
+		ClassSearch: {Rank: 0},
+		ClassDetail: {Rank: 1},
+		ClassPhotos: {Rank: 2},
+	}}
+}
+
+// BudgetClass reports the endpoint class whose budget refused err, if any.
+func BudgetClass(err error) (EndpointClass, bool) {
+	var qe *QuotaError
+	if errors.As(err, &qe) && qe.Class != "" {
+		return qe.Class, true
+	}
+	return "", false
+}
+
+// admit charges one request of class at priority p against the daily
+// counters, or returns the quota error refusing it. Callers hold c.mu and
+// have rolled the day.
+func (c *Client) admit(class EndpointClass, p Priority) *QuotaError {
+	b := c.budgets.Classes[class]
+	rank := b.Rank
+	if p == PriorityBulk {
+		rank++
+	}
+	remaining := c.dailyLimit - c.dayCount
+	if rank > 0 && remaining <= c.budgets.Reserve*rank {
+		return &QuotaError{Daily: true, Class: class, Limit: c.dailyLimit, Remaining: remaining}
+	}
+	if b.Daily > 0 && c.classCount[class] >= b.Daily {
+		return &QuotaError{Daily: true, Class: class, Limit: b.Daily, Remaining: 0}
+	}
+	c.dayCount++
+	c.classCount[class]++
+	return nil
+}
+
+// refund returns a request of class that beforeRequest charged but that
+// was never sent.
+func (c *Client) refund(class EndpointClass) {
+	if c.dailyLimit <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rollDay(time.Now().UTC())
+	if c.dayCount > 0 {
+		c.dayCount--
+	}
+	if c.classCount[class] > 0 {
+		c.classCount[class]--
+	}
+}
+
+// BudgetUsage returns today's request count per endpoint class.
+func (c *Client) BudgetUsage() map[EndpointClass]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[EndpointClass]int, len(c.classCount))
+	for k, v := range c.classCount {
+		out[k] = v
+	}
+	return out
+}
@@ -13,7 +13,10 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/time/rate"
+
+	"github.com/yourorg/search-api/internal/logger"
 )
 
 var ErrDailyLimitExceeded = errors.New("attom: daily quota exceeded")
@@ -38,10 +41,22 @@ func (t *quotaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	if err := t.client.beforeRequest(ctx); err != nil {
+	class := classOf(req.URL.Path)
+	if err := t.client.beforeRequest(ctx, class); err != nil {
+		return nil, err
+	}
+	// The caller's admission check runs once the provider quota has room,
+	// so a call the quota refuses doesn't spend the caller's budget.
+	if err := admit(ctx); err != nil {
+		t.client.refund(class)
 		return nil, err
 	}
-	return base.RoundTrip(req)
+	logger.CountProviderCall(ctx)
+	resp, err := base.RoundTrip(req)
+	if err == nil {
+		t.client.observe(resp)
+	}
+	return resp, err
 }
 
 // Client targets RapidAPI Realtor endpoints with quota protections.
@@ -49,20 +64,35 @@ type Client struct {
 	key        string
 	baseURL    string
 	host       string
+	hostSet    bool
+	transport  http.RoundTripper
 	http       *retryablehttp.Client
 	limiter    *rate.Limiter
+	baseRate   rate.Limit // configured rate; the limiter backs off below it on throttles
 	dailyLimit int
+	budgets    Budgets
+
+	transportOpts TransportOptions
 
-	mu       sync.Mutex
-	dayKey   string
-	dayCount int
+	mu          sync.Mutex
+	dayKey      string
+	dayCount    int
+	classCount  map[EndpointClass]int
+	pausedUntil time.Time // set from the provider's Retry-After
+	pausedDaily bool      // the pause is plan-quota exhaustion, not a throttle
+
+	driftHook     func(DriftReport)
+	driftReported sync.Map // signature -> time.Time
+
+	defaults RequestOptions
+	profiles sync.Map // retryProfile -> *retryablehttp.Client
 }
 
-func NewClient(apiKey string) *Client {
-	return NewClientWithLimits(apiKey, defaultRequestsPerSecond, defaultRateBurst, defaultDailyLimit)
+func NewClient(apiKey string, opts ...Option) *Client {
+	return NewClientWithLimits(apiKey, defaultRequestsPerSecond, defaultRateBurst, defaultDailyLimit, opts...)
 }
 
-func NewClientWithLimits(apiKey string, perSecond float64, burst int, dailyLimit int) *Client {
+func NewClientWithLimits(apiKey string, perSecond float64, burst int, dailyLimit int, opts ...Option) *Client {
 	rc := retryablehttp.NewClient()
 	rc.RetryWaitMin = 100 * time.Millisecond
 	rc.RetryWaitMax = 900 * time.Millisecond
@@ -79,30 +109,58 @@ func NewClientWithLimits(apiKey string, perSecond float64, burst int, dailyLimit
 
 	c := &Client{
 		key:        apiKey,
-		baseURL:    "https://realtor16.p.rapidapi.com",
-		host:       "realtor16.p.rapidapi.com",
+		baseURL:    defaultBaseURL,
+		host:       defaultHost,
 		http:       rc,
 		limiter:    limiter,
 		dailyLimit: dailyLimit,
-	}
+		budgets:    DefaultBudgets(),
+		classCount: make(map[EndpointClass]int),
 
-	qt := &quotaTransport{client: c}
-	if rc.HTTPClient.Transport != nil {
-		qt.base = rc.HTTPClient.Transport
+		transportOpts: DefaultTransportOptions(),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
-	rc.HTTPClient.Transport = qt
+	if c.limiter != nil {
+		c.baseRate = c.limiter.Limit()
+	}
+	if c.budgets.Reserve <= 0 {
+		c.budgets.Reserve = c.dailyLimit / 20
+	}
+
+	qt := &quotaTransport{client: c, base: c.transport}
+	pooled := connTraceTransport{base: NewTransport(c.transportOpts)}
+	if qt.base == nil {
+		qt.base = pooled
+	} else if rt, ok := qt.base.(*RecordingTransport); ok && rt.Base == nil {
+		rt.Base = pooled
+	}
+	// Spans cover each attempt including the local quota wait.
+	rc.HTTPClient.Transport = otelhttp.NewTransport(qt, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return "rapidapi " + r.URL.Path
+	}))
 
 	rc.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
-		if errors.Is(err, ErrDailyLimitExceeded) {
+		var refused *AdmissionError
+		if errors.Is(err, ErrDailyLimitExceeded) || errors.Is(err, ErrThrottled) || errors.Is(err, ErrNoRecording) || errors.As(err, &refused) {
 			return false, err
 		}
+		// 429s are classified by the caller and surfaced with Retry-After
+		// rather than retried blindly.
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			return false, nil
+		}
 		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
 	}
 
 	return c
 }
 
-func (c *Client) beforeRequest(ctx context.Context) error {
+func (c *Client) beforeRequest(ctx context.Context, class EndpointClass) error {
+	if err := c.waitPause(ctx); err != nil {
+		return err
+	}
 	if c.limiter != nil {
 		if err := c.limiter.Wait(ctx); err != nil {
 			return err
@@ -112,20 +170,52 @@ func (c *Client) beforeRequest(ctx context.Context) error {
 		return nil
 	}
 	now := time.Now().UTC()
-	dayKey := now.Format("2006-01-02")
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.dayKey != dayKey {
-		c.dayKey = dayKey
-		c.dayCount = 0
+	c.rollDay(now)
+	if c.dayCount >= c.dailyLimit {
+		return &QuotaError{Daily: true, RetryAfter: untilUTCMidnight(now), Limit: c.dailyLimit, Remaining: 0}
 	}
-	if c.dailyLimit > 0 && c.dayCount >= c.dailyLimit {
-		return ErrDailyLimitExceeded
+	if qe := c.admit(class, priorityOf(ctx)); qe != nil {
+		qe.RetryAfter = untilUTCMidnight(now)
+		return qe
 	}
-	c.dayCount++
 	return nil
 }
 
+// waitPause holds requests while a throttle's Retry-After is in effect,
+// failing fast when the caller's deadline would expire first or the pause
+// is quota exhaustion.
+func (c *Client) waitPause(ctx context.Context) error {
+	c.mu.Lock()
+	until, daily := c.pausedUntil, c.pausedDaily
+	c.mu.Unlock()
+	wait := time.Until(until)
+	if wait <= 0 {
+		return nil
+	}
+	if dl, ok := ctx.Deadline(); daily || (ok && dl.Before(until)) {
+		return &QuotaError{Daily: daily, RetryAfter: wait, Limit: -1, Remaining: -1}
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// rollDay resets the daily counter at UTC midnight. Callers hold c.mu.
+func (c *Client) rollDay(now time.Time) {
+	if dayKey := now.UTC().Format("2006-01-02"); c.dayKey != dayKey {
+		c.dayKey = dayKey
+		c.dayCount = 0
+		clear(c.classCount)
+	}
+}
+
 func (c *Client) RemainingDailyQuota() int {
 	if c.dailyLimit <= 0 {
 		return -1
@@ -141,7 +231,9 @@ func (c *Client) SearchByRadius(ctx context.Context, lat, lon float64, radiusMil
 }
 
 // SearchByPostal uses RapidAPI Realtor: GET /search/forsale?location=ZIP&page=&limit=
-func (c *Client) SearchByPostal(ctx context.Context, postal string, pagesize, page int, propertyType, orderBy string) ([]byte, error) {
+// The location is passed through as-is, so "City, ST" works as well as a ZIP.
+// propertyType and orderBy become the provider's property_type and sort.
+func (c *Client) SearchByPostal(ctx context.Context, location string, pagesize, page int, propertyType, orderBy string) ([]byte, error) {
 	if pagesize <= 0 {
 		pagesize = 5
 	}
@@ -149,9 +241,10 @@ func (c *Client) SearchByPostal(ctx context.Context, postal string, pagesize, pa
 		page = 1
 	}
 	q := url.Values{}
-	q.Set("location", postal)
+	q.Set("location", location)
 	q.Set("page", fmt.Sprintf("%d", page))
 	q.Set("limit", fmt.Sprintf("%d", pagesize))
+	setSearchFilters(q, 0, 0, 0, 0, propertyType, orderBy)
 
 	u := fmt.Sprintf("%s/search/forsale?%s", c.baseURL, q.Encode())
 	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, u, nil)
@@ -162,13 +255,13 @@ func (c *Client) SearchByPostal(ctx context.Context, postal string, pagesize, pa
 	req.Header.Set("X-RapidAPI-Key", c.key)
 	req.Header.Set("X-RapidAPI-Host", c.host)
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusTooManyRequests {
-		return nil, ErrDailyLimitExceeded
+		return nil, quotaError(resp, time.Now())
 	}
 	if resp.StatusCode >= 400 {
 		var body map[string]any
@@ -180,11 +273,13 @@ func (c *Client) SearchByPostal(ctx context.Context, postal string, pagesize, pa
 		return nil, err
 	}
 	logBody("SearchByPostal", b)
+	c.checkDrift("search/forsale", b)
 	return b, nil
 }
 
-// SearchListingsByPostal mirrors SearchByPostal for listings.
-func (c *Client) SearchListingsByPostal(ctx context.Context, postal string, pagesize, page int, beds, baths, minPrice, maxPrice int, propertyType, orderBy string) ([]byte, error) {
+// SearchListingsByPostal mirrors SearchByPostal for listings, and also sends
+// the bed, bath and price filters upstream.
+func (c *Client) SearchListingsByPostal(ctx context.Context, location string, pagesize, page int, beds, baths, minPrice, maxPrice int, propertyType, orderBy string) ([]byte, error) {
 	if pagesize <= 0 {
 		pagesize = 5
 	}
@@ -192,9 +287,10 @@ func (c *Client) SearchListingsByPostal(ctx context.Context, postal string, page
 		page = 1
 	}
 	q := url.Values{}
-	q.Set("location", postal)
+	q.Set("location", location)
 	q.Set("page", fmt.Sprintf("%d", page))
 	q.Set("limit", fmt.Sprintf("%d", pagesize))
+	setSearchFilters(q, beds, baths, minPrice, maxPrice, propertyType, orderBy)
 
 	u := fmt.Sprintf("%s/search/forsale?%s", c.baseURL, q.Encode())
 	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, u, nil)
@@ -205,13 +301,13 @@ func (c *Client) SearchListingsByPostal(ctx context.Context, postal string, page
 	req.Header.Set("X-RapidAPI-Key", c.key)
 	req.Header.Set("X-RapidAPI-Host", c.host)
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusTooManyRequests {
-		return nil, ErrDailyLimitExceeded
+		return nil, quotaError(resp, time.Now())
 	}
 	if resp.StatusCode >= 400 {
 		var body map[string]any
@@ -223,6 +319,7 @@ func (c *Client) SearchListingsByPostal(ctx context.Context, postal string, page
 		return nil, err
 	}
 	logBody("SearchListingsByPostal", b)
+	c.checkDrift("search/forsale", b)
 	return b, nil
 }
 
@@ -240,13 +337,13 @@ func (c *Client) GetPhotos(ctx context.Context, propertyID string) ([]PhotoAsset
 	req.Header.Set("X-RapidAPI-Key", c.key)
 	req.Header.Set("X-RapidAPI-Host", c.host)
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusTooManyRequests {
-		return nil, ErrDailyLimitExceeded
+		return nil, quotaError(resp, time.Now())
 	}
 	if resp.StatusCode >= 400 {
 		var body any
@@ -258,6 +355,7 @@ func (c *Client) GetPhotos(ctx context.Context, propertyID string) ([]PhotoAsset
 		return nil, err
 	}
 	log.Printf("[DEBUG] photos response for property %s: %s", propertyID, string(b))
+	c.checkDrift("property/photos", b)
 	var arr []struct {
 		Description string `json:"description"`
 		Href        string `json:"href"`
@@ -293,6 +391,28 @@ func (c *Client) GetPhotos(ctx context.Context, propertyID string) ([]PhotoAsset
 	return assets, nil
 }
 
+// driftReportEvery throttles repeat alerts for an unchanged drift signature.
+const driftReportEvery = time.Hour
+
+// checkDrift validates a payload against its schema and, for drift not
+// reported within driftReportEvery, logs it and passes it to the drift hook.
+func (c *Client) checkDrift(endpoint string, body []byte) {
+	r, ok := CheckPayload(endpoint, body)
+	if !ok || !r.Drifted() {
+		return
+	}
+	sig := r.Signature()
+	now := time.Now()
+	if last, ok := c.driftReported.Load(sig); ok && now.Sub(last.(time.Time)) < driftReportEvery {
+		return
+	}
+	c.driftReported.Store(sig, now)
+	log.Printf("[WARN] provider payload drift on %s: unknown=%v missing=%v", endpoint, r.Unknown, r.Missing)
+	if c.driftHook != nil {
+		c.driftHook(r)
+	}
+}
+
 func logBody(label string, body []byte) {
 	const max = 2048
 	preview := body
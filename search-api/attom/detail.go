@@ -0,0 +1,82 @@
+package attom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// GetListingDetail fetches the provider's property detail for a listing_id.
+// found is false when the provider has no listing by that ID. It returns the
+// raw payload alongside the mapped card.
+func (c *Client) GetListingDetail(ctx context.Context, listingID string) (raw []byte, card PropertyCard, found bool, err error) {
+	q := url.Values{}
+	q.Set("listing_id", listingID)
+	u := fmt.Sprintf("%s/property?%s", c.baseURL, q.Encode())
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, PropertyCard{}, false, err
+	}
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("X-RapidAPI-Key", c.key)
+	req.Header.Set("X-RapidAPI-Host", c.host)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, PropertyCard{}, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, PropertyCard{}, false, quotaError(resp, time.Now())
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, PropertyCard{}, false, nil
+	}
+	if resp.StatusCode >= 400 {
+		var body any
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return nil, PropertyCard{}, false, fmt.Errorf("rapidapi error %d: %v", resp.StatusCode, body)
+	}
+	b, err := ioReadAllLimit(resp.Body, 4<<20)
+	if err != nil {
+		return nil, PropertyCard{}, false, err
+	}
+	logBody("GetListingDetail", b)
+	card, found, err = MapDetailPayloadToCard(b)
+	return b, card, found, err
+}
+
+// MapDetailPayloadToCard maps a property detail payload, which carries one
+// property in the search result shape either at the top level or under
+// data. found is false when the payload names no listing with an address.
+func MapDetailPayloadToCard(raw []byte) (PropertyCard, bool, error) {
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return PropertyCard{}, false, err
+	}
+	prop := raw
+	if len(body.Data) > 0 && string(body.Data) != "null" {
+		prop = body.Data
+	}
+	wrapped, err := json.Marshal(map[string][]json.RawMessage{"properties": {prop}})
+	if err != nil {
+		return PropertyCard{}, false, err
+	}
+	cards, err := MapSearchPayloadToCards(wrapped)
+	if err != nil || len(cards) == 0 {
+		return PropertyCard{}, false, err
+	}
+	card := cards[0]
+	if card.ID == "" || card.Address == "" || card.Zip == "" {
+		return PropertyCard{}, false, nil
+	}
+	return card, true, nil
+}
@@ -0,0 +1,245 @@
+package attom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PayloadSchema lists the field paths a provider payload is expected to
+// carry. Paths are dotted, with "[]" for array elements, e.g.
+// "properties[].location.address.line"; a top-level array is "[]".
+type PayloadSchema struct {
+	// Required paths must appear in at least one element of the payload;
+	// a page where none carry the field means the provider dropped or
+	// renamed it.
+	Required []string
+	// Known paths are mapped or deliberately ignored. A known path with no
+	// known children is opaque: its contents are not inspected.
+	Known []string
+
+	once     sync.Once
+	known    map[string]bool
+	parents  map[string]bool
+	required []string
+}
+
+func (s *PayloadSchema) init() { s.once.Do(s.build) }
+
+func (s *PayloadSchema) build() {
+	s.known = map[string]bool{}
+	s.parents = map[string]bool{}
+	for _, p := range append(append([]string(nil), s.Known...), s.Required...) {
+		s.known[p] = true
+		for i := range p {
+			if p[i] == '.' || (p[i] == '[' && i > 0) {
+				s.parents[p[:i]] = true
+			}
+		}
+		if strings.HasSuffix(p, "[]") {
+			s.parents[strings.TrimSuffix(p, "[]")] = true
+		}
+	}
+	s.required = append([]string(nil), s.Required...)
+	sort.Strings(s.required)
+}
+
+// DriftReport is the result of checking one payload against its schema.
+type DriftReport struct {
+	Endpoint string
+	Unknown  []string // fields the schema has never seen, top-most path only
+	Missing  []string // required fields absent from every element
+}
+
+// Drifted reports whether the payload differs from the schema.
+func (r DriftReport) Drifted() bool { return len(r.Unknown) > 0 || len(r.Missing) > 0 }
+
+// Signature identifies the drift so repeated reports of the same change can
+// be collapsed.
+func (r DriftReport) Signature() string {
+	sum := sha256.Sum256([]byte(r.Endpoint + "|" + strings.Join(r.Unknown, ",") + "|" + strings.Join(r.Missing, ",")))
+	return hex.EncodeToString(sum[:12])
+}
+
+// Schemas for the endpoints the client calls, keyed by endpoint path.
+var payloadSchemas = map[string]*PayloadSchema{
+	"search/forsale": {
+		Required: []string{
+			"properties",
+			"properties[].property_id",
+			"properties[].listing_id",
+			"properties[].list_price",
+			"properties[].status",
+			"properties[].location.address.line",
+			"properties[].location.address.city",
+			"properties[].location.address.state_code",
+			"properties[].location.address.postal_code",
+			"properties[].description.beds",
+			"properties[].description.sqft",
+			"properties[].description.type",
+		},
+		Known: []string{
+			"count", "total", "meta", "status", "message",
+			"properties[].location.address.state",
+			"properties[].location.address.coordinate.lat",
+			"properties[].location.address.coordinate.lon",
+			"properties[].location.address.street_name",
+			"properties[].location.address.street_number",
+			"properties[].location.address.street_suffix",
+			"properties[].location.address.unit",
+			"properties[].location.county",
+			"properties[].location.street_view_url",
+			"properties[].description.baths_consolidated",
+			"properties[].description.baths",
+			"properties[].description.baths_full",
+			"properties[].description.baths_half",
+			"properties[].description.lot_sqft",
+			"properties[].description.sub_type",
+			"properties[].description.year_built",
+			"properties[].description.garage",
+			"properties[].description.stories",
+			"properties[].hoa.fee",
+			"properties[].tax_record",
+			"properties[].details[].category",
+			"properties[].details[].text",
+			"properties[].description.name",
+			"properties[].description.text",
+			"properties[].primary_photo.href",
+			"properties[].photos[].href",
+			"properties[].photos[].tags",
+			"properties[].photo_count",
+			"properties[].advertisers[].fulfillment_id",
+			"properties[].advertisers[].type",
+			"properties[].advertisers[].name",
+			"properties[].advertisers[].email",
+			"properties[].advertisers[].phones",
+			"properties[].advertisers[].office",
+			"properties[].advertisers[].broker",
+			"properties[].advertisers[].builder",
+			"properties[].open_houses[].start_date",
+			"properties[].open_houses[].end_date",
+			"properties[].open_houses[].description",
+			"properties[].open_houses[].time_zone",
+			"properties[].open_houses[].dst",
+			"properties[].open_houses[].href",
+			"properties[].open_houses[].methods",
+			"properties[].source",
+			"properties[].list_date",
+			"properties[].last_update_date",
+			"properties[].last_sold_date",
+			"properties[].last_sold_price",
+			"properties[].list_price_min",
+			"properties[].list_price_max",
+			"properties[].price_reduced_amount",
+			"properties[].estimate",
+			"properties[].flags",
+			"properties[].tags",
+			"properties[].branding",
+			"properties[].products",
+			"properties[].permalink",
+			"properties[].href",
+			"properties[].virtual_tours",
+			"properties[].matterport",
+			"properties[].community",
+			"properties[].lead_attributes",
+			"properties[].other_listings",
+			"properties[].coming_soon_date",
+			"properties[].pet_policy",
+			"properties[].listing_price",
+		},
+	},
+	"property/photos": {
+		Required: []string{"[].href"},
+		Known:    []string{"[].description", "[].title", "[].type", "[].tags"},
+	},
+}
+
+var driftVars = expvar.NewMap("provider_drift")
+
+// CheckPayload compares raw against the schema for endpoint. ok is false
+// when no schema is registered or the payload is not JSON.
+func CheckPayload(endpoint string, raw []byte) (r DriftReport, ok bool) {
+	s := payloadSchemas[endpoint]
+	if s == nil {
+		return DriftReport{}, false
+	}
+	s.init()
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return DriftReport{}, false
+	}
+	seen := map[string]bool{}
+	unknown := map[string]bool{}
+	walkPayload(s, v, "", seen, unknown)
+
+	r.Endpoint = endpoint
+	for p := range unknown {
+		r.Unknown = append(r.Unknown, p)
+	}
+	sort.Strings(r.Unknown)
+	// An empty page says nothing about element fields.
+	empty := isEmptyPage(v)
+	for _, p := range s.required {
+		if !seen[p] && !(empty && strings.Contains(p, "[]")) {
+			r.Missing = append(r.Missing, p)
+		}
+	}
+	driftVars.Add(endpoint+".checked", 1)
+	if len(r.Unknown) > 0 {
+		driftVars.Add(endpoint+".unknown", 1)
+	}
+	if len(r.Missing) > 0 {
+		driftVars.Add(endpoint+".missing", 1)
+	}
+	return r, true
+}
+
+func walkPayload(s *PayloadSchema, v any, path string, seen, unknown map[string]bool) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			if !s.known[p] && !s.parents[p] {
+				unknown[p] = true
+				continue
+			}
+			if child == nil {
+				continue
+			}
+			seen[p] = true
+			if s.parents[p] {
+				walkPayload(s, child, p, seen, unknown)
+			}
+		}
+	case []any:
+		p := path + "[]"
+		for _, el := range t {
+			if s.parents[p] || s.known[p] {
+				seen[p] = true
+			}
+			walkPayload(s, el, p, seen, unknown)
+		}
+	}
+}
+
+func isEmptyPage(v any) bool {
+	switch t := v.(type) {
+	case []any:
+		return len(t) == 0
+	case map[string]any:
+		for _, child := range t {
+			if arr, ok := child.([]any); ok && len(arr) > 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
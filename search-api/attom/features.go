@@ -0,0 +1,98 @@
+package attom
+
+import (
+	"strconv"
+	"strings"
+)
+
+// providerDetail is one block of the provider's details list: a category
+// and "Label: value" lines such as "Garage Spaces: 2" or "Basement: Finished".
+type providerDetail struct {
+	Category string   `json:"category"`
+	Text     []string `json:"text"`
+}
+
+// detailLabels maps normalized detail labels onto the card feature they
+// describe. MLS feeds word these differently, so several labels share one.
+var detailLabels = map[string]string{
+	"association fee": "hoa", "hoa fee": "hoa", "hoa dues": "hoa", "association fee amount": "hoa",
+	"garage spaces": "garage", "garage": "garage", "parking garage spaces": "garage",
+	"stories": "stories", "levels": "stories", "stories total": "stories",
+	"pool": "pool", "has pool": "pool", "private pool": "pool", "pool features": "pool", "pool private": "pool",
+	"basement": "basement", "has basement": "basement", "basement features": "basement",
+}
+
+// applyDetails fills the card's features from the details list. Values the
+// description block already set win.
+func applyDetails(c *PropertyCard, details []providerDetail) {
+	for _, d := range details {
+		for _, line := range d.Text {
+			label, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			switch detailLabels[strings.ToLower(strings.TrimSpace(label))] {
+			case "hoa":
+				if c.HOAFee == nil {
+					c.HOAFee = leadingInt(value)
+				}
+			case "garage":
+				if c.GarageSpaces == nil {
+					c.GarageSpaces = leadingInt(value)
+				}
+			case "stories":
+				if c.Stories == nil {
+					c.Stories = leadingInt(value)
+				}
+			case "pool":
+				if c.Pool == nil {
+					c.Pool = hasFeature(value)
+				}
+			case "basement":
+				if c.Basement == nil {
+					c.Basement = hasFeature(value)
+				}
+			}
+		}
+	}
+}
+
+// leadingInt reads the first number in v ("$250 monthly", "2.0", "2 car"),
+// rounding down, or nil if there is none.
+func leadingInt(v string) *int {
+	v = strings.TrimLeft(strings.TrimSpace(v), "$")
+	end := 0
+	for end < len(v) && (v[end] >= '0' && v[end] <= '9' || v[end] == '.' || v[end] == ',') {
+		end++
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(v[:end], ",", ""), 64)
+	if err != nil || f < 0 {
+		return nil
+	}
+	i := int(f)
+	return &i
+}
+
+// hasFeature reads a yes/no detail value. Anything other than an explicit
+// negative ("None", "No", "0") describes the feature, so it counts as present.
+func hasFeature(v string) *bool {
+	var has bool
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "":
+		return nil
+	case "no", "none", "n", "false", "0":
+		has = false
+	default:
+		has = true
+	}
+	return &has
+}
+
+// numberPtr converts an optional provider number to an int, or nil.
+func numberPtr(v stringNumber) *int {
+	if v == "" {
+		return nil
+	}
+	return leadingInt(string(v))
+}
@@ -0,0 +1,148 @@
+package attom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// TaxAssessment is one tax year's assessment and bill for a property.
+type TaxAssessment struct {
+	Year             int     `json:"year"`
+	Tax              float64 `json:"tax,omitempty"`
+	AssessedLand     float64 `json:"assessedLand,omitempty"`
+	AssessedBuilding float64 `json:"assessedBuilding,omitempty"`
+	AssessedTotal    float64 `json:"assessedTotal,omitempty"`
+	MarketTotal      float64 `json:"marketTotal,omitempty"`
+}
+
+// Transfer is a recorded sale of a property.
+type Transfer struct {
+	Date   time.Time `json:"date"`
+	Price  int       `json:"price,omitempty"`
+	Event  string    `json:"event"` // provider event name, e.g. "Sold"
+	Source string    `json:"source,omitempty"`
+}
+
+// PropertyHistory is the off-market record of a property: tax assessments,
+// newest year first, and ownership transfers, newest first. APN and FIPS
+// identify the parcel when the tax record names it.
+type PropertyHistory struct {
+	APN         string          `json:"apn,omitempty"`
+	FIPS        string          `json:"fips,omitempty"`
+	Assessments []TaxAssessment `json:"assessments"`
+	Transfers   []Transfer      `json:"transfers"`
+}
+
+// GetPropertyHistory fetches the tax and sale history of a provider
+// property_id. It returns the raw payload alongside the mapped history.
+func (c *Client) GetPropertyHistory(ctx context.Context, propertyID string) ([]byte, PropertyHistory, error) {
+	q := url.Values{}
+	q.Set("property_id", propertyID)
+	u := fmt.Sprintf("%s/property?%s", c.baseURL, q.Encode())
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, PropertyHistory{}, err
+	}
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("X-RapidAPI-Key", c.key)
+	req.Header.Set("X-RapidAPI-Host", c.host)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, PropertyHistory{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, PropertyHistory{}, quotaError(resp, time.Now())
+	}
+	if resp.StatusCode >= 400 {
+		var body any
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return nil, PropertyHistory{}, fmt.Errorf("rapidapi error %d: %v", resp.StatusCode, body)
+	}
+	b, err := ioReadAllLimit(resp.Body, 4<<20)
+	if err != nil {
+		return nil, PropertyHistory{}, err
+	}
+	logBody("GetPropertyHistory", b)
+	h, err := MapPropertyHistory(b)
+	return b, h, err
+}
+
+// MapPropertyHistory reads the parcel, tax_history and the sale events of
+// property_history from a property detail payload. Listing events other than
+// sales (listed, price changed, removed) are not ownership changes and are
+// dropped.
+func MapPropertyHistory(raw []byte) (PropertyHistory, error) {
+	type rValues struct {
+		Building float64 `json:"building"`
+		Land     float64 `json:"land"`
+		Total    float64 `json:"total"`
+	}
+	type rDetail struct {
+		Location struct {
+			County rCounty `json:"county"`
+		} `json:"location"`
+		TaxRecord  rTaxRecord `json:"tax_record"`
+		TaxHistory []struct {
+			Year       int      `json:"year"`
+			Tax        float64  `json:"tax"`
+			Assessment *rValues `json:"assessment"`
+			Market     *rValues `json:"market"`
+		} `json:"tax_history"`
+		PropertyHistory []struct {
+			Date       string `json:"date"`
+			EventName  string `json:"event_name"`
+			Price      int    `json:"price"`
+			SourceName string `json:"source_name"`
+		} `json:"property_history"`
+	}
+	var body struct {
+		rDetail
+		Data *rDetail `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return PropertyHistory{}, err
+	}
+	d := body.rDetail
+	if body.Data != nil {
+		d = *body.Data
+	}
+
+	h := PropertyHistory{APN: d.TaxRecord.apn(), FIPS: d.Location.County.fips(), Assessments: []TaxAssessment{}, Transfers: []Transfer{}}
+	for _, t := range d.TaxHistory {
+		if t.Year <= 0 {
+			continue
+		}
+		a := TaxAssessment{Year: t.Year, Tax: t.Tax}
+		if t.Assessment != nil {
+			a.AssessedLand, a.AssessedBuilding, a.AssessedTotal = t.Assessment.Land, t.Assessment.Building, t.Assessment.Total
+		}
+		if t.Market != nil {
+			a.MarketTotal = t.Market.Total
+		}
+		h.Assessments = append(h.Assessments, a)
+	}
+	for _, ev := range d.PropertyHistory {
+		if !strings.EqualFold(ev.EventName, "sold") {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", ev.Date)
+		if err != nil {
+			continue
+		}
+		h.Transfers = append(h.Transfers, Transfer{Date: date, Price: ev.Price, Event: ev.EventName, Source: ev.SourceName})
+	}
+	sort.Slice(h.Assessments, func(i, j int) bool { return h.Assessments[i].Year > h.Assessments[j].Year })
+	sort.Slice(h.Transfers, func(i, j int) bool { return h.Transfers[i].Date.After(h.Transfers[j].Date) })
+	return h, nil
+}
@@ -2,7 +2,13 @@ package attom
 
 import (
 	"encoding/json"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/yourorg/search-api/internal/proptype"
 )
 
 // stringNumber accepts string or number JSON and stores as string
@@ -32,6 +38,12 @@ func (s *stringNumber) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// providerPhone is the phone entry shape shared by advertiser, office and broker blocks.
+type providerPhone struct {
+	Number string `json:"number"`
+	Type   string `json:"type"`
+}
+
 func MapSearchPayloadToCards(raw []byte) ([]PropertyCard, error) {
 	// RapidAPI Realtor search payload: { count, properties: [ {...} ] }
 	type rCoord struct {
@@ -47,25 +59,75 @@ func MapSearchPayloadToCards(raw []byte) ([]PropertyCard, error) {
 		Coordinate rCoord `json:"coordinate"`
 	}
 	type rDesc struct {
-		Beds              int    `json:"beds"`
-		BathsConsolidated string `json:"baths_consolidated"`
-		Sqft              int    `json:"sqft"`
-		Type              string `json:"type"`
+		Beds              int          `json:"beds"`
+		BathsConsolidated string       `json:"baths_consolidated"`
+		Sqft              int          `json:"sqft"`
+		Type              string       `json:"type"`
+		Text              string       `json:"text"`
+		Garage            stringNumber `json:"garage"`
+		Stories           stringNumber `json:"stories"`
 	}
 	type rPhoto struct {
 		Href string `json:"href"`
+		Tags []struct {
+			Label string `json:"label"`
+		} `json:"tags"`
+	}
+	type rOrg struct {
+		FulfillmentID stringNumber    `json:"fulfillment_id"`
+		Name          string          `json:"name"`
+		Phones        []providerPhone `json:"phones"`
+	}
+	type rAdvertiser struct {
+		FulfillmentID stringNumber    `json:"fulfillment_id"`
+		Type          string          `json:"type"`
+		Name          string          `json:"name"`
+		Email         string          `json:"email"`
+		Phones        []providerPhone `json:"phones"`
+		Office        rOrg            `json:"office"`
+		Broker        rOrg            `json:"broker"`
+	}
+	type rSourceAgent struct {
+		AgentID    stringNumber `json:"agent_id"`
+		AgentName  string       `json:"agent_name"`
+		OfficeID   stringNumber `json:"office_id"`
+		OfficeName string       `json:"office_name"`
+		Type       string       `json:"type"`
+	}
+	type rOpenHouse struct {
+		StartDate   string `json:"start_date"`
+		EndDate     string `json:"end_date"`
+		Description string `json:"description"`
+		TimeZone    string `json:"time_zone"`
 	}
 	type rProp struct {
 		ListingID  string `json:"listing_id"`
 		PropertyID string `json:"property_id"`
 		ListPrice  int    `json:"list_price"`
+		ListDate   string `json:"list_date"`
 		Location   struct {
-			Address rAddr `json:"address"`
+			Address rAddr   `json:"address"`
+			County  rCounty `json:"county"`
 		} `json:"location"`
-		Description  rDesc    `json:"description"`
-		PrimaryPhoto rPhoto   `json:"primary_photo"`
-		Photos       []rPhoto `json:"photos"`
-		Status       string   `json:"status"`
+		TaxRecord   rTaxRecord       `json:"tax_record"`
+		Description rDesc            `json:"description"`
+		Details     []providerDetail `json:"details"`
+		HOA         struct {
+			Fee stringNumber `json:"fee"`
+		} `json:"hoa"`
+		Flags struct {
+			IsNewConstruction *bool `json:"is_new_construction"`
+			IsForeclosure     *bool `json:"is_foreclosure"`
+			IsPriceReduced    *bool `json:"is_price_reduced"`
+		} `json:"flags"`
+		PrimaryPhoto rPhoto        `json:"primary_photo"`
+		Photos       []rPhoto      `json:"photos"`
+		Status       string        `json:"status"`
+		Advertisers  []rAdvertiser `json:"advertisers"`
+		OpenHouses   []rOpenHouse  `json:"open_houses"`
+		Source       struct {
+			Agents []rSourceAgent `json:"agents"`
+		} `json:"source"`
 	}
 	var root struct {
 		Properties []rProp `json:"properties"`
@@ -83,7 +145,22 @@ func MapSearchPayloadToCards(raw []byte) ([]PropertyCard, error) {
 				baths = i
 			}
 		}
+		// The primary photo leads, then the few photos the payload inlines.
 		var imgs []string
+		var photos []PhotoAsset
+		for _, ph := range append([]rPhoto{p.PrimaryPhoto}, p.Photos...) {
+			if ph.Href == "" || slices.Contains(imgs, ph.Href) {
+				continue
+			}
+			var tags []string
+			for _, t := range ph.Tags {
+				if t.Label != "" {
+					tags = append(tags, t.Label)
+				}
+			}
+			imgs = append(imgs, ph.Href)
+			photos = append(photos, PhotoAsset{Href: ph.Href, Tags: tags, Position: len(photos)})
+		}
 
 		state := p.Location.Address.StateCode
 		if state == "" {
@@ -99,29 +176,110 @@ func MapSearchPayloadToCards(raw []byte) ([]PropertyCard, error) {
 			listingID = propertyID
 		}
 
-		out = append(out, PropertyCard{
-			ID:         listingID,
-			ListingID:  listingID,
-			PropertyID: propertyID,
-			Address:    p.Location.Address.Line,
-			City:       p.Location.Address.City,
-			State:      state,
-			Zip:        p.Location.Address.PostalCode,
-			Type:       p.Description.Type,
-			Price:      p.ListPrice,
-			Beds:       maxInt(p.Description.Beds, 0),
-			Baths:      maxInt(baths, 0),
-			Sqft:       maxInt(p.Description.Sqft, 0),
-			YearBuilt:  0,
-			Images:     imgs,
-			Coords:     [2]float64{p.Location.Address.Coordinate.Lon, p.Location.Address.Coordinate.Lat},
-			MLS:        "",
-			Source:     "rapidapi",
-		})
+		var agents []Agent
+		for _, a := range p.Advertisers {
+			if a.Name == "" {
+				continue
+			}
+			agents = append(agents, Agent{
+				ID:         string(a.FulfillmentID),
+				Role:       a.Type,
+				Name:       a.Name,
+				Email:      a.Email,
+				Phone:      firstPhone(a.Phones, a.Office.Phones),
+				OfficeID:   string(a.Office.FulfillmentID),
+				OfficeName: a.Office.Name,
+				BrokerName: a.Broker.Name,
+			})
+		}
+		if len(agents) == 0 {
+			// Some plans only expose the MLS source block.
+			for _, a := range p.Source.Agents {
+				if a.AgentName == "" {
+					continue
+				}
+				agents = append(agents, Agent{
+					ID:         string(a.AgentID),
+					Role:       nonEmpty(a.Type, "seller"),
+					Name:       a.AgentName,
+					OfficeID:   string(a.OfficeID),
+					OfficeName: a.OfficeName,
+				})
+			}
+		}
+
+		// Open houses stay nil when the payload has no open_houses field,
+		// and empty when it lists none.
+		var openHouses []OpenHouse
+		if p.OpenHouses != nil {
+			openHouses = make([]OpenHouse, 0, len(p.OpenHouses))
+		}
+		for _, oh := range p.OpenHouses {
+			start, ok := parseProviderTime(oh.StartDate, oh.TimeZone)
+			if !ok {
+				continue
+			}
+			end, ok := parseProviderTime(oh.EndDate, oh.TimeZone)
+			if !ok || end.Before(start) {
+				end = start
+			}
+			openHouses = append(openHouses, OpenHouse{Start: start, End: end, Description: oh.Description})
+		}
+
+		card := PropertyCard{
+			ID:              listingID,
+			ListingID:       listingID,
+			PropertyID:      propertyID,
+			Address:         p.Location.Address.Line,
+			City:            p.Location.Address.City,
+			State:           state,
+			Zip:             p.Location.Address.PostalCode,
+			Type:            proptype.ForStorage(p.Description.Type),
+			Status:          ListingStatus(p.Status),
+			Price:           p.ListPrice,
+			Beds:            maxInt(p.Description.Beds, 0),
+			Baths:           maxInt(baths, 0),
+			Sqft:            maxInt(p.Description.Sqft, 0),
+			YearBuilt:       0,
+			Images:          imgs,
+			Photos:          photos,
+			Coords:          [2]float64{p.Location.Address.Coordinate.Lon, p.Location.Address.Coordinate.Lat},
+			MLS:             "",
+			Source:          "rapidapi",
+			Agents:          agents,
+			OpenHouses:      openHouses,
+			Description:     strings.TrimSpace(p.Description.Text),
+			HOAFee:          numberPtr(p.HOA.Fee),
+			GarageSpaces:    numberPtr(p.Description.Garage),
+			Stories:         numberPtr(p.Description.Stories),
+			NewConstruction: p.Flags.IsNewConstruction,
+			Foreclosure:     p.Flags.IsForeclosure,
+			PriceReduced:    p.Flags.IsPriceReduced,
+			APN:             p.TaxRecord.apn(),
+			FIPS:            p.Location.County.fips(),
+		}
+		applyDetails(&card, p.Details)
+		card.SetCounty(card.FIPS, p.Location.County.Name)
+		if t, ok := parseProviderTime(p.ListDate, ""); ok {
+			card.SetListDate(t, time.Now())
+		}
+		card.Localize()
+		out = append(out, card)
 	}
 	return out, nil
 }
 
+// ListingStatus normalizes a provider listing status: sold, pending,
+// contingent, off_market and the like pass through lowercased, while an
+// empty status or ready_to_build, a listed plan, counts as for_sale.
+func ListingStatus(s string) string {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "", "ready_to_build":
+		return "for_sale"
+	}
+	return s
+}
+
 // MapListingPayloadToCards maps listing provider snapshot payload to PropertyCard slice.
 // This function is intentionally defensive to tolerate minor schema differences across plans.
 func MapListingPayloadToCards(raw []byte) ([]PropertyCard, error) {
@@ -129,6 +287,51 @@ func MapListingPayloadToCards(raw []byte) ([]PropertyCard, error) {
 	return MapSearchPayloadToCards(raw)
 }
 
+// providerZones maps the US zone abbreviations the provider sends alongside
+// local open-house times.
+var providerZones = map[string]string{
+	"EST": "America/New_York", "EDT": "America/New_York",
+	"CST": "America/Chicago", "CDT": "America/Chicago",
+	"MST": "America/Denver", "MDT": "America/Denver",
+	"PST": "America/Los_Angeles", "PDT": "America/Los_Angeles",
+	"AKST": "America/Anchorage", "AKDT": "America/Anchorage",
+	"HST": "Pacific/Honolulu",
+}
+
+// parseProviderTime parses provider timestamps, which arrive either with an
+// offset or as local wall-clock time plus a zone abbreviation.
+func parseProviderTime(v, zone string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	loc := time.UTC
+	if name, ok := providerZones[strings.ToUpper(zone)]; ok {
+		if l, err := time.LoadLocation(name); err == nil {
+			loc = l
+		}
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func firstPhone(lists ...[]providerPhone) string {
+	for _, phones := range lists {
+		for _, ph := range phones {
+			if ph.Number != "" {
+				return ph.Number
+			}
+		}
+	}
+	return ""
+}
+
 func nonEmpty(a, b string) string {
 	if a != "" {
 		return a
@@ -1,23 +1,104 @@
 package attom
 
+import (
+	"time"
+
+	"github.com/yourorg/search-api/internal/timezone"
+)
+
 type PropertyCard struct {
-	ID         string     `json:"id"`
-	ListingID  string     `json:"listingId,omitempty"`
-	PropertyID string     `json:"propertyId,omitempty"`
-	Address    string     `json:"address"`
-	City       string     `json:"city"`
-	State      string     `json:"state"`
-	Zip        string     `json:"zip"`
-	Type       string     `json:"type"`
-	Price      int        `json:"price"` // prefer last sale or AVM if available
-	Beds       int        `json:"beds"`
-	Baths      int        `json:"baths"`
-	Sqft       int        `json:"sqft"`
-	YearBuilt  int        `json:"yearBuilt"`
-	Images     []string   `json:"images"` // may be empty
-	Coords     [2]float64 `json:"coords"` // [lng, lat]
-	MLS        string     `json:"mls"`
-	Source     string     `json:"source"` // e.g., "rapidapi"
+	ID         string      `json:"id"`
+	ListingID  string      `json:"listingId,omitempty"`
+	PropertyID string      `json:"propertyId,omitempty"`
+	Address    string      `json:"address"`
+	City       string      `json:"city"`
+	State      string      `json:"state"`
+	Zip        string      `json:"zip"`
+	Type       string      `json:"type"`
+	Status     string      `json:"status,omitempty"`
+	Price      int         `json:"price"` // prefer last sale or AVM if available
+	Beds       int         `json:"beds"`
+	Baths      int         `json:"baths"`
+	Sqft       int         `json:"sqft"`
+	YearBuilt  int         `json:"yearBuilt"`
+	Images     []string    `json:"images"` // may be empty
+	Coords     [2]float64  `json:"coords"` // [lng, lat]
+	MLS        string      `json:"mls"`
+	Source     string      `json:"source"` // e.g., "rapidapi"
+	Agents     []Agent     `json:"agents,omitempty"`
+	OpenHouses []OpenHouse `json:"openHouses,omitempty"`
+	// Description is the listing remarks; Highlight is the matched excerpt of
+	// it, with terms in <mark> tags, on keyword searches only.
+	Description string `json:"description,omitempty"`
+	Highlight   string `json:"highlight,omitempty"`
+	// ListDate is when the listing went on the market; DaysOnMarket counts
+	// whole days since then as of the response.
+	ListDate     *time.Time `json:"listDate,omitempty"`
+	DaysOnMarket *int       `json:"daysOnMarket,omitempty"`
+	// LastFetchAt is when the listing was last fetched from the provider and
+	// StaleAfter when it is due a refresh; both are only set on cards served
+	// from the database. They and ListDate are given in TimeZone, the IANA
+	// zone the property lies in.
+	LastFetchAt *time.Time `json:"lastFetchAt,omitempty"`
+	StaleAfter  *time.Time `json:"staleAfter,omitempty"`
+	TimeZone    string     `json:"timeZone,omitempty"`
+	// Features beyond beds, baths and size. Nil when the provider didn't
+	// report them; HOAFee is monthly, in dollars.
+	HOAFee       *int  `json:"hoaFee,omitempty"`
+	GarageSpaces *int  `json:"garageSpaces,omitempty"`
+	Stories      *int  `json:"stories,omitempty"`
+	Pool         *bool `json:"pool,omitempty"`
+	Basement     *bool `json:"basement,omitempty"`
+	// Listing flags as the provider reports them; nil when not sent.
+	NewConstruction *bool `json:"newConstruction,omitempty"`
+	Foreclosure     *bool `json:"foreclosure,omitempty"`
+	PriceReduced    *bool `json:"priceReduced,omitempty"`
+	// APN is the assessor parcel number as the county formats it and FIPS
+	// the county's code; together they identify the parcel where the
+	// address may not. Empty when the provider didn't report them.
+	APN  string `json:"apn,omitempty"`
+	FIPS string `json:"fips,omitempty"`
+	// County and CountyFIPS are the county to group the property under:
+	// the parcel's county when the provider reported it, otherwise the one
+	// its ZIP mostly lies in. ZIPs only resolve when the county crosswalk
+	// has them, which takes the full file loaded through COUNTY_ZIP_FILE;
+	// the embedded sample covers few.
+	County     string `json:"county,omitempty"`
+	CountyFIPS string `json:"countyFips,omitempty"`
+	// EstimatedValue is only populated on detail responses.
+	EstimatedValue *EstimatedValue `json:"estimatedValue,omitempty"`
+	// Photos are the search payload's photos behind Images, primary first,
+	// with their tags; they are persisted, not served.
+	Photos []PhotoAsset `json:"-"`
+}
+
+// EstimatedValue is an automated valuation attached to a property.
+type EstimatedValue struct {
+	Value      int       `json:"value"`
+	Low        int       `json:"low,omitempty"`
+	High       int       `json:"high,omitempty"`
+	Confidence float64   `json:"confidence"`
+	Provider   string    `json:"provider"`
+	ValuedAt   time.Time `json:"valuedAt"`
+}
+
+// OpenHouse is a scheduled showing window for a listing.
+type OpenHouse struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Description string    `json:"description,omitempty"`
+}
+
+// Agent is a listing advertiser: the listing/buyer agent plus their office and brokerage.
+type Agent struct {
+	ID         string `json:"id,omitempty"`
+	Role       string `json:"role,omitempty"` // e.g., "seller", "buyer"
+	Name       string `json:"name"`
+	Email      string `json:"email,omitempty"`
+	Phone      string `json:"phone,omitempty"`
+	OfficeID   string `json:"officeId,omitempty"`
+	OfficeName string `json:"officeName,omitempty"`
+	BrokerName string `json:"brokerName,omitempty"`
 }
 
 type PhotoAsset struct {
@@ -29,3 +110,36 @@ type PhotoAsset struct {
 	Tags        []string `json:"tags,omitempty"`
 	Position    int      `json:"position"`
 }
+
+// SetListDate records when the listing went on the market and its days on
+// market as of now.
+func (c *PropertyCard) SetListDate(listDate, now time.Time) {
+	dom := DaysOnMarket(listDate, now)
+	c.ListDate = &listDate
+	c.DaysOnMarket = &dom
+}
+
+// Localize sets TimeZone from the card's state and ZIP and converts its
+// listing timestamps to that zone. Cards in an unknown state are left as
+// they are.
+func (c *PropertyCard) Localize() {
+	loc := timezone.For(c.State, c.Zip)
+	if loc == nil {
+		return
+	}
+	c.TimeZone = loc.String()
+	for _, t := range []**time.Time{&c.ListDate, &c.LastFetchAt, &c.StaleAfter} {
+		if *t != nil {
+			local := (*t).In(loc)
+			*t = &local
+		}
+	}
+}
+
+// DaysOnMarket counts whole days from listDate to now, never negative.
+func DaysOnMarket(listDate, now time.Time) int {
+	if !now.After(listDate) {
+		return 0
+	}
+	return int(now.Sub(listDate) / (24 * time.Hour))
+}
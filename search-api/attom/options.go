@@ -0,0 +1,165 @@
+package attom
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultBaseURL = "https://realtor16.p.rapidapi.com"
+	defaultHost    = "realtor16.p.rapidapi.com"
+)
+
+// Option configures a Client at construction.
+type Option func(*Client)
+
+// WithBaseURL points the client at another deployment of the provider API,
+// e.g. a proxy or a mock server. Unless WithHost is also given, the
+// X-RapidAPI-Host header follows the URL's host.
+func WithBaseURL(u string) Option {
+	return func(c *Client) {
+		c.baseURL = u
+		if !c.hostSet {
+			if parsed, err := url.Parse(u); err == nil && parsed.Host != "" {
+				c.host = parsed.Host
+			}
+		}
+	}
+}
+
+// WithHost overrides the X-RapidAPI-Host header.
+func WithHost(h string) Option {
+	return func(c *Client) {
+		c.host = h
+		c.hostSet = true
+	}
+}
+
+// WithTransport replaces the underlying HTTP transport. Quota and rate
+// limiting still wrap it.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) { c.transport = rt }
+}
+
+// WithSandbox serves every request from fixture files under dir instead of
+// the network; see SandboxTransport. Local rate and daily limits are
+// disabled since no provider quota is spent.
+func WithSandbox(dir string) Option {
+	return func(c *Client) {
+		c.transport = &SandboxTransport{Dir: dir}
+		c.limiter = nil
+		c.dailyLimit = 0
+	}
+}
+
+// WithMock points the client at a mock provider such as cmd/mockprovider.
+// Like WithSandbox it disables local limits; the mock's own quota headers
+// and injected errors still apply.
+func WithMock(u string) Option {
+	return func(c *Client) {
+		WithBaseURL(u)(c)
+		c.limiter = nil
+		c.dailyLimit = 0
+	}
+}
+
+// WithRecorder writes every provider response under dir while still calling
+// the provider; see RecordingTransport.
+func WithRecorder(dir string) Option {
+	return func(c *Client) {
+		c.transport = &RecordingTransport{Base: c.transport, Dir: dir}
+	}
+}
+
+// WithReplay serves responses recorded under dir and never touches the
+// network. Like WithSandbox it disables local limits.
+func WithReplay(dir string) Option {
+	return func(c *Client) {
+		c.transport = &ReplayTransport{Dir: dir}
+		c.limiter = nil
+		c.dailyLimit = 0
+	}
+}
+
+// WithDriftHook receives payload drift reports, at most once per hour for
+// each distinct drift. The hook runs on the request goroutine.
+func WithDriftHook(fn func(DriftReport)) Option {
+	return func(c *Client) { c.driftHook = fn }
+}
+
+// WithBudgets replaces the default per-class budgets.
+func WithBudgets(b Budgets) Option {
+	return func(c *Client) { c.budgets = b }
+}
+
+// budgetsFromEnv reads PROVIDER_BUDGET_ORDER (classes by priority, e.g.
+// "search,detail,photos"), PROVIDER_BUDGET_<CLASS> daily caps and
+// PROVIDER_BUDGET_RESERVE. ok is false when none are set.
+func budgetsFromEnv() (b Budgets, ok bool) {
+	b = DefaultBudgets()
+	if v := os.Getenv("PROVIDER_BUDGET_ORDER"); v != "" {
+		b.Classes = make(map[EndpointClass]Budget)
+		for rank, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(strings.ToLower(name)); name != "" {
+				b.Classes[EndpointClass(name)] = Budget{Rank: rank}
+			}
+		}
+		ok = true
+	}
+	for _, class := range []EndpointClass{ClassSearch, ClassPhotos, ClassDetail} {
+		if n, err := strconv.Atoi(os.Getenv("PROVIDER_BUDGET_" + strings.ToUpper(string(class)))); err == nil && n > 0 {
+			cb := b.Classes[class]
+			cb.Daily = n
+			b.Classes[class] = cb
+			ok = true
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("PROVIDER_BUDGET_RESERVE")); err == nil && n > 0 {
+		b.Reserve = n
+		ok = true
+	}
+	return b, ok
+}
+
+// OptionsFromEnv reads PROVIDER_BASE_URL, PROVIDER_HOST,
+// PROVIDER_MOCK_URL, PROVIDER_SANDBOX_DIR, PROVIDER_RECORD_DIR,
+// PROVIDER_REPLAY_DIR, PROVIDER_TIMEOUT_MS, PROVIDER_RETRIES, the
+// connection pool settings transportOptionsFromEnv reads and the
+// PROVIDER_BUDGET_* settings. offline reports whether a mock, fixtures or
+// recordings replace the provider, in which case no API key is needed.
+func OptionsFromEnv() (opts []Option, offline bool) {
+	if v := os.Getenv("PROVIDER_BASE_URL"); v != "" {
+		opts = append(opts, WithBaseURL(v))
+	}
+	if v := os.Getenv("PROVIDER_HOST"); v != "" {
+		opts = append(opts, WithHost(v))
+	}
+	if o := RequestOptionsFromEnv("", RequestOptions{}); o != (RequestOptions{}) {
+		opts = append(opts, WithDefaultRequestOptions(o))
+	}
+	if t, ok := transportOptionsFromEnv(); ok {
+		opts = append(opts, WithTransportOptions(t))
+	}
+	if b, ok := budgetsFromEnv(); ok {
+		opts = append(opts, WithBudgets(b))
+	}
+	if v := os.Getenv("PROVIDER_MOCK_URL"); v != "" {
+		opts = append(opts, WithMock(v))
+		offline = true
+	}
+	if v := os.Getenv("PROVIDER_SANDBOX_DIR"); v != "" {
+		opts = append(opts, WithSandbox(v))
+		offline = true
+	}
+	if v := os.Getenv("PROVIDER_REPLAY_DIR"); v != "" {
+		opts = append(opts, WithReplay(v))
+		offline = true
+	}
+	if v := os.Getenv("PROVIDER_RECORD_DIR"); v != "" && !offline {
+		opts = append(opts, WithRecorder(v))
+	}
+	return opts, offline
+}
@@ -0,0 +1,47 @@
+package attom
+
+import (
+	"strings"
+
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/county"
+)
+
+// rCounty is the county block of a provider location. fips_code arrives as
+// a string on most plans and as a number on some, which drops leading zeros.
+type rCounty struct {
+	Name     string       `json:"name"`
+	FIPSCode stringNumber `json:"fips_code"`
+}
+
+// fips is the county's five-digit FIPS code, or "" when it isn't one.
+func (c rCounty) fips() string { return canon.FIPS(string(c.FIPSCode)) }
+
+// SetCounty sets County and CountyFIPS to the county fips names, or, when
+// fips is empty, to the county of the card's ZIP. name is the provider's
+// name for the county, used when the dataset doesn't know the code.
+func (c *PropertyCard) SetCounty(fips, name string) {
+	fips = canon.FIPS(fips)
+	if fips == "" {
+		if z, ok := county.ForZip(c.Zip); ok {
+			c.CountyFIPS, c.County = z.FIPS, z.Name
+		}
+		return
+	}
+	if known, ok := county.ByFIPS(fips); ok {
+		name = known.Name
+	}
+	c.CountyFIPS, c.County = fips, strings.TrimSpace(name)
+}
+
+// rTaxRecord is the public-record block of a property detail payload.
+type rTaxRecord struct {
+	APN         string `json:"apn"`
+	TaxParcelID string `json:"tax_parcel_id"`
+}
+
+// apn is the assessor parcel number; older payloads only carry it as
+// tax_parcel_id.
+func (t rTaxRecord) apn() string {
+	return strings.TrimSpace(firstNonEmpty(t.APN, t.TaxParcelID))
+}
@@ -0,0 +1,178 @@
+package attom
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrThrottled marks a short provider rate limit (per second or per minute).
+// Unlike ErrDailyLimitExceeded the request can be retried after RetryAfter.
+var ErrThrottled = errors.New("attom: provider throttled")
+
+// dailyRetryAfterThreshold separates throttles from quota exhaustion when the
+// provider only sends Retry-After.
+const dailyRetryAfterThreshold = time.Hour
+
+// QuotaError is returned when the provider (or the local daily counter)
+// refuses a request. It matches ErrDailyLimitExceeded or ErrThrottled with
+// errors.Is depending on Daily.
+type QuotaError struct {
+	Daily      bool
+	RetryAfter time.Duration
+	// Limit and Remaining echo the provider's X-RateLimit-Requests-* headers
+	// when present; -1 otherwise.
+	Limit     int
+	Remaining int
+	// Class is set when a per-class budget refused the request rather than
+	// the shared daily limit.
+	Class EndpointClass
+}
+
+func (e *QuotaError) Error() string {
+	if e.Class != "" {
+		return fmt.Sprintf("%v for %s budget (retry after %s)", ErrDailyLimitExceeded, e.Class, e.RetryAfter.Round(time.Second))
+	}
+	if e.Daily {
+		return fmt.Sprintf("%v (retry after %s)", ErrDailyLimitExceeded, e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("%v (retry after %s)", ErrThrottled, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *QuotaError) Is(target error) bool {
+	if e.Daily {
+		return target == ErrDailyLimitExceeded
+	}
+	return target == ErrThrottled
+}
+
+// RetryAfter returns how long to wait before retrying err, if it is a quota
+// error.
+func RetryAfter(err error) (time.Duration, bool) {
+	var qe *QuotaError
+	if errors.As(err, &qe) {
+		return qe.RetryAfter, true
+	}
+	return 0, false
+}
+
+// rateHeaders is what the provider reports about its limits on a response.
+type rateHeaders struct {
+	limit, remaining int           // X-RateLimit-Requests-Limit/-Remaining, -1 if absent
+	reset            time.Duration // X-RateLimit-Requests-Reset
+	retryAfter       time.Duration // Retry-After
+}
+
+func parseRateHeaders(h http.Header, now time.Time) rateHeaders {
+	rh := rateHeaders{
+		limit:     headerInt(h, "X-RateLimit-Requests-Limit"),
+		remaining: headerInt(h, "X-RateLimit-Requests-Remaining"),
+	}
+	if v := headerInt(h, "X-RateLimit-Requests-Reset"); v > 0 {
+		rh.reset = time.Duration(v) * time.Second
+	}
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			rh.retryAfter = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(v); err == nil && t.After(now) {
+			rh.retryAfter = t.Sub(now)
+		}
+	}
+	return rh
+}
+
+func headerInt(h http.Header, key string) int {
+	v := h.Get(key)
+	if v == "" {
+		return -1
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return -1
+	}
+	return i
+}
+
+// quotaError classifies a 429. The plan quota is exhausted when the provider
+// says no requests remain or asks for a long wait; anything else is a short
+// throttle.
+func quotaError(resp *http.Response, now time.Time) *QuotaError {
+	rh := parseRateHeaders(resp.Header, now)
+	qe := &QuotaError{Limit: rh.limit, Remaining: rh.remaining, RetryAfter: rh.retryAfter}
+	switch {
+	case rh.remaining == 0:
+		qe.Daily = true
+		if qe.RetryAfter == 0 {
+			qe.RetryAfter = rh.reset
+		}
+	case rh.retryAfter >= dailyRetryAfterThreshold:
+		qe.Daily = true
+	}
+	if qe.RetryAfter <= 0 {
+		if qe.Daily {
+			qe.RetryAfter = untilUTCMidnight(now)
+		} else {
+			qe.RetryAfter = time.Second
+		}
+	}
+	return qe
+}
+
+func untilUTCMidnight(now time.Time) time.Duration {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
+
+// observe adapts the client to what the provider reports on every response:
+// a throttle halves the local request rate and pauses calls for RetryAfter,
+// successful calls restore the rate gradually, and the provider's remaining
+// count tightens the local daily counter.
+func (c *Client) observe(resp *http.Response) {
+	now := time.Now()
+	rh := parseRateHeaders(resp.Header, now)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dailyLimit > 0 && rh.remaining >= 0 {
+		c.rollDay(now)
+		if used := c.dailyLimit - rh.remaining; used > c.dayCount {
+			c.dayCount = used
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		qe := quotaError(resp, now)
+		if until := now.Add(qe.RetryAfter); until.After(c.pausedUntil) {
+			c.pausedUntil = until
+			c.pausedDaily = qe.Daily
+		}
+		if !qe.Daily && c.limiter != nil {
+			floor := c.baseRate / 8
+			next := c.limiter.Limit() / 2
+			if next < floor {
+				next = floor
+			}
+			c.limiter.SetLimit(next)
+		}
+		return
+	}
+	if c.limiter != nil && resp.StatusCode < 400 {
+		if cur := c.limiter.Limit(); cur < c.baseRate {
+			next := cur * 1.1
+			if next > c.baseRate {
+				next = c.baseRate
+			}
+			c.limiter.SetLimit(next)
+		}
+	}
+}
+
+// CurrentRate is the adaptive request rate, for diagnostics.
+func (c *Client) CurrentRate() rate.Limit {
+	if c.limiter == nil {
+		return rate.Inf
+	}
+	return c.limiter.Limit()
+}
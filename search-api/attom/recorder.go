@@ -0,0 +1,117 @@
+package attom
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoRecording is returned by ReplayTransport for a request that was never
+// recorded.
+var ErrNoRecording = errors.New("attom: no recorded response")
+
+// Recording is one captured provider exchange, stored as
+// <endpoint>-<hash>.json. Credentials are never written: the key covers only
+// method, path and query.
+type Recording struct {
+	Method   string          `json:"method"`
+	Path     string          `json:"path"`
+	Query    string          `json:"query,omitempty"`
+	Status   int             `json:"status"`
+	Body     json.RawMessage `json:"body,omitempty"`      // JSON bodies, kept readable for diffs
+	BodyText string          `json:"body_text,omitempty"` // anything else
+}
+
+// RecordingKey is the file name a request is recorded under. Query
+// parameters are sorted so equivalent requests share a recording.
+func RecordingKey(req *http.Request) string {
+	endpoint := strings.ReplaceAll(strings.Trim(req.URL.Path, "/"), "/", "_")
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.Path + "?" + req.URL.Query().Encode()))
+	return endpoint + "-" + hex.EncodeToString(sum[:8]) + ".json"
+}
+
+// RecordingTransport forwards requests to Base and writes each response to
+// Dir, VCR-style, so it can later be served by ReplayTransport.
+type RecordingTransport struct {
+	Base http.RoundTripper // default http.DefaultTransport
+	Dir  string
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := Recording{Method: req.Method, Path: req.URL.Path, Query: req.URL.Query().Encode(), Status: resp.StatusCode}
+	if json.Valid(body) {
+		rec.Body = body
+	} else {
+		rec.BodyText = string(body)
+	}
+	if err := writeRecording(filepath.Join(t.Dir, RecordingKey(req)), rec); err != nil {
+		return nil, fmt.Errorf("record provider response: %w", err)
+	}
+	return resp, nil
+}
+
+func writeRecording(path string, rec Recording) error {
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Write then rename so a concurrent replay never sees a partial file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReplayTransport serves responses captured by RecordingTransport from Dir.
+// Unrecorded requests fail with ErrNoRecording rather than reaching the
+// network, which keeps tests and local runs deterministic.
+type ReplayTransport struct {
+	Dir string
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := RecordingKey(req)
+	b, err := os.ReadFile(filepath.Join(t.Dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w for %s %s (%s)", ErrNoRecording, req.Method, req.URL.RequestURI(), key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec Recording
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("recording %s: %w", key, err)
+	}
+	body := []byte(rec.Body)
+	if rec.Body == nil {
+		body = []byte(rec.BodyText)
+	}
+	return sandboxResponse(req, rec.Status, body), nil
+}
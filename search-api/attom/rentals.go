@@ -0,0 +1,134 @@
+package attom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// RentalListing is a for-rent listing as a rent comparable. Rent is the
+// monthly asking rent; for listings that quote a range across units it is
+// the middle of the range.
+type RentalListing struct {
+	PropertyID string     `json:"propertyId"`
+	Address    string     `json:"address"`
+	City       string     `json:"city"`
+	State      string     `json:"state"`
+	Zip        string     `json:"zip"`
+	Rent       int        `json:"rent"`
+	Beds       int        `json:"beds"`
+	Baths      float64    `json:"baths"`
+	Sqft       int        `json:"sqft"`
+	Coords     [2]float64 `json:"coords"` // [lng, lat]
+}
+
+// SearchRentalsByPostal fetches a page of for-rent listings in a ZIP. beds
+// zero leaves the bedroom count open.
+func (c *Client) SearchRentalsByPostal(ctx context.Context, zip string, limit, beds int) ([]RentalListing, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	q := url.Values{}
+	q.Set("location", zip)
+	q.Set("page", "1")
+	q.Set("limit", strconv.Itoa(limit))
+	if beds > 0 {
+		q.Set("beds_min", strconv.Itoa(max(beds-1, 0)))
+		q.Set("beds_max", strconv.Itoa(beds+1))
+	}
+
+	u := fmt.Sprintf("%s/search/forrent?%s", c.baseURL, q.Encode())
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("X-RapidAPI-Key", c.key)
+	req.Header.Set("X-RapidAPI-Host", c.host)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, quotaError(resp, time.Now())
+	}
+	if resp.StatusCode >= 400 {
+		var body map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return nil, fmt.Errorf("rapidapi error %d: %v", resp.StatusCode, body)
+	}
+	b, err := ioReadAllLimit(resp.Body, 4<<20)
+	if err != nil {
+		return nil, err
+	}
+	logBody("SearchRentalsByPostal", b)
+	return MapRentalPayload(b)
+}
+
+// MapRentalPayload maps a for-rent search payload, dropping listings
+// without a rent.
+func MapRentalPayload(raw []byte) ([]RentalListing, error) {
+	var root struct {
+		Properties []struct {
+			PropertyID   string `json:"property_id"`
+			ListPrice    int    `json:"list_price"`
+			ListPriceMin int    `json:"list_price_min"`
+			ListPriceMax int    `json:"list_price_max"`
+			Location     struct {
+				Address struct {
+					Line       string `json:"line"`
+					City       string `json:"city"`
+					StateCode  string `json:"state_code"`
+					PostalCode string `json:"postal_code"`
+					Coordinate struct {
+						Lat float64 `json:"lat"`
+						Lon float64 `json:"lon"`
+					} `json:"coordinate"`
+				} `json:"address"`
+			} `json:"location"`
+			Description struct {
+				Beds    int          `json:"beds"`
+				BedsMin int          `json:"beds_min"`
+				Baths   stringNumber `json:"baths_consolidated"`
+				Sqft    int          `json:"sqft"`
+				SqftMin int          `json:"sqft_min"`
+			} `json:"description"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, err
+	}
+	out := make([]RentalListing, 0, len(root.Properties))
+	for _, p := range root.Properties {
+		rent := p.ListPrice
+		if rent <= 0 && p.ListPriceMin > 0 {
+			rent = (p.ListPriceMin + max(p.ListPriceMax, p.ListPriceMin)) / 2
+		}
+		if rent <= 0 {
+			continue
+		}
+		baths, _ := strconv.ParseFloat(string(p.Description.Baths), 64)
+		a := p.Location.Address
+		out = append(out, RentalListing{
+			PropertyID: p.PropertyID,
+			Address:    a.Line,
+			City:       a.City,
+			State:      a.StateCode,
+			Zip:        a.PostalCode,
+			Rent:       rent,
+			Beds:       max(p.Description.Beds, p.Description.BedsMin),
+			Baths:      baths,
+			Sqft:       max(p.Description.Sqft, p.Description.SqftMin),
+			Coords:     [2]float64{a.Coordinate.Lon, a.Coordinate.Lat},
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,78 @@
+package attom
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// The recordings under testdata/recordings were captured with WithRecorder
+// against the sandbox fixtures; re-record them the same way when the
+// fixtures or request shapes change.
+const recordingsDir = "testdata/recordings"
+
+func TestReplaySearchByPostal(t *testing.T) {
+	c := NewClient("test", WithReplay(recordingsDir))
+	raw, err := c.SearchByPostal(context.Background(), "94110", 20, 1, "", "")
+	if err != nil {
+		t.Fatalf("SearchByPostal: %v", err)
+	}
+	cards, err := MapSearchPayloadToCards(raw)
+	if err != nil {
+		t.Fatalf("MapSearchPayloadToCards: %v", err)
+	}
+	if len(cards) != 2 {
+		t.Fatalf("got %d cards, want 2", len(cards))
+	}
+	got := cards[0]
+	if got.ListingID != "2960000001" || got.Address != "123 Sandbox St" || got.City != "San Francisco" || got.State != "CA" || got.Zip != "94110" {
+		t.Errorf("card address = %q %q, %q %q %q", got.ListingID, got.Address, got.City, got.State, got.Zip)
+	}
+	if got.Price != 875000 || got.Beds != 2 || got.Sqft != 1150 || got.Status != "for_sale" {
+		t.Errorf("card facts = price %d, beds %d, sqft %d, status %q", got.Price, got.Beds, got.Sqft, got.Status)
+	}
+	if got.Coords != [2]float64{-122.4184, 37.7485} {
+		t.Errorf("coords = %v", got.Coords)
+	}
+	if len(got.Images) != 2 {
+		t.Errorf("got %d images, want 2", len(got.Images))
+	}
+}
+
+func TestReplayListingDetail(t *testing.T) {
+	c := NewClient("test", WithReplay(recordingsDir))
+	_, card, found, err := c.GetListingDetail(context.Background(), "2960000003")
+	if err != nil {
+		t.Fatalf("GetListingDetail: %v", err)
+	}
+	if !found {
+		t.Fatal("listing not found")
+	}
+	if card.ListingID != "2960000003" || card.PropertyID != "9990000003" || card.Price != 1195000 {
+		t.Errorf("card = %q %q price %d", card.ListingID, card.PropertyID, card.Price)
+	}
+}
+
+func TestReplayPhotos(t *testing.T) {
+	c := NewClient("test", WithReplay(recordingsDir))
+	photos, err := c.GetPhotos(context.Background(), "9990000001")
+	if err != nil {
+		t.Fatalf("GetPhotos: %v", err)
+	}
+	if len(photos) == 0 {
+		t.Fatal("no photos")
+	}
+	for _, p := range photos {
+		if p.Href == "" {
+			t.Errorf("photo without href: %+v", p)
+		}
+	}
+}
+
+func TestReplayUnrecorded(t *testing.T) {
+	c := NewClient("test", WithReplay(recordingsDir))
+	_, err := c.SearchByPostal(context.Background(), "00000", 20, 1, "", "")
+	if !errors.Is(err, ErrNoRecording) {
+		t.Fatalf("err = %v, want ErrNoRecording", err)
+	}
+}
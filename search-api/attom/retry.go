@@ -0,0 +1,107 @@
+package attom
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// RequestOptions tune the timeout and retries of provider calls. Zero
+// fields keep the client's defaults, 8s per attempt and 3 retries.
+type RequestOptions struct {
+	Timeout time.Duration // per attempt
+	Retries int           // attempts after the first
+	NoRetry bool          // one attempt only, whatever Retries says
+}
+
+// Profiles for the two kinds of caller: a request someone is waiting on
+// should fail fast, background crawls can wait out a slow provider. Each
+// can be tuned with the PROVIDER_INTERACTIVE_* and PROVIDER_BULK_* settings.
+var (
+	InteractiveRequests = RequestOptionsFromEnv("interactive", RequestOptions{Timeout: 4 * time.Second, Retries: 1})
+	BulkRequests        = RequestOptionsFromEnv("bulk", RequestOptions{Timeout: 20 * time.Second, Retries: 5})
+)
+
+// RequestOptionsFromEnv returns def with PROVIDER_<NAME>_TIMEOUT_MS and
+// PROVIDER_<NAME>_RETRIES applied, e.g. PROVIDER_RESOLVE_TIMEOUT_MS, or
+// PROVIDER_TIMEOUT_MS and PROVIDER_RETRIES for an empty name. A retries
+// value of 0 disables retries.
+func RequestOptionsFromEnv(name string, def RequestOptions) RequestOptions {
+	prefix := "PROVIDER_"
+	if name != "" {
+		prefix += strings.ToUpper(name) + "_"
+	}
+	if ms, err := strconv.Atoi(os.Getenv(prefix + "TIMEOUT_MS")); err == nil && ms > 0 {
+		def.Timeout = time.Duration(ms) * time.Millisecond
+	}
+	if n, err := strconv.Atoi(os.Getenv(prefix + "RETRIES")); err == nil && n >= 0 {
+		def.Retries, def.NoRetry = n, n == 0
+	}
+	return def
+}
+
+type requestOptionsKey struct{}
+
+// WithRequestOptions applies o to provider calls made with ctx, over the
+// client's defaults.
+func WithRequestOptions(ctx context.Context, o RequestOptions) context.Context {
+	return context.WithValue(ctx, requestOptionsKey{}, o)
+}
+
+// WithDefaultRequestOptions sets the client's defaults for calls without
+// their own RequestOptions.
+func WithDefaultRequestOptions(o RequestOptions) Option {
+	return func(c *Client) { c.defaults = o }
+}
+
+// do sends req with the options its context sets, over the client's
+// defaults. Each combination of timeout and retries gets its own retryable
+// client over the shared transport, so quota, rate limiting and tracing
+// apply to all.
+func (c *Client) do(req *retryablehttp.Request) (*http.Response, error) {
+	timeout, retries := c.http.HTTPClient.Timeout, c.http.RetryMax
+	apply := func(o RequestOptions) {
+		if o.Timeout > 0 {
+			timeout = o.Timeout
+		}
+		if o.NoRetry {
+			retries = 0
+		} else if o.Retries > 0 {
+			retries = o.Retries
+		}
+	}
+	apply(c.defaults)
+	if o, ok := req.Context().Value(requestOptionsKey{}).(RequestOptions); ok {
+		apply(o)
+	}
+	if timeout == c.http.HTTPClient.Timeout && retries == c.http.RetryMax {
+		return c.http.Do(req)
+	}
+	return c.retryClient(timeout, retries).Do(req)
+}
+
+type retryProfile struct {
+	timeout time.Duration
+	retries int
+}
+
+// retryClient returns the retryable client for one timeout and retry count,
+// built on first use.
+func (c *Client) retryClient(timeout time.Duration, retries int) *retryablehttp.Client {
+	p := retryProfile{timeout, retries}
+	if rc, ok := c.profiles.Load(p); ok {
+		return rc.(*retryablehttp.Client)
+	}
+	rc := retryablehttp.NewClient()
+	rc.HTTPClient = &http.Client{Transport: c.http.HTTPClient.Transport, Timeout: timeout}
+	rc.RetryMax = retries
+	rc.RetryWaitMin, rc.RetryWaitMax = c.http.RetryWaitMin, c.http.RetryWaitMax
+	rc.CheckRetry, rc.Backoff, rc.Logger = c.http.CheckRetry, c.http.Backoff, c.http.Logger
+	actual, _ := c.profiles.LoadOrStore(p, rc)
+	return actual.(*retryablehttp.Client)
+}
@@ -0,0 +1,89 @@
+package attom
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SandboxTransport answers provider requests from JSON fixtures on disk so
+// staging and integration runs never spend RapidAPI quota. The request path
+// picks a directory ("/search/forsale" -> "search_forsale") and the file is
+// the first that exists of:
+//
+//	<key>-p<page>.json, <key>.json, default.json
+//
+// where key is the slugged location (search), property_id (photos, history)
+// or listing_id (detail), e.g. search_forsale/94110.json or
+// search_forsale/austin-tx-p2.json. A request
+// with no matching fixture gets a 404.
+type SandboxTransport struct {
+	Dir string
+}
+
+func (t *SandboxTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := strings.ReplaceAll(strings.Trim(req.URL.Path, "/"), "/", "_")
+	q := req.URL.Query()
+	key := q.Get("location")
+	if key == "" {
+		key = q.Get("property_id")
+	}
+	if key == "" {
+		key = q.Get("listing_id")
+	}
+	key = slug(key)
+
+	var candidates []string
+	if key != "" {
+		if page := q.Get("page"); page != "" && page != "1" {
+			candidates = append(candidates, key+"-p"+page+".json")
+		}
+		candidates = append(candidates, key+".json")
+	}
+	candidates = append(candidates, "default.json")
+	for _, name := range candidates {
+		b, err := os.ReadFile(filepath.Join(t.Dir, endpoint, name))
+		if err == nil {
+			return sandboxResponse(req, http.StatusOK, b), nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return sandboxResponse(req, http.StatusNotFound, []byte(`{"message":"no sandbox fixture for `+endpoint+`"}`)), nil
+}
+
+func sandboxResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode:    status,
+		Status:        http.StatusText(status),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// slug lower-cases s and collapses every run of non-alphanumerics to "-".
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
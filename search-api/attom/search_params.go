@@ -0,0 +1,71 @@
+package attom
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yourorg/search-api/internal/proptype"
+)
+
+// sortValues maps the orderby values the API accepts onto the provider's
+// sort parameter. The provider's own names pass through; the rest are
+// shorter aliases clients tend to guess. beds is only honoured on pages
+// served from the database; the provider cannot sort by it.
+var sortValues = map[string]string{
+	"relevant":           "relevant",
+	"newest":             "newest",
+	"lowest_price":       "lowest_price",
+	"highest_price":      "highest_price",
+	"open_house_date":    "open_house_date",
+	"price_reduced_date": "price_reduced_date",
+	"largest_sqft":       "largest_sqft",
+	"lot_size":           "lot_size",
+	"list_date":          "newest",
+	"price":              "lowest_price",
+	"price_asc":          "lowest_price",
+	"price_desc":         "highest_price",
+	"sqft":               "largest_sqft",
+	"beds":               "",
+}
+
+// SortValue returns the provider sort for an orderby value, case-insensitively.
+func SortValue(orderBy string) (string, bool) {
+	v, ok := sortValues[strings.ToLower(strings.TrimSpace(orderBy))]
+	return v, ok
+}
+
+// SortOrders lists the accepted orderby values, sorted.
+func SortOrders() []string {
+	out := make([]string, 0, len(sortValues))
+	for k := range sortValues {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// setSearchFilters adds a for-sale search's optional filters to q so the
+// provider narrows the page instead of us over-fetching. Zero values and
+// unknown types or orders are left out.
+func setSearchFilters(q url.Values, beds, baths, minPrice, maxPrice int, propertyType, orderBy string) {
+	if beds > 0 {
+		q.Set("beds_min", strconv.Itoa(beds))
+	}
+	if baths > 0 {
+		q.Set("baths_min", strconv.Itoa(baths))
+	}
+	if minPrice > 0 {
+		q.Set("list_price_min", strconv.Itoa(minPrice))
+	}
+	if maxPrice > 0 {
+		q.Set("list_price_max", strconv.Itoa(maxPrice))
+	}
+	if pt := proptype.Provider(proptype.Normalize(propertyType)); pt != "" {
+		q.Set("property_type", pt)
+	}
+	if s, _ := SortValue(orderBy); s != "" {
+		q.Set("sort", s)
+	}
+}
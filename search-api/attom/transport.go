@@ -0,0 +1,138 @@
+package attom
+
+import (
+	"crypto/tls"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strconv"
+	"time"
+)
+
+// TransportOptions tune the connection pool provider calls share. Bulk
+// hydration runs many workers against one host, so the pool must keep
+// enough idle connections for all of them or each request re-handshakes.
+type TransportOptions struct {
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int // 0 is unlimited
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+	TLSSessionCache     int // resumable TLS sessions kept; 0 disables resumption
+	DisableHTTP2        bool
+}
+
+// DefaultTransportOptions keeps 32 idle connections to the provider for 90s
+// and resumes TLS sessions, preferring HTTP/2.
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSSessionCache:     64,
+	}
+}
+
+// NewTransport returns a pooled transport configured by o.
+func NewTransport(o TransportOptions) *http.Transport {
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: o.KeepAlive,
+		}).DialContext,
+		ForceAttemptHTTP2:     !o.DisableHTTP2,
+		MaxIdleConns:          max(100, o.MaxIdleConnsPerHost),
+		MaxIdleConnsPerHost:   o.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       o.MaxConnsPerHost,
+		IdleConnTimeout:       o.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig:       &tls.Config{},
+	}
+	if o.TLSSessionCache > 0 {
+		t.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(o.TLSSessionCache)
+	}
+	if o.DisableHTTP2 {
+		// A non-nil empty map is how net/http is told not to negotiate h2.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t
+}
+
+// WithTransportOptions tunes the client's connection pool. It has no effect
+// when WithTransport, a sandbox or a replay replaces the transport.
+func WithTransportOptions(o TransportOptions) Option {
+	return func(c *Client) { c.transportOpts = o }
+}
+
+// transportOptionsFromEnv reads PROVIDER_MAX_IDLE_CONNS_PER_HOST,
+// PROVIDER_MAX_CONNS_PER_HOST, PROVIDER_IDLE_CONN_TIMEOUT_SECONDS,
+// PROVIDER_KEEPALIVE_SECONDS, PROVIDER_TLS_SESSION_CACHE and
+// PROVIDER_DISABLE_HTTP2 over the defaults. ok is false when none are set.
+func transportOptionsFromEnv() (o TransportOptions, ok bool) {
+	o = DefaultTransportOptions()
+	intVar := func(name string, dst *int) {
+		if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n >= 0 {
+			*dst, ok = n, true
+		}
+	}
+	secondsVar := func(name string, dst *time.Duration) {
+		if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+			*dst, ok = time.Duration(n)*time.Second, true
+		}
+	}
+	intVar("PROVIDER_MAX_IDLE_CONNS_PER_HOST", &o.MaxIdleConnsPerHost)
+	intVar("PROVIDER_MAX_CONNS_PER_HOST", &o.MaxConnsPerHost)
+	intVar("PROVIDER_TLS_SESSION_CACHE", &o.TLSSessionCache)
+	secondsVar("PROVIDER_IDLE_CONN_TIMEOUT_SECONDS", &o.IdleConnTimeout)
+	secondsVar("PROVIDER_KEEPALIVE_SECONDS", &o.KeepAlive)
+	if b, err := strconv.ParseBool(os.Getenv("PROVIDER_DISABLE_HTTP2")); err == nil {
+		o.DisableHTTP2, ok = b, true
+	}
+	return o, ok
+}
+
+// connVars counts how provider requests got their connections, under
+// /debug/vars as provider_conns: reused and new connections, full and
+// resumed TLS handshakes, and responses by protocol. A high new-to-reused
+// ratio means the idle pool is too small for the request concurrency.
+var connVars = expvar.NewMap("provider_conns")
+
+// connTraceTransport records connection reuse for each request it sends.
+type connTraceTransport struct {
+	base http.RoundTripper
+}
+
+func (t connTraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				connVars.Add("reused", 1)
+			} else {
+				connVars.Add("new", 1)
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			switch {
+			case err != nil:
+				connVars.Add("tls_failed", 1)
+			case state.DidResume:
+				connVars.Add("tls_resumed", 1)
+			default:
+				connVars.Add("tls_full", 1)
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		if resp.ProtoMajor == 2 {
+			connVars.Add("http2", 1)
+		} else {
+			connVars.Add("http1", 1)
+		}
+	}
+	return resp, err
+}
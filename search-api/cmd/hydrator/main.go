@@ -12,31 +12,111 @@ import (
 	"time"
 
 	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/archive"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/county"
+	"github.com/yourorg/search-api/internal/digest"
+	"github.com/yourorg/search-api/internal/enrichment"
 	"github.com/yourorg/search-api/internal/env"
 	"github.com/yourorg/search-api/internal/events"
 	"github.com/yourorg/search-api/internal/hydrator"
+	"github.com/yourorg/search-api/internal/markets"
+	"github.com/yourorg/search-api/internal/photoclass"
+	"github.com/yourorg/search-api/internal/proptype"
 	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/tracing"
 )
 
 func main() {
-	apiKey := env.Must("RAPIDAPI_KEY")
+	providerOpts, offline := attom.OptionsFromEnv()
+	apiKey := os.Getenv("RAPIDAPI_KEY")
+	if offline {
+		log.Printf("[INFO] provider offline: serving fixtures/recordings instead of RapidAPI")
+	} else {
+		apiKey = env.Must("RAPIDAPI_KEY")
+	}
 	dsn := env.Must("PG_DSN")
+	rules, err := canon.RulesFromEnv()
+	if err != nil {
+		log.Fatalf("canon rules: %v", err)
+	}
+	canon.SetRules(rules)
+	if err := county.FromEnv(); errors.Is(err, county.ErrSampleOnly) {
+		log.Printf("[WARN] county crosswalk: %v", err)
+	} else if err != nil {
+		log.Fatalf("county crosswalk: %v", err)
+	}
 
+	// HYDRATOR_TARGETS=table|stale|demand picks ZIPs from the database each cycle
+	// instead of the static HYDRATOR_ZIPS list.
+	targets := os.Getenv("HYDRATOR_TARGETS")
+	if targets == "static" {
+		targets = hydrator.TargetsStatic
+	}
 	zips := splitList(os.Getenv("HYDRATOR_ZIPS"))
-	if len(zips) == 0 {
+	if targets == hydrator.TargetsStatic && len(zips) == 0 {
 		log.Fatal("HYDRATOR_ZIPS must be provided")
 	}
+	targetLimit := parseInt(os.Getenv("HYDRATOR_TARGET_LIMIT"), 50)
+	staleAfter := parseDuration(os.Getenv("HYDRATOR_STALE_AFTER"), 24*time.Hour)
+	demandWindow := parseDuration(os.Getenv("HYDRATOR_DEMAND_WINDOW"), 7*24*time.Hour)
 
 	interval := parseDuration(os.Getenv("HYDRATOR_INTERVAL"), 6*time.Hour)
+	adaptive := parseBool(os.Getenv("HYDRATOR_ADAPTIVE"), false)
+	minInterval := parseDuration(os.Getenv("HYDRATOR_MIN_INTERVAL"), 0)
+	maxInterval := parseDuration(os.Getenv("HYDRATOR_MAX_INTERVAL"), 0)
+	schedulerTick := parseDuration(os.Getenv("HYDRATOR_SCHEDULER_TICK"), 5*time.Minute)
+	concurrency := parseInt(os.Getenv("HYDRATOR_CONCURRENCY"), 1)
+	// HYDRATOR_DELIST_AFTER=0 turns delisting detection off.
+	delistAfter := parseInt(os.Getenv("HYDRATOR_DELIST_AFTER"), 3)
+	if delistAfter <= 0 {
+		delistAfter = -1
+	}
 	pageSize := parseInt(os.Getenv("HYDRATOR_PAGE_SIZE"), 50)
 	maxPages := parseInt(os.Getenv("HYDRATOR_MAX_PAGES"), 5)
 	pause := parseDuration(os.Getenv("HYDRATOR_PAUSE"), 1500*time.Millisecond)
 	requestTimeout := parseDuration(os.Getenv("HYDRATOR_REQUEST_TIMEOUT"), 12*time.Second)
 	fetchPhotos := parseBool(os.Getenv("HYDRATOR_FETCH_PHOTOS"), false)
+	fetchHistory := parseBool(os.Getenv("HYDRATOR_FETCH_HISTORY"), false)
+	historyMaxAge := parseDuration(os.Getenv("HYDRATOR_HISTORY_MAX_AGE"), 30*24*time.Hour)
 	runOnce := parseBool(os.Getenv("HYDRATOR_RUN_ONCE"), false)
+	rollupEnabled := parseBool(os.Getenv("HYDRATOR_MARKET_ROLLUP"), true)
+	rollupAt := parseDuration(os.Getenv("HYDRATOR_MARKET_ROLLUP_AT"), 15*time.Minute)
+	// Weekly reports run on Mondays, after that day's rollup.
+	reportsEnabled := parseBool(os.Getenv("HYDRATOR_MARKET_REPORTS"), true)
+	reportsAt := parseDuration(os.Getenv("HYDRATOR_MARKET_REPORTS_AT"), time.Hour)
+	// HYDRATOR_RECONCILE_INTERVAL=0 turns cross-provider listing dedup off.
+	reconcileEvery := parseDuration(os.Getenv("HYDRATOR_RECONCILE_INTERVAL"), time.Hour)
+	providerOrder := splitList(os.Getenv("HYDRATOR_PROVIDER_ORDER"))
+	photoHash := parseBool(os.Getenv("HYDRATOR_PHOTO_HASH"), false)
+	photoHashEvery := parseDuration(os.Getenv("HYDRATOR_PHOTO_HASH_INTERVAL"), 10*time.Minute)
+	photoHashBatch := parseInt(os.Getenv("HYDRATOR_PHOTO_HASH_BATCH"), 200)
+	// HYDRATOR_PHOTO_CLASSIFIER_URL enables room classification of photos
+	// whose provider tags name no room.
+	classifierURL := os.Getenv("HYDRATOR_PHOTO_CLASSIFIER_URL")
+	classifyEvery := parseDuration(os.Getenv("HYDRATOR_PHOTO_CLASSIFY_INTERVAL"), 10*time.Minute)
+	// HYDRATOR_SNAPSHOT_KEEP_MONTHS=0 keeps every raw snapshot partition.
+	snapshotKeepMonths := parseInt(os.Getenv("HYDRATOR_SNAPSHOT_KEEP_MONTHS"), 6)
+	snapshotRetentionEvery := parseDuration(os.Getenv("HYDRATOR_SNAPSHOT_RETENTION_INTERVAL"), 24*time.Hour)
+	// Saved-search digests are mailed when DIGEST_SMTP_ADDR is set.
+	digestEvery := parseDuration(os.Getenv("DIGEST_INTERVAL"), 15*time.Minute)
+	digestMaxListings := parseInt(os.Getenv("DIGEST_MAX_LISTINGS"), 25)
+	digestListingURL := os.Getenv("DIGEST_LISTING_URL")
+	digestSearchURL := os.Getenv("DIGEST_SEARCH_URL")
 
-	propertyTypes := splitList(os.Getenv("HYDRATOR_PROPERTY_TYPES"))
+	var propertyTypes []string
+	for _, t := range splitList(os.Getenv("HYDRATOR_PROPERTY_TYPES")) {
+		if n := proptype.Normalize(t); n != "" {
+			propertyTypes = append(propertyTypes, n)
+		} else {
+			log.Printf("[WARN] HYDRATOR_PROPERTY_TYPES: unknown property type %q skipped", t)
+		}
+	}
 	orderBy := os.Getenv("HYDRATOR_ORDER_BY")
+	if _, ok := attom.SortValue(orderBy); orderBy != "" && !ok {
+		log.Printf("[WARN] HYDRATOR_ORDER_BY=%q is not a provider sort order; pages will use the provider default", orderBy)
+	}
+	jobName := env.Get("HYDRATOR_JOB_NAME", "bulk")
 	provider := env.Get("HYDRATOR_PROVIDER", "rapidapi.realtor16")
 	endpoint := env.Get("HYDRATOR_ENDPOINT", "search/forsale")
 	minBeds := parseInt(os.Getenv("HYDRATOR_MIN_BEDS"), 0)
@@ -44,13 +124,12 @@ func main() {
 	minPrice := parseInt(os.Getenv("HYDRATOR_MIN_PRICE"), 0)
 	maxPrice := parseInt(os.Getenv("HYDRATOR_MAX_PRICE"), 0)
 
-	client := attom.NewClient(apiKey)
-
-	st, err := store.Open(dsn)
+	store.SetSlowQueryThreshold(time.Duration(env.GetInt("PG_SLOW_QUERY_MS", 500)) * time.Millisecond)
+	st, err := store.Open(dsn, store.PoolConfigFromEnv())
 	if err != nil {
 		log.Fatalf("store open error: %v", err)
 	}
-	defer st.DB.Close()
+	defer st.Close()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	if err := st.Ping(ctx); err != nil {
@@ -65,16 +144,28 @@ func main() {
 
 	pub := events.NewInMemory(256)
 	hyd := &hydrator.Hydrator{Store: st, Pub: pub}
+	client := attom.NewClient(apiKey, append(providerOpts, attom.WithDriftHook(hyd.RecordDrift))...)
 
 	job := &hydrator.BulkJob{
 		Client:   client,
 		Hydrator: hyd,
 		Config: hydrator.BulkConfig{
+			Name:                 jobName,
+			Targets:              targets,
+			TargetLimit:          targetLimit,
+			StaleAfter:           staleAfter,
+			DemandWindow:         demandWindow,
 			Zips:                 zips,
 			PropertyTypes:        propertyTypes,
 			PageSize:             pageSize,
 			MaxPagesPerZip:       maxPages,
 			Interval:             interval,
+			Adaptive:             adaptive,
+			MinInterval:          minInterval,
+			MaxInterval:          maxInterval,
+			SchedulerTick:        schedulerTick,
+			Concurrency:          concurrency,
+			DelistAfterCycles:    delistAfter,
 			PauseBetweenRequests: pause,
 			RequestTimeout:       requestTimeout,
 			FetchPhotos:          fetchPhotos,
@@ -85,12 +176,93 @@ func main() {
 			Baths:                minBaths,
 			MinPrice:             minPrice,
 			MaxPrice:             maxPrice,
+			FetchHistory:         fetchHistory,
+			HistoryMaxAge:        historyMaxAge,
 		},
 	}
 
 	rootCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	shutdownTracing, err := tracing.Setup(rootCtx, "search-api-hydrator")
+	if err != nil {
+		log.Printf("[WARN] tracing disabled: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = shutdownTracing(ctx)
+	}()
+
+	// Bulk ingest publishes most property updates, so enrichment follows
+	// them here as well as in the API.
+	if enricher := (&enrichment.Service{Store: st, Sources: enrichment.SourcesFromEnv()}); enricher.Enabled() {
+		go enricher.Run(rootCtx, pub)
+	}
+
+	if rollupEnabled && !runOnce {
+		rollup := &markets.RollupJob{Store: st, RunAtUTC: rollupAt}
+		go func() {
+			if err := rollup.Run(rootCtx); err != nil {
+				log.Printf("market rollup stopped: %v", err)
+			}
+		}()
+	}
+
+	if reportsEnabled && !runOnce {
+		reports := &markets.ReportJob{Store: st, Archive: archive.FromEnv("MARKET_REPORTS", "market-reports/"), RunAtUTC: reportsAt}
+		go func() {
+			if err := reports.Run(rootCtx); err != nil {
+				log.Printf("market reports stopped: %v", err)
+			}
+		}()
+	}
+
+	if reconcileEvery > 0 && !runOnce {
+		reconcile := &hydrator.ReconcileJob{Store: st, ProviderOrder: providerOrder, Interval: reconcileEvery}
+		go func() {
+			if err := reconcile.Run(rootCtx); err != nil {
+				log.Printf("listing reconcile stopped: %v", err)
+			}
+		}()
+	}
+
+	if photoHash && !runOnce {
+		hasher := &hydrator.PhotoHashJob{Store: st, BatchSize: photoHashBatch, Interval: photoHashEvery}
+		go func() {
+			if err := hasher.Run(rootCtx); err != nil {
+				log.Printf("photo hashing stopped: %v", err)
+			}
+		}()
+	}
+
+	if classifierURL != "" && !runOnce {
+		classify := &hydrator.PhotoClassifyJob{Store: st, Classifier: &photoclass.Classifier{URL: classifierURL}, Interval: classifyEvery}
+		go func() {
+			if err := classify.Run(rootCtx); err != nil {
+				log.Printf("photo classification stopped: %v", err)
+			}
+		}()
+	}
+
+	if !runOnce {
+		retention := &hydrator.SnapshotRetentionJob{Store: st, Archive: archive.S3FromEnv(), KeepMonths: snapshotKeepMonths, Interval: snapshotRetentionEvery}
+		go func() {
+			if err := retention.Run(rootCtx); err != nil {
+				log.Printf("snapshot retention stopped: %v", err)
+			}
+		}()
+	}
+
+	if mailer := digest.SMTPFromEnv(); mailer != nil && !runOnce {
+		digests := &digest.Job{Store: st, Mailer: mailer, ListingURL: digestListingURL, SearchURL: digestSearchURL, MaxListings: digestMaxListings, Interval: digestEvery}
+		go func() {
+			if err := digests.Run(rootCtx); err != nil {
+				log.Printf("saved-search digests stopped: %v", err)
+			}
+		}()
+	}
+
 	if runOnce {
 		if err := job.RunOnce(rootCtx); err != nil && !errors.Is(err, context.Canceled) {
 			log.Fatalf("hydrator bulk run failed: %v", err)
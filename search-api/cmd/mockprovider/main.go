@@ -0,0 +1,176 @@
+// Command mockprovider serves RapidAPI-shaped provider responses from the
+// fixture tree, so the search API, hydrator and indexer run end to end
+// without RapidAPI. Point them at it with PROVIDER_MOCK_URL.
+//
+// Responses come from MOCK_PROVIDER_DIR, laid out as for
+// PROVIDER_SANDBOX_DIR (see attom.SandboxTransport). Settings:
+//
+//	MOCK_PROVIDER_ADDR          listen address, default :4010
+//	MOCK_PROVIDER_DIR           fixture directory, default fixtures/provider
+//	MOCK_PROVIDER_LATENCY       added to every response, e.g. 150ms
+//	MOCK_PROVIDER_JITTER        up to this much more, at random
+//	MOCK_PROVIDER_ERROR_RATE    fraction of requests failed, 0 to 1
+//	MOCK_PROVIDER_ERROR_STATUS  status of injected failures, default 503
+//	MOCK_PROVIDER_DAILY_QUOTA   requests per UTC day before 429s; 0 is unlimited
+//
+// A request can override the latency and failure settings with the
+// X-Mock-Latency (duration) and X-Mock-Status (status code) headers.
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/env"
+)
+
+type mock struct {
+	fixtures    http.RoundTripper
+	latency     time.Duration
+	jitter      time.Duration
+	errorRate   float64
+	errorStatus int
+	dailyQuota  int
+
+	mu   sync.Mutex
+	day  string
+	used int
+}
+
+func main() {
+	m := &mock{
+		fixtures:    &attom.SandboxTransport{Dir: env.Get("MOCK_PROVIDER_DIR", "fixtures/provider")},
+		latency:     parseDuration(os.Getenv("MOCK_PROVIDER_LATENCY"), 0),
+		jitter:      parseDuration(os.Getenv("MOCK_PROVIDER_JITTER"), 0),
+		errorRate:   parseFloat(os.Getenv("MOCK_PROVIDER_ERROR_RATE"), 0),
+		errorStatus: env.GetInt("MOCK_PROVIDER_ERROR_STATUS", http.StatusServiceUnavailable),
+		dailyQuota:  env.GetInt("MOCK_PROVIDER_DAILY_QUOTA", 0),
+	}
+	addr := env.Get("MOCK_PROVIDER_ADDR", ":4010")
+	srv := &http.Server{Addr: addr, Handler: m}
+	go func() {
+		log.Printf("mockprovider listening on %s", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("[WARN] http shutdown: %v", err)
+	}
+}
+
+func (m *mock) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	status := m.serve(w, req)
+	log.Printf("%s %s -> %d in %s", req.Method, req.URL.RequestURI(), status, time.Since(start).Round(time.Millisecond))
+}
+
+func (m *mock) serve(w http.ResponseWriter, req *http.Request) int {
+	delay := m.latency
+	if m.jitter > 0 {
+		delay += rand.N(m.jitter)
+	}
+	if v := req.Header.Get("X-Mock-Latency"); v != "" {
+		delay = parseDuration(v, delay)
+	}
+	select {
+	case <-time.After(delay):
+	case <-req.Context().Done():
+		return 499
+	}
+
+	remaining, reset, ok := m.take()
+	if m.dailyQuota > 0 {
+		w.Header().Set("X-RateLimit-Requests-Limit", strconv.Itoa(m.dailyQuota))
+		w.Header().Set("X-RateLimit-Requests-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Requests-Reset", strconv.Itoa(int(reset.Seconds())))
+	}
+	status := 0
+	switch {
+	case !ok:
+		status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", strconv.Itoa(int(reset.Seconds())))
+	case req.Header.Get("X-Mock-Status") != "":
+		status, _ = strconv.Atoi(req.Header.Get("X-Mock-Status"))
+	case m.errorRate > 0 && rand.Float64() < m.errorRate:
+		status = m.errorStatus
+	}
+	if status >= 400 {
+		return writeJSON(w, status, `{"message":"mock provider: injected `+http.StatusText(status)+`"}`)
+	}
+
+	resp, err := m.fixtures.RoundTrip(req)
+	if err != nil {
+		return writeJSON(w, http.StatusInternalServerError, `{"message":"mock provider: `+err.Error()+`"}`)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return writeJSON(w, resp.StatusCode, string(body))
+}
+
+// take counts one request against the daily quota and reports what is left
+// and how long until it resets at UTC midnight. ok is false once the quota
+// is spent.
+func (m *mock) take() (remaining int, reset time.Duration, ok bool) {
+	now := time.Now().UTC()
+	reset = now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+	if m.dailyQuota <= 0 {
+		return -1, reset, true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if day := now.Format(time.DateOnly); day != m.day {
+		m.day, m.used = day, 0
+	}
+	if m.used >= m.dailyQuota {
+		return 0, reset, false
+	}
+	m.used++
+	return m.dailyQuota - m.used, reset, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) int {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+	return status
+}
+
+func parseDuration(v string, def time.Duration) time.Duration {
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return def
+	}
+	return d
+}
+
+func parseFloat(v string, def float64) float64 {
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return def
+	}
+	return f
+}
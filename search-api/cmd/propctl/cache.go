@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yourorg/search-api/internal/cache"
+	"github.com/yourorg/search-api/internal/canon"
+)
+
+func cacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage Redis cache entries",
+	}
+	cmd.AddCommand(cacheFlushCmd())
+	return cmd
+}
+
+func cacheFlushCmd() *cobra.Command {
+	var city, state, zip, pattern string
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "flush [KEY...]",
+		Short: "Delete cache keys by name, by address or by pattern",
+		Long: "Deletes the given Redis keys. With --zip, the only argument is a street " +
+			"address and its resolved-property entry and negative-cache marker are " +
+			"deleted. --pattern adds every key matching a SCAN glob.",
+		Example: "  propctl cache flush prop:pk:<property-key>\n" +
+			"  propctl cache flush '123 Main St' --city Austin --state TX --zip 78701\n" +
+			"  propctl cache flush --pattern 'prop:miss:*' --dry-run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if len(args) == 0 && pattern == "" {
+				return errors.New("nothing to flush: pass keys, an address or --pattern")
+			}
+			keys := args
+			if zip != "" {
+				if len(args) != 1 || city == "" || state == "" {
+					return errors.New("flushing by address needs ADDRESS plus --city, --state and --zip")
+				}
+				_, _, _, _, pkey := canon.Canonicalize(args[0], city, state, zip)
+				keys = []string{cache.PropertyKey(pkey), "prop:miss:" + pkey}
+			}
+			rdb, err := openRedis(ctx)
+			if err != nil {
+				return err
+			}
+			if pattern != "" {
+				matched, err := rdb.Scan(ctx, pattern)
+				if err != nil {
+					return err
+				}
+				keys = append(keys, matched...)
+			}
+			for _, k := range keys {
+				fmt.Fprintln(cmd.OutOrStdout(), k)
+			}
+			if dryRun {
+				return nil
+			}
+			for i := 0; i < len(keys); i += 500 {
+				if err := rdb.Del(ctx, keys[i:min(i+500, len(keys))]...); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "flushed %d keys\n", len(keys))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&city, "city", "", "city of the address to flush")
+	cmd.Flags().StringVar(&state, "state", "", "state of the address to flush")
+	cmd.Flags().StringVar(&zip, "zip", "", "ZIP of the address to flush")
+	cmd.Flags().StringVar(&pattern, "pattern", "", "SCAN glob of keys to flush, e.g. 'listings:*'")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the keys without deleting them")
+	return cmd
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yourorg/search-api/internal/storediff"
+)
+
+func diffCmd() *cobra.Command {
+	var differ storediff.Differ
+	var failOnDiff bool
+	cmd := &cobra.Command{
+		Use:   "diff ZIP...",
+		Short: "Compare the listings the store serves for ZIPs with the provider's",
+		Long: "Reads each ZIP from Postgres and from the provider's for-sale search " +
+			"and prints the listings the store is missing, those it still serves as " +
+			"for sale after the provider dropped them, and status, price and fact " +
+			"mismatches. Each ZIP spends up to --max-pages provider calls.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := openStore()
+			if err != nil {
+				return err
+			}
+			defer st.Pool.Close()
+			client, err := providerClient()
+			if err != nil {
+				return err
+			}
+			differ.Store, differ.Client = st, client
+			var disagree int
+			for _, zip := range args {
+				report, err := differ.Diff(cmd.Context(), zip)
+				if err != nil {
+					return fmt.Errorf("%s: %w", zip, err)
+				}
+				if !report.Agrees() {
+					disagree++
+				}
+				if err := printJSON(cmd, report); err != nil {
+					return err
+				}
+			}
+			if failOnDiff && disagree > 0 {
+				return fmt.Errorf("%d of %d ZIPs disagree with the provider", disagree, len(args))
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.IntVar(&differ.PageSize, "page-size", 50, "results per provider page")
+	f.IntVar(&differ.MaxPages, "max-pages", 5, "provider pages per ZIP")
+	f.BoolVar(&failOnDiff, "fail", false, "exit non-zero when any ZIP disagrees")
+	return cmd
+}
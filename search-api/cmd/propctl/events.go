@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yourorg/search-api/internal/env"
+	"github.com/yourorg/search-api/internal/events"
+)
+
+func eventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Follow domain events",
+	}
+	cmd.AddCommand(eventsTailCmd())
+	return cmd
+}
+
+func eventsTailCmd() *cobra.Command {
+	var channel string
+	var types []string
+	cmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Print events as the API publishes them, one JSON object per line",
+		Long: "Subscribes to the Redis channel the API mirrors its events to; the " +
+			"API must run with EVENTS_REDIS_CHANNEL set. --type keeps events whose " +
+			"type starts with one of the given prefixes, e.g. listing.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			rdb, err := openRedis(ctx)
+			if err != nil {
+				return err
+			}
+			sub := rdb.Rdb.Subscribe(ctx, channel)
+			defer sub.Close()
+			if _, err := sub.Receive(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "tailing %s\n", channel)
+			msgs := sub.Channel()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case msg, ok := <-msgs:
+					if !ok {
+						return nil
+					}
+					evt, err := events.Decode([]byte(msg.Payload))
+					if err != nil {
+						fmt.Fprintf(cmd.ErrOrStderr(), "skip: %v\n", err)
+						continue
+					}
+					if !matchesType(evt.Type, types) {
+						continue
+					}
+					fmt.Fprintln(cmd.OutOrStdout(), msg.Payload)
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVar(&channel, "channel", env.Get("EVENTS_REDIS_CHANNEL", "events"), "Redis channel (env EVENTS_REDIS_CHANNEL)")
+	cmd.Flags().StringSliceVar(&types, "type", nil, "event type prefixes to keep, e.g. listing.,property.resolved")
+	return cmd
+}
+
+func matchesType(t events.Type, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(string(t), p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/hydrator"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+func rehydrateCmd() *cobra.Command {
+	cfg := hydrator.BulkConfig{
+		Name:              "propctl",
+		Targets:           hydrator.TargetsStatic,
+		DelistAfterCycles: -1,
+	}
+	cmd := &cobra.Command{
+		Use:   "rehydrate ZIP...",
+		Short: "Run one bulk hydration pass over the given ZIPs now",
+		Long: "Pages through the provider's for-sale search for each ZIP and writes " +
+			"every card to Postgres, exactly like one HYDRATOR_RUN_ONCE cycle of the " +
+			"hydrator limited to these ZIPs. Provider calls run at bulk priority.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := openStore()
+			if err != nil {
+				return err
+			}
+			defer st.Pool.Close()
+			hyd := &hydrator.Hydrator{Store: st}
+			client, err := providerClient(attom.WithDriftHook(hyd.RecordDrift))
+			if err != nil {
+				return err
+			}
+			cfg.Zips = args
+			job := &hydrator.BulkJob{
+				Client:   client,
+				Hydrator: hyd,
+				Logger:   log.New(cmd.ErrOrStderr(), "", log.LstdFlags),
+				Config:   cfg,
+			}
+			return job.RunOnce(cmd.Context())
+		},
+	}
+	f := cmd.Flags()
+	f.StringSliceVar(&cfg.PropertyTypes, "types", nil, "property types to crawl, default all")
+	f.IntVar(&cfg.PageSize, "page-size", 50, "results per provider page")
+	f.IntVar(&cfg.MaxPagesPerZip, "max-pages", 5, "pages per ZIP and property type")
+	f.DurationVar(&cfg.PauseBetweenRequests, "pause", 1500*time.Millisecond, "pause between provider pages")
+	f.DurationVar(&cfg.RequestTimeout, "request-timeout", 12*time.Second, "timeout per provider call")
+	f.BoolVar(&cfg.FetchPhotos, "photos", false, "also fetch photos for every listing")
+	f.BoolVar(&cfg.FetchHistory, "history", false, "also fetch tax and deed history for every listing")
+	return cmd
+}
+
+func replayCmd() *cobra.Command {
+	var filter store.SnapshotFilter
+	var since time.Duration
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-apply stored provider snapshots through the current mapper",
+		Long: "Maps the latest stored raw payload of each matching card with the " +
+			"current attom mapper and upserts the result, without calling the " +
+			"provider. Use it after a mapper fix to backfill existing rows.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			st, err := openStore()
+			if err != nil {
+				return err
+			}
+			defer st.Pool.Close()
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+			snaps, err := st.RawSnapshots(cmd.Context(), filter)
+			if err != nil {
+				return err
+			}
+			hyd := &hydrator.Hydrator{Store: st}
+			var replayed, skipped, failed int
+			for _, snap := range snaps {
+				if dryRun {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\t%s\n", snap.FetchedAt.Format(time.RFC3339), snap.Endpoint, snap.ExternalID, snap.ID)
+					continue
+				}
+				err := hyd.Replay(cmd.Context(), snap)
+				switch {
+				case err == nil:
+					replayed++
+				case errors.Is(err, hydrator.ErrReplayUnsupported):
+					skipped++
+				default:
+					failed++
+					fmt.Fprintf(cmd.ErrOrStderr(), "replay %s: %v\n", snap.ExternalID, err)
+				}
+				if cmd.Context().Err() != nil {
+					return cmd.Context().Err()
+				}
+			}
+			if dryRun {
+				return nil
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "replayed %d, skipped %d unsupported, %d failed\n", replayed, skipped, failed)
+			if failed > 0 {
+				return fmt.Errorf("%d snapshots failed to replay", failed)
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&filter.Provider, "provider", "", "only snapshots from this provider")
+	f.StringVar(&filter.Endpoint, "endpoint", "", "only snapshots of this endpoint, e.g. search/forsale")
+	f.StringVar(&filter.ExternalID, "id", "", "only the card with this provider ID")
+	f.DurationVar(&since, "since", 0, "only snapshots fetched within this window, e.g. 72h")
+	f.IntVar(&filter.Limit, "limit", 100, "maximum snapshots to replay")
+	f.BoolVar(&dryRun, "dry-run", false, "list the snapshots without replaying them")
+	return cmd
+}
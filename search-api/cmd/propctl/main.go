@@ -0,0 +1,90 @@
+// Command propctl runs operational tasks against the search API's Redis,
+// Postgres and provider using the same internal packages the services use.
+// Connection settings come from the services' environment variables:
+// PG_DSN, REDIS_ADDR, REDIS_PASSWORD, REDIS_DB, RAPIDAPI_KEY and the
+// PROVIDER_* settings read by attom.OptionsFromEnv. Property keys are
+// computed with the canon rules CANON_KEY_VERSION and CANON_RULES_FILE name.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/env"
+	"github.com/yourorg/search-api/internal/redisx"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	rules, err := canon.RulesFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "canon rules: %v\n", err)
+		os.Exit(1)
+	}
+	canon.SetRules(rules)
+	if err := rootCmd().ExecuteContext(ctx); err != nil {
+		os.Exit(1)
+	}
+}
+
+func rootCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "propctl",
+		Short:        "Operate the search API: resolve, cache, hydrate, migrate, events, diff and rekey",
+		SilenceUsage: true,
+	}
+	root.PersistentFlags().String("api", env.Get("PROPCTL_API", "http://localhost:4002"), "base URL of a running search-api (env PROPCTL_API)")
+	root.AddCommand(
+		resolveCmd(),
+		cacheCmd(),
+		rehydrateCmd(),
+		replayCmd(),
+		quotaCmd(),
+		migrateCmd(),
+		eventsCmd(),
+		diffCmd(),
+		rekeyCmd(),
+	)
+	return root
+}
+
+func openStore() (*store.Store, error) {
+	dsn := os.Getenv("PG_DSN")
+	if dsn == "" {
+		return nil, errors.New("PG_DSN is not set")
+	}
+	return store.Open(dsn, store.PoolConfigFromEnv())
+}
+
+func openRedis(ctx context.Context) (*redisx.Client, error) {
+	rdb := redisx.New(env.Get("REDIS_ADDR", "127.0.0.1:6379"), env.Get("REDIS_PASSWORD", ""), env.GetInt("REDIS_DB", 0))
+	if err := rdb.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return rdb, nil
+}
+
+func providerClient(opts ...attom.Option) (*attom.Client, error) {
+	envOpts, offline := attom.OptionsFromEnv()
+	apiKey := os.Getenv("RAPIDAPI_KEY")
+	if apiKey == "" && !offline {
+		return nil, errors.New("RAPIDAPI_KEY is not set")
+	}
+	return attom.NewClient(apiKey, append(envOpts, opts...)...), nil
+}
+
+func printJSON(cmd *cobra.Command, v any) error {
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
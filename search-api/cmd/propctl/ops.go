@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func quotaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "quota",
+		Short: "Show today's provider requests per endpoint class",
+		Long: "Reads provider_budgets from a running search-api's /debug/vars. " +
+			"Quota counters live in the API process, so this needs --api.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			api, _ := cmd.Flags().GetString("api")
+			req, err := http.NewRequestWithContext(cmd.Context(), http.MethodGet, strings.TrimRight(api, "/")+"/debug/vars", nil)
+			if err != nil {
+				return err
+			}
+			resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("%s/debug/vars: %s", api, resp.Status)
+			}
+			var vars map[string]json.RawMessage
+			if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+				return err
+			}
+			usage, ok := vars["provider_budgets"]
+			if !ok {
+				return fmt.Errorf("%s does not publish provider_budgets", api)
+			}
+			return printJSON(cmd, usage)
+		},
+	}
+}
+
+func migrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply the Postgres schema migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			st, err := openStore()
+			if err != nil {
+				return err
+			}
+			defer st.Pool.Close()
+			if err := st.Migrate(cmd.Context()); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.ErrOrStderr(), "migrations applied")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+func rekeyCmd() *cobra.Command {
+	var batch, limit int
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "rekey",
+		Short: "Move properties keyed under older canon rules to their current keys",
+		Long: "Recomputes the key of every property whose key_version is below the " +
+			"canon rules version in use (CANON_KEY_VERSION, CANON_RULES_FILE) from its " +
+			"stored address. Renamed properties keep their old key as an alias; a " +
+			"property whose new key already exists is merged into that property. " +
+			"Run it after deploying new rules to the API and hydrator.",
+		Example: "  CANON_KEY_VERSION=2 propctl rekey --dry-run\n" +
+			"  CANON_KEY_VERSION=2 propctl rekey --limit 1000",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			st, err := openStore()
+			if err != nil {
+				return err
+			}
+			defer st.Pool.Close()
+			ctx := store.WithActor(cmd.Context(), store.Actor{Name: "propctl:rekey"})
+			version := canon.KeyVersion()
+			counts := map[string]int{}
+			var failed, seen int
+			after := ""
+			for limit <= 0 || seen < limit {
+				n := batch
+				if limit > 0 && limit-seen < n {
+					n = limit - seen
+				}
+				keys, err := st.StaleKeys(ctx, version, after, n)
+				if err != nil {
+					return err
+				}
+				if len(keys) == 0 {
+					break
+				}
+				for _, key := range keys {
+					seen++
+					after = key
+					if dryRun {
+						fmt.Fprintln(cmd.OutOrStdout(), key)
+						continue
+					}
+					r, err := st.RekeyProperty(ctx, key, "propctl:rekey")
+					if err != nil {
+						failed++
+						fmt.Fprintf(cmd.ErrOrStderr(), "rekey %s: %v\n", key, err)
+						continue
+					}
+					counts[r.Outcome]++
+					if r.Outcome != store.RekeyUnchanged {
+						fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\n", r.Outcome, r.OldKey, r.NewKey)
+					}
+				}
+				if cmd.Context().Err() != nil {
+					return cmd.Context().Err()
+				}
+			}
+			if dryRun {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%d properties keyed below version %d\n", seen, version)
+				return nil
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "version %d: %d renamed, %d merged, %d unchanged, %d failed\n",
+				version, counts[store.RekeyRenamed], counts[store.RekeyMerged], counts[store.RekeyUnchanged], failed)
+			if failed > 0 {
+				return fmt.Errorf("%d properties failed to rekey", failed)
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.IntVar(&batch, "batch", 500, "properties read per query")
+	f.IntVar(&limit, "limit", 0, "maximum properties to rekey; 0 for all")
+	f.BoolVar(&dryRun, "dry-run", false, "list the properties without rekeying them")
+	return cmd
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yourorg/search-api/attom"
+	httpv1 "github.com/yourorg/search-api/http/v1"
+	"github.com/yourorg/search-api/internal/hydrator"
+)
+
+func resolveCmd() *cobra.Command {
+	var body httpv1.ResolveRequest
+	var noStore bool
+	cmd := &cobra.Command{
+		Use:   "resolve ADDRESS --city CITY --state ST --zip ZIP",
+		Short: "Resolve an address through the cache, store and provider",
+		Long: "Runs the same pipeline as POST /v1/properties/resolve: negative cache, " +
+			"Redis, the store, then the provider. Fresh results are written back to " +
+			"Redis and, when PG_DSN is set, to Postgres.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			body.Address = args[0]
+			rdb, err := openRedis(ctx)
+			if err != nil {
+				return err
+			}
+			deps := httpv1.ResolveDeps{
+				Redis:       rdb,
+				CacheTTL:    time.Hour,
+				StaleAfter:  5 * time.Minute,
+				NegativeTTL: 60 * time.Second,
+			}
+			if !noStore {
+				if st, err := openStore(); err == nil {
+					defer st.Pool.Close()
+					deps.Hydrator = &hydrator.Hydrator{Store: st}
+				}
+			}
+			var opts []attom.Option
+			if deps.Hydrator != nil {
+				opts = append(opts, attom.WithDriftHook(deps.Hydrator.RecordDrift))
+			}
+			if deps.Rapid, err = providerClient(opts...); err != nil {
+				return err
+			}
+			return printJSON(cmd, httpv1.Resolve(ctx, deps, body))
+		},
+	}
+	cmd.Flags().StringVar(&body.City, "city", "", "city")
+	cmd.Flags().StringVar(&body.State, "state", "", "two-letter state")
+	cmd.Flags().StringVar(&body.Zip, "zip", "", "ZIP code")
+	cmd.Flags().BoolVar(&noStore, "no-store", false, "skip Postgres even when PG_DSN is set")
+	for _, f := range []string{"city", "state", "zip"} {
+		_ = cmd.MarkFlagRequired(f)
+	}
+	return cmd
+}
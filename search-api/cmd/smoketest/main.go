@@ -0,0 +1,220 @@
+// Command smoketest runs a scripted scenario against a deployed search API
+// and prints a JSON report, exiting 1 when any check fails. It is meant for
+// deployment pipelines and canaries:
+//
+//	smoketest -api https://search.internal -zip 94110
+//
+// The scenario resolves an address twice, expecting the second answer from
+// the cache or store; searches a ZIP twice, expecting the second page from
+// the cache or database; loads the first listing's photos; and reads the
+// provider quota counters from /debug/vars. The defaults match the sandbox
+// fixtures, so a stack on PROVIDER_SANDBOX_DIR or PROVIDER_MOCK_URL passes
+// as is. Every flag also reads a SMOKE_* variable.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yourorg/search-api/internal/env"
+)
+
+// Check is one step of the scenario.
+type Check struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	Status     int    `json:"status,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is what smoketest prints.
+type Report struct {
+	API        string    `json:"api"`
+	OK         bool      `json:"ok"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Checks     []Check   `json:"checks"`
+}
+
+type runner struct {
+	api    string
+	token  string
+	client *http.Client
+	report Report
+}
+
+func main() {
+	api := flag.String("api", env.Get("SMOKE_API", "http://localhost:4002"), "base URL of the search API (SMOKE_API)")
+	address := flag.String("address", env.Get("SMOKE_ADDRESS", "123 Sandbox St"), "street address to resolve (SMOKE_ADDRESS)")
+	city := flag.String("city", env.Get("SMOKE_CITY", "San Francisco"), "city of the address (SMOKE_CITY)")
+	state := flag.String("state", env.Get("SMOKE_STATE", "CA"), "state of the address (SMOKE_STATE)")
+	zip := flag.String("zip", env.Get("SMOKE_ZIP", "94110"), "ZIP of the address, also searched (SMOKE_ZIP)")
+	token := flag.String("token", os.Getenv("SMOKE_TOKEN"), "bearer token sent with every request (SMOKE_TOKEN)")
+	timeout := flag.Duration("timeout", time.Duration(env.GetInt("SMOKE_TIMEOUT_SECONDS", 60))*time.Second, "overall deadline (SMOKE_TIMEOUT_SECONDS)")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	r := &runner{
+		api:    strings.TrimRight(*api, "/"),
+		token:  *token,
+		client: &http.Client{Timeout: 30 * time.Second},
+		report: Report{API: *api, StartedAt: time.Now().UTC()},
+	}
+	r.run(ctx, *address, *city, *state, *zip)
+
+	r.report.OK = true
+	for _, c := range r.report.Checks {
+		r.report.OK = r.report.OK && c.OK
+	}
+	r.report.DurationMS = time.Since(r.report.StartedAt).Milliseconds()
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(r.report)
+	if !r.report.OK {
+		os.Exit(1)
+	}
+}
+
+func (r *runner) run(ctx context.Context, address, city, state, zip string) {
+	r.check(ctx, "health", "/health", func(resp *http.Response, body map[string]any) (string, error) {
+		return "", nil
+	})
+
+	resolve := "/v1/properties/resolve?" + url.Values{
+		"address": {address}, "city": {city}, "state": {state}, "zip": {zip}, "wait": {"true"},
+	}.Encode()
+	var propertyKey string
+	r.check(ctx, "resolve", resolve, func(resp *http.Response, body map[string]any) (string, error) {
+		propertyKey, _ = body["property_key"].(string)
+		if propertyKey == "" {
+			return "", fmt.Errorf("no property_key")
+		}
+		return fmt.Sprintf("property %s from %v", propertyKey, body["source"]), nil
+	})
+	if propertyKey != "" {
+		r.check(ctx, "resolve_cached", resolve, func(resp *http.Response, body map[string]any) (string, error) {
+			switch source := body["source"]; source {
+			case "cache", "store":
+				return fmt.Sprintf("served from %v", source), nil
+			default:
+				return "", fmt.Errorf("second resolve came from %v, not the cache or store", source)
+			}
+		})
+	}
+
+	search := "/search/listings?" + url.Values{"postalcode": {zip}, "limit": {"5"}}.Encode()
+	var listingID string
+	r.check(ctx, "search", search, func(resp *http.Response, body map[string]any) (string, error) {
+		props, _ := body["properties"].([]any)
+		if len(props) == 0 {
+			return "", fmt.Errorf("no listings for %s", zip)
+		}
+		for _, p := range props {
+			if card, ok := p.(map[string]any); ok {
+				if id, _ := card["listingId"].(string); id != "" {
+					listingID = id
+					break
+				}
+			}
+		}
+		return fmt.Sprintf("%d listing(s) from %s", len(props), pageSource(resp, body)), nil
+	})
+	r.check(ctx, "search_cached", search, func(resp *http.Response, body map[string]any) (string, error) {
+		source := pageSource(resp, body)
+		if source == "provider" {
+			return "", fmt.Errorf("second search went to the provider (X-Cache %s)", resp.Header.Get("X-Cache"))
+		}
+		return "served from " + source, nil
+	})
+	if listingID != "" {
+		r.check(ctx, "listing_photos", "/search/listings/"+url.PathEscape(listingID)+"/photos", func(resp *http.Response, body map[string]any) (string, error) {
+			photos, _ := body["photos"].([]any)
+			if len(photos) == 0 {
+				return "", fmt.Errorf("listing %s has no photos", listingID)
+			}
+			return fmt.Sprintf("%d photo(s) for listing %s", len(photos), listingID), nil
+		})
+	}
+
+	r.check(ctx, "quota", "/debug/vars", func(resp *http.Response, body map[string]any) (string, error) {
+		usage, ok := body["provider_budgets"].(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("provider_budgets not published")
+		}
+		if _, ok := body["refresh"]; !ok {
+			return "", fmt.Errorf("refresh stats not published")
+		}
+		b, _ := json.Marshal(usage)
+		return string(b), nil
+	})
+}
+
+// pageSource says where a listings page came from: the database, the
+// cache, or the provider.
+func pageSource(resp *http.Response, body map[string]any) string {
+	switch resp.Header.Get("X-Cache") {
+	case "":
+		if _, ok := body["cache"]; !ok {
+			return "database"
+		}
+	case "HIT", "STALE":
+		return "cache"
+	}
+	return "provider"
+}
+
+// check GETs path, expecting 200 and a JSON object, and records the step;
+// verify inspects the body and describes it.
+func (r *runner) check(ctx context.Context, name, path string, verify func(*http.Response, map[string]any) (string, error)) {
+	start := time.Now()
+	c := Check{Name: name}
+	defer func() {
+		c.DurationMS = time.Since(start).Milliseconds()
+		r.report.Checks = append(r.report.Checks, c)
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.api+path, nil)
+	if err != nil {
+		c.Error = err.Error()
+		return
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		c.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	c.Status = resp.StatusCode
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		c.Error = err.Error()
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.Error = fmt.Sprintf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw[:min(len(raw), 300)])))
+		return
+	}
+	var body map[string]any
+	if err := json.Unmarshal(raw, &body); err != nil {
+		c.Error = "response is not a JSON object: " + err.Error()
+		return
+	}
+	if c.Detail, err = verify(resp, body); err != nil {
+		c.Error = err.Error()
+		return
+	}
+	c.OK = true
+}
@@ -0,0 +1,46 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+)
+
+// Where a read was served from, as X-Data-Source reports it.
+const (
+	sourceCache    = "cache"
+	sourceDatabase = "database"
+	sourceProvider = "provider"
+)
+
+// setFreshness reports where a read was served from and how fresh it is,
+// so clients need not parse the body: X-Data-Source, X-Last-Fetched-At
+// (RFC 3339, UTC) and X-Stale. A zero fetchedAt leaves X-Last-Fetched-At
+// out.
+func setFreshness(w http.ResponseWriter, source string, fetchedAt time.Time, stale bool) {
+	h := w.Header()
+	h.Set("X-Data-Source", source)
+	if !fetchedAt.IsZero() {
+		h.Set("X-Last-Fetched-At", fetchedAt.UTC().Format(time.RFC3339))
+	}
+	h.Set("X-Stale", strconv.FormatBool(stale))
+}
+
+// setCardsFreshness reports database-served cards: fetched when the least
+// recently fetched of them was, and stale when any of them is.
+func setCardsFreshness(w http.ResponseWriter, cards []attom.PropertyCard) {
+	var oldest time.Time
+	stale := false
+	now := time.Now()
+	for _, c := range cards {
+		if c.LastFetchAt != nil && (oldest.IsZero() || c.LastFetchAt.Before(oldest)) {
+			oldest = *c.LastFetchAt
+		}
+		if c.StaleAfter != nil && now.After(*c.StaleAfter) {
+			stale = true
+		}
+	}
+	setFreshness(w, sourceDatabase, oldest, stale)
+}
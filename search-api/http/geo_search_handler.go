@@ -0,0 +1,130 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/respond"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type GeoSearchDeps struct {
+	Store *store.Store
+}
+
+// GeoSearchRequest takes a GeoJSON-style bbox [west, south, east, north]
+// or a GeoJSON Polygon geometry. When both are given the polygon wins and the
+// bbox is only validated.
+type GeoSearchRequest struct {
+	BBox         []float64        `json:"bbox,omitempty"`
+	Polygon      *geoJSONGeometry `json:"polygon,omitempty"`
+	PropertyType string           `json:"property_type,omitempty"`
+	Limit        *int             `json:"limit,omitempty"`
+	Page         *int             `json:"page,omitempty"`
+}
+
+type geoJSONGeometry struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+const (
+	maxGeoPageSize     = 200
+	maxPolygonVertices = 500
+)
+
+func RegisterGeoSearch(r chi.Router, d GeoSearchDeps) {
+	r = r.With(cardShaping)
+	r.Post("/v1/search/geo", func(w http.ResponseWriter, req *http.Request) {
+		var body GeoSearchRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			apierror.WriteError(w, req, err, apierror.InvalidJSON)
+			return
+		}
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		q, err := body.toQuery()
+		if err != nil {
+			apierror.Write(w, req, apierror.BadRequest("invalid_geometry", "bbox or polygon is invalid").WithDetail(err.Error()))
+			return
+		}
+		records, err := d.Store.FetchListingsInBounds(req.Context(), q)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "geo search failed"))
+			return
+		}
+		cards := RecordsToCards(records)
+		respond.Rows(w, req, map[string]any{"ok": true, "count": len(cards), "properties": shapeCards(req, cards)}, "properties")
+	})
+}
+
+func (b GeoSearchRequest) toQuery() (store.GeoQuery, error) {
+	var q store.GeoQuery
+	pagesize := defInt(b.Limit, 50)
+	if pagesize <= 0 || pagesize > maxGeoPageSize {
+		return q, fmt.Errorf("limit must be between 1 and %d", maxGeoPageSize)
+	}
+	page := defInt(b.Page, 1)
+	if page <= 0 {
+		page = 1
+	}
+	q.Limit, q.Offset, q.PropertyType = pagesize, (page-1)*pagesize, b.PropertyType
+
+	if b.Polygon != nil {
+		if b.Polygon.Type != "Polygon" || len(b.Polygon.Coordinates) == 0 {
+			return q, fmt.Errorf("polygon must be a GeoJSON Polygon")
+		}
+		ring := b.Polygon.Coordinates[0]
+		if len(ring) < 4 {
+			return q, fmt.Errorf("polygon ring needs at least 4 positions")
+		}
+		if len(ring) > maxPolygonVertices {
+			return q, fmt.Errorf("polygon ring exceeds %d positions", maxPolygonVertices)
+		}
+		q.MinLon, q.MinLat = math.Inf(1), math.Inf(1)
+		q.MaxLon, q.MaxLat = math.Inf(-1), math.Inf(-1)
+		for _, pt := range ring {
+			if err := checkLonLat(pt[0], pt[1]); err != nil {
+				return q, err
+			}
+			q.MinLon, q.MaxLon = math.Min(q.MinLon, pt[0]), math.Max(q.MaxLon, pt[0])
+			q.MinLat, q.MaxLat = math.Min(q.MinLat, pt[1]), math.Max(q.MaxLat, pt[1])
+		}
+		q.Polygon = ring
+	}
+	if len(b.BBox) > 0 {
+		if len(b.BBox) != 4 {
+			return q, fmt.Errorf("bbox must be [west, south, east, north]")
+		}
+		west, south, east, north := b.BBox[0], b.BBox[1], b.BBox[2], b.BBox[3]
+		if err := checkLonLat(west, south); err != nil {
+			return q, err
+		}
+		if err := checkLonLat(east, north); err != nil {
+			return q, err
+		}
+		if west >= east || south >= north {
+			return q, fmt.Errorf("bbox must have west < east and south < north")
+		}
+		if q.Polygon == nil {
+			q.MinLon, q.MinLat, q.MaxLon, q.MaxLat = west, south, east, north
+		}
+	}
+	if q.Polygon == nil && len(b.BBox) == 0 {
+		return q, fmt.Errorf("bbox or polygon is required")
+	}
+	return q, nil
+}
+
+func checkLonLat(lon, lat float64) error {
+	if lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+		return fmt.Errorf("coordinate out of range: [%g, %g]", lon, lat)
+	}
+	return nil
+}
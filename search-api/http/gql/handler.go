@@ -0,0 +1,117 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/go-chi/chi/v5"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	httpv1 "github.com/yourorg/search-api/http/v1"
+)
+
+// maxQueryComplexity caps the cost queryComplexity assigns a query, which
+// grows with the objects it can return: a list field costs its page size
+// times its selection. Store calls are bounded separately, by the loaders
+// batching each nested level into one call.
+const maxQueryComplexity = 5000
+
+const (
+	// unlimitedPhotos is the photo count assumed for photos without a limit.
+	unlimitedPhotos = 25
+	// nestedListFanOut is the length assumed for unbounded short lists
+	// such as agents and open houses.
+	nestedListFanOut = 3
+)
+
+var errStoreUnavailable = errors.New("store unavailable")
+
+// Register mounts POST/GET /graphql and a playground at /graphql/playground.
+func Register(r chi.Router, res *Resolver) {
+	srv := handler.New(NewExecutableSchema(Config{Resolvers: res, Complexity: queryComplexity()}))
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.FixedComplexityLimit(maxQueryComplexity))
+	srv.SetRecoverFunc(func(ctx context.Context, err any) error {
+		log.Printf("[WARN] graphql resolver panic: %v", err)
+		return errors.New("internal error")
+	})
+	r.Handle("/graphql", res.withLoaders(srv))
+	r.Get("/graphql/playground", playground.Handler("search-api", "/graphql"))
+}
+
+// queryComplexity weights list fields by how many objects they return, so
+// the complexity limit sees the limit arguments.
+func queryComplexity() ComplexityRoot {
+	var c ComplexityRoot
+	c.Query.Listings = func(child int, _ string, _ *string, limit *int, _ *int) int {
+		return 1 + clampLimit(limit, 20, 200)*child
+	}
+	c.Query.MarketStats = func(child int, _ string, window *string) int {
+		return 1 + windowDays(window)*child
+	}
+	c.Property.Listings = func(child int, limit *int) int {
+		return 1 + clampLimit(limit, 20, 100)*child
+	}
+	c.Property.MarketStats = func(child int, window *string) int {
+		return 1 + windowDays(window)*child
+	}
+	c.Listing.Photos = func(child int, limit *int) int {
+		n := unlimitedPhotos
+		if limit != nil && *limit >= 0 {
+			n = *limit
+		}
+		return 1 + n*child
+	}
+	c.Listing.Agents = func(child int) int { return 1 + nestedListFanOut*child }
+	c.Listing.OpenHouses = func(child int, _ *bool) int { return 1 + nestedListFanOut*child }
+	return c
+}
+
+// windowDays is the number of daily rows a marketStats window returns. An
+// invalid window fails in the resolver, so it costs the default.
+func windowDays(window *string) int {
+	w := "90d"
+	if window != nil && *window != "" {
+		w = *window
+	}
+	d, err := httpv1.ParseWindow(w)
+	if err != nil {
+		return 90
+	}
+	return max(1, int(d/(24*time.Hour)))
+}
+
+// clampLimit applies a default and ceiling to an optional page size argument.
+func clampLimit(v *int, def, max int) int {
+	if v == nil || *v <= 0 {
+		return def
+	}
+	if *v > max {
+		return max
+	}
+	return *v
+}
+
+func optString(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func optInt(v int) *int {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
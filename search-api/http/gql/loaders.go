@@ -0,0 +1,191 @@
+package gql
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yourorg/search-api/http/gql/model"
+	"github.com/yourorg/search-api/internal/cache"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+const (
+	// loaderWait is how long a batch stays open for sibling resolvers.
+	// gqlgen resolves the elements of a list concurrently, so a page of
+	// listings asking for photos lands in one batch.
+	loaderWait = 2 * time.Millisecond
+	// loaderMaxBatch closes a batch early; it covers the largest page.
+	loaderMaxBatch = 200
+)
+
+// batchLoader coalesces the loads made within loaderWait into one fetch and
+// remembers each key's result for the rest of the request.
+type batchLoader[K comparable, V any] struct {
+	fetch func(keys []K) (map[K]V, error)
+
+	mu    sync.Mutex
+	open  *loaderBatch[K, V]
+	byKey map[K]*loaderBatch[K, V]
+}
+
+type loaderBatch[K comparable, V any] struct {
+	keys    []K
+	started bool
+	done    chan struct{}
+	vals    map[K]V
+	err     error
+}
+
+func newBatchLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{fetch: fetch, byKey: map[K]*loaderBatch[K, V]{}}
+}
+
+// Load returns the value fetched for key; a key the fetch did not return
+// yields the zero V.
+func (l *batchLoader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.byKey[key]
+	if !ok {
+		b = l.open
+		if b == nil {
+			b = &loaderBatch[K, V]{done: make(chan struct{})}
+			l.open = b
+			time.AfterFunc(loaderWait, func() { l.run(b) })
+		}
+		b.keys = append(b.keys, key)
+		l.byKey[key] = b
+		if len(b.keys) >= loaderMaxBatch {
+			go l.run(b)
+		}
+	}
+	l.mu.Unlock()
+	select {
+	case <-b.done:
+		return b.vals[key], b.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (l *batchLoader[K, V]) run(b *loaderBatch[K, V]) {
+	l.mu.Lock()
+	if b.started {
+		l.mu.Unlock()
+		return
+	}
+	b.started = true
+	if l.open == b {
+		l.open = nil
+	}
+	l.mu.Unlock()
+	b.vals, b.err = l.fetch(b.keys)
+	close(b.done)
+}
+
+// loaders batches the per-object lookups of one request so nested fields
+// cost one store call per level instead of one per parent.
+type loaders struct {
+	ctx context.Context
+	r   *Resolver
+
+	photos     *batchLoader[string, []string]
+	agents     *batchLoader[string, []store.ListingAgent]
+	openHouses [2]*batchLoader[string, []store.OpenHouse] // by includePast
+	properties *batchLoader[string, *model.Property]
+
+	mu       sync.Mutex
+	listings map[int]*batchLoader[string, []store.ListingRecord] // by limit
+}
+
+type loadersKey struct{}
+
+func newLoaders(ctx context.Context, r *Resolver) *loaders {
+	ld := &loaders{ctx: ctx, r: r, listings: map[int]*batchLoader[string, []store.ListingRecord]{}}
+	ld.photos = newBatchLoader(func(ids []string) (map[string][]string, error) {
+		return r.Store.FetchListingPhotosByIDs(ctx, ids)
+	})
+	ld.agents = newBatchLoader(func(ids []string) (map[string][]store.ListingAgent, error) {
+		return r.Store.FetchListingAgentsByIDs(ctx, ids)
+	})
+	for i, includePast := range []bool{false, true} {
+		ld.openHouses[i] = newBatchLoader(func(ids []string) (map[string][]store.OpenHouse, error) {
+			return r.Store.FetchOpenHousesByIDs(ctx, ids, includePast)
+		})
+	}
+	ld.properties = newBatchLoader(func(keys []string) (map[string]*model.Property, error) {
+		return r.loadProperties(ctx, keys)
+	})
+	return ld
+}
+
+// listingsByProperty returns the loader for property listings pages of n.
+func (ld *loaders) listingsByProperty(n int) *batchLoader[string, []store.ListingRecord] {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+	l, ok := ld.listings[n]
+	if !ok {
+		l = newBatchLoader(func(keys []string) (map[string][]store.ListingRecord, error) {
+			return ld.r.Store.FetchListingsByProperties(ld.ctx, keys, n)
+		})
+		ld.listings[n] = l
+	}
+	return l
+}
+
+// withLoaders gives each GraphQL request its own loaders.
+func (r *Resolver) withLoaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		ctx = context.WithValue(ctx, loadersKey{}, newLoaders(ctx, r))
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// loadersFor returns the request's loaders, or unshared ones when the
+// resolver runs outside Register's handler.
+func (r *Resolver) loadersFor(ctx context.Context) *loaders {
+	if ld, ok := ctx.Value(loadersKey{}).(*loaders); ok {
+		return ld
+	}
+	return newLoaders(ctx, r)
+}
+
+// loadProperties is Query.Property for many keys: warm keys come from one
+// cache MGET and the rest from one store query.
+func (r *Resolver) loadProperties(ctx context.Context, keys []string) (map[string]*model.Property, error) {
+	out := make(map[string]*model.Property, len(keys))
+	misses := keys
+	if r.Redis != nil {
+		cacheKeys := make([]string, len(keys))
+		for i, k := range keys {
+			cacheKeys[i] = cache.PropertyKey(k)
+		}
+		if vals, err := r.Redis.MGet(ctx, cacheKeys...); err == nil {
+			misses = nil
+			for i, k := range keys {
+				if env, err := cache.Decode(vals[i]); err == nil && env.Data.Address != "" {
+					out[k] = model.PropertyFromCard(k, env.Data)
+					continue
+				}
+				misses = append(misses, k)
+			}
+		}
+	}
+	if len(misses) == 0 {
+		return out, nil
+	}
+	if r.Store == nil {
+		return nil, errStoreUnavailable
+	}
+	records, err := r.Store.FetchProperties(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+	for k, p := range records {
+		out[k] = model.PropertyFromRecord(p)
+	}
+	return out, nil
+}
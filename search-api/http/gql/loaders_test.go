@@ -0,0 +1,52 @@
+package gql
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatchLoaderCoalescesConcurrentLoads(t *testing.T) {
+	var calls atomic.Int32
+	l := newBatchLoader(func(keys []string) (map[string]int, error) {
+		calls.Add(1)
+		out := map[string]int{}
+		for _, k := range keys {
+			n, _ := strconv.Atoi(k)
+			out[k] = n * 2
+		}
+		return out, nil
+	})
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := l.Load(ctx, strconv.Itoa(i))
+			if err != nil || v != i*2 {
+				t.Errorf("Load(%d) = %d, %v", i, v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("fetch called %d times, want 1", got)
+	}
+	if v, _ := l.Load(ctx, "7"); v != 14 || calls.Load() != 1 {
+		t.Fatalf("repeat load = %d after %d fetches, want 14 from the first fetch", v, calls.Load())
+	}
+}
+
+func TestQueryComplexityCountsLimits(t *testing.T) {
+	c := queryComplexity()
+	small, big := 10, 200
+	if got := c.Query.Listings(10, "94110", nil, &small, nil); got != 101 {
+		t.Fatalf("listings(limit: 10) = %d, want 101", got)
+	}
+	if got := c.Query.Listings(30, "94110", nil, &big, nil); got <= maxQueryComplexity {
+		t.Fatalf("listings(limit: 200) selecting 30 = %d, want it over the %d limit", got, maxQueryComplexity)
+	}
+}
@@ -0,0 +1,121 @@
+// Package model holds the GraphQL types that are bound by hand; the rest are
+// generated into models_gen.go.
+package model
+
+import (
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// Property is the GraphQL property. Card is set when it came from the
+// resolve cache and is used to build the valuation subject.
+type Property struct {
+	Key     string
+	Address string
+	City    string
+	State   string
+	Zip     string
+	Lat     *float64
+	Lon     *float64
+	Source  string
+	Card    *attom.PropertyCard `json:"-"`
+}
+
+// Listing is the GraphQL listing. ID is the provider listing ID, or the
+// stored row ID for a listing the provider gave none; ProviderID is set only
+// in the first case and keys the nested lookups, which are all by provider
+// listing ID. PhotoURLs carries photos already loaded by the page query so
+// the photos resolver does not refetch them.
+type Listing struct {
+	ID           string
+	PropertyKey  string
+	Status       *string
+	ListDate     *time.Time
+	Price        *int
+	Beds         *int
+	Baths        *float64
+	Sqft         *int
+	PropertyType *string
+	ProviderID   string   `json:"-"`
+	PhotoURLs    []string `json:"-"`
+}
+
+// PropertyFromRecord maps a stored property.
+func PropertyFromRecord(p *store.PropertyRecord) *Property {
+	out := &Property{
+		Key:     p.PropertyKey,
+		Address: p.AddressLine1,
+		City:    p.City,
+		State:   p.State,
+		Zip:     p.Zip,
+		Source:  "store",
+	}
+	if p.Lat.Valid && p.Lon.Valid {
+		lat, lon := p.Lat.Float64, p.Lon.Float64
+		out.Lat, out.Lon = &lat, &lon
+	}
+	return out
+}
+
+// PropertyFromCard maps a cached resolve payload.
+func PropertyFromCard(key string, card attom.PropertyCard) *Property {
+	out := &Property{
+		Key:     key,
+		Address: card.Address,
+		City:    card.City,
+		State:   card.State,
+		Zip:     card.Zip,
+		Source:  "cache",
+		Card:    &card,
+	}
+	if card.Coords != [2]float64{} {
+		lon, lat := card.Coords[0], card.Coords[1]
+		out.Lat, out.Lon = &lat, &lon
+	}
+	return out
+}
+
+// ListingFromRecord maps a stored listing. The GraphQL ID is the provider
+// listing ID, falling back to the internal UUID when the provider gave none.
+func ListingFromRecord(rec store.ListingRecord) *Listing {
+	out := &Listing{
+		ID:          rec.ListingID,
+		PropertyKey: rec.PropertyKey,
+		PhotoURLs:   rec.Photos,
+	}
+	if rec.ListingExternalID.Valid && rec.ListingExternalID.String != "" {
+		out.ID = rec.ListingExternalID.String
+		out.ProviderID = out.ID
+	}
+	if rec.Status != "" {
+		st := rec.Status
+		out.Status = &st
+	}
+	if rec.ListDate.Valid {
+		t := rec.ListDate.Time
+		out.ListDate = &t
+	}
+	if rec.ListPrice.Valid {
+		v := int(rec.ListPrice.Float64)
+		out.Price = &v
+	}
+	if rec.Beds.Valid {
+		v := int(rec.Beds.Int64)
+		out.Beds = &v
+	}
+	if rec.Baths.Valid {
+		v := rec.Baths.Float64
+		out.Baths = &v
+	}
+	if rec.Sqft.Valid {
+		v := int(rec.Sqft.Int64)
+		out.Sqft = &v
+	}
+	if rec.PropertyType.Valid {
+		v := rec.PropertyType.String
+		out.PropertyType = &v
+	}
+	return out
+}
@@ -0,0 +1,19 @@
+// Package gql serves the GraphQL gateway over properties, listings, photos
+// and market stats.
+package gql
+
+//go:generate go tool gqlgen generate
+
+import (
+	"github.com/yourorg/search-api/internal/redisx"
+	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/valuation"
+)
+
+// Resolver is the root resolver. Store is required for everything except
+// cache-served property lookups; Redis and Valuation are optional.
+type Resolver struct {
+	Store     *store.Store
+	Redis     *redisx.Client
+	Valuation *valuation.Service
+}
@@ -0,0 +1,263 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.85
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/search-api/http/gql/model"
+	httpv1 "github.com/yourorg/search-api/http/v1"
+	"github.com/yourorg/search-api/internal/cache"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/valuation"
+)
+
+// Photos is the resolver for the photos field.
+func (r *listingResolver) Photos(ctx context.Context, obj *model.Listing, limit *int) ([]model.Photo, error) {
+	urls := obj.PhotoURLs
+	if urls == nil && obj.ProviderID != "" {
+		if r.Store == nil {
+			return nil, errStoreUnavailable
+		}
+		var err error
+		if urls, err = r.loadersFor(ctx).photos.Load(ctx, obj.ProviderID); err != nil {
+			return nil, err
+		}
+	}
+	if limit != nil && *limit >= 0 && *limit < len(urls) {
+		urls = urls[:*limit]
+	}
+	out := make([]model.Photo, len(urls))
+	for i, u := range urls {
+		out[i] = model.Photo{URL: u, Position: i}
+	}
+	return out, nil
+}
+
+// Agents is the resolver for the agents field.
+func (r *listingResolver) Agents(ctx context.Context, obj *model.Listing) ([]model.Agent, error) {
+	if obj.ProviderID == "" {
+		return []model.Agent{}, nil
+	}
+	if r.Store == nil {
+		return nil, errStoreUnavailable
+	}
+	agents, err := r.loadersFor(ctx).agents.Load(ctx, obj.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.Agent, 0, len(agents))
+	for _, a := range agents {
+		out = append(out, model.Agent{
+			ID:         optString(a.SourceID),
+			Role:       optString(a.Role),
+			Name:       a.Name,
+			Email:      optString(a.Email),
+			Phone:      optString(a.Phone),
+			OfficeName: optString(a.OfficeName),
+			BrokerName: optString(a.BrokerName),
+		})
+	}
+	return out, nil
+}
+
+// OpenHouses is the resolver for the openHouses field.
+func (r *listingResolver) OpenHouses(ctx context.Context, obj *model.Listing, includePast *bool) ([]model.OpenHouse, error) {
+	if obj.ProviderID == "" {
+		return []model.OpenHouse{}, nil
+	}
+	if r.Store == nil {
+		return nil, errStoreUnavailable
+	}
+	past := 0
+	if includePast != nil && *includePast {
+		past = 1
+	}
+	events, err := r.loadersFor(ctx).openHouses[past].Load(ctx, obj.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.OpenHouse, 0, len(events))
+	for _, ev := range events {
+		out = append(out, model.OpenHouse{Start: ev.Start, End: ev.End, Description: optString(ev.Description)})
+	}
+	return out, nil
+}
+
+// Property is the resolver for the property field.
+func (r *listingResolver) Property(ctx context.Context, obj *model.Listing) (*model.Property, error) {
+	return r.loadersFor(ctx).properties.Load(ctx, obj.PropertyKey)
+}
+
+// Listings is the resolver for the listings field.
+func (r *propertyResolver) Listings(ctx context.Context, obj *model.Property, limit *int) ([]model.Listing, error) {
+	if r.Store == nil {
+		return nil, errStoreUnavailable
+	}
+	n := clampLimit(limit, 20, 100)
+	records, err := r.loadersFor(ctx).listingsByProperty(n).Load(ctx, obj.Key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.Listing, 0, len(records))
+	for _, rec := range records {
+		out = append(out, *model.ListingFromRecord(rec))
+	}
+	return out, nil
+}
+
+// EstimatedValue is the resolver for the estimatedValue field.
+func (r *propertyResolver) EstimatedValue(ctx context.Context, obj *model.Property) (*model.EstimatedValue, error) {
+	if r.Valuation == nil {
+		return nil, nil
+	}
+	var subject valuation.Subject
+	if obj.Card != nil {
+		subject = valuation.SubjectFromCard(obj.Key, *obj.Card)
+	} else {
+		if r.Store == nil {
+			return nil, nil
+		}
+		records, err := r.Store.FetchListingsByProperty(ctx, obj.Key, 1)
+		if err != nil || len(records) == 0 {
+			return nil, err
+		}
+		subject = valuation.SubjectFromRecord(records[0])
+	}
+	est, err := r.Valuation.Get(ctx, subject)
+	if err != nil || est == nil {
+		return nil, err
+	}
+	v := est.CardValue()
+	return &model.EstimatedValue{
+		Value:      v.Value,
+		Low:        optInt(v.Low),
+		High:       optInt(v.High),
+		Confidence: v.Confidence,
+		Provider:   v.Provider,
+		ValuedAt:   v.ValuedAt,
+	}, nil
+}
+
+// MarketStats is the resolver for the marketStats field.
+func (r *propertyResolver) MarketStats(ctx context.Context, obj *model.Property, window *string) ([]model.MarketStats, error) {
+	return r.Query().MarketStats(ctx, obj.Zip, window)
+}
+
+// Property is the resolver for the property field.
+func (r *queryResolver) Property(ctx context.Context, key string) (*model.Property, error) {
+	if r.Redis != nil {
+		if env, err := cache.Get(ctx, r.Redis, cache.PropertyKey(key)); err == nil && env != nil && env.Data.Address != "" {
+			return model.PropertyFromCard(key, env.Data), nil
+		}
+	}
+	if r.Store == nil {
+		return nil, errStoreUnavailable
+	}
+	p, err := r.Store.FetchProperty(ctx, key)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return model.PropertyFromRecord(p), nil
+}
+
+// Listing is the resolver for the listing field.
+func (r *queryResolver) Listing(ctx context.Context, id string) (*model.Listing, error) {
+	if r.Store == nil {
+		return nil, errStoreUnavailable
+	}
+	rec, err := r.Store.FetchListingDetail(ctx, id)
+	if err != nil || rec == nil {
+		return nil, err
+	}
+	return model.ListingFromRecord(*rec), nil
+}
+
+// Listings is the resolver for the listings field.
+func (r *queryResolver) Listings(ctx context.Context, location string, propertyType *string, limit *int, offset *int) ([]model.Listing, error) {
+	if r.Store == nil {
+		return nil, errStoreUnavailable
+	}
+	n := clampLimit(limit, 20, 200)
+	off := 0
+	if offset != nil && *offset > 0 {
+		off = *offset
+	}
+	ptype := ""
+	if propertyType != nil {
+		ptype = *propertyType
+	}
+	var (
+		records []store.ListingRecord
+		err     error
+	)
+	if canon.IsZIP(location) {
+		records, err = r.Store.FetchListingsByPostal(ctx, location, n, off, store.ListingFilter{PropertyType: ptype})
+	} else if city, state, ok := canon.ParseLocation(location); ok {
+		records, err = r.Store.FetchListingsByCity(ctx, city, state, n, off, store.ListingFilter{PropertyType: ptype})
+	} else {
+		return nil, fmt.Errorf("location must be a ZIP or \"City, ST\"")
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.Listing, 0, len(records))
+	for _, rec := range records {
+		out = append(out, *model.ListingFromRecord(rec))
+	}
+	return out, nil
+}
+
+// MarketStats is the resolver for the marketStats field.
+func (r *queryResolver) MarketStats(ctx context.Context, zip string, window *string) ([]model.MarketStats, error) {
+	if r.Store == nil {
+		return nil, errStoreUnavailable
+	}
+	w := "90d"
+	if window != nil && *window != "" {
+		w = *window
+	}
+	d, err := httpv1.ParseWindow(w)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := r.Store.FetchMarketTrends(ctx, zip, time.Now().Add(-d))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.MarketStats, 0, len(stats))
+	for _, s := range stats {
+		m := model.MarketStats{
+			Zip:         s.Zip,
+			Day:         s.Day.Format("2006-01-02"),
+			ActiveCount: s.ActiveCount,
+			NewCount:    s.NewCount,
+			SoldCount:   s.SoldCount,
+		}
+		if s.MedianPrice.Valid {
+			v := s.MedianPrice.Float64
+			m.MedianPrice = &v
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// Listing returns ListingResolver implementation.
+func (r *Resolver) Listing() ListingResolver { return &listingResolver{r} }
+
+// Property returns PropertyResolver implementation.
+func (r *Resolver) Property() PropertyResolver { return &propertyResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type listingResolver struct{ *Resolver }
+type propertyResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
@@ -1,34 +1,36 @@
 package httpapi
 
 import (
-    "encoding/json"
-    "net/http"
+	"encoding/json"
+	"net/http"
 
-    "github.com/go-chi/chi/v5"
-    "github.com/go-chi/render"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
 )
 
 type HydrateDeps struct {
-    // e.g., Kafka producer, etc.
+	// e.g., Kafka producer, etc.
+}
+
+type HydrateRequest struct {
+	Address string `json:"address"`
+	Scope   string `json:"scope,omitempty"`
 }
 
 func RegisterHydrate(r chi.Router, _ HydrateDeps) {
-    r.Post("/hydrate", func(w http.ResponseWriter, req *http.Request) {
-        var body struct {
-            Address string `json:"address"`
-            Scope   string `json:"scope"`
-        }
-        if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
-            render.Status(req, http.StatusBadRequest)
-            _ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_json", "detail": err.Error()})
-            return
-        }
-        if body.Address == "" {
-            render.Status(req, http.StatusBadRequest)
-            _ = json.NewEncoder(w).Encode(map[string]any{"error": "address_required"})
-            return
-        }
-        // TODO: enqueue into Kafka "hydrate-jobs" (out of scope for listing)
-        render.JSON(w, req, map[string]any{"ok": true})
-    })
+	r.With(auth.Require(auth.ScopeHydrate)).Post("/hydrate", func(w http.ResponseWriter, req *http.Request) {
+		var body HydrateRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			apierror.WriteError(w, req, err, apierror.InvalidJSON)
+			return
+		}
+		if body.Address == "" {
+			apierror.Write(w, req, apierror.BadRequest("address_required", "address is required"))
+			return
+		}
+		// TODO: enqueue into Kafka "hydrate-jobs" (out of scope for listing)
+		render.JSON(w, req, map[string]any{"ok": true})
+	})
 }
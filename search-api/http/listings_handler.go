@@ -4,45 +4,192 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/render"
 	"github.com/yourorg/search-api/attom"
-	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/cache"
+	"github.com/yourorg/search-api/internal/flags"
 	"github.com/yourorg/search-api/internal/hydrator"
+	"github.com/yourorg/search-api/internal/logger"
+	"github.com/yourorg/search-api/internal/photoclass"
+	"github.com/yourorg/search-api/internal/redisx"
+	"github.com/yourorg/search-api/internal/refresh"
+	"github.com/yourorg/search-api/internal/respond"
+	"github.com/yourorg/search-api/internal/shadow"
 	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/valuation"
 )
 
 type ListingsDeps struct {
 	Hydrator       *hydrator.Hydrator
 	Store          *store.Store
 	ListingsClient *attom.Client
+	Valuation      *valuation.Service
+	// Shadow sends a sample of the provider pages fetched for callers to a
+	// candidate provider too; nil disables it.
+	Shadow *shadow.Provider
+	// Redis caches provider pages stale-while-revalidate; nil disables it.
+	Redis *redisx.Client
+	// Refetch queues a background refresh of a stale page.
+	Refetch func(p refresh.ListingsPage)
+	// Warmer counts ZIP page requests so popular pages are refreshed before
+	// they go stale; nil disables it.
+	Warmer *refresh.Warmer
+	// PageTTL and PageStaleAfter default to 1h and 5m.
+	PageTTL        time.Duration
+	PageStaleAfter time.Duration
+	// RefreshZip queues a background crawl of a ZIP through bulk hydration;
+	// in dual-read mode it is called for the ZIPs of database pages whose
+	// listings were fetched more than ZipStaleAfter (default 1h) ago, at
+	// most once per ZipCrawlCooldown (default 6h) per ZIP. It reports
+	// whether the crawl was queued. Nil disables the refresh.
+	RefreshZip       func(zip string) bool
+	ZipStaleAfter    time.Duration
+	ZipCrawlCooldown time.Duration
+	// PhotoBudget caps the provider photo calls made for one provider page,
+	// default 10; cards past it keep the search payload's images.
+	// StoredPhotosOnly makes no provider photo calls for pages at all.
+	PhotoBudget      int
+	StoredPhotosOnly bool
 }
 
+// listingStore is Store, or else the hydrator's store, or nil.
+func (d ListingsDeps) listingStore() store.Listings {
+	if d.Store != nil {
+		return d.Store
+	}
+	if d.Hydrator != nil {
+		return d.Hydrator.Store
+	}
+	return nil
+}
+
+// EnrichmentDTO is one enricher's result for the listing's property, keyed
+// by enricher name: flood_zone, schools or walkability.
+type EnrichmentDTO struct {
+	Data      json.RawMessage `json:"data"`
+	FetchedAt time.Time       `json:"fetchedAt"`
+}
+
+// ListingPhotoDTO is a listing photo with the metadata the provider sent
+// for it, in display order by position.
+type ListingPhotoDTO struct {
+	Href        string   `json:"href"`
+	Description string   `json:"description,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Kind        string   `json:"kind,omitempty" doc:"Provider photo type, such as photo or floorplan"`
+	MediaType   string   `json:"mediaType,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Position    int      `json:"position"`
+	Room        string   `json:"room,omitempty" doc:"Room type from the provider tags or the photo classifier"`
+	Primary     bool     `json:"isPrimary,omitempty" doc:"Chosen as the listing's cover photo"`
+}
+
+// PhotosToDTOs converts stored listing photos for responses.
+func PhotosToDTOs(photos []store.ListingPhoto) []ListingPhotoDTO {
+	out := make([]ListingPhotoDTO, 0, len(photos))
+	for _, p := range photos {
+		out = append(out, ListingPhotoDTO{
+			Href: p.Href, Description: p.Description, Title: p.Title, Kind: p.Kind,
+			MediaType: p.MediaType, Tags: p.Tags, Position: p.Position, Room: p.Room, Primary: p.Primary,
+		})
+	}
+	return out
+}
+
+// ListingsRequest is a listings search. The store query applies MinDOM,
+// MaxDOM and the feature filters after them; on provider pages they filter
+// the fetched page, which can then come back short, and the response
+// counts the listings they dropped.
 type ListingsRequest struct {
-	PostalCode   string `json:"postalcode,omitempty"`
-	PropertyType string `json:"property_type,omitempty"`
-	OrderBy      string `json:"orderby,omitempty"`
-	Limit        *int   `json:"limit,omitempty"` // pagesize
-	Page         *int   `json:"page,omitempty"`
-	Beds         *int   `json:"beds,omitempty"`
-	Baths        *int   `json:"baths,omitempty"`
-	MinPrice     *int   `json:"minprice,omitempty"`
-	MaxPrice     *int   `json:"maxprice,omitempty"`
+	PostalCode      string `json:"postalcode,omitempty"`
+	Location        string `json:"location,omitempty"` // "City, ST"
+	City            string `json:"city,omitempty"`
+	State           string `json:"state,omitempty"`
+	PropertyType    string `json:"property_type,omitempty" doc:"single_family, condo, townhome, multi_family, mobile, land or farm; common spellings such as condos are accepted"`
+	OrderBy         string `json:"orderby,omitempty" doc:"price_asc, price_desc, newest, sqft or beds, or a provider order such as open_house_date; beds only applies to pages served from the database"`
+	Limit           *int   `json:"limit,omitempty"` // pagesize
+	Page            *int   `json:"page,omitempty"`
+	Beds            *int   `json:"beds,omitempty"`
+	Baths           *int   `json:"baths,omitempty"`
+	MinPrice        *int   `json:"minprice,omitempty"`
+	MaxPrice        *int   `json:"maxprice,omitempty"`
+	MinDOM          *int   `json:"min_dom,omitempty" doc:"Only listings on the market at least this many days"`
+	MaxDOM          *int   `json:"max_dom,omitempty" doc:"Only listings on the market at most this many days"`
+	MaxHOA          *int   `json:"max_hoa,omitempty" doc:"Maximum monthly HOA fee in dollars; listings with no reported fee match"`
+	MinGarage       *int   `json:"min_garage,omitempty" doc:"Minimum garage spaces"`
+	MinStories      *int   `json:"min_stories,omitempty" doc:"Minimum stories"`
+	Pool            *bool  `json:"pool,omitempty" doc:"true for listings with a pool, false for listings without one"`
+	Basement        *bool  `json:"basement,omitempty" doc:"true for listings with a basement, false for listings without one"`
+	NewConstruction *bool  `json:"new_construction,omitempty" doc:"true for new construction only, false to exclude it"`
+	Foreclosure     *bool  `json:"foreclosure,omitempty" doc:"true for foreclosures only, false to exclude them"`
+	PriceReduced    *bool  `json:"price_reduced,omitempty" doc:"true for listings with a price cut only, false to exclude them"`
+	// Keywords searches stored listing descriptions ("pool", "ADU",
+	// "\"new roof\""); results come from the store only.
+	Keywords string `json:"keywords,omitempty" doc:"Keyword search over listing descriptions; matches carry a highlight excerpt"`
 }
 
 // use defInt from search_handler.go (same package)
 
+func (b ListingsRequest) featureFilter() store.FeatureFilter {
+	return store.FeatureFilter{
+		MaxHOA:          b.MaxHOA,
+		MinGarage:       b.MinGarage,
+		MinStories:      b.MinStories,
+		Pool:            b.Pool,
+		Basement:        b.Basement,
+		NewConstruction: b.NewConstruction,
+		Foreclosure:     b.Foreclosure,
+		PriceReduced:    b.PriceReduced,
+	}
+}
+
+// filterNames lists the filters the request sets besides location,
+// property type and order, for search analytics.
+func (b ListingsRequest) filterNames() []string {
+	var names []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"beds", b.Beds != nil}, {"baths", b.Baths != nil},
+		{"minprice", b.MinPrice != nil}, {"maxprice", b.MaxPrice != nil},
+		{"min_dom", b.MinDOM != nil}, {"max_dom", b.MaxDOM != nil},
+		{"max_hoa", b.MaxHOA != nil}, {"min_garage", b.MinGarage != nil}, {"min_stories", b.MinStories != nil},
+		{"pool", b.Pool != nil}, {"basement", b.Basement != nil}, {"new_construction", b.NewConstruction != nil},
+		{"foreclosure", b.Foreclosure != nil}, {"price_reduced", b.PriceReduced != nil},
+		{"keywords", strings.TrimSpace(b.Keywords) != ""},
+	} {
+		if f.set {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
 func RegisterListings(r chi.Router, d ListingsDeps) {
+	// Card responses honour ?fields= and ?includePhotos=; see shape.go.
+	r = r.With(cardShaping)
 	// POST JSON
 	r.Post("/search/listings", func(w http.ResponseWriter, req *http.Request) {
 		var body ListingsRequest
 		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
-			render.Status(req, http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_json", "detail": err.Error()})
+			apierror.WriteError(w, req, err, apierror.InvalidJSON)
+			return
+		}
+		var val validator
+		body.validate(&val)
+		if apiErr := val.err(); apiErr != nil {
+			apierror.Write(w, req, apiErr)
 			return
 		}
 		handleListingsRequest(w, req, d, body)
@@ -52,65 +199,197 @@ func RegisterListings(r chi.Router, d ListingsDeps) {
 	r.Get("/search/listings", func(w http.ResponseWriter, req *http.Request) {
 		q := req.URL.Query()
 		var body ListingsRequest
+		var val validator
 		body.PostalCode = q.Get("postalcode")
+		body.Location = q.Get("location")
+		body.City = q.Get("city")
+		body.State = q.Get("state")
 		body.PropertyType = q.Get("property_type")
 		body.OrderBy = q.Get("orderby")
-		if v := q.Get("limit"); v != "" {
-			if i, err := strconv.Atoi(v); err == nil {
-				body.Limit = &i
-			}
+		body.Keywords = q.Get("keywords")
+		val.intParam(q, "limit", &body.Limit)
+		val.intParam(q, "page", &body.Page)
+		val.intParam(q, "beds", &body.Beds)
+		val.intParam(q, "baths", &body.Baths)
+		val.intParam(q, "minprice", &body.MinPrice)
+		val.intParam(q, "maxprice", &body.MaxPrice)
+		val.intParam(q, "min_dom", &body.MinDOM)
+		val.intParam(q, "max_dom", &body.MaxDOM)
+		val.intParam(q, "max_hoa", &body.MaxHOA)
+		val.intParam(q, "min_garage", &body.MinGarage)
+		val.intParam(q, "min_stories", &body.MinStories)
+		val.boolParam(q, "pool", &body.Pool)
+		val.boolParam(q, "basement", &body.Basement)
+		val.boolParam(q, "new_construction", &body.NewConstruction)
+		val.boolParam(q, "foreclosure", &body.Foreclosure)
+		val.boolParam(q, "price_reduced", &body.PriceReduced)
+		body.validate(&val)
+		if apiErr := val.err(); apiErr != nil {
+			apierror.Write(w, req, apiErr)
+			return
 		}
-		if v := q.Get("page"); v != "" {
-			if i, err := strconv.Atoi(v); err == nil {
-				body.Page = &i
-			}
+		handleListingsRequest(w, req, d, body)
+	})
+
+	r.Get("/search/listings/{listingID}", func(w http.ResponseWriter, req *http.Request) {
+		listingID := chi.URLParam(req, "listingID")
+		st := d.listingStore()
+		if st == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
 		}
-		if v := q.Get("beds"); v != "" {
-			if i, err := strconv.Atoi(v); err == nil {
-				body.Beds = &i
+		includeNotes, _ := strconv.ParseBool(req.URL.Query().Get("include_notes"))
+		var owner string
+		if includeNotes {
+			p, ok := auth.FromContext(req.Context())
+			switch {
+			case !ok:
+				auth.Unauthorized(w, req, nil)
+				return
+			case !p.HasScope(auth.ScopeNotes):
+				apierror.Write(w, req, apierror.New(http.StatusForbidden, "forbidden", "notes scope required").With("scope", auth.ScopeNotes))
+				return
 			}
+			owner = p.ID()
 		}
-		if v := q.Get("baths"); v != "" {
-			if i, err := strconv.Atoi(v); err == nil {
-				body.Baths = &i
-			}
+		rec, err := st.FetchListingDetail(req.Context(), listingID)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load listing"))
+			return
+		}
+		if rec == nil {
+			apierror.Write(w, req, apierror.NotFound("not_found", "listing not found").With("listing_id", listingID))
+			return
+		}
+		card := RecordsToCards([]store.ListingRecord{*rec})[0]
+		if est, err := d.Valuation.Get(req.Context(), valuation.SubjectFromCard(rec.PropertyKey, card)); err != nil {
+			log.Printf("[WARN] valuation failed for listing %s: %v", listingID, err)
+		} else {
+			card.EstimatedValue = est.CardValue()
 		}
-		if v := q.Get("minprice"); v != "" {
-			if i, err := strconv.Atoi(v); err == nil {
-				body.MinPrice = &i
+		enrichments := map[string]EnrichmentDTO{}
+		// Enrichments are only kept in Postgres.
+		if pg := store.Postgres(st); pg != nil {
+			if stored, err := pg.PropertyEnrichments(req.Context(), rec.PropertyKey); err != nil {
+				log.Printf("[WARN] enrichments unavailable for listing %s: %v", listingID, err)
+			} else {
+				for _, e := range stored {
+					enrichments[e.Enricher] = EnrichmentDTO{Data: e.Data, FetchedAt: e.FetchedAt}
+				}
 			}
 		}
-		if v := q.Get("maxprice"); v != "" {
-			if i, err := strconv.Atoi(v); err == nil {
-				body.MaxPrice = &i
+		photos := rec.PhotoAssets
+		if store.InlineOnly(photos) {
+			// With stored photos to fall back on this does not fail.
+			photos, _ = completeListingPhotos(req.Context(), listingID, photos, d)
+		}
+		setCardsFreshness(w, []attom.PropertyCard{card})
+		out := map[string]any{"ok": true, "listing": shapeCard(req, card), "photos": PhotosToDTOs(photos), "enrichments": enrichments}
+		if includeNotes {
+			// Notes are only kept in Postgres.
+			pg := store.Postgres(st)
+			if pg == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			var notes []store.Note
+			for _, target := range [][2]string{{store.NoteProperty, rec.PropertyKey}, {store.NoteListing, listingID}} {
+				got, err := pg.Notes(req.Context(), owner, target[0], target[1])
+				if err != nil {
+					apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load notes"))
+					return
+				}
+				notes = append(notes, got...)
 			}
+			out["notes"] = NotesToDTOs(notes)
 		}
-		handleListingsRequest(w, req, d, body)
+		respond.Rows(w, req, out, "listing")
 	})
 
 	r.Get("/search/listings/{listingID}/photos", func(w http.ResponseWriter, req *http.Request) {
 		listingID := chi.URLParam(req, "listingID")
 		if listingID == "" {
-			render.Status(req, http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "listing_id_required"})
+			apierror.Write(w, req, apierror.BadRequest("listing_id_required", "listing id is required"))
+			return
+		}
+		q := req.URL.Query()
+		var val validator
+		var limit, page *int
+		val.intParam(q, "limit", &limit)
+		val.intParam(q, "page", &page)
+		val.paging(limit, page, "")
+		if apiErr := val.err(); apiErr != nil {
+			apierror.Write(w, req, apiErr)
 			return
 		}
+		pageSize, pageNum := maxPageSize, 1
+		if limit != nil {
+			pageSize = *limit
+		}
+		if page != nil {
+			pageNum = *page
+		}
+		var rooms []string
+		if v := q.Get("room"); v != "" {
+			for _, room := range strings.Split(v, ",") {
+				room = strings.TrimSpace(room)
+				if !photoclass.Valid(room) {
+					apierror.Write(w, req, apierror.BadRequest("invalid_room", "unknown room "+room).With("rooms", photoclass.Rooms))
+					return
+				}
+				rooms = append(rooms, room)
+			}
+		}
+		var tags []string
+		if v := q.Get("tag"); v != "" {
+			for _, tag := range strings.Split(v, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+		}
 		photos, err := fetchListingPhotos(req.Context(), listingID, d)
 		if err != nil {
-			render.Status(req, http.StatusInternalServerError)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "photos_error", "detail": err.Error()})
+			apierror.WriteError(w, req, err, apierror.Internal("photos_error", "unable to load photos"))
 			return
 		}
-		render.JSON(w, req, map[string]any{"ok": true, "count": len(photos), "photos": photos})
+		roomByHref := map[string]string{}
+		filtered := photos[:0]
+		for _, p := range photos {
+			if p.Room != "" {
+				roomByHref[p.Href] = p.Room
+			}
+			if (len(rooms) == 0 || slices.Contains(rooms, p.Room)) && (len(tags) == 0 || slices.ContainsFunc(tags, p.HasTag)) {
+				filtered = append(filtered, p)
+			}
+		}
+		photos = filtered
+		// The primary photo is the curated one, or else the first in
+		// display order, whatever page, room or tag was asked for.
+		var primary *ListingPhotoDTO
+		if len(photos) > 0 {
+			i := max(slices.IndexFunc(photos, func(p store.ListingPhoto) bool { return p.Primary }), 0)
+			primary = &PhotosToDTOs(photos[i : i+1])[0]
+		}
+		total := len(photos)
+		start := min((pageNum-1)*pageSize, total)
+		end := min(start+pageSize, total)
+		out := PhotosToDTOs(photos[start:end])
+		respond.Write(w, req, map[string]any{
+			"ok": true, "count": len(out), "total": total, "page": pageNum, "limit": pageSize,
+			"has_more": end < total, "primary": primary, "photos": out, "rooms": roomByHref,
+		})
 	})
 }
 
 func handleListingsRequest(w http.ResponseWriter, req *http.Request, d ListingsDeps, body ListingsRequest) {
-	if body.PostalCode == "" {
-		render.Status(req, http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "postalcode_required"})
+	loc, ok := resolveLocation(body.PostalCode, body.Location, body.City, body.State)
+	if !ok {
+		apierror.Write(w, req, apierror.BadRequest("postalcode_required", "postalcode or location (city, state) is required"))
 		return
 	}
+	d.Hydrator.NoteDemand(loc.Postal)
+	noteSearch(req.Context(), "listings", loc, body.PropertyType, body.OrderBy, body.filterNames())
 	// Default to 5 listings as requested
 	pagesize := defInt(body.Limit, 5)
 	page := defInt(body.Page, 1)
@@ -120,94 +399,364 @@ func handleListingsRequest(w http.ResponseWriter, req *http.Request, d ListingsD
 	maxp := defInt(body.MaxPrice, 0)
 
 	offset := (page - 1) * pagesize
-	store := d.Store
-	if store == nil && d.Hydrator != nil {
-		store = d.Hydrator.Store
+	st := d.listingStore()
+	filter := storeFilter(body.PropertyType, body.OrderBy)
+	filter.MinDOM, filter.MaxDOM = body.MinDOM, body.MaxDOM
+	filter.Features = body.featureFilter()
+	if keywords := strings.TrimSpace(body.Keywords); keywords != "" {
+		searchDescriptions(w, req, store.Postgres(st), loc, keywords, filter, pagesize, offset)
+		return
 	}
-	if store != nil {
-		records, err := store.FetchListingsByPostal(req.Context(), body.PostalCode, pagesize, offset, body.PropertyType)
+	// Dual-read serves the store's page whenever there is one, however
+	// stale, and refreshes it behind the response.
+	dualRead := flags.Enabled(req.Context(), flags.DualRead)
+	if st != nil && (dualRead || flags.Enabled(req.Context(), flags.ServeFromIndex)) {
+		records, err := loc.fetchRecords(req.Context(), st, pagesize, offset, filter)
 		if err != nil {
-			log.Printf("[WARN] db lookup failed for postal %s: %v", body.PostalCode, err)
+			log.Printf("[WARN] db lookup failed for %s: %v", loc, err)
 		} else if len(records) > 0 {
-			cards := recordsToCards(records)
-			log.Printf("[INFO] serving listings for %s from database (%d listings)", body.PostalCode, len(cards))
-			render.JSON(w, req, map[string]any{"ok": true, "count": len(cards), "properties": cards})
+			cards := RecordsToCards(records)
+			if dualRead {
+				d.refreshStaleZips(req.Context(), cards)
+			}
+			log.Printf("[INFO] serving listings for %s from database (%d listings)", loc, len(cards))
+			logger.SetCache(req.Context(), logger.CacheMiss)
+			logger.SetResults(req.Context(), len(cards))
+			setCardsFreshness(w, cards)
+			respond.Rows(w, req, map[string]any{"ok": true, "count": len(cards), "properties": shapeCards(req, cards)}, "properties")
 			return
 		} else {
-			log.Printf("[INFO] no database listings for %s; falling back to RapidAPI", body.PostalCode)
+			log.Printf("[INFO] no database listings for %s; falling back to RapidAPI", loc)
 		}
 	}
-	raw, err := d.ListingsClient.SearchListingsByPostal(req.Context(), body.PostalCode, pagesize, page, beds, baths, minp, maxp, body.PropertyType, body.OrderBy)
-	if err != nil {
-		if errors.Is(err, attom.ErrDailyLimitExceeded) {
-			render.Status(req, http.StatusTooManyRequests)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "provider_quota", "detail": "daily quota reached"})
+	lp := refresh.ListingsPage{
+		Location: loc.provider(), PageSize: pagesize, Page: page,
+		Beds: beds, Baths: baths, MinPrice: minp, MaxPrice: maxp,
+		PropertyType: body.PropertyType, OrderBy: body.OrderBy,
+		PrimaryPhoto: primaryPhotoOnly(req),
+	}
+	lp.CacheKey = listingsPageKey(lp)
+	d.Warmer.Record(lp)
+
+	// Provider pages are served stale-while-revalidate: a cached page is
+	// returned at once and, when stale, refreshed in the background.
+	if d.Redis != nil {
+		if cached, err := cache.GetPage(req.Context(), d.Redis, lp.CacheKey); err == nil && cached != nil {
+			status := "HIT"
+			if cached.Meta.Stale(time.Now()) {
+				status = "STALE"
+				if d.Refetch != nil {
+					d.Refetch(lp)
+				}
+			}
+			log.Printf("[INFO] serving listings for %s from cache (%d listings, %s)", loc, len(cached.Data), status)
+			writeListingsPage(w, req, cached.Data, filter, cached.Meta, status)
 			return
 		}
-		render.Status(req, http.StatusBadGateway)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "upstream_error", "detail": err.Error()})
-		return
 	}
-	cards, err := attom.MapListingPayloadToCards(raw)
+
+	compare := d.Shadow.Begin(shadow.Search{
+		Route:    "listings",
+		Location: lp.Location,
+		Params: map[string]any{
+			"page": lp.Page, "pageSize": lp.PageSize, "beds": lp.Beds, "baths": lp.Baths,
+			"minPrice": lp.MinPrice, "maxPrice": lp.MaxPrice, "propertyType": lp.PropertyType, "orderBy": lp.OrderBy,
+		},
+		Fetch: func(ctx context.Context, c *attom.Client) ([]attom.PropertyCard, error) {
+			raw, err := c.SearchListingsByPostal(ctx, lp.Location, lp.PageSize, lp.Page, lp.Beds, lp.Baths, lp.MinPrice, lp.MaxPrice, lp.PropertyType, lp.OrderBy)
+			if err != nil {
+				return nil, err
+			}
+			return attom.MapListingPayloadToCards(raw)
+		},
+	})
+	cards, err := fetchProviderListings(req.Context(), d, lp)
+	compare(cards, err)
 	if err != nil {
-		render.Status(req, http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "map_error", "detail": err.Error()})
+		if errors.Is(err, errListingsMap) {
+			apierror.Write(w, req, apierror.MapError.WithDetail(err.Error()))
+			return
+		}
+		if serveDegraded(w, req, st, loc, pagesize, offset, filter, err) {
+			return
+		}
+		apierror.WriteError(w, req, err, apierror.Upstream)
 		return
 	}
-	persistCards(req.Context(), d.Hydrator, "search/forsale", raw, cards)
-	for i := range cards {
-		listingID := cards[i].ListingID
-		if listingID == "" {
-			listingID = cards[i].ID
+	meta := cache.Meta{LastFetch: time.Now(), Source: "rapidapi"}
+	if d.Redis != nil {
+		pg := d.newPage(cards)
+		if err := cache.PutPage(req.Context(), d.Redis, lp.CacheKey, pg); err != nil {
+			log.Printf("[WARN] listings page cache write failed for %s: %v", loc, err)
 		}
-		propertyID := cards[i].PropertyID
-		if propertyID == "" {
-			if _, _, _, _, pk := canon.Canonicalize(cards[i].Address, cards[i].City, cards[i].State, cards[i].Zip); pk != "" {
-				propertyID = pk
-				cards[i].PropertyID = pk
+		meta = pg.Meta
+	}
+	log.Printf("[INFO] served listings for %s from RapidAPI (%d listings)", loc, len(cards))
+	writeListingsPage(w, req, cards, filter, meta, "MISS")
+}
+
+// filterCards applies the filters the provider cannot: days on market and
+// listing features. Pages are cached unfiltered and trimmed per request,
+// matching the store queries: cards with no list date are dropped when a DOM
+// bound is set, and see store.FeatureFilter for missing features.
+func filterCards(cards []attom.PropertyCard, f store.ListingFilter) []attom.PropertyCard {
+	if f.MinDOM == nil && f.MaxDOM == nil && f.Features == (store.FeatureFilter{}) {
+		return cards
+	}
+	out := make([]attom.PropertyCard, 0, len(cards))
+	for _, c := range cards {
+		if f.MinDOM != nil || f.MaxDOM != nil {
+			if c.ListDate == nil {
+				continue
+			}
+			dom := attom.DaysOnMarket(*c.ListDate, time.Now())
+			if (f.MinDOM != nil && dom < *f.MinDOM) || (f.MaxDOM != nil && dom > *f.MaxDOM) {
+				continue
 			}
 		}
-		if listingID == "" && propertyID == "" {
+		if !matchesFeatures(c, f.Features) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func matchesFeatures(c attom.PropertyCard, f store.FeatureFilter) bool {
+	if f.MaxHOA != nil && c.HOAFee != nil && *c.HOAFee > *f.MaxHOA {
+		return false
+	}
+	if f.MinGarage != nil && (c.GarageSpaces == nil || *c.GarageSpaces < *f.MinGarage) {
+		return false
+	}
+	if f.MinStories != nil && (c.Stories == nil || *c.Stories < *f.MinStories) {
+		return false
+	}
+	return flagMatches(c.Pool, f.Pool) && flagMatches(c.Basement, f.Basement) &&
+		flagMatches(c.NewConstruction, f.NewConstruction) && flagMatches(c.Foreclosure, f.Foreclosure) &&
+		flagMatches(c.PriceReduced, f.PriceReduced)
+}
+
+// flagMatches compares a card flag against a filter, a missing flag counting
+// as false.
+func flagMatches(v, want *bool) bool {
+	return want == nil || (v != nil && *v) == *want
+}
+
+var errListingsMap = errors.New("listings payload mapping failed")
+
+// fetchProviderListings fetches one provider page, persists it and attaches
+// photos.
+func fetchProviderListings(ctx context.Context, d ListingsDeps, lp refresh.ListingsPage) ([]attom.PropertyCard, error) {
+	raw, err := d.ListingsClient.SearchListingsByPostal(ctx, lp.Location, lp.PageSize, lp.Page, lp.Beds, lp.Baths, lp.MinPrice, lp.MaxPrice, lp.PropertyType, lp.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := attom.MapListingPayloadToCards(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errListingsMap, err)
+	}
+	persistCards(ctx, d.Hydrator, "search/forsale", raw, cards)
+	d.attachPhotos(ctx, cards, lp.PrimaryPhoto)
+	return cards, nil
+}
+
+// RefreshListingsPage refetches a cached provider page. It is the refresh
+// queue's handler for refresh.KindListings jobs.
+func (d ListingsDeps) RefreshListingsPage(ctx context.Context, lp refresh.ListingsPage) error {
+	if d.Redis == nil || lp.CacheKey == "" {
+		return refresh.Permanent(errors.New("listings refresh without cache"))
+	}
+	if cached, err := cache.GetPage(ctx, d.Redis, lp.CacheKey); err == nil && cached != nil && !cached.Meta.Stale(time.Now().Add(lp.Lead)) {
+		return nil
+	}
+	cards, err := fetchProviderListings(ctx, d, lp)
+	if err != nil {
+		if errors.Is(err, attom.ErrDailyLimitExceeded) || errors.Is(err, errListingsMap) {
+			return refresh.Permanent(err)
+		}
+		return err
+	}
+	return cache.PutPage(ctx, d.Redis, lp.CacheKey, d.newPage(cards))
+}
+
+// refreshStaleZips queues a crawl of every ZIP on a database page with a
+// listing fetched more than ZipStaleAfter ago, so later pages are fresh
+// without this one waiting on the provider. A ZIP crawled within
+// ZipCrawlCooldown is skipped: listings the provider no longer returns stay
+// stale, and would otherwise have their ZIP crawled after every run. The
+// refresh queue also drops ZIPs already queued or being crawled. A crawl the
+// queue drops lifts the cooldown, so the next page retries it.
+func (d ListingsDeps) refreshStaleZips(ctx context.Context, cards []attom.PropertyCard) {
+	if d.RefreshZip == nil || d.Redis == nil {
+		return
+	}
+	cooldown := d.ZipCrawlCooldown
+	if cooldown <= 0 {
+		cooldown = 6 * time.Hour
+	}
+	window := d.ZipStaleAfter
+	if window <= 0 {
+		window = time.Hour
+	}
+	cutoff := time.Now().Add(-window)
+	var zips []string
+	for _, c := range cards {
+		if c.Zip == "" || c.LastFetchAt == nil || !c.LastFetchAt.Before(cutoff) || slices.Contains(zips, c.Zip) {
 			continue
 		}
-		cards[i].ListingID = listingID
-		photos, err := loadListingPhotos(req.Context(), listingID, propertyID, store, d.ListingsClient)
+		zips = append(zips, c.Zip)
+	}
+	for _, zip := range zips {
+		ok, err := d.Redis.SetNX(ctx, "listings:zipcrawl:"+zip, time.Now().UTC().Format(time.RFC3339), cooldown)
 		if err != nil {
-			log.Printf("[WARN] unable to load photos for listing %s: %v", listingID, err)
+			log.Printf("[WARN] zip crawl cooldown check failed for %s: %v", zip, err)
 			continue
 		}
-		cards[i].Images = photos
+		if ok && !d.RefreshZip(zip) {
+			if err := d.Redis.Del(ctx, "listings:zipcrawl:"+zip); err != nil {
+				log.Printf("[WARN] zip crawl cooldown reset failed for %s: %v", zip, err)
+			}
+		}
+	}
+}
+
+func (d ListingsDeps) newPage(cards []attom.PropertyCard) cache.Page {
+	ttl, staleAfter := d.PageTTL, d.PageStaleAfter
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	if staleAfter <= 0 {
+		staleAfter = 5 * time.Minute
+	}
+	return cache.NewPage(cards, "rapidapi", staleAfter, ttl)
+}
+
+// listingsPageKey identifies a provider page by every parameter sent
+// upstream. Primary-photo pages are cached apart since they lack the rest.
+func listingsPageKey(lp refresh.ListingsPage) string {
+	if lp.PrimaryPhoto {
+		lp.PrimaryPhoto = false
+		return listingsPageKey(lp) + ":primary"
+	}
+	return fmt.Sprintf("listings:page:%s:%d:%d:%d:%d:%d:%d:%s:%s",
+		strings.ToLower(lp.Location), lp.PageSize, lp.Page, lp.Beds, lp.Baths,
+		lp.MinPrice, lp.MaxPrice, strings.ToLower(lp.PropertyType), strings.ToLower(lp.OrderBy))
+}
+
+// writeListingsPage renders a provider page with its freshness in both the
+// body and headers: X-Cache is HIT, STALE or MISS, Age counts seconds
+// since the provider fetch, and the setFreshness headers are set too. The
+// provider has no DOM or feature filters, so they apply to the fetched page
+// and can leave it short of the page size; the response counts the listings
+// they dropped.
+func writeListingsPage(w http.ResponseWriter, req *http.Request, page []attom.PropertyCard, filter store.ListingFilter, meta cache.Meta, status string) {
+	cards := filterCards(page, filter)
+	source := meta.Source
+	if status != "MISS" {
+		source = "cache"
+	}
+	age := int(time.Since(meta.LastFetch).Seconds())
+	w.Header().Set("X-Cache", status)
+	switch status {
+	case "HIT":
+		logger.SetCache(req.Context(), logger.CacheHit)
+	case "STALE":
+		logger.SetCache(req.Context(), logger.CacheStale)
+	default:
+		logger.SetCache(req.Context(), logger.CacheProvider)
+	}
+	w.Header().Set("Age", strconv.Itoa(age))
+	if status == "MISS" {
+		setFreshness(w, sourceProvider, meta.LastFetch, false)
+	} else {
+		setFreshness(w, sourceCache, meta.LastFetch, status == "STALE")
+	}
+	logger.SetResults(req.Context(), len(cards))
+	info := map[string]any{"source": source, "stale": status == "STALE", "fetched_at": meta.LastFetch, "age_seconds": age}
+	if !meta.StaleAfter.IsZero() {
+		info["stale_after"] = meta.StaleAfter
+	}
+	out := map[string]any{"ok": true, "count": len(cards), "properties": shapeCards(req, cards), "cache": info}
+	if n := len(page) - len(cards); n > 0 {
+		out["filtered"] = n
+	}
+	respond.Rows(w, req, out, "properties")
+}
+
+// searchDescriptions serves a keyword search over stored listing
+// descriptions. The provider has no equivalent, so there is no fallback.
+func searchDescriptions(w http.ResponseWriter, req *http.Request, st *store.Store, loc searchLocation, keywords string, f store.ListingFilter, limit, offset int) {
+	if st == nil {
+		apierror.Write(w, req, apierror.StoreUnavailable)
+		return
+	}
+	records, err := st.SearchListingDescriptions(req.Context(), store.DescriptionQuery{
+		Keywords: keywords, Zip: loc.Postal, City: loc.City, State: loc.State,
+		PropertyType: f.PropertyType, MinDOM: f.MinDOM, MaxDOM: f.MaxDOM, Features: f.Features, Limit: limit, Offset: offset,
+	})
+	if err != nil {
+		apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to search listings"))
+		return
+	}
+	cards := RecordsToCards(records)
+	logger.SetResults(req.Context(), len(cards))
+	setCardsFreshness(w, cards)
+	respond.Rows(w, req, map[string]any{"ok": true, "count": len(cards), "properties": shapeCards(req, cards), "keywords": keywords}, "properties")
+}
+
+// fetchListingPhotos returns a listing's photos with their metadata, from
+// the store when it has the full set and otherwise from the provider,
+// persisted like loadListingPhotos does.
+func fetchListingPhotos(ctx context.Context, listingID string, d ListingsDeps) ([]store.ListingPhoto, error) {
+	var stored []store.ListingPhoto
+	if st := d.listingStore(); st != nil {
+		photos, err := st.FetchListingPhotoAssets(ctx, listingID)
+		if err != nil {
+			log.Printf("[WARN] store photo lookup failed for listing %s: %v", listingID, err)
+		}
+		stored = photos
 	}
-	log.Printf("[INFO] served listings for %s from RapidAPI (%d listings)", body.PostalCode, len(cards))
-	render.JSON(w, req, map[string]any{"ok": true, "count": len(cards), "properties": cards})
+	return completeListingPhotos(ctx, listingID, stored, d)
 }
 
-func fetchListingPhotos(ctx context.Context, listingID string, d ListingsDeps) ([]string, error) {
-	store := d.Store
-	if store == nil && d.Hydrator != nil {
-		store = d.Hydrator.Store
+// completeListingPhotos returns the stored photos unless there are none or
+// they are only the few a search payload carried, in which case it fetches
+// the listing's full set from the provider. Stored photos are served when
+// the provider fails.
+func completeListingPhotos(ctx context.Context, listingID string, stored []store.ListingPhoto, d ListingsDeps) ([]store.ListingPhoto, error) {
+	if len(stored) > 0 && !store.InlineOnly(stored) {
+		return stored, nil
 	}
+	st := d.listingStore()
 	var propertyID string
-	if store != nil && listingID != "" {
-		pk, err := store.LookupPropertyKeyByListing(ctx, listingID)
+	if st != nil && listingID != "" {
+		pk, err := st.LookupPropertyKeyByListing(ctx, listingID)
 		if err != nil {
 			log.Printf("[WARN] property lookup failed for listing %s: %v", listingID, err)
 		} else {
 			propertyID = pk
 		}
 	}
-	return loadListingPhotos(ctx, listingID, propertyID, store, d.ListingsClient)
-}
-
-func photoHrefs(assets []attom.PhotoAsset) []string {
-	hrefs := make([]string, 0, len(assets))
-	for _, asset := range assets {
-		if asset.Href == "" {
-			continue
+	inputs, err := providerListingPhotos(ctx, listingID, propertyID, st, d.Hydrator, d.ListingsClient)
+	if err != nil {
+		if len(stored) > 0 {
+			log.Printf("[WARN] provider photos failed for listing %s, serving search payload photos: %v", listingID, err)
+			return stored, nil
 		}
-		hrefs = append(hrefs, asset.Href)
+		return nil, err
+	}
+	if len(inputs) == 0 && len(stored) > 0 {
+		return stored, nil
 	}
-	return hrefs
+	photos := make([]store.ListingPhoto, 0, len(inputs))
+	for _, in := range inputs {
+		photos = append(photos, store.ListingPhoto{
+			Href: in.Href, Description: in.Description, Title: in.Title, Kind: in.Kind,
+			MediaType: in.MediaType, Tags: in.Tags, Position: in.Position, Room: in.Room,
+		})
+	}
+	return photos, nil
 }
 
 func toStorePhotoInputs(assets []attom.PhotoAsset) []store.ListingPhotoInput {
@@ -228,25 +777,60 @@ func toStorePhotoInputs(assets []attom.PhotoAsset) []store.ListingPhotoInput {
 			MediaType:   mediaType,
 			Tags:        asset.Tags,
 			Position:    idx,
+			Room:        photoclass.FromTags(asset.Tags, asset.Title),
 		})
 	}
 	return out
 }
 
-func loadListingPhotos(ctx context.Context, listingID, propertyID string, st *store.Store, client *attom.Client) ([]string, error) {
+// loadListingPhotos serves stored photos, falling back to the provider and
+// persisting its answer through hydr so a changed set is announced. Photos
+// stored from a search payload also fall back, since they are not the full
+// set. The provider is only asked when allowProvider agrees; otherwise the
+// stored photos, if any, are served.
+func loadListingPhotos(ctx context.Context, listingID, propertyID string, st store.Listings, hydr *hydrator.Hydrator, client *attom.Client, allowProvider func() bool) ([]string, error) {
 	if listingID == "" && propertyID == "" {
 		return nil, nil
 	}
+	var stored []string
 	if listingID != "" && st != nil {
-		urls, err := st.FetchListingPhotos(ctx, listingID)
-		if err == nil {
-			if len(urls) > 0 {
-				return urls, nil
-			}
-		} else {
+		photos, err := st.FetchListingPhotoAssets(ctx, listingID)
+		if err != nil {
 			log.Printf("[WARN] store photo lookup failed for listing %s: %v", listingID, err)
 		}
+		for _, p := range photos {
+			stored = append(stored, p.Href)
+		}
+		// Photos from a search payload stand in until the full set is
+		// fetched.
+		if len(photos) > 0 && !store.InlineOnly(photos) {
+			return stored, nil
+		}
+	}
+	if client == nil || !allowProvider() {
+		return stored, nil
+	}
+	inputs, err := providerListingPhotos(ctx, listingID, propertyID, st, hydr, client)
+	if err != nil {
+		if len(stored) > 0 {
+			log.Printf("[WARN] provider photos failed for listing %s, serving search payload photos: %v", listingID, err)
+			return stored, nil
+		}
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return stored, nil
 	}
+	hrefs := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		hrefs = append(hrefs, in.Href)
+	}
+	return hrefs, nil
+}
+
+// providerListingPhotos fetches photos from the provider and persists them
+// for listingID through hydr, or st when hydration is off.
+func providerListingPhotos(ctx context.Context, listingID, propertyID string, st store.Listings, hydr *hydrator.Hydrator, client *attom.Client) ([]store.ListingPhotoInput, error) {
 	if client == nil {
 		return nil, nil
 	}
@@ -258,10 +842,18 @@ func loadListingPhotos(ctx context.Context, listingID, propertyID string, st *st
 	if err != nil {
 		return nil, err
 	}
-	if st != nil && listingID != "" && len(assets) > 0 {
-		if err := st.ReplaceListingPhotos(ctx, listingID, toStorePhotoInputs(assets)); err != nil {
+	inputs := toStorePhotoInputs(assets)
+	if listingID != "" && len(inputs) > 0 {
+		var err error
+		switch {
+		case hydr.Enabled():
+			err = hydr.SyncPhotos(ctx, listingID, inputs)
+		case st != nil:
+			_, err = st.SyncListingPhotos(ctx, listingID, inputs)
+		}
+		if err != nil {
 			log.Printf("[WARN] unable to persist photos for %s: %v", listingID, err)
 		}
 	}
-	return photoHrefs(assets), nil
+	return inputs, nil
 }
@@ -0,0 +1,99 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/yourorg/search-api/internal/hydrator"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// newMemoryListings serves the listing routes from an in-memory store
+// holding one listing, with no provider client configured.
+func newMemoryListings(t *testing.T) http.Handler {
+	t.Helper()
+	mem := store.NewMemory(0)
+	_, err := mem.WriteSnapshotAndUpsert(context.Background(), store.UpsertInput{
+		PropertyKey: "123 sandbox st|san francisco|ca|94110",
+		Address1:    "123 Sandbox St",
+		City:        "San Francisco",
+		State:       "CA",
+		Zip:         "94110",
+		Provider:    "rapidapi.realtor16",
+		SourceID:    "9990000001",
+		ListingID:   sql.NullString{String: "2960000001", Valid: true},
+		Status:      "for_sale",
+		ListPrice:   sql.NullFloat64{Float64: 875000, Valid: true},
+		Beds:        sql.NullInt64{Int64: 2, Valid: true},
+		Sqft:        sql.NullInt64{Int64: 1150, Valid: true},
+		Photos: []store.ListingPhotoInput{
+			{Href: "https://example.com/sandbox/9990000001-0.jpg", Position: 0, Primary: true},
+			{Href: "https://example.com/sandbox/9990000001-1.jpg", Position: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("WriteSnapshotAndUpsert: %v", err)
+	}
+	r := chi.NewRouter()
+	RegisterListings(r, ListingsDeps{Hydrator: &hydrator.Hydrator{Store: mem}})
+	return r
+}
+
+func getJSON(t *testing.T, h http.Handler, path string, want int) map[string]any {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != want {
+		t.Fatalf("GET %s: status %d, want %d: %s", path, rec.Code, want, rec.Body)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	return out
+}
+
+func TestListingsPageFromMemoryStore(t *testing.T) {
+	h := newMemoryListings(t)
+	out := getJSON(t, h, "/search/listings?postalcode=94110", http.StatusOK)
+	props, _ := out["properties"].([]any)
+	if len(props) != 1 {
+		t.Fatalf("got %d properties, want 1: %v", len(props), out)
+	}
+	card := props[0].(map[string]any)
+	if card["listingId"] != "2960000001" || card["address"] != "123 Sandbox St" || card["price"] != float64(875000) {
+		t.Errorf("card = %v", card)
+	}
+}
+
+func TestListingDetailFromMemoryStore(t *testing.T) {
+	h := newMemoryListings(t)
+	out := getJSON(t, h, "/search/listings/2960000001", http.StatusOK)
+	listing, _ := out["listing"].(map[string]any)
+	if listing["listingId"] != "2960000001" || listing["beds"] != float64(2) {
+		t.Errorf("listing = %v", listing)
+	}
+	if photos, _ := out["photos"].([]any); len(photos) != 2 {
+		t.Errorf("got %d photos, want 2", len(photos))
+	}
+
+	getJSON(t, h, "/search/listings/0000000000", http.StatusNotFound)
+}
+
+func TestListingPhotosFromMemoryStore(t *testing.T) {
+	h := newMemoryListings(t)
+	out := getJSON(t, h, "/search/listings/2960000001/photos", http.StatusOK)
+	photos, _ := out["photos"].([]any)
+	if len(photos) != 2 {
+		t.Fatalf("got %d photos, want 2: %v", len(photos), out)
+	}
+	if first := photos[0].(map[string]any); first["href"] != "https://example.com/sandbox/9990000001-0.jpg" {
+		t.Errorf("first photo = %v", first)
+	}
+}
@@ -0,0 +1,99 @@
+package httpapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/logger"
+	"github.com/yourorg/search-api/internal/proptype"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// searchLocation is the target of a search: either a ZIP or a city/state pair.
+type searchLocation struct {
+	Postal string
+	City   string
+	State  string
+}
+
+// resolveLocation picks the search target from the request fields. A ZIP wins;
+// otherwise location ("City, ST" or a bare ZIP) or explicit city+state is used.
+func resolveLocation(postal, location, city, state string) (searchLocation, bool) {
+	if postal = strings.TrimSpace(postal); postal != "" {
+		return searchLocation{Postal: postal}, true
+	}
+	if location = strings.TrimSpace(location); location != "" {
+		if canon.IsZIP(location) {
+			return searchLocation{Postal: location}, true
+		}
+		if c, st, ok := canon.ParseLocation(location); ok {
+			return searchLocation{City: c, State: st}, true
+		}
+		return searchLocation{}, false
+	}
+	if c, st, ok := canon.ParseLocation(city + "," + state); ok {
+		return searchLocation{City: c, State: st}, true
+	}
+	return searchLocation{}, false
+}
+
+// provider renders the location in the form the provider's location param expects.
+func (l searchLocation) provider() string {
+	if l.Postal != "" {
+		return l.Postal
+	}
+	return l.City + ", " + l.State
+}
+
+func (l searchLocation) String() string { return l.provider() }
+
+// noteSearch records the search for analytics. Only validated, normalized
+// values are kept; free text such as keywords is recorded by filter name.
+func noteSearch(ctx context.Context, route string, l searchLocation, propertyType, orderBy string, filters []string) {
+	s := logger.Search{Route: route, Zip: l.Postal, PropertyType: proptype.Normalize(propertyType), Filters: filters}
+	if l.Postal == "" {
+		s.City = l.City + ", " + l.State
+	}
+	if strings.EqualFold(strings.TrimSpace(orderBy), "beds") {
+		s.OrderBy = "beds"
+	} else if v, ok := attom.SortValue(orderBy); ok {
+		s.OrderBy = v
+	}
+	logger.SetSearch(ctx, s)
+}
+
+// fetchRecords serves a page for the location from the store.
+func (l searchLocation) fetchRecords(ctx context.Context, st store.Listings, limit, offset int, f store.ListingFilter) ([]store.ListingRecord, error) {
+	if l.Postal != "" {
+		return st.FetchListingsByPostal(ctx, l.Postal, limit, offset, f)
+	}
+	return st.FetchListingsByCity(ctx, l.City, l.State, limit, offset, f)
+}
+
+// storeFilter is the store filter for a search's property_type and, in the
+// order it asks the provider for, orderby.
+func storeFilter(propertyType, orderBy string) store.ListingFilter {
+	return store.ListingFilter{PropertyType: propertyType, Sort: listingSort(orderBy)}
+}
+
+// listingSort maps an orderby value onto the store order with the same
+// meaning. Provider orders the store has no data for, such as
+// open_house_date, keep the default order.
+func listingSort(orderBy string) store.ListingSort {
+	if strings.EqualFold(strings.TrimSpace(orderBy), "beds") {
+		return store.SortBeds
+	}
+	switch v, _ := attom.SortValue(orderBy); v {
+	case "lowest_price":
+		return store.SortPriceAsc
+	case "highest_price":
+		return store.SortPriceDesc
+	case "newest":
+		return store.SortNewest
+	case "largest_sqft":
+		return store.SortSqft
+	}
+	return store.SortUpdated
+}
@@ -0,0 +1,32 @@
+package httpapi
+
+import (
+	"time"
+
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// NoteDTO is an internal note on a property or listing, visible only to
+// the API key or token subject that wrote it.
+type NoteDTO struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind" doc:"property or listing"`
+	Target    string    `json:"target" doc:"Property key or provider listing ID"`
+	Body      string    `json:"body"`
+	Labels    []string  `json:"labels"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// NotesToDTOs converts stored notes for responses.
+func NotesToDTOs(notes []store.Note) []NoteDTO {
+	out := make([]NoteDTO, 0, len(notes))
+	for _, n := range notes {
+		out = append(out, NoteDTO{
+			ID: n.ID, Kind: n.Kind, Target: n.Target, Body: n.Body, Labels: n.Labels,
+			Author: n.Author, CreatedAt: n.CreatedAt, UpdatedAt: n.UpdatedAt,
+		})
+	}
+	return out
+}
@@ -0,0 +1,1316 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+	httpapi "github.com/yourorg/search-api/http"
+	httpv1 "github.com/yourorg/search-api/http/v1"
+	"github.com/yourorg/search-api/internal/addrverify"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/markets"
+	"github.com/yourorg/search-api/internal/storediff"
+)
+
+// Version is the published API version in the spec's info block.
+const Version = "1.0.0"
+
+// Response envelopes. Handlers build these as maps; the structs here pin
+// down the documented shape.
+
+type PropertiesResponse struct {
+	OK         bool                 `json:"ok"`
+	Count      int                  `json:"count"`
+	Properties []attom.PropertyCard `json:"properties"`
+	Degraded   bool                 `json:"degraded,omitempty" doc:"The provider quota is exhausted; the properties are whatever the database has, however stale"`
+}
+
+// ListingsPageResponse is a listings search result. Cache is present when the
+// page came from the provider rather than the database.
+type ListingsPageResponse struct {
+	OK         bool                 `json:"ok"`
+	Count      int                  `json:"count"`
+	Properties []attom.PropertyCard `json:"properties"`
+	Cache      *PageCacheInfo       `json:"cache,omitempty"`
+	Degraded   bool                 `json:"degraded,omitempty" doc:"The provider quota is exhausted; the properties are whatever the database has, however stale"`
+	Filtered   int                  `json:"filtered,omitempty" doc:"Listings on a provider page dropped by the DOM and feature filters, which the provider cannot apply; such a page can hold fewer than limit listings"`
+}
+
+type PageCacheInfo struct {
+	Source     string     `json:"source" doc:"rapidapi for a fresh fetch, cache otherwise"`
+	Stale      bool       `json:"stale" doc:"A background refresh has been queued"`
+	FetchedAt  time.Time  `json:"fetched_at"`
+	AgeSeconds int        `json:"age_seconds"`
+	StaleAfter *time.Time `json:"stale_after,omitempty"`
+}
+
+type ListingResponse struct {
+	OK          bool                             `json:"ok"`
+	Listing     attom.PropertyCard               `json:"listing"`
+	Photos      []httpapi.ListingPhotoDTO        `json:"photos" doc:"The listing's stored photos with their tags, room and position, in display order"`
+	Enrichments map[string]httpapi.EnrichmentDTO `json:"enrichments" doc:"Neighbourhood data by enricher: flood_zone, schools, walkability. Only enrichers that have run for the property appear."`
+	Notes       []httpapi.NoteDTO                `json:"notes,omitempty" doc:"The caller's notes on the property, then on the listing; only with include_notes=true"`
+}
+
+type NotesResponse struct {
+	OK     bool              `json:"ok"`
+	Kind   string            `json:"kind" doc:"property or listing"`
+	Target string            `json:"target"`
+	Count  int               `json:"count"`
+	Notes  []httpapi.NoteDTO `json:"notes" doc:"The caller's notes, oldest first"`
+}
+
+type SavedSearchesResponse struct {
+	OK            bool                    `json:"ok"`
+	Count         int                     `json:"count"`
+	SavedSearches []httpv1.SavedSearchDTO `json:"saved_searches" doc:"The caller's saved searches, oldest first"`
+}
+
+type SavedSearchResponse struct {
+	OK          bool                  `json:"ok"`
+	SavedSearch httpv1.SavedSearchDTO `json:"saved_search"`
+}
+
+type NoteResponse struct {
+	OK   bool            `json:"ok"`
+	Note httpapi.NoteDTO `json:"note"`
+}
+
+type PhotosResponse struct {
+	OK      bool                      `json:"ok"`
+	Count   int                       `json:"count" doc:"Photos on this page"`
+	Total   int                       `json:"total" doc:"Photos matching the room and tag filters across all pages"`
+	Page    int                       `json:"page"`
+	Limit   int                       `json:"limit"`
+	HasMore bool                      `json:"has_more" doc:"Later pages hold more photos"`
+	Primary *httpapi.ListingPhotoDTO  `json:"primary" doc:"First matching photo in display order, whatever the page; null when there are none"`
+	Photos  []httpapi.ListingPhotoDTO `json:"photos"`
+	Rooms   map[string]string         `json:"rooms" doc:"Room type by photo URL: exterior, kitchen, living_room, dining_room, bedroom, bathroom or floorplan. Unlabelled photos are absent."`
+}
+
+type NormalizedAddress struct {
+	Line1 string `json:"line1"`
+	City  string `json:"city"`
+	State string `json:"state"`
+	Zip   string `json:"zip"`
+}
+
+type ResolveResponse struct {
+	OK          bool               `json:"ok"`
+	Source      string             `json:"source" doc:"cache, store or fresh"`
+	Stale       bool               `json:"stale"`
+	PropertyKey string             `json:"property_key"`
+	KeyVersion  int                `json:"key_version" doc:"Canon rules version the property key was computed with"`
+	Normalized  NormalizedAddress  `json:"normalized"`
+	Data        attom.PropertyCard `json:"data"`
+	// Verification is present when address verification is configured.
+	Verification *addrverify.Result `json:"verification,omitempty"`
+}
+
+// ResolveLocationResponse is a resolve envelope plus how the point was
+// matched to the property.
+type ResolveLocationResponse struct {
+	ResolveResponse
+	Match httpv1.LocationMatch `json:"match"`
+}
+
+type ResolveInProgressResponse struct {
+	OK          bool   `json:"ok"`
+	InProgress  bool   `json:"in_progress"`
+	PropertyKey string `json:"property_key"`
+}
+
+type ResolveBatchResponse struct {
+	OK            bool                   `json:"ok"`
+	Count         int                    `json:"count"`
+	Summary       map[string]int         `json:"summary" doc:"Item counts by outcome: resolved, in_progress, failed"`
+	ProviderCalls int                    `json:"provider_calls"`
+	Results       []httpv1.ResolveResult `json:"results"`
+}
+
+type OpenHousesResponse struct {
+	OK         bool                  `json:"ok"`
+	ListingID  string                `json:"listing_id,omitempty"`
+	Zip        string                `json:"zip,omitempty"`
+	Days       int                   `json:"days,omitempty"`
+	Count      int                   `json:"count"`
+	OpenHouses []httpv1.OpenHouseDTO `json:"open_houses"`
+}
+
+type MergedListingsResponse struct {
+	OK          bool                      `json:"ok"`
+	PropertyKey string                    `json:"property_key"`
+	Count       int                       `json:"count"`
+	Listings    []httpv1.MergedListingDTO `json:"listings"`
+}
+
+type PropertyHistoryResponse struct {
+	OK          bool                      `json:"ok"`
+	PropertyKey string                    `json:"property_key"`
+	FetchedAt   *time.Time                `json:"fetched_at" doc:"When history was last ingested; null if never"`
+	Assessments []httpv1.TaxAssessmentDTO `json:"assessments" doc:"Tax assessments, newest year first"`
+	Transfers   []httpv1.TransferDTO      `json:"transfers" doc:"Sales and deed transfers, newest first"`
+	LastSale    *httpv1.TransferDTO       `json:"last_sale" doc:"Most recent transfer with a recorded price"`
+}
+
+type PaymentEstimateResponse struct {
+	OK          bool                         `json:"ok"`
+	ListingID   string                       `json:"listing_id"`
+	PropertyKey string                       `json:"property_key"`
+	Estimate    httpv1.PaymentEstimateDTO    `json:"estimate" doc:"Monthly amounts in dollars"`
+	Assumptions httpv1.PaymentAssumptionsDTO `json:"assumptions" doc:"Inputs after defaults were applied"`
+}
+
+type RentEstimateResponse struct {
+	OK          bool                      `json:"ok"`
+	PropertyKey string                    `json:"property_key"`
+	Estimate    httpv1.RentEstimateDTO    `json:"estimate" doc:"Monthly rent in dollars"`
+	Returns     httpv1.RentalReturnsDTO   `json:"returns" doc:"Yearly amounts in dollars; grossYield and capRate in percent"`
+	Assumptions httpv1.RentAssumptionsDTO `json:"assumptions" doc:"Inputs after defaults were applied"`
+	Comparables []attom.RentalListing     `json:"comparables" doc:"Up to 10 of the for-rent listings the estimate used, nearest first; empty when rent was passed"`
+}
+
+type PhotoReuseResponse struct {
+	OK          bool                   `json:"ok"`
+	ListingID   string                 `json:"listing_id"`
+	PropertyKey string                 `json:"property_key"`
+	Count       int                    `json:"count"`
+	Listings    []httpv1.PhotoReuseDTO `json:"listings" doc:"Other listings sharing images, most shared first"`
+}
+
+type ArrangePhotosResponse struct {
+	OK        bool                      `json:"ok"`
+	ListingID string                    `json:"listing_id"`
+	Count     int                       `json:"count"`
+	Photos    []httpapi.ListingPhotoDTO `json:"photos" doc:"The listing's photos in their new order"`
+}
+
+type PropertyMergeResponse struct {
+	OK    bool                    `json:"ok"`
+	Merge httpv1.PropertyMergeDTO `json:"merge"`
+}
+
+type PropertyMergesResponse struct {
+	OK          bool                      `json:"ok"`
+	PropertyKey string                    `json:"property_key"`
+	Count       int                       `json:"count"`
+	Merges      []httpv1.PropertyMergeDTO `json:"merges"`
+}
+
+type AuditLogResponse struct {
+	OK          bool                   `json:"ok"`
+	PropertyKey string                 `json:"property_key"`
+	Count       int                    `json:"count"`
+	Entries     []httpv1.AuditEntryDTO `json:"entries"`
+}
+
+type ChangesResponse struct {
+	OK         bool               `json:"ok"`
+	Count      int                `json:"count"`
+	Changes    []httpv1.ChangeDTO `json:"changes"`
+	NextCursor string             `json:"next_cursor" doc:"Pass as since on the next call; unchanged when there was nothing new"`
+	HasMore    bool               `json:"has_more" doc:"The page was full; call again right away"`
+}
+
+type UsageResponse struct {
+	OK     bool              `json:"ok"`
+	From   time.Time         `json:"from"`
+	To     time.Time         `json:"to"`
+	Count  int               `json:"count"`
+	Usage  []httpv1.UsageDTO `json:"usage"`
+	Totals []httpv1.UsageDTO `json:"totals" doc:"Per consumer over the range, most provider calls first"`
+}
+
+type TopSearchZipsResponse struct {
+	OK    bool                  `json:"ok"`
+	From  time.Time             `json:"from"`
+	To    time.Time             `json:"to"`
+	Count int                   `json:"count"`
+	Zips  []httpv1.ZipSearchDTO `json:"zips"`
+}
+
+type SearchFiltersResponse struct {
+	OK            bool                     `json:"ok"`
+	From          time.Time                `json:"from"`
+	To            time.Time                `json:"to"`
+	PropertyTypes []httpv1.SearchFilterDTO `json:"property_types"`
+	Orders        []httpv1.SearchFilterDTO `json:"orders"`
+	Filters       []httpv1.SearchFilterDTO `json:"filters" doc:"Other filters by name, such as beds or keywords"`
+	Cities        []httpv1.SearchFilterDTO `json:"cities" doc:"City searches, as \"City, ST\""`
+}
+
+type ShadowReportResponse struct {
+	OK      bool                      `json:"ok"`
+	From    time.Time                 `json:"from"`
+	To      time.Time                 `json:"to"`
+	Summary []httpv1.ShadowSummaryDTO `json:"summary" doc:"Per candidate and route"`
+	Count   int                       `json:"count"`
+	Results []httpv1.ShadowResultDTO  `json:"results" doc:"Latest comparisons first"`
+}
+
+type ShadowResultResponse struct {
+	OK     bool                   `json:"ok"`
+	Result httpv1.ShadowResultDTO `json:"result"`
+}
+
+type ZipDiffResponse struct {
+	OK     bool             `json:"ok"`
+	Agrees bool             `json:"agrees" doc:"No missing, unlisted or mismatched listings"`
+	Diff   storediff.Report `json:"diff"`
+}
+
+type FlagsResponse struct {
+	OK    bool             `json:"ok"`
+	Count int              `json:"count"`
+	Flags []httpv1.FlagDTO `json:"flags"`
+}
+
+type FlagResponse struct {
+	OK   bool           `json:"ok"`
+	Flag httpv1.FlagDTO `json:"flag"`
+}
+
+type DeletedRecordsResponse struct {
+	OK      bool                      `json:"ok"`
+	Count   int                       `json:"count"`
+	Deleted []httpv1.DeletedRecordDTO `json:"deleted"`
+}
+
+type MarketTrendsResponse struct {
+	OK     bool                `json:"ok"`
+	Zip    string              `json:"zip"`
+	Window string              `json:"window"`
+	Count  int                 `json:"count"`
+	Series []httpv1.TrendPoint `json:"series"`
+}
+
+type ReportWeeksResponse struct {
+	OK    bool                   `json:"ok"`
+	Count int                    `json:"count"`
+	Weeks []httpv1.ReportWeekDTO `json:"weeks"`
+}
+
+type MarketReportsResponse struct {
+	OK        bool              `json:"ok"`
+	WeekStart string            `json:"week_start"`
+	WeekEnd   string            `json:"week_end"`
+	Count     int               `json:"count"`
+	Reports   []markets.Summary `json:"reports"`
+}
+
+type OKResponse struct {
+	OK bool `json:"ok"`
+}
+
+type ErrorResponse struct {
+	OK    bool           `json:"ok"`
+	Error apierror.Error `json:"error"`
+}
+
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type route struct {
+	method string
+	path   string
+	op     *Operation
+}
+
+// Spec builds the document for every public route. New handlers must add
+// their operations to routes below.
+func Spec() *Document {
+	s := newSchemas()
+	errResp := func(desc string) *Response {
+		return &Response{Description: desc, Content: jsonContent(s.of(ErrorResponse{}))}
+	}
+	ok := func(desc string, v any) *Response {
+		return &Response{Description: desc, Content: jsonContent(s.of(v))}
+	}
+	quota := &Response{
+		Description: "Provider quota exhausted or client rate limited",
+		Headers:     map[string]*Header{"Retry-After": {Description: "Seconds until the request may be retried", Schema: &Schema{Type: "integer"}}},
+		Content:     jsonContent(s.of(ErrorResponse{})),
+	}
+	// cards documents card responses, which are also served as MessagePack
+	// and, one card per row, as CSV when the Accept header asks for them.
+	cards := func(desc string, v any) *Response {
+		r := ok(desc, v)
+		r.Content["application/x-msgpack"] = &MediaType{Schema: r.Content["application/json"].Schema}
+		r.Content["text/csv"] = &MediaType{Schema: &Schema{Type: "string"}}
+		return r
+	}
+	listingID := pathParam("listingID", "Provider listing ID")
+	flagNameParam := pathParam("name", "Flag name, e.g. serve-from-index")
+	flagConsumer := queryParam("consumer", "Also evaluate the flags for this consumer, e.g. key:mobile", &Schema{Type: "string"})
+	flagsDescription := "A flag is on for a consumer when its override says so, else when the stored rollout percentage includes the consumer, else per FLAGS, else by default. Raising a rollout only adds consumers."
+	notesDescription := "Notes are private to the caller's API key or token subject and need the notes scope, which the operator role grants."
+	notePropertyKey := pathParam("propertyKey", "Canonical property key")
+	noteID := pathParam("noteID", "Note ID")
+	savedSearchID := pathParam("id", "Saved search ID")
+	noteListResponses := map[string]*Response{
+		"200": ok("Notes", NotesResponse{}),
+		"401": errResp("Missing credentials"),
+		"403": errResp("Credentials without the notes scope"),
+		"503": errResp("Store unavailable"),
+	}
+	noteWriteResponses := func(notFound string) map[string]*Response {
+		return map[string]*Response{
+			"200": ok("The note", NoteResponse{}),
+			"400": errResp("Invalid JSON, an empty note, a body over 10000 characters or over 20 labels"),
+			"401": errResp("Missing credentials"),
+			"403": errResp("Credentials without the notes scope"),
+			"404": errResp(notFound),
+			"503": errResp("Store unavailable"),
+		}
+	}
+	// fresh adds the freshness headers search and listing reads carry.
+	fresh := func(r *Response) *Response {
+		if r.Headers == nil {
+			r.Headers = map[string]*Header{}
+		}
+		r.Headers["X-Data-Source"] = &Header{Description: "Where the response was served from", Schema: &Schema{Type: "string", Enum: []any{"cache", "database", "provider"}}}
+		r.Headers["X-Last-Fetched-At"] = &Header{Description: "When the data was fetched from the provider; for database results, the least recently fetched listing", Schema: &Schema{Type: "string", Format: "date-time"}}
+		r.Headers["X-Stale"] = &Header{Description: "true when the data is past its refresh time", Schema: &Schema{Type: "boolean"}}
+		return r
+	}
+	listingsPage := cards("Matching listings", ListingsPageResponse{})
+	listingsPage.Headers = map[string]*Header{
+		"X-Cache": {Description: "HIT, STALE or MISS for provider pages", Schema: &Schema{Type: "string", Enum: []any{"HIT", "STALE", "MISS"}}},
+		"Age":     {Description: "Seconds since the provider page was fetched", Schema: &Schema{Type: "integer"}},
+	}
+	fresh(listingsPage)
+	// shapeParams trim card responses; invalid values are a 400.
+	shapeParams := []Parameter{
+		queryParam("fields", "Comma-separated card fields to return, such as address,price,primaryImage; id is always included", &Schema{Type: "string"}),
+		queryParam("includePhotos", "false drops the images array from every card; primary keeps only each card's primary photo and skips fetching the rest", &Schema{Type: "string", Enum: []any{"true", "false", "primary"}}),
+	}
+	photos := ok("Photos in display order", PhotosResponse{})
+	photos.Content["application/x-msgpack"] = &MediaType{Schema: photos.Content["application/json"].Schema}
+	waitParam := queryParam("wait", "When true, wait for an in-progress fetch of the same property instead of returning 202", &Schema{Type: "boolean"})
+
+	routes := []route{
+		{http.MethodPost, "/v1/properties/resolve", &Operation{
+			OperationID: "resolveProperty",
+			Summary:     "Resolve an address to a property",
+			Description: "Canonicalizes the address and serves the cached property, refreshing it in the background when stale. When address verification is configured the address is first standardized, which may correct its ZIP. Returns 202 while another request is fetching the same property, unless wait=true is set, in which case the request is held until that fetch finishes or the wait timeout passes.",
+			Tags:        []string{"resolve"},
+			Parameters:  []Parameter{waitParam},
+			RequestBody: jsonBody(s.of(httpv1.ResolveRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("Resolved property", ResolveResponse{}),
+				"202": ok("Fetch already in progress", ResolveInProgressResponse{}),
+				"400": errResp("Missing address fields or invalid JSON"),
+				"404": errResp("No property matched the address"),
+				"422": errResp("Address verification could not confirm the address is deliverable (strict verification only)"),
+				"429": quota,
+				"502": errResp("Provider request failed"),
+			},
+		}},
+		{http.MethodGet, "/v1/properties/resolve", &Operation{
+			OperationID: "resolvePropertyQuery",
+			Summary:     "Resolve an address to a property (query string)",
+			Tags:        []string{"resolve"},
+			Parameters:  append(queryParams(s, httpv1.ResolveRequest{}), waitParam),
+			Responses: map[string]*Response{
+				"200": ok("Resolved property", ResolveResponse{}),
+				"202": ok("Fetch already in progress", ResolveInProgressResponse{}),
+				"400": errResp("Missing address fields"),
+				"404": errResp("No property matched the address"),
+				"422": errResp("Address verification could not confirm the address is deliverable (strict verification only)"),
+				"429": quota,
+				"502": errResp("Provider request failed"),
+			},
+		}},
+		{http.MethodGet, "/v1/properties/by-listing/{providerListingID}", &Operation{
+			OperationID: "resolvePropertyByListing",
+			Summary:     "Resolve a provider listing ID to a property",
+			Description: "For clients that only have the Realtor listing_id. A listing already in the store is served from the property's cache entry or stored listing; otherwise the provider's listing detail is fetched, persisted and cached. The response has the same envelope as an address resolve.",
+			Tags:        []string{"resolve"},
+			Parameters:  []Parameter{pathParam("providerListingID", "Provider (Realtor) listing_id")},
+			Responses: map[string]*Response{
+				"200": ok("Resolved property", ResolveResponse{}),
+				"404": errResp("No listing with that ID"),
+				"429": quota,
+				"502": errResp("Provider request failed"),
+			},
+		}},
+		{http.MethodGet, "/v1/properties/by-apn/{apn}", &Operation{
+			OperationID: "resolvePropertyByAPN",
+			Summary:     "Resolve an assessor parcel number to a property",
+			Description: "Finds the stored property recorded on a parcel, which is steadier than address text for rural properties. Dashes, spaces and case in the APN are ignored. Only properties ingested with their parcel are found; the provider is not searched. The response has the same envelope as an address resolve.",
+			Tags:        []string{"resolve"},
+			Parameters: []Parameter{
+				pathParam("apn", "Assessor parcel number"),
+				queryParam("fips", "5-digit county FIPS code; required when the APN is recorded in more than one county", &Schema{Type: "string"}),
+			},
+			Responses: map[string]*Response{
+				"200": ok("Resolved property", ResolveResponse{}),
+				"400": errResp("Missing APN or malformed fips"),
+				"404": errResp("No property recorded for this parcel"),
+				"409": errResp("APN recorded in more than one county; the fips detail lists them"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPost, "/v1/properties/resolve-by-location", &Operation{
+			OperationID: "resolvePropertyByLocation",
+			Summary:     "Resolve the property at a map point",
+			Description: "Finds the nearest stored property within radius_m of lat/lon and resolves its address. With none nearby, the point is reverse geocoded (when GEOCODE_REVERSE is enabled) and that address resolved. match.method says which was used.",
+			Tags:        []string{"resolve"},
+			RequestBody: jsonBody(s.of(httpv1.ResolveLocationRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("Resolved property", ResolveLocationResponse{}),
+				"202": ok("Fetch already in progress", ResolveInProgressResponse{}),
+				"400": errResp("Missing or out-of-range lat/lon, or invalid JSON"),
+				"404": errResp("No property or street address at the location"),
+				"429": quota,
+				"502": errResp("Provider or geocoder request failed"),
+			},
+		}},
+		{http.MethodPost, "/v1/properties/resolve:batch", &Operation{
+			OperationID: "resolvePropertiesBatch",
+			Summary:     "Resolve many addresses in one request",
+			Description: "Each item goes through the same cache, store and provider pipeline as the single resolve. Duplicate addresses are resolved once and addresses in the same ZIP share one provider call; items beyond the per-batch provider budget fail with batch_budget_exceeded. Per-item outcomes carry their own status code.",
+			Tags:        []string{"resolve"},
+			RequestBody: jsonBody(s.of(httpv1.ResolveBatchRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("Per-item results in request order", ResolveBatchResponse{}),
+				"400": errResp("Empty or oversized batch, or invalid JSON"),
+			},
+		}},
+		{http.MethodGet, "/v1/properties/{propertyKey}/listings", &Operation{
+			OperationID: "getPropertyListings",
+			Summary:     "Merged listings for a property",
+			Description: "One canonical listing per status, with every provider record linked to it by the cross-provider reconciliation pass.",
+			Tags:        []string{"listings"},
+			Parameters: []Parameter{pathParam("propertyKey", "Canonical property key"),
+				queryParam("limit", "Maximum listings (1-100, default 20)", &Schema{Type: "integer"})},
+			Responses: map[string]*Response{
+				"200": ok("Canonical listings with their provider sources", MergedListingsResponse{}),
+				"404": errResp("No listings recorded for the property"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/properties/{propertyKey}/rent-estimate", &Operation{
+			OperationID: "getPropertyRentEstimate",
+			Summary:     "Market rent and rental returns for a property",
+			Description: "Monthly rent from the median rent per square foot of for-rent listings in the property's ZIP within a bedroom of it, and the yearly income, operating costs, gross yield and unlevered cap rate of letting it at that rent. Price defaults to the list price, then the estimated value. Omitted inputs use the server defaults (PAYMENT_* and RENT_* env).",
+			Tags:        []string{"listings"},
+			Parameters: []Parameter{pathParam("propertyKey", "Canonical property key"),
+				queryParam("price", "Purchase price (default list price, then estimated value)", &Schema{Type: "number"}),
+				queryParam("rent", "Monthly rent; skips the comparables", &Schema{Type: "number"}),
+				queryParam("tax_rate", "Annual property tax, percent of price, when no assessment is on record", &Schema{Type: "number"}),
+				queryParam("insurance_rate", "Annual insurance, percent of price", &Schema{Type: "number"}),
+				queryParam("hoa", "Monthly HOA dues", &Schema{Type: "number"}),
+				queryParam("vacancy_pct", "Vacancy allowance, percent of gross rent", &Schema{Type: "number"}),
+				queryParam("maintenance_pct", "Maintenance, percent of gross rent", &Schema{Type: "number"}),
+				queryParam("management_pct", "Property management, percent of rent collected", &Schema{Type: "number"})},
+			Responses: map[string]*Response{
+				"200": ok("Rent estimate and returns", RentEstimateResponse{}),
+				"400": errResp("Invalid or non-positive input, or no price known and none given"),
+				"404": errResp("No listings recorded for the property"),
+				"422": errResp("Too few comparable rentals and no rent given"),
+				"429": quota,
+				"502": errResp("Provider request failed"),
+				"503": errResp("Store or rental search unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/properties/{propertyKey}/history", &Operation{
+			OperationID: "getPropertyHistory",
+			Summary:     "Tax assessment and deed history for a property",
+			Description: "Assessed values by tax year and recorded ownership transfers, including off-market sales. History is ingested by the hydrator when HYDRATOR_FETCH_HISTORY is enabled.",
+			Tags:        []string{"listings"},
+			Parameters:  []Parameter{pathParam("propertyKey", "Canonical property key")},
+			Responses: map[string]*Response{
+				"200": ok("Assessments and transfers", PropertyHistoryResponse{}),
+				"404": errResp("Unknown property"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPost, "/search", &Operation{
+			OperationID: "search",
+			Summary:     "Search properties by ZIP, city/state, or radius",
+			Tags:        []string{"search"},
+			Parameters:  shapeParams,
+			RequestBody: jsonBody(s.of(httpapi.SearchRequest{})),
+			Responses: map[string]*Response{
+				"200": fresh(cards("Matching properties", PropertiesResponse{})),
+				"400": errResp("Missing location or invalid JSON"),
+				"422": errResp("Invalid fields, such as a limit outside 1-50, an unknown orderby, a malformed ZIP or an unknown state; meta.fields lists each one"),
+				"429": quota,
+				"502": errResp("Provider request failed"),
+			},
+		}},
+		{http.MethodGet, "/search", &Operation{
+			OperationID: "searchQuery",
+			Summary:     "Search properties (query string)",
+			Tags:        []string{"search"},
+			Parameters: append(append(queryParams(s, httpapi.SearchRequest{}),
+				queryParam("q", "Free-text query; a 5-digit ZIP inside it is used when postalcode is absent", &Schema{Type: "string"})), shapeParams...),
+			Responses: map[string]*Response{
+				"200": fresh(cards("Matching properties", PropertiesResponse{})),
+				"400": errResp("Missing location"),
+				"422": errResp("Invalid fields, such as a limit outside 1-50, an unknown orderby, a malformed ZIP or an unknown state; meta.fields lists each one"),
+				"429": quota,
+				"502": errResp("Provider request failed"),
+			},
+		}},
+		{http.MethodPost, "/v1/search/geo", &Operation{
+			OperationID: "geoSearch",
+			Summary:     "Search stored listings inside a bbox or polygon",
+			Tags:        []string{"search"},
+			Parameters:  shapeParams,
+			RequestBody: jsonBody(s.of(httpapi.GeoSearchRequest{})),
+			Responses: map[string]*Response{
+				"200": cards("Listings inside the geometry", PropertiesResponse{}),
+				"400": errResp("Invalid geometry or JSON"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPost, "/search/listings", &Operation{
+			OperationID: "searchListings",
+			Summary:     "Search for-sale listings",
+			Description: "Serves from the database when it has listings for the location and falls back to the provider otherwise. Provider pages are cached stale-while-revalidate: a stale page is served immediately and refreshed in the background. With keywords, listing descriptions are searched in the database only, best match first, and each result carries a highlight with matched terms in <mark> tags. Under the dual-read flag a database page is served whenever there is one, and ZIPs on it whose listings were fetched more than an hour ago are re-crawled in the background.",
+			Tags:        []string{"listings"},
+			Parameters:  shapeParams,
+			RequestBody: jsonBody(s.of(httpapi.ListingsRequest{})),
+			Responses: map[string]*Response{
+				"200": listingsPage,
+				"400": errResp("Missing location or invalid JSON"),
+				"422": errResp("Invalid fields, such as a limit outside 1-50, an unknown orderby, a malformed ZIP or an unknown state; meta.fields lists each one"),
+				"429": quota,
+				"502": errResp("Provider request failed"),
+			},
+		}},
+		{http.MethodGet, "/search/listings", &Operation{
+			OperationID: "searchListingsQuery",
+			Summary:     "Search for-sale listings (query string)",
+			Tags:        []string{"listings"},
+			Parameters:  append(queryParams(s, httpapi.ListingsRequest{}), shapeParams...),
+			Responses: map[string]*Response{
+				"200": listingsPage,
+				"400": errResp("Missing location"),
+				"422": errResp("Invalid fields, such as a limit outside 1-50, an unknown orderby, a malformed ZIP or an unknown state; meta.fields lists each one"),
+				"429": quota,
+				"502": errResp("Provider request failed"),
+			},
+		}},
+		{http.MethodGet, "/search/listings/{listingID}", &Operation{
+			OperationID: "getListing",
+			Summary:     "Listing detail with agents and estimated value",
+			Tags:        []string{"listings"},
+			Parameters: append([]Parameter{listingID,
+				queryParam("include_notes", "true adds the caller's notes on the listing and its property; needs the notes scope", &Schema{Type: "boolean"})},
+				shapeParams...),
+			Responses: map[string]*Response{
+				"200": fresh(cards("Listing detail", ListingResponse{})),
+				"401": errResp("include_notes without credentials"),
+				"403": errResp("include_notes without the notes scope"),
+				"404": errResp("Listing not found"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/search/listings/{listingID}/photos", &Operation{
+			OperationID: "getListingPhotos",
+			Summary:     "Listing photos",
+			Description: "Photos with their captions, tags and position. Rooms come from normalized provider tags, or from the photo classifier when HYDRATOR_PHOTO_CLASSIFIER_URL is set.",
+			Tags:        []string{"photos"},
+			Parameters: []Parameter{listingID,
+				queryParam("room", "Only photos of these comma-separated room types", &Schema{Type: "string"}),
+				queryParam("tag", "Only photos carrying any of these comma-separated provider tags, such as exterior; case is ignored", &Schema{Type: "string"}),
+				queryParam("page", "1-based page (default 1)", &Schema{Type: "integer"}),
+				queryParam("limit", "Photos per page (1-50, default 50)", &Schema{Type: "integer"})},
+			Responses: map[string]*Response{
+				"200": photos,
+				"400": errResp("Missing listing ID or unknown room"),
+				"422": errResp("Invalid page or limit; meta.fields lists each one"),
+			},
+		}},
+		{http.MethodGet, "/v1/listings/{listingID}/open-houses", &Operation{
+			OperationID: "getListingOpenHouses",
+			Summary:     "Open houses for a listing",
+			Tags:        []string{"listings"},
+			Parameters: []Parameter{listingID,
+				queryParam("include_past", "Include open houses that already ended", &Schema{Type: "boolean"})},
+			Responses: map[string]*Response{
+				"200": ok("Open houses", OpenHousesResponse{}),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/listings/{listingID}/payment-estimate", &Operation{
+			OperationID: "getListingPaymentEstimate",
+			Summary:     "Monthly payment estimate for a listing",
+			Description: "Principal and interest on a fixed-rate loan plus property tax, insurance and HOA dues. Tax comes from the latest assessment when one is on record, otherwise from tax_rate. Omitted inputs use the server defaults (PAYMENT_* env).",
+			Tags:        []string{"listings"},
+			Parameters: []Parameter{listingID,
+				queryParam("price", "Purchase price (default list price)", &Schema{Type: "number"}),
+				queryParam("rate", "Annual interest rate, percent", &Schema{Type: "number"}),
+				queryParam("down_payment", "Down payment in dollars; overrides down_payment_pct", &Schema{Type: "number"}),
+				queryParam("down_payment_pct", "Down payment, percent of price", &Schema{Type: "number"}),
+				queryParam("term_years", "Loan term in years", &Schema{Type: "integer"}),
+				queryParam("tax_rate", "Annual property tax, percent of price, when no assessment is on record", &Schema{Type: "number"}),
+				queryParam("insurance_rate", "Annual insurance, percent of price", &Schema{Type: "number"}),
+				queryParam("hoa", "Monthly HOA dues", &Schema{Type: "number"})},
+			Responses: map[string]*Response{
+				"200": ok("Payment breakdown", PaymentEstimateResponse{}),
+				"400": errResp("Invalid input, or no list price and no price given"),
+				"404": errResp("Listing not found"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPatch, "/v1/listings/{listingID}/photos", &Operation{
+			OperationID: "arrangeListingPhotos",
+			Summary:     "Reorder a listing's photos or set its primary photo",
+			Description: "Renumbers the photos' positions and flags the primary photo, which always moves to the front. The curated order survives provider resyncs; photos the provider adds later are placed by their provider position. Requires the admin scope.",
+			Tags:        []string{"photos"},
+			Parameters:  []Parameter{listingID},
+			RequestBody: jsonBody(s.of(httpv1.ArrangePhotosRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("Photos in their new order", ArrangePhotosResponse{}),
+				"400": errResp("Invalid JSON, nothing to change or a photo URL the listing does not have"),
+				"401": errResp("Missing credentials"),
+				"403": errResp("Credentials without the admin scope"),
+				"404": errResp("Unknown listing"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/listings/{listingID}/photo-reuse", &Operation{
+			OperationID: "getListingPhotoReuse",
+			Summary:     "Other listings that reuse this listing's photos",
+			Description: "Matches photos by perceptual hash, so the same image at another URL or size counts. Includes deleted listings; a new listing sharing photos with an old one at another property is worth a look. Only photos the hydrator has hashed are compared.",
+			Tags:        []string{"listings"},
+			Parameters: []Parameter{listingID,
+				queryParam("limit", "Maximum listings (1-100, default 20)", &Schema{Type: "integer"})},
+			Responses: map[string]*Response{
+				"200": ok("Listings sharing photos", PhotoReuseResponse{}),
+				"404": errResp("Listing not found"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/open-houses", &Operation{
+			OperationID: "listOpenHouses",
+			Summary:     "Upcoming open houses in a ZIP",
+			Tags:        []string{"listings"},
+			Parameters: []Parameter{
+				{Name: "zip", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+				queryParam("days", "Days ahead to include (1-90, default 7)", &Schema{Type: "integer"}),
+				queryParam("limit", "Maximum results (1-200, default 50)", &Schema{Type: "integer"}),
+			},
+			Responses: map[string]*Response{
+				"200": ok("Upcoming open houses", OpenHousesResponse{}),
+				"400": errResp("Missing zip"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/markets/{zip}/trends", &Operation{
+			OperationID: "getMarketTrends",
+			Summary:     "Daily market series for a ZIP",
+			Tags:        []string{"markets"},
+			Parameters: []Parameter{pathParam("zip", "5-digit ZIP"),
+				queryParam("window", "Lookback such as 90d, 12w or 1y (default 90d)", &Schema{Type: "string"})},
+			Responses: map[string]*Response{
+				"200": ok("Market series", MarketTrendsResponse{}),
+				"400": errResp("Invalid window"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/reports", &Operation{
+			OperationID: "listReportWeeks",
+			Summary:     "Weeks with market reports",
+			Description: "Weekly per-ZIP market reports are generated every Monday for the week before, from the daily market rollup.",
+			Tags:        []string{"markets"},
+			Parameters:  []Parameter{queryParam("limit", "Weeks to list, newest first (default 12, max 104)", &Schema{Type: "integer"})},
+			Responses: map[string]*Response{
+				"200": ok("Report weeks", ReportWeeksResponse{}),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/reports/markets/{week}", &Operation{
+			OperationID: "getMarketReports",
+			Summary:     "Weekly per-ZIP market reports",
+			Description: "New listings, median price movement and the fastest-selling segments of each ZIP for a Monday-to-Sunday UTC week.",
+			Tags:        []string{"markets"},
+			Parameters: []Parameter{
+				pathParam("week", "latest, or any date (2006-01-02) in the week"),
+				queryParam("zip", "Only this ZIP", &Schema{Type: "string"}),
+				queryParam("format", "json (default) or csv", &Schema{Type: "string", Enum: []any{"json", "csv"}}),
+			},
+			Responses: map[string]*Response{
+				"200": {Description: "Reports ordered by ZIP", Content: map[string]*MediaType{
+					"application/json": {Schema: s.of(MarketReportsResponse{})},
+					"text/csv":         {Schema: &Schema{Type: "string"}},
+				}},
+				"400": errResp("Invalid week or format"),
+				"404": errResp("No report for the week or ZIP"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/export/listings", &Operation{
+			OperationID: "exportListings",
+			Summary:     "Stream listings as CSV or NDJSON",
+			Description: "Streams every stored listing matching the filters. The body is gzip-encoded when the client accepts it. CSV joins photo URLs with \"|\".",
+			Tags:        []string{"export"},
+			Parameters: []Parameter{
+				queryParam("zip", "Only listings in this ZIP", &Schema{Type: "string"}),
+				queryParam("since", "Only listings updated at or after this date or RFC 3339 timestamp", &Schema{Type: "string"}),
+				queryParam("format", "csv (default) or ndjson", &Schema{Type: "string", Enum: []any{"csv", "ndjson"}}),
+			},
+			Responses: map[string]*Response{
+				"200": {Description: "Listing rows", Content: map[string]*MediaType{
+					"text/csv":             {Schema: &Schema{Type: "string"}},
+					"application/x-ndjson": {Schema: s.of(httpv1.ExportRow{})},
+				}},
+				"400": errResp("Invalid format or since"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/changes", &Operation{
+			OperationID: "listChanges",
+			Summary:     "Feed of property and listing changes",
+			Description: "Every insert, change, soft delete, restore and purge of stored properties and listings, oldest first, for incremental sync. Start without since, then pass each response's next_cursor. A change is only served once the transaction that wrote it and every older open one have finished, so a client following cursors never misses one.",
+			Tags:        []string{"export"},
+			Parameters: []Parameter{
+				queryParam("since", "Cursor from a previous response; the start of the feed when omitted", &Schema{Type: "string"}),
+				queryParam("limit", "Maximum changes (1-1000, default 100)", &Schema{Type: "integer"}),
+			},
+			Responses: map[string]*Response{
+				"200": ok("Changes after the cursor, oldest first", ChangesResponse{}),
+				"400": errResp("Malformed cursor"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPost, "/hydrate", &Operation{
+			OperationID: "hydrate",
+			Summary:     "Request hydration of an address",
+			Tags:        []string{"hydrate"},
+			RequestBody: jsonBody(s.of(httpapi.HydrateRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("Accepted", OKResponse{}),
+				"400": errResp("Missing address or invalid JSON"),
+			},
+		}},
+		{http.MethodPost, "/graphql", &Operation{
+			OperationID: "graphql",
+			Summary:     "GraphQL gateway",
+			Description: "Properties, listings, photos and market stats in one round trip. The schema is available through introspection and at /graphql/playground.",
+			Tags:        []string{"graphql"},
+			RequestBody: jsonBody(s.of(GraphQLRequest{})),
+			Responses: map[string]*Response{
+				"200": {Description: "GraphQL response; field errors are reported in the errors array", Content: jsonContent(&Schema{Type: "object", AdditionalProperties: &Schema{}})},
+			},
+		}},
+		{http.MethodPost, "/v1/admin/properties/merge", &Operation{
+			OperationID: "mergeProperties",
+			Summary:     "Merge two property records",
+			Description: "Moves the source property's listings and valuations to the target, records the source key as an alias of the target and deletes the source row. Requires the admin bearer token.",
+			Tags:        []string{"admin"},
+			RequestBody: jsonBody(s.of(httpv1.MergePropertiesRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("Audit record of the merge", PropertyMergeResponse{}),
+				"400": errResp("Missing keys or invalid JSON"),
+				"401": errResp("Missing or wrong admin token"),
+				"404": errResp("Unknown property key"),
+				"409": errResp("Source and target are the same property"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPost, "/v1/admin/properties/split", &Operation{
+			OperationID: "splitProperty",
+			Summary:     "Move listings to the property at a corrected address",
+			Description: "Moves the listed listings to the property for the given address, creating it if needed, and pins them there so later ingests do not move them back. Splitting to the address of a merged-away property undoes that merge. Requires the admin bearer token.",
+			Tags:        []string{"admin"},
+			RequestBody: jsonBody(s.of(httpv1.SplitPropertyRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("Audit record of the split", PropertyMergeResponse{}),
+				"400": errResp("Missing listings or address, or invalid JSON"),
+				"401": errResp("Missing or wrong admin token"),
+				"404": errResp("Unknown property key"),
+				"409": errResp("Listings do not belong to the property, or the address is the same property"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/properties/{propertyKey}/merges", &Operation{
+			OperationID: "listPropertyMerges",
+			Summary:     "Merge and split history for a property",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{pathParam("propertyKey", "Property key, current or merged away"),
+				queryParam("limit", "Maximum records (1-200, default 50)", &Schema{Type: "integer"})},
+			Responses: map[string]*Response{
+				"200": ok("Audit records, newest first", PropertyMergesResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/properties/{propertyKey}/audit", &Operation{
+			OperationID: "getPropertyAuditLog",
+			Summary:     "Change history of a property and its listings",
+			Description: "Every insert, change, soft delete and restore of the property row and its listing rows, with the hydrator run or API caller that made it. Requires the admin bearer token.",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{pathParam("propertyKey", "Property key"),
+				queryParam("limit", "Maximum entries (1-500, default 100)", &Schema{Type: "integer"})},
+			Responses: map[string]*Response{
+				"200": ok("Audit entries, newest first", AuditLogResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/deleted", &Operation{
+			OperationID: "listDeletedRecords",
+			Summary:     "Soft-deleted properties and listings",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{
+				queryParam("kind", "property or listing; both when omitted", &Schema{Type: "string", Enum: []any{"property", "listing"}}),
+				queryParam("limit", "Maximum records (1-200, default 50)", &Schema{Type: "integer"}),
+			},
+			Responses: map[string]*Response{
+				"200": ok("Deleted records, most recently deleted first", DeletedRecordsResponse{}),
+				"400": errResp("Unknown kind"),
+				"401": errResp("Missing or wrong admin token"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/analytics/top-zips", &Operation{
+			OperationID: "getTopSearchZips",
+			Summary:     "Most searched ZIPs",
+			Description: "Searches and listings requests per ZIP with result counts and how the cache answered, from the hourly search rollup. No caller identity is recorded. sort=zero_results finds ZIPs worth hydrating; sort=provider finds ZIPs worth keeping warm.",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{
+				queryParam("from", "Start date or RFC 3339 timestamp; 7 days ago when omitted", &Schema{Type: "string"}),
+				queryParam("to", "End (exclusive); now when omitted", &Schema{Type: "string"}),
+				queryParam("route", "Only search or listings requests", &Schema{Type: "string", Enum: []any{"search", "listings"}}),
+				queryParam("sort", "searches (default), zero_results or provider", &Schema{Type: "string", Enum: []any{"searches", "zero_results", "provider"}}),
+				queryParam("limit", "ZIPs to return, 1-500 (default 50)", &Schema{Type: "integer"}),
+			},
+			Responses: map[string]*Response{
+				"200": ok("ZIPs in sort order", TopSearchZipsResponse{}),
+				"400": errResp("Invalid from, to, route, sort or limit"),
+				"401": errResp("Missing or wrong admin token"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/analytics/filters", &Operation{
+			OperationID: "getSearchFilters",
+			Summary:     "Most used search filters",
+			Description: "How often each property type, order, other filter and city was searched. Free-text filters such as keywords are counted by name only.",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{
+				queryParam("from", "Start date or RFC 3339 timestamp; 7 days ago when omitted", &Schema{Type: "string"}),
+				queryParam("to", "End (exclusive); now when omitted", &Schema{Type: "string"}),
+				queryParam("route", "Only search or listings requests", &Schema{Type: "string", Enum: []any{"search", "listings"}}),
+				queryParam("limit", "Values per dimension, 1-500 (default 50)", &Schema{Type: "integer"}),
+			},
+			Responses: map[string]*Response{
+				"200": ok("Values by dimension, most searched first", SearchFiltersResponse{}),
+				"400": errResp("Invalid from, to, route or limit"),
+				"401": errResp("Missing or wrong admin token"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/shadow", &Operation{
+			OperationID: "getShadowReport",
+			Summary:     "Shadow provider report",
+			Description: "How a candidate listing provider's answers compare with the primary's on the searches sent to both (SHADOW_PERCENT of provider searches). Listings are matched by property key; the report counts listings only one provider returned and fields the matches disagree on. Callers are always served the primary's answer.",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{
+				queryParam("from", "Start date or RFC 3339 timestamp; 7 days ago when omitted", &Schema{Type: "string"}),
+				queryParam("to", "End (exclusive); now when omitted", &Schema{Type: "string"}),
+				queryParam("candidate", "Only this candidate (SHADOW_PROVIDER_NAME)", &Schema{Type: "string"}),
+				queryParam("route", "Only search or listings requests", &Schema{Type: "string", Enum: []any{"search", "listings"}}),
+				queryParam("all", "List agreeing comparisons too, not only disagreements", &Schema{Type: "boolean"}),
+				queryParam("limit", "Comparisons to list, 1-500 (default 50)", &Schema{Type: "integer"}),
+			},
+			Responses: map[string]*Response{
+				"200": ok("Totals and the latest comparisons", ShadowReportResponse{}),
+				"400": errResp("Invalid from, to, route, all or limit"),
+				"401": errResp("Missing or wrong admin token"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/diff/zips/{zip}", &Operation{
+			OperationID: "getZipDiff",
+			Summary:     "Store versus provider diff for a ZIP",
+			Description: "Runs the ZIP's for-sale search against the store and the live provider and reports listings the store is missing, listings it still serves as for sale that the provider no longer lists (only when every provider page was read), and status, price, beds, baths, sqft and type mismatches with the store row's last update. Spends up to pages provider calls. `propctl diff` runs the same comparison.",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{
+				pathParam("zip", "5-digit ZIP"),
+				queryParam("pages", "Provider pages of 50 to read, 1-20 (default 5)", &Schema{Type: "integer"}),
+			},
+			Responses: map[string]*Response{
+				"200": ok("The diff", ZipDiffResponse{}),
+				"400": errResp("Invalid zip or pages"),
+				"401": errResp("Missing or wrong admin token"),
+				"429": errResp("Provider quota exhausted"),
+				"502": errResp("Provider request failed"),
+				"503": errResp("Store or provider unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/shadow/{id}", &Operation{
+			OperationID: "getShadowResult",
+			Summary:     "Shadow comparison detail",
+			Description: "One comparison with both providers' listings, without photos or remarks.",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{
+				pathParam("id", "Comparison ID from the report"),
+			},
+			Responses: map[string]*Response{
+				"200": ok("The comparison", ShadowResultResponse{}),
+				"400": errResp("Invalid id"),
+				"401": errResp("Missing or wrong admin token"),
+				"404": errResp("No comparison with the ID"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/usage", &Operation{
+			OperationID: "getUsage",
+			Summary:     "API usage per consumer",
+			Description: "Requests, provider calls and cache results per API key or token subject, rolled up hourly. Counts reach the database within a minute.",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{
+				queryParam("from", "Start date or RFC 3339 timestamp; 7 days ago when omitted", &Schema{Type: "string"}),
+				queryParam("to", "End (exclusive); now when omitted", &Schema{Type: "string"}),
+				queryParam("consumer", "Only this consumer, e.g. key:mobile", &Schema{Type: "string"}),
+				queryParam("granularity", "day (default) or hour", &Schema{Type: "string", Enum: []any{"day", "hour"}}),
+				queryParam("format", "json (default) or csv", &Schema{Type: "string", Enum: []any{"json", "csv"}}),
+			},
+			Responses: map[string]*Response{
+				"200": {Description: "Usage by consumer and bucket, oldest first", Content: map[string]*MediaType{
+					"application/json": {Schema: s.of(UsageResponse{})},
+					"text/csv":         {Schema: &Schema{Type: "string"}},
+				}},
+				"400": errResp("Invalid from, to, granularity or format"),
+				"401": errResp("Missing or wrong admin token"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/flags", &Operation{
+			OperationID: "listFlags",
+			Summary:     "Feature flags",
+			Description: flagsDescription,
+			Tags:        []string{"admin"},
+			Parameters:  []Parameter{flagConsumer},
+			Responses: map[string]*Response{
+				"200": ok("Known, configured and stored flags by name", FlagsResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+			},
+		}},
+		{http.MethodGet, "/v1/admin/flags/{name}", &Operation{
+			OperationID: "getFlag",
+			Summary:     "A feature flag",
+			Tags:        []string{"admin"},
+			Parameters:  []Parameter{flagNameParam, flagConsumer},
+			Responses: map[string]*Response{
+				"200": ok("Flag", FlagResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+			},
+		}},
+		{http.MethodPut, "/v1/admin/flags/{name}", &Operation{
+			OperationID: "setFlag",
+			Summary:     "Set a feature flag's rollout",
+			Description: "Stores the share of consumers with the flag on, over FLAGS and the default. Replicas pick the change up within seconds.",
+			Tags:        []string{"admin"},
+			Parameters:  []Parameter{flagNameParam},
+			RequestBody: jsonBody(s.of(httpv1.SetFlagRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("Flag", FlagResponse{}),
+				"400": errResp("Invalid name, percent or enabled"),
+				"401": errResp("Missing or wrong admin token"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodDelete, "/v1/admin/flags/{name}", &Operation{
+			OperationID: "deleteFlag",
+			Summary:     "Remove a feature flag's stored rollout",
+			Description: "The flag falls back to FLAGS or its default; overrides are kept.",
+			Tags:        []string{"admin"},
+			Parameters:  []Parameter{flagNameParam},
+			Responses: map[string]*Response{
+				"200": ok("Flag", FlagResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+				"404": errResp("No stored rollout for the flag"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPut, "/v1/admin/flags/{name}/overrides/{consumer}", &Operation{
+			OperationID: "setFlagOverride",
+			Summary:     "Force a feature flag on or off for one consumer",
+			Tags:        []string{"admin"},
+			Parameters:  []Parameter{flagNameParam, pathParam("consumer", "API key (key:name), token subject (jwt:subject) or anonymous")},
+			RequestBody: jsonBody(s.of(httpv1.FlagOverrideRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("Flag", FlagResponse{}),
+				"400": errResp("Invalid name or missing enabled"),
+				"401": errResp("Missing or wrong admin token"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodDelete, "/v1/admin/flags/{name}/overrides/{consumer}", &Operation{
+			OperationID: "deleteFlagOverride",
+			Summary:     "Return a consumer to a feature flag's rollout",
+			Tags:        []string{"admin"},
+			Parameters:  []Parameter{flagNameParam, pathParam("consumer", "API key (key:name), token subject (jwt:subject) or anonymous")},
+			Responses: map[string]*Response{
+				"200": ok("Flag", FlagResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+				"404": errResp("No override for the consumer"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodDelete, "/v1/admin/properties/{propertyKey}", &Operation{
+			OperationID: "deleteProperty",
+			Summary:     "Soft-delete a property",
+			Description: "Hides the property and its listings from every read path until restored.",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{pathParam("propertyKey", "Property key"),
+				queryParam("actor", "Who requested the change, for the audit log", &Schema{Type: "string"})},
+			Responses: map[string]*Response{
+				"200": ok("Deleted", OKResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+				"404": errResp("No live property with this key"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPost, "/v1/admin/properties/{propertyKey}/restore", &Operation{
+			OperationID: "restoreProperty",
+			Summary:     "Restore a soft-deleted property",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{pathParam("propertyKey", "Property key"),
+				queryParam("actor", "Who requested the change, for the audit log", &Schema{Type: "string"})},
+			Responses: map[string]*Response{
+				"200": ok("Restored", OKResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+				"404": errResp("No deleted property with this key"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodDelete, "/v1/admin/listings/{listingID}", &Operation{
+			OperationID: "deleteListing",
+			Summary:     "Soft-delete a listing",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{listingID,
+				queryParam("actor", "Who requested the change, for the audit log", &Schema{Type: "string"})},
+			Responses: map[string]*Response{
+				"200": ok("Deleted", OKResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+				"404": errResp("No live listing with this ID"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPost, "/v1/admin/listings/{listingID}/restore", &Operation{
+			OperationID: "restoreListing",
+			Summary:     "Restore a soft-deleted listing",
+			Tags:        []string{"admin"},
+			Parameters: []Parameter{listingID,
+				queryParam("actor", "Who requested the change, for the audit log", &Schema{Type: "string"})},
+			Responses: map[string]*Response{
+				"200": ok("Restored", OKResponse{}),
+				"401": errResp("Missing or wrong admin token"),
+				"404": errResp("No deleted listing with this ID"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/properties/{propertyKey}/notes", &Operation{
+			OperationID: "listPropertyNotes",
+			Summary:     "The caller's notes on a property",
+			Tags:        []string{"notes"},
+			Parameters:  []Parameter{notePropertyKey},
+			Responses:   noteListResponses,
+		}},
+		{http.MethodPost, "/v1/properties/{propertyKey}/notes", &Operation{
+			OperationID: "addPropertyNote",
+			Summary:     "Add a note to a property",
+			Description: notesDescription,
+			Tags:        []string{"notes"},
+			Parameters:  []Parameter{notePropertyKey},
+			RequestBody: jsonBody(s.of(httpv1.NoteRequest{})),
+			Responses:   noteWriteResponses("Unknown property key"),
+		}},
+		{http.MethodGet, "/v1/listings/{listingID}/notes", &Operation{
+			OperationID: "listListingNotes",
+			Summary:     "The caller's notes on a listing",
+			Tags:        []string{"notes"},
+			Parameters:  []Parameter{listingID},
+			Responses:   noteListResponses,
+		}},
+		{http.MethodPost, "/v1/listings/{listingID}/notes", &Operation{
+			OperationID: "addListingNote",
+			Summary:     "Add a note to a listing",
+			Description: notesDescription,
+			Tags:        []string{"notes"},
+			Parameters:  []Parameter{listingID},
+			RequestBody: jsonBody(s.of(httpv1.NoteRequest{})),
+			Responses:   noteWriteResponses("Unknown listing"),
+		}},
+		{http.MethodPatch, "/v1/notes/{noteID}", &Operation{
+			OperationID: "updateNote",
+			Summary:     "Change a note's body or labels",
+			Tags:        []string{"notes"},
+			Parameters:  []Parameter{noteID},
+			RequestBody: jsonBody(s.of(httpv1.NoteRequest{})),
+			Responses:   noteWriteResponses("No note with this ID owned by the caller"),
+		}},
+		{http.MethodDelete, "/v1/notes/{noteID}", &Operation{
+			OperationID: "deleteNote",
+			Summary:     "Delete a note",
+			Tags:        []string{"notes"},
+			Parameters:  []Parameter{noteID},
+			Responses: map[string]*Response{
+				"200": ok("Deleted", OKResponse{}),
+				"401": errResp("Missing credentials"),
+				"403": errResp("Credentials without the notes scope"),
+				"404": errResp("No note with this ID owned by the caller"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/v1/saved-searches", &Operation{
+			OperationID: "listSavedSearches",
+			Summary:     "The caller's saved searches",
+			Tags:        []string{"saved searches"},
+			Responses: map[string]*Response{
+				"200": ok("Saved searches", SavedSearchesResponse{}),
+				"401": errResp("Missing credentials"),
+				"403": errResp("Credentials without the saved scope"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPost, "/v1/saved-searches", &Operation{
+			OperationID: "createSavedSearch",
+			Summary:     "Save a search and subscribe to its email digest",
+			Description: "Digests list the listings that appeared or changed price or status since the last one, and are mailed by the hydrator when DIGEST_SMTP_ADDR is set. Saved searches are private to the caller's API key or token subject and need the saved scope, which the search and operator roles grant.",
+			Tags:        []string{"saved searches"},
+			RequestBody: jsonBody(s.of(httpv1.SavedSearchRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("The saved search", SavedSearchResponse{}),
+				"400": errResp("Invalid JSON, a missing or invalid email, frequency or criteria"),
+				"401": errResp("Missing credentials"),
+				"403": errResp("Credentials without the saved scope"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodPatch, "/v1/saved-searches/{id}", &Operation{
+			OperationID: "updateSavedSearch",
+			Summary:     "Change a saved search's name, email or digest frequency",
+			Description: "A new frequency reschedules the next digest one interval after the last; turning digests back on from off starts the next window now.",
+			Tags:        []string{"saved searches"},
+			Parameters:  []Parameter{savedSearchID},
+			RequestBody: jsonBody(s.of(httpv1.SavedSearchRequest{})),
+			Responses: map[string]*Response{
+				"200": ok("The saved search", SavedSearchResponse{}),
+				"400": errResp("Invalid JSON or field, or criteria given"),
+				"401": errResp("Missing credentials"),
+				"403": errResp("Credentials without the saved scope"),
+				"404": errResp("No saved search with this ID owned by the caller"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodDelete, "/v1/saved-searches/{id}", &Operation{
+			OperationID: "deleteSavedSearch",
+			Summary:     "Delete a saved search and stop its digests",
+			Tags:        []string{"saved searches"},
+			Parameters:  []Parameter{savedSearchID},
+			Responses: map[string]*Response{
+				"200": ok("Deleted", OKResponse{}),
+				"401": errResp("Missing credentials"),
+				"403": errResp("Credentials without the saved scope"),
+				"404": errResp("No saved search with this ID owned by the caller"),
+				"503": errResp("Store unavailable"),
+			},
+		}},
+		{http.MethodGet, "/health", &Operation{
+			OperationID: "health",
+			Summary:     "Liveness probe",
+			Tags:        []string{"admin"},
+			Responses:   map[string]*Response{"200": ok("Service is up", OKResponse{})},
+		}},
+	}
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "search-api",
+			Version:     Version,
+			Description: "Property resolve, search and listing API. Errors share one envelope: {\"ok\": false, \"error\": {...}}.",
+		},
+		Tags: []Tag{
+			{Name: "resolve", Description: "Address to property resolution"},
+			{Name: "search", Description: "Property search"},
+			{Name: "listings", Description: "For-sale listings and open houses"},
+			{Name: "photos", Description: "Listing media"},
+			{Name: "notes", Description: "Internal notes on properties and listings"},
+			{Name: "saved searches", Description: "Saved searches and their email digests"},
+			{Name: "markets", Description: "Per-ZIP market statistics"},
+			{Name: "export", Description: "Bulk data export"},
+			{Name: "hydrate", Description: "Background hydration"},
+			{Name: "graphql", Description: "GraphQL gateway"},
+			{Name: "admin", Description: "Operational endpoints"},
+		},
+		Paths: map[string]*PathItem{},
+	}
+	for _, rt := range routes {
+		item := doc.Paths[rt.path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[rt.path] = item
+		}
+		switch rt.method {
+		case http.MethodGet:
+			item.Get = rt.op
+		case http.MethodPost:
+			item.Post = rt.op
+		case http.MethodPut:
+			item.Put = rt.op
+		case http.MethodPatch:
+			item.Patch = rt.op
+		case http.MethodDelete:
+			item.Delete = rt.op
+		}
+	}
+	doc.Components.Schemas = s.components
+	return doc
+}
+
+func jsonContent(sc *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: sc}}
+}
+
+func jsonBody(sc *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: jsonContent(sc)}
+}
+
+func pathParam(name, desc string) Parameter {
+	return Parameter{Name: name, In: "path", Description: desc, Required: true, Schema: &Schema{Type: "string"}}
+}
+
+func queryParam(name, desc string, sc *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: desc, Schema: sc}
+}
+
+// queryParams documents a request struct's scalar fields as query
+// parameters, matching how the GET variants of the handlers read them.
+func queryParams(s *schemas, v any) []Parameter {
+	t := reflect.TypeOf(v)
+	var out []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.String, reflect.Int, reflect.Int64, reflect.Float64, reflect.Bool:
+		default:
+			continue
+		}
+		sc := s.forType(ft)
+		out = append(out, Parameter{Name: name, In: "query", Schema: sc})
+	}
+	return out
+}
@@ -3,6 +3,7 @@ package httpapi
 import (
 	"context"
 	"math"
+	"time"
 
 	"github.com/yourorg/search-api/attom"
 	"github.com/yourorg/search-api/internal/canon"
@@ -33,7 +34,8 @@ func persistCards(ctx context.Context, hydr *hydrator.Hydrator, endpoint string,
 	}
 }
 
-func recordsToCards(records []store.ListingRecord) []attom.PropertyCard {
+// RecordsToCards maps stored listings to API cards.
+func RecordsToCards(records []store.ListingRecord) []attom.PropertyCard {
 	cards := make([]attom.PropertyCard, 0, len(records))
 	for _, rec := range records {
 		var card attom.PropertyCard
@@ -51,6 +53,7 @@ func recordsToCards(records []store.ListingRecord) []attom.PropertyCard {
 		card.City = rec.City
 		card.State = rec.State
 		card.Zip = rec.Zip
+		card.Status = rec.Status
 		if rec.PropertyType.Valid {
 			card.Type = rec.PropertyType.String
 		}
@@ -72,7 +75,35 @@ func recordsToCards(records []store.ListingRecord) []attom.PropertyCard {
 		if len(rec.Photos) > 0 {
 			card.Images = append([]string(nil), rec.Photos...)
 		}
+		card.Highlight = rec.Highlight
+		card.APN, card.FIPS = rec.Parcel.APN, rec.Parcel.FIPS
+		card.SetCounty(rec.County.FIPS, rec.County.Name)
+		if rec.ListDate.Valid {
+			card.SetListDate(rec.ListDate.Time, time.Now())
+		}
+		if rec.LastFetchAt.Valid {
+			card.LastFetchAt = &rec.LastFetchAt.Time
+		}
+		if rec.StaleAfter.Valid {
+			card.StaleAfter = &rec.StaleAfter.Time
+		}
+		card.HOAFee, card.GarageSpaces, card.Stories = rec.Features.HOAFee, rec.Features.GarageSpaces, rec.Features.Stories
+		card.Pool, card.Basement = rec.Features.Pool, rec.Features.Basement
+		card.NewConstruction, card.Foreclosure, card.PriceReduced = rec.Features.NewConstruction, rec.Features.Foreclosure, rec.Features.PriceReduced
+		for _, a := range rec.Agents {
+			card.Agents = append(card.Agents, attom.Agent{
+				ID:         a.SourceID,
+				Role:       a.Role,
+				Name:       a.Name,
+				Email:      a.Email,
+				Phone:      a.Phone,
+				OfficeID:   a.OfficeID,
+				OfficeName: a.OfficeName,
+				BrokerName: a.BrokerName,
+			})
+		}
 		card.Source = "database"
+		card.Localize()
 		cards = append(cards, card)
 	}
 	return cards
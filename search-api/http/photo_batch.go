@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/canon"
+)
+
+const (
+	defaultPhotoBudget = 10
+	photoConcurrency   = 4
+)
+
+// photoBudget caps the provider photo calls made for one page. Stored
+// photos cost nothing and are not counted.
+type photoBudget struct {
+	mu   sync.Mutex
+	left int
+}
+
+func (d ListingsDeps) newPhotoBudget() *photoBudget {
+	if d.StoredPhotosOnly {
+		return &photoBudget{}
+	}
+	n := d.PhotoBudget
+	if n <= 0 {
+		n = defaultPhotoBudget
+	}
+	return &photoBudget{left: n}
+}
+
+// take claims a provider call, reporting false once the budget is spent.
+func (b *photoBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.left <= 0 {
+		return false
+	}
+	b.left--
+	return true
+}
+
+// attachPhotos replaces each card's images with its listing's stored
+// photos, or the provider's, photoConcurrency cards at a time. Cards past
+// the page's provider budget, or all cards under StoredPhotosOnly, keep the
+// images the search payload carried. With primaryOnly only cards without a
+// payload photo are looked up, and only in the store.
+func (d ListingsDeps) attachPhotos(ctx context.Context, cards []attom.PropertyCard, primaryOnly bool) {
+	st := d.listingStore()
+	if primaryOnly {
+		d.StoredPhotosOnly = true
+	}
+	budget := d.newPhotoBudget()
+	sem := make(chan struct{}, photoConcurrency)
+	var wg sync.WaitGroup
+	for i := range cards {
+		listingID := cards[i].ListingID
+		if listingID == "" {
+			listingID = cards[i].ID
+		}
+		propertyID := cards[i].PropertyID
+		if propertyID == "" {
+			if _, _, _, _, pk := canon.Canonicalize(cards[i].Address, cards[i].City, cards[i].State, cards[i].Zip); pk != "" {
+				propertyID = pk
+				cards[i].PropertyID = pk
+			}
+		}
+		if listingID == "" && propertyID == "" {
+			continue
+		}
+		cards[i].ListingID = listingID
+		if primaryOnly && len(cards[i].Images) > 0 {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(card *attom.PropertyCard, listingID, propertyID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			photos, err := loadListingPhotos(ctx, listingID, propertyID, st, d.Hydrator, d.ListingsClient, budget.take)
+			if err != nil {
+				log.Printf("[WARN] unable to load photos for listing %s: %v", listingID, err)
+				return
+			}
+			if len(photos) > 0 {
+				card.Images = photos
+			}
+		}(&cards[i], listingID, propertyID)
+	}
+	wg.Wait()
+}
@@ -1,28 +1,41 @@
 package httpapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/render"
 	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/flags"
 	"github.com/yourorg/search-api/internal/hydrator"
+	"github.com/yourorg/search-api/internal/logger"
+	"github.com/yourorg/search-api/internal/respond"
+	"github.com/yourorg/search-api/internal/shadow"
+	"github.com/yourorg/search-api/internal/store"
 )
 
 type SearchDeps struct {
 	Hydrator       *hydrator.Hydrator
 	ListingsClient *attom.Client
+	// Shadow sends a sample of provider searches to a candidate provider
+	// too; nil disables it.
+	Shadow *shadow.Provider
 }
 
 type SearchRequest struct {
 	// Postal-based search (preferred)
-	PostalCode   string `json:"postalcode,omitempty"`
-	PropertyType string `json:"property_type,omitempty"`
-	OrderBy      string `json:"orderby,omitempty"`
+	PostalCode string `json:"postalcode,omitempty"`
+	// Location-based search: "City, ST", or city + state
+	Location     string `json:"location,omitempty"`
+	City         string `json:"city,omitempty"`
+	State        string `json:"state,omitempty"`
+	PropertyType string `json:"property_type,omitempty" doc:"single_family, condo, townhome, multi_family, mobile, land or farm; common spellings such as condos are accepted"`
+	OrderBy      string `json:"orderby,omitempty" doc:"price_asc, price_desc, newest, sqft or beds, or a provider order such as open_house_date; beds only applies to pages served from the database"`
 	Limit        *int   `json:"limit,omitempty"` // maps to pagesize
 	Page         *int   `json:"page,omitempty"`
 
@@ -46,12 +59,18 @@ func defInt(v *int, d int) int {
 }
 
 func RegisterSearch(r chi.Router, d SearchDeps) {
+	r = r.With(cardShaping)
 	// POST: JSON body
 	r.Post("/search", func(w http.ResponseWriter, req *http.Request) {
 		var body SearchRequest
 		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
-			render.Status(req, http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_json", "detail": err.Error()})
+			apierror.WriteError(w, req, err, apierror.InvalidJSON)
+			return
+		}
+		var val validator
+		body.validate(&val)
+		if apiErr := val.err(); apiErr != nil {
+			apierror.Write(w, req, apiErr)
 			return
 		}
 		handleSearchRequest(w, req, d, body)
@@ -61,6 +80,7 @@ func RegisterSearch(r chi.Router, d SearchDeps) {
 	r.Get("/search", func(w http.ResponseWriter, req *http.Request) {
 		q := req.URL.Query()
 		var body SearchRequest
+		var val validator
 		// Postal-based
 		body.PostalCode = q.Get("postalcode")
 		// If not provided, try to extract ZIP from human-readable `q` param
@@ -84,102 +104,102 @@ func RegisterSearch(r chi.Router, d SearchDeps) {
 				}
 			}
 		}
-		if v := q.Get("limit"); v != "" {
-			if i, err := strconv.Atoi(v); err == nil {
-				body.Limit = &i
-			}
-		}
-		if v := q.Get("page"); v != "" {
-			if i, err := strconv.Atoi(v); err == nil {
-				body.Page = &i
-			}
-		}
+		val.intParam(q, "limit", &body.Limit)
+		val.intParam(q, "page", &body.Page)
 		body.PropertyType = q.Get("property_type")
 		body.OrderBy = q.Get("orderby")
+		body.Location = q.Get("location")
+		body.City = q.Get("city")
+		body.State = q.Get("state")
 
 		// Legacy radius (optional)
-		if v := q.Get("lat"); v != "" {
-			if f, err := strconv.ParseFloat(v, 64); err == nil {
-				body.Lat = &f
-			}
-		}
-		if v := q.Get("lon"); v != "" {
-			if f, err := strconv.ParseFloat(v, 64); err == nil {
-				body.Lon = &f
-			}
-		}
+		val.floatParam(q, "lat", &body.Lat)
+		val.floatParam(q, "lon", &body.Lon)
 		// Support `lng` alias
 		if body.Lon == nil {
-			if v := q.Get("lng"); v != "" {
-				if f, err := strconv.ParseFloat(v, 64); err == nil {
-					body.Lon = &f
-				}
-			}
+			val.floatParam(q, "lng", &body.Lon)
 		}
-		if v := q.Get("radius"); v != "" {
-			if f, err := strconv.ParseFloat(v, 64); err == nil {
-				body.Radius = &f
-			}
+		val.floatParam(q, "radius", &body.Radius)
+		body.validate(&val)
+		if apiErr := val.err(); apiErr != nil {
+			apierror.Write(w, req, apiErr)
+			return
 		}
 		handleSearchRequest(w, req, d, body)
 	})
 }
 
 func handleSearchRequest(w http.ResponseWriter, req *http.Request, d SearchDeps, body SearchRequest) {
-	// Prefer postal-based search
-	if body.PostalCode != "" {
+	// Prefer postal- or city-based search
+	if loc, ok := resolveLocation(body.PostalCode, body.Location, body.City, body.State); ok {
+		d.Hydrator.NoteDemand(loc.Postal)
+		noteSearch(req.Context(), "search", loc, body.PropertyType, body.OrderBy, nil)
 		// Default to 5 to align with RapidAPI usage
 		pagesize := defInt(body.Limit, 5)
 		page := defInt(body.Page, 1)
 		offset := (page - 1) * pagesize
-		if d.Hydrator != nil && d.Hydrator.Store != nil {
-			records, err := d.Hydrator.Store.FetchListingsByPostal(req.Context(), body.PostalCode, pagesize, offset, body.PropertyType)
+		if d.Hydrator != nil && d.Hydrator.Store != nil && flags.Enabled(req.Context(), flags.ServeFromIndex) {
+			records, err := loc.fetchRecords(req.Context(), d.Hydrator.Store, pagesize, offset, storeFilter(body.PropertyType, body.OrderBy))
 			if err != nil {
-				log.Printf("[WARN] db lookup failed for postal %s: %v", body.PostalCode, err)
+				log.Printf("[WARN] db lookup failed for %s: %v", loc, err)
 			} else if len(records) > 0 {
-				cards := recordsToCards(records)
-				log.Printf("[INFO] serving postal %s from database (%d listings)", body.PostalCode, len(cards))
-				render.JSON(w, req, map[string]any{
+				cards := RecordsToCards(records)
+				log.Printf("[INFO] serving %s from database (%d listings)", loc, len(cards))
+				logger.SetCache(req.Context(), logger.CacheMiss)
+				logger.SetResults(req.Context(), len(cards))
+				setCardsFreshness(w, cards)
+				respond.Rows(w, req, map[string]any{
 					"ok":         true,
 					"count":      len(cards),
-					"properties": cards,
-				})
+					"properties": shapeCards(req, cards),
+				}, "properties")
 				return
 			} else {
-				log.Printf("[INFO] no database listings for %s; falling back to RapidAPI", body.PostalCode)
+				log.Printf("[INFO] no database listings for %s; falling back to RapidAPI", loc)
 			}
 		}
-		raw, err := d.ListingsClient.SearchByPostal(req.Context(), body.PostalCode, pagesize, page, body.PropertyType, body.OrderBy)
+		compare := d.Shadow.Begin(shadow.Search{
+			Route:    "search",
+			Location: loc.provider(),
+			Params:   map[string]any{"page": page, "pageSize": pagesize, "propertyType": body.PropertyType, "orderBy": body.OrderBy},
+			Fetch: func(ctx context.Context, c *attom.Client) ([]attom.PropertyCard, error) {
+				raw, err := c.SearchByPostal(ctx, loc.provider(), pagesize, page, body.PropertyType, body.OrderBy)
+				if err != nil {
+					return nil, err
+				}
+				return attom.MapSearchPayloadToCards(raw)
+			},
+		})
+		raw, err := d.ListingsClient.SearchByPostal(req.Context(), loc.provider(), pagesize, page, body.PropertyType, body.OrderBy)
 		if err != nil {
-			if errors.Is(err, attom.ErrDailyLimitExceeded) {
-				render.Status(req, http.StatusTooManyRequests)
-				_ = json.NewEncoder(w).Encode(map[string]any{"error": "provider_quota", "detail": "daily quota reached"})
+			compare(nil, err)
+			if d.Hydrator != nil && serveDegraded(w, req, d.Hydrator.Store, loc, pagesize, offset, storeFilter(body.PropertyType, body.OrderBy), err) {
 				return
 			}
-			render.Status(req, http.StatusBadGateway)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "upstream_error", "detail": err.Error()})
+			apierror.WriteError(w, req, err, apierror.Upstream)
 			return
 		}
 		cards, err := attom.MapSearchPayloadToCards(raw)
+		compare(cards, err)
 		if err != nil {
-			render.Status(req, http.StatusInternalServerError)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "map_error", "detail": err.Error()})
+			apierror.Write(w, req, apierror.MapError.WithDetail(err.Error()))
 			return
 		}
 		persistCards(req.Context(), d.Hydrator, "search/forsale", raw, cards)
-		log.Printf("[INFO] served postal %s from RapidAPI (%d listings)", body.PostalCode, len(cards))
-		render.JSON(w, req, map[string]any{
+		log.Printf("[INFO] served %s from RapidAPI (%d listings)", loc, len(cards))
+		logger.SetResults(req.Context(), len(cards))
+		setFreshness(w, sourceProvider, time.Now(), false)
+		respond.Rows(w, req, map[string]any{
 			"ok":         true,
 			"count":      len(cards),
-			"properties": cards,
-		})
+			"properties": shapeCards(req, cards),
+		}, "properties")
 		return
 	}
 
 	// Legacy radius fallback
 	if body.Lat == nil || body.Lon == nil {
-		render.Status(req, http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "postalcode_required", "detail": "postalcode is required"})
+		apierror.Write(w, req, apierror.BadRequest("postalcode_required", "postalcode or location (city, state) is required"))
 		return
 	}
 	lat := *body.Lat
@@ -188,19 +208,48 @@ func handleSearchRequest(w http.ResponseWriter, req *http.Request, d SearchDeps,
 	limit := defInt(body.Limit, 40)
 	raw, err := d.ListingsClient.SearchByRadius(req.Context(), lat, lon, radius, limit, 0, 0, 0, 0, "")
 	if err != nil {
-		render.Status(req, http.StatusBadGateway)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "upstream_error", "detail": err.Error()})
+		apierror.WriteError(w, req, err, apierror.Upstream)
 		return
 	}
 	cards, err := attom.MapSearchPayloadToCards(raw)
 	if err != nil {
-		render.Status(req, http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "map_error", "detail": err.Error()})
+		apierror.Write(w, req, apierror.MapError.WithDetail(err.Error()))
 		return
 	}
-	render.JSON(w, req, map[string]any{
+	setFreshness(w, sourceProvider, time.Now(), false)
+	respond.Rows(w, req, map[string]any{
 		"ok":         true,
 		"count":      len(cards),
-		"properties": cards,
-	})
+		"properties": shapeCards(req, cards),
+	}, "properties")
+}
+
+// serveDegraded answers a search the provider refused for quota from what
+// the store has for the location, however stale, marked "degraded": true.
+// It reports whether it wrote a response; for any other error, or when the
+// store has nothing, the caller returns the error as usual.
+func serveDegraded(w http.ResponseWriter, req *http.Request, st store.Listings, loc searchLocation, limit, offset int, f store.ListingFilter, err error) bool {
+	if st == nil || !errors.Is(err, attom.ErrDailyLimitExceeded) {
+		return false
+	}
+	records, serr := loc.fetchRecords(req.Context(), st, limit, offset, f)
+	if serr != nil {
+		log.Printf("[WARN] degraded db lookup failed for %s: %v", loc, serr)
+		return false
+	}
+	if len(records) == 0 {
+		return false
+	}
+	cards := RecordsToCards(records)
+	log.Printf("[WARN] provider quota reached; serving %s from database, degraded (%d listings)", loc, len(cards))
+	logger.SetCache(req.Context(), logger.CacheMiss)
+	logger.SetResults(req.Context(), len(cards))
+	setCardsFreshness(w, cards)
+	respond.Rows(w, req, map[string]any{
+		"ok":         true,
+		"count":      len(cards),
+		"properties": shapeCards(req, cards),
+		"degraded":   true,
+	}, "properties")
+	return true
 }
@@ -0,0 +1,149 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/apierror"
+)
+
+// primaryImageField is a virtual card field holding the first image URL,
+// for clients that want a thumbnail without the full images array.
+const primaryImageField = "primaryImage"
+
+// cardFields are the card JSON fields a client may select with ?fields=.
+var cardFields = func() map[string]bool {
+	out := map[string]bool{primaryImageField: true}
+	t := reflect.TypeOf(attom.PropertyCard{})
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "" && name != "-" {
+			out[name] = true
+		}
+	}
+	return out
+}()
+
+// cardShape is how a client asked for cards to be trimmed: ?fields= keeps
+// only the named fields (plus id), includePhotos=false drops images and
+// includePhotos=primary keeps only the first.
+type cardShape struct {
+	fields       map[string]bool
+	noPhotos     bool
+	primaryPhoto bool
+}
+
+type cardShapeKey struct{}
+
+func parseCardShape(req *http.Request) (*cardShape, *apierror.Error) {
+	q := req.URL.Query()
+	var s cardShape
+	if v := q.Get("includePhotos"); v == "primary" {
+		s.primaryPhoto = true
+	} else if v != "" {
+		include, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, apierror.BadRequest("invalid_include_photos", "includePhotos must be true, false or primary")
+		}
+		s.noPhotos = !include
+	}
+	if v := q.Get("fields"); v != "" {
+		s.fields = map[string]bool{"id": true}
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			if !cardFields[f] {
+				names := make([]string, 0, len(cardFields))
+				for name := range cardFields {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				return nil, apierror.BadRequest("invalid_fields", "unknown field "+f).With("fields", names)
+			}
+			s.fields[f] = true
+		}
+	}
+	if s.fields == nil && !s.noPhotos && !s.primaryPhoto {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// cardShaping validates ?fields= and ?includePhotos= before the handler runs
+// so a bad selection fails without a provider call.
+func cardShaping(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s, apiErr := parseCardShape(req)
+		if apiErr != nil {
+			apierror.Write(w, req, apiErr)
+			return
+		}
+		if s != nil {
+			req = req.WithContext(context.WithValue(req.Context(), cardShapeKey{}, s))
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// primaryPhotoOnly reports whether the request asked for
+// includePhotos=primary, so listing photos need not be fetched beyond each
+// card's first.
+func primaryPhotoOnly(req *http.Request) bool {
+	s, _ := req.Context().Value(cardShapeKey{}).(*cardShape)
+	return s != nil && s.primaryPhoto
+}
+
+// shapeCards applies the request's field selection to cards. Without one the
+// cards are returned as they are.
+func shapeCards(req *http.Request, cards []attom.PropertyCard) any {
+	s, _ := req.Context().Value(cardShapeKey{}).(*cardShape)
+	if s == nil {
+		return cards
+	}
+	out := make([]map[string]json.RawMessage, 0, len(cards))
+	for _, c := range cards {
+		out = append(out, s.apply(c))
+	}
+	return out
+}
+
+// shapeCard is shapeCards for a single card.
+func shapeCard(req *http.Request, card attom.PropertyCard) any {
+	s, _ := req.Context().Value(cardShapeKey{}).(*cardShape)
+	if s == nil {
+		return card
+	}
+	return s.apply(card)
+}
+
+func (s *cardShape) apply(card attom.PropertyCard) map[string]json.RawMessage {
+	var full map[string]json.RawMessage
+	b, _ := json.Marshal(card)
+	_ = json.Unmarshal(b, &full)
+	if s.fields[primaryImageField] && len(card.Images) > 0 {
+		full[primaryImageField], _ = json.Marshal(card.Images[0])
+	}
+	if s.noPhotos {
+		delete(full, "images")
+	}
+	if s.primaryPhoto && len(card.Images) > 1 {
+		full["images"], _ = json.Marshal(card.Images[:1])
+	}
+	if s.fields == nil {
+		return full
+	}
+	out := make(map[string]json.RawMessage, len(s.fields))
+	for name := range s.fields {
+		if v, ok := full[name]; ok {
+			out[name] = v
+		}
+	}
+	return out
+}
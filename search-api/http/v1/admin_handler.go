@@ -0,0 +1,341 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/flags"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type AdminDeps struct {
+	Store *store.Store
+	Flags *flags.Set
+	// Provider answers the store-versus-provider diff.
+	Provider *attom.Client
+	// Enabled opens the admin routes to callers with the admin scope: the
+	// admin token, or an API key or token granting it.
+	Enabled bool
+}
+
+type MergePropertiesRequest struct {
+	TargetKey string `json:"target_key" doc:"Property that keeps the listings"`
+	SourceKey string `json:"source_key" doc:"Property merged away; its key becomes an alias of target_key"`
+	Actor     string `json:"actor,omitempty" doc:"Who requested the change, for the audit log"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type SplitPropertyRequest struct {
+	PropertyKey string   `json:"property_key" doc:"Property the listings are on now"`
+	ListingIDs  []string `json:"listing_ids" doc:"Listings to move, by listing ID"`
+	Address     string   `json:"address" doc:"Correct address of the moved listings"`
+	City        string   `json:"city"`
+	State       string   `json:"state"`
+	Zip         string   `json:"zip"`
+	Actor       string   `json:"actor,omitempty" doc:"Who requested the change, for the audit log"`
+	Reason      string   `json:"reason,omitempty"`
+}
+
+// PropertyMergeDTO is one merge or split audit record.
+type PropertyMergeDTO struct {
+	ID         string          `json:"id"`
+	Op         string          `json:"op"`
+	TargetKey  string          `json:"targetKey"`
+	SourceKey  string          `json:"sourceKey"`
+	ListingIDs []string        `json:"listingIds"`
+	Source     json.RawMessage `json:"source,omitempty" doc:"Merged-away property row"`
+	Actor      string          `json:"actor,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// DeletedRecordDTO is a soft-deleted property or listing.
+type DeletedRecordDTO struct {
+	Kind        string    `json:"kind" doc:"property or listing"`
+	ID          string    `json:"id"`
+	PropertyKey string    `json:"propertyKey"`
+	ListingID   string    `json:"listingId,omitempty"`
+	Address     string    `json:"address"`
+	DeletedAt   time.Time `json:"deletedAt"`
+}
+
+// AuditEntryDTO is one change to a property or listing row.
+type AuditEntryDTO struct {
+	ID        int64           `json:"id"`
+	Table     string          `json:"table"`
+	RowID     string          `json:"rowId"`
+	Action    string          `json:"action" doc:"insert, update, delete, restore or purge"`
+	Actor     string          `json:"actor,omitempty" doc:"API caller, admin or hydrator job that made the change"`
+	RunID     string          `json:"runId,omitempty" doc:"Hydrator run ID"`
+	RequestID string          `json:"requestId,omitempty"`
+	Changes   json.RawMessage `json:"changes" doc:"Changed columns as [old, new] for updates; the row for inserts and purges"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+func RegisterAdmin(r chi.Router, d AdminDeps) {
+	r.Route("/v1/admin", func(r chi.Router) {
+		r.Use(requireAdmin(d.Enabled))
+
+		// POST /v1/admin/properties/merge
+		r.Post("/properties/merge", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			var body MergePropertiesRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				apierror.WriteError(w, req, err, apierror.InvalidJSON)
+				return
+			}
+			if body.TargetKey == "" || body.SourceKey == "" {
+				apierror.Write(w, req, apierror.BadRequest("keys_required", "target_key and source_key are required"))
+				return
+			}
+			actor := adminActor(req, body.Actor)
+			m, err := d.Store.MergeProperties(adminCtx(req, actor), body.TargetKey, body.SourceKey, actor, body.Reason)
+			if err != nil {
+				writeMergeError(w, req, err)
+				return
+			}
+			render.JSON(w, req, map[string]any{"ok": true, "merge": propertyMergeDTO(m)})
+		})
+
+		// POST /v1/admin/properties/split
+		r.Post("/properties/split", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			var body SplitPropertyRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				apierror.WriteError(w, req, err, apierror.InvalidJSON)
+				return
+			}
+			if body.PropertyKey == "" || len(body.ListingIDs) == 0 {
+				apierror.Write(w, req, apierror.BadRequest("listings_required", "property_key and listing_ids are required"))
+				return
+			}
+			if body.Address == "" || body.City == "" || body.State == "" || body.Zip == "" {
+				apierror.Write(w, req, apierror.BadRequest("address_required", "address, city, state and zip are required"))
+				return
+			}
+			line1, city, state, zip, pkey := canon.Canonicalize(body.Address, body.City, body.State, body.Zip)
+			actor := adminActor(req, body.Actor)
+			m, err := d.Store.SplitProperty(adminCtx(req, actor), store.SplitInput{
+				PropertyKey: body.PropertyKey,
+				ListingIDs:  body.ListingIDs,
+				NewKey:      pkey,
+				Address1:    line1,
+				City:        city,
+				State:       state,
+				Zip:         zip,
+				Actor:       actor,
+				Reason:      body.Reason,
+			})
+			if err != nil {
+				writeMergeError(w, req, err)
+				return
+			}
+			render.JSON(w, req, map[string]any{"ok": true, "merge": propertyMergeDTO(m)})
+		})
+
+		// GET /v1/admin/properties/{propertyKey}/merges?limit=50
+		r.Get("/properties/{propertyKey}/merges", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			pkey := chi.URLParam(req, "propertyKey")
+			limit := 50
+			if v, err := strconv.Atoi(req.URL.Query().Get("limit")); err == nil && v > 0 && v <= 200 {
+				limit = v
+			}
+			merges, err := d.Store.PropertyMerges(req.Context(), pkey, limit)
+			if err != nil {
+				apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load merge history"))
+				return
+			}
+			out := make([]PropertyMergeDTO, 0, len(merges))
+			for _, m := range merges {
+				out = append(out, propertyMergeDTO(m))
+			}
+			render.JSON(w, req, map[string]any{"ok": true, "property_key": pkey, "count": len(out), "merges": out})
+		})
+
+		// GET /v1/admin/properties/{propertyKey}/audit?limit=100
+		r.Get("/properties/{propertyKey}/audit", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			pkey := chi.URLParam(req, "propertyKey")
+			limit := 100
+			if v, err := strconv.Atoi(req.URL.Query().Get("limit")); err == nil && v > 0 && v <= 500 {
+				limit = v
+			}
+			entries, err := d.Store.AuditLog(req.Context(), pkey, limit)
+			if err != nil {
+				apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load audit log"))
+				return
+			}
+			out := make([]AuditEntryDTO, 0, len(entries))
+			for _, e := range entries {
+				out = append(out, AuditEntryDTO{
+					ID:        e.ID,
+					Table:     e.Table,
+					RowID:     e.RowID,
+					Action:    e.Action,
+					Actor:     e.Actor.String,
+					RunID:     e.RunID.String,
+					RequestID: e.RequestID.String,
+					Changes:   e.Changes,
+					CreatedAt: e.CreatedAt,
+				})
+			}
+			render.JSON(w, req, map[string]any{"ok": true, "property_key": pkey, "count": len(out), "entries": out})
+		})
+
+		// GET /v1/admin/deleted?kind=property|listing&limit=50
+		r.Get("/deleted", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			q := req.URL.Query()
+			kind := q.Get("kind")
+			if kind != "" && kind != "property" && kind != "listing" {
+				apierror.Write(w, req, apierror.BadRequest("invalid_kind", "kind must be property or listing"))
+				return
+			}
+			limit := 50
+			if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 && v <= 200 {
+				limit = v
+			}
+			recs, err := d.Store.DeletedRecords(req.Context(), kind, limit)
+			if err != nil {
+				apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load deleted records"))
+				return
+			}
+			out := make([]DeletedRecordDTO, 0, len(recs))
+			for _, rec := range recs {
+				out = append(out, DeletedRecordDTO{
+					Kind:        rec.Kind,
+					ID:          rec.ID,
+					PropertyKey: rec.PropertyKey,
+					ListingID:   rec.ListingID.String,
+					Address:     rec.Address,
+					DeletedAt:   rec.DeletedAt,
+				})
+			}
+			render.JSON(w, req, map[string]any{"ok": true, "count": len(out), "deleted": out})
+		})
+
+		r.Get("/usage", usageHandler(d))
+		r.Get("/analytics/top-zips", topZipsHandler(d))
+		r.Get("/analytics/filters", searchFiltersHandler(d))
+		r.Get("/shadow", shadowReportHandler(d))
+		r.Get("/shadow/{id}", shadowResultHandler(d))
+		r.Get("/diff/zips/{zip}", zipDiffHandler(d))
+		registerFlags(r, d)
+
+		// DELETE /v1/admin/properties/{propertyKey}, POST .../restore
+		r.Delete("/properties/{propertyKey}", softDeleteHandler(d, "propertyKey", (*store.Store).SoftDeleteProperty))
+		r.Post("/properties/{propertyKey}/restore", softDeleteHandler(d, "propertyKey", (*store.Store).RestoreProperty))
+		// DELETE /v1/admin/listings/{listingID}, POST .../restore
+		r.Delete("/listings/{listingID}", softDeleteHandler(d, "listingID", (*store.Store).SoftDeleteListing))
+		r.Post("/listings/{listingID}/restore", softDeleteHandler(d, "listingID", (*store.Store).RestoreListing))
+	})
+}
+
+// softDeleteHandler runs a soft delete or restore of the record named by the
+// URL parameter param. ?actor= overrides the audit log actor.
+func softDeleteHandler(d AdminDeps, param string, op func(*store.Store, context.Context, string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		id := chi.URLParam(req, param)
+		err := op(d.Store, adminCtx(req, adminActor(req, req.URL.Query().Get("actor"))), id)
+		switch {
+		case errors.Is(err, store.ErrPropertyNotFound):
+			apierror.Write(w, req, apierror.NotFound("property_not_found", "no property in that state with this key").WithDetail(err.Error()))
+		case errors.Is(err, store.ErrListingNotFound):
+			apierror.Write(w, req, apierror.NotFound("listing_not_found", "no listing in that state with this ID").WithDetail(err.Error()))
+		case err != nil:
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to update record"))
+		default:
+			render.JSON(w, req, map[string]any{"ok": true})
+		}
+	}
+}
+
+// requireAdmin answers 404 on every admin route while they are disabled,
+// so the surface is invisible, and otherwise requires the admin scope.
+func requireAdmin(enabled bool) func(http.Handler) http.Handler {
+	if !enabled {
+		return func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				apierror.Write(w, req, apierror.NotFound("not_found", "not found"))
+			})
+		}
+	}
+	return auth.Require(auth.ScopeAdmin)
+}
+
+func adminActor(req *http.Request, actor string) string {
+	if actor != "" {
+		return actor
+	}
+	if p, ok := auth.FromContext(req.Context()); ok {
+		return p.ID()
+	}
+	return "admin:" + req.RemoteAddr
+}
+
+// adminCtx attributes the request's store writes to actor, keeping the
+// request ID set by the router.
+func adminCtx(req *http.Request, actor string) context.Context {
+	a := store.ActorFrom(req.Context())
+	a.Name = actor
+	return store.WithActor(req.Context(), a)
+}
+
+func writeMergeError(w http.ResponseWriter, req *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrPropertyNotFound):
+		apierror.Write(w, req, apierror.NotFound("property_not_found", "property not found").WithDetail(err.Error()))
+	case errors.Is(err, store.ErrInvalidMerge):
+		apierror.Write(w, req, apierror.New(http.StatusConflict, "invalid_merge", "merge would not change anything or names listings of another property").WithDetail(err.Error()))
+	default:
+		apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to update properties"))
+	}
+}
+
+func propertyMergeDTO(m store.PropertyMerge) PropertyMergeDTO {
+	dto := PropertyMergeDTO{
+		ID:         m.ID,
+		Op:         m.Op,
+		TargetKey:  m.TargetKey,
+		SourceKey:  m.SourceKey,
+		ListingIDs: m.ListingIDs,
+		Source:     m.Source,
+		Actor:      m.Actor,
+		Reason:     m.Reason,
+		CreatedAt:  m.CreatedAt,
+	}
+	if dto.ListingIDs == nil {
+		dto.ListingIDs = []string{}
+	}
+	return dto
+}
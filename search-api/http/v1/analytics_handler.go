@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// ZipSearchDTO is a ZIP's searches over the requested range.
+type ZipSearchDTO struct {
+	Zip           string  `json:"zip"`
+	Searches      int64   `json:"searches"`
+	Results       int64   `json:"results" doc:"Results returned, summed over the searches"`
+	ZeroResults   int64   `json:"zeroResults" doc:"Searches that found nothing"`
+	CacheHits     int64   `json:"cacheHits" doc:"Answered from the cache, fresh or stale"`
+	CacheMisses   int64   `json:"cacheMisses" doc:"Answered from the database"`
+	ProviderReads int64   `json:"providerReads" doc:"Answered from the provider"`
+	AvgResults    float64 `json:"avgResults"`
+}
+
+// SearchFilterDTO is how often one value of a search dimension was used.
+type SearchFilterDTO struct {
+	Value       string `json:"value"`
+	Searches    int64  `json:"searches"`
+	ZeroResults int64  `json:"zeroResults"`
+}
+
+// analyticsQuery parses the from, to, route and limit parameters shared by
+// the analytics routes; the range defaults to the last 7 days.
+func analyticsQuery(w http.ResponseWriter, req *http.Request) (store.SearchAnalyticsQuery, bool) {
+	q := req.URL.Query()
+	now := time.Now().UTC()
+	aq := store.SearchAnalyticsQuery{From: now.AddDate(0, 0, -7), To: now, Route: q.Get("route"), Limit: 50}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &aq.From}, {"to", &aq.To}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := parseSince(v)
+			if err != nil {
+				apierror.Write(w, req, apierror.BadRequest("invalid_"+p.name, p.name+" must be a date (2006-01-02) or RFC 3339 timestamp").WithDetail(err.Error()))
+				return aq, false
+			}
+			*p.dst = t
+		}
+	}
+	if aq.Route != "" && aq.Route != "search" && aq.Route != "listings" {
+		apierror.Write(w, req, apierror.BadRequest("invalid_route", "route must be search or listings"))
+		return aq, false
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			apierror.Write(w, req, apierror.BadRequest("invalid_limit", "limit must be between 1 and 500"))
+			return aq, false
+		}
+		aq.Limit = n
+	}
+	return aq, true
+}
+
+// GET /v1/admin/analytics/top-zips?from=2024-05-01&to=2024-06-01&route=listings&sort=zero_results&limit=20
+func topZipsHandler(d AdminDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		aq, ok := analyticsQuery(w, req)
+		if !ok {
+			return
+		}
+		switch aq.Sort = req.URL.Query().Get("sort"); aq.Sort {
+		case "", store.SearchSortSearches, store.SearchSortZeroResults, store.SearchSortProvider:
+		default:
+			apierror.Write(w, req, apierror.BadRequest("invalid_sort", "sort must be searches, zero_results or provider"))
+			return
+		}
+		zips, err := d.Store.TopSearchZips(req.Context(), aq)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load search analytics"))
+			return
+		}
+		out := make([]ZipSearchDTO, 0, len(zips))
+		for _, z := range zips {
+			dto := ZipSearchDTO{
+				Zip:           z.Zip,
+				Searches:      z.Searches,
+				Results:       z.Results,
+				ZeroResults:   z.ZeroResults,
+				CacheHits:     z.CacheHits,
+				CacheMisses:   z.CacheMisses,
+				ProviderReads: z.ProviderReads,
+			}
+			if z.Searches > 0 {
+				dto.AvgResults = float64(z.Results) / float64(z.Searches)
+			}
+			out = append(out, dto)
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "from": aq.From, "to": aq.To, "count": len(out), "zips": out})
+	}
+}
+
+// GET /v1/admin/analytics/filters?from=2024-05-01&route=listings&limit=10
+func searchFiltersHandler(d AdminDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		aq, ok := analyticsQuery(w, req)
+		if !ok {
+			return
+		}
+		stats, err := d.Store.SearchFilterStats(req.Context(), aq)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load search analytics"))
+			return
+		}
+		out := map[string][]SearchFilterDTO{"property_type": {}, "orderby": {}, "filter": {}, "city": {}}
+		for _, s := range stats {
+			out[s.Dimension] = append(out[s.Dimension], SearchFilterDTO{Value: s.Value, Searches: s.Searches, ZeroResults: s.ZeroResults})
+		}
+		render.JSON(w, req, map[string]any{
+			"ok": true, "from": aq.From, "to": aq.To,
+			"property_types": out["property_type"], "orders": out["orderby"], "filters": out["filter"], "cities": out["city"],
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type ChangesDeps struct {
+	Store *store.Store
+}
+
+// ChangeDTO is one change-feed entry.
+type ChangeDTO struct {
+	Cursor      string          `json:"cursor" doc:"Position of this change; pass as since to continue after it"`
+	Kind        string          `json:"kind" doc:"property or listing"`
+	RowID       string          `json:"rowId"`
+	PropertyKey string          `json:"propertyKey"`
+	ListingID   string          `json:"listingId,omitempty" doc:"Provider listing ID, for listing changes"`
+	Action      string          `json:"action" doc:"insert, update, delete, restore or purge"`
+	Changes     json.RawMessage `json:"changes" doc:"Changed columns as [old, new] for updates; the row for inserts and purges"`
+	ChangedAt   time.Time       `json:"changedAt"`
+}
+
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+)
+
+func RegisterChanges(r chi.Router, d ChangesDeps) {
+	// GET /v1/changes?since=<cursor>&limit=100
+	r.With(auth.Require(auth.ScopeExport)).Get("/v1/changes", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		q := req.URL.Query()
+		since, err := store.ParseChangeCursor(q.Get("since"))
+		if err != nil {
+			apierror.Write(w, req, apierror.BadRequest("invalid_cursor", "since must be a cursor returned by this feed"))
+			return
+		}
+		limit := defaultChangesLimit
+		if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 && v <= maxChangesLimit {
+			limit = v
+		}
+		changes, err := d.Store.FetchChanges(req.Context(), since, limit)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load changes"))
+			return
+		}
+		out := make([]ChangeDTO, 0, len(changes))
+		next := since
+		for _, c := range changes {
+			out = append(out, ChangeDTO{
+				Cursor:      c.Cursor.String(),
+				Kind:        c.Kind,
+				RowID:       c.RowID,
+				PropertyKey: c.PropertyKey,
+				ListingID:   c.ListingID.String,
+				Action:      c.Action,
+				Changes:     c.Changes,
+				ChangedAt:   c.ChangedAt,
+			})
+			next = c.Cursor
+		}
+		// A full page means more may be waiting; otherwise the client has
+		// caught up and polls again later from next_cursor.
+		render.JSON(w, req, map[string]any{
+			"ok":          true,
+			"count":       len(out),
+			"changes":     out,
+			"next_cursor": next.String(),
+			"has_more":    len(out) == limit,
+		})
+	})
+}
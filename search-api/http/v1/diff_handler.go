@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/storediff"
+)
+
+// GET /v1/admin/diff/zips/{zip}?pages=5
+//
+// Reads the ZIP from the store and the live provider and reports where
+// they disagree. It spends up to pages provider calls.
+func zipDiffHandler(d AdminDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		if d.Provider == nil {
+			apierror.Write(w, req, apierror.New(http.StatusServiceUnavailable, "provider_unavailable", "provider client is not configured"))
+			return
+		}
+		zip := chi.URLParam(req, "zip")
+		if !canon.IsZIP(zip) {
+			apierror.Write(w, req, apierror.BadRequest("invalid_zip", "zip must be 5 digits").With("zip", zip))
+			return
+		}
+		differ := &storediff.Differ{Store: d.Store, Client: d.Provider}
+		if v := req.URL.Query().Get("pages"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 20 {
+				apierror.Write(w, req, apierror.BadRequest("invalid_pages", "pages must be between 1 and 20"))
+				return
+			}
+			differ.MaxPages = n
+		}
+		report, err := differ.Diff(req.Context(), zip)
+		switch {
+		case errors.Is(err, storediff.ErrStore):
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load listings"))
+			return
+		case err != nil:
+			apierror.WriteError(w, req, err, apierror.Upstream)
+			return
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "agrees": report.Agrees(), "diff": report})
+	}
+}
@@ -0,0 +1,236 @@
+package v1
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type ExportDeps struct {
+	Store *store.Store
+}
+
+// ExportRow is one exported listing. In CSV, PhotoURLs is joined with "|".
+type ExportRow struct {
+	ListingID    string    `json:"listingId"`
+	PropertyKey  string    `json:"propertyKey"`
+	Address      string    `json:"address"`
+	City         string    `json:"city"`
+	State        string    `json:"state"`
+	Zip          string    `json:"zip"`
+	Lat          *float64  `json:"lat"`
+	Lon          *float64  `json:"lon"`
+	Status       string    `json:"status"`
+	ListDate     *string   `json:"listDate"`
+	ListPrice    *float64  `json:"listPrice"`
+	Beds         *int64    `json:"beds"`
+	Baths        *float64  `json:"baths"`
+	Sqft         *int64    `json:"sqft"`
+	PropertyType string    `json:"propertyType"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	PhotoURLs    []string  `json:"photoUrls"`
+}
+
+var exportCSVHeader = []string{
+	"listing_id", "property_key", "address", "city", "state", "zip", "lat", "lon",
+	"status", "list_date", "list_price", "beds", "baths", "sqft", "property_type", "updated_at", "photo_urls",
+}
+
+func RegisterExport(r chi.Router, d ExportDeps) {
+	// GET /v1/export/listings?zip=94110&since=2024-01-01&format=csv|ndjson
+	r.With(auth.Require(auth.ScopeExport)).Get("/v1/export/listings", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		q := req.URL.Query()
+		format := strings.ToLower(q.Get("format"))
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "ndjson" {
+			apierror.Write(w, req, apierror.BadRequest("invalid_format", "format must be csv or ndjson"))
+			return
+		}
+		var since time.Time
+		if v := q.Get("since"); v != "" {
+			t, err := parseSince(v)
+			if err != nil {
+				apierror.Write(w, req, apierror.BadRequest("invalid_since", "since must be a date (2006-01-02) or RFC 3339 timestamp").WithDetail(err.Error()))
+				return
+			}
+			since = t
+		}
+		zip := strings.TrimSpace(q.Get("zip"))
+
+		filename := "listings"
+		if zip != "" {
+			filename += "-" + zip
+		}
+		filename += "." + format
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		var out io.Writer = w
+		if strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			out = gz
+		}
+		enc := newExportEncoder(format, out)
+		flusher, _ := w.(http.Flusher)
+		w.WriteHeader(http.StatusOK)
+
+		n := 0
+		err := d.Store.ExportListings(req.Context(), store.ExportQuery{Zip: zip, Since: since}, func(rec store.ExportRecord) error {
+			if err := enc.write(exportRowFromRecord(rec)); err != nil {
+				return err
+			}
+			n++
+			if n%exportFlushEvery == 0 {
+				if err := enc.flush(); err != nil {
+					return err
+				}
+				if gz, ok := out.(*gzip.Writer); ok {
+					_ = gz.Flush()
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return nil
+		})
+		if ferr := enc.flush(); err == nil {
+			err = ferr
+		}
+		if err != nil {
+			// Headers are already sent; the truncated body is the only signal.
+			log.Printf("[WARN] listings export aborted after %d rows (zip=%q): %v", n, zip, err)
+		}
+	})
+}
+
+const exportFlushEvery = 500
+
+func parseSince(v string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+type exportEncoder struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newExportEncoder(format string, w io.Writer) *exportEncoder {
+	if format == "csv" {
+		cw := csv.NewWriter(w)
+		_ = cw.Write(exportCSVHeader)
+		return &exportEncoder{csv: cw}
+	}
+	return &exportEncoder{json: json.NewEncoder(w)}
+}
+
+func (e *exportEncoder) write(row ExportRow) error {
+	if e.json != nil {
+		return e.json.Encode(row)
+	}
+	return e.csv.Write([]string{
+		row.ListingID, row.PropertyKey, row.Address, row.City, row.State, row.Zip,
+		optFloat(row.Lat), optFloat(row.Lon), row.Status, optStr(row.ListDate),
+		optFloat(row.ListPrice), optInt(row.Beds), optFloat(row.Baths), optInt(row.Sqft),
+		row.PropertyType, row.UpdatedAt.UTC().Format(time.RFC3339), strings.Join(row.PhotoURLs, "|"),
+	})
+}
+
+func (e *exportEncoder) flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func exportRowFromRecord(rec store.ExportRecord) ExportRow {
+	row := ExportRow{
+		ListingID:    rec.ListingExternalID.String,
+		PropertyKey:  rec.PropertyKey,
+		Address:      rec.AddressLine1,
+		City:         rec.City,
+		State:        rec.State,
+		Zip:          rec.Zip,
+		Status:       rec.Status,
+		PropertyType: rec.PropertyType.String,
+		UpdatedAt:    rec.UpdatedAt,
+		PhotoURLs:    rec.Photos,
+	}
+	if row.ListingID == "" {
+		row.ListingID = rec.ListingID
+	}
+	if row.PhotoURLs == nil {
+		row.PhotoURLs = []string{}
+	}
+	if rec.Lat.Valid {
+		row.Lat = &rec.Lat.Float64
+	}
+	if rec.Lon.Valid {
+		row.Lon = &rec.Lon.Float64
+	}
+	if rec.ListDate.Valid {
+		d := rec.ListDate.Time.UTC().Format("2006-01-02")
+		row.ListDate = &d
+	}
+	if rec.ListPrice.Valid {
+		row.ListPrice = &rec.ListPrice.Float64
+	}
+	if rec.Beds.Valid {
+		row.Beds = &rec.Beds.Int64
+	}
+	if rec.Baths.Valid {
+		row.Baths = &rec.Baths.Float64
+	}
+	if rec.Sqft.Valid {
+		row.Sqft = &rec.Sqft.Int64
+	}
+	return row
+}
+
+func optFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+func optInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+func optStr(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
@@ -0,0 +1,219 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/flags"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+var flagName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// FlagDTO is a feature flag's configuration and, when a consumer was
+// asked about, its value for that consumer.
+type FlagDTO struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Known       bool            `json:"known" doc:"Checked by this version of the API"`
+	Default     bool            `json:"default"`
+	Env         *int            `json:"env,omitempty" doc:"Rollout percentage set by FLAGS"`
+	Percent     *int            `json:"percent,omitempty" doc:"Stored rollout percentage; overrides env and default"`
+	Overrides   map[string]bool `json:"overrides" doc:"Consumers with the flag forced on or off"`
+	UpdatedBy   string          `json:"updatedBy,omitempty"`
+	UpdatedAt   *time.Time      `json:"updatedAt,omitempty"`
+	Consumer    string          `json:"consumer,omitempty"`
+	Enabled     *bool           `json:"enabled,omitempty" doc:"Value for consumer"`
+	Source      string          `json:"source,omitempty" doc:"What decided enabled: override, rollout, env or default"`
+}
+
+// SetFlagRequest sets a flag's rollout; give percent or enabled.
+type SetFlagRequest struct {
+	Percent     *int   `json:"percent,omitempty" doc:"Share of consumers with the flag on, 0-100"`
+	Enabled     *bool  `json:"enabled,omitempty" doc:"Shorthand for percent 100 or 0"`
+	Description string `json:"description,omitempty"`
+}
+
+// FlagOverrideRequest forces a flag on or off for one consumer.
+type FlagOverrideRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func flagDTO(fs *flags.Set, name, consumer string) FlagDTO {
+	def := flags.Known[name]
+	_, known := flags.Known[name]
+	out := FlagDTO{Name: name, Description: def.Description, Known: known, Default: def.Default, Overrides: map[string]bool{}}
+	if p, ok := fs.Env[name]; ok {
+		out.Env = &p
+	}
+	if rule, ok := fs.Rules()[name]; ok {
+		if rule.Percent >= 0 {
+			out.Percent = &rule.Percent
+			out.UpdatedBy = rule.UpdatedBy
+			out.UpdatedAt = &rule.UpdatedAt
+		}
+		if rule.Description != "" {
+			out.Description = rule.Description
+		}
+		out.Overrides = rule.Overrides
+	}
+	if consumer != "" {
+		st := fs.Evaluate(name, consumer)
+		out.Consumer, out.Enabled, out.Source = consumer, &st.Enabled, st.Source
+	}
+	return out
+}
+
+func registerFlags(r chi.Router, d AdminDeps) {
+	fs := d.Flags
+	if fs == nil {
+		fs = &flags.Set{}
+	}
+	// The store is needed to change flags; reading them works from the
+	// environment and defaults alone.
+	writable := func(w http.ResponseWriter, req *http.Request) (string, bool) {
+		if d.Store == nil || d.Flags == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return "", false
+		}
+		name := chi.URLParam(req, "name")
+		if !flagName.MatchString(name) {
+			apierror.Write(w, req, apierror.BadRequest("invalid_flag", "flag names are lowercase letters, digits, dots, dashes and underscores"))
+			return "", false
+		}
+		return name, true
+	}
+	changed := func(w http.ResponseWriter, req *http.Request, name string) {
+		if err := d.Flags.Changed(req.Context()); err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "flag saved but not reloaded"))
+			return
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "flag": flagDTO(d.Flags, name, "")})
+	}
+
+	// GET /v1/admin/flags?consumer=key:mobile
+	r.Get("/flags", func(w http.ResponseWriter, req *http.Request) {
+		consumer := req.URL.Query().Get("consumer")
+		names := map[string]bool{}
+		for name := range flags.Known {
+			names[name] = true
+		}
+		for name := range fs.Rules() {
+			names[name] = true
+		}
+		for name := range fs.Env {
+			names[name] = true
+		}
+		out := make([]FlagDTO, 0, len(names))
+		for name := range names {
+			out = append(out, flagDTO(fs, name, consumer))
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+		render.JSON(w, req, map[string]any{"ok": true, "count": len(out), "flags": out})
+	})
+
+	// GET /v1/admin/flags/{name}?consumer=key:mobile
+	r.Get("/flags/{name}", func(w http.ResponseWriter, req *http.Request) {
+		render.JSON(w, req, map[string]any{"ok": true, "flag": flagDTO(fs, chi.URLParam(req, "name"), req.URL.Query().Get("consumer"))})
+	})
+
+	// PUT /v1/admin/flags/{name} {"percent": 25}
+	r.Put("/flags/{name}", func(w http.ResponseWriter, req *http.Request) {
+		name, ok := writable(w, req)
+		if !ok {
+			return
+		}
+		var body SetFlagRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			apierror.WriteError(w, req, err, apierror.InvalidJSON)
+			return
+		}
+		var percent int
+		switch {
+		case body.Percent != nil && body.Enabled != nil:
+			apierror.Write(w, req, apierror.BadRequest("invalid_rollout", "give percent or enabled, not both"))
+			return
+		case body.Percent != nil:
+			if *body.Percent < 0 || *body.Percent > 100 {
+				apierror.Write(w, req, apierror.BadRequest("invalid_rollout", "percent must be between 0 and 100"))
+				return
+			}
+			percent = *body.Percent
+		case body.Enabled != nil:
+			if *body.Enabled {
+				percent = 100
+			}
+		default:
+			apierror.Write(w, req, apierror.BadRequest("invalid_rollout", "percent or enabled is required"))
+			return
+		}
+		if err := d.Store.SetFeatureFlag(req.Context(), name, percent, body.Description, adminActor(req, "")); err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to save flag"))
+			return
+		}
+		changed(w, req, name)
+	})
+
+	// DELETE /v1/admin/flags/{name} returns the flag to env or its default.
+	r.Delete("/flags/{name}", func(w http.ResponseWriter, req *http.Request) {
+		name, ok := writable(w, req)
+		if !ok {
+			return
+		}
+		if err := d.Store.DeleteFeatureFlag(req.Context(), name); err != nil {
+			writeFlagError(w, req, err)
+			return
+		}
+		changed(w, req, name)
+	})
+
+	// PUT /v1/admin/flags/{name}/overrides/{consumer} {"enabled": true}
+	r.Put("/flags/{name}/overrides/{consumer}", func(w http.ResponseWriter, req *http.Request) {
+		name, ok := writable(w, req)
+		if !ok {
+			return
+		}
+		var body FlagOverrideRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			apierror.WriteError(w, req, err, apierror.InvalidJSON)
+			return
+		}
+		if body.Enabled == nil {
+			apierror.Write(w, req, apierror.BadRequest("enabled_required", "enabled is required"))
+			return
+		}
+		if err := d.Store.SetFlagOverride(req.Context(), name, chi.URLParam(req, "consumer"), *body.Enabled, adminActor(req, "")); err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to save override"))
+			return
+		}
+		changed(w, req, name)
+	})
+
+	// DELETE /v1/admin/flags/{name}/overrides/{consumer}
+	r.Delete("/flags/{name}/overrides/{consumer}", func(w http.ResponseWriter, req *http.Request) {
+		name, ok := writable(w, req)
+		if !ok {
+			return
+		}
+		if err := d.Store.DeleteFlagOverride(req.Context(), name, chi.URLParam(req, "consumer")); err != nil {
+			writeFlagError(w, req, err)
+			return
+		}
+		changed(w, req, name)
+	})
+}
+
+func writeFlagError(w http.ResponseWriter, req *http.Request, err error) {
+	if errors.Is(err, store.ErrFlagNotFound) {
+		apierror.Write(w, req, apierror.NotFound("flag_not_found", "no stored rule for that flag").WithDetail(err.Error()))
+		return
+	}
+	apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to update flag"))
+}
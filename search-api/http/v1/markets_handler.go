@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+const maxTrendWindow = 730 * 24 * time.Hour
+
+type MarketsDeps struct {
+	Store *store.Store
+}
+
+// TrendPoint is one day of a ZIP's market series.
+type TrendPoint struct {
+	Day         string   `json:"day"`
+	MedianPrice *float64 `json:"medianPrice"`
+	ActiveCount int      `json:"activeCount"`
+	NewCount    int      `json:"newCount"`
+	SoldCount   int      `json:"soldCount"`
+}
+
+func RegisterMarkets(r chi.Router, d MarketsDeps) {
+	// GET /v1/markets/{zip}/trends?window=90d
+	r.Get("/v1/markets/{zip}/trends", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		zip := chi.URLParam(req, "zip")
+		windowParam := req.URL.Query().Get("window")
+		if windowParam == "" {
+			windowParam = "90d"
+		}
+		window, err := ParseWindow(windowParam)
+		if err != nil {
+			apierror.Write(w, req, apierror.BadRequest("invalid_window", "window must look like 90d, 12w or 1y").WithDetail(err.Error()))
+			return
+		}
+		stats, err := d.Store.FetchMarketTrends(req.Context(), zip, time.Now().Add(-window))
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load market trends"))
+			return
+		}
+		series := make([]TrendPoint, 0, len(stats))
+		for _, s := range stats {
+			pt := TrendPoint{
+				Day:         s.Day.Format("2006-01-02"),
+				ActiveCount: s.ActiveCount,
+				NewCount:    s.NewCount,
+				SoldCount:   s.SoldCount,
+			}
+			if s.MedianPrice.Valid {
+				v := s.MedianPrice.Float64
+				pt.MedianPrice = &v
+			}
+			series = append(series, pt)
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "zip": zip, "window": windowParam, "count": len(series), "series": series})
+	})
+}
+
+// ParseWindow accepts day/week/year suffixes ("90d", "12w", "1y") in
+// addition to Go durations.
+func ParseWindow(v string) (time.Duration, error) {
+	v = strings.TrimSpace(strings.ToLower(v))
+	var window time.Duration
+	if n := len(v); n > 1 && strings.ContainsRune("dwy", rune(v[n-1])) {
+		count, err := strconv.Atoi(v[:n-1])
+		if err != nil || count <= 0 {
+			return 0, fmt.Errorf("invalid window %q", v)
+		}
+		unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour, 'y': 365 * 24 * time.Hour}[v[n-1]]
+		window = time.Duration(count) * unit
+	} else {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid window %q", v)
+		}
+		window = d
+	}
+	if window > maxTrendWindow {
+		return 0, fmt.Errorf("window exceeds %d days", int(maxTrendWindow.Hours()/24))
+	}
+	return window, nil
+}
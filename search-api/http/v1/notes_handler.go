@@ -0,0 +1,172 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	httpapi "github.com/yourorg/search-api/http"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type NotesDeps struct {
+	Store *store.Store
+}
+
+// NoteRequest writes a note. On PATCH, omitted fields are left as they are.
+type NoteRequest struct {
+	Body   *string  `json:"body,omitempty" doc:"Free text, up to 10000 characters"`
+	Labels []string `json:"labels,omitempty" doc:"Short tags such as needs-review; lower-cased and deduplicated"`
+	Author string   `json:"author,omitempty" doc:"Person writing the note when several share a key; defaults to the key or token subject. Ignored on PATCH."`
+}
+
+const (
+	maxNoteBody   = 10000
+	maxNoteLabels = 20
+)
+
+// validate normalizes the labels and checks the sizes.
+func (b *NoteRequest) validate() *apierror.Error {
+	if b.Body != nil && len(*b.Body) > maxNoteBody {
+		return apierror.BadRequest("body_too_long", "body must be at most 10000 characters")
+	}
+	if b.Labels != nil {
+		labels := make([]string, 0, len(b.Labels))
+		for _, l := range b.Labels {
+			if l = strings.ToLower(strings.TrimSpace(l)); l != "" && !slices.Contains(labels, l) {
+				labels = append(labels, l)
+			}
+		}
+		if len(labels) > maxNoteLabels {
+			return apierror.BadRequest("too_many_labels", "at most 20 labels are allowed")
+		}
+		b.Labels = labels
+	}
+	return nil
+}
+
+// RegisterNotes adds the internal notes routes. Notes belong to the caller's
+// API key or token subject, so callers only ever see their own.
+func RegisterNotes(r chi.Router, d NotesDeps) {
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Require(auth.ScopeNotes))
+
+		// GET, POST /v1/properties/{propertyKey}/notes
+		r.Get("/v1/properties/{propertyKey}/notes", listNotesHandler(d, store.NoteProperty, "propertyKey"))
+		r.Post("/v1/properties/{propertyKey}/notes", addNoteHandler(d, store.NoteProperty, "propertyKey"))
+		// GET, POST /v1/listings/{listingID}/notes
+		r.Get("/v1/listings/{listingID}/notes", listNotesHandler(d, store.NoteListing, "listingID"))
+		r.Post("/v1/listings/{listingID}/notes", addNoteHandler(d, store.NoteListing, "listingID"))
+
+		// PATCH /v1/notes/{noteID} {"body": "...", "labels": [...]}
+		r.Patch("/v1/notes/{noteID}", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			var body NoteRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				apierror.WriteError(w, req, err, apierror.InvalidJSON)
+				return
+			}
+			if apiErr := body.validate(); apiErr != nil {
+				apierror.Write(w, req, apiErr)
+				return
+			}
+			n, err := d.Store.UpdateNote(req.Context(), callerID(req), chi.URLParam(req, "noteID"), body.Body, body.Labels)
+			if err != nil {
+				writeNoteError(w, req, err)
+				return
+			}
+			render.JSON(w, req, map[string]any{"ok": true, "note": httpapi.NotesToDTOs([]store.Note{n})[0]})
+		})
+
+		// DELETE /v1/notes/{noteID}
+		r.Delete("/v1/notes/{noteID}", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			if err := d.Store.DeleteNote(req.Context(), callerID(req), chi.URLParam(req, "noteID")); err != nil {
+				writeNoteError(w, req, err)
+				return
+			}
+			render.JSON(w, req, map[string]any{"ok": true})
+		})
+	})
+}
+
+func listNotesHandler(d NotesDeps, kind, param string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		target := chi.URLParam(req, param)
+		notes, err := d.Store.Notes(req.Context(), callerID(req), kind, target)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load notes"))
+			return
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "kind": kind, "target": target, "count": len(notes), "notes": httpapi.NotesToDTOs(notes)})
+	}
+}
+
+func addNoteHandler(d NotesDeps, kind, param string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		var body NoteRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			apierror.WriteError(w, req, err, apierror.InvalidJSON)
+			return
+		}
+		if apiErr := body.validate(); apiErr != nil {
+			apierror.Write(w, req, apiErr)
+			return
+		}
+		if (body.Body == nil || strings.TrimSpace(*body.Body) == "") && len(body.Labels) == 0 {
+			apierror.Write(w, req, apierror.BadRequest("note_required", "body or labels is required"))
+			return
+		}
+		n := store.Note{Owner: callerID(req), Kind: kind, Target: chi.URLParam(req, param), Labels: body.Labels, Author: body.Author}
+		if body.Body != nil {
+			n.Body = *body.Body
+		}
+		n, err := d.Store.AddNote(req.Context(), n)
+		if err != nil {
+			writeNoteError(w, req, err)
+			return
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "note": httpapi.NotesToDTOs([]store.Note{n})[0]})
+	}
+}
+
+// callerID is the caller that personal data such as notes and saved
+// searches belong to; auth.Require of a personal scope guarantees there is
+// one.
+func callerID(req *http.Request) string {
+	p, _ := auth.FromContext(req.Context())
+	return p.ID()
+}
+
+func writeNoteError(w http.ResponseWriter, req *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrPropertyNotFound):
+		apierror.Write(w, req, apierror.NotFound("property_not_found", "property not found").WithDetail(err.Error()))
+	case errors.Is(err, store.ErrListingNotFound):
+		apierror.Write(w, req, apierror.NotFound("listing_not_found", "listing not found").WithDetail(err.Error()))
+	case errors.Is(err, store.ErrNoteNotFound):
+		apierror.Write(w, req, apierror.NotFound("note_not_found", "note not found").WithDetail(err.Error()))
+	default:
+		apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to update notes"))
+	}
+}
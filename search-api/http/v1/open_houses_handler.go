@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type OpenHousesDeps struct {
+	Store *store.Store
+}
+
+// OpenHouseDTO is the wire shape for both open house endpoints; listing
+// fields are only set on the by-ZIP listing.
+type OpenHouseDTO struct {
+	ListingID   string    `json:"listingId,omitempty"`
+	PropertyKey string    `json:"propertyKey,omitempty"`
+	Address     string    `json:"address,omitempty"`
+	City        string    `json:"city,omitempty"`
+	State       string    `json:"state,omitempty"`
+	Zip         string    `json:"zip,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Description string    `json:"description,omitempty"`
+}
+
+func RegisterOpenHouses(r chi.Router, d OpenHousesDeps) {
+	// GET /v1/listings/{listingID}/open-houses?include_past=true
+	r.Get("/v1/listings/{listingID}/open-houses", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		listingID := chi.URLParam(req, "listingID")
+		includePast, _ := strconv.ParseBool(req.URL.Query().Get("include_past"))
+		events, err := d.Store.FetchOpenHouses(req.Context(), listingID, includePast)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load open houses"))
+			return
+		}
+		out := make([]OpenHouseDTO, 0, len(events))
+		for _, ev := range events {
+			out = append(out, OpenHouseDTO{Start: ev.Start, End: ev.End, Description: ev.Description})
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "listing_id": listingID, "count": len(out), "open_houses": out})
+	})
+
+	// GET /v1/open-houses?zip=94110&days=7&limit=50
+	r.Get("/v1/open-houses", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		q := req.URL.Query()
+		zip := q.Get("zip")
+		if zip == "" {
+			apierror.Write(w, req, apierror.BadRequest("zip_required", "zip is required"))
+			return
+		}
+		days := 7
+		if v, err := strconv.Atoi(q.Get("days")); err == nil && v > 0 && v <= 90 {
+			days = v
+		}
+		limit := 50
+		if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 && v <= 200 {
+			limit = v
+		}
+		now := time.Now()
+		events, err := d.Store.FetchUpcomingOpenHousesByPostal(req.Context(), zip, now, now.AddDate(0, 0, days), limit)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load open houses"))
+			return
+		}
+		out := make([]OpenHouseDTO, 0, len(events))
+		for _, ev := range events {
+			out = append(out, OpenHouseDTO{
+				ListingID:   ev.ListingID,
+				PropertyKey: ev.PropertyKey,
+				Address:     ev.AddressLine1,
+				City:        ev.City,
+				State:       ev.State,
+				Zip:         ev.Zip,
+				Start:       ev.Start,
+				End:         ev.End,
+				Description: ev.Description,
+			})
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "zip": zip, "days": days, "count": len(out), "open_houses": out})
+	})
+}
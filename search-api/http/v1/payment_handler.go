@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/payment"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type PaymentDeps struct {
+	Store *store.Store
+	// Defaults fill any input the caller leaves out.
+	Defaults payment.Assumptions
+}
+
+// PaymentEstimateDTO is the monthly cost breakdown of buying a listing.
+type PaymentEstimateDTO struct {
+	Price                float64 `json:"price"`
+	DownPayment          float64 `json:"downPayment"`
+	LoanAmount           float64 `json:"loanAmount"`
+	PrincipalAndInterest float64 `json:"principalAndInterest"`
+	Tax                  float64 `json:"tax"`
+	TaxSource            string  `json:"taxSource" doc:"assessment when taken from the property's tax record, otherwise estimate"`
+	Insurance            float64 `json:"insurance"`
+	HOA                  float64 `json:"hoa"`
+	Total                float64 `json:"total"`
+}
+
+// PaymentAssumptionsDTO echoes the inputs an estimate was computed with.
+type PaymentAssumptionsDTO struct {
+	InterestRate   float64 `json:"interestRate"`
+	DownPaymentPct float64 `json:"downPaymentPct"`
+	TermYears      int     `json:"termYears"`
+	TaxRate        float64 `json:"taxRate"`
+	InsuranceRate  float64 `json:"insuranceRate"`
+	HOAMonthly     float64 `json:"hoaMonthly"`
+}
+
+func RegisterPayments(r chi.Router, d PaymentDeps) {
+	// GET /v1/listings/{listingID}/payment-estimate?rate=6.5&down_payment_pct=10&term_years=15
+	r.Get("/v1/listings/{listingID}/payment-estimate", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		listingID := chi.URLParam(req, "listingID")
+		q := req.URL.Query()
+		a := d.Defaults
+		var price, downPayment float64
+		for _, p := range []struct {
+			name string
+			dst  *float64
+		}{
+			{"price", &price},
+			{"rate", &a.InterestRate},
+			{"down_payment", &downPayment},
+			{"down_payment_pct", &a.DownPaymentPct},
+			{"tax_rate", &a.TaxRate},
+			{"insurance_rate", &a.InsuranceRate},
+			{"hoa", &a.HOAMonthly},
+		} {
+			v := q.Get(p.name)
+			if v == "" {
+				continue
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				apierror.Write(w, req, apierror.BadRequest("invalid_"+p.name, p.name+" must be a number"))
+				return
+			}
+			*p.dst = f
+		}
+		if q.Get("price") != "" && price <= 0 {
+			apierror.Write(w, req, apierror.BadRequest("invalid_price", "price must be positive"))
+			return
+		}
+		if v := q.Get("term_years"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				apierror.Write(w, req, apierror.BadRequest("invalid_term_years", "term_years must be an integer"))
+				return
+			}
+			a.TermYears = n
+		}
+
+		rec, err := d.Store.FetchListingDetail(req.Context(), listingID)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load listing"))
+			return
+		}
+		if rec == nil {
+			apierror.Write(w, req, apierror.NotFound("not_found", "listing not found").With("listing_id", listingID))
+			return
+		}
+		if price <= 0 {
+			if !rec.ListPrice.Valid || rec.ListPrice.Float64 <= 0 {
+				apierror.Write(w, req, apierror.BadRequest("price_required", "listing has no list price; pass price"))
+				return
+			}
+			price = rec.ListPrice.Float64
+		}
+		// An absolute down payment wins over the percentage.
+		if downPayment > 0 {
+			a.DownPaymentPct = downPayment / price * 100
+		}
+		if err := a.Validate(); err != nil {
+			apierror.Write(w, req, apierror.BadRequest("invalid_assumptions", err.Error()))
+			return
+		}
+
+		var annualTax float64
+		if h, err := d.Store.FetchPropertyHistory(req.Context(), rec.PropertyKey); err != nil {
+			log.Printf("[WARN] tax history unavailable for listing %s: %v", listingID, err)
+		} else if h != nil && len(h.Assessments) > 0 && h.Assessments[0].Tax.Valid {
+			annualTax = h.Assessments[0].Tax.Float64
+		}
+
+		est := payment.Calculate(price, annualTax, a)
+		taxSource := "estimate"
+		if est.TaxFromAssessment {
+			taxSource = "assessment"
+		}
+		render.JSON(w, req, map[string]any{
+			"ok":           true,
+			"listing_id":   listingID,
+			"property_key": rec.PropertyKey,
+			"estimate": PaymentEstimateDTO{
+				Price:                est.Price,
+				DownPayment:          est.DownPayment,
+				LoanAmount:           est.LoanAmount,
+				PrincipalAndInterest: est.PrincipalAndInterest,
+				Tax:                  est.Tax,
+				TaxSource:            taxSource,
+				Insurance:            est.Insurance,
+				HOA:                  est.HOA,
+				Total:                est.Total,
+			},
+			"assumptions": PaymentAssumptionsDTO{
+				InterestRate:   a.InterestRate,
+				DownPaymentPct: a.DownPaymentPct,
+				TermYears:      a.TermYears,
+				TaxRate:        a.TaxRate,
+				InsuranceRate:  a.InsuranceRate,
+				HOAMonthly:     a.HOAMonthly,
+			},
+		})
+	})
+}
@@ -0,0 +1,108 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	httpapi "github.com/yourorg/search-api/http"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type PhotosDeps struct {
+	Store *store.Store
+}
+
+// PhotoReuseDTO is another listing showing some of the same images.
+type PhotoReuseDTO struct {
+	ListingID    string    `json:"listingId"`
+	PropertyKey  string    `json:"propertyKey"`
+	Provider     string    `json:"provider"`
+	Status       string    `json:"status"`
+	SameProperty bool      `json:"sameProperty"`
+	SharedPhotos int       `json:"sharedPhotos"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	Deleted      bool      `json:"deleted"`
+}
+
+// ArrangePhotosRequest curates a listing's photo order.
+type ArrangePhotosRequest struct {
+	Order   []string `json:"order,omitempty" doc:"Photo URLs to show first, in this order; the listing's other photos follow in their current order"`
+	Primary string   `json:"primary,omitempty" doc:"Photo URL to make the primary (cover) photo; it is moved to the front"`
+}
+
+func RegisterPhotos(r chi.Router, d PhotosDeps) {
+	// PATCH /v1/listings/{listingID}/photos {"order": [...], "primary": "..."}
+	r.With(auth.Require(auth.ScopeAdmin)).Patch("/v1/listings/{listingID}/photos", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		var body ArrangePhotosRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			apierror.WriteError(w, req, err, apierror.InvalidJSON)
+			return
+		}
+		if len(body.Order) == 0 && body.Primary == "" {
+			apierror.Write(w, req, apierror.BadRequest("arrangement_required", "order or primary is required"))
+			return
+		}
+		listingID := chi.URLParam(req, "listingID")
+		err := d.Store.ArrangeListingPhotos(req.Context(), listingID, store.PhotoArrangement{Order: body.Order, Primary: body.Primary})
+		switch {
+		case errors.Is(err, store.ErrListingNotFound):
+			apierror.Write(w, req, apierror.NotFound("listing_not_found", "listing not found").With("listing_id", listingID))
+			return
+		case errors.Is(err, store.ErrPhotoNotFound):
+			apierror.Write(w, req, apierror.BadRequest("photo_not_found", "photo is not one of the listing's photos").WithDetail(err.Error()))
+			return
+		case err != nil:
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to arrange photos"))
+			return
+		}
+		photos, err := d.Store.FetchListingPhotoAssets(req.Context(), listingID)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load photos"))
+			return
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "listing_id": listingID, "count": len(photos), "photos": httpapi.PhotosToDTOs(photos)})
+	})
+
+	// GET /v1/listings/{listingID}/photo-reuse?limit=20
+	r.Get("/v1/listings/{listingID}/photo-reuse", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		listingID := chi.URLParam(req, "listingID")
+		limit := 20
+		if v, err := strconv.Atoi(req.URL.Query().Get("limit")); err == nil && v > 0 && v <= 100 {
+			limit = v
+		}
+		pkey, err := d.Store.LookupPropertyKeyByListing(req.Context(), listingID)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load listing"))
+			return
+		}
+		if pkey == "" {
+			apierror.Write(w, req, apierror.NotFound("not_found", "listing not found").With("listing_id", listingID))
+			return
+		}
+		matches, err := d.Store.FetchPhotoReuse(req.Context(), listingID, limit)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load photo reuse"))
+			return
+		}
+		out := make([]PhotoReuseDTO, 0, len(matches))
+		for _, m := range matches {
+			out = append(out, PhotoReuseDTO(m))
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "listing_id": listingID, "property_key": pkey, "count": len(out), "listings": out})
+	})
+}
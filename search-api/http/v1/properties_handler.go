@@ -0,0 +1,224 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/timezone"
+)
+
+type PropertiesDeps struct {
+	Store *store.Store
+}
+
+// MergedListingDTO is a property's canonical listing for one status, with
+// the provider records it was merged from. Dates and times are in
+// TimeZone, the property's local zone.
+type MergedListingDTO struct {
+	ListingID    string             `json:"listingId"`
+	Provider     string             `json:"provider"`
+	Status       string             `json:"status"`
+	ListDate     *string            `json:"listDate"`
+	ListPrice    *float64           `json:"listPrice"`
+	Beds         *int64             `json:"beds"`
+	Baths        *float64           `json:"baths"`
+	Sqft         *int64             `json:"sqft"`
+	PropertyType string             `json:"propertyType,omitempty"`
+	PhotoURLs    []string           `json:"photoUrls"`
+	Sources      []ListingSourceDTO `json:"sources"`
+	TimeZone     string             `json:"timeZone,omitempty"`
+}
+
+// ListingSourceDTO is one provider's record of a merged listing.
+type ListingSourceDTO struct {
+	ListingID   string     `json:"listingId"`
+	Provider    string     `json:"provider"`
+	SourceID    string     `json:"sourceId"`
+	ListPrice   *float64   `json:"listPrice"`
+	LastFetchAt *time.Time `json:"lastFetchAt"`
+}
+
+// TaxAssessmentDTO is one tax year of a property's assessment record.
+type TaxAssessmentDTO struct {
+	Year             int      `json:"year"`
+	Tax              *float64 `json:"tax"`
+	AssessedLand     *float64 `json:"assessedLand"`
+	AssessedBuilding *float64 `json:"assessedBuilding"`
+	AssessedTotal    *float64 `json:"assessedTotal"`
+	MarketTotal      *float64 `json:"marketTotal"`
+	Provider         string   `json:"provider"`
+}
+
+// TransferDTO is one recorded sale or deed transfer of a property.
+type TransferDTO struct {
+	Date     string   `json:"date"`
+	Price    *float64 `json:"price"`
+	Event    string   `json:"event"`
+	Source   string   `json:"source,omitempty"`
+	Provider string   `json:"provider"`
+}
+
+func RegisterProperties(r chi.Router, d PropertiesDeps) {
+	// GET /v1/properties/{propertyKey}/history
+	r.Get("/v1/properties/{propertyKey}/history", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		pkey := chi.URLParam(req, "propertyKey")
+		h, err := d.Store.FetchPropertyHistory(req.Context(), pkey)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load property history"))
+			return
+		}
+		if h == nil {
+			apierror.Write(w, req, apierror.NotFound("property_not_found", "property not found"))
+			return
+		}
+		assessments := make([]TaxAssessmentDTO, 0, len(h.Assessments))
+		for _, a := range h.Assessments {
+			assessments = append(assessments, taxAssessmentDTO(a))
+		}
+		transfers := make([]TransferDTO, 0, len(h.Transfers))
+		for _, t := range h.Transfers {
+			transfers = append(transfers, transferDTO(t))
+		}
+		// Transfers are newest first, so the first priced one is the last sale.
+		var lastSale *TransferDTO
+		for i := range transfers {
+			if transfers[i].Price != nil {
+				lastSale = &transfers[i]
+				break
+			}
+		}
+		var fetchedAt *time.Time
+		if h.FetchedAt.Valid {
+			fetchedAt = &h.FetchedAt.Time
+		}
+		render.JSON(w, req, map[string]any{
+			"ok":           true,
+			"property_key": h.PropertyKey,
+			"fetched_at":   fetchedAt,
+			"assessments":  assessments,
+			"transfers":    transfers,
+			"last_sale":    lastSale,
+		})
+	})
+
+	// GET /v1/properties/{propertyKey}/listings?limit=20
+	r.Get("/v1/properties/{propertyKey}/listings", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		pkey := chi.URLParam(req, "propertyKey")
+		limit := 20
+		if v, err := strconv.Atoi(req.URL.Query().Get("limit")); err == nil && v > 0 && v <= 100 {
+			limit = v
+		}
+		merged, err := d.Store.FetchMergedListings(req.Context(), pkey, limit)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load listings"))
+			return
+		}
+		if len(merged) == 0 {
+			apierror.Write(w, req, apierror.NotFound("property_not_found", "no listings recorded for this property"))
+			return
+		}
+		out := make([]MergedListingDTO, 0, len(merged))
+		for _, m := range merged {
+			out = append(out, mergedListingDTO(m))
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "property_key": pkey, "count": len(out), "listings": out})
+	})
+}
+
+func mergedListingDTO(m store.MergedListing) MergedListingDTO {
+	dto := MergedListingDTO{
+		ListingID:    m.ListingExternalID.String,
+		Provider:     m.Provider,
+		Status:       m.Status,
+		PropertyType: m.PropertyType.String,
+		PhotoURLs:    m.Photos,
+		Sources:      make([]ListingSourceDTO, 0, len(m.Sources)),
+	}
+	if dto.ListingID == "" {
+		dto.ListingID = m.ListingID
+	}
+	if dto.PhotoURLs == nil {
+		dto.PhotoURLs = []string{}
+	}
+	loc := time.UTC
+	if l := timezone.For(m.State, m.Zip); l != nil {
+		loc, dto.TimeZone = l, l.String()
+	}
+	if m.ListDate.Valid {
+		d := m.ListDate.Time.In(loc).Format("2006-01-02")
+		dto.ListDate = &d
+	}
+	if m.ListPrice.Valid {
+		dto.ListPrice = &m.ListPrice.Float64
+	}
+	if m.Beds.Valid {
+		dto.Beds = &m.Beds.Int64
+	}
+	if m.Baths.Valid {
+		dto.Baths = &m.Baths.Float64
+	}
+	if m.Sqft.Valid {
+		dto.Sqft = &m.Sqft.Int64
+	}
+	for _, src := range m.Sources {
+		s := ListingSourceDTO{ListingID: src.ListingExternalID.String, Provider: src.Provider, SourceID: src.SourceID}
+		if s.ListingID == "" {
+			s.ListingID = src.ListingID
+		}
+		if src.ListPrice.Valid {
+			s.ListPrice = &src.ListPrice.Float64
+		}
+		if src.LastFetchAt.Valid {
+			t := src.LastFetchAt.Time.In(loc)
+			s.LastFetchAt = &t
+		}
+		dto.Sources = append(dto.Sources, s)
+	}
+	return dto
+}
+
+func taxAssessmentDTO(a store.TaxAssessment) TaxAssessmentDTO {
+	dto := TaxAssessmentDTO{Year: a.Year, Provider: a.Provider}
+	if a.Tax.Valid {
+		dto.Tax = &a.Tax.Float64
+	}
+	if a.AssessedLand.Valid {
+		dto.AssessedLand = &a.AssessedLand.Float64
+	}
+	if a.AssessedBuilding.Valid {
+		dto.AssessedBuilding = &a.AssessedBuilding.Float64
+	}
+	if a.AssessedTotal.Valid {
+		dto.AssessedTotal = &a.AssessedTotal.Float64
+	}
+	if a.MarketTotal.Valid {
+		dto.MarketTotal = &a.MarketTotal.Float64
+	}
+	return dto
+}
+
+func transferDTO(t store.PropertyTransfer) TransferDTO {
+	dto := TransferDTO{
+		Date:     t.Date.UTC().Format("2006-01-02"),
+		Event:    t.Event,
+		Source:   t.Source.String,
+		Provider: t.Provider,
+	}
+	if t.Price.Valid {
+		dto.Price = &t.Price.Float64
+	}
+	return dto
+}
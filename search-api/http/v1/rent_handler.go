@@ -0,0 +1,285 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/payment"
+	"github.com/yourorg/search-api/internal/redisx"
+	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/valuation"
+)
+
+type RentDeps struct {
+	Store *store.Store
+	// Rapid searches for-rent listings near the property as comparables.
+	Rapid *attom.Client
+	// Redis caches each ZIP's comparables for CompsTTL, default 24h; nil
+	// disables it.
+	Redis    *redisx.Client
+	CompsTTL time.Duration
+	// Valuation prices the property when it has no list price.
+	Valuation *valuation.Service
+	// Defaults and Rental fill any input the caller leaves out.
+	Defaults payment.Assumptions
+	Rental   payment.RentalAssumptions
+}
+
+// RentEstimateDTO is a property's estimated monthly market rent.
+type RentEstimateDTO struct {
+	Rent       float64 `json:"rent"`
+	Low        float64 `json:"low"`
+	High       float64 `json:"high"`
+	Confidence float64 `json:"confidence"`
+	CompsCount int     `json:"compsCount"`
+	Source     string  `json:"source" doc:"comparables when estimated from nearby for-rent listings, request when the caller passed rent"`
+}
+
+// RentalReturnsDTO are the yearly cap-rate inputs of letting a property at
+// the estimated rent. Yields are percentages.
+type RentalReturnsDTO struct {
+	Price             float64 `json:"price"`
+	PriceSource       string  `json:"priceSource" doc:"request, list_price or estimated_value"`
+	GrossRent         float64 `json:"grossRent"`
+	VacancyLoss       float64 `json:"vacancyLoss"`
+	EffectiveIncome   float64 `json:"effectiveIncome"`
+	Tax               float64 `json:"tax"`
+	TaxSource         string  `json:"taxSource" doc:"assessment when taken from the property's tax record, otherwise estimate"`
+	Insurance         float64 `json:"insurance"`
+	HOA               float64 `json:"hoa"`
+	Maintenance       float64 `json:"maintenance"`
+	Management        float64 `json:"management"`
+	OperatingExpenses float64 `json:"operatingExpenses"`
+	NetOperating      float64 `json:"netOperatingIncome"`
+	GrossYield        float64 `json:"grossYield"`
+	CapRate           float64 `json:"capRate"`
+}
+
+// RentAssumptionsDTO echoes the inputs the returns were computed with.
+type RentAssumptionsDTO struct {
+	TaxRate        float64 `json:"taxRate"`
+	InsuranceRate  float64 `json:"insuranceRate"`
+	HOAMonthly     float64 `json:"hoaMonthly"`
+	VacancyPct     float64 `json:"vacancyPct"`
+	MaintenancePct float64 `json:"maintenancePct"`
+	ManagementPct  float64 `json:"managementPct"`
+}
+
+// maxRentComps is how many comparables the response lists, nearest first.
+const maxRentComps = 10
+
+func RegisterRentEstimates(r chi.Router, d RentDeps) {
+	// GET /v1/properties/{propertyKey}/rent-estimate?price=450000&vacancy_pct=8
+	r.Get("/v1/properties/{propertyKey}/rent-estimate", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		pkey := chi.URLParam(req, "propertyKey")
+		q := req.URL.Query()
+		a, ra := d.Defaults, d.Rental
+		var price, rent float64
+		for _, p := range []struct {
+			name string
+			dst  *float64
+		}{
+			{"price", &price},
+			{"rent", &rent},
+			{"tax_rate", &a.TaxRate},
+			{"insurance_rate", &a.InsuranceRate},
+			{"hoa", &a.HOAMonthly},
+			{"vacancy_pct", &ra.VacancyPct},
+			{"maintenance_pct", &ra.MaintenancePct},
+			{"management_pct", &ra.ManagementPct},
+		} {
+			v := q.Get(p.name)
+			if v == "" {
+				continue
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				apierror.Write(w, req, apierror.BadRequest("invalid_"+p.name, p.name+" must be a number"))
+				return
+			}
+			*p.dst = f
+		}
+		for _, p := range []struct {
+			name string
+			v    float64
+		}{{"price", price}, {"rent", rent}} {
+			if q.Get(p.name) != "" && p.v <= 0 {
+				apierror.Write(w, req, apierror.BadRequest("invalid_"+p.name, p.name+" must be positive"))
+				return
+			}
+		}
+		if err := a.Validate(); err != nil {
+			apierror.Write(w, req, apierror.BadRequest("invalid_assumptions", err.Error()))
+			return
+		}
+		if err := ra.Validate(); err != nil {
+			apierror.Write(w, req, apierror.BadRequest("invalid_assumptions", err.Error()))
+			return
+		}
+
+		records, err := d.Store.FetchListingsByProperty(req.Context(), pkey, 1)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load property"))
+			return
+		}
+		if len(records) == 0 {
+			apierror.Write(w, req, apierror.NotFound("property_not_found", "no listings recorded for this property"))
+			return
+		}
+		rec := records[0]
+		subject := valuation.SubjectFromRecord(rec)
+
+		priceSource := "request"
+		switch {
+		case price > 0:
+		case rec.ListPrice.Valid && rec.ListPrice.Float64 > 0:
+			price, priceSource = rec.ListPrice.Float64, "list_price"
+		default:
+			if est, err := d.Valuation.Get(req.Context(), subject); err != nil {
+				log.Printf("[WARN] valuation failed for %s: %v", pkey, err)
+			} else if est != nil {
+				price, priceSource = est.Value, "estimated_value"
+			}
+		}
+		if price <= 0 {
+			apierror.Write(w, req, apierror.BadRequest("price_required", "property has no list price or estimated value; pass price"))
+			return
+		}
+
+		var comps []attom.RentalListing
+		est := valuation.RentEstimate{Rent: rent}
+		rentSource := "request"
+		if rent <= 0 {
+			if comps, err = d.rentComps(req.Context(), subject.Zip, subject.Beds); err != nil {
+				apierror.WriteError(w, req, err, apierror.Upstream)
+				return
+			}
+			est, err = valuation.EstimateRent(subject, comps)
+			if errors.Is(err, valuation.ErrInsufficientData) {
+				apierror.Write(w, req, apierror.New(http.StatusUnprocessableEntity, "insufficient_comps", "too few comparable rentals near the property; pass rent").With("comps", len(comps)))
+				return
+			}
+			if err != nil {
+				apierror.WriteError(w, req, err, apierror.Internal("rent_estimate_error", "unable to estimate rent"))
+				return
+			}
+			rentSource = "comparables"
+		}
+
+		var annualTax float64
+		if h, err := d.Store.FetchPropertyHistory(req.Context(), pkey); err != nil {
+			log.Printf("[WARN] tax history unavailable for %s: %v", pkey, err)
+		} else if h != nil && len(h.Assessments) > 0 && h.Assessments[0].Tax.Valid {
+			annualTax = h.Assessments[0].Tax.Float64
+		}
+		ret := payment.CalculateRental(price, est.Rent, annualTax, a, ra)
+		taxSource := "estimate"
+		if ret.TaxFromAssessment {
+			taxSource = "assessment"
+		}
+		render.JSON(w, req, map[string]any{
+			"ok":           true,
+			"property_key": pkey,
+			"estimate": RentEstimateDTO{
+				Rent:       est.Rent,
+				Low:        est.Low,
+				High:       est.High,
+				Confidence: est.Confidence,
+				CompsCount: est.CompsCount,
+				Source:     rentSource,
+			},
+			"returns": RentalReturnsDTO{
+				Price:             ret.Price,
+				PriceSource:       priceSource,
+				GrossRent:         ret.GrossRent,
+				VacancyLoss:       ret.VacancyLoss,
+				EffectiveIncome:   ret.EffectiveIncome,
+				Tax:               ret.Tax,
+				TaxSource:         taxSource,
+				Insurance:         ret.Insurance,
+				HOA:               ret.HOA,
+				Maintenance:       ret.Maintenance,
+				Management:        ret.Management,
+				OperatingExpenses: ret.OperatingExpenses,
+				NetOperating:      ret.NetOperating,
+				GrossYield:        ret.GrossYield,
+				CapRate:           ret.CapRate,
+			},
+			"assumptions": RentAssumptionsDTO{
+				TaxRate:        a.TaxRate,
+				InsuranceRate:  a.InsuranceRate,
+				HOAMonthly:     a.HOAMonthly,
+				VacancyPct:     ra.VacancyPct,
+				MaintenancePct: ra.MaintenancePct,
+				ManagementPct:  ra.ManagementPct,
+			},
+			"comparables": nearestRentals(comps, subject, maxRentComps),
+		})
+	})
+}
+
+// rentComps returns the for-rent listings in zip within a bedroom of beds,
+// from Redis when a recent search is cached. Empty searches are not cached.
+func (d RentDeps) rentComps(ctx context.Context, zip string, beds int) ([]attom.RentalListing, error) {
+	if d.Rapid == nil {
+		return nil, apierror.Unavailable("rental_search_unavailable", "rental comparables are not configured; pass rent")
+	}
+	key := fmt.Sprintf("rent:comps:%s:%d", zip, beds)
+	if d.Redis != nil {
+		if v, _ := d.Redis.Get(ctx, key); v != "" {
+			var comps []attom.RentalListing
+			if err := json.Unmarshal([]byte(v), &comps); err == nil {
+				return comps, nil
+			}
+		}
+	}
+	comps, err := d.Rapid.SearchRentalsByPostal(ctx, zip, 50, beds)
+	if err != nil {
+		return nil, err
+	}
+	if d.Redis != nil && len(comps) > 0 {
+		ttl := d.CompsTTL
+		if ttl <= 0 {
+			ttl = 24 * time.Hour
+		}
+		if b, err := json.Marshal(comps); err == nil {
+			_ = d.Redis.Set(ctx, key, string(b), ttl)
+		}
+	}
+	return comps, nil
+}
+
+// nearestRentals returns up to n comparables, nearest the subject first when
+// it has coordinates.
+func nearestRentals(comps []attom.RentalListing, subject valuation.Subject, n int) []attom.RentalListing {
+	out := append([]attom.RentalListing{}, comps...)
+	if subject.Lat != 0 || subject.Lon != 0 {
+		dist := func(c attom.RentalListing) float64 {
+			if c.Coords == ([2]float64{}) {
+				return math.Inf(1)
+			}
+			return math.Hypot(c.Coords[0]-subject.Lon, c.Coords[1]-subject.Lat)
+		}
+		sort.SliceStable(out, func(i, j int) bool { return dist(out[i]) < dist(out[j]) })
+	}
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
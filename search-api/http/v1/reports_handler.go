@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/markets"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type ReportsDeps struct {
+	Store *store.Store
+}
+
+// ReportWeekDTO is a week with market reports.
+type ReportWeekDTO struct {
+	WeekStart   string    `json:"weekStart"`
+	WeekEnd     string    `json:"weekEnd"`
+	Zips        int       `json:"zips"`
+	GeneratedAt time.Time `json:"generatedAt"`
+}
+
+func RegisterReports(r chi.Router, d ReportsDeps) {
+	// GET /v1/reports?limit=12
+	r.Get("/v1/reports", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		limit := 12
+		if v, err := strconv.Atoi(req.URL.Query().Get("limit")); err == nil && v > 0 && v <= 104 {
+			limit = v
+		}
+		weeks, err := d.Store.MarketReportWeeks(req.Context(), limit)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load reports"))
+			return
+		}
+		out := make([]ReportWeekDTO, 0, len(weeks))
+		for _, wk := range weeks {
+			out = append(out, ReportWeekDTO{
+				WeekStart:   wk.WeekStart.Format("2006-01-02"),
+				WeekEnd:     wk.WeekStart.AddDate(0, 0, 6).Format("2006-01-02"),
+				Zips:        wk.Zips,
+				GeneratedAt: wk.GeneratedAt,
+			})
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "count": len(out), "weeks": out})
+	})
+
+	// GET /v1/reports/markets/{week}?zip=78704&format=csv
+	// week is latest or any day of the week.
+	r.Get("/v1/reports/markets/{week}", func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		q := req.URL.Query()
+		format := q.Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			apierror.Write(w, req, apierror.BadRequest("invalid_format", "format must be json or csv"))
+			return
+		}
+		var week time.Time
+		if v := chi.URLParam(req, "week"); v == "latest" {
+			weeks, err := d.Store.MarketReportWeeks(req.Context(), 1)
+			if err != nil {
+				apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load reports"))
+				return
+			}
+			if len(weeks) == 0 {
+				apierror.Write(w, req, apierror.NotFound("report_not_found", "no market reports have been generated"))
+				return
+			}
+			week = weeks[0].WeekStart
+		} else {
+			day, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				apierror.Write(w, req, apierror.BadRequest("invalid_week", "week must be latest or a date (2006-01-02)").WithDetail(err.Error()))
+				return
+			}
+			week = markets.WeekStart(day)
+		}
+		zip := q.Get("zip")
+		reports, err := d.Store.MarketReports(req.Context(), week, zip)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load reports"))
+			return
+		}
+		if len(reports) == 0 {
+			e := apierror.NotFound("report_not_found", "no market report for that week").With("week_start", week.Format("2006-01-02"))
+			if zip != "" {
+				e = e.With("zip", zip)
+			}
+			apierror.Write(w, req, e)
+			return
+		}
+		out := make([]markets.Summary, 0, len(reports))
+		for _, r := range reports {
+			out = append(out, markets.Summarize(r))
+		}
+
+		if format == "csv" {
+			name := "market-report-" + week.Format("20060102")
+			if zip != "" {
+				name += "-" + zip
+			}
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+			_ = markets.WriteCSV(w, out)
+			return
+		}
+		render.JSON(w, req, map[string]any{
+			"ok": true, "week_start": week.Format("2006-01-02"), "week_end": week.AddDate(0, 0, 6).Format("2006-01-02"),
+			"count": len(out), "reports": out,
+		})
+	})
+}
@@ -4,15 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/yourorg/search-api/attom"
+	httpapi "github.com/yourorg/search-api/http"
+	"github.com/yourorg/search-api/internal/addrverify"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/cache"
 	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/events"
+	"github.com/yourorg/search-api/internal/geocode"
 	"github.com/yourorg/search-api/internal/hydrator"
+	"github.com/yourorg/search-api/internal/logger"
 	"github.com/yourorg/search-api/internal/redisx"
+	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/valuation"
 )
 
 type ResolveDeps struct {
@@ -20,12 +31,51 @@ type ResolveDeps struct {
 	Rapid    *attom.Client
 	Refetch  func(propertyKey, line1, city, state, zip string)
 	Hydrator *hydrator.Hydrator
+	// Valuation attaches estimatedValue to freshly resolved properties.
+	Valuation *valuation.Service
+	// Geocoder reverse-geocodes resolve-by-location points with no stored
+	// property nearby; nil disables that fallback.
+	Geocoder geocode.Reverser
+	// Verifier standardizes addresses before they are canonicalized; nil
+	// skips verification, as does a verifier error. RejectUndeliverable
+	// answers 422 for addresses it can't confirm USPS delivers to, instead
+	// of resolving them as given.
+	Verifier            addrverify.Verifier
+	RejectUndeliverable bool
 	// TTL and staleness tuning
 	CacheTTL    time.Duration
 	StaleAfter  time.Duration
 	NegativeTTL time.Duration
+	// Batch limits: items per request and distinct provider calls per batch.
+	// Zero uses defaultBatchMaxItems / defaultBatchFetchBudget.
+	BatchMaxItems    int
+	BatchFetchBudget int
+	// WaitTimeout bounds how long ?wait=true holds a request behind another
+	// caller's fetch before answering 202. Zero uses defaultWaitTimeout.
+	WaitTimeout time.Duration
 }
 
+// postgres is the hydrator's store when it is the Postgres one, which the
+// parcel and location lookups need.
+func (d ResolveDeps) postgres() *store.Store {
+	if d.Hydrator == nil {
+		return nil
+	}
+	return store.Postgres(d.Hydrator.Store)
+}
+
+const (
+	resolveLockTTL     = 8 * time.Second
+	defaultWaitTimeout = 10 * time.Second
+	// waitPollEvery re-checks the cache while waiting in case the lock holder
+	// died without publishing.
+	waitPollEvery = time.Second
+
+	defaultBatchMaxItems    = 50
+	defaultBatchFetchBudget = 10
+	batchConcurrency        = 4
+)
+
 type ResolveRequest struct {
 	Address string `json:"address"`
 	City    string `json:"city"`
@@ -33,20 +83,8 @@ type ResolveRequest struct {
 	Zip     string `json:"zip"`
 }
 
-type cachedEnvelope struct {
-	Data any `json:"data"`
-	Meta struct {
-		LastFetch  time.Time `json:"last_fetch_at"`
-		StaleAfter time.Time `json:"stale_after"`
-		TTLSeconds int       `json:"ttl_seconds"`
-		Source     string    `json:"source"`
-	} `json:"meta"`
-	Norm struct {
-		Line1 string `json:"line1"`
-		City  string `json:"city"`
-		State string `json:"state"`
-		Zip   string `json:"zip"`
-	} `json:"normalized"`
+type ResolveBatchRequest struct {
+	Items []ResolveRequest `json:"items"`
 }
 
 func RegisterResolve(r chi.Router, d ResolveDeps) {
@@ -54,8 +92,7 @@ func RegisterResolve(r chi.Router, d ResolveDeps) {
 		r.Post("/resolve", func(w http.ResponseWriter, req *http.Request) {
 			var body ResolveRequest
 			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
-				render.Status(req, http.StatusBadRequest)
-				_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_json", "detail": err.Error()})
+				apierror.WriteError(w, req, err, apierror.InvalidJSON)
 				return
 			}
 			resolve(w, req, d, body)
@@ -70,118 +107,546 @@ func RegisterResolve(r chi.Router, d ResolveDeps) {
 			}
 			resolve(w, req, d, body)
 		})
+		r.Get("/by-listing/{providerListingID}", func(w http.ResponseWriter, req *http.Request) {
+			writeResolveResult(w, req, resolveByListing(req.Context(), d, chi.URLParam(req, "providerListingID")))
+		})
+		r.Get("/by-apn/{apn}", func(w http.ResponseWriter, req *http.Request) {
+			writeResolveResult(w, req, resolveByAPN(req.Context(), d, chi.URLParam(req, "apn"), req.URL.Query().Get("fips")))
+		})
+		r.Post("/resolve-by-location", func(w http.ResponseWriter, req *http.Request) {
+			var body ResolveLocationRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				apierror.WriteError(w, req, err, apierror.InvalidJSON)
+				return
+			}
+			writeResolveResult(w, req, resolveByLocation(req.Context(), d, body))
+		})
+		r.Post("/resolve:batch", func(w http.ResponseWriter, req *http.Request) {
+			var body ResolveBatchRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				apierror.WriteError(w, req, err, apierror.InvalidJSON)
+				return
+			}
+			resolveBatch(w, req, d, body.Items)
+		})
+	})
+}
+
+// resolveBatch resolves up to BatchMaxItems addresses in one request.
+// Duplicate addresses are resolved once, addresses in the same ZIP share a
+// single provider search page, and at most BatchFetchBudget provider calls
+// are made; items past the budget come back as batch_budget_exceeded.
+func resolveBatch(w http.ResponseWriter, req *http.Request, d ResolveDeps, items []ResolveRequest) {
+	maxItems := d.BatchMaxItems
+	if maxItems <= 0 {
+		maxItems = defaultBatchMaxItems
+	}
+	if len(items) == 0 {
+		apierror.Write(w, req, apierror.BadRequest("items_required", "items must contain at least one address"))
+		return
+	}
+	if len(items) > maxItems {
+		apierror.Write(w, req, apierror.BadRequest("too_many_items", "batch exceeds the item limit").With("max_items", maxItems))
+		return
+	}
+	budget := d.BatchFetchBudget
+	if budget <= 0 {
+		budget = defaultBatchFetchBudget
+	}
+	fetcher := newZipFetcher(d.Rapid, budget)
+
+	// Dedup on property key so repeated addresses cost one resolve.
+	firstByKey := map[string]int{}
+	dupOf := make([]int, len(items))
+	for i, it := range items {
+		dupOf[i] = -1
+		if it.Address == "" || it.City == "" || it.State == "" || it.Zip == "" {
+			continue
+		}
+		_, _, _, _, pkey := canon.Canonicalize(it.Address, it.City, it.State, it.Zip)
+		if first, ok := firstByKey[pkey]; ok {
+			dupOf[i] = first
+			continue
+		}
+		firstByKey[pkey] = i
+	}
+
+	// Read every cache and negative-cache key up front in one round-trip.
+	pkeys := make([]string, 0, len(firstByKey))
+	for pkey := range firstByKey {
+		pkeys = append(pkeys, pkey)
+	}
+	pre := prefetchCache(req.Context(), d, pkeys)
+
+	results := make([]ResolveResult, len(items))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, it := range items {
+		if dupOf[i] >= 0 {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, it ResolveRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = resolveAddress(req.Context(), d, it, fetcher, pre)
+		}(i, it)
+	}
+	wg.Wait()
+	pre.flush(req.Context(), d)
+
+	counts := map[string]int{}
+	for i := range results {
+		if dupOf[i] >= 0 {
+			results[i] = results[dupOf[i]]
+		}
+		results[i].Index = i
+		switch {
+		case results[i].OK:
+			counts["resolved"]++
+		case results[i].InProgress:
+			counts["in_progress"]++
+		default:
+			counts["failed"]++
+		}
+	}
+	render.JSON(w, req, map[string]any{
+		"ok":             true,
+		"count":          len(results),
+		"summary":        counts,
+		"provider_calls": fetcher.providerCalls(),
+		"results":        results,
 	})
 }
 
 func resolve(w http.ResponseWriter, req *http.Request, d ResolveDeps, body ResolveRequest) {
+	res := resolveAddress(req.Context(), d, body, newZipFetcher(d.Rapid, 0), nil)
+	if res.InProgress && req.URL.Query().Get("wait") == "true" {
+		res = waitForResolve(req.Context(), d, body, res)
+	}
+	writeResolveResult(w, req, res)
+}
+
+// writeResolveResult renders a single resolve: the envelope on success, 202
+// while another request fetches the property, or the error.
+func writeResolveResult(w http.ResponseWriter, req *http.Request, res ResolveResult) {
+	switch {
+	case res.Error != nil:
+		apierror.Write(w, req, res.Error)
+	case res.InProgress:
+		render.Status(req, http.StatusAccepted)
+		render.JSON(w, req, map[string]any{"ok": false, "in_progress": true, "property_key": res.PropertyKey})
+	default:
+		logger.SetCache(req.Context(), resolveCacheResult(res))
+		body := map[string]any{
+			"ok":           true,
+			"source":       res.Source,
+			"stale":        res.Stale,
+			"property_key": res.PropertyKey,
+			"normalized":   res.Normalized,
+			"data":         res.Data,
+		}
+		if res.Match != nil {
+			body["match"] = res.Match
+		}
+		render.JSON(w, req, body)
+	}
+}
+
+// resolveCacheResult is the access log's cache result for a resolve.
+func resolveCacheResult(res ResolveResult) string {
+	switch {
+	case res.Source == "cache" && res.Stale:
+		return logger.CacheStale
+	case res.Source == "cache":
+		return logger.CacheHit
+	case res.Source == "store":
+		return logger.CacheMiss
+	}
+	return logger.CacheProvider
+}
+
+// ResolveResult is the outcome of resolving one address. The single endpoint
+// renders it as before; the batch endpoint returns it per item.
+type ResolveResult struct {
+	Index       int               `json:"index"`
+	Status      int               `json:"status"`
+	OK          bool              `json:"ok"`
+	Source      string            `json:"source,omitempty"`
+	Stale       bool              `json:"stale,omitempty"`
+	InProgress  bool              `json:"in_progress,omitempty"`
+	PropertyKey string            `json:"property_key,omitempty"`
+	KeyVersion  int               `json:"key_version,omitempty"`
+	Normalized  map[string]string `json:"normalized,omitempty"`
+	Data        any               `json:"data,omitempty"`
+	Error       *apierror.Error   `json:"error,omitempty"`
+	// Match is set by resolve-by-location.
+	Match *LocationMatch `json:"match,omitempty"`
+	// Verification is set when an address verifier checked the address.
+	Verification *addrverify.Result `json:"verification,omitempty"`
+}
+
+func failed(pkey string, e *apierror.Error) ResolveResult {
+	if pkey != "" {
+		e = e.With("property_key", pkey)
+	}
+	return ResolveResult{Status: e.Status, PropertyKey: pkey, Error: e}
+}
+
+// resolveAddress runs the resolve pipeline: negative cache, Redis, the
+// store, then the provider via fetcher, which shares ZIP pages and the
+// provider call budget across a batch. pre, when set, supplies prefetched
+// cache reads and collects store backfills for one batched write. Every
+// successful resolve is announced as a PropertyResolved event.
+func resolveAddress(ctx context.Context, d ResolveDeps, body ResolveRequest, fetcher *zipFetcher, pre *cachePrefetch) ResolveResult {
+	verified, e := verifyAddress(ctx, d, &body)
+	if e != nil {
+		res := failed("", e)
+		res.Verification = verified
+		return res
+	}
+	res := resolvePipeline(ctx, d, body, fetcher, pre)
+	res.Verification = verified
+	if res.PropertyKey != "" {
+		res.KeyVersion = canon.KeyVersion()
+	}
+	if res.OK {
+		d.Hydrator.Publish(ctx, events.PropertyResolved{PropertyKey: res.PropertyKey, Source: res.Source})
+	}
+	return res
+}
+
+// verifyAddress replaces body's address with the verifier's standardized
+// one, which may fill in or correct the ZIP. Addresses it can't confirm are
+// resolved as given unless d.RejectUndeliverable is set.
+func verifyAddress(ctx context.Context, d ResolveDeps, body *ResolveRequest) (*addrverify.Result, *apierror.Error) {
+	if d.Verifier == nil || body.Address == "" || (body.Zip == "" && (body.City == "" || body.State == "")) {
+		return nil, nil
+	}
+	r, err := d.Verifier.Verify(ctx, addrverify.Address{Line1: body.Address, City: body.City, State: body.State, Zip: body.Zip})
+	if err != nil {
+		log.Printf("[WARN] address verification failed for %q: %v", body.Address, err)
+		return nil, nil
+	}
+	if !r.Deliverable() {
+		if d.RejectUndeliverable {
+			return &r, apierror.New(http.StatusUnprocessableEntity, "address_undeliverable", "address could not be verified as deliverable").With("dpv", r.DPV)
+		}
+		return &r, nil
+	}
+	body.Address, body.City, body.State, body.Zip = r.Address.Line1, r.Address.City, r.Address.State, r.Address.Zip
+	return &r, nil
+}
+
+func resolvePipeline(ctx context.Context, d ResolveDeps, body ResolveRequest, fetcher *zipFetcher, pre *cachePrefetch) ResolveResult {
 	if body.Address == "" || body.City == "" || body.State == "" || body.Zip == "" {
-		render.Status(req, http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "address_required", "detail": "address, city, state, zip are required"})
-		return
+		return failed("", apierror.BadRequest("address_required", "address, city, state, zip are required"))
 	}
 	line1, city, st, zip, pkey := canon.Canonicalize(body.Address, body.City, body.State, body.Zip)
-	ctx := req.Context()
+	norm := map[string]string{"line1": line1, "city": city, "state": st, "zip": zip}
 	missKey := "prop:miss:" + pkey
-	cacheKey := "prop:pk:" + pkey
+	cacheKey := cache.PropertyKey(pkey)
 
-	if ok, _ := d.Redis.Exists(ctx, missKey); ok {
-		render.Status(req, http.StatusNotFound)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "not_found", "property_key": pkey, "cache_miss_cooldown": true})
-		return
+	if v, _ := pre.get(ctx, d, missKey); v != "" {
+		return failed(pkey, apierror.NotFound("not_found", "property not found").With("cache_miss_cooldown", true))
 	}
 
-	if val, err := d.Redis.Get(ctx, cacheKey); err == nil && val != "" {
-		var env cachedEnvelope
-		if err := json.Unmarshal([]byte(val), &env); err == nil {
-			stale := time.Now().After(env.Meta.StaleAfter)
+	if val, err := pre.get(ctx, d, cacheKey); err == nil && val != "" {
+		// Entries from an older envelope version fall through and are
+		// overwritten below.
+		if env, err := cache.Decode(val); err == nil {
+			stale := env.Meta.Stale(time.Now())
 			// fire-and-forget background refresh if stale
 			if stale && d.Refetch != nil {
 				d.Refetch(pkey, line1, city, st, zip)
 			}
 			// Serve cached immediately
-			render.JSON(w, req, map[string]any{
-				"ok":           true,
-				"source":       "cache",
-				"stale":        stale,
-				"property_key": pkey,
-				"normalized":   map[string]string{"line1": line1, "city": city, "state": st, "zip": zip},
-				"data":         env.Data,
-			})
-			return
+			return ResolveResult{Status: http.StatusOK, OK: true, Source: "cache", Stale: stale, PropertyKey: pkey, Normalized: norm, Data: env.Data}
 		}
 	}
 
-	// Cache miss: attempt a short lock to avoid stampedes
-	if ok, _ := d.Redis.SetNX(ctx, "prop:lock:"+pkey, "1", 8*time.Second); !ok {
-		render.Status(req, http.StatusAccepted)
-		_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "in_progress": true, "property_key": pkey})
-		return
+	// Cache miss: serve what the hydrator already persisted, if anything.
+	card, ok := storedCard(ctx, d, pkey)
+	if !ok {
+		card, ok = aliasedCard(ctx, d, body, pkey)
 	}
-
-	// Cache miss and lock acquired: do a best-effort fetch via RapidAPI provider
-	raw, data, found, fetchErr := fetchResolveRaw(ctx, d.Rapid, zip, line1, city, st)
-	if fetchErr != nil {
-		if errors.Is(fetchErr, attom.ErrDailyLimitExceeded) {
-			render.Status(req, http.StatusTooManyRequests)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "provider_quota", "detail": "daily quota reached", "property_key": pkey})
-			return
+	if ok {
+		if pre != nil {
+			pre.backfill(cacheKey, cacheEnvelope(d, card, "store", line1, city, st, zip))
+		} else {
+			writeCache(ctx, d, cacheKey, card, "store", line1, city, st, zip)
 		}
-		render.Status(req, http.StatusBadGateway)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "upstream_error", "detail": fetchErr.Error(), "property_key": pkey})
-		return
+		return ResolveResult{Status: http.StatusOK, OK: true, Source: "store", PropertyKey: pkey, Normalized: norm, Data: card}
+	}
+
+	// Attempt a short lock to avoid stampedes. It is kept alive while the
+	// provider call runs and released as soon as this resolve finishes, so a
+	// failed fetch does not block other callers until the TTL lapses.
+	lock, err := d.Redis.TryLock(ctx, "prop:lock:"+pkey, resolveLockTTL)
+	if err == nil && lock == nil {
+		return ResolveResult{Status: http.StatusAccepted, InProgress: true, PropertyKey: pkey}
+	}
+	if lock != nil {
+		lock.KeepAlive(ctx)
+		defer func() {
+			// The request may already be cancelled; release regardless, then
+			// wake any ?wait=true callers so they re-read the cache.
+			uctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, _ = lock.Unlock(uctx)
+			_ = d.Redis.Publish(uctx, resolvedChannel(pkey), "done")
+		}()
+	}
+
+	// Lock acquired: claim the ZIP's page from the batch budget, then do a
+	// best-effort fetch via RapidAPI provider. Callers that lost the lock
+	// never reach here, so they don't spend the budget.
+	if err := fetcher.reserve(zip); err != nil {
+		return failed(pkey, apierror.New(http.StatusTooManyRequests, "batch_budget_exceeded", "provider call budget for this batch is spent; retry this address"))
+	}
+	raw, cards, err := fetcher.page(ctx, zip)
+	if err != nil {
+		return failed(pkey, apierror.FromError(err, apierror.Upstream))
 	}
+	card, found := matchCard(cards, line1, city, st, zip)
 	if !found {
 		_ = d.Redis.Set(ctx, missKey, "1", d.NegativeTTL)
-		render.Status(req, http.StatusNotFound)
-		_ = json.NewEncoder(w).Encode(map[string]any{"error": "not_found", "property_key": pkey})
-		return
+		return failed(pkey, apierror.NotFound("not_found", "property not found"))
 	}
-	env := cachedEnvelope{Data: data}
-	env.Meta.LastFetch = time.Now()
-	env.Meta.StaleAfter = env.Meta.LastFetch.Add(maxDur(d.StaleAfter, 5*time.Minute))
-	env.Meta.TTLSeconds = int(maxDur(d.CacheTTL, time.Hour).Seconds())
-	env.Meta.Source = "rapidapi"
-	env.Norm.Line1, env.Norm.City, env.Norm.State, env.Norm.Zip = line1, city, st, zip
-	b, _ := json.Marshal(env)
-	_ = d.Redis.Set(ctx, cacheKey, string(b), time.Duration(env.Meta.TTLSeconds)*time.Second)
-
-	// Optional write-behind: persist and publish
+	// Optional write-behind: persist and publish. This runs before valuation
+	// so the estimate has a property row to attach to.
 	if d.Hydrator != nil {
-		norm := map[string]string{"line1": line1, "city": city, "state": st, "zip": zip, "property_key": pkey}
-		if card, ok := data.(attom.PropertyCard); ok {
-			_ = d.Hydrator.Write(req.Context(), "rapidapi.realtor16", "search/forsale", raw, norm, card)
+		_ = d.Hydrator.Write(ctx, "rapidapi.realtor16", "search/forsale", raw, map[string]string{
+			"line1": line1, "city": city, "state": st, "zip": zip, "property_key": pkey,
+			"address_key": canon.AddressKey(body.Address, body.City, body.State, body.Zip),
+		}, card)
+	}
+	if d.Valuation != nil {
+		if est, err := d.Valuation.Get(ctx, valuation.SubjectFromCard(pkey, card)); err == nil && est != nil {
+			card.EstimatedValue = est.CardValue()
 		}
 	}
+	writeCache(ctx, d, cacheKey, card, "rapidapi", line1, city, st, zip)
+	return ResolveResult{Status: http.StatusOK, OK: true, Source: "fresh", PropertyKey: pkey, Normalized: norm, Data: card}
+}
 
-	render.JSON(w, req, map[string]any{
-		"ok":           true,
-		"source":       "fresh",
-		"stale":        false,
-		"property_key": pkey,
-		"normalized":   map[string]string{"line1": line1, "city": city, "state": st, "zip": zip},
-		"data":         data,
-	})
+// Resolve runs the resolve pipeline for one address outside of an HTTP
+// request, as the /v1/properties/resolve handler would without ?wait.
+func Resolve(ctx context.Context, d ResolveDeps, body ResolveRequest) ResolveResult {
+	return resolveAddress(ctx, d, body, newZipFetcher(d.Rapid, 0), nil)
 }
 
-// fetchResolve uses a ZIP search and filters by normalized address to find a match.
-func fetchResolveRaw(ctx context.Context, rapid *attom.Client, zip string, line1 string, city string, state string) ([]byte, any, bool, error) {
-	raw, err := rapid.SearchByPostal(ctx, zip, 20, 1, "", "")
+func resolvedChannel(pkey string) string { return "prop:resolved:" + pkey }
+
+// waitForResolve holds an in-progress resolve until the lock holder publishes
+// on the property's channel, then resolves again, which normally hits the
+// fresh cache entry. If the holder failed, the retry may take the lock and
+// fetch itself. Past WaitTimeout the last in-progress result is returned.
+func waitForResolve(ctx context.Context, d ResolveDeps, body ResolveRequest, res ResolveResult) ResolveResult {
+	timeout := d.WaitTimeout
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sub := d.Redis.Rdb.Subscribe(wctx, resolvedChannel(res.PropertyKey))
+	defer sub.Close()
+	// Wait for the subscription to be confirmed so a publish between the
+	// first attempt and here is caught by the re-check below.
+	if _, err := sub.Receive(wctx); err != nil {
+		return res
+	}
+	done := sub.Channel()
+	poll := time.NewTicker(waitPollEvery)
+	defer poll.Stop()
+	for {
+		res = resolveAddress(ctx, d, body, newZipFetcher(d.Rapid, 0), nil)
+		if !res.InProgress {
+			return res
+		}
+		select {
+		case <-done:
+		case <-poll.C:
+		case <-wctx.Done():
+			return res
+		}
+	}
+}
+
+// storedCard returns the newest stored listing for the property as a card.
+func storedCard(ctx context.Context, d ResolveDeps, pkey string) (attom.PropertyCard, bool) {
+	if d.Hydrator == nil || d.Hydrator.Store == nil {
+		return attom.PropertyCard{}, false
+	}
+	records, err := d.Hydrator.Store.FetchListingsByProperty(ctx, pkey, 1)
+	if err != nil || len(records) == 0 {
+		return attom.PropertyCard{}, false
+	}
+	return httpapi.RecordsToCards(records)[0], true
+}
+
+// aliasedCard serves an address stored under a key it no longer
+// canonicalizes to, as after a canon rules change, by the address as given.
+// pkey is then kept as an alias of that property so it resolves directly.
+func aliasedCard(ctx context.Context, d ResolveDeps, body ResolveRequest, pkey string) (attom.PropertyCard, bool) {
+	st := d.postgres()
+	if st == nil {
+		return attom.PropertyCard{}, false
+	}
+	key, err := st.PropertyKeyByAddress(ctx, canon.AddressKey(body.Address, body.City, body.State, body.Zip))
+	if err != nil || key == "" || key == pkey {
+		return attom.PropertyCard{}, false
+	}
+	card, ok := storedCard(ctx, d, key)
+	if ok {
+		_ = st.AddKeyAlias(ctx, pkey, key, canon.KeyVersion())
+	}
+	return card, ok
+}
+
+func writeCache(ctx context.Context, d ResolveDeps, cacheKey string, card attom.PropertyCard, source, line1, city, st, zip string) {
+	_ = cache.Put(ctx, d.Redis, cacheKey, cacheEnvelope(d, card, source, line1, city, st, zip))
+}
+
+func cacheEnvelope(d ResolveDeps, card attom.PropertyCard, source, line1, city, st, zip string) cache.Envelope {
+	norm := cache.Normalized{Line1: line1, City: city, State: st, Zip: zip}
+	return cache.New(card, source, norm, maxDur(d.StaleAfter, 5*time.Minute), cacheTTL(d))
+}
+
+func cacheTTL(d ResolveDeps) time.Duration { return maxDur(d.CacheTTL, time.Hour) }
+
+// cachePrefetch holds a batch's cache and negative-cache values, read with
+// one MGET, and the store backfills to write with one pipeline afterwards.
+// A nil *cachePrefetch reads straight from Redis.
+type cachePrefetch struct {
+	vals map[string]string
+
+	mu     sync.Mutex
+	writes map[string]string
+}
+
+func prefetchCache(ctx context.Context, d ResolveDeps, pkeys []string) *cachePrefetch {
+	p := &cachePrefetch{writes: map[string]string{}}
+	keys := make([]string, 0, 2*len(pkeys))
+	for _, pkey := range pkeys {
+		keys = append(keys, "prop:miss:"+pkey, cache.PropertyKey(pkey))
+	}
+	vals, err := d.Redis.MGet(ctx, keys...)
 	if err != nil {
-		return nil, nil, false, err
+		// Fall back to per-item reads.
+		return p
+	}
+	p.vals = make(map[string]string, len(keys))
+	for i, k := range keys {
+		p.vals[k] = vals[i]
+	}
+	return p
+}
+
+func (p *cachePrefetch) get(ctx context.Context, d ResolveDeps, key string) (string, error) {
+	if p != nil && p.vals != nil {
+		if v, ok := p.vals[key]; ok {
+			return v, nil
+		}
 	}
-	cards, err := attom.MapSearchPayloadToCards(raw)
+	return d.Redis.Get(ctx, key)
+}
+
+func (p *cachePrefetch) backfill(key string, env cache.Envelope) {
+	val, err := cache.Encode(env)
 	if err != nil {
-		return nil, nil, false, err
+		return
 	}
+	p.mu.Lock()
+	p.writes[key] = val
+	p.mu.Unlock()
+}
+
+func (p *cachePrefetch) flush(ctx context.Context, d ResolveDeps) {
+	_ = d.Redis.SetBatch(ctx, p.writes, cacheTTL(d))
+}
+
+// matchCard filters a ZIP search page by normalized address.
+func matchCard(cards []attom.PropertyCard, line1, city, state, zip string) (attom.PropertyCard, bool) {
 	n1, c, st, _, _ := canon.Canonicalize(line1, city, state, zip)
 	for _, card := range cards {
 		ln1, cy, st2, _, _ := canon.Canonicalize(card.Address, card.City, card.State, card.Zip)
-		if ln1 == n1 && cy == c && st2 == st {
-			return raw, card, true, nil
+		if ln1 == n1 && canon.CityKey(cy) == canon.CityKey(c) && st2 == st {
+			return card, true
+		}
+	}
+	// only the first page is searched to avoid heavy quota
+	return attom.PropertyCard{}, false
+}
+
+var errFetchBudget = errors.New("provider call budget exhausted")
+
+// zipFetcher fetches the first search page per ZIP at most once and caps the
+// number of distinct ZIPs claimed. Budget zero means unlimited.
+type zipFetcher struct {
+	rapid  *attom.Client
+	budget int
+
+	mu       sync.Mutex
+	reserved int
+	calls    int // provider requests actually sent
+	pages    map[string]*zipPage
+}
+
+type zipPage struct {
+	once  sync.Once
+	raw   []byte
+	cards []attom.PropertyCard
+	err   error
+}
+
+func newZipFetcher(rapid *attom.Client, budget int) *zipFetcher {
+	return &zipFetcher{rapid: rapid, budget: budget, pages: map[string]*zipPage{}}
+}
+
+// reserve claims a provider call for zip unless the page is already claimed.
+func (f *zipFetcher) reserve(zip string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.pages[zip]; ok {
+		return nil
+	}
+	if f.budget > 0 && f.reserved >= f.budget {
+		return errFetchBudget
+	}
+	f.reserved++
+	f.pages[zip] = &zipPage{}
+	return nil
+}
+
+func (f *zipFetcher) page(ctx context.Context, zip string) ([]byte, []attom.PropertyCard, error) {
+	f.mu.Lock()
+	p, ok := f.pages[zip]
+	f.mu.Unlock()
+	if !ok {
+		if err := f.reserve(zip); err != nil {
+			return nil, nil, err
 		}
+		return f.page(ctx, zip)
 	}
-	// not found in first page; give up for now to avoid heavy quota
-	return raw, nil, false, nil
+	p.once.Do(func() {
+		f.mu.Lock()
+		f.calls++
+		f.mu.Unlock()
+		p.raw, p.err = f.rapid.SearchByPostal(ctx, zip, 20, 1, "", "")
+		if p.err == nil {
+			p.cards, p.err = attom.MapSearchPayloadToCards(p.raw)
+		}
+	})
+	return p.raw, p.cards, p.err
+}
+
+func (f *zipFetcher) providerCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
 }
 
 func maxDur(a, b time.Duration) time.Duration {
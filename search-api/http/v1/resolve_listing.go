@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/cache"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/events"
+	"github.com/yourorg/search-api/internal/valuation"
+)
+
+// resolveByListing resolves a provider listing_id for clients that have no
+// address. The store maps the listing to its property key, whose cache entry
+// or stored listing is served; a listing the store doesn't know is fetched
+// from the provider's detail endpoint, persisted and cached exactly as an
+// address resolve would be.
+func resolveByListing(ctx context.Context, d ResolveDeps, listingID string) ResolveResult {
+	res := resolveListingPipeline(ctx, d, listingID)
+	if res.OK {
+		d.Hydrator.Publish(ctx, events.PropertyResolved{PropertyKey: res.PropertyKey, Source: res.Source})
+	}
+	return res
+}
+
+func resolveListingPipeline(ctx context.Context, d ResolveDeps, listingID string) ResolveResult {
+	if listingID == "" {
+		return failed("", apierror.BadRequest("listing_id_required", "provider listing ID is required"))
+	}
+	missKey := "listing:miss:" + listingID
+	if v, _ := d.Redis.Get(ctx, missKey); v != "" {
+		return failed("", apierror.NotFound("listing_not_found", "listing not found").With("cache_miss_cooldown", true))
+	}
+
+	if d.Hydrator != nil && d.Hydrator.Store != nil {
+		pkey, err := d.Hydrator.Store.LookupPropertyKeyByListing(ctx, listingID)
+		if err != nil {
+			log.Printf("[WARN] listing lookup failed for %s: %v", listingID, err)
+		}
+		if pkey != "" {
+			if res, ok := resolveKnownKey(ctx, d, pkey); ok {
+				return res
+			}
+		}
+	}
+
+	raw, card, found, err := d.Rapid.GetListingDetail(ctx, listingID)
+	if err != nil {
+		return failed("", apierror.FromError(err, apierror.Upstream))
+	}
+	if !found {
+		_ = d.Redis.Set(ctx, missKey, "1", d.NegativeTTL)
+		return failed("", apierror.NotFound("listing_not_found", "listing not found"))
+	}
+	line1, city, st, zip, pkey := canon.Canonicalize(card.Address, card.City, card.State, card.Zip)
+	if d.Hydrator != nil {
+		_ = d.Hydrator.Write(ctx, "rapidapi.realtor16", "property", raw, map[string]string{"line1": line1, "city": city, "state": st, "zip": zip, "property_key": pkey}, card)
+	}
+	if d.Valuation != nil {
+		if est, err := d.Valuation.Get(ctx, valuation.SubjectFromCard(pkey, card)); err == nil && est != nil {
+			card.EstimatedValue = est.CardValue()
+		}
+	}
+	writeCache(ctx, d, cache.PropertyKey(pkey), card, "rapidapi", line1, city, st, zip)
+	norm := cache.Normalized{Line1: line1, City: city, State: st, Zip: zip}
+	return ResolveResult{Status: http.StatusOK, OK: true, Source: "fresh", PropertyKey: pkey, Normalized: normalizedMap(norm), Data: card}
+}
+
+// resolveKnownKey serves a property key the store already maps to from its
+// cache entry, refreshing it in the background when stale, or else from its
+// stored listing. ok is false when neither has it.
+func resolveKnownKey(ctx context.Context, d ResolveDeps, pkey string) (ResolveResult, bool) {
+	if env, err := cache.Get(ctx, d.Redis, cache.PropertyKey(pkey)); err == nil && env != nil {
+		stale := env.Meta.Stale(time.Now())
+		if stale && d.Refetch != nil {
+			d.Refetch(pkey, env.Norm.Line1, env.Norm.City, env.Norm.State, env.Norm.Zip)
+		}
+		return ResolveResult{Status: http.StatusOK, OK: true, Source: "cache", Stale: stale, PropertyKey: pkey, Normalized: normalizedMap(env.Norm), Data: env.Data}, true
+	}
+	if card, ok := storedCard(ctx, d, pkey); ok {
+		line1, city, st, zip, _ := canon.Canonicalize(card.Address, card.City, card.State, card.Zip)
+		writeCache(ctx, d, cache.PropertyKey(pkey), card, "store", line1, city, st, zip)
+		norm := cache.Normalized{Line1: line1, City: city, State: st, Zip: zip}
+		return ResolveResult{Status: http.StatusOK, OK: true, Source: "store", PropertyKey: pkey, Normalized: normalizedMap(norm), Data: card}, true
+	}
+	return ResolveResult{}, false
+}
+
+func normalizedMap(n cache.Normalized) map[string]string {
+	return map[string]string{"line1": n.Line1, "city": n.City, "state": n.State, "zip": n.Zip}
+}
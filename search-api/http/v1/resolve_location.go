@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/yourorg/search-api/internal/apierror"
+)
+
+const (
+	defaultLocationRadius = 25.0  // meters
+	maxLocationRadius     = 250.0 // meters
+)
+
+// ResolveLocationRequest resolves the property at a map point.
+type ResolveLocationRequest struct {
+	Lat *float64 `json:"lat"`
+	Lon *float64 `json:"lon"`
+	// RadiusMeters bounds the nearest stored property search.
+	RadiusMeters float64 `json:"radius_m,omitempty" doc:"Search radius for stored properties in meters, default 25, at most 250"`
+}
+
+// LocationMatch says how a point was tied to a property: the nearest stored
+// property ("nearest") or the reverse-geocoded address ("geocode").
+type LocationMatch struct {
+	Method         string   `json:"method"`
+	DistanceMeters *float64 `json:"distance_meters,omitempty"`
+}
+
+// resolveByLocation ties a map tap to a property: the nearest stored
+// property within the radius is resolved by its address; with none, the
+// point is reverse geocoded and that address resolved. Either way the result
+// goes through the address resolve pipeline, so caching and the response
+// envelope are the same.
+func resolveByLocation(ctx context.Context, d ResolveDeps, body ResolveLocationRequest) ResolveResult {
+	if body.Lat == nil || body.Lon == nil {
+		return failed("", apierror.BadRequest("location_required", "lat and lon are required"))
+	}
+	lat, lon := *body.Lat, *body.Lon
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return failed("", apierror.BadRequest("invalid_location", "lat must be between -90 and 90 and lon between -180 and 180"))
+	}
+	radius := body.RadiusMeters
+	if radius <= 0 {
+		radius = defaultLocationRadius
+	}
+	radius = math.Min(radius, maxLocationRadius)
+
+	if pg := d.postgres(); pg != nil {
+		near, err := pg.NearestProperty(ctx, lat, lon, radius)
+		if err != nil {
+			log.Printf("[WARN] nearest property lookup failed at %f,%f: %v", lat, lon, err)
+		} else if near != nil {
+			res := resolveAddress(ctx, d, ResolveRequest{Address: near.AddressLine1, City: near.City, State: near.State, Zip: near.Zip}, newZipFetcher(d.Rapid, 0), nil)
+			dist := math.Round(near.DistanceMeters*10) / 10
+			res.Match = &LocationMatch{Method: "nearest", DistanceMeters: &dist}
+			return res
+		}
+	}
+
+	if d.Geocoder == nil {
+		return failed("", apierror.NotFound("not_found", "no stored property near the location"))
+	}
+	addr, found, err := d.Geocoder.Reverse(ctx, lat, lon)
+	if err != nil {
+		return failed("", apierror.FromError(err, apierror.New(http.StatusBadGateway, "geocoder_error", "reverse geocoding failed")))
+	}
+	if !found {
+		return failed("", apierror.NotFound("no_address", "no street address at the location"))
+	}
+	res := resolveAddress(ctx, d, ResolveRequest{Address: addr.Line1, City: addr.City, State: addr.State, Zip: addr.Zip}, newZipFetcher(d.Rapid, 0), nil)
+	res.Match = &LocationMatch{Method: "geocode"}
+	return res
+}
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/events"
+)
+
+// resolveByAPN resolves an assessor parcel number to the stored property on
+// that parcel. Parcels are more stable than address text, which matters for
+// rural addresses that canonicalize differently between sources. The
+// provider can't be searched by APN, so only properties ingested with their
+// parcel are found. APNs repeat across counties: without fips, a number
+// recorded in more than one county is a 409 listing the counties.
+func resolveByAPN(ctx context.Context, d ResolveDeps, apn, fips string) ResolveResult {
+	if canon.APN(apn) == "" {
+		return failed("", apierror.BadRequest("apn_required", "assessor parcel number is required"))
+	}
+	if fips = strings.TrimSpace(fips); fips != "" && canon.FIPS(fips) == "" {
+		return failed("", apierror.BadRequest("invalid_fips", "fips must be a 5-digit county FIPS code"))
+	}
+	pg := d.postgres()
+	if pg == nil {
+		return failed("", apierror.StoreUnavailable)
+	}
+	matches, err := pg.LookupPropertiesByAPN(ctx, apn, fips)
+	if err != nil {
+		log.Printf("[WARN] apn lookup failed for %s: %v", apn, err)
+		return failed("", apierror.Internal("store_error", "unable to look up parcel"))
+	}
+	if len(matches) == 0 {
+		return failed("", apierror.NotFound("parcel_not_found", "no property recorded for this parcel"))
+	}
+	var counties []string
+	for _, m := range matches {
+		if m.FIPS != "" && !slices.Contains(counties, m.FIPS) {
+			counties = append(counties, m.FIPS)
+		}
+	}
+	if fips == "" && len(counties) > 1 {
+		return failed("", apierror.New(http.StatusConflict, "apn_ambiguous", "apn is recorded in more than one county; pass fips").With("fips", counties))
+	}
+	pkey := matches[0].PropertyKey
+	res, ok := resolveKnownKey(ctx, d, pkey)
+	if !ok {
+		return failed(pkey, apierror.NotFound("parcel_not_found", "no listing stored for this parcel's property"))
+	}
+	d.Hydrator.Publish(ctx, events.PropertyResolved{PropertyKey: res.PropertyKey, Source: res.Source})
+	return res
+}
@@ -0,0 +1,220 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/proptype"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+type SavedSearchesDeps struct {
+	Store *store.Store
+}
+
+// SavedSearchRequest creates a saved search, or on PATCH changes its name,
+// email or frequency; omitted fields are left as they are.
+type SavedSearchRequest struct {
+	Name      *string                    `json:"name,omitempty"`
+	Email     *string                    `json:"email,omitempty" doc:"Where digests are sent; required on create"`
+	Frequency *string                    `json:"frequency,omitempty" doc:"daily, weekly or off; default daily"`
+	Criteria  *store.SavedSearchCriteria `json:"criteria,omitempty" doc:"postalcode, or city and state, plus optional property_type, minprice, maxprice, beds and baths; required on create and fixed after"`
+}
+
+// SavedSearchDTO is a saved search and its digest schedule.
+type SavedSearchDTO struct {
+	ID            string                    `json:"id"`
+	Name          string                    `json:"name"`
+	Email         string                    `json:"email"`
+	Frequency     string                    `json:"frequency"`
+	Criteria      store.SavedSearchCriteria `json:"criteria"`
+	CreatedAt     time.Time                 `json:"createdAt"`
+	DigestedUntil time.Time                 `json:"digestedUntil" doc:"Changes after this go in the next digest"`
+	NextDigestAt  *time.Time                `json:"nextDigestAt,omitempty" doc:"Absent while frequency is off"`
+}
+
+func savedSearchDTO(ss store.SavedSearch) SavedSearchDTO {
+	dto := SavedSearchDTO{
+		ID:            ss.ID,
+		Name:          ss.Name,
+		Email:         ss.Email,
+		Frequency:     ss.Frequency,
+		Criteria:      ss.Criteria,
+		CreatedAt:     ss.CreatedAt,
+		DigestedUntil: ss.DigestedUntil,
+	}
+	if ss.Frequency != store.DigestOff {
+		dto.NextDigestAt = &ss.NextDigestAt
+	}
+	return dto
+}
+
+// validate checks and normalizes the fields that are set.
+func (b *SavedSearchRequest) validate() *apierror.Error {
+	if b.Name != nil {
+		name := strings.TrimSpace(*b.Name)
+		if len(name) > 200 || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+			return apierror.BadRequest("invalid_name", "name must be at most 200 characters without control characters")
+		}
+		b.Name = &name
+	}
+	if b.Email != nil {
+		addr, err := mail.ParseAddress(strings.TrimSpace(*b.Email))
+		if err != nil || addr.Name != "" {
+			return apierror.BadRequest("invalid_email", "email must be a bare email address")
+		}
+		b.Email = &addr.Address
+	}
+	if b.Frequency != nil {
+		switch *b.Frequency {
+		case store.DigestDaily, store.DigestWeekly, store.DigestOff:
+		default:
+			return apierror.BadRequest("invalid_frequency", "frequency must be daily, weekly or off")
+		}
+	}
+	if c := b.Criteria; c != nil {
+		c.PostalCode = strings.TrimSpace(c.PostalCode)
+		switch {
+		case c.PostalCode != "":
+			if !canon.IsZIP(c.PostalCode) {
+				return apierror.BadRequest("invalid_postalcode", "postalcode must be a 5-digit ZIP")
+			}
+			c.City, c.State = "", ""
+		default:
+			city, state, ok := canon.ParseLocation(c.City + "," + c.State)
+			if !ok || !canon.IsStateCode(state) {
+				return apierror.BadRequest("location_required", "criteria need a postalcode, or a city and a US state")
+			}
+			c.City, c.State = city, state
+		}
+		if c.PropertyType != "" {
+			if c.PropertyType = proptype.Normalize(c.PropertyType); c.PropertyType == "" {
+				return apierror.BadRequest("invalid_property_type", "unknown property_type")
+			}
+		}
+		if c.MinPrice < 0 || c.MaxPrice < 0 || c.Beds < 0 || c.Baths < 0 {
+			return apierror.BadRequest("invalid_criteria", "price, beds and baths must not be negative")
+		}
+	}
+	return nil
+}
+
+// RegisterSavedSearches adds the saved search routes. Saved searches belong
+// to the caller's API key or token subject; the hydrator mails their
+// digests.
+func RegisterSavedSearches(r chi.Router, d SavedSearchesDeps) {
+	r.Route("/v1/saved-searches", func(r chi.Router) {
+		r.Use(auth.Require(auth.ScopeSaved))
+
+		// GET /v1/saved-searches
+		r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			searches, err := d.Store.SavedSearches(req.Context(), callerID(req))
+			if err != nil {
+				apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load saved searches"))
+				return
+			}
+			out := make([]SavedSearchDTO, 0, len(searches))
+			for _, ss := range searches {
+				out = append(out, savedSearchDTO(ss))
+			}
+			render.JSON(w, req, map[string]any{"ok": true, "count": len(out), "saved_searches": out})
+		})
+
+		// POST /v1/saved-searches {"name": "...", "email": "...", "frequency": "daily", "criteria": {...}}
+		r.Post("/", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			var body SavedSearchRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				apierror.WriteError(w, req, err, apierror.InvalidJSON)
+				return
+			}
+			if body.Email == nil || body.Criteria == nil {
+				apierror.Write(w, req, apierror.BadRequest("fields_required", "email and criteria are required"))
+				return
+			}
+			if apiErr := body.validate(); apiErr != nil {
+				apierror.Write(w, req, apiErr)
+				return
+			}
+			ss := store.SavedSearch{Owner: callerID(req), Email: *body.Email, Criteria: *body.Criteria, Frequency: store.DigestDaily}
+			if body.Name != nil {
+				ss.Name = *body.Name
+			}
+			if body.Frequency != nil {
+				ss.Frequency = *body.Frequency
+			}
+			ss, err := d.Store.CreateSavedSearch(req.Context(), ss)
+			if err != nil {
+				apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to save search"))
+				return
+			}
+			render.JSON(w, req, map[string]any{"ok": true, "saved_search": savedSearchDTO(ss)})
+		})
+
+		// PATCH /v1/saved-searches/{id} {"frequency": "weekly"}
+		r.Patch("/{id}", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			var body SavedSearchRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				apierror.WriteError(w, req, err, apierror.InvalidJSON)
+				return
+			}
+			if body.Criteria != nil {
+				apierror.Write(w, req, apierror.BadRequest("criteria_fixed", "criteria cannot be changed; create a new saved search"))
+				return
+			}
+			if apiErr := body.validate(); apiErr != nil {
+				apierror.Write(w, req, apiErr)
+				return
+			}
+			ss, err := d.Store.UpdateSavedSearch(req.Context(), callerID(req), chi.URLParam(req, "id"),
+				store.SavedSearchUpdate{Name: body.Name, Email: body.Email, Frequency: body.Frequency})
+			if err != nil {
+				writeSavedSearchError(w, req, err)
+				return
+			}
+			render.JSON(w, req, map[string]any{"ok": true, "saved_search": savedSearchDTO(ss)})
+		})
+
+		// DELETE /v1/saved-searches/{id}
+		r.Delete("/{id}", func(w http.ResponseWriter, req *http.Request) {
+			if d.Store == nil {
+				apierror.Write(w, req, apierror.StoreUnavailable)
+				return
+			}
+			if err := d.Store.DeleteSavedSearch(req.Context(), callerID(req), chi.URLParam(req, "id")); err != nil {
+				writeSavedSearchError(w, req, err)
+				return
+			}
+			render.JSON(w, req, map[string]any{"ok": true})
+		})
+	})
+}
+
+func writeSavedSearchError(w http.ResponseWriter, req *http.Request, err error) {
+	if errors.Is(err, store.ErrSavedSearchNotFound) {
+		apierror.Write(w, req, apierror.NotFound("saved_search_not_found", "saved search not found").WithDetail(err.Error()))
+		return
+	}
+	apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to update saved search"))
+}
@@ -0,0 +1,167 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// ShadowSummaryDTO totals a candidate provider's comparisons on one route.
+type ShadowSummaryDTO struct {
+	Candidate       string           `json:"candidate"`
+	Route           string           `json:"route"`
+	Comparisons     int64            `json:"comparisons"`
+	Agreed          int64            `json:"agreed" doc:"Comparisons with the same listings and no field disagreeing"`
+	AgreementRatio  float64          `json:"agreementRatio"`
+	PrimaryErrors   int64            `json:"primaryErrors"`
+	CandidateErrors int64            `json:"candidateErrors"`
+	Matched         int64            `json:"matched" doc:"Listings both providers returned"`
+	OnlyPrimary     int64            `json:"onlyPrimary" doc:"Listings only the primary returned"`
+	OnlyCandidate   int64            `json:"onlyCandidate" doc:"Listings only the candidate returned"`
+	Mismatches      int64            `json:"mismatches" doc:"Fields that disagreed on matched listings"`
+	Fields          map[string]int64 `json:"fields" doc:"Mismatches by field"`
+	MedianMs        int              `json:"medianMs" doc:"Median candidate latency"`
+}
+
+// ShadowResultDTO is one search both providers answered. The answers
+// themselves are included by the detail route only.
+type ShadowResultDTO struct {
+	ID             int64                  `json:"id"`
+	Candidate      string                 `json:"candidate"`
+	Route          string                 `json:"route"`
+	Location       string                 `json:"location"`
+	Params         json.RawMessage        `json:"params"`
+	Agrees         bool                   `json:"agrees"`
+	PrimaryCount   int                    `json:"primaryCount"`
+	CandidateCount int                    `json:"candidateCount"`
+	Matched        int                    `json:"matched"`
+	OnlyPrimary    []string               `json:"onlyPrimary" doc:"Property keys only the primary returned"`
+	OnlyCandidate  []string               `json:"onlyCandidate" doc:"Property keys only the candidate returned"`
+	Mismatches     []store.ShadowMismatch `json:"mismatches"`
+	PrimaryError   string                 `json:"primaryError,omitempty"`
+	CandidateError string                 `json:"candidateError,omitempty"`
+	CandidateMs    int                    `json:"candidateMs"`
+	CreatedAt      time.Time              `json:"createdAt"`
+	PrimaryCards   json.RawMessage        `json:"primaryListings,omitempty" doc:"The primary's listings, without photos"`
+	CandidateCards json.RawMessage        `json:"candidateListings,omitempty" doc:"The candidate's listings, without photos"`
+}
+
+func shadowResultDTO(r store.ShadowResult) ShadowResultDTO {
+	return ShadowResultDTO{
+		ID:             r.ID,
+		Candidate:      r.Candidate,
+		Route:          r.Route,
+		Location:       r.Location,
+		Params:         r.Params,
+		Agrees:         r.Agrees(),
+		PrimaryCount:   r.PrimaryCount,
+		CandidateCount: r.CandidateCount,
+		Matched:        r.Matched,
+		OnlyPrimary:    r.OnlyPrimary,
+		OnlyCandidate:  r.OnlyCandidate,
+		Mismatches:     r.Mismatches,
+		PrimaryError:   r.PrimaryError,
+		CandidateError: r.CandidateError,
+		CandidateMs:    r.CandidateMs,
+		CreatedAt:      r.CreatedAt,
+		PrimaryCards:   r.PrimaryCards,
+		CandidateCards: r.CandidateCards,
+	}
+}
+
+// GET /v1/admin/shadow?from=2024-05-01&to=2024-06-01&candidate=realtor&route=listings&all=true&limit=50
+//
+// Totals per candidate and route, and the latest comparisons: those that
+// disagree, or all of them with all=true.
+func shadowReportHandler(d AdminDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		aq, ok := analyticsQuery(w, req)
+		if !ok {
+			return
+		}
+		q := req.URL.Query()
+		sq := store.ShadowQuery{From: aq.From, To: aq.To, Candidate: q.Get("candidate"), Route: aq.Route, Limit: aq.Limit, Disagreements: true}
+		if v := q.Get("all"); v != "" {
+			all, err := strconv.ParseBool(v)
+			if err != nil {
+				apierror.Write(w, req, apierror.BadRequest("invalid_all", "all must be true or false"))
+				return
+			}
+			sq.Disagreements = !all
+		}
+		summaries, err := d.Store.ShadowSummaries(req.Context(), sq)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load shadow summary"))
+			return
+		}
+		results, err := d.Store.ShadowResults(req.Context(), sq)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load shadow results"))
+			return
+		}
+		sums := make([]ShadowSummaryDTO, 0, len(summaries))
+		for _, s := range summaries {
+			dto := ShadowSummaryDTO{
+				Candidate:       s.Candidate,
+				Route:           s.Route,
+				Comparisons:     s.Comparisons,
+				Agreed:          s.Agreed,
+				PrimaryErrors:   s.PrimaryErrors,
+				CandidateErrors: s.CandidateErrors,
+				Matched:         s.Matched,
+				OnlyPrimary:     s.OnlyPrimary,
+				OnlyCandidate:   s.OnlyCandidate,
+				Mismatches:      s.Mismatches,
+				Fields:          s.Fields,
+				MedianMs:        s.MedianMs,
+			}
+			if s.Comparisons > 0 {
+				dto.AgreementRatio = float64(s.Agreed) / float64(s.Comparisons)
+			}
+			sums = append(sums, dto)
+		}
+		out := make([]ShadowResultDTO, 0, len(results))
+		for _, r := range results {
+			out = append(out, shadowResultDTO(r))
+		}
+		render.JSON(w, req, map[string]any{
+			"ok": true, "from": sq.From, "to": sq.To, "summary": sums, "count": len(out), "results": out,
+		})
+	}
+}
+
+// GET /v1/admin/shadow/{id}
+func shadowResultHandler(d AdminDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+		if err != nil || id < 1 {
+			apierror.Write(w, req, apierror.BadRequest("invalid_id", "id must be a positive integer"))
+			return
+		}
+		r, err := d.Store.ShadowResult(req.Context(), id)
+		if errors.Is(err, store.ErrShadowResultNotFound) {
+			apierror.Write(w, req, apierror.NotFound("shadow_result_not_found", "shadow result not found").With("id", id))
+			return
+		}
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load shadow result"))
+			return
+		}
+		render.JSON(w, req, map[string]any{"ok": true, "result": shadowResultDTO(r)})
+	}
+}
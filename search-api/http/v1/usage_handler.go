@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/usage"
+)
+
+// UsageDTO is one consumer's usage in one hour or day, or over the whole
+// range in the totals.
+type UsageDTO struct {
+	Consumer      string     `json:"consumer" doc:"API key name (key:...), token subject (jwt:...) or anonymous"`
+	Bucket        *time.Time `json:"bucket,omitempty" doc:"Start of the hour or UTC day; absent in totals"`
+	Requests      int64      `json:"requests"`
+	ProviderCalls int64      `json:"providerCalls" doc:"Provider HTTP calls the requests caused, retries included"`
+	CacheHits     int64      `json:"cacheHits" doc:"Answered from the cache, fresh or stale"`
+	CacheMisses   int64      `json:"cacheMisses" doc:"Answered from the database"`
+	ProviderReads int64      `json:"providerReads" doc:"Answered from the provider"`
+	Errors        int64      `json:"errors" doc:"5xx responses"`
+	CacheHitRatio float64    `json:"cacheHitRatio" doc:"cacheHits over requests with a cache result"`
+}
+
+func usageDTO(consumer string, bucket *time.Time, c store.UsageCounts) UsageDTO {
+	return UsageDTO{
+		Consumer:      consumer,
+		Bucket:        bucket,
+		Requests:      c.Requests,
+		ProviderCalls: c.ProviderCalls,
+		CacheHits:     c.CacheHits,
+		CacheMisses:   c.CacheMisses,
+		ProviderReads: c.ProviderReads,
+		Errors:        c.Errors,
+		CacheHitRatio: usage.HitRatio(c),
+	}
+}
+
+// GET /v1/admin/usage?from=2024-05-01&to=2024-06-01&consumer=key:mobile&granularity=day&format=csv
+func usageHandler(d AdminDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if d.Store == nil {
+			apierror.Write(w, req, apierror.StoreUnavailable)
+			return
+		}
+		q := req.URL.Query()
+		now := time.Now().UTC()
+		uq := store.UsageQuery{From: now.AddDate(0, 0, -7).Truncate(24 * time.Hour), To: now, Consumer: q.Get("consumer")}
+		for _, p := range []struct {
+			name string
+			dst  *time.Time
+		}{{"from", &uq.From}, {"to", &uq.To}} {
+			if v := q.Get(p.name); v != "" {
+				t, err := parseSince(v)
+				if err != nil {
+					apierror.Write(w, req, apierror.BadRequest("invalid_"+p.name, p.name+" must be a date (2006-01-02) or RFC 3339 timestamp").WithDetail(err.Error()))
+					return
+				}
+				*p.dst = t
+			}
+		}
+		switch q.Get("granularity") {
+		case "", "day":
+			uq.Day = true
+		case "hour":
+		default:
+			apierror.Write(w, req, apierror.BadRequest("invalid_granularity", "granularity must be hour or day"))
+			return
+		}
+		format := q.Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			apierror.Write(w, req, apierror.BadRequest("invalid_format", "format must be json or csv"))
+			return
+		}
+
+		rows, err := d.Store.Usage(req.Context(), uq)
+		if err != nil {
+			apierror.WriteError(w, req, err, apierror.Internal("store_error", "unable to load usage"))
+			return
+		}
+		out := make([]UsageDTO, 0, len(rows))
+		totals := map[string]*store.UsageCounts{}
+		for _, row := range rows {
+			out = append(out, usageDTO(row.Consumer, &row.Bucket, row.UsageCounts))
+			if totals[row.Consumer] == nil {
+				totals[row.Consumer] = &store.UsageCounts{}
+			}
+			totals[row.Consumer].Add(row.UsageCounts)
+		}
+
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="usage-`+uq.From.Format("20060102")+`-`+uq.To.Format("20060102")+`.csv"`)
+			cw := csv.NewWriter(w)
+			_ = cw.Write([]string{"consumer", "bucket", "requests", "provider_calls", "cache_hits", "cache_misses", "provider_reads", "errors", "cache_hit_ratio"})
+			for _, u := range out {
+				_ = cw.Write([]string{
+					u.Consumer, u.Bucket.Format(time.RFC3339),
+					strconv.FormatInt(u.Requests, 10), strconv.FormatInt(u.ProviderCalls, 10),
+					strconv.FormatInt(u.CacheHits, 10), strconv.FormatInt(u.CacheMisses, 10),
+					strconv.FormatInt(u.ProviderReads, 10), strconv.FormatInt(u.Errors, 10),
+					strconv.FormatFloat(u.CacheHitRatio, 'f', 4, 64),
+				})
+			}
+			cw.Flush()
+			return
+		}
+
+		sums := make([]UsageDTO, 0, len(totals))
+		for consumer, c := range totals {
+			sums = append(sums, usageDTO(consumer, nil, *c))
+		}
+		sort.Slice(sums, func(i, j int) bool { return sums[i].ProviderCalls > sums[j].ProviderCalls })
+		render.JSON(w, req, map[string]any{
+			"ok": true, "from": uq.From, "to": uq.To, "count": len(out), "usage": out, "totals": sums,
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package httpapi
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/proptype"
+)
+
+// maxPageSize caps limit on search routes; larger pages are rejected rather
+// than passed through to the provider.
+const maxPageSize = 50
+
+// validator collects field errors so a request reports every bad field at
+// once. Handlers run it before any store or provider work and write a 422
+// when it failed.
+type validator struct {
+	fields []apierror.FieldError
+}
+
+func (v *validator) fail(field, code, message string) {
+	v.fields = append(v.fields, apierror.FieldError{Field: field, Code: code, Message: message})
+}
+
+// err is nil when every check passed.
+func (v *validator) err() *apierror.Error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return apierror.Invalid(v.fields)
+}
+
+// intParam parses an optional integer query parameter into dst.
+func (v *validator) intParam(q url.Values, name string, dst **int) {
+	s := q.Get(name)
+	if s == "" {
+		return
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.fail(name, "not_integer", name+" must be an integer")
+		return
+	}
+	*dst = &i
+}
+
+// floatParam parses an optional numeric query parameter into dst. NaN and
+// infinities are refused: range checks are false for NaN, so they would
+// pass them.
+func (v *validator) floatParam(q url.Values, name string, dst **float64) {
+	s := q.Get(name)
+	if s == "" {
+		return
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || !finite(f) {
+		v.fail(name, "not_number", name+" must be a number")
+		return
+	}
+	*dst = &f
+}
+
+// boolParam parses an optional true/false query parameter into dst.
+func (v *validator) boolParam(q url.Values, name string, dst **bool) {
+	s := q.Get(name)
+	if s == "" {
+		return
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.fail(name, "not_boolean", name+" must be true or false")
+		return
+	}
+	*dst = &b
+}
+
+func (v *validator) paging(limit, page *int, orderBy string) {
+	if limit != nil && (*limit < 1 || *limit > maxPageSize) {
+		v.fail("limit", "out_of_range", "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+	}
+	if page != nil && *page < 1 {
+		v.fail("page", "out_of_range", "page must be 1 or greater")
+	}
+	if _, ok := attom.SortValue(orderBy); orderBy != "" && !ok {
+		v.fail("orderby", "unsupported", "orderby must be one of "+strings.Join(attom.SortOrders(), ", "))
+	}
+}
+
+// location checks the ZIP and state codes a search names. Whether enough of
+// them were given is left to resolveLocation.
+func (v *validator) location(postal, location, state string) {
+	if postal = strings.TrimSpace(postal); postal != "" && !canon.IsZIP(postal) {
+		v.fail("postalcode", "invalid_zip", "postalcode must be a 5-digit ZIP")
+	}
+	if location = strings.TrimSpace(location); location != "" && !canon.IsZIP(location) {
+		if _, st, ok := canon.ParseLocation(location); ok && !canon.IsStateCode(st) {
+			v.fail("location", "invalid_state", "location must end in a US state code or name, as in \"Austin, TX\"")
+		}
+	}
+	if state = strings.TrimSpace(state); state != "" {
+		if _, st, ok := canon.ParseLocation("x," + state); !ok || !canon.IsStateCode(st) {
+			v.fail("state", "invalid_state", "state must be a US state code or name")
+		}
+	}
+}
+
+func (v *validator) nonNegative(name string, n *int) {
+	if n != nil && *n < 0 {
+		v.fail(name, "out_of_range", name+" must not be negative")
+	}
+}
+
+func (b SearchRequest) validate(v *validator) {
+	v.paging(b.Limit, b.Page, b.OrderBy)
+	v.propertyType(b.PropertyType)
+	v.location(b.PostalCode, b.Location, b.State)
+	if b.Lat != nil && !(*b.Lat >= -90 && *b.Lat <= 90) {
+		v.fail("lat", "out_of_range", "lat must be between -90 and 90")
+	}
+	if b.Lon != nil && !(*b.Lon >= -180 && *b.Lon <= 180) {
+		v.fail("lon", "out_of_range", "lon must be between -180 and 180")
+	}
+	if b.Radius != nil && !(*b.Radius > 0 && finite(*b.Radius)) {
+		v.fail("radius", "out_of_range", "radius must be a finite number greater than 0")
+	}
+}
+
+func finite(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }
+
+func (v *validator) propertyType(t string) {
+	if t != "" && proptype.Normalize(t) == "" {
+		v.fail("property_type", "unsupported", "property_type must be one of "+strings.Join(proptype.Types, ", "))
+	}
+}
+
+func (b ListingsRequest) validate(v *validator) {
+	v.paging(b.Limit, b.Page, b.OrderBy)
+	v.propertyType(b.PropertyType)
+	v.location(b.PostalCode, b.Location, b.State)
+	v.nonNegative("beds", b.Beds)
+	v.nonNegative("baths", b.Baths)
+	v.nonNegative("minprice", b.MinPrice)
+	v.nonNegative("maxprice", b.MaxPrice)
+	v.nonNegative("min_dom", b.MinDOM)
+	v.nonNegative("max_dom", b.MaxDOM)
+	v.nonNegative("max_hoa", b.MaxHOA)
+	v.nonNegative("min_garage", b.MinGarage)
+	v.nonNegative("min_stories", b.MinStories)
+	if b.MinDOM != nil && b.MaxDOM != nil && *b.MinDOM > *b.MaxDOM {
+		v.fail("max_dom", "out_of_range", "max_dom must not be below min_dom")
+	}
+	if b.MinPrice != nil && b.MaxPrice != nil && *b.MaxPrice > 0 && *b.MinPrice > *b.MaxPrice {
+		v.fail("maxprice", "out_of_range", "maxprice must not be below minprice")
+	}
+}
@@ -0,0 +1,91 @@
+package addrverify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultSmartyURL = "https://us-street.api.smarty.com"
+
+// Smarty verifies with the Smarty (SmartyStreets) US Street Address API.
+type Smarty struct {
+	BaseURL   string // default the public API
+	AuthID    string
+	AuthToken string
+	Client    *http.Client
+}
+
+func (s *Smarty) Verify(ctx context.Context, a Address) (Result, error) {
+	base := s.BaseURL
+	if base == "" {
+		base = defaultSmartyURL
+	}
+	q := url.Values{}
+	q.Set("auth-id", s.AuthID)
+	q.Set("auth-token", s.AuthToken)
+	q.Set("street", a.Line1)
+	q.Set("city", a.City)
+	q.Set("state", a.State)
+	q.Set("zipcode", a.Zip)
+	q.Set("candidates", "1")
+	// invalid returns a candidate for addresses that fail DPV, so those
+	// can be told apart from no match at all.
+	q.Set("match", "invalid")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+"/street-address?"+q.Encode(), nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	client := s.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Result{}, fmt.Errorf("%s: %s: %s", req.URL.Host, resp.Status, body)
+	}
+	var candidates []struct {
+		DeliveryLine1 string `json:"delivery_line_1"`
+		Components    struct {
+			CityName          string `json:"city_name"`
+			StateAbbreviation string `json:"state_abbreviation"`
+			Zipcode           string `json:"zipcode"`
+			Plus4Code         string `json:"plus4_code"`
+		} `json:"components"`
+		Analysis struct {
+			DPVMatchCode string `json:"dpv_match_code"`
+		} `json:"analysis"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&candidates); err != nil {
+		return Result{}, err
+	}
+	r := Result{Provider: "smarty", Address: a}
+	if len(candidates) == 0 {
+		return r, nil
+	}
+	c := candidates[0]
+	r.Found = true
+	r.DPV = c.Analysis.DPVMatchCode
+	if r.DPV == "" {
+		r.DPV = DPVNotConfirmed
+	}
+	r.Address = Address{
+		Line1: c.DeliveryLine1,
+		City:  c.Components.CityName,
+		State: c.Components.StateAbbreviation,
+		Zip:   c.Components.Zipcode,
+		Plus4: c.Components.Plus4Code,
+	}
+	r.ZipCorrected = a.Zip != "" && zip5(a.Zip) != r.Address.Zip
+	return r, nil
+}
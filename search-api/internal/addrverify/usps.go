@@ -0,0 +1,162 @@
+package addrverify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultUSPSURL = "https://apis.usps.com"
+
+// USPS verifies with the USPS Addresses API (v3), authenticating with OAuth
+// client credentials. The token is reused until shortly before it expires.
+type USPS struct {
+	BaseURL      string // default the production API
+	ClientID     string
+	ClientSecret string
+	Client       *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (u *USPS) Verify(ctx context.Context, a Address) (Result, error) {
+	token, err := u.accessToken(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	q := url.Values{}
+	q.Set("streetAddress", a.Line1)
+	q.Set("city", a.City)
+	q.Set("state", a.State)
+	if z := zip5(a.Zip); z != "" {
+		q.Set("ZIPCode", z)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.base()+"/addresses/v3/address?"+q.Encode(), nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := u.client().Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	r := Result{Provider: "usps", Address: a}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// No such address.
+		return r, nil
+	case http.StatusUnauthorized:
+		u.mu.Lock()
+		u.token = ""
+		u.mu.Unlock()
+		fallthrough
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Result{}, fmt.Errorf("%s: %s: %s", req.URL.Host, resp.Status, body)
+	}
+	var body struct {
+		Address struct {
+			StreetAddress    string `json:"streetAddress"`
+			SecondaryAddress string `json:"secondaryAddress"`
+			City             string `json:"city"`
+			State            string `json:"state"`
+			ZIPCode          string `json:"ZIPCode"`
+			ZIPPlus4         string `json:"ZIPPlus4"`
+		} `json:"address"`
+		AdditionalInfo struct {
+			DPVConfirmation string `json:"DPVConfirmation"`
+		} `json:"additionalInfo"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Result{}, err
+	}
+	line1 := body.Address.StreetAddress
+	if body.Address.SecondaryAddress != "" {
+		line1 += " " + body.Address.SecondaryAddress
+	}
+	r.Found = true
+	r.DPV = body.AdditionalInfo.DPVConfirmation
+	if r.DPV == "" {
+		r.DPV = DPVNotConfirmed
+	}
+	r.Address = Address{
+		Line1: line1,
+		City:  body.Address.City,
+		State: body.Address.State,
+		Zip:   body.Address.ZIPCode,
+		Plus4: body.Address.ZIPPlus4,
+	}
+	r.ZipCorrected = a.Zip != "" && zip5(a.Zip) != r.Address.Zip
+	return r, nil
+}
+
+func (u *USPS) base() string {
+	if u.BaseURL == "" {
+		return defaultUSPSURL
+	}
+	return strings.TrimRight(u.BaseURL, "/")
+}
+
+func (u *USPS) client() *http.Client {
+	if u.Client == nil {
+		return defaultHTTPClient
+	}
+	return u.Client
+}
+
+// accessToken returns the cached token or fetches a new one.
+func (u *USPS) accessToken(ctx context.Context) (string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.token != "" && time.Now().Before(u.expires) {
+		return u.token, nil
+	}
+	payload, _ := json.Marshal(map[string]string{
+		"grant_type":    "client_credentials",
+		"client_id":     u.ClientID,
+		"client_secret": u.ClientSecret,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.base()+"/oauth2/v3/token", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := u.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("%s token: %s: %s", req.URL.Host, resp.Status, body)
+	}
+	var tok struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+	if tok.AccessToken == "" {
+		return "", fmt.Errorf("%s token: no access_token in response", req.URL.Host)
+	}
+	ttl := 8 * time.Hour
+	if secs, err := tok.ExpiresIn.Int64(); err == nil && secs > 0 {
+		ttl = time.Duration(secs) * time.Second
+	}
+	// Renew a minute early so a request never carries an expired token.
+	u.token, u.expires = tok.AccessToken, time.Now().Add(ttl-time.Minute)
+	return u.token, nil
+}
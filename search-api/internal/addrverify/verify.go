@@ -0,0 +1,136 @@
+// Package addrverify standardizes and validates US street addresses with an
+// external verification service before they are canonicalized: it corrects
+// ZIPs and spellings and reports whether USPS delivers to the address (DPV,
+// delivery point validation). Results are cached in Redis, so each address
+// is verified once per TTL.
+package addrverify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/redisx"
+)
+
+const defaultCacheTTL = 30 * 24 * time.Hour
+
+var defaultHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// Address is a US street address. Plus4 is set on verified addresses only.
+type Address struct {
+	Line1 string `json:"line1"`
+	City  string `json:"city"`
+	State string `json:"state"`
+	Zip   string `json:"zip"`
+	Plus4 string `json:"plus4,omitempty"`
+}
+
+// DPV match codes, as USPS and Smarty report them.
+const (
+	DPVConfirmed        = "Y" // the address is deliverable
+	DPVSecondaryUnknown = "S" // the building is, the unit given isn't known
+	DPVSecondaryMissing = "D" // the building is, but needs a unit
+	DPVNotConfirmed     = "N"
+)
+
+// Result is one verification. Found is false when the service matched no
+// address; Address is then the input.
+type Result struct {
+	Provider     string  `json:"provider"`
+	Found        bool    `json:"found"`
+	Address      Address `json:"address"`
+	DPV          string  `json:"dpv,omitempty" doc:"Delivery point validation: Y confirmed, S unit not confirmed, D unit missing, N not deliverable"`
+	ZipCorrected bool    `json:"zip_corrected,omitempty"`
+	Cached       bool    `json:"cached,omitempty"`
+}
+
+// Deliverable reports whether USPS confirmed the building, with or without
+// the unit.
+func (r Result) Deliverable() bool {
+	switch r.DPV {
+	case DPVConfirmed, DPVSecondaryUnknown, DPVSecondaryMissing:
+		return r.Found
+	}
+	return false
+}
+
+// Verifier standardizes and validates an address.
+type Verifier interface {
+	Verify(ctx context.Context, a Address) (Result, error)
+}
+
+// FromEnv returns the verifier ADDRESS_VERIFY names, "smarty" or "usps",
+// cached in rdb for ADDRESS_VERIFY_CACHE_HOURS (default 720), or nil when
+// it is unset. Smarty takes SMARTY_AUTH_ID and SMARTY_AUTH_TOKEN; USPS
+// takes USPS_CLIENT_ID and USPS_CLIENT_SECRET. ADDRESS_VERIFY_URL points
+// either at another base URL.
+func FromEnv(rdb *redisx.Client) Verifier {
+	var v Verifier
+	switch os.Getenv("ADDRESS_VERIFY") {
+	case "smarty":
+		v = &Smarty{
+			BaseURL:   os.Getenv("ADDRESS_VERIFY_URL"),
+			AuthID:    os.Getenv("SMARTY_AUTH_ID"),
+			AuthToken: os.Getenv("SMARTY_AUTH_TOKEN"),
+		}
+	case "usps":
+		v = &USPS{
+			BaseURL:      os.Getenv("ADDRESS_VERIFY_URL"),
+			ClientID:     os.Getenv("USPS_CLIENT_ID"),
+			ClientSecret: os.Getenv("USPS_CLIENT_SECRET"),
+		}
+	default:
+		return nil
+	}
+	ttl := defaultCacheTTL
+	if h, err := strconv.Atoi(os.Getenv("ADDRESS_VERIFY_CACHE_HOURS")); err == nil && h > 0 {
+		ttl = time.Duration(h) * time.Hour
+	}
+	return &Cached{Verifier: v, Redis: rdb, TTL: ttl}
+}
+
+// Cached remembers Verifier's results, found or not, in Redis for TTL,
+// default 30 days. Errors are not cached.
+type Cached struct {
+	Verifier Verifier
+	Redis    *redisx.Client
+	TTL      time.Duration
+}
+
+func (c *Cached) Verify(ctx context.Context, a Address) (Result, error) {
+	if c.Redis == nil {
+		return c.Verifier.Verify(ctx, a)
+	}
+	key := "addr:verify:" + canon.AddressKey(a.Line1, a.City, a.State, a.Zip)
+	if val, err := c.Redis.Get(ctx, key); err == nil && val != "" {
+		var r Result
+		if json.Unmarshal([]byte(val), &r) == nil {
+			r.Cached = true
+			return r, nil
+		}
+	}
+	r, err := c.Verifier.Verify(ctx, a)
+	if err != nil {
+		return r, err
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	if b, err := json.Marshal(r); err == nil {
+		_ = c.Redis.Set(ctx, key, string(b), ttl)
+	}
+	return r, nil
+}
+
+func zip5(z string) string {
+	if len(z) > 5 {
+		return z[:5]
+	}
+	return z
+}
@@ -0,0 +1,113 @@
+// Package analytics rolls up the searches the API serves — ZIP or city,
+// property type, order, which other filters were set, how many results came
+// back and how the cache answered — hourly in Postgres, so hydrator
+// targeting and cache warming can follow what is searched. Nothing about
+// the caller is kept, and free-text filters are recorded by name only.
+package analytics
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/search-api/internal/logger"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// Recorder counts searches in memory and flushes them to the
+// search_analytics rollup. Its Record method is an access log OnRequest
+// hook.
+type Recorder struct {
+	Store *store.Store
+
+	mu     sync.Mutex
+	counts map[store.SearchKey]*store.SearchCounts
+}
+
+// Record counts a finished request that annotated a search. Failed
+// searches are not counted.
+func (r *Recorder) Record(e logger.Entry) {
+	if r == nil || e.Search == nil || e.Status >= 400 {
+		return
+	}
+	filters := slices.Clone(e.Search.Filters)
+	slices.Sort(filters)
+	k := store.SearchKey{
+		Hour:         time.Now().UTC().Truncate(time.Hour),
+		Route:        e.Search.Route,
+		Zip:          e.Search.Zip,
+		City:         e.Search.City,
+		PropertyType: e.Search.PropertyType,
+		OrderBy:      e.Search.OrderBy,
+		Filters:      strings.Join(slices.Compact(filters), ","),
+		Cache:        e.Cache,
+	}
+	c := store.SearchCounts{Searches: 1, Results: int64(e.Search.Results)}
+	if e.Search.Results == 0 {
+		c.ZeroResults = 1
+	}
+	r.mu.Lock()
+	if r.counts == nil {
+		r.counts = make(map[store.SearchKey]*store.SearchCounts)
+	}
+	if r.counts[k] == nil {
+		r.counts[k] = &store.SearchCounts{}
+	}
+	r.counts[k].Add(c)
+	r.mu.Unlock()
+}
+
+// Flush writes pending counts. Counts that fail to write are kept for the
+// next flush.
+func (r *Recorder) Flush(ctx context.Context) {
+	if r == nil || r.Store == nil {
+		return
+	}
+	r.mu.Lock()
+	counts := r.counts
+	r.counts = nil
+	r.mu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+	rows := make([]store.SearchStatRow, 0, len(counts))
+	for k, c := range counts {
+		rows = append(rows, store.SearchStatRow{SearchKey: k, SearchCounts: *c})
+	}
+	if err := r.Store.AddSearchStats(ctx, rows); err != nil {
+		log.Printf("[WARN] search analytics flush (%d group(s)) failed, will retry: %v", len(rows), err)
+		r.mu.Lock()
+		if r.counts == nil {
+			r.counts = make(map[store.SearchKey]*store.SearchCounts)
+		}
+		for k, c := range counts {
+			if r.counts[k] == nil {
+				r.counts[k] = &store.SearchCounts{}
+			}
+			r.counts[k].Add(*c)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// Run flushes every interval until ctx is done.
+func (r *Recorder) Run(ctx context.Context, every time.Duration) {
+	if every <= 0 {
+		every = time.Minute
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			r.Flush(fctx)
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package apierror is the single error model for HTTP responses. Every
+// handler writes failures through Write so clients always receive
+//
+//	{"ok": false, "error": {"code", "message", "detail", "request_id", "retry_after"}}
+//
+// with a matching status code and, when relevant, a Retry-After header.
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/yourorg/search-api/attom"
+)
+
+// Error is an HTTP-facing failure with a machine-readable code.
+type Error struct {
+	Status     int            `json:"-"`
+	Code       string         `json:"code"`
+	Message    string         `json:"message"`
+	Detail     string         `json:"detail,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	RetryAfter int            `json:"retry_after,omitempty"` // seconds
+	Meta       map[string]any `json:"meta,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.Code + ": " + e.Detail
+	}
+	return e.Code + ": " + e.Message
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// WithDetail returns a copy carrying extra human-readable context.
+func (e *Error) WithDetail(detail string) *Error {
+	c := *e
+	c.Detail = detail
+	return &c
+}
+
+// WithRetryAfter returns a copy that tells clients when to retry.
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	c := *e
+	c.RetryAfter = int(d.Round(time.Second).Seconds())
+	return &c
+}
+
+// With returns a copy with an extra meta field (e.g., the property key).
+func (e *Error) With(key string, val any) *Error {
+	c := *e
+	c.Meta = make(map[string]any, len(e.Meta)+1)
+	for k, v := range e.Meta {
+		c.Meta[k] = v
+	}
+	c.Meta[key] = val
+	return &c
+}
+
+func BadRequest(code, message string) *Error { return New(http.StatusBadRequest, code, message) }
+func NotFound(code, message string) *Error   { return New(http.StatusNotFound, code, message) }
+func Internal(code, message string) *Error   { return New(http.StatusInternalServerError, code, message) }
+
+// FieldError is one request field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Invalid reports request fields that failed validation. They are listed in
+// meta.fields so clients can point at each one.
+func Invalid(fields []FieldError) *Error {
+	return New(http.StatusUnprocessableEntity, "validation_failed", "request has invalid fields").With("fields", fields)
+}
+
+// Unavailable reports a dependency that is not configured or reachable.
+func Unavailable(code, message string) *Error {
+	return New(http.StatusServiceUnavailable, code, message)
+}
+
+// StoreUnavailable is returned by DB-backed routes when Postgres is not configured.
+var StoreUnavailable = Unavailable("store_unavailable", "listing store is not configured")
+
+// FromError maps internal errors onto the API model. Errors that are already
+// *Error pass through; provider quota, timeouts, and cancellations get
+// dedicated codes, as does a request body over its size limit; anything else
+// is reported as fallback.
+func FromError(err error, fallback *Error) *Error {
+	var apiErr *Error
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.As(err, &tooLarge):
+		return BodyTooLarge(tooLarge.Limit)
+	case errors.Is(err, attom.ErrDailyLimitExceeded):
+		e := New(http.StatusTooManyRequests, "provider_quota", "provider daily quota reached")
+		if class, ok := attom.BudgetClass(err); ok {
+			e = e.WithDetail(string(class) + " request budget reached")
+		}
+		e.RetryAfter = secondsUntilUTCMidnight(time.Now())
+		if d, ok := attom.RetryAfter(err); ok && d > 0 {
+			e.RetryAfter = ceilSeconds(d)
+		}
+		return e
+	case errors.Is(err, attom.ErrThrottled):
+		e := New(http.StatusTooManyRequests, "provider_throttled", "provider is rate limiting requests; retry shortly")
+		e.RetryAfter = 1
+		if d, ok := attom.RetryAfter(err); ok && d > 0 {
+			e.RetryAfter = ceilSeconds(d)
+		}
+		return e
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(http.StatusGatewayTimeout, "timeout", "request timed out").WithDetail(err.Error())
+	case errors.Is(err, context.Canceled):
+		return New(499, "canceled", "request canceled")
+	case fallback != nil:
+		return fallback.WithDetail(err.Error())
+	default:
+		return Internal("internal_error", "internal error").WithDetail(err.Error())
+	}
+}
+
+// BodyTooLarge reports a request body over the route's limit of limit bytes.
+func BodyTooLarge(limit int64) *Error {
+	return New(http.StatusRequestEntityTooLarge, "body_too_large", "request body is too large").With("limit_bytes", limit)
+}
+
+// Upstream is the fallback for provider call failures.
+var Upstream = New(http.StatusBadGateway, "upstream_error", "provider request failed")
+
+// Write renders e (stamped with the request ID) and sets the status code once.
+func Write(w http.ResponseWriter, r *http.Request, e *Error) {
+	if e == nil {
+		e = Internal("internal_error", "internal error")
+	}
+	out := *e
+	if out.Status == 0 {
+		out.Status = http.StatusInternalServerError
+	}
+	if out.RequestID == "" {
+		out.RequestID = middleware.GetReqID(r.Context())
+	}
+	if out.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(out.RetryAfter))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(out.Status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": out})
+}
+
+// WriteError maps err with FromError and writes it.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, fallback *Error) {
+	Write(w, r, FromError(err, fallback))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// secondsUntilUTCMidnight matches the attom client's UTC daily quota window.
+func secondsUntilUTCMidnight(now time.Time) int {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return int(next.Sub(now).Seconds()) + 1
+}
+
+// Common request and mapping failures shared across handlers.
+var (
+	InvalidJSON = BadRequest("invalid_json", "request body is not valid JSON")
+	MapError    = New(http.StatusBadGateway, "map_error", "provider payload could not be mapped")
+)
@@ -0,0 +1,165 @@
+// Package archive uploads files to S3 or an S3-compatible store such as
+// MinIO. It signs requests with AWS Signature Version 4 itself and only
+// supports single-request PUTs, which covers the gzipped exports written to it.
+package archive
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourorg/search-api/internal/env"
+)
+
+// S3 writes objects under Prefix in Bucket. Requests are path-style,
+// {Endpoint}/{Bucket}/{key}, which AWS and S3-compatible stores both accept.
+type S3 struct {
+	Endpoint     string // default https://s3.{Region}.amazonaws.com
+	Region       string // default us-east-1
+	Bucket       string
+	Prefix       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	HTTP         *http.Client
+}
+
+// S3FromEnv configures an S3 target from SNAPSHOT_ARCHIVE_BUCKET,
+// SNAPSHOT_ARCHIVE_PREFIX, SNAPSHOT_ARCHIVE_ENDPOINT and the standard AWS_*
+// credential variables. It returns nil when no bucket is set.
+func S3FromEnv() *S3 {
+	return FromEnv("SNAPSHOT_ARCHIVE", "raw-snapshots/")
+}
+
+// FromEnv is S3FromEnv for another set of {name}_BUCKET, {name}_PREFIX and
+// {name}_ENDPOINT variables, with prefix as the default key prefix.
+func FromEnv(name, prefix string) *S3 {
+	bucket := os.Getenv(name + "_BUCKET")
+	if bucket == "" {
+		return nil
+	}
+	return &S3{
+		Endpoint:     os.Getenv(name + "_ENDPOINT"),
+		Region:       env.Get("AWS_REGION", "us-east-1"),
+		Bucket:       bucket,
+		Prefix:       env.Get(name+"_PREFIX", prefix),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+func (c *S3) region() string {
+	if c.Region == "" {
+		return "us-east-1"
+	}
+	return c.Region
+}
+
+func (c *S3) endpoint() string {
+	if c.Endpoint == "" {
+		return "https://s3." + c.region() + ".amazonaws.com"
+	}
+	return strings.TrimRight(c.Endpoint, "/")
+}
+
+// URL is where key is stored.
+func (c *S3) URL(key string) string {
+	return c.endpoint() + c.path(key)
+}
+
+func (c *S3) path(key string) string {
+	segs := strings.Split(c.Bucket+"/"+c.Prefix+key, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	return "/" + strings.Join(segs, "/")
+}
+
+// Put uploads size bytes from body as key. The payload is sent unsigned, so
+// the endpoint should be HTTPS.
+func (c *S3) Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.URL(key), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.sign(req, time.Now().UTC())
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Minute}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 put %s: status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// sign adds a Signature Version 4 Authorization header covering the host
+// and x-amz-* headers.
+func (c *S3) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") || lk == "content-type" {
+			headers[lk] = strings.TrimSpace(req.Header.Get(k))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonHeaders.String(), signed, "UNSIGNED-PAYLOAD",
+	}, "\n")
+	scope := day + "/" + c.region() + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), day)
+	key = hmacSHA256(key, c.region())
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.AccessKey+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+sig)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,216 @@
+// Package auth identifies API callers. A caller presents an API key, in
+// X-API-Key or as a bearer token, or a JWT issued by the configured OIDC
+// provider; either way it becomes a Principal with scopes, so routes check
+// one permission model whichever credential was used.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/yourorg/search-api/internal/apierror"
+)
+
+// Scopes granted to callers. Routes declare the scope they need with
+// Require when they are registered.
+const (
+	ScopeRead    = "read"    // search, resolve and listing reads
+	ScopeExport  = "export"  // bulk export and the change feed
+	ScopeHydrate = "hydrate" // queueing hydrations
+	ScopeNotes   = "notes"   // internal notes, kept per key or token subject
+	ScopeSaved   = "saved"   // saved searches and their email digests, kept per key or token subject
+	ScopeAdmin   = "admin"   // /v1/admin; implies every other scope
+)
+
+// personal scopes guard data kept per caller, so they always need one.
+var personal = map[string]bool{ScopeNotes: true, ScopeSaved: true}
+
+// Roles name sets of scopes. A key or token granted a role gets its scopes.
+var Roles = map[string][]string{
+	"search":   {ScopeRead, ScopeSaved},
+	"partner":  {ScopeRead, ScopeExport},
+	"operator": {ScopeRead, ScopeExport, ScopeHydrate, ScopeNotes, ScopeSaved},
+}
+
+// expandRoles replaces role names in scopes with the scopes they grant.
+func expandRoles(scopes []string) []string {
+	var out []string
+	for _, s := range scopes {
+		granted, ok := Roles[s]
+		if !ok {
+			granted = []string{s}
+		}
+		for _, g := range granted {
+			if !slices.Contains(out, g) {
+				out = append(out, g)
+			}
+		}
+	}
+	return out
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Subject names the caller: the key's name or the token's sub claim.
+	Subject string
+	// Method is "api_key" or "jwt".
+	Method string
+	Scopes []string
+}
+
+// HasScope reports whether p was granted scope. Admin implies every scope.
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// ID identifies the caller in audit logs and usage, e.g. "key:mobile".
+func (p Principal) ID() string {
+	if p.Method == "jwt" {
+		return "jwt:" + p.Subject
+	}
+	return "key:" + p.Subject
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the request's caller, if it authenticated.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Key is a static API key.
+type Key struct {
+	Name   string
+	Secret string
+	// Scopes and roles granted; default read.
+	Scopes []string
+}
+
+// Authenticator checks the credentials on each request.
+type Authenticator struct {
+	Keys []Key
+	// OIDC verifies JWTs; nil accepts API keys only.
+	OIDC *Verifier
+	// AdminToken is a bearer token with the admin scope. On its own it
+	// leaves other credentials unchecked, as they were before API keys.
+	AdminToken string
+	// Required refuses requests without credentials. Otherwise they pass
+	// anonymously and only routes that check scopes refuse them.
+	Required bool
+	// Public paths skip authentication, such as health checks.
+	Public map[string]bool
+}
+
+// Enabled reports whether callers are identified by API keys or tokens,
+// so that Require refuses anonymous callers beyond ScopeRead.
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (len(a.Keys) > 0 || a.OIDC != nil)
+}
+
+// Authenticate returns the caller presenting r's credentials. ok is false
+// when r carries none; err is set when they are invalid.
+func (a *Authenticator) Authenticate(r *http.Request) (p Principal, ok bool, err error) {
+	cred := r.Header.Get("X-API-Key")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && cred == "" {
+		cred = strings.TrimSpace(bearer)
+	}
+	if cred == "" {
+		return Principal{}, false, nil
+	}
+	if a.AdminToken != "" && subtle.ConstantTimeCompare([]byte(cred), []byte(a.AdminToken)) == 1 {
+		return Principal{Subject: "admin", Method: "api_key", Scopes: []string{ScopeAdmin}}, true, nil
+	}
+	for _, k := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(cred), []byte(k.Secret)) == 1 {
+			scopes := k.Scopes
+			if len(scopes) == 0 {
+				scopes = []string{ScopeRead}
+			}
+			return Principal{Subject: k.Name, Method: "api_key", Scopes: expandRoles(scopes)}, true, nil
+		}
+	}
+	if a.OIDC != nil && strings.Count(cred, ".") == 2 {
+		p, err := a.OIDC.Verify(r.Context(), cred)
+		p.Scopes = expandRoles(p.Scopes)
+		return p, err == nil, err
+	}
+	if !a.Enabled() {
+		return Principal{}, false, nil
+	}
+	return Principal{}, false, errInvalidKey
+}
+
+var errInvalidKey = apierror.New(http.StatusUnauthorized, "unauthorized", "invalid API key")
+
+// Middleware attaches the caller to the request context, answering 401 for
+// invalid credentials and, when Required, for missing ones.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if !a.Enabled() && (a == nil || a.AdminToken == "") {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Enabled() {
+			r = r.WithContext(context.WithValue(r.Context(), enforcedKey{}, true))
+		}
+		if a.Public[r.URL.Path] || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p, ok, err := a.Authenticate(r)
+		switch {
+		case err != nil:
+			Unauthorized(w, r, err)
+		case ok:
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		case a.Required:
+			Unauthorized(w, r, nil)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+type enforcedKey struct{}
+
+// Require refuses callers without scope: 403 for those who authenticated,
+// 401 for anonymous ones. Anonymous callers keep ScopeRead, and every scope
+// but ScopeAdmin and the personal scopes while no API keys or OIDC issuer
+// are configured.
+func Require(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := FromContext(r.Context())
+			enforced, _ := r.Context().Value(enforcedKey{}).(bool)
+			switch {
+			case ok && p.HasScope(scope):
+				next.ServeHTTP(w, r)
+			case ok:
+				apierror.Write(w, r, apierror.New(http.StatusForbidden, "forbidden", scope+" scope required").With("scope", scope))
+			case scope == ScopeRead || (scope != ScopeAdmin && !personal[scope] && !enforced):
+				next.ServeHTTP(w, r)
+			default:
+				Unauthorized(w, r, nil)
+			}
+		})
+	}
+}
+
+// Unauthorized answers 401 with a bearer challenge; err, when set, says
+// what was wrong with the credentials.
+func Unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer`)
+	if err == nil {
+		apierror.Write(w, r, apierror.New(http.StatusUnauthorized, "unauthorized", "credentials required"))
+		return
+	}
+	apierror.WriteError(w, r, err, apierror.New(http.StatusUnauthorized, "invalid_token", "invalid bearer token"))
+}
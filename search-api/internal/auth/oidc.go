@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/search-api/internal/apierror"
+)
+
+// Verifier checks JWTs issued by an OIDC provider against the signing keys
+// it publishes. Keys are fetched on first use, refreshed hourly and
+// refetched when a token names a key not yet seen, at most once a minute.
+type Verifier struct {
+	// Issuer must match the iss claim; discovery is read from its
+	// /.well-known/openid-configuration unless JWKSURL is set.
+	Issuer  string
+	JWKSURL string
+	// Audience must be in the aud claim. It is required: the issuer signs
+	// tokens for all of its clients, not only this API.
+	Audience string
+	// ScopeMap maps the provider's scopes to API scopes, e.g.
+	// "search.read" to "read". Unmapped scopes are dropped, so with no
+	// ScopeMap a token grants no scopes.
+	ScopeMap map[string]string
+	// Leeway tolerates clock skew on exp and nbf, default 1m.
+	Leeway time.Duration
+	Client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Iss   string          `json:"iss"`
+	Sub   string          `json:"sub"`
+	Aud   json.RawMessage `json:"aud"`
+	Exp   int64           `json:"exp"`
+	Nbf   int64           `json:"nbf"`
+	Scope string          `json:"scope"`
+	Scp   json.RawMessage `json:"scp"`
+}
+
+// Verify checks token's signature, issuer, audience and lifetime and
+// returns its subject with the mapped scopes.
+func (v *Verifier) Verify(ctx context.Context, token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, errors.New("malformed token")
+	}
+	var h jwtHeader
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Principal{}, fmt.Errorf("token header: %w", err)
+	}
+	key, err := v.key(ctx, h.Kid)
+	if err != nil {
+		return Principal{}, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("token signature: %w", err)
+	}
+	if err := verifySignature(h.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return Principal{}, err
+	}
+
+	var c jwtClaims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return Principal{}, fmt.Errorf("token claims: %w", err)
+	}
+	leeway := v.Leeway
+	if leeway <= 0 {
+		leeway = time.Minute
+	}
+	now := time.Now()
+	switch {
+	case c.Iss != v.Issuer:
+		return Principal{}, fmt.Errorf("token issuer %q is not trusted", c.Iss)
+	case v.Audience == "" || !slices.Contains(stringOrList(c.Aud), v.Audience):
+		return Principal{}, fmt.Errorf("token is not for audience %q", v.Audience)
+	case c.Exp == 0 || now.After(time.Unix(c.Exp, 0).Add(leeway)):
+		return Principal{}, errors.New("token expired")
+	case c.Nbf != 0 && now.Add(leeway).Before(time.Unix(c.Nbf, 0)):
+		return Principal{}, errors.New("token not yet valid")
+	case c.Sub == "":
+		return Principal{}, errors.New("token has no subject")
+	}
+
+	var scopes []string
+	for _, s := range append(strings.Fields(c.Scope), stringOrList(c.Scp)...) {
+		if m, ok := v.ScopeMap[s]; ok && !slices.Contains(scopes, m) {
+			scopes = append(scopes, m)
+		}
+	}
+	return Principal{Subject: c.Sub, Method: "jwt", Scopes: scopes}, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// stringOrList reads a claim that may be a string or a list of them; scp
+// strings are space-separated.
+func stringOrList(raw json.RawMessage) []string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.Fields(s)
+	}
+	var list []string
+	_ = json.Unmarshal(raw, &list)
+	return list
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("token algorithm %q is not supported", alg)
+	}
+	var digest []byte
+	switch hash {
+	case crypto.SHA256:
+		d := sha256.Sum256([]byte(signed))
+		digest = d[:]
+	case crypto.SHA384:
+		d := sha512.Sum384([]byte(signed))
+		digest = d[:]
+	default:
+		d := sha512.Sum512([]byte(signed))
+		digest = d[:]
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			break
+		}
+		if rsa.VerifyPKCS1v15(k, hash, digest, sig) != nil {
+			return errors.New("token signature is invalid")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(sig) != 2*size {
+			break
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("token signature is invalid")
+		}
+		return nil
+	}
+	return fmt.Errorf("token algorithm %q does not match its key", alg)
+}
+
+// key returns the signing key kid, fetching the key set when it is stale
+// or does not have kid.
+func (v *Verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	k, ok := v.keys[kid]
+	age := time.Since(v.fetched)
+	if ok && age < time.Hour {
+		return k, nil
+	}
+	if !ok && v.keys != nil && age < time.Minute {
+		return nil, fmt.Errorf("token signing key %q is unknown", kid)
+	}
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		if ok {
+			return k, nil // keep using the cached key while the provider is down
+		}
+		return nil, apierror.Unavailable("auth_unavailable", "unable to fetch the identity provider's signing keys").WithDetail(err.Error())
+	}
+	v.keys, v.fetched = keys, time.Now()
+	if k, ok = keys[kid]; !ok {
+		return nil, fmt.Errorf("token signing key %q is unknown", kid)
+	}
+	return k, nil
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	jwksURL := v.JWKSURL
+	if jwksURL == "" {
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, strings.TrimRight(v.Issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+			return nil, err
+		}
+		if doc.Issuer != v.Issuer || doc.JWKSURI == "" {
+			return nil, fmt.Errorf("discovery document for %q has issuer %q and jwks_uri %q", v.Issuer, doc.Issuer, doc.JWKSURI)
+		}
+		jwksURL = doc.JWKSURI
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, jwksURL, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("key set has no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, url string, out any) error {
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
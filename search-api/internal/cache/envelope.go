@@ -0,0 +1,156 @@
+// Package cache defines the Redis envelopes shared by every writer and reader
+// of resolved-property and listings-page cache entries.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/redisx"
+)
+
+// Version is the current envelope schema. Bump it whenever an envelope or
+// attom.PropertyCard changes incompatibly; entries written under any other
+// version are treated as misses and deleted on read.
+const Version = 2
+
+// ErrVersion is returned by Decode for envelopes from another schema version,
+// including the unversioned envelopes written before versioning.
+var ErrVersion = errors.New("cache: envelope version mismatch")
+
+// Meta carries the stale-while-revalidate timestamps.
+type Meta struct {
+	LastFetch  time.Time `json:"last_fetch_at"`
+	StaleAfter time.Time `json:"stale_after"`
+	TTLSeconds int       `json:"ttl_seconds"`
+	Source     string    `json:"source"`
+}
+
+// Stale reports whether the entry should be revalidated.
+func (m Meta) Stale(now time.Time) bool { return now.After(m.StaleAfter) }
+
+// TTL is how long the entry lives in Redis.
+func (m Meta) TTL() time.Duration { return time.Duration(m.TTLSeconds) * time.Second }
+
+func newMeta(source string, staleAfter, ttl time.Duration) Meta {
+	now := time.Now()
+	return Meta{LastFetch: now, StaleAfter: now.Add(staleAfter), TTLSeconds: int(ttl.Seconds()), Source: source}
+}
+
+// Normalized is the canonical address the entry was resolved for.
+type Normalized struct {
+	Line1 string `json:"line1"`
+	City  string `json:"city"`
+	State string `json:"state"`
+	Zip   string `json:"zip"`
+}
+
+// Envelope is one cached property.
+type Envelope struct {
+	Version int                `json:"v"`
+	Data    attom.PropertyCard `json:"data"`
+	Meta    Meta               `json:"meta"`
+	Norm    Normalized         `json:"normalized"`
+}
+
+// PropertyKey is the Redis key holding the envelope for a property key.
+func PropertyKey(pkey string) string { return "prop:pk:" + pkey }
+
+// New builds a current-version envelope fetched now.
+func New(card attom.PropertyCard, source string, norm Normalized, staleAfter, ttl time.Duration) Envelope {
+	return Envelope{Version: Version, Data: card, Meta: newMeta(source, staleAfter, ttl), Norm: norm}
+}
+
+// Page is one cached provider listings page.
+type Page struct {
+	Version int                  `json:"v"`
+	Data    []attom.PropertyCard `json:"data"`
+	Meta    Meta                 `json:"meta"`
+}
+
+// NewPage builds a current-version page envelope fetched now.
+func NewPage(cards []attom.PropertyCard, source string, staleAfter, ttl time.Duration) Page {
+	return Page{Version: Version, Data: cards, Meta: newMeta(source, staleAfter, ttl)}
+}
+
+// Encode serializes e.
+func Encode(e Envelope) (string, error) {
+	b, err := json.Marshal(e)
+	return string(b), err
+}
+
+// Decode parses a cached value, rejecting other schema versions.
+func Decode(val string) (Envelope, error) {
+	var e Envelope
+	if err := decode(val, &e, &e.Version); err != nil {
+		return Envelope{}, err
+	}
+	return e, nil
+}
+
+func decode(val string, dst any, version *int) error {
+	if err := json.Unmarshal([]byte(val), dst); err != nil {
+		return err
+	}
+	if *version != Version {
+		return ErrVersion
+	}
+	return nil
+}
+
+// Get reads and decodes the envelope at key. A missing, unreadable or
+// outdated entry returns nil; outdated and unreadable entries are deleted so
+// the next write starts clean.
+func Get(ctx context.Context, rdb *redisx.Client, key string) (*Envelope, error) {
+	var e Envelope
+	if ok, err := load(ctx, rdb, key, &e, &e.Version); !ok {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// GetPage is Get for listings pages.
+func GetPage(ctx context.Context, rdb *redisx.Client, key string) (*Page, error) {
+	var p Page
+	if ok, err := load(ctx, rdb, key, &p, &p.Version); !ok {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func load(ctx context.Context, rdb *redisx.Client, key string, dst any, version *int) (bool, error) {
+	val, err := rdb.Get(ctx, key)
+	if errors.Is(err, redis.Nil) || (err == nil && val == "") {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := decode(val, dst, version); err != nil {
+		_ = rdb.Del(ctx, key)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Put writes e at key with its TTL.
+func Put(ctx context.Context, rdb *redisx.Client, key string, e Envelope) error {
+	return put(ctx, rdb, key, e, e.Meta.TTL())
+}
+
+// PutPage writes p at key with its TTL.
+func PutPage(ctx context.Context, rdb *redisx.Client, key string, p Page) error {
+	return put(ctx, rdb, key, p, p.Meta.TTL())
+}
+
+func put(ctx context.Context, rdb *redisx.Client, key string, v any, ttl time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return rdb.Set(ctx, key, string(b), ttl)
+}
@@ -7,14 +7,11 @@ import (
 
 var rePunct = regexp.MustCompile(`[^A-Za-z0-9\s]`)
 
-// Canonicalize normalizes an address and computes a stable property key.
+// Canonicalize normalizes an address and computes a stable property key
+// with the rules set by SetRules; KeyVersion names them.
 // It intentionally ignores unit/suite to stabilize identity per parcel.
 func Canonicalize(line1, city, state, zip string) (normLine1, normCity, normState, normZip, propertyKey string) {
-    n1 := strings.TrimSpace(strings.ToUpper(line1))
-    n1 = stripUnit(n1)
-    n1 = rePunct.ReplaceAllString(n1, " ")
-    n1 = abbreviateSuffix(n1)
-    n1 = collapseSpaces(n1)
+    n1 := current.Load().line(strings.TrimSpace(strings.ToUpper(line1)))
 
     c := collapseSpaces(rePunct.ReplaceAllString(strings.ToUpper(strings.TrimSpace(city)), " "))
     st := strings.ToUpper(strings.TrimSpace(state))
@@ -25,6 +22,55 @@ func Canonicalize(line1, city, state, zip string) (normLine1, normCity, normStat
     return n1, c, st, z, key
 }
 
+// AddressKey identifies an address as given, before any rules apply: only
+// case, punctuation and spacing are folded. It doesn't change when the
+// rules do, so an address seen under one key version still finds its
+// property under the next.
+func AddressKey(line1, city, state, zip string) string {
+    fold := func(s string) string { return strings.ToLower(collapseSpaces(rePunct.ReplaceAllString(s, " "))) }
+    return fold(line1) + "|" + fold(city) + "|" + fold(state) + "|" + trimZIP(zip)
+}
+
+// cityAliases folds common city-name prefixes to their USPS abbreviations.
+var cityAliases = map[string]string{
+    "SAINT":  "ST",
+    "SAINTE": "STE",
+    "FORT":   "FT",
+    "MOUNT":  "MT",
+}
+
+// CityKey returns a matching key for a city name so spelling variants like
+// "Saint Paul" and "St. Paul" compare equal. It is used for lookups only and
+// does not feed into the property key.
+func CityKey(city string) string {
+    toks := strings.Fields(rePunct.ReplaceAllString(strings.ToUpper(city), " "))
+    for i, t := range toks {
+        if v, ok := cityAliases[t]; ok { toks[i] = v }
+    }
+    return strings.Join(toks, " ")
+}
+
+// ParseLocation splits a "City, ST" location string. ok is false when the
+// input does not contain both parts.
+func ParseLocation(location string) (city, state string, ok bool) {
+    i := strings.LastIndex(location, ",")
+    if i < 0 { return "", "", false }
+    city = strings.TrimSpace(location[:i])
+    state = strings.ToUpper(strings.TrimSpace(location[i+1:]))
+    if len(state) > 2 { state = stateAbbrev(state) }
+    if city == "" || state == "" { return "", "", false }
+    return city, state, true
+}
+
+// IsZIP reports whether v is a bare 5-digit ZIP.
+func IsZIP(v string) bool {
+    if len(v) != 5 { return false }
+    for _, ch := range v {
+        if ch < '0' || ch > '9' { return false }
+    }
+    return true
+}
+
 func collapseSpaces(s string) string {
     return strings.Join(strings.Fields(s), " ")
 }
@@ -35,6 +81,7 @@ func trimZIP(z string) string {
     return z
 }
 
+// stripUnit and abbreviateSuffix are the version 1 rules.
 func stripUnit(s string) string {
     // Remove trailing unit designators like APT, UNIT, STE, SUITE, #
     toks := []string{" APT ", " UNIT ", " STE ", " SUITE ", " #"}
@@ -68,11 +115,22 @@ func abbreviateSuffix(s string) string {
     return out
 }
 
-func stateAbbrev(s string) string {
-    m := map[string]string{
-        "ALABAMA":"AL","ALASKA":"AK","ARIZONA":"AZ","ARKANSAS":"AR","CALIFORNIA":"CA","COLORADO":"CO","CONNECTICUT":"CT","DELAWARE":"DE","FLORIDA":"FL","GEORGIA":"GA","HAWAII":"HI","IDAHO":"ID","ILLINOIS":"IL","INDIANA":"IN","IOWA":"IA","KANSAS":"KS","KENTUCKY":"KY","LOUISIANA":"LA","MAINE":"ME","MARYLAND":"MD","MASSACHUSETTS":"MA","MICHIGAN":"MI","MINNESOTA":"MN","MISSISSIPPI":"MS","MISSOURI":"MO","MONTANA":"MT","NEBRASKA":"NE","NEVADA":"NV","NEW HAMPSHIRE":"NH","NEW JERSEY":"NJ","NEW MEXICO":"NM","NEW YORK":"NY","NORTH CAROLINA":"NC","NORTH DAKOTA":"ND","OHIO":"OH","OKLAHOMA":"OK","OREGON":"OR","PENNSYLVANIA":"PA","RHODE ISLAND":"RI","SOUTH CAROLINA":"SC","SOUTH DAKOTA":"SD","TENNESSEE":"TN","TEXAS":"TX","UTAH":"UT","VERMONT":"VT","VIRGINIA":"VA","WASHINGTON":"WA","WEST VIRGINIA":"WV","WISCONSIN":"WI","WYOMING":"WY",
+// IsStateCode reports whether v is a two-letter US state code.
+func IsStateCode(v string) bool {
+    v = strings.ToUpper(v)
+    if v == "DC" { return true }
+    for _, code := range stateCodes {
+        if code == v { return true }
     }
-    if v, ok := m[s]; ok { return v }
+    return false
+}
+
+func stateAbbrev(s string) string {
+    if v, ok := stateCodes[s]; ok { return v }
     return s
 }
 
+var stateCodes = map[string]string{
+    "ALABAMA":"AL","ALASKA":"AK","ARIZONA":"AZ","ARKANSAS":"AR","CALIFORNIA":"CA","COLORADO":"CO","CONNECTICUT":"CT","DELAWARE":"DE","FLORIDA":"FL","GEORGIA":"GA","HAWAII":"HI","IDAHO":"ID","ILLINOIS":"IL","INDIANA":"IN","IOWA":"IA","KANSAS":"KS","KENTUCKY":"KY","LOUISIANA":"LA","MAINE":"ME","MARYLAND":"MD","MASSACHUSETTS":"MA","MICHIGAN":"MI","MINNESOTA":"MN","MISSISSIPPI":"MS","MISSOURI":"MO","MONTANA":"MT","NEBRASKA":"NE","NEVADA":"NV","NEW HAMPSHIRE":"NH","NEW JERSEY":"NJ","NEW MEXICO":"NM","NEW YORK":"NY","NORTH CAROLINA":"NC","NORTH DAKOTA":"ND","OHIO":"OH","OKLAHOMA":"OK","OREGON":"OR","PENNSYLVANIA":"PA","RHODE ISLAND":"RI","SOUTH CAROLINA":"SC","SOUTH DAKOTA":"SD","TENNESSEE":"TN","TEXAS":"TX","UTAH":"UT","VERMONT":"VT","VIRGINIA":"VA","WASHINGTON":"WA","WEST VIRGINIA":"WV","WISCONSIN":"WI","WYOMING":"WY",
+}
+
@@ -0,0 +1,31 @@
+package canon
+
+import "strings"
+
+// APN normalizes an assessor parcel number for matching. Counties and
+// providers format the same number differently ("123-456-78", "123 456 78",
+// "12345678"), so only letters and digits are kept, upper-cased.
+func APN(apn string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(apn) {
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// FIPS normalizes a county FIPS code to its five digits, restoring leading
+// zeros a numeric encoding dropped. It returns "" for anything else.
+func FIPS(code string) string {
+	code = strings.TrimSpace(code)
+	if code == "" || len(code) > 5 {
+		return ""
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return strings.Repeat("0", 5-len(code)) + code
+}
@@ -0,0 +1,306 @@
+package canon
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Rules are the tables Canonicalize normalizes address lines with. Version
+// is stamped on the property keys they produce: any change to the tables
+// that changes keys needs a new version, so stored keys made under the old
+// one can be found and migrated.
+//
+// Version 1 is the original built-in normalization and takes no tables.
+// Version 2 is the embedded USPS Publication 28 dataset in rules/usps.json:
+// street suffixes (C1), unit designators (C2) and directionals. A rules
+// file, in the same shape, is merged over it.
+type Rules struct {
+	Version int `json:"version"`
+	// Suffixes and Directionals map every accepted spelling to its
+	// abbreviation, e.g. "AVENUE": "AVE" and "NORTH": "N".
+	Suffixes     map[string]string `json:"suffixes"`
+	Directionals map[string]string `json:"directionals"`
+	// Units are the designators that start a secondary address, e.g. "APT".
+	Units []string `json:"units"`
+	// Words are whole-word replacements applied before the other rules,
+	// for local spellings the tables don't know, e.g. "FM": "FARM TO MARKET".
+	Words map[string]string `json:"words"`
+}
+
+// LegacyVersion is the key version of the original normalization.
+const LegacyVersion = 1
+
+//go:embed rules/usps.json
+var uspsRules []byte
+
+var current atomic.Pointer[ruleset]
+
+func init() {
+	current.Store(&ruleset{Rules: Legacy()})
+}
+
+// Legacy returns the version 1 rules: the original dozen suffixes and
+// APT, UNIT, STE, SUITE and # as unit designators.
+func Legacy() Rules {
+	return Rules{Version: LegacyVersion}
+}
+
+// Default returns the version 2 rules from the embedded USPS dataset.
+func Default() Rules {
+	var r Rules
+	if err := json.Unmarshal(uspsRules, &r); err != nil {
+		panic("canon: embedded rules: " + err.Error())
+	}
+	return r
+}
+
+// ParseRules reads a rules file: its suffixes, directionals and words are
+// added to or override the embedded ones, and its units are added to them.
+// The file must name its version, above 2.
+func ParseRules(data []byte) (Rules, error) {
+	r := Default()
+	var custom Rules
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return Rules{}, fmt.Errorf("parse canon rules: %w", err)
+	}
+	if custom.Version <= 2 {
+		return Rules{}, fmt.Errorf("canon rules: version must be above 2, the embedded rules, got %d", custom.Version)
+	}
+	r.Version = custom.Version
+	for k, v := range custom.Suffixes {
+		r.Suffixes[k] = v
+	}
+	for k, v := range custom.Directionals {
+		r.Directionals[k] = v
+	}
+	if r.Words == nil {
+		r.Words = map[string]string{}
+	}
+	for k, v := range custom.Words {
+		r.Words[k] = v
+	}
+	r.Units = append(r.Units, custom.Units...)
+	return r, nil
+}
+
+// LoadRules reads the rules file at path; see ParseRules.
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, fmt.Errorf("read canon rules: %w", err)
+	}
+	return ParseRules(data)
+}
+
+// RulesFromEnv returns the rules CANON_RULES_FILE names, merged over the
+// embedded ones, or else the built-in rules for CANON_KEY_VERSION, 1 by
+// default. When both are set the versions must match, so a deploy can't
+// change keys by accident.
+func RulesFromEnv() (Rules, error) {
+	version, pinned := LegacyVersion, false
+	if v := os.Getenv("CANON_KEY_VERSION"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return Rules{}, fmt.Errorf("CANON_KEY_VERSION must be a positive integer, got %q", v)
+		}
+		version, pinned = n, true
+	}
+	if path := os.Getenv("CANON_RULES_FILE"); path != "" {
+		r, err := LoadRules(path)
+		if err != nil {
+			return Rules{}, err
+		}
+		if pinned && r.Version != version {
+			return Rules{}, fmt.Errorf("CANON_RULES_FILE is version %d but CANON_KEY_VERSION is %d", r.Version, version)
+		}
+		return r, nil
+	}
+	switch version {
+	case LegacyVersion:
+		return Legacy(), nil
+	case 2:
+		return Default(), nil
+	}
+	return Rules{}, fmt.Errorf("no built-in canon rules for version %d; set CANON_RULES_FILE", version)
+}
+
+// SetRules makes r the rules Canonicalize uses. Call it at startup, before
+// any keys are computed.
+func SetRules(r Rules) {
+	current.Store(compile(r))
+}
+
+// KeyVersion returns the version of the rules in use, which is stored with
+// the property keys they produce.
+func KeyVersion() int {
+	return current.Load().Version
+}
+
+// ruleset is Rules with its tables uppercased and indexed.
+type ruleset struct {
+	Rules
+	units map[string]bool
+}
+
+func compile(r Rules) *ruleset {
+	rs := &ruleset{Rules: Rules{Version: r.Version}, units: map[string]bool{}}
+	rs.Suffixes = upperMap(r.Suffixes)
+	rs.Directionals = upperMap(r.Directionals)
+	rs.Words = upperMap(r.Words)
+	for _, u := range r.Units {
+		rs.units[strings.ToUpper(u)] = true
+	}
+	return rs
+}
+
+func upperMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[strings.ToUpper(k)] = strings.ToUpper(v)
+	}
+	return out
+}
+
+var (
+	// reLinePunct keeps # so it can mark a unit; it is dropped with the unit.
+	reLinePunct = regexp.MustCompile(`[^A-Z0-9#\s]`)
+	rePOBox     = regexp.MustCompile(`^(?:P\s*O|POST\s*OFFICE|POST)\s*BOX\s*([A-Z0-9]+)\b`)
+	reBareBox   = regexp.MustCompile(`^(?:POB|BOX)\s*([0-9][A-Z0-9]*)\b`)
+)
+
+// IsPOBox reports whether line1 is a post office box rather than a street
+// address.
+func IsPOBox(line1 string) bool {
+	_, ok := poBox(line1)
+	return ok
+}
+
+// poBox returns the box number when line1 is a PO box, in any of the usual
+// spellings: "P.O. Box 12", "POB 12", "Post Office Box 12", "Box 12".
+func poBox(line1 string) (string, bool) {
+	s := collapseSpaces(rePunct.ReplaceAllString(strings.ToUpper(line1), " "))
+	for _, re := range []*regexp.Regexp{rePOBox, reBareBox} {
+		if m := re.FindStringSubmatch(s); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// line normalizes an uppercased address line.
+func (r *ruleset) line(s string) string {
+	if r.Version == LegacyVersion {
+		s = stripUnit(s)
+		s = rePunct.ReplaceAllString(s, " ")
+		s = abbreviateSuffix(s)
+		return collapseSpaces(s)
+	}
+	if box, ok := poBox(s); ok {
+		return "PO BOX " + box
+	}
+	s = reLinePunct.ReplaceAllString(strings.ReplaceAll(s, "#", " # "), " ")
+	toks := r.replaceWords(strings.Fields(s))
+	toks = r.stripUnit(toks)
+	return strings.Join(r.abbreviate(toks), " ")
+}
+
+func (r *ruleset) replaceWords(toks []string) []string {
+	if len(r.Words) == 0 {
+		return toks
+	}
+	out := make([]string, 0, len(toks))
+	for _, t := range toks {
+		if w, ok := r.Words[t]; ok {
+			out = append(out, strings.Fields(w)...)
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// stripUnit drops the secondary address: everything from a # or a unit
+// designator past the street name. A designator counts when a number or
+// single letter follows it, as in "APT 4" or "STE B", or it ends the line,
+// as in "REAR", so street names like "Old Pier Rd" are kept.
+func (r *ruleset) stripUnit(toks []string) []string {
+	for i := 1; i < len(toks); i++ {
+		if toks[i] == "#" {
+			return toks[:i]
+		}
+		if i < 2 || !r.units[toks[i]] {
+			continue
+		}
+		if i == len(toks)-1 || len(toks[i+1]) == 1 || startsWithDigit(toks[i+1]) {
+			return toks[:i]
+		}
+	}
+	return toks
+}
+
+// abbreviate applies the directional and suffix tables to a line laid out
+// as number, pre-directional, name, suffix, post-directional; only the
+// number and name are required. A suffix word followed by a number, as in
+// "Highway 5" or "County Road 12", is abbreviated wherever it appears.
+func (r *ruleset) abbreviate(toks []string) []string {
+	toks = mergeDirectionals(toks)
+	end := len(toks)
+	if end >= 3 {
+		if d, ok := r.Directionals[toks[end-1]]; ok {
+			if _, prev := r.Directionals[toks[end-2]]; !prev {
+				toks[end-1] = d
+				end--
+			}
+		}
+	}
+	name := 1
+	if end >= 3 {
+		if d, ok := r.Directionals[toks[1]]; ok {
+			// "North Ave" is a street named North, "North Main" isn't.
+			if _, suffix := r.Suffixes[toks[2]]; end > 3 || !suffix {
+				toks[1] = d
+				name = 2
+			}
+		}
+	}
+	for i := name; i < end-1; i++ {
+		if s, ok := r.Suffixes[toks[i]]; ok && startsWithDigit(toks[i+1]) {
+			toks[i] = s
+		}
+	}
+	if last := end - 1; last > name {
+		if s, ok := r.Suffixes[toks[last]]; ok {
+			toks[last] = s
+		}
+	}
+	return toks
+}
+
+// mergeDirectionals joins directionals split by punctuation, so "N.E."
+// reads as NE, in the pre- or post-directional position of a line long
+// enough to have one.
+func mergeDirectionals(toks []string) []string {
+	if len(toks) < 5 {
+		return toks
+	}
+	pair := func(i int) bool {
+		return (toks[i] == "N" || toks[i] == "S") && (toks[i+1] == "E" || toks[i+1] == "W")
+	}
+	if n := len(toks); pair(n - 2) {
+		toks = append(toks[:n-2], toks[n-2]+toks[n-1])
+	}
+	if pair(1) {
+		toks = append(toks[:1], append([]string{toks[1] + toks[2]}, toks[3:]...)...)
+	}
+	return toks
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
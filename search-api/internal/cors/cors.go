@@ -0,0 +1,139 @@
+// Package cors lets browsers call the API from other origins. A Policy says
+// which origins may call, with which methods and headers; Middleware applies
+// one policy by default and others under path prefixes, such as a stricter
+// one for /v1/admin.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is the CORS policy for a set of routes. The zero Policy allows no
+// origin, so routes under it send no CORS headers and refuse preflights.
+type Policy struct {
+	// Origins may call: an exact origin such as https://app.example.com,
+	// a subdomain wildcard such as https://*.example.com, or "*" for any.
+	Origins []string
+	// Methods default to GET, POST, PUT, PATCH and DELETE.
+	Methods []string
+	// Headers are the request headers allowed, default Content-Type,
+	// Authorization and X-Request-Id; "*" allows whatever is asked for.
+	Headers []string
+	// Expose are response headers scripts may read, default X-Cache, Age,
+	// Retry-After, X-Request-Id and the freshness headers X-Data-Source,
+	// X-Last-Fetched-At and X-Stale.
+	Expose []string
+	// Credentials allows cookies and HTTP auth. The origin is then echoed
+	// even under "*", since browsers refuse a wildcard with credentials;
+	// callers should not combine the two, as any site could then make
+	// credentialed calls.
+	Credentials bool
+	// MaxAge is how long browsers cache a preflight, default 10m.
+	MaxAge time.Duration
+}
+
+var (
+	defaultMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	defaultHeaders = []string{"Content-Type", "Authorization", "X-Request-Id"}
+	defaultExpose  = []string{"X-Cache", "Age", "Retry-After", "X-Request-Id", "X-Data-Source", "X-Last-Fetched-At", "X-Stale"}
+)
+
+func (p Policy) allows(origin string) bool {
+	for _, o := range p.Origins {
+		switch {
+		case o == "*" || strings.EqualFold(o, origin):
+			return true
+		case strings.Contains(o, "://*."):
+			scheme, suffix, _ := strings.Cut(o, "*")
+			if rest, ok := strings.CutPrefix(strings.ToLower(origin), strings.ToLower(scheme)); ok &&
+				strings.HasSuffix(rest, strings.ToLower(suffix)) && len(rest) > len(suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p Policy) wildcard() bool {
+	for _, o := range p.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func orDefault(v, def []string) string {
+	if len(v) == 0 {
+		v = def
+	}
+	return strings.Join(v, ", ")
+}
+
+// Middleware applies def, or the policy of the longest prefix in routes
+// matching the request path. It answers preflight requests itself, so it
+// must run before routing.
+func Middleware(def Policy, routes map[string]Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			p, matched := def, ""
+			for prefix, rp := range routes {
+				if strings.HasPrefix(r.URL.Path, prefix) && len(prefix) > len(matched) {
+					p, matched = rp, prefix
+				}
+			}
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			h := w.Header()
+			if !p.wildcard() || p.Credentials {
+				h.Add("Vary", "Origin")
+			}
+			if !p.allows(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if p.wildcard() && !p.Credentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if p.Credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				h.Set("Access-Control-Expose-Headers", orDefault(p.Expose, defaultExpose))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", orDefault(p.Methods, defaultMethods))
+			if len(p.Headers) == 1 && p.Headers[0] == "*" {
+				if asked := r.Header.Get("Access-Control-Request-Headers"); asked != "" {
+					h.Set("Access-Control-Allow-Headers", asked)
+				}
+			} else {
+				h.Set("Access-Control-Allow-Headers", orDefault(p.Headers, defaultHeaders))
+			}
+			maxAge := p.MaxAge
+			if maxAge <= 0 {
+				maxAge = 10 * time.Minute
+			}
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package county maps ZIP codes to the county most of their addresses lie
+// in, so properties can be grouped by county and joined against
+// county-level public datasets by FIPS code. ZIPs that cross county lines
+// resolve to one county only; a provider's parcel county, where known, is
+// the better answer.
+//
+// The embedded dataset is only a sample of metro ZIPs. Deployments load the
+// full HUD USPS ZIP-county file through COUNTY_ZIP_FILE; without it most
+// ZIPs have no county.
+package county
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yourorg/search-api/internal/canon"
+)
+
+// County is a US county or county equivalent. FIPS is its five-digit code.
+type County struct {
+	FIPS  string `json:"fips"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+//go:embed data/zip_county.csv
+var seed []byte
+
+var (
+	mu     sync.RWMutex
+	byZip  map[string]County
+	byFIPS map[string]County
+	once   sync.Once
+)
+
+func load() {
+	once.Do(func() {
+		byZip, byFIPS = map[string]County{}, map[string]County{}
+		if err := merge(bytes.NewReader(seed)); err != nil {
+			panic("county: embedded dataset: " + err.Error())
+		}
+	})
+}
+
+// ForZip returns the county of a five-digit ZIP, or ZIP+4.
+func ForZip(zip string) (County, bool) {
+	load()
+	zip = strings.TrimSpace(zip)
+	if len(zip) > 5 {
+		zip = zip[:5]
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := byZip[zip]
+	return c, ok
+}
+
+// ByFIPS returns the county a FIPS code names, when any ZIP loaded maps to
+// it.
+func ByFIPS(fips string) (County, bool) {
+	load()
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := byFIPS[canon.FIPS(fips)]
+	return c, ok
+}
+
+// FromEnv merges the crosswalk file COUNTY_ZIP_FILE names, if any, over the
+// embedded dataset. Without one it reports ErrSampleOnly, which callers may
+// log and carry on past.
+func FromEnv() error {
+	path := os.Getenv("COUNTY_ZIP_FILE")
+	if path == "" {
+		return ErrSampleOnly
+	}
+	return LoadFile(path)
+}
+
+// ErrSampleOnly reports that only the embedded sample of ZIPs is loaded.
+var ErrSampleOnly = errors.New("COUNTY_ZIP_FILE is not set; only the embedded sample of ZIPs maps to counties")
+
+// LoadFile merges a crosswalk file over the loaded ZIPs. It is CSV with a
+// header naming at least zip and fips; county and state are optional, and
+// a county name missing from the file is taken from other rows with the
+// same FIPS. With a res_ratio column, as in the HUD USPS ZIP-county file, a
+// ZIP listed under several counties maps to the one with the highest
+// ratio. Lines starting with # are skipped.
+func LoadFile(path string) error {
+	load()
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := merge(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func merge(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("county crosswalk header: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	zipCol, ok1 := col["zip"]
+	fipsCol, ok2 := col["fips"]
+	if !ok1 || !ok2 {
+		return errors.New("county crosswalk: header must name zip and fips columns")
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	zips := map[string]County{}
+	ratios := map[string]float64{}
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if zipCol >= len(rec) || fipsCol >= len(rec) {
+			continue
+		}
+		zip := strings.TrimSpace(rec[zipCol])
+		fips := canon.FIPS(rec[fipsCol])
+		if len(zip) != 5 || fips == "" {
+			continue
+		}
+		if s := field(rec, "res_ratio"); s != "" {
+			ratio, _ := strconv.ParseFloat(s, 64)
+			if prev, seen := ratios[zip]; seen && prev >= ratio {
+				continue
+			}
+			ratios[zip] = ratio
+		}
+		zips[zip] = County{FIPS: fips, Name: field(rec, "county"), State: strings.ToUpper(field(rec, "state"))}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, c := range zips {
+		if c.Name != "" {
+			byFIPS[c.FIPS] = c
+		}
+	}
+	for zip, c := range zips {
+		if known, ok := byFIPS[c.FIPS]; ok {
+			if c.Name == "" {
+				c.Name = known.Name
+			}
+			if c.State == "" {
+				c.State = known.State
+			}
+		}
+		byZip[zip] = c
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// Package digest emails saved-search owners the listings that appeared or
+// changed price or status since their last digest, at each saved search's
+// daily or weekly frequency. A send log in Postgres keeps a window from
+// being mailed twice, across restarts and concurrent workers.
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// Job sends the digests that are due.
+type Job struct {
+	Store  *store.Store
+	Mailer Mailer
+	Logger *log.Logger
+	// ListingURL links each listing; {listingId} is replaced by the
+	// provider listing ID. Empty leaves listings unlinked.
+	ListingURL string
+	// MaxListings caps the listings in one email; default 25. The email
+	// counts the rest and links them through SearchURL, where
+	// {savedSearchId} is replaced by the saved search's ID; empty leaves
+	// the count unlinked.
+	MaxListings int
+	SearchURL   string
+	// BatchSize is how many saved searches one pass handles; default 100.
+	BatchSize int
+	// RetryAfter delays a failed send's retry; default 1h.
+	RetryAfter time.Duration
+	Interval   time.Duration
+}
+
+func (j *Job) logf(format string, args ...any) {
+	if j.Logger != nil {
+		j.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (j *Job) batchSize() int {
+	if j.BatchSize <= 0 {
+		return 100
+	}
+	return j.BatchSize
+}
+
+// RunOnce sends one batch of due digests and reports how many saved
+// searches it handled, not counting those another worker holds.
+func (j *Job) RunOnce(ctx context.Context) (int, error) {
+	if j == nil || j.Store == nil || j.Mailer == nil {
+		return 0, errors.New("digest requires store and mailer")
+	}
+	start := time.Now()
+	due, err := j.Store.DueSavedSearches(ctx, start, j.batchSize())
+	if err != nil {
+		return 0, err
+	}
+	var sent, empty, failed int
+	for _, ss := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		n, err := j.send(ctx, ss, time.Now())
+		switch {
+		case err != nil:
+			failed++
+			j.logf("[WARN] digest %s for %s: %v", ss.ID, ss.Owner, err)
+		case n == 0:
+			empty++
+		case n > 0:
+			sent++
+		}
+	}
+	handled := sent + empty + failed
+	if handled > 0 {
+		j.logf("digest sent %d, empty %d, failed %d of %d saved search(es) in %s", sent, empty, failed, len(due), time.Since(start).Round(time.Millisecond))
+	}
+	return handled, ctx.Err()
+}
+
+// send mails ss's digest for the window ending at until. It returns -1
+// when another worker holds the window.
+func (j *Job) send(ctx context.Context, ss store.SavedSearch, until time.Time) (int, error) {
+	retry := j.RetryAfter
+	if retry <= 0 {
+		retry = time.Hour
+	}
+	claimed, err := j.Store.ClaimDigest(ctx, ss, until, 15*time.Minute)
+	if err != nil || !claimed {
+		return -1, err
+	}
+	limit := j.MaxListings
+	if limit <= 0 {
+		limit = 25
+	}
+	listings, total, err := j.Store.DigestListings(ctx, ss.Criteria, ss.DigestedUntil, until, limit)
+	if err == nil && len(listings) > 0 {
+		err = j.Mailer.Send(ctx, j.render(ss, listings, total))
+	}
+	if finishErr := j.Store.FinishDigest(context.WithoutCancel(ctx), ss, until, total, err, retry); finishErr != nil {
+		return total, errors.Join(err, finishErr)
+	}
+	return total, err
+}
+
+// Line is one listing as the templates show it.
+type Line struct {
+	Address string
+	Price   string
+	Facts   string
+	Change  string
+	URL     string
+}
+
+type view struct {
+	Name  string
+	Since string
+	Total int
+	Lines []Line
+	// More counts the updates past MaxListings, linked by MoreURL.
+	More    int
+	MoreURL string
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Parse(`{{.Total}} update(s) for your saved search "{{.Name}}" since {{.Since}}:
+{{range .Lines}}
+{{.Change}}: {{.Address}}
+  {{.Price}}{{if .Facts}} · {{.Facts}}{{end}}{{if .URL}}
+  {{.URL}}{{end}}
+{{end}}{{if .More}}
+…and {{.More}} more{{if .MoreURL}}: {{.MoreURL}}{{end}}
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!doctype html>
+<html><body style="font-family:sans-serif">
+<p>{{.Total}} update(s) for your saved search <strong>{{.Name}}</strong> since {{.Since}}:</p>
+<table cellpadding="6">
+{{range .Lines}}<tr>
+<td><em>{{.Change}}</em></td>
+<td>{{if .URL}}<a href="{{.URL}}">{{.Address}}</a>{{else}}{{.Address}}{{end}}<br>{{.Price}}{{if .Facts}} · {{.Facts}}{{end}}</td>
+</tr>
+{{end}}</table>
+{{if .More}}<p>{{if .MoreURL}}<a href="{{.MoreURL}}">…and {{.More}} more</a>{{else}}…and {{.More}} more{{end}}</p>
+{{end}}</body></html>`))
+
+// render builds the email for listings, the first of total updates.
+func (j *Job) render(ss store.SavedSearch, listings []store.DigestListing, total int) Message {
+	name := ss.Name
+	if name == "" {
+		name = describe(ss.Criteria)
+	}
+	total = max(total, len(listings))
+	v := view{Name: name, Since: ss.DigestedUntil.UTC().Format("Jan 2, 2006 15:04 MST"), Total: total, More: total - len(listings)}
+	if v.More > 0 && j.SearchURL != "" {
+		v.MoreURL = strings.ReplaceAll(j.SearchURL, "{savedSearchId}", url.PathEscape(ss.ID))
+	}
+	for _, l := range listings {
+		v.Lines = append(v.Lines, j.line(l))
+	}
+	var text, html strings.Builder
+	// The templates are fixed and their data is plain strings, so they
+	// cannot fail to execute.
+	_ = textTemplate.Execute(&text, v)
+	_ = htmlTemplate.Execute(&html, v)
+	return Message{
+		To:      ss.Email,
+		Subject: fmt.Sprintf("%d new or updated listing(s) for %s", total, name),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}
+}
+
+func (j *Job) line(l store.DigestListing) Line {
+	out := Line{Address: fmt.Sprintf("%s, %s, %s %s", l.Address, l.City, l.State, l.Zip), Price: "Price not listed"}
+	if l.Price != nil {
+		out.Price = "$" + thousands(int64(*l.Price))
+	}
+	var facts []string
+	if l.Beds != nil {
+		facts = append(facts, strconv.Itoa(*l.Beds)+" bd")
+	}
+	if l.Baths != nil {
+		facts = append(facts, strconv.FormatFloat(*l.Baths, 'f', -1, 64)+" ba")
+	}
+	if l.Sqft != nil {
+		facts = append(facts, thousands(int64(*l.Sqft))+" sqft")
+	}
+	out.Facts = strings.Join(facts, " · ")
+	switch {
+	case l.New:
+		out.Change = "New"
+	case l.PriceChanged:
+		out.Change = "Price change"
+	case l.StatusChanged:
+		out.Change = "Now " + strings.ReplaceAll(l.Status, "_", " ")
+	}
+	if j.ListingURL != "" {
+		out.URL = strings.ReplaceAll(j.ListingURL, "{listingId}", url.PathEscape(l.ListingID))
+	}
+	return out
+}
+
+// describe names a saved search without a name after its criteria.
+func describe(c store.SavedSearchCriteria) string {
+	if c.PostalCode != "" {
+		return c.PostalCode
+	}
+	return c.City + ", " + c.State
+}
+
+func thousands(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 && s[i-1] != '-' {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Run sends due digests every Interval (default 15m) until ctx is done.
+func (j *Job) Run(ctx context.Context) error {
+	every := j.Interval
+	if every <= 0 {
+		every = 15 * time.Minute
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		n, err := j.RunOnce(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			j.logf("digest error: %v", err)
+		}
+		if err == nil && n >= j.batchSize() {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package enrichment attaches third-party neighbourhood data (school
+// ratings, flood zones, walkability) to stored properties. Enrichers run on
+// property.updated events and their results are stored per property in
+// property_enrichments, where the listing detail endpoint reads them.
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/yourorg/search-api/internal/events"
+	"github.com/yourorg/search-api/internal/redisx"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// ErrNoData is returned by an enricher that has nothing for a location, such
+// as an unmapped flood zone. Nothing is stored and the property is retried
+// after the enricher's MaxAge.
+var ErrNoData = errors.New("enrichment: no data")
+
+// Subject is the property being enriched.
+type Subject struct {
+	PropertyKey string
+	Address     string
+	City        string
+	State       string
+	Zip         string
+	Lat         float64
+	Lon         float64
+}
+
+// Enricher is implemented by each enrichment source. Results are marshalled
+// to JSON as returned.
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, subject Subject) (any, error)
+}
+
+// Source is an enricher with its own request rate and result lifetime.
+type Source struct {
+	Enricher Enricher
+	// Limiter bounds calls to the enricher; nil is unlimited. Properties
+	// that find it exhausted are skipped and picked up on a later update.
+	Limiter *rate.Limiter
+	// MaxAge is how long a stored result is kept before the enricher runs
+	// again for the property. Default 30 days.
+	MaxAge time.Duration
+	// CacheTTL is how long a result is cached in Redis for other properties
+	// at the same location, such as units of one building. Default 24h.
+	CacheTTL time.Duration
+}
+
+func (src *Source) maxAge() time.Duration {
+	if src.MaxAge <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return src.MaxAge
+}
+
+func (src *Source) cacheTTL() time.Duration {
+	if src.CacheTTL <= 0 {
+		return 24 * time.Hour
+	}
+	return src.CacheTTL
+}
+
+var stats = expvar.NewMap("enrichment")
+
+// Service runs every Source against properties and stores the results.
+type Service struct {
+	Store   *store.Store
+	Redis   *redisx.Client // optional location cache
+	Sources []*Source
+}
+
+// Enabled reports whether there is anything to run.
+func (s *Service) Enabled() bool { return s != nil && s.Store != nil && len(s.Sources) > 0 }
+
+// Run enriches the property of every property.updated event on pub until
+// ctx is done.
+func (s *Service) Run(ctx context.Context, pub events.Publisher) {
+	sub, unsubscribe := pub.Subscribe("enrichment")
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-sub:
+			if !ok {
+				return
+			}
+			p, ok := evt.Payload.(events.PropertyUpdated)
+			if !ok || p.PropertyID == "" {
+				continue
+			}
+			if err := s.Enrich(ctx, p.PropertyID); err != nil && ctx.Err() == nil {
+				log.Printf("[WARN] enrichment of %s failed: %v", p.PropertyKey, err)
+			}
+		}
+	}
+}
+
+// Enrich runs each source whose stored result for the property is missing
+// or older than its MaxAge. Properties without coordinates are skipped.
+func (s *Service) Enrich(ctx context.Context, propertyID string) error {
+	if !s.Enabled() {
+		return nil
+	}
+	rec, err := s.Store.EnrichmentSubject(ctx, propertyID)
+	if err != nil || rec == nil || !rec.Lat.Valid || !rec.Lon.Valid {
+		return err
+	}
+	subject := Subject{
+		PropertyKey: rec.PropertyKey,
+		Address:     rec.AddressLine1,
+		City:        rec.City,
+		State:       rec.State,
+		Zip:         rec.Zip,
+		Lat:         rec.Lat.Float64,
+		Lon:         rec.Lon.Float64,
+	}
+	fetched, err := s.Store.EnrichmentTimes(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	var joined error
+	for _, src := range s.Sources {
+		name := src.Enricher.Name()
+		if at, ok := fetched[name]; ok && time.Since(at) < src.maxAge() {
+			continue
+		}
+		data, err := s.lookup(ctx, src, subject)
+		switch {
+		case errors.Is(err, ErrNoData), errors.Is(err, errRateLimited):
+			continue
+		case err != nil:
+			stats.Add(name+".errors", 1)
+			joined = errors.Join(joined, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if err := s.Store.SaveEnrichment(ctx, subject.PropertyKey, name, data); err != nil {
+			joined = errors.Join(joined, fmt.Errorf("%s: save: %w", name, err))
+		}
+	}
+	return joined
+}
+
+var errRateLimited = errors.New("enrichment: rate limited")
+
+// lookup serves a result from the location cache or calls the enricher.
+func (s *Service) lookup(ctx context.Context, src *Source, subject Subject) ([]byte, error) {
+	name := src.Enricher.Name()
+	key := fmt.Sprintf("enrich:%s:%.4f,%.4f", name, subject.Lat, subject.Lon)
+	if s.Redis != nil {
+		if v, err := s.Redis.Get(ctx, key); err == nil && v != "" {
+			stats.Add(name+".cached", 1)
+			return []byte(v), nil
+		}
+	}
+	if src.Limiter != nil && !src.Limiter.Allow() {
+		stats.Add(name+".rate_limited", 1)
+		return nil, errRateLimited
+	}
+	v, err := src.Enricher.Enrich(ctx, subject)
+	if errors.Is(err, ErrNoData) {
+		stats.Add(name+".no_data", 1)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	stats.Add(name+".enriched", 1)
+	if s.Redis != nil {
+		_ = s.Redis.Set(ctx, key, string(data), src.cacheTTL())
+	}
+	return data, nil
+}
@@ -0,0 +1,48 @@
+package enrichment
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// SourcesFromEnv builds the configured enrichers:
+//
+//	ENRICH_FLOOD=1                 FEMA flood zones (no key needed)
+//	ENRICH_SCHOOLS_API_KEY         GreatSchools nearby schools
+//	ENRICH_WALKSCORE_API_KEY       Walk Score walkability
+//
+// Each NAME (FLOOD, SCHOOLS, WALKSCORE) is tuned by ENRICH_<NAME>_RPS
+// (requests per second, default 1), ENRICH_<NAME>_MAX_AGE (refresh age of a
+// stored result) and ENRICH_<NAME>_CACHE_TTL (Redis location cache).
+func SourcesFromEnv() []*Source {
+	var out []*Source
+	if os.Getenv("ENRICH_FLOOD") == "1" {
+		out = append(out, sourceFromEnv("FLOOD", &FloodEnricher{BaseURL: os.Getenv("ENRICH_FLOOD_URL")}, 180*24*time.Hour))
+	}
+	if key := os.Getenv("ENRICH_SCHOOLS_API_KEY"); key != "" {
+		out = append(out, sourceFromEnv("SCHOOLS", &SchoolsEnricher{APIKey: key, BaseURL: os.Getenv("ENRICH_SCHOOLS_URL")}, 30*24*time.Hour))
+	}
+	if key := os.Getenv("ENRICH_WALKSCORE_API_KEY"); key != "" {
+		out = append(out, sourceFromEnv("WALKSCORE", &WalkScoreEnricher{APIKey: key, BaseURL: os.Getenv("ENRICH_WALKSCORE_URL")}, 90*24*time.Hour))
+	}
+	return out
+}
+
+func sourceFromEnv(name string, e Enricher, maxAge time.Duration) *Source {
+	prefix := "ENRICH_" + name + "_"
+	rps := 1.0
+	if v, err := strconv.ParseFloat(os.Getenv(prefix+"RPS"), 64); err == nil && v > 0 {
+		rps = v
+	}
+	src := &Source{Enricher: e, Limiter: rate.NewLimiter(rate.Limit(rps), max(1, int(rps))), MaxAge: maxAge}
+	if d, err := time.ParseDuration(os.Getenv(prefix + "MAX_AGE")); err == nil && d > 0 {
+		src.MaxAge = d
+	}
+	if d, err := time.ParseDuration(os.Getenv(prefix + "CACHE_TTL")); err == nil && d > 0 {
+		src.CacheTTL = d
+	}
+	return src
+}
@@ -0,0 +1,78 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const defaultFloodURL = "https://hazards.fema.gov/arcgis/rest/services/public/NFHL/MapServer/28/query"
+
+// FloodZone is the FEMA flood hazard zone at a property.
+type FloodZone struct {
+	Zone    string `json:"zone"`
+	Subtype string `json:"subtype,omitempty"`
+	// SpecialFloodHazardArea is set for zones where flood insurance is
+	// mandatory for federally backed mortgages (A and V zones).
+	SpecialFloodHazardArea bool     `json:"specialFloodHazardArea"`
+	BaseFloodElevation     *float64 `json:"baseFloodElevation,omitempty"`
+	Source                 string   `json:"source"`
+}
+
+// FloodEnricher looks up the flood zone of a point in FEMA's National Flood
+// Hazard Layer. The service is public and needs no key.
+type FloodEnricher struct {
+	BaseURL string // default FEMA's NFHL flood hazard zones layer
+	Client  *http.Client
+}
+
+func (f *FloodEnricher) Name() string { return "flood_zone" }
+
+func (f *FloodEnricher) Enrich(ctx context.Context, subject Subject) (any, error) {
+	base := f.BaseURL
+	if base == "" {
+		base = defaultFloodURL
+	}
+	q := url.Values{}
+	q.Set("geometry", strconv.FormatFloat(subject.Lon, 'f', 6, 64)+","+strconv.FormatFloat(subject.Lat, 'f', 6, 64))
+	q.Set("geometryType", "esriGeometryPoint")
+	q.Set("inSR", "4326")
+	q.Set("spatialRel", "esriSpatialRelIntersects")
+	q.Set("outFields", "FLD_ZONE,ZONE_SUBTY,SFHA_TF,STATIC_BFE")
+	q.Set("returnGeometry", "false")
+	q.Set("f", "json")
+	var body struct {
+		Features []struct {
+			Attributes struct {
+				Zone    string  `json:"FLD_ZONE"`
+				Subtype string  `json:"ZONE_SUBTY"`
+				SFHA    string  `json:"SFHA_TF"`
+				BFE     float64 `json:"STATIC_BFE"`
+			} `json:"attributes"`
+		} `json:"features"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := getJSON(ctx, f.Client, base+"?"+q.Encode(), nil, &body); err != nil {
+		return nil, err
+	}
+	// ArcGIS reports query errors in a 200 body.
+	if body.Error != nil {
+		return nil, fmt.Errorf("nfhl: %d %s", body.Error.Code, body.Error.Message)
+	}
+	if len(body.Features) == 0 {
+		return nil, ErrNoData
+	}
+	a := body.Features[0].Attributes
+	zone := FloodZone{Zone: a.Zone, Subtype: a.Subtype, SpecialFloodHazardArea: a.SFHA == "T", Source: "FEMA NFHL"}
+	// -9999 marks zones without a static base flood elevation.
+	if a.BFE > -9999 && a.BFE != 0 {
+		bfe := a.BFE
+		zone.BaseFloodElevation = &bfe
+	}
+	return zone, nil
+}
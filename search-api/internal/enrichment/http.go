@@ -0,0 +1,37 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// getJSON fetches url and decodes a 200 response into out.
+func getJSON(ctx context.Context, client *http.Client, url string, header http.Header, out any) error {
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s: %s", req.URL.Host, resp.Status, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
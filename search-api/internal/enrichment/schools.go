@@ -0,0 +1,102 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const defaultSchoolsURL = "https://gs-api.greatschools.org/nearby-schools"
+
+// School is one nearby school.
+type School struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type,omitempty"`  // public, charter or private
+	Level    string   `json:"level,omitempty"` // e.g. "Elementary"
+	Rating   *int     `json:"rating,omitempty"`
+	Distance float64  `json:"distanceMiles"`
+	Grades   []string `json:"grades,omitempty"`
+}
+
+// Schools lists the schools near a property. AverageRating is over the rated
+// schools only.
+type Schools struct {
+	Schools       []School `json:"schools"`
+	AverageRating *float64 `json:"averageRating,omitempty"`
+	Source        string   `json:"source"`
+}
+
+// SchoolsEnricher looks up nearby schools and their 1-10 ratings from the
+// GreatSchools NearbySchools API.
+type SchoolsEnricher struct {
+	APIKey  string
+	BaseURL string  // default GreatSchools' nearby-schools endpoint
+	Radius  float64 // miles, default 5
+	Limit   int     // default 10
+	Client  *http.Client
+}
+
+func (e *SchoolsEnricher) Name() string { return "schools" }
+
+func (e *SchoolsEnricher) Enrich(ctx context.Context, subject Subject) (any, error) {
+	if e.APIKey == "" {
+		return nil, errors.New("schools: no API key")
+	}
+	base := e.BaseURL
+	if base == "" {
+		base = defaultSchoolsURL
+	}
+	radius, limit := e.Radius, e.Limit
+	if radius <= 0 {
+		radius = 5
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	q := url.Values{}
+	q.Set("lat", strconv.FormatFloat(subject.Lat, 'f', 6, 64))
+	q.Set("lon", strconv.FormatFloat(subject.Lon, 'f', 6, 64))
+	q.Set("distance", strconv.FormatFloat(radius, 'f', -1, 64))
+	q.Set("limit", strconv.Itoa(limit))
+	var body struct {
+		Schools []struct {
+			Name       string   `json:"name"`
+			Type       string   `json:"type"`
+			Level      string   `json:"level"`
+			LevelCodes string   `json:"level-codes"`
+			Rating     *int     `json:"rating"`
+			Distance   float64  `json:"distance"`
+			Grades     []string `json:"grades"`
+		} `json:"schools"`
+	}
+	if err := getJSON(ctx, e.Client, base+"?"+q.Encode(), http.Header{"X-Api-Key": {e.APIKey}}, &body); err != nil {
+		return nil, err
+	}
+	if len(body.Schools) == 0 {
+		return nil, ErrNoData
+	}
+	out := Schools{Schools: make([]School, 0, len(body.Schools)), Source: "GreatSchools"}
+	var sum, rated float64
+	for _, s := range body.Schools {
+		out.Schools = append(out.Schools, School{
+			Name:     s.Name,
+			Type:     s.Type,
+			Level:    s.Level,
+			Rating:   s.Rating,
+			Distance: math.Round(s.Distance*100) / 100,
+			Grades:   s.Grades,
+		})
+		if s.Rating != nil {
+			sum += float64(*s.Rating)
+			rated++
+		}
+	}
+	if rated > 0 {
+		avg := math.Round(sum/rated*10) / 10
+		out.AverageRating = &avg
+	}
+	return out, nil
+}
@@ -0,0 +1,80 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const defaultWalkScoreURL = "https://api.walkscore.com/score"
+
+// Walkability holds the 0-100 Walk Score, Transit Score and Bike Score of a
+// property. Transit and bike scores are missing in areas Walk Score does not
+// cover.
+type Walkability struct {
+	WalkScore    int    `json:"walkScore"`
+	Description  string `json:"description,omitempty"`
+	TransitScore *int   `json:"transitScore,omitempty"`
+	BikeScore    *int   `json:"bikeScore,omitempty"`
+	Source       string `json:"source"`
+}
+
+// WalkScoreEnricher looks up walkability from the Walk Score API.
+type WalkScoreEnricher struct {
+	APIKey  string
+	BaseURL string // default the Walk Score score endpoint
+	Client  *http.Client
+}
+
+func (e *WalkScoreEnricher) Name() string { return "walkability" }
+
+func (e *WalkScoreEnricher) Enrich(ctx context.Context, subject Subject) (any, error) {
+	if e.APIKey == "" {
+		return nil, errors.New("walkscore: no API key")
+	}
+	base := e.BaseURL
+	if base == "" {
+		base = defaultWalkScoreURL
+	}
+	q := url.Values{}
+	q.Set("format", "json")
+	q.Set("address", strings.Join([]string{subject.Address, subject.City, subject.State, subject.Zip}, " "))
+	q.Set("lat", strconv.FormatFloat(subject.Lat, 'f', 6, 64))
+	q.Set("lon", strconv.FormatFloat(subject.Lon, 'f', 6, 64))
+	q.Set("transit", "1")
+	q.Set("bike", "1")
+	q.Set("wsapikey", e.APIKey)
+	var body struct {
+		Status      int    `json:"status"`
+		WalkScore   int    `json:"walkscore"`
+		Description string `json:"description"`
+		Transit     *struct {
+			Score int `json:"score"`
+		} `json:"transit"`
+		Bike *struct {
+			Score int `json:"score"`
+		} `json:"bike"`
+	}
+	if err := getJSON(ctx, e.Client, base+"?"+q.Encode(), nil, &body); err != nil {
+		return nil, err
+	}
+	switch body.Status {
+	case 1:
+	case 2: // score is being calculated; try again on a later update
+		return nil, ErrNoData
+	default:
+		return nil, fmt.Errorf("walkscore: status %d", body.Status)
+	}
+	out := Walkability{WalkScore: body.WalkScore, Description: body.Description, Source: "Walk Score"}
+	if body.Transit != nil {
+		out.TransitScore = &body.Transit.Score
+	}
+	if body.Bike != nil {
+		out.BikeScore = &body.Bike.Score
+	}
+	return out, nil
+}
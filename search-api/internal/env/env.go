@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 )
 
 func Must(k string) string {
@@ -25,3 +26,17 @@ func GetInt(k string, def int) int {
 	if err != nil { return def }
 	return i
 }
+func GetFloat(k string, def float64) float64 {
+	v := os.Getenv(k)
+	if v == "" { return def }
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil { return def }
+	return f
+}
+func GetDuration(k string, def time.Duration) time.Duration {
+	v := os.Getenv(k)
+	if v == "" { return def }
+	d, err := time.ParseDuration(v)
+	if err != nil { return def }
+	return d
+}
@@ -2,28 +2,97 @@ package events
 
 import (
     "context"
+    "sync"
+    "sync/atomic"
 )
 
-type PropertyUpdated struct {
-    PropertyID   string
-    PropertyKey  string
+type Publisher interface {
+    Publish(ctx context.Context, evt Event)
+    // Subscribe registers a named subscriber with its own buffered channel.
+    // Every subscriber receives every event published after it subscribed;
+    // when its buffer is full the event is dropped for that subscriber only.
+    // Calling unsubscribe closes the channel.
+    Subscribe(name string) (ch <-chan Event, unsubscribe func())
+    Stats() Stats
 }
 
-type Publisher interface {
-    PublishPropertyUpdated(ctx context.Context, evt PropertyUpdated)
-    SubscribePropertyUpdated() <-chan PropertyUpdated
+// Stats counts events per subscriber. Published counts Publish calls.
+type Stats struct {
+    Published   uint64                     `json:"published"`
+    Subscribers map[string]SubscriberStats `json:"subscribers"`
+}
+
+type SubscriberStats struct {
+    Delivered uint64 `json:"delivered"`
+    Dropped   uint64 `json:"dropped"`
+    Pending   int    `json:"pending"`
+}
+
+type subscriber struct {
+    name      string
+    ch        chan Event
+    delivered atomic.Uint64
+    dropped   atomic.Uint64
 }
 
-type inMemory struct { ch chan PropertyUpdated }
+// inMemory fans each event out to every subscriber without blocking the
+// publisher.
+type inMemory struct {
+    buffer    int
+    published atomic.Uint64
+
+    mu   sync.RWMutex
+    subs map[*subscriber]struct{}
+}
 
+// NewInMemory returns a fan-out publisher; buffer is the channel size of
+// each subscriber.
 func NewInMemory(buffer int) Publisher {
     if buffer <= 0 { buffer = 256 }
-    return &inMemory{ ch: make(chan PropertyUpdated, buffer) }
+    return &inMemory{ buffer: buffer, subs: map[*subscriber]struct{}{} }
 }
 
-func (m *inMemory) PublishPropertyUpdated(_ context.Context, evt PropertyUpdated) {
-    select { case m.ch <- evt: default: }
+func (m *inMemory) Publish(_ context.Context, evt Event) {
+    m.published.Add(1)
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    for s := range m.subs {
+        select {
+        case s.ch <- evt:
+            s.delivered.Add(1)
+        default:
+            s.dropped.Add(1)
+        }
+    }
 }
 
-func (m *inMemory) SubscribePropertyUpdated() <-chan PropertyUpdated { return m.ch }
+func (m *inMemory) Subscribe(name string) (<-chan Event, func()) {
+    s := &subscriber{ name: name, ch: make(chan Event, m.buffer) }
+    m.mu.Lock()
+    m.subs[s] = struct{}{}
+    m.mu.Unlock()
+    var once sync.Once
+    return s.ch, func() {
+        once.Do(func() {
+            m.mu.Lock()
+            delete(m.subs, s)
+            m.mu.Unlock()
+            close(s.ch)
+        })
+    }
+}
 
+// Stats sums subscribers that share a name.
+func (m *inMemory) Stats() Stats {
+    st := Stats{ Published: m.published.Load(), Subscribers: map[string]SubscriberStats{} }
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    for s := range m.subs {
+        cur := st.Subscribers[s.name]
+        cur.Delivered += s.delivered.Load()
+        cur.Dropped += s.dropped.Load()
+        cur.Pending += len(s.ch)
+        st.Subscribers[s.name] = cur
+    }
+    return st
+}
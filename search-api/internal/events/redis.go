@@ -0,0 +1,35 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/yourorg/search-api/internal/redisx"
+)
+
+// Forward mirrors every event published on pub to the Redis pub/sub channel
+// as JSON, so other processes can follow this one's events with Decode. It
+// returns when ctx is done.
+func Forward(ctx context.Context, pub Publisher, rdb *redisx.Client, channel string) {
+	sub, unsubscribe := pub.Subscribe("redis:" + channel)
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-sub:
+			if !ok {
+				return
+			}
+			b, err := json.Marshal(evt)
+			if err != nil {
+				log.Printf("[WARN] events: marshal %s: %v", evt.Type, err)
+				continue
+			}
+			if err := rdb.Publish(ctx, channel, string(b)); err != nil {
+				log.Printf("[WARN] events: publish %s to %s: %v", evt.Type, channel, err)
+			}
+		}
+	}
+}
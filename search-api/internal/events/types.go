@@ -0,0 +1,141 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Version is the envelope schema version stamped on every event. Bump it
+// when a payload changes incompatibly.
+const Version = 1
+
+// Type names an event kind on the wire.
+type Type string
+
+const (
+	TypePropertyUpdated      Type = "property.updated"
+	TypePropertyResolved     Type = "property.resolved"
+	TypeListingPriceChanged  Type = "listing.price_changed"
+	TypeListingStatusChanged Type = "listing.status_changed"
+	TypePhotoSetChanged      Type = "listing.photos_changed"
+)
+
+// Payload is the body of one event kind.
+type Payload interface {
+	EventType() Type
+}
+
+// Event is the versioned envelope every event travels in.
+type Event struct {
+	ID         string    `json:"id"`
+	Type       Type      `json:"type"`
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Payload    Payload   `json:"payload"`
+}
+
+// New wraps p in an envelope stamped now.
+func New(p Payload) Event {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return Event{
+		ID:         hex.EncodeToString(b[:]),
+		Type:       p.EventType(),
+		Version:    Version,
+		OccurredAt: time.Now().UTC(),
+		Payload:    p,
+	}
+}
+
+// PropertyUpdated is published whenever a property or one of its listings
+// is written.
+type PropertyUpdated struct {
+	PropertyID  string `json:"property_id"`
+	PropertyKey string `json:"property_key"`
+}
+
+// PropertyResolved is published when an address resolves to a property.
+// Source is cache, store or fresh.
+type PropertyResolved struct {
+	PropertyKey string `json:"property_key"`
+	Source      string `json:"source"`
+}
+
+// ListingPriceChanged is published when an existing listing's list price
+// changes. A nil price was unknown.
+type ListingPriceChanged struct {
+	ListingID   string   `json:"listing_id"`
+	PropertyID  string   `json:"property_id"`
+	PropertyKey string   `json:"property_key"`
+	OldPrice    *float64 `json:"old_price"`
+	NewPrice    *float64 `json:"new_price"`
+}
+
+// ListingStatusChanged is published when an existing listing's status
+// changes: to a status the provider reports, such as sold or pending, or to
+// pending_removal when crawls stop finding it.
+type ListingStatusChanged struct {
+	ListingID   string `json:"listing_id"`
+	PropertyID  string `json:"property_id"`
+	PropertyKey string `json:"property_key"`
+	OldStatus   string `json:"old_status"`
+	NewStatus   string `json:"new_status"`
+}
+
+// PhotoSetChanged is published when a listing's stored photos gain or lose
+// URLs.
+type PhotoSetChanged struct {
+	ListingID   string `json:"listing_id"`
+	PropertyID  string `json:"property_id"`
+	PropertyKey string `json:"property_key"`
+	Added       int    `json:"added"`
+	Removed     int    `json:"removed"`
+	Count       int    `json:"count"`
+}
+
+func (PropertyUpdated) EventType() Type      { return TypePropertyUpdated }
+func (PropertyResolved) EventType() Type     { return TypePropertyResolved }
+func (ListingPriceChanged) EventType() Type  { return TypeListingPriceChanged }
+func (ListingStatusChanged) EventType() Type { return TypeListingStatusChanged }
+func (PhotoSetChanged) EventType() Type      { return TypePhotoSetChanged }
+
+// Decode parses an envelope written by json.Marshal(Event), typing the
+// payload by the envelope's type.
+func Decode(data []byte) (Event, error) {
+	var raw struct {
+		ID         string          `json:"id"`
+		Type       Type            `json:"type"`
+		Version    int             `json:"version"`
+		OccurredAt time.Time       `json:"occurred_at"`
+		Payload    json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Event{}, err
+	}
+	evt := Event{ID: raw.ID, Type: raw.Type, Version: raw.Version, OccurredAt: raw.OccurredAt}
+	var err error
+	switch raw.Type {
+	case TypePropertyUpdated:
+		evt.Payload, err = decodePayload[PropertyUpdated](raw.Payload)
+	case TypePropertyResolved:
+		evt.Payload, err = decodePayload[PropertyResolved](raw.Payload)
+	case TypeListingPriceChanged:
+		evt.Payload, err = decodePayload[ListingPriceChanged](raw.Payload)
+	case TypeListingStatusChanged:
+		evt.Payload, err = decodePayload[ListingStatusChanged](raw.Payload)
+	case TypePhotoSetChanged:
+		evt.Payload, err = decodePayload[PhotoSetChanged](raw.Payload)
+	default:
+		return evt, fmt.Errorf("events: unknown type %q", raw.Type)
+	}
+	return evt, err
+}
+
+func decodePayload[T Payload](data json.RawMessage) (Payload, error) {
+	var p T
+	err := json.Unmarshal(data, &p)
+	return p, err
+}
@@ -0,0 +1,275 @@
+// Package flags gates new or risky behaviour so it can be rolled out to a
+// share of consumers, turned on for one API key first, or switched off
+// without a deploy.
+//
+// A flag is on for a consumer (an API key or token subject, as in usage
+// accounting) when, in order: the consumer has an override; the flag's
+// stored rollout includes the consumer; FLAGS in the environment sets it;
+// or its default in Known is on. Rollouts of n percent turn the flag on for
+// the consumers whose hash of flag and consumer falls in the first n
+// buckets, so raising the percentage only adds consumers.
+package flags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/redisx"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// Flags the API checks.
+const (
+	// ServeFromIndex serves searches and listings pages from the store when
+	// it has them; off sends them straight to the provider.
+	ServeFromIndex = "serve-from-index"
+	// DualRead serves listings pages from the store whenever it has them and
+	// re-crawls the ZIPs of stale ones in the background.
+	DualRead = "dual-read"
+)
+
+// Definition is a flag the code knows about.
+type Definition struct {
+	Default     bool
+	Description string
+}
+
+// Known lists the flags the API checks and their defaults. Other flags may
+// be stored and evaluated; they default to off.
+var Known = map[string]Definition{
+	ServeFromIndex: {Default: true, Description: "Serve searches and listings pages from the store before asking the provider"},
+	DualRead:       {Description: "Serve listings pages from the store even when stale and refresh their ZIPs in the background"},
+}
+
+// Sources for State.Source.
+const (
+	SourceOverride = "override"
+	SourceRollout  = "rollout"
+	SourceEnv      = "env"
+	SourceDefault  = "default"
+)
+
+// versionKey holds the time of the last flag change, so replicas reload
+// soon after one instead of waiting for the next periodic reload.
+const versionKey = "flags:version"
+
+// Set evaluates flags. A nil or empty Set gives every flag its default in
+// Known; Env adds FLAGS, and Store the rollouts and overrides managed
+// through the admin API.
+type Set struct {
+	Store *store.Store
+	// Redis shares change notices between replicas; nil leaves them to
+	// the periodic reload.
+	Redis *redisx.Client
+	// Env holds percentages from the FLAGS variable; see ParseEnv.
+	Env map[string]int
+	// Reload is how often stored flags are re-read, default 1m. Changes
+	// announced through Redis are picked up within 5s.
+	Reload time.Duration
+
+	mu      sync.RWMutex
+	rules   map[string]store.FeatureFlag
+	version string
+}
+
+// ParseEnv reads FLAGS: comma-separated name=value entries, where value is
+// on, off or a rollout percentage, e.g. "serve-from-index=off,new-ranking=25".
+// Invalid entries are skipped.
+func ParseEnv(v string) map[string]int {
+	var m map[string]int
+	for _, part := range strings.Split(v, ",") {
+		name, val, ok := strings.Cut(part, "=")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			continue
+		}
+		p, err := ParsePercent(val)
+		if err != nil {
+			continue
+		}
+		if m == nil {
+			m = map[string]int{}
+		}
+		m[name] = p
+	}
+	return m
+}
+
+// ParsePercent accepts on, off, true, false or a percentage (25 or 25%).
+func ParsePercent(v string) (int, error) {
+	switch v = strings.ToLower(strings.TrimSpace(v)); v {
+	case "on", "true", "1":
+		return 100, nil
+	case "off", "false", "0":
+		return 0, nil
+	}
+	p, err := strconv.Atoi(strings.TrimSuffix(v, "%"))
+	if err != nil || p < 0 || p > 100 {
+		return 0, fmt.Errorf("%q must be on, off or a percentage from 0 to 100", v)
+	}
+	return p, nil
+}
+
+// State is a flag's value for one consumer and what decided it.
+type State struct {
+	Enabled bool
+	Source  string
+}
+
+// Evaluate returns name's state for consumer.
+func (s *Set) Evaluate(name, consumer string) State {
+	if s != nil {
+		s.mu.RLock()
+		rule, ok := s.rules[name]
+		s.mu.RUnlock()
+		if ok {
+			if on, ok := rule.Overrides[consumer]; ok {
+				return State{Enabled: on, Source: SourceOverride}
+			}
+			if rule.Percent >= 0 {
+				return State{Enabled: inRollout(name, consumer, rule.Percent), Source: SourceRollout}
+			}
+		}
+		if p, ok := s.Env[name]; ok {
+			return State{Enabled: inRollout(name, consumer, p), Source: SourceEnv}
+		}
+	}
+	return State{Enabled: Known[name].Default, Source: SourceDefault}
+}
+
+// inRollout reports whether consumer falls in the first percent of name's
+// 100 buckets.
+func inRollout(name, consumer string, percent int) bool {
+	if percent <= 0 {
+		return false
+	}
+	if percent >= 100 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name + "\x00" + consumer))
+	return int(h.Sum32()%100) < percent
+}
+
+// Rules returns the stored flags as last loaded.
+func (s *Set) Rules() map[string]store.FeatureFlag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules
+}
+
+// Load re-reads the stored flags. Without a store it does nothing.
+func (s *Set) Load(ctx context.Context) error {
+	if s == nil || s.Store == nil {
+		return nil
+	}
+	flags, err := s.Store.FeatureFlags(ctx)
+	if err != nil {
+		return err
+	}
+	rules := make(map[string]store.FeatureFlag, len(flags))
+	for _, f := range flags {
+		rules[f.Name] = f
+	}
+	s.mu.Lock()
+	s.rules = rules
+	s.mu.Unlock()
+	return nil
+}
+
+// Changed reloads after a flag change and tells the other replicas.
+func (s *Set) Changed(ctx context.Context) error {
+	if err := s.Load(ctx); err != nil {
+		return err
+	}
+	if s.Redis != nil {
+		v := strconv.FormatInt(time.Now().UnixNano(), 10)
+		if err := s.Redis.Set(ctx, versionKey, v, 0); err != nil {
+			log.Printf("[WARN] flags: change notice not published: %v", err)
+		} else {
+			s.mu.Lock()
+			s.version = v
+			s.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// Run loads the stored flags and keeps them current until ctx is done.
+func (s *Set) Run(ctx context.Context) {
+	if s == nil || s.Store == nil {
+		return
+	}
+	reload := s.Reload
+	if reload <= 0 {
+		reload = time.Minute
+	}
+	load := func() {
+		lctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := s.Load(lctx); err != nil && ctx.Err() == nil {
+			log.Printf("[WARN] flags: reload failed, keeping previous flags: %v", err)
+		}
+	}
+	load()
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if s.Redis != nil {
+			v, err := s.Redis.Get(ctx, versionKey)
+			s.mu.RLock()
+			changed := err == nil && v != s.version
+			s.mu.RUnlock()
+			if changed {
+				load()
+				last = time.Now()
+				s.mu.Lock()
+				s.version = v
+				s.mu.Unlock()
+				continue
+			}
+		}
+		if time.Since(last) >= reload {
+			load()
+			last = time.Now()
+		}
+	}
+}
+
+type setKey struct{}
+
+// Middleware makes the set available to Enabled in the request's handlers.
+func (s *Set) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), setKey{}, s)))
+	})
+}
+
+// Enabled reports whether name is on for the request's caller. Outside a
+// request served with Middleware it returns name's default.
+func Enabled(ctx context.Context, name string) bool {
+	s, _ := ctx.Value(setKey{}).(*Set)
+	return s.Evaluate(name, Consumer(ctx)).Enabled
+}
+
+// Consumer is the ID flags are evaluated for: the caller's API key or token
+// subject, or anonymous.
+func Consumer(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.ID()
+	}
+	return "anonymous"
+}
@@ -0,0 +1,147 @@
+// Package geocode turns coordinates into street addresses for the
+// resolve-by-location fallback.
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const defaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+// Address is a reverse-geocoded US street address.
+type Address struct {
+	Line1 string
+	City  string
+	State string // two-letter code
+	Zip   string
+}
+
+// Reverser finds the street address at a point. found is false when the
+// point has no addressable building, such as a park or a road.
+type Reverser interface {
+	Reverse(ctx context.Context, lat, lon float64) (addr Address, found bool, err error)
+}
+
+// Nominatim reverse-geocodes with an OpenStreetMap Nominatim server. The
+// public server allows one request per second and requires an identifying
+// User-Agent; point BaseURL at a self-hosted instance for real traffic.
+type Nominatim struct {
+	BaseURL   string // default the public OSM server
+	UserAgent string
+	Client    *http.Client
+	Limiter   *rate.Limiter // optional
+}
+
+// FromEnv returns the configured reverser, or nil when GEOCODE_REVERSE is
+// not 1. GEOCODE_URL, GEOCODE_USER_AGENT and GEOCODE_RPS (default 1) tune it.
+func FromEnv() Reverser {
+	if os.Getenv("GEOCODE_REVERSE") != "1" {
+		return nil
+	}
+	rps := 1.0
+	if v, err := strconv.ParseFloat(os.Getenv("GEOCODE_RPS"), 64); err == nil && v > 0 {
+		rps = v
+	}
+	return &Nominatim{
+		BaseURL:   os.Getenv("GEOCODE_URL"),
+		UserAgent: os.Getenv("GEOCODE_USER_AGENT"),
+		Limiter:   rate.NewLimiter(rate.Limit(rps), max(1, int(rps))),
+	}
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+func (n *Nominatim) Reverse(ctx context.Context, lat, lon float64) (Address, bool, error) {
+	if n.Limiter != nil {
+		if err := n.Limiter.Wait(ctx); err != nil {
+			return Address{}, false, err
+		}
+	}
+	base := n.BaseURL
+	if base == "" {
+		base = defaultNominatimURL
+	}
+	q := url.Values{}
+	q.Set("format", "jsonv2")
+	q.Set("lat", strconv.FormatFloat(lat, 'f', 6, 64))
+	q.Set("lon", strconv.FormatFloat(lon, 'f', 6, 64))
+	q.Set("zoom", "18") // building level
+	q.Set("addressdetails", "1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+"/reverse?"+q.Encode(), nil)
+	if err != nil {
+		return Address{}, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	ua := n.UserAgent
+	if ua == "" {
+		ua = "search-api"
+	}
+	req.Header.Set("User-Agent", ua)
+	client := n.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Address{}, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Address{}, false, fmt.Errorf("%s: %s: %s", req.URL.Host, resp.Status, body)
+	}
+	var body struct {
+		Error   string `json:"error"`
+		Address struct {
+			HouseNumber string `json:"house_number"`
+			Road        string `json:"road"`
+			City        string `json:"city"`
+			Town        string `json:"town"`
+			Village     string `json:"village"`
+			Hamlet      string `json:"hamlet"`
+			State       string `json:"state"`
+			ISOState    string `json:"ISO3166-2-lvl4"` // e.g. "US-CA"
+			Postcode    string `json:"postcode"`
+			CountryCode string `json:"country_code"`
+		} `json:"address"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Address{}, false, err
+	}
+	a := body.Address
+	// Nominatim answers an unaddressable point with {"error": "Unable to geocode"}.
+	if body.Error != "" || a.CountryCode != "us" || a.HouseNumber == "" || a.Road == "" {
+		return Address{}, false, nil
+	}
+	state := strings.TrimPrefix(a.ISOState, "US-")
+	if state == "" {
+		state = a.State
+	}
+	zip, _, _ := strings.Cut(a.Postcode, "-")
+	return Address{
+		Line1: a.HouseNumber + " " + a.Road,
+		City:  firstNonEmpty(a.City, a.Town, a.Village, a.Hamlet),
+		State: state,
+		Zip:   strings.TrimSpace(zip),
+	}, true, nil
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
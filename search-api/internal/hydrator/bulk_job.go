@@ -5,15 +5,41 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/yourorg/search-api/attom"
 	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/photoclass"
 	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type BulkConfig struct {
+	// Name identifies the job's runs and checkpoints; default "bulk".
+	Name string
+	// Targets selects where each cycle's ZIPs come from: TargetsStatic
+	// (Zips), TargetsTable, TargetsStale or TargetsDemand.
+	Targets      string
+	TargetLimit  int           // ZIPs per cycle for table, stale or demand targets, default 50
+	StaleAfter   time.Duration // stale window for TargetsStale, default 24h
+	DemandWindow time.Duration // search window for TargetsDemand, default 7 days
+	// Adaptive replaces the fixed Interval ticker with a scheduler that
+	// refreshes ZIPs by staleness and API demand within the quota pace.
+	// Interval is then the refresh interval of a ZIP with one recent lookup.
+	Adaptive      bool
+	MinInterval   time.Duration // hottest ZIPs, default Interval/8
+	MaxInterval   time.Duration // ZIPs without lookups, default Interval*4
+	SchedulerTick time.Duration // default 5m
+	// Concurrency is how many ZIPs ingest in parallel; default 1.
+	Concurrency int
+	// DelistAfterCycles is how many consecutive complete crawls a listing may
+	// be missing from before it is marked pending removal; default 3,
+	// negative disables delisting.
+	DelistAfterCycles    int
 	Zips                 []string
 	PropertyTypes        []string
 	PageSize             int
@@ -29,6 +55,10 @@ type BulkConfig struct {
 	Baths                int
 	MinPrice             int
 	MaxPrice             int
+	// FetchHistory also fetches tax and sale history for each property
+	// whose stored history is older than HistoryMaxAge (default 30 days).
+	FetchHistory  bool
+	HistoryMaxAge time.Duration
 }
 
 type BulkJob struct {
@@ -37,6 +67,8 @@ type BulkJob struct {
 	Store    *store.Store
 	Logger   *log.Logger
 	Config   BulkConfig
+
+	lastRun sync.Map // zip -> time.Time, adaptive schedule only
 }
 
 func (j *BulkJob) logf(format string, args ...any) {
@@ -57,7 +89,7 @@ func (j *BulkJob) validate() error {
 	if j.Hydrator == nil || j.Hydrator.Store == nil {
 		return errors.New("hydrator bulk job requires hydrator with store")
 	}
-	if len(j.Config.Zips) == 0 {
+	if j.Config.Targets == TargetsStatic && len(j.Config.Zips) == 0 {
 		return errors.New("hydrator bulk job requires at least one zip")
 	}
 	if j.Config.Provider == "" {
@@ -66,8 +98,14 @@ func (j *BulkJob) validate() error {
 	if j.Config.Endpoint == "" {
 		j.Config.Endpoint = "search/forsale"
 	}
+	if j.Config.Name == "" {
+		j.Config.Name = "bulk"
+	}
 	if j.Store == nil {
-		j.Store = j.Hydrator.Store
+		j.Store = store.Postgres(j.Hydrator.Store)
+	}
+	if j.Store == nil {
+		return errors.New("hydrator bulk job requires the postgres store")
 	}
 	return nil
 }
@@ -80,9 +118,16 @@ func (j *BulkJob) Run(ctx context.Context) error {
 	if interval <= 0 {
 		return j.RunOnce(ctx)
 	}
+	if j.Config.Adaptive {
+		return j.runAdaptive(ctx)
+	}
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
-	j.logf("hydrator bulk job starting with interval %s (%d zip(s))", interval, len(j.Config.Zips))
+	if j.Config.Targets == TargetsStatic {
+		j.logf("hydrator bulk job starting with interval %s (%d zip(s))", interval, len(j.Config.Zips))
+	} else {
+		j.logf("hydrator bulk job starting with interval %s (%s targets)", interval, j.Config.Targets)
+	}
 	if err := j.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
 		j.logf("hydrator bulk job initial run error: %v", err)
 	}
@@ -102,36 +147,138 @@ func (j *BulkJob) Run(ctx context.Context) error {
 	}
 }
 
+// RunOnce ingests every configured ZIP once. Provider calls are tagged as
+// bulk so they yield the last of the daily quota to interactive traffic.
+// Progress is checkpointed per ZIP and page: a run cut short by shutdown or
+// quota exhaustion resumes after its last persisted page on the next call.
 func (j *BulkJob) RunOnce(ctx context.Context) error {
 	if err := j.validate(); err != nil {
 		return err
 	}
-	propTypes := j.Config.PropertyTypes
-	if len(propTypes) == 0 {
-		propTypes = []string{""}
+	zips, err := j.targetZips(ctx)
+	if err != nil {
+		return err
+	}
+	if len(zips) == 0 {
+		j.logf("hydrator bulk job has no target zips")
+		return nil
+	}
+	return j.runZips(ctx, zips)
+}
+
+func (j *BulkJob) propertyTypes() []string {
+	if len(j.Config.PropertyTypes) == 0 {
+		return []string{""}
+	}
+	return j.Config.PropertyTypes
+}
+
+// runZips ingests zips as one checkpointed run, Concurrency ZIPs at a time.
+// Workers share the client, so its rate limiter and daily budget bound the
+// combined request rate. Quota exhaustion stops every worker.
+func (j *BulkJob) runZips(ctx context.Context, zips []string) (err error) {
+	ctx = attom.WithPriority(ctx, attom.PriorityBulk)
+	ctx = attom.WithRequestOptions(ctx, attom.BulkRequests)
+	propTypes := j.propertyTypes()
+	prog := j.openProgress(ctx, len(zips)*len(propTypes))
+	defer func() { prog.finish(err) }()
+	ctx = store.WithActor(ctx, store.Actor{Name: "hydrator:" + j.Config.Name, RunID: prog.runID()})
+
+	workers := min(max(j.Config.Concurrency, 1), len(zips))
+	runCtx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	var (
+		mu     sync.Mutex
+		joined error
+		wg     sync.WaitGroup
+	)
+	next := make(chan string)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for zip := range next {
+				zerr := j.ingestTarget(runCtx, zip, propTypes, prog)
+				if zerr == nil {
+					continue
+				}
+				if errors.Is(zerr, attom.ErrDailyLimitExceeded) {
+					stop(zerr)
+					continue
+				}
+				if runCtx.Err() == nil {
+					mu.Lock()
+					joined = errors.Join(joined, zerr)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+feed:
+	for _, zip := range zips {
+		select {
+		case next <- zip:
+		case <-runCtx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
 	}
+	if cause := context.Cause(runCtx); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	return joined
+}
+
+// ingestTarget ingests every property type of one ZIP, skipping those a
+// resumed run already finished.
+func (j *BulkJob) ingestTarget(ctx context.Context, zip string, propTypes []string, prog *progress) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "hydrator.zip", trace.WithAttributes(
+		attribute.String("hydrator.job", j.Config.Name),
+		attribute.String("hydrator.zip", zip),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 	var joined error
-	for _, rawZip := range j.Config.Zips {
-		zip := strings.TrimSpace(rawZip)
-		if zip == "" {
+	for _, propType := range propTypes {
+		cp := prog.checkpoint(zip, propType)
+		if cp.Done {
 			continue
 		}
-		for _, propType := range propTypes {
-			if err := j.ingestZip(ctx, zip, propType); err != nil {
-				if ctx.Err() != nil {
-					return ctx.Err()
-				}
-				if errors.Is(err, attom.ErrDailyLimitExceeded) {
-					return err
-				}
-				joined = errors.Join(joined, err)
+		if err := j.ingestZip(ctx, &cp, prog); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, attom.ErrDailyLimitExceeded) {
+				return err
 			}
+			joined = errors.Join(joined, err)
+			continue
 		}
+		prog.zipDone(ctx, &cp)
+	}
+	if joined == nil {
+		j.markTargetRun(ctx, zip)
 	}
 	return joined
 }
 
-func (j *BulkJob) ingestZip(ctx context.Context, zip string, propertyType string) error {
+// maxThrottleRetries bounds consecutive retries of one page after provider
+// throttling.
+const maxThrottleRetries = 3
+
+// ingestZip pages through one ZIP and property type starting after
+// cp.LastPage, checkpointing each persisted page.
+func (j *BulkJob) ingestZip(ctx context.Context, cp *store.HydratorCheckpoint, prog *progress) error {
+	zip, propertyType := cp.Zip, cp.PropertyType
 	pageSize := j.Config.PageSize
 	if pageSize <= 0 {
 		pageSize = 50
@@ -145,11 +292,18 @@ func (j *BulkJob) ingestZip(ctx context.Context, zip string, propertyType string
 		timeout = 10 * time.Second
 	}
 	pause := j.Config.PauseBetweenRequests
+	crawlStart := time.Now()
 	fetched := 0
-	for page := 1; page <= maxPages; page++ {
+	throttled := 0
+	// exhaustive is set when the provider ran out of results before
+	// maxPages and every card was written: only then does a listing's absence
+	// mean anything.
+	exhaustive, clean := false, true
+	for page := cp.LastPage + 1; page <= maxPages; page++ {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
+		prog.request()
 		reqCtx, cancel := context.WithTimeout(ctx, timeout)
 		raw, err := j.Client.SearchListingsByPostal(reqCtx, zip, pageSize, page, j.Config.Beds, j.Config.Baths, j.Config.MinPrice, j.Config.MaxPrice, propertyType, j.Config.OrderBy)
 		cancel()
@@ -157,8 +311,21 @@ func (j *BulkJob) ingestZip(ctx context.Context, zip string, propertyType string
 			if errors.Is(err, attom.ErrDailyLimitExceeded) {
 				return err
 			}
+			// Short provider throttles: wait as asked and retry the page.
+			if wait, ok := attom.RetryAfter(err); ok && throttled < maxThrottleRetries {
+				throttled++
+				j.logf("hydrator bulk job zip %s page %d throttled; retrying in %s", zip, page, wait)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+				page--
+				continue
+			}
 			return fmt.Errorf("zip %s page %d fetch: %w", zip, page, err)
 		}
+		throttled = 0
 		cards, err := attom.MapListingPayloadToCards(raw)
 		if err != nil {
 			return fmt.Errorf("zip %s page %d map: %w", zip, page, err)
@@ -167,22 +334,28 @@ func (j *BulkJob) ingestZip(ctx context.Context, zip string, propertyType string
 			if page == 1 {
 				j.logf("hydrator bulk job zip %s returned 0 listings", zip)
 			}
+			exhaustive = true
 			break
 		}
+		persisted := 0
 		for _, card := range cards {
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
-			if err := j.persistCard(ctx, raw, card); err != nil {
+			if err := j.persistCard(ctx, raw, card, prog); err != nil {
 				if errors.Is(err, attom.ErrDailyLimitExceeded) {
 					return err
 				}
 				j.logf("hydrator bulk job zip %s listing %s error: %v", zip, card.ID, err)
+				clean = false
 				continue
 			}
-			fetched++
+			persisted++
 		}
+		fetched += persisted
+		prog.pageDone(ctx, cp, page, persisted)
 		if len(cards) < pageSize {
+			exhaustive = true
 			break
 		}
 		if pause > 0 {
@@ -200,10 +373,13 @@ func (j *BulkJob) ingestZip(ctx context.Context, zip string, propertyType string
 			j.logf("hydrator bulk job zip %s persisted %d listings", zip, fetched)
 		}
 	}
+	if exhaustive && clean && j.unfiltered(propertyType) {
+		j.detectDelistings(ctx, zip, prog.seenSince(crawlStart))
+	}
 	return nil
 }
 
-func (j *BulkJob) persistCard(ctx context.Context, raw []byte, card attom.PropertyCard) error {
+func (j *BulkJob) persistCard(ctx context.Context, raw []byte, card attom.PropertyCard, prog *progress) error {
 	if card.Address == "" || card.City == "" || card.State == "" || card.Zip == "" {
 		return errors.New("incomplete address data")
 	}
@@ -221,9 +397,18 @@ func (j *BulkJob) persistCard(ctx context.Context, raw []byte, card attom.Proper
 	if err := j.Hydrator.Write(ctx, j.Config.Provider, j.Config.Endpoint, raw, norm, card); err != nil {
 		return err
 	}
+	if err := j.persistHistory(ctx, pk, card, prog); err != nil {
+		return err
+	}
 	if !j.Config.FetchPhotos || j.Store == nil {
 		return nil
 	}
+	// Photos the payload carried were written with the listing; the full
+	// set is fetched when the listing's detail or photos are first asked
+	// for.
+	if len(card.Photos) > 0 {
+		return nil
+	}
 	listingID := card.ListingID
 	if listingID == "" {
 		listingID = card.ID
@@ -235,10 +420,15 @@ func (j *BulkJob) persistCard(ctx context.Context, raw []byte, card attom.Proper
 	if targetID == "" {
 		targetID = card.ID
 	}
+	prog.request()
 	reqCtx, cancel := context.WithTimeout(ctx, j.Config.RequestTimeout)
 	assets, err := j.Client.GetPhotos(reqCtx, targetID)
 	cancel()
 	if err != nil {
+		// An exhausted photos budget only skips photos; listings continue.
+		if class, ok := attom.BudgetClass(err); ok && class == attom.ClassPhotos {
+			return nil
+		}
 		if errors.Is(err, attom.ErrDailyLimitExceeded) {
 			return err
 		}
@@ -264,13 +454,51 @@ func (j *BulkJob) persistCard(ctx context.Context, raw []byte, card attom.Proper
 			MediaType:   mediaType,
 			Tags:        append([]string(nil), asset.Tags...),
 			Position:    idx,
+			Room:        photoclass.FromTags(asset.Tags, asset.Title),
 		})
 	}
 	if len(inputs) == 0 {
 		return nil
 	}
-	if err := j.Store.ReplaceListingPhotos(ctx, listingID, inputs); err != nil {
+	if err := j.Hydrator.SyncPhotos(ctx, listingID, inputs); err != nil {
 		return fmt.Errorf("persist photos: %w", err)
 	}
 	return nil
 }
+
+// persistHistory stores the tax and sale history of a card's property when
+// FetchHistory is on and the stored history is missing or stale.
+func (j *BulkJob) persistHistory(ctx context.Context, propertyKey string, card attom.PropertyCard, prog *progress) error {
+	if !j.Config.FetchHistory || j.Store == nil || card.PropertyID == "" {
+		return nil
+	}
+	maxAge := j.Config.HistoryMaxAge
+	if maxAge <= 0 {
+		maxAge = 30 * 24 * time.Hour
+	}
+	if at, err := j.Store.HistoryFetchedAt(ctx, propertyKey); err != nil {
+		return fmt.Errorf("history lookup: %w", err)
+	} else if at.Valid && time.Since(at.Time) < maxAge {
+		return nil
+	}
+	prog.request()
+	reqCtx, cancel := context.WithTimeout(ctx, j.Config.RequestTimeout)
+	_, history, err := j.Client.GetPropertyHistory(reqCtx, card.PropertyID)
+	cancel()
+	if err != nil {
+		// An exhausted detail budget only skips history; listings continue.
+		if class, ok := attom.BudgetClass(err); ok && class == attom.ClassDetail {
+			return nil
+		}
+		if errors.Is(err, attom.ErrDailyLimitExceeded) {
+			return err
+		}
+		return fmt.Errorf("history fetch: %w", err)
+	}
+	taxes, transfers := toStoreHistory(history)
+	parcel := store.Parcel{APN: history.APN, FIPS: history.FIPS}
+	if err := j.Store.SavePropertyHistory(ctx, propertyKey, j.Config.Provider, parcel, taxes, transfers); err != nil {
+		return fmt.Errorf("persist history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package hydrator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// progress tracks a bulk run's per-ZIP checkpoints and summary counters. A
+// nil progress records nothing, so the job still runs when the checkpoint
+// tables are unreachable. It is shared by concurrent ZIP workers.
+type progress struct {
+	job *BulkJob
+
+	mu  sync.Mutex
+	run store.HydratorRun
+	cps map[string]store.HydratorCheckpoint
+}
+
+// openProgress resumes the job's unfinished run or starts a new one.
+// zipsTotal counts ZIP and property type pairs.
+func (j *BulkJob) openProgress(ctx context.Context, zipsTotal int) *progress {
+	run, resumed, err := j.Store.OpenHydratorRun(ctx, j.Config.Name, zipsTotal)
+	if err != nil {
+		j.logf("hydrator bulk job checkpoints unavailable: %v", err)
+		return nil
+	}
+	cps, err := j.Store.HydratorCheckpoints(ctx, run.ID)
+	if err != nil {
+		j.logf("hydrator bulk job checkpoints unavailable: %v", err)
+		return nil
+	}
+	if resumed {
+		j.logf("hydrator bulk job resuming run %s (%d/%d zip(s) done)", run.ID, run.ZipsDone, zipsTotal)
+	}
+	return &progress{job: j, run: run, cps: cps}
+}
+
+// checkpoint returns the saved progress for zip and propertyType, or a fresh
+// one starting at page 1.
+func (p *progress) checkpoint(zip, propertyType string) store.HydratorCheckpoint {
+	if p == nil {
+		return store.HydratorCheckpoint{Zip: zip, PropertyType: propertyType}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cp, ok := p.cps[store.CheckpointKey(zip, propertyType)]; ok {
+		return cp
+	}
+	return store.HydratorCheckpoint{RunID: p.run.ID, Zip: zip, PropertyType: propertyType}
+}
+
+// seenSince is when the run started, so listings fetched by earlier pages of
+// a resumed run still count as seen; fallback is used without a run.
+func (p *progress) seenSince(fallback time.Time) time.Time {
+	if p == nil || p.run.StartedAt.IsZero() {
+		return fallback
+	}
+	return p.run.StartedAt
+}
+
+// runID is the run's ID for the audit log, or empty without a run.
+func (p *progress) runID() string {
+	if p == nil {
+		return ""
+	}
+	return p.run.ID
+}
+
+// request counts one provider call against the run.
+func (p *progress) request() {
+	if p != nil {
+		p.mu.Lock()
+		p.run.Requests++
+		p.mu.Unlock()
+	}
+}
+
+// pageDone records that page was fully persisted with n listings.
+func (p *progress) pageDone(ctx context.Context, cp *store.HydratorCheckpoint, page, n int) {
+	cp.LastPage = page
+	cp.Listings += n
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.run.ListingsPersisted += n
+	p.mu.Unlock()
+	p.save(ctx, *cp)
+}
+
+// zipDone marks cp finished so a resumed run skips it.
+func (p *progress) zipDone(ctx context.Context, cp *store.HydratorCheckpoint) {
+	cp.Done = true
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.run.ZipsDone++
+	p.mu.Unlock()
+	p.save(ctx, *cp)
+}
+
+func (p *progress) save(ctx context.Context, cp store.HydratorCheckpoint) {
+	p.mu.Lock()
+	p.cps[store.CheckpointKey(cp.Zip, cp.PropertyType)] = cp
+	p.mu.Unlock()
+	if err := p.job.Store.SaveHydratorCheckpoint(ctx, cp); err != nil {
+		p.job.logf("hydrator bulk job checkpoint zip %s page %d: %v", cp.Zip, cp.LastPage, err)
+	}
+}
+
+// finish writes the run summary. It uses its own context because ctx may
+// already be cancelled by shutdown.
+func (p *progress) finish(err error) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case err == nil:
+		p.run.Status = store.RunCompleted
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		p.run.Status = store.RunInterrupted
+	case errors.Is(err, attom.ErrDailyLimitExceeded):
+		p.run.Status = store.RunQuotaExhausted
+	default:
+		p.run.Status = store.RunFailed
+	}
+	p.run.LastError = ""
+	if err != nil {
+		p.run.LastError = err.Error()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if ferr := p.job.Store.FinishHydratorRun(ctx, p.run); ferr != nil {
+		p.job.logf("hydrator bulk job run summary: %v", ferr)
+		return
+	}
+	p.job.logf("hydrator bulk job run %s %s: %d/%d zip(s), %d listings, %d provider request(s)",
+		p.run.ID, p.run.Status, p.run.ZipsDone, p.run.ZipsTotal, p.run.ListingsPersisted, p.run.Requests)
+}
@@ -0,0 +1,87 @@
+package hydrator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/events"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// unfiltered reports whether a crawl of propertyType sees every active
+// listing in a ZIP. Filtered crawls cannot tell a delisting from a listing
+// the filter excluded.
+func (j *BulkJob) unfiltered(propertyType string) bool {
+	c := j.Config
+	return propertyType == "" && c.Beds == 0 && c.Baths == 0 && c.MinPrice == 0 && c.MaxPrice == 0
+}
+
+// detectDelistings runs after a complete crawl of zip: active listings the
+// crawl did not fetch miss a cycle, and those past DelistAfterCycles are
+// marked pending removal and announced as status changes. The for-sale
+// search never returns sold listings, so each one's detail is then fetched
+// to record the status it left the market with, such as sold.
+func (j *BulkJob) detectDelistings(ctx context.Context, zip string, seenSince time.Time) {
+	after := j.Config.DelistAfterCycles
+	if after < 0 {
+		return
+	}
+	if after == 0 {
+		after = 3
+	}
+	gone, err := j.Store.MarkUnseenListings(ctx, zip, j.Config.Provider, seenSince, after)
+	if err != nil {
+		j.logf("hydrator bulk job zip %s delisting check: %v", zip, err)
+		return
+	}
+	if len(gone) == 0 {
+		return
+	}
+	j.logf("hydrator bulk job zip %s: %d listing(s) pending removal", zip, len(gone))
+	for _, l := range gone {
+		j.Hydrator.Publish(ctx, events.ListingStatusChanged{
+			ListingID:   l.ListingID,
+			PropertyID:  l.PropertyID,
+			PropertyKey: l.PropertyKey,
+			OldStatus:   "for_sale",
+			NewStatus:   store.StatusPendingRemoval,
+		})
+		j.Hydrator.Publish(ctx, events.PropertyUpdated{PropertyID: l.PropertyID, PropertyKey: l.PropertyKey})
+	}
+	for _, l := range gone {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := j.settleDelisting(ctx, l); err != nil {
+			j.logf("hydrator bulk job zip %s: final status of listing %s: %v", zip, l.SourceID, err)
+		}
+	}
+}
+
+// settleDelisting fetches a listing pending removal from the provider and,
+// when it reports the listing off the market, stores that status in place
+// of pending removal.
+func (j *BulkJob) settleDelisting(ctx context.Context, l store.DelistedListing) error {
+	if l.SourceID == "" {
+		return nil
+	}
+	timeout := j.Config.RequestTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	raw, card, found, err := j.Client.GetListingDetail(reqCtx, l.SourceID)
+	cancel()
+	if err != nil || !found || card.Status == "" || card.Status == "for_sale" {
+		return err
+	}
+	line1, city, st, zip, pk := canon.Canonicalize(card.Address, card.City, card.State, card.Zip)
+	if pk == "" {
+		return errors.New("empty property key")
+	}
+	norm := map[string]string{"line1": line1, "city": city, "state": st, "zip": zip, "property_key": pk}
+	return j.Hydrator.Write(ctx, j.Config.Provider, "property", raw, norm, card)
+}
+
@@ -0,0 +1,76 @@
+package hydrator
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// DemandRecorder counts API lookups per ZIP in memory and flushes them to
+// zip_demand, where the adaptive scheduler reads them.
+type DemandRecorder struct {
+	Store *store.Store
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Hit counts one lookup for zip.
+func (r *DemandRecorder) Hit(zip string) {
+	if r == nil || zip == "" {
+		return
+	}
+	r.mu.Lock()
+	if r.counts == nil {
+		r.counts = make(map[string]int)
+	}
+	r.counts[zip]++
+	r.mu.Unlock()
+}
+
+// Flush writes pending counts. Counts that fail to write are dropped; demand
+// is a scheduling hint, not an audit log.
+func (r *DemandRecorder) Flush(ctx context.Context) {
+	if r == nil || r.Store == nil {
+		return
+	}
+	r.mu.Lock()
+	counts := r.counts
+	r.counts = nil
+	r.mu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+	if err := r.Store.RecordZipDemand(ctx, time.Now(), counts); err != nil {
+		log.Printf("[WARN] zip demand flush (%d zip(s)) failed: %v", len(counts), err)
+	}
+}
+
+// Run flushes every interval until ctx is done.
+func (r *DemandRecorder) Run(ctx context.Context, every time.Duration) {
+	if every <= 0 {
+		every = time.Minute
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			r.Flush(fctx)
+			cancel()
+		}
+	}
+}
+
+// NoteDemand records an API lookup of zip when demand tracking is enabled.
+func (h *Hydrator) NoteDemand(zip string) {
+	if h != nil {
+		h.Demand.Hit(zip)
+	}
+}
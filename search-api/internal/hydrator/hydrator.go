@@ -3,15 +3,23 @@ package hydrator
 import (
 	"context"
 	"database/sql"
+	"log"
+	"time"
 
 	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/county"
 	"github.com/yourorg/search-api/internal/events"
+	"github.com/yourorg/search-api/internal/photoclass"
+	"github.com/yourorg/search-api/internal/proptype"
 	"github.com/yourorg/search-api/internal/store"
 )
 
 type Hydrator struct {
-	Store *store.Store
+	Store store.Listings
 	Pub   events.Publisher
+	// Demand counts ZIP lookups for the adaptive bulk scheduler; optional.
+	Demand *DemandRecorder
 }
 
 func (h *Hydrator) Enabled() bool { return h != nil && h.Store != nil }
@@ -20,36 +28,139 @@ func (h *Hydrator) Write(ctx context.Context, provider string, endpoint string,
 	if !h.Enabled() {
 		return nil
 	}
-	in := store.UpsertInput{
-		PropertyKey: norm["property_key"],
-		Address1:    norm["line1"],
-		City:        norm["city"],
-		State:       norm["state"],
-		Zip:         norm["zip"],
-		Lat:         sqlNullFloat(card.Coords[1]),
-		Lon:         sqlNullFloat(card.Coords[0]),
-		Provider:    provider,
-		SourceID:    card.ID,
-		ListingID:   sqlNullString(card.ID),
-		Status:      "for_sale",
-		ListPrice:   sqlNullFloat64(float64(card.Price)),
-		Beds:        sqlNullInt(int64(card.Beds)),
-		Baths:       sqlNullFloat64(float64(card.Baths)),
-		Sqft:        sqlNullInt(int64(card.Sqft)),
-		Endpoint:    endpoint,
-		ExternalID:  card.ID,
-		PayloadJSON: raw,
-	}
+	return h.write(ctx, upsertInput(provider, endpoint, raw, norm, card))
+}
+
+func (h *Hydrator) write(ctx context.Context, in store.UpsertInput) error {
 	res, err := h.Store.WriteSnapshotAndUpsert(ctx, in)
 	if err != nil {
 		return err
 	}
-	if h.Pub != nil {
-		h.Pub.PublishPropertyUpdated(ctx, events.PropertyUpdated{PropertyID: res.PropertyID, PropertyKey: norm["property_key"]})
-	}
+	h.publishUpsert(ctx, in.PropertyKey, in, res)
 	return nil
 }
 
+func upsertInput(provider string, endpoint string, raw []byte, norm map[string]string, card attom.PropertyCard) store.UpsertInput {
+	return store.UpsertInput{
+		PropertyKey:  norm["property_key"],
+		Address1:     norm["line1"],
+		City:         norm["city"],
+		State:        norm["state"],
+		Zip:          norm["zip"],
+		AddressKey:   addressKey(norm, card),
+		Lat:          sqlNullFloat(card.Coords[1]),
+		Lon:          sqlNullFloat(card.Coords[0]),
+		Parcel:       store.Parcel{APN: card.APN, FIPS: card.FIPS},
+		County:       propertyCounty(norm, card),
+		Provider:     provider,
+		SourceID:     card.ID,
+		ListingID:    sqlNullString(card.ID),
+		Status:       attom.ListingStatus(card.Status),
+		ListPrice:    sqlNullFloat64(float64(card.Price)),
+		Beds:         sqlNullInt(int64(card.Beds)),
+		Baths:        sqlNullFloat64(float64(card.Baths)),
+		Sqft:         sqlNullInt(int64(card.Sqft)),
+		ListDate:     sqlNullTime(card.ListDate),
+		PropertyType: proptype.ForStorage(card.Type),
+		Agents:       toStoreAgents(card.Agents),
+		OpenHouses:   toStoreOpenHouses(card.OpenHouses),
+		Description:  card.Description,
+		Features:     toStoreFeatures(card),
+		Photos:       inlinePhotos(card.Photos),
+		InlinePhotos: true,
+		Endpoint:     endpoint,
+		ExternalID:   card.ID,
+		PayloadJSON:  raw,
+	}
+}
+
+// RecordDrift persists a provider payload drift report. It matches the
+// attom.WithDriftHook signature and writes in the background so the provider
+// call that detected the drift is not slowed down.
+func (h *Hydrator) RecordDrift(r attom.DriftReport) {
+	if !h.Enabled() {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := h.Store.SaveDriftReport(ctx, store.DriftReport{
+			Provider:      "rapidapi.realtor16",
+			Endpoint:      r.Endpoint,
+			Signature:     r.Signature(),
+			UnknownFields: r.Unknown,
+			MissingFields: r.Missing,
+		})
+		if err != nil {
+			log.Printf("[WARN] drift report write failed for %s: %v", r.Endpoint, err)
+		}
+	}()
+}
+
+func toStoreAgents(agents []attom.Agent) []store.ListingAgent {
+	if len(agents) == 0 {
+		return nil
+	}
+	out := make([]store.ListingAgent, 0, len(agents))
+	for _, a := range agents {
+		out = append(out, store.ListingAgent{
+			SourceID:   a.ID,
+			Role:       a.Role,
+			Name:       a.Name,
+			Email:      a.Email,
+			Phone:      a.Phone,
+			OfficeID:   a.OfficeID,
+			OfficeName: a.OfficeName,
+			BrokerName: a.BrokerName,
+		})
+	}
+	return out
+}
+
+// inlinePhotos are the photos a search payload carried, the first being the
+// listing's primary photo. They let a listing show photos before, or
+// without, a photos call.
+func inlinePhotos(photos []attom.PhotoAsset) []store.ListingPhotoInput {
+	out := make([]store.ListingPhotoInput, 0, len(photos))
+	for i, ph := range photos {
+		out = append(out, store.ListingPhotoInput{
+			Href:     ph.Href,
+			Tags:     ph.Tags,
+			Position: i,
+			Room:     photoclass.FromTags(ph.Tags, ""),
+			Primary:  i == 0,
+		})
+	}
+	return out
+}
+
+func toStoreFeatures(card attom.PropertyCard) store.ListingFeatures {
+	return store.ListingFeatures{
+		HOAFee:          card.HOAFee,
+		GarageSpaces:    card.GarageSpaces,
+		Stories:         card.Stories,
+		Pool:            card.Pool,
+		Basement:        card.Basement,
+		NewConstruction: card.NewConstruction,
+		Foreclosure:     card.Foreclosure,
+		PriceReduced:    card.PriceReduced,
+	}
+}
+
+// toStoreOpenHouses returns nil for a card whose payload had no open_houses
+// field, so the store keeps the schedule it has. Otherwise the slice is
+// non-nil and the store clears open houses the provider dropped.
+func toStoreOpenHouses(events []attom.OpenHouse) []store.OpenHouse {
+	if events == nil {
+		return nil
+	}
+	out := make([]store.OpenHouse, 0, len(events))
+	for _, ev := range events {
+		out = append(out, store.OpenHouse{Start: ev.Start, End: ev.End, Description: ev.Description})
+	}
+	return out
+}
+
 func sqlNullFloat(v float64) sql.NullFloat64 {
 	if v == 0 {
 		return sql.NullFloat64{}
@@ -63,9 +174,58 @@ func sqlNullInt(v int64) sql.NullInt64 {
 	}
 	return sql.NullInt64{Int64: v, Valid: true}
 }
+func sqlNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
 func sqlNullString(s string) sql.NullString {
 	if s == "" {
 		return sql.NullString{}
 	}
 	return sql.NullString{String: s, Valid: true}
 }
+
+func toStoreHistory(h attom.PropertyHistory) ([]store.TaxAssessment, []store.PropertyTransfer) {
+	taxes := make([]store.TaxAssessment, 0, len(h.Assessments))
+	for _, a := range h.Assessments {
+		taxes = append(taxes, store.TaxAssessment{
+			Year:             a.Year,
+			Tax:              sqlNullFloat(a.Tax),
+			AssessedLand:     sqlNullFloat(a.AssessedLand),
+			AssessedBuilding: sqlNullFloat(a.AssessedBuilding),
+			AssessedTotal:    sqlNullFloat(a.AssessedTotal),
+			MarketTotal:      sqlNullFloat(a.MarketTotal),
+		})
+	}
+	transfers := make([]store.PropertyTransfer, 0, len(h.Transfers))
+	for _, t := range h.Transfers {
+		transfers = append(transfers, store.PropertyTransfer{
+			Date:   t.Date,
+			Price:  sqlNullFloat(float64(t.Price)),
+			Event:  t.Event,
+			Source: sqlNullString(t.Source),
+		})
+	}
+	return taxes, transfers
+}
+
+// addressKey is the address the write was asked for, when the caller gave
+// one, or else the provider's.
+func addressKey(norm map[string]string, card attom.PropertyCard) string {
+	if k := norm["address_key"]; k != "" {
+		return k
+	}
+	return canon.AddressKey(card.Address, card.City, card.State, card.Zip)
+}
+
+// propertyCounty is the card's county, or the county of the ZIP the write
+// was asked for when the card has none.
+func propertyCounty(norm map[string]string, card attom.PropertyCard) county.County {
+	if card.CountyFIPS != "" {
+		return county.County{FIPS: card.CountyFIPS, Name: card.County, State: card.State}
+	}
+	c, _ := county.ForZip(norm["zip"])
+	return c
+}
@@ -0,0 +1,90 @@
+package hydrator
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yourorg/search-api/internal/photoclass"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// PhotoClassifyJob sends stored photos whose provider tags named no room to
+// an external classifier and records the room it returns.
+type PhotoClassifyJob struct {
+	Store      *store.Store
+	Classifier *photoclass.Classifier
+	Logger     *log.Logger
+	// BatchSize is how many photos one pass classifies; default 200.
+	BatchSize int
+	// Concurrency is how many classifier calls run at once; default 4.
+	Concurrency int
+	Interval    time.Duration
+}
+
+func (j *PhotoClassifyJob) logf(format string, args ...any) {
+	if j.Logger != nil {
+		j.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (j *PhotoClassifyJob) batchSize() int {
+	if j.BatchSize <= 0 {
+		return 200
+	}
+	return j.BatchSize
+}
+
+// RunOnce classifies one batch and reports how many photos it attempted.
+func (j *PhotoClassifyJob) RunOnce(ctx context.Context) (int, error) {
+	if j == nil || j.Store == nil || j.Classifier == nil {
+		return 0, errors.New("photo classification requires store and classifier")
+	}
+	start := time.Now()
+	photos, err := j.Store.PhotosToClassify(ctx, j.batchSize())
+	if err != nil {
+		return 0, err
+	}
+	labelled := eachPhoto(ctx, photos, j.Concurrency, func(p store.PhotoRef) bool {
+		room, err := j.Classifier.Classify(ctx, p.Href)
+		if ctx.Err() != nil {
+			return false
+		}
+		if err != nil {
+			// Recorded as unlabelled, so the photo is retried in a week.
+			j.logf("[WARN] photo classify %s: %v", p.Href, err)
+		}
+		if err := j.Store.SavePhotoRoom(ctx, p.ID, room); err != nil {
+			j.logf("[WARN] photo classify save %s: %v", p.ID, err)
+			return false
+		}
+		return room != ""
+	})
+	if len(photos) > 0 {
+		j.logf("photo classify labelled %d of %d photo(s) in %s", labelled, len(photos), time.Since(start).Round(time.Millisecond))
+	}
+	return len(photos), ctx.Err()
+}
+
+// Run classifies a batch every Interval (default 10m).
+func (j *PhotoClassifyJob) Run(ctx context.Context) error {
+	every := j.Interval
+	if every <= 0 {
+		every = 10 * time.Minute
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			j.logf("photo classify error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
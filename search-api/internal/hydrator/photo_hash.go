@@ -0,0 +1,159 @@
+package hydrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yourorg/search-api/internal/photohash"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// maxPhotoBytes caps a photo download; larger files are treated as failures.
+const maxPhotoBytes = 20 << 20
+
+// PhotoHashJob downloads stored photos that have no perceptual hash yet and
+// records one, so listing reads can drop the same image served at several
+// URLs and reuse across listings can be found.
+type PhotoHashJob struct {
+	Store  *store.Store
+	Client *http.Client
+	Logger *log.Logger
+	// BatchSize is how many photos one pass hashes; default 200.
+	BatchSize int
+	// Concurrency is how many photos download at once; default 4.
+	Concurrency int
+	Interval    time.Duration
+}
+
+func (j *PhotoHashJob) logf(format string, args ...any) {
+	if j.Logger != nil {
+		j.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (j *PhotoHashJob) batchSize() int {
+	if j.BatchSize <= 0 {
+		return 200
+	}
+	return j.BatchSize
+}
+
+// RunOnce hashes one batch and reports how many photos it attempted.
+func (j *PhotoHashJob) RunOnce(ctx context.Context) (int, error) {
+	if j == nil || j.Store == nil {
+		return 0, errors.New("photo hashing requires store")
+	}
+	start := time.Now()
+	photos, err := j.Store.PhotosToHash(ctx, j.batchSize())
+	if err != nil {
+		return 0, err
+	}
+	hashed := eachPhoto(ctx, photos, j.Concurrency, func(p store.PhotoRef) bool {
+		hash, hashErr := j.hash(ctx, p.Href)
+		if ctx.Err() != nil {
+			return false
+		}
+		if hashErr != nil {
+			j.logf("[WARN] photo hash %s: %v", p.Href, hashErr)
+		}
+		if err := j.Store.SavePhotoHash(ctx, p.ID, hash, hashErr == nil); err != nil {
+			j.logf("[WARN] photo hash save %s: %v", p.ID, err)
+			return false
+		}
+		return hashErr == nil
+	})
+	if len(photos) > 0 {
+		j.logf("photo hash stored %d of %d hash(es) in %s", hashed, len(photos), time.Since(start).Round(time.Millisecond))
+	}
+	return len(photos), ctx.Err()
+}
+
+func (j *PhotoHashJob) hash(ctx context.Context, href string) (uint64, error) {
+	client := j.Client
+	if client == nil {
+		client = &http.Client{Timeout: 20 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, href, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return photohash.Compute(io.LimitReader(resp.Body, maxPhotoBytes))
+}
+
+// Run hashes a batch every Interval (default 10m), and straight away again
+// while passes find a full batch so a backlog drains quickly.
+func (j *PhotoHashJob) Run(ctx context.Context) error {
+	every := j.Interval
+	if every <= 0 {
+		every = 10 * time.Minute
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		n, err := j.RunOnce(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			j.logf("photo hash error: %v", err)
+		}
+		if err == nil && n >= j.batchSize() {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// eachPhoto runs fn over photos on up to workers goroutines (default 4) and
+// counts the calls that returned true. It stops handing out photos once ctx
+// is done.
+func eachPhoto(ctx context.Context, photos []store.PhotoRef, workers int, fn func(store.PhotoRef) bool) int {
+	if workers <= 0 {
+		workers = 4
+	}
+	var (
+		mu sync.Mutex
+		n  int
+		wg sync.WaitGroup
+	)
+	work := make(chan store.PhotoRef)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range work {
+				if fn(p) {
+					mu.Lock()
+					n++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, p := range photos {
+		select {
+		case work <- p:
+		case <-ctx.Done():
+		}
+	}
+	close(work)
+	wg.Wait()
+	return n
+}
@@ -0,0 +1,78 @@
+package hydrator
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yourorg/search-api/internal/events"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// Publish sends p in a new envelope when a publisher is configured.
+func (h *Hydrator) Publish(ctx context.Context, p events.Payload) {
+	if h != nil && h.Pub != nil {
+		h.Pub.Publish(ctx, events.New(p))
+	}
+}
+
+// publishUpsert announces a listing write: always a property update, plus
+// price, status and photo set changes of a listing that already existed.
+func (h *Hydrator) publishUpsert(ctx context.Context, propertyKey string, in store.UpsertInput, res store.UpsertResult) {
+	h.Publish(ctx, events.PropertyUpdated{PropertyID: res.PropertyID, PropertyKey: propertyKey})
+	if res.Inserted {
+		return
+	}
+	if res.PrevPrice != in.ListPrice {
+		h.Publish(ctx, events.ListingPriceChanged{
+			ListingID:   res.ListingID,
+			PropertyID:  res.PropertyID,
+			PropertyKey: propertyKey,
+			OldPrice:    floatPtr(res.PrevPrice),
+			NewPrice:    floatPtr(in.ListPrice),
+		})
+	}
+	if res.PrevStatus != in.Status {
+		h.Publish(ctx, events.ListingStatusChanged{
+			ListingID:   res.ListingID,
+			PropertyID:  res.PropertyID,
+			PropertyKey: propertyKey,
+			OldStatus:   res.PrevStatus,
+			NewStatus:   in.Status,
+		})
+	}
+	h.publishPhotos(ctx, res.Photos)
+}
+
+func (h *Hydrator) publishPhotos(ctx context.Context, d store.PhotoDiff) {
+	if !d.Changed() {
+		return
+	}
+	h.Publish(ctx, events.PhotoSetChanged{
+		ListingID:   d.ListingID,
+		PropertyID:  d.PropertyID,
+		PropertyKey: d.PropertyKey,
+		Added:       d.Added,
+		Removed:     d.Removed,
+		Count:       d.Count,
+	})
+}
+
+// SyncPhotos persists a listing's photos and announces a changed set.
+func (h *Hydrator) SyncPhotos(ctx context.Context, providerListingID string, photos []store.ListingPhotoInput) error {
+	if !h.Enabled() {
+		return nil
+	}
+	diff, err := h.Store.SyncListingPhotos(ctx, providerListingID, photos)
+	if err != nil {
+		return err
+	}
+	h.publishPhotos(ctx, diff)
+	return nil
+}
+
+func floatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
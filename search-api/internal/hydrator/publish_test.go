@@ -0,0 +1,54 @@
+package hydrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/events"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// statusChanges drains the events buffered on sub and returns the listing
+// status changes among them.
+func statusChanges(sub <-chan events.Event) []events.ListingStatusChanged {
+	var out []events.ListingStatusChanged
+	for {
+		select {
+		case evt := <-sub:
+			if c, ok := evt.Payload.(events.ListingStatusChanged); ok {
+				out = append(out, c)
+			}
+		default:
+			return out
+		}
+	}
+}
+
+func TestWritePublishesProviderStatusChange(t *testing.T) {
+	pub := events.NewInMemory(16)
+	sub, unsubscribe := pub.Subscribe("test")
+	defer unsubscribe()
+	h := &Hydrator{Store: store.NewMemory(0), Pub: pub}
+	norm := map[string]string{"line1": "123 SANDBOX ST", "city": "SAN FRANCISCO", "state": "CA", "zip": "94110", "property_key": "123 sandbox st|san francisco|ca|94110"}
+	card := attom.PropertyCard{ID: "2960000001", ListingID: "2960000001", Address: "123 Sandbox St", City: "San Francisco", State: "CA", Zip: "94110", Price: 875000, Status: "for_sale"}
+	ctx := context.Background()
+
+	for range 2 {
+		if err := h.Write(ctx, "rapidapi.realtor16", "search/forsale", nil, norm, card); err != nil {
+			t.Fatalf("for_sale write: %v", err)
+		}
+	}
+	if got := statusChanges(sub); len(got) != 0 {
+		t.Fatalf("status changes before the sale = %+v, want none", got)
+	}
+
+	card.Status = "sold"
+	if err := h.Write(ctx, "rapidapi.realtor16", "property", nil, norm, card); err != nil {
+		t.Fatalf("sold write: %v", err)
+	}
+	got := statusChanges(sub)
+	if len(got) != 1 || got[0].OldStatus != "for_sale" || got[0].NewStatus != "sold" {
+		t.Fatalf("status changes = %+v, want one for_sale -> sold", got)
+	}
+}
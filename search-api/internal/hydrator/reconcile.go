@@ -0,0 +1,71 @@
+package hydrator
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// ReconcileJob periodically links listings of the same property reported by
+// different providers, picking a canonical listing per status.
+type ReconcileJob struct {
+	Store  *store.Store
+	Logger *log.Logger
+	// ProviderOrder ranks providers when picking the canonical listing;
+	// unlisted providers rank last.
+	ProviderOrder []string
+	Interval      time.Duration
+
+	last time.Time
+}
+
+func (j *ReconcileJob) logf(format string, args ...any) {
+	if j.Logger != nil {
+		j.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// RunOnce reconciles properties with listings updated since the previous
+// pass; the first pass covers every property.
+func (j *ReconcileJob) RunOnce(ctx context.Context) error {
+	if j == nil || j.Store == nil {
+		return errors.New("listing reconcile requires store")
+	}
+	start := time.Now()
+	since := j.last
+	if !since.IsZero() {
+		// Overlap passes so listings written mid-pass are not missed.
+		since = since.Add(-time.Minute)
+	}
+	n, err := j.Store.ReconcileListings(ctx, j.ProviderOrder, since)
+	if err != nil {
+		return err
+	}
+	j.last = start
+	j.logf("listing reconcile relinked %d listing(s) in %s", n, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (j *ReconcileJob) Run(ctx context.Context) error {
+	every := j.Interval
+	if every <= 0 {
+		every = time.Hour
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if err := j.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			j.logf("listing reconcile error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
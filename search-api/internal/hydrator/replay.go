@@ -0,0 +1,49 @@
+package hydrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// ErrReplayUnsupported is returned for snapshots of endpoints Replay cannot
+// map back into cards.
+var ErrReplayUnsupported = errors.New("replay: unsupported endpoint")
+
+// Replay re-applies a stored provider payload through the current mapper, as
+// if it had just been fetched. The snapshot itself is not stored again.
+func (h *Hydrator) Replay(ctx context.Context, snap store.RawSnapshot) error {
+	if !h.Enabled() {
+		return errors.New("replay requires a store")
+	}
+	var cards []attom.PropertyCard
+	var err error
+	switch snap.Endpoint {
+	case "search/forsale":
+		cards, err = attom.MapSearchPayloadToCards(snap.Payload)
+	default:
+		return fmt.Errorf("%w: %s", ErrReplayUnsupported, snap.Endpoint)
+	}
+	if err != nil {
+		return fmt.Errorf("replay %s: %w", snap.ID, err)
+	}
+	for _, card := range cards {
+		if card.ID != snap.ExternalID {
+			continue
+		}
+		line1, city, st, zip, pk := canon.Canonicalize(card.Address, card.City, card.State, card.Zip)
+		if pk == "" {
+			return fmt.Errorf("replay %s: empty property key", snap.ID)
+		}
+		in := upsertInput(snap.Provider, snap.Endpoint, snap.Payload, map[string]string{
+			"line1": line1, "city": city, "state": st, "zip": zip, "property_key": pk,
+		}, card)
+		in.SkipSnapshot = true
+		return h.write(ctx, in)
+	}
+	return fmt.Errorf("replay %s: card %s not in payload", snap.ID, snap.ExternalID)
+}
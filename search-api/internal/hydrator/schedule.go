@@ -0,0 +1,168 @@
+package hydrator
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// demandWindow is how far back API lookups count toward a ZIP's demand.
+const demandWindow = 24 * time.Hour
+
+// zipPlan is one ZIP's scheduling state for a tick.
+type zipPlan struct {
+	zip      string
+	age      time.Duration // since the ZIP was last hydrated
+	interval time.Duration // how often it should be
+	demand   int
+}
+
+func (p zipPlan) overdue() float64 { return float64(p.age) / float64(p.interval) }
+
+// runAdaptive replaces the fixed ticker: every SchedulerTick it hydrates the
+// ZIPs that are due, most overdue first, within the quota pace.
+func (j *BulkJob) runAdaptive(ctx context.Context) error {
+	tick := j.Config.SchedulerTick
+	if tick <= 0 {
+		tick = 5 * time.Minute
+	}
+	j.logf("hydrator bulk job starting adaptive schedule (tick %s, intervals %s-%s)", tick, j.minInterval(), j.maxInterval())
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			j.logf("hydrator bulk job stopping: %v", ctx.Err())
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+		zips, err := j.plan(ctx, time.Now(), tick)
+		if err != nil {
+			j.logf("hydrator bulk job schedule error: %v", err)
+		} else if len(zips) > 0 {
+			if err := j.runZips(ctx, zips); err != nil && !errors.Is(err, context.Canceled) {
+				j.logf("hydrator bulk job iteration error: %v", err)
+			}
+			now := time.Now()
+			for _, zip := range zips {
+				j.lastRun.Store(zip, now)
+			}
+		}
+		timer.Reset(tick)
+	}
+}
+
+// plan returns the ZIPs to hydrate this tick. A ZIP is due once its age
+// reaches its interval: MaxInterval with no recent lookups, shrinking toward
+// MinInterval as demand grows. Age counts from the newer of its freshest
+// listing and this process's last run of it, so ZIPs without listings are
+// not retried every tick.
+func (j *BulkJob) plan(ctx context.Context, now time.Time, tick time.Duration) ([]string, error) {
+	zips, err := j.targetZips(ctx)
+	if err != nil || len(zips) == 0 {
+		return nil, err
+	}
+	acts, err := j.Store.FetchZipActivity(ctx, zips, now.Add(-demandWindow))
+	if err != nil {
+		return nil, err
+	}
+	var due []zipPlan
+	for _, zip := range zips {
+		a := acts[zip]
+		var last time.Time
+		if a.LastFetchAt.Valid {
+			last = a.LastFetchAt.Time
+		}
+		if v, ok := j.lastRun.Load(zip); ok && v.(time.Time).After(last) {
+			last = v.(time.Time)
+		}
+		p := zipPlan{zip: zip, interval: j.zipInterval(a.Demand), demand: a.Demand}
+		if last.IsZero() {
+			p.age = 2 * j.maxInterval()
+		} else {
+			p.age = now.Sub(last)
+		}
+		if p.age >= p.interval {
+			due = append(due, p)
+		}
+	}
+	sort.SliceStable(due, func(a, b int) bool {
+		if oa, ob := due[a].overdue(), due[b].overdue(); oa != ob {
+			return oa > ob
+		}
+		return due[a].demand > due[b].demand
+	})
+	n := j.quotaPace(len(due), now, tick)
+	if n < len(due) {
+		j.logf("hydrator bulk job deferring %d due zip(s) to pace the daily quota", len(due)-n)
+	}
+	out := make([]string, 0, n)
+	for _, p := range due[:n] {
+		out = append(out, p.zip)
+	}
+	return out, nil
+}
+
+// zipInterval maps demand over demandWindow to a refresh interval: Interval
+// for one lookup, halving with every doubling of lookups.
+func (j *BulkJob) zipInterval(demand int) time.Duration {
+	if demand <= 0 {
+		return j.maxInterval()
+	}
+	iv := time.Duration(float64(j.Config.Interval) / math.Log2(1+float64(demand)))
+	return min(max(iv, j.minInterval()), j.maxInterval())
+}
+
+func (j *BulkJob) minInterval() time.Duration {
+	if j.Config.MinInterval > 0 {
+		return j.Config.MinInterval
+	}
+	return j.Config.Interval / 8
+}
+
+func (j *BulkJob) maxInterval() time.Duration {
+	if j.Config.MaxInterval > 0 {
+		return j.Config.MaxInterval
+	}
+	return j.Config.Interval * 4
+}
+
+// requestsPerZip estimates the provider calls one ZIP can cost.
+func (j *BulkJob) requestsPerZip() int {
+	pages := j.Config.MaxPagesPerZip
+	if pages <= 0 {
+		pages = 5
+	}
+	calls := pages * len(j.propertyTypes())
+	if j.Config.FetchPhotos {
+		pageSize := j.Config.PageSize
+		if pageSize <= 0 {
+			pageSize = 50
+		}
+		calls += calls * pageSize
+	}
+	return calls
+}
+
+// quotaPace caps how many of n due ZIPs run this tick so the remaining daily
+// quota is spread evenly until the UTC reset. At least one ZIP runs while the
+// quota can afford it.
+func (j *BulkJob) quotaPace(n int, now time.Time, tick time.Duration) int {
+	remaining := j.Client.RemainingDailyQuota()
+	if remaining < 0 || n == 0 {
+		return n
+	}
+	cost := j.requestsPerZip()
+	if remaining < cost {
+		return 0
+	}
+	now = now.UTC()
+	reset := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	allow := int(float64(remaining) * float64(tick) / float64(reset.Sub(now)))
+	return min(n, max(allow/cost, 1))
+}
@@ -0,0 +1,148 @@
+package hydrator
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/yourorg/search-api/internal/archive"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// SnapshotRetentionJob keeps the monthly raw snapshot partitions ahead of
+// time and drops those older than KeepMonths. With Archive set, each
+// partition is exported to it as gzipped JSON lines before it is dropped,
+// and a failed export keeps the partition for the next pass; expired
+// snapshots in the default partition are exported the same way before they
+// are deleted.
+type SnapshotRetentionJob struct {
+	Store   *store.Store
+	Archive *archive.S3
+	Logger  *log.Logger
+	// KeepMonths full months are kept besides the current one; 0 keeps
+	// every partition.
+	KeepMonths int
+	// MonthsAhead partitions are created past the current month, default 2.
+	MonthsAhead int
+	Interval    time.Duration
+}
+
+func (j *SnapshotRetentionJob) logf(format string, args ...any) {
+	if j.Logger != nil {
+		j.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// RunOnce creates upcoming partitions and drops expired ones.
+func (j *SnapshotRetentionJob) RunOnce(ctx context.Context) error {
+	if j == nil || j.Store == nil {
+		return errors.New("snapshot retention requires store")
+	}
+	ahead := j.MonthsAhead
+	if ahead <= 0 {
+		ahead = 2
+	}
+	now := time.Now().UTC()
+	if err := j.Store.EnsureSnapshotPartitions(ctx, now, ahead); err != nil {
+		return err
+	}
+	if j.KeepMonths <= 0 {
+		return nil
+	}
+	cutoff := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -j.KeepMonths, 0)
+	parts, err := j.Store.ExpiredSnapshotPartitions(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if j.Archive != nil {
+			n, err := j.export(ctx, p.Name, func(w io.Writer) (int64, error) {
+				return j.Store.CopySnapshotPartition(ctx, p.Name, w)
+			})
+			if err != nil {
+				j.logf("snapshot partition %s export failed, keeping it: %v", p.Name, err)
+				continue
+			}
+			if n > 0 {
+				j.logf("snapshot partition %s exported %d snapshot(s) to %s", p.Name, n, j.Archive.URL(p.Name+".ndjson.gz"))
+			}
+		}
+		if err := j.Store.DropSnapshotPartition(ctx, p.Name); err != nil {
+			return err
+		}
+		j.logf("snapshot partition %s dropped", p.Name)
+	}
+	if j.Archive != nil {
+		name := "default-" + now.Format("20060102T150405Z")
+		n, err := j.export(ctx, name, func(w io.Writer) (int64, error) {
+			return j.Store.CopyDefaultSnapshots(ctx, cutoff, w)
+		})
+		if err != nil {
+			j.logf("default snapshot partition export failed, keeping its snapshots: %v", err)
+			return nil
+		}
+		if n > 0 {
+			j.logf("default snapshot partition exported %d snapshot(s) to %s", n, j.Archive.URL(name+".ndjson.gz"))
+		}
+	}
+	if n, err := j.Store.TrimDefaultSnapshots(ctx, cutoff); err != nil {
+		return err
+	} else if n > 0 {
+		j.logf("snapshot retention deleted %d snapshot(s) from the default partition", n)
+	}
+	return nil
+}
+
+// export writes the snapshots write produces to a gzipped temp file, then
+// uploads it as name.ndjson.gz, so the upload knows its length and a slow
+// bucket doesn't hold the query open. Nothing is uploaded when there are no
+// snapshots.
+func (j *SnapshotRetentionJob) export(ctx context.Context, name string, write func(io.Writer) (int64, error)) (int64, error) {
+	f, err := os.CreateTemp("", name+"-*.ndjson.gz")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	n, err := write(zw)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	if err := zw.Close(); err != nil {
+		return n, err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return n, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return n, err
+	}
+	return n, j.Archive.Put(ctx, name+".ndjson.gz", "application/gzip", f, size)
+}
+
+func (j *SnapshotRetentionJob) Run(ctx context.Context) error {
+	every := j.Interval
+	if every <= 0 {
+		every = 24 * time.Hour
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if err := j.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			j.logf("snapshot retention error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
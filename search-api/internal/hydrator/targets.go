@@ -0,0 +1,90 @@
+package hydrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// Target sources for BulkConfig.Targets.
+const (
+	TargetsStatic = ""      // BulkConfig.Zips
+	TargetsTable  = "table" // enabled rows of hydrator_targets by priority
+	TargetsStale  = "stale" // ZIPs with the most stale for-sale listings
+	// TargetsDemand is the ZIPs searched most, from search analytics.
+	TargetsDemand = "demand"
+)
+
+// targetZips resolves the ZIPs for one cycle. Table, stale and demand
+// targets are re-read every cycle so coverage changes take effect without a restart.
+func (j *BulkJob) targetZips(ctx context.Context) ([]string, error) {
+	limit := j.Config.TargetLimit
+	if limit <= 0 {
+		limit = 50
+	}
+	switch j.Config.Targets {
+	case TargetsStatic:
+		var zips []string
+		for _, rawZip := range j.Config.Zips {
+			if zip := strings.TrimSpace(rawZip); zip != "" {
+				zips = append(zips, zip)
+			}
+		}
+		return zips, nil
+	case TargetsTable:
+		targets, err := j.Store.HydratorTargets(ctx, limit)
+		if err != nil {
+			return nil, fmt.Errorf("load hydrator targets: %w", err)
+		}
+		zips := make([]string, 0, len(targets))
+		for _, t := range targets {
+			zips = append(zips, t.Zip)
+		}
+		return zips, nil
+	case TargetsStale:
+		staleAfter := j.Config.StaleAfter
+		if staleAfter <= 0 {
+			staleAfter = 24 * time.Hour
+		}
+		targets, err := j.Store.StaleListingZips(ctx, staleAfter, limit)
+		if err != nil {
+			return nil, fmt.Errorf("load stale zips: %w", err)
+		}
+		zips := make([]string, 0, len(targets))
+		for _, t := range targets {
+			zips = append(zips, t.Zip)
+		}
+		return zips, nil
+	case TargetsDemand:
+		window := j.Config.DemandWindow
+		if window <= 0 {
+			window = 7 * 24 * time.Hour
+		}
+		now := time.Now()
+		top, err := j.Store.TopSearchZips(ctx, store.SearchAnalyticsQuery{From: now.Add(-window), To: now, Limit: limit})
+		if err != nil {
+			return nil, fmt.Errorf("load searched zips: %w", err)
+		}
+		zips := make([]string, 0, len(top))
+		for _, z := range top {
+			zips = append(zips, z.Zip)
+		}
+		return zips, nil
+	default:
+		return nil, fmt.Errorf("unknown hydrator target source %q", j.Config.Targets)
+	}
+}
+
+// markTargetRun stamps last_run_at for table targets so the next cycle
+// rotates to ZIPs visited longest ago.
+func (j *BulkJob) markTargetRun(ctx context.Context, zip string) {
+	if j.Config.Targets != TargetsTable {
+		return
+	}
+	if err := j.Store.MarkHydratorTargetRun(ctx, zip, time.Now()); err != nil {
+		j.logf("hydrator bulk job mark target %s: %v", zip, err)
+	}
+}
@@ -1,15 +1,208 @@
+// Package logger writes the access log: one JSON line per request with its
+// status, bytes out, duration, how the cache answered and how many provider
+// calls and database queries it took. Handlers annotate the cache result
+// with SetCache, and searches with SetSearch for analytics; the provider
+// client and the store count their calls through the request context.
 package logger
 
 import (
-	"log"
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Cache results for SetCache.
+const (
+	CacheHit   = "hit"   // served fresh from the Redis cache
+	CacheStale = "stale" // served stale from the cache, refresh queued
+	CacheMiss  = "miss"  // not cached; served from the database
+	// CacheProvider is a response fetched from the provider. Requests that
+	// made provider calls and set no result are logged as provider.
+	CacheProvider = "provider"
 )
 
+// stats counts the work done for one request.
+type stats struct {
+	providerCalls atomic.Int64
+	queries       atomic.Int64
+	mu            sync.Mutex
+	cache         string
+	consumer      string
+	search        *Search
+}
+
+// Search describes a search for analytics: where and how it searched,
+// never who searched.
+type Search struct {
+	Route        string // search or listings
+	Zip          string
+	City         string // "City, ST" for city searches
+	PropertyType string
+	OrderBy      string
+	// Filters names the other filters used, such as beds or min_price.
+	Filters []string
+	Results int
+}
+
+type statsKey struct{}
+
+func statsFrom(ctx context.Context) *stats {
+	s, _ := ctx.Value(statsKey{}).(*stats)
+	return s
+}
+
+// SetCache records how the request's response was served, one of the Cache*
+// results. Outside a logged request it does nothing.
+func SetCache(ctx context.Context, result string) {
+	if s := statsFrom(ctx); s != nil {
+		s.mu.Lock()
+		s.cache = result
+		s.mu.Unlock()
+	}
+}
+
+// SetConsumer records who made the request, such as an API key's name, for
+// the log line and usage accounting.
+func SetConsumer(ctx context.Context, consumer string) {
+	if s := statsFrom(ctx); s != nil {
+		s.mu.Lock()
+		s.consumer = consumer
+		s.mu.Unlock()
+	}
+}
+
+// SetSearch records the search the request made. Outside a logged request
+// it does nothing.
+func SetSearch(ctx context.Context, search Search) {
+	if s := statsFrom(ctx); s != nil {
+		s.mu.Lock()
+		s.search = &search
+		s.mu.Unlock()
+	}
+}
+
+// SetResults records how many results the request's search returned.
+func SetResults(ctx context.Context, n int) {
+	if s := statsFrom(ctx); s != nil {
+		s.mu.Lock()
+		if s.search != nil {
+			s.search.Results = n
+		}
+		s.mu.Unlock()
+	}
+}
+
+// CountProviderCall counts one provider HTTP attempt, retries included.
+func CountProviderCall(ctx context.Context) {
+	if s := statsFrom(ctx); s != nil {
+		s.providerCalls.Add(1)
+	}
+}
+
+// CountQuery counts one database query or batch.
+func CountQuery(ctx context.Context) {
+	if s := statsFrom(ctx); s != nil {
+		s.queries.Add(1)
+	}
+}
+
+// AccessLog configures the access log middleware.
+type AccessLog struct {
+	// SampleRate is the fraction of requests logged, at most 1; 0 logs none
+	// but server errors and requests slower than Slow, which are always
+	// logged.
+	SampleRate float64
+	Slow       time.Duration
+	// Out receives the JSON lines, default stdout.
+	Out io.Writer
+	// OnRequest, when set, sees every request, sampled out or not.
+	OnRequest func(Entry)
+}
+
+// Entry summarises a finished request for AccessLog.OnRequest.
+type Entry struct {
+	Consumer      string // empty for anonymous callers
+	Status        int
+	Duration      time.Duration
+	Cache         string // one of the Cache* results, or empty
+	ProviderCalls int64
+	Queries       int64
+	Search        *Search // set by SetSearch, or nil
+}
+
+// Middleware logs every request to stdout.
 func Middleware(next http.Handler) http.Handler {
+	return AccessLog{SampleRate: 1}.Middleware(next)
+}
+
+func (a AccessLog) Middleware(next http.Handler) http.Handler {
+	rate := min(max(a.SampleRate, 0), 1)
+	out := a.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	lg := slog.New(slog.NewJSONHandler(out, nil))
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
+		st := &stats{}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), statsKey{}, st)))
+		took := time.Since(start)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		st.mu.Lock()
+		cache, consumer, search := st.cache, st.consumer, st.search
+		st.mu.Unlock()
+		if cache == "" && st.providerCalls.Load() > 0 {
+			cache = CacheProvider
+		}
+		if a.OnRequest != nil {
+			a.OnRequest(Entry{
+				Consumer:      consumer,
+				Status:        status,
+				Duration:      took,
+				Cache:         cache,
+				ProviderCalls: st.providerCalls.Load(),
+				Queries:       st.queries.Load(),
+				Search:        search,
+			})
+		}
+		if rate < 1 && status < 500 && (a.Slow <= 0 || took < a.Slow) && rand.Float64() >= rate {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Float64("duration_ms", float64(took.Microseconds())/1000),
+			slog.Int64("provider_calls", st.providerCalls.Load()),
+			slog.Int64("db_queries", st.queries.Load()),
+			slog.String("remote", r.RemoteAddr),
+		}
+		if cache != "" {
+			attrs = append(attrs, slog.String("cache", cache))
+		}
+		if consumer != "" {
+			attrs = append(attrs, slog.String("consumer", consumer))
+		}
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if rate < 1 {
+			attrs = append(attrs, slog.Float64("sample_rate", rate))
+		}
+		lg.LogAttrs(r.Context(), slog.LevelInfo, "access", attrs...)
 	})
 }
@@ -0,0 +1,231 @@
+package markets
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourorg/search-api/internal/archive"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// Segment is one of a ZIP's fastest-selling segments in a report.
+type Segment struct {
+	Segment            string  `json:"segment" doc:"Property type and bedrooms, e.g. \"single_family, 3 bd\""`
+	PropertyType       string  `json:"propertyType,omitempty"`
+	Beds               *int    `json:"beds,omitempty" doc:"5 means 5 or more"`
+	Sold               int     `json:"sold"`
+	MedianDaysOnMarket float64 `json:"medianDaysOnMarket"`
+}
+
+// Summary is a ZIP's weekly market report as served and archived.
+type Summary struct {
+	Zip                  string    `json:"zip"`
+	WeekStart            string    `json:"weekStart" doc:"Monday the week starts (UTC)"`
+	WeekEnd              string    `json:"weekEnd" doc:"Sunday the week ends (UTC)"`
+	NewListings          int       `json:"newListings"`
+	SoldCount            int       `json:"soldCount"`
+	ActiveCount          int       `json:"activeCount" doc:"Active inventory at the end of the week"`
+	MedianPrice          *float64  `json:"medianPrice"`
+	PreviousMedianPrice  *float64  `json:"previousMedianPrice" doc:"Median list price at the end of the week before"`
+	MedianPriceChange    *float64  `json:"medianPriceChange"`
+	MedianPriceChangePct *float64  `json:"medianPriceChangePct"`
+	FastestSegments      []Segment `json:"fastestSegments" doc:"Segments sold during the week, quickest median days on market first"`
+	GeneratedAt          time.Time `json:"generatedAt"`
+}
+
+// Summarize shapes a stored report for output.
+func Summarize(r store.MarketReport) Summary {
+	out := Summary{
+		Zip:             r.Zip,
+		WeekStart:       r.WeekStart.Format("2006-01-02"),
+		WeekEnd:         r.WeekStart.AddDate(0, 0, 6).Format("2006-01-02"),
+		NewListings:     r.NewListings,
+		SoldCount:       r.SoldCount,
+		ActiveCount:     r.ActiveCount,
+		FastestSegments: make([]Segment, 0, len(r.Segments)),
+		GeneratedAt:     r.GeneratedAt,
+	}
+	if r.MedianPrice.Valid {
+		v := r.MedianPrice.Float64
+		out.MedianPrice = &v
+	}
+	if r.PrevMedianPrice.Valid {
+		v := r.PrevMedianPrice.Float64
+		out.PreviousMedianPrice = &v
+	}
+	if out.MedianPrice != nil && out.PreviousMedianPrice != nil {
+		change := *out.MedianPrice - *out.PreviousMedianPrice
+		out.MedianPriceChange = &change
+		if *out.PreviousMedianPrice != 0 {
+			pct := math.Round(change / *out.PreviousMedianPrice * 10000) / 100
+			out.MedianPriceChangePct = &pct
+		}
+	}
+	for _, s := range r.Segments {
+		out.FastestSegments = append(out.FastestSegments, Segment{
+			Segment:            segmentLabel(s),
+			PropertyType:       s.PropertyType,
+			Beds:               s.Beds,
+			Sold:               s.Sold,
+			MedianDaysOnMarket: s.MedianDaysOnMarket,
+		})
+	}
+	return out
+}
+
+func segmentLabel(s store.MarketSegment) string {
+	var parts []string
+	if s.PropertyType != "" {
+		parts = append(parts, s.PropertyType)
+	}
+	switch {
+	case s.Beds == nil:
+	case *s.Beds >= 5:
+		parts = append(parts, "5+ bd")
+	default:
+		parts = append(parts, strconv.Itoa(*s.Beds)+" bd")
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WriteCSV writes one row per ZIP; the fastest segment is flattened into
+// its own columns.
+func WriteCSV(w io.Writer, reports []Summary) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"zip", "week_start", "week_end", "new_listings", "sold_count", "active_count",
+		"median_price", "previous_median_price", "median_price_change", "median_price_change_pct",
+		"fastest_segment", "fastest_segment_sold", "fastest_segment_median_days_on_market"})
+	for _, r := range reports {
+		row := []string{r.Zip, r.WeekStart, r.WeekEnd, strconv.Itoa(r.NewListings), strconv.Itoa(r.SoldCount), strconv.Itoa(r.ActiveCount),
+			csvFloat(r.MedianPrice), csvFloat(r.PreviousMedianPrice), csvFloat(r.MedianPriceChange), csvFloat(r.MedianPriceChangePct)}
+		if len(r.FastestSegments) > 0 {
+			s := r.FastestSegments[0]
+			row = append(row, s.Segment, strconv.Itoa(s.Sold), strconv.FormatFloat(s.MedianDaysOnMarket, 'f', -1, 64))
+		} else {
+			row = append(row, "", "", "")
+		}
+		_ = cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// WeekStart returns the Monday (UTC) starting t's week.
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// ReportJob writes the weekly per-ZIP market reports every Monday at
+// RunAtUTC (offset from midnight UTC), covering the week that just ended.
+// Run it after the daily rollup so the week's Sunday is included. With
+// Archive set, the week's reports are also uploaded as {week}.json and
+// {week}.csv.
+type ReportJob struct {
+	Store      *store.Store
+	Archive    *archive.S3
+	Logger     *log.Logger
+	RunAtUTC   time.Duration
+	RunOnStart bool
+}
+
+func (j *ReportJob) logf(format string, args ...any) {
+	if j.Logger != nil {
+		j.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// RunOnce reports on the last full week.
+func (j *ReportJob) RunOnce(ctx context.Context) error {
+	if j == nil || j.Store == nil {
+		return errors.New("market reports require store")
+	}
+	start := time.Now()
+	week := WeekStart(start).AddDate(0, 0, -7)
+	n, err := j.Store.GenerateMarketReports(ctx, week)
+	if err != nil {
+		return err
+	}
+	j.logf("market reports for week of %s wrote %d zip(s) in %s", week.Format("2006-01-02"), n, time.Since(start).Round(time.Millisecond))
+	if j.Archive == nil || n == 0 {
+		return nil
+	}
+	return j.archive(ctx, week)
+}
+
+func (j *ReportJob) archive(ctx context.Context, week time.Time) error {
+	reports, err := j.Store.MarketReports(ctx, week, "")
+	if err != nil {
+		return err
+	}
+	summaries := make([]Summary, 0, len(reports))
+	for _, r := range reports {
+		summaries = append(summaries, Summarize(r))
+	}
+	name := week.Format("2006-01-02")
+	var js, cs bytes.Buffer
+	if err := json.NewEncoder(&js).Encode(map[string]any{"weekStart": name, "count": len(summaries), "reports": summaries}); err != nil {
+		return err
+	}
+	if err := WriteCSV(&cs, summaries); err != nil {
+		return err
+	}
+	for _, a := range []struct {
+		key, contentType string
+		body             *bytes.Buffer
+	}{{name + ".json", "application/json", &js}, {name + ".csv", "text/csv; charset=utf-8", &cs}} {
+		if err := j.Archive.Put(ctx, a.key, a.contentType, bytes.NewReader(a.body.Bytes()), int64(a.body.Len())); err != nil {
+			return fmt.Errorf("archive market report %s: %w", a.key, err)
+		}
+		j.logf("market report archived to %s", j.Archive.URL(a.key))
+	}
+	return nil
+}
+
+func (j *ReportJob) Run(ctx context.Context) error {
+	if j.RunOnStart {
+		if err := j.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			j.logf("market reports initial run error: %v", err)
+		}
+	}
+	for {
+		now := time.Now().UTC()
+		next := WeekStart(now).Add(j.RunAtUTC)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 7)
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+			if err := j.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				j.logf("market reports error: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package markets
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// RollupJob writes the per-ZIP daily market aggregates once a day at
+// RunAtUTC (offset from midnight UTC), covering the UTC day that just ended.
+type RollupJob struct {
+	Store      *store.Store
+	Logger     *log.Logger
+	RunAtUTC   time.Duration
+	RunOnStart bool
+}
+
+func (j *RollupJob) logf(format string, args ...any) {
+	if j.Logger != nil {
+		j.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// RunOnce rolls up the previous UTC day.
+func (j *RollupJob) RunOnce(ctx context.Context) error {
+	if j == nil || j.Store == nil {
+		return errors.New("market rollup requires store")
+	}
+	start := time.Now()
+	day := start.UTC().AddDate(0, 0, -1)
+	n, err := j.Store.RollupMarketDailyStats(ctx, day)
+	if err != nil {
+		return err
+	}
+	j.logf("market rollup for %s wrote %d zip(s) in %s", day.Format("2006-01-02"), n, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (j *RollupJob) Run(ctx context.Context) error {
+	if j.RunOnStart {
+		if err := j.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			j.logf("market rollup initial run error: %v", err)
+		}
+	}
+	for {
+		next := nextRun(time.Now().UTC(), j.RunAtUTC)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+			if err := j.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				j.logf("market rollup error: %v", err)
+			}
+		}
+	}
+}
+
+// nextRun returns the next time-of-day occurrence of at after now.
+func nextRun(now time.Time, at time.Duration) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	next := midnight.Add(at)
+	if !next.After(now) {
+		next = next.Add(24 * time.Hour)
+	}
+	return next
+}
@@ -0,0 +1,133 @@
+// Package payment estimates the monthly cost of owning a listing: principal
+// and interest on a fixed-rate mortgage plus property tax, homeowners
+// insurance and HOA dues. It also prices the returns of letting one.
+package payment
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strconv"
+)
+
+// Assumptions are the loan and cost inputs of an estimate. Rates are annual
+// percentages, so 6.5 means 6.5%.
+type Assumptions struct {
+	InterestRate   float64
+	DownPaymentPct float64
+	TermYears      int
+	// TaxRate is used only when the property has no assessed tax on record.
+	TaxRate       float64
+	InsuranceRate float64
+	HOAMonthly    float64
+}
+
+// Defaults are used when neither the caller nor the environment sets a value.
+var Defaults = Assumptions{
+	InterestRate:   6.75,
+	DownPaymentPct: 20,
+	TermYears:      30,
+	TaxRate:        1.1,
+	InsuranceRate:  0.35,
+}
+
+// AssumptionsFromEnv overrides Defaults with PAYMENT_INTEREST_RATE,
+// PAYMENT_DOWN_PAYMENT_PCT, PAYMENT_TERM_YEARS, PAYMENT_TAX_RATE and
+// PAYMENT_INSURANCE_RATE where set.
+func AssumptionsFromEnv() Assumptions {
+	a := Defaults
+	envFloat("PAYMENT_INTEREST_RATE", &a.InterestRate)
+	envFloat("PAYMENT_DOWN_PAYMENT_PCT", &a.DownPaymentPct)
+	envFloat("PAYMENT_TAX_RATE", &a.TaxRate)
+	envFloat("PAYMENT_INSURANCE_RATE", &a.InsuranceRate)
+	if v, err := strconv.Atoi(os.Getenv("PAYMENT_TERM_YEARS")); err == nil && v > 0 {
+		a.TermYears = v
+	}
+	return a
+}
+
+func envFloat(key string, dst *float64) {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v >= 0 && finite(v) {
+		*dst = v
+	}
+}
+
+// finite reports whether none of vs is NaN or infinite. Range checks are
+// false for NaN, so they alone let it through.
+func finite(vs ...float64) bool {
+	for _, v := range vs {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate reports the first assumption outside a sane range.
+func (a Assumptions) Validate() error {
+	switch {
+	case !finite(a.InterestRate, a.DownPaymentPct, a.TaxRate, a.InsuranceRate, a.HOAMonthly):
+		return errors.New("assumptions must be finite numbers")
+	case a.InterestRate < 0 || a.InterestRate > 30:
+		return errors.New("rate must be between 0 and 30")
+	case a.DownPaymentPct < 0 || a.DownPaymentPct > 100:
+		return errors.New("down_payment_pct must be between 0 and 100")
+	case a.TermYears < 1 || a.TermYears > 50:
+		return errors.New("term_years must be between 1 and 50")
+	case a.TaxRate < 0 || a.TaxRate > 10:
+		return errors.New("tax_rate must be between 0 and 10")
+	case a.InsuranceRate < 0 || a.InsuranceRate > 10:
+		return errors.New("insurance_rate must be between 0 and 10")
+	case a.HOAMonthly < 0:
+		return errors.New("hoa must not be negative")
+	}
+	return nil
+}
+
+// Estimate is a monthly payment breakdown. All amounts are in dollars and
+// rounded to cents.
+type Estimate struct {
+	Price                float64
+	DownPayment          float64
+	LoanAmount           float64
+	PrincipalAndInterest float64
+	Tax                  float64
+	Insurance            float64
+	HOA                  float64
+	Total                float64
+	// TaxFromAssessment is true when Tax came from the property's assessed
+	// tax rather than Assumptions.TaxRate.
+	TaxFromAssessment bool
+}
+
+// Calculate prices a purchase at price. annualTax is the assessed yearly
+// tax; zero falls back to price times TaxRate.
+func Calculate(price, annualTax float64, a Assumptions) Estimate {
+	e := Estimate{Price: price, HOA: a.HOAMonthly}
+	e.DownPayment = price * a.DownPaymentPct / 100
+	e.LoanAmount = price - e.DownPayment
+	n := float64(a.TermYears * 12)
+	if r := a.InterestRate / 100 / 12; r > 0 {
+		e.PrincipalAndInterest = e.LoanAmount * r / (1 - math.Pow(1+r, -n))
+	} else {
+		e.PrincipalAndInterest = e.LoanAmount / n
+	}
+	if annualTax > 0 {
+		e.Tax = annualTax / 12
+		e.TaxFromAssessment = true
+	} else {
+		e.Tax = price * a.TaxRate / 100 / 12
+	}
+	e.Insurance = price * a.InsuranceRate / 100 / 12
+
+	e.DownPayment = cents(e.DownPayment)
+	e.LoanAmount = cents(e.LoanAmount)
+	e.PrincipalAndInterest = cents(e.PrincipalAndInterest)
+	e.Tax = cents(e.Tax)
+	e.Insurance = cents(e.Insurance)
+	e.HOA = cents(e.HOA)
+	e.Total = cents(e.PrincipalAndInterest + e.Tax + e.Insurance + e.HOA)
+	return e
+}
+
+func cents(v float64) float64 { return math.Round(v*100) / 100 }
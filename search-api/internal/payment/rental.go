@@ -0,0 +1,96 @@
+package payment
+
+import "errors"
+
+// RentalAssumptions are the operating inputs of a rental's returns, as
+// percentages of gross rent.
+type RentalAssumptions struct {
+	VacancyPct     float64
+	MaintenancePct float64
+	ManagementPct  float64
+}
+
+// RentalDefaults are used when neither the caller nor the environment sets a
+// value.
+var RentalDefaults = RentalAssumptions{
+	VacancyPct:     5,
+	MaintenancePct: 5,
+	ManagementPct:  8,
+}
+
+// RentalAssumptionsFromEnv overrides RentalDefaults with RENT_VACANCY_PCT,
+// RENT_MAINTENANCE_PCT and RENT_MANAGEMENT_PCT where set.
+func RentalAssumptionsFromEnv() RentalAssumptions {
+	r := RentalDefaults
+	envFloat("RENT_VACANCY_PCT", &r.VacancyPct)
+	envFloat("RENT_MAINTENANCE_PCT", &r.MaintenancePct)
+	envFloat("RENT_MANAGEMENT_PCT", &r.ManagementPct)
+	return r
+}
+
+// Validate reports the first assumption outside a sane range.
+func (r RentalAssumptions) Validate() error {
+	switch {
+	case !finite(r.VacancyPct, r.MaintenancePct, r.ManagementPct):
+		return errors.New("rental assumptions must be finite numbers")
+	case r.VacancyPct < 0 || r.VacancyPct > 100:
+		return errors.New("vacancy_pct must be between 0 and 100")
+	case r.MaintenancePct < 0 || r.MaintenancePct > 100:
+		return errors.New("maintenance_pct must be between 0 and 100")
+	case r.ManagementPct < 0 || r.ManagementPct > 100:
+		return errors.New("management_pct must be between 0 and 100")
+	}
+	return nil
+}
+
+// RentalReturns are a rental's yearly income, costs and yields at a
+// purchase price. Amounts are in dollars, rounded to cents; yields are
+// percentages.
+type RentalReturns struct {
+	Price             float64
+	GrossRent         float64
+	VacancyLoss       float64
+	EffectiveIncome   float64
+	Tax               float64
+	Insurance         float64
+	HOA               float64
+	Maintenance       float64
+	Management        float64
+	OperatingExpenses float64
+	NetOperating      float64
+	GrossYield        float64
+	CapRate           float64
+	// TaxFromAssessment is true when Tax came from the property's assessed
+	// tax rather than Assumptions.TaxRate.
+	TaxFromAssessment bool
+}
+
+// CalculateRental prices a rental bought at price and let at monthlyRent.
+// Tax, insurance and HOA follow Calculate; annualTax zero falls back to
+// price times TaxRate. Financing is left out, so CapRate is unlevered.
+func CalculateRental(price, monthlyRent, annualTax float64, a Assumptions, r RentalAssumptions) RentalReturns {
+	out := RentalReturns{Price: price, GrossRent: monthlyRent * 12, HOA: a.HOAMonthly * 12}
+	out.VacancyLoss = out.GrossRent * r.VacancyPct / 100
+	out.EffectiveIncome = out.GrossRent - out.VacancyLoss
+	if annualTax > 0 {
+		out.Tax = annualTax
+		out.TaxFromAssessment = true
+	} else {
+		out.Tax = price * a.TaxRate / 100
+	}
+	out.Insurance = price * a.InsuranceRate / 100
+	out.Maintenance = out.GrossRent * r.MaintenancePct / 100
+	out.Management = out.EffectiveIncome * r.ManagementPct / 100
+	out.OperatingExpenses = out.Tax + out.Insurance + out.HOA + out.Maintenance + out.Management
+	out.NetOperating = out.EffectiveIncome - out.OperatingExpenses
+	if price > 0 {
+		out.GrossYield = out.GrossRent / price * 100
+		out.CapRate = out.NetOperating / price * 100
+	}
+
+	for _, v := range []*float64{&out.GrossRent, &out.VacancyLoss, &out.EffectiveIncome, &out.Tax, &out.Insurance,
+		&out.HOA, &out.Maintenance, &out.Management, &out.OperatingExpenses, &out.NetOperating, &out.GrossYield, &out.CapRate} {
+		*v = cents(*v)
+	}
+	return out
+}
@@ -0,0 +1,125 @@
+// Package photoclass labels listing photos with a normalized room type.
+// Provider tags are free-form and inconsistent ("kitchen", "Kitchen Island",
+// "floor_plan", "house_view"), so they are mapped onto a small fixed set;
+// photos the tags don't cover can be sent to an external image classifier.
+package photoclass
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Room types a photo can be labelled with.
+const (
+	Exterior   = "exterior"
+	Kitchen    = "kitchen"
+	Bathroom   = "bathroom"
+	Bedroom    = "bedroom"
+	LivingRoom = "living_room"
+	DiningRoom = "dining_room"
+	Floorplan  = "floorplan"
+)
+
+// Rooms lists every room type in display order.
+var Rooms = []string{Exterior, Kitchen, LivingRoom, DiningRoom, Bedroom, Bathroom, Floorplan}
+
+// aliases maps normalized provider tags and classifier labels to a room.
+var aliases = map[string]string{
+	"exterior": Exterior, "front": Exterior, "house_view": Exterior, "facade": Exterior,
+	"yard": Exterior, "backyard": Exterior, "front_yard": Exterior, "garden": Exterior,
+	"pool": Exterior, "patio": Exterior, "porch": Exterior, "deck": Exterior, "aerial_view": Exterior,
+	"kitchen": Kitchen, "kitchen_island": Kitchen, "pantry": Kitchen,
+	"bathroom": Bathroom, "bath": Bathroom, "master_bathroom": Bathroom, "primary_bathroom": Bathroom,
+	"powder_room": Bathroom, "shower": Bathroom,
+	"bedroom": Bedroom, "master_bedroom": Bedroom, "primary_bedroom": Bedroom,
+	"living_room": LivingRoom, "living": LivingRoom, "family_room": LivingRoom, "great_room": LivingRoom,
+	"dining_room": DiningRoom, "dining": DiningRoom, "dining_area": DiningRoom,
+	"floorplan": Floorplan, "floor_plan": Floorplan, "floor_plans": Floorplan, "site_plan": Floorplan,
+}
+
+// Normalize maps a tag or label to a room type, or "" if it names none.
+func Normalize(label string) string {
+	key := strings.ToLower(strings.TrimSpace(label))
+	key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+	return aliases[key]
+}
+
+// Valid reports whether room is one of Rooms.
+func Valid(room string) bool {
+	for _, r := range Rooms {
+		if r == room {
+			return true
+		}
+	}
+	return false
+}
+
+// FromTags picks the room named by a photo's provider tags, falling back to
+// its title. Tags are ordered by the provider's confidence, so the first
+// recognised one wins.
+func FromTags(tags []string, title string) string {
+	for _, t := range tags {
+		if room := Normalize(t); room != "" {
+			return room
+		}
+	}
+	return Normalize(title)
+}
+
+// Classifier calls an external image classification endpoint. It POSTs
+// {"url": "<photo url>"} and expects {"label": "...", "score": 0..1}; labels
+// go through Normalize.
+type Classifier struct {
+	URL    string
+	Client *http.Client
+	// MinScore discards less confident labels; default 0.5.
+	MinScore float64
+}
+
+// Classify returns the room of the photo at href, or "" when the model is
+// unsure or names no known room.
+func (c *Classifier) Classify(ctx context.Context, href string) (string, error) {
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 20 * time.Second}
+	}
+	minScore := c.MinScore
+	if minScore <= 0 {
+		minScore = 0.5
+	}
+	body, err := json.Marshal(map[string]string{"url": href})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("classifier status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var out struct {
+		Label string  `json:"label"`
+		Score float64 `json:"score"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	if out.Score < minScore {
+		return "", nil
+	}
+	return Normalize(out.Label), nil
+}
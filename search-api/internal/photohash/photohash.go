@@ -0,0 +1,39 @@
+// Package photohash computes perceptual hashes of listing photos so the same
+// image served at different URLs, sizes or compression levels can be
+// recognised.
+package photohash
+
+import (
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Compute decodes a JPEG, PNG, GIF or WebP image and returns its 64-bit
+// difference hash: the image is reduced to 9x8 grayscale and each bit
+// records whether a pixel is brighter than its right-hand neighbour.
+// Re-encodes and resizes of one image almost always hash equal.
+func Compute(r io.Reader) (uint64, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return 0, err
+	}
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.BiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+	var h uint64
+	for y := 0; y < 8; y++ {
+		row := small.Pix[y*small.Stride:]
+		for x := 0; x < 8; x++ {
+			h <<= 1
+			if row[x] > row[x+1] {
+				h |= 1
+			}
+		}
+	}
+	return h, nil
+}
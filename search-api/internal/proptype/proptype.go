@@ -0,0 +1,73 @@
+// Package proptype normalizes property types. The provider, clients and
+// hydrator config spell them differently ("condos", "Condo", "townhomes",
+// "single family"), so every read and write path maps them onto one small
+// set before filtering or storing.
+package proptype
+
+import "strings"
+
+// Property types a listing can have.
+const (
+	SingleFamily = "single_family"
+	Condo        = "condo"
+	Townhome     = "townhome"
+	MultiFamily  = "multi_family"
+	Mobile       = "mobile"
+	Land         = "land"
+	Farm         = "farm"
+	// Other is stored for provider types the table doesn't know. It is not
+	// a filter value.
+	Other = "other"
+)
+
+// Types lists every filterable property type.
+var Types = []string{SingleFamily, Condo, Townhome, MultiFamily, Mobile, Land, Farm}
+
+// aliases maps normalized spellings to a type.
+var aliases = map[string]string{
+	"single_family": SingleFamily, "single_family_home": SingleFamily, "single_family_residence": SingleFamily,
+	"sfr": SingleFamily, "house": SingleFamily, "detached": SingleFamily,
+	"condo": Condo, "condos": Condo, "condominium": Condo, "condominiums": Condo, "coop": Condo, "co_op": Condo,
+	"townhome": Townhome, "townhomes": Townhome, "townhouse": Townhome, "townhouses": Townhome, "row_house": Townhome,
+	"multi_family": MultiFamily, "multifamily": MultiFamily, "duplex": MultiFamily, "triplex": MultiFamily,
+	"fourplex": MultiFamily, "duplex_triplex": MultiFamily,
+	"mobile": Mobile, "mobile_home": Mobile, "manufactured": Mobile, "manufactured_home": Mobile,
+	"land": Land, "lot": Land, "lots": Land, "lots_land": Land, "vacant_land": Land,
+	"farm": Farm, "farms_ranches": Farm, "ranch": Farm,
+}
+
+// providerValues are the provider's property_type filter values.
+var providerValues = map[string]string{
+	SingleFamily: "single_family",
+	Condo:        "condos",
+	Townhome:     "townhomes",
+	MultiFamily:  "multi_family",
+	Mobile:       "mobile",
+	Land:         "land",
+	Farm:         "farm",
+}
+
+// Normalize maps a spelling to a type, or "" if it names none.
+func Normalize(v string) string {
+	key := strings.ToLower(strings.TrimSpace(v))
+	key = strings.NewReplacer(" ", "_", "-", "_", "/", "_").Replace(key)
+	return aliases[key]
+}
+
+// ForStorage is Normalize for values read off a listing: unknown non-empty
+// types become Other rather than being lost.
+func ForStorage(v string) string {
+	if t := Normalize(v); t != "" {
+		return t
+	}
+	if strings.TrimSpace(v) == "" {
+		return ""
+	}
+	return Other
+}
+
+// Provider returns the provider's filter value for a type, or "" for types
+// it cannot filter on.
+func Provider(t string) string {
+	return providerValues[t]
+}
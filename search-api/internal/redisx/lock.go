@@ -0,0 +1,92 @@
+package redisx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Compare-and-delete / compare-and-expire so a holder whose lock already
+// expired can never release or extend someone else's.
+var (
+	unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Lock is a held distributed lock identified by a random ownership token.
+type Lock struct {
+	c     *Client
+	key   string
+	token string
+	ttl   time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// TryLock acquires key for ttl. It returns nil without error when another
+// holder owns the lock.
+func (c *Client) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(b[:])
+	ok, err := c.Rdb.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &Lock{c: c, key: key, token: token, ttl: ttl, stop: make(chan struct{})}, nil
+}
+
+// Unlock releases the lock if it is still ours and stops any keep-alive.
+// It reports whether the key was deleted.
+func (l *Lock) Unlock(ctx context.Context) (bool, error) {
+	l.stopKeepAlive()
+	n, err := unlockScript.Run(ctx, l.c.Rdb, []string{l.key}, l.token).Int()
+	return n == 1, err
+}
+
+// Extend resets the lock's TTL if it is still ours.
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) (bool, error) {
+	n, err := extendScript.Run(ctx, l.c.Rdb, []string{l.key}, l.token, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+// KeepAlive extends the lock every ttl/2 until Unlock is called or ctx ends,
+// for work that may outlive the initial TTL. It stops on the first failed
+// extension, since the lock has then been lost.
+func (l *Lock) KeepAlive(ctx context.Context) {
+	go func() {
+		t := time.NewTicker(l.ttl / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if ok, err := l.Extend(ctx, l.ttl); err != nil || !ok {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (l *Lock) stopKeepAlive() {
+	l.stopOnce.Do(func() { close(l.stop) })
+}
@@ -2,6 +2,7 @@ package redisx
 
 import (
     "context"
+    "errors"
     "time"
 
     "github.com/redis/go-redis/v9"
@@ -11,6 +12,7 @@ type Client struct { Rdb *redis.Client }
 
 func New(addr string, password string, db int) *Client {
     rdb := redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db})
+    rdb.AddHook(tracingHook{addr: addr})
     return &Client{Rdb: rdb}
 }
 
@@ -38,3 +40,62 @@ func (c *Client) TTL(ctx context.Context, key string) (time.Duration, error) {
 func (c *Client) SetNX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
     return c.Rdb.SetNX(ctx, key, val, ttl).Result()
 }
+
+// PushCapped prepends val to a list and trims it to maxLen entries.
+func (c *Client) PushCapped(ctx context.Context, key string, val string, maxLen int64) error {
+    pipe := c.Rdb.TxPipeline()
+    pipe.LPush(ctx, key, val)
+    if maxLen > 0 { pipe.LTrim(ctx, key, 0, maxLen-1) }
+    _, err := pipe.Exec(ctx)
+    return err
+}
+
+func (c *Client) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+    return c.Rdb.LRange(ctx, key, start, stop).Result()
+}
+
+func (c *Client) Publish(ctx context.Context, channel string, msg string) error {
+    return c.Rdb.Publish(ctx, channel, msg).Err()
+}
+
+// MGet fetches many keys in one round-trip. Missing keys come back as "".
+func (c *Client) MGet(ctx context.Context, keys ...string) ([]string, error) {
+    out := make([]string, len(keys))
+    if len(keys) == 0 { return out, nil }
+    vals, err := c.Rdb.MGet(ctx, keys...).Result()
+    if err != nil { return nil, err }
+    for i, v := range vals {
+        if s, ok := v.(string); ok { out[i] = s }
+    }
+    return out, nil
+}
+
+// Pipeline queues the commands fn issues and sends them in one round-trip.
+// A redis.Nil reply on a queued read is not treated as an error.
+func (c *Client) Pipeline(ctx context.Context, fn func(redis.Pipeliner) error) error {
+    _, err := c.Rdb.Pipelined(ctx, fn)
+    if errors.Is(err, redis.Nil) { return nil }
+    return err
+}
+
+// SetBatch writes every key in vals with the same TTL in one round-trip.
+func (c *Client) SetBatch(ctx context.Context, vals map[string]string, ttl time.Duration) error {
+    if len(vals) == 0 { return nil }
+    return c.Pipeline(ctx, func(p redis.Pipeliner) error {
+        for k, v := range vals { p.Set(ctx, k, v, ttl) }
+        return nil
+    })
+}
+
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+    return c.Rdb.Del(ctx, keys...).Err()
+}
+
+// Scan returns every key matching pattern. It walks the keyspace with SCAN,
+// so it is safe against a live server but not a point-in-time snapshot.
+func (c *Client) Scan(ctx context.Context, pattern string) ([]string, error) {
+    var keys []string
+    iter := c.Rdb.Scan(ctx, 0, pattern, 500).Iterator()
+    for iter.Next(ctx) { keys = append(keys, iter.Val()) }
+    return keys, iter.Err()
+}
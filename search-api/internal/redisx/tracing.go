@@ -0,0 +1,62 @@
+package redisx
+
+import (
+    "context"
+    "net"
+    "strings"
+
+    "github.com/redis/go-redis/v9"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/trace"
+
+    "github.com/yourorg/search-api/internal/tracing"
+)
+
+// tracingHook opens a client span per command and per pipeline. Keys and
+// values are left off the span; only command names are recorded.
+type tracingHook struct{ addr string }
+
+func (h tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        ctx, span := h.start(ctx, "redis dial")
+        conn, err := next(ctx, network, addr)
+        end(span, err)
+        return conn, err
+    }
+}
+
+func (h tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+    return func(ctx context.Context, cmd redis.Cmder) error {
+        ctx, span := h.start(ctx, "redis "+cmd.Name(), attribute.String("db.operation.name", cmd.Name()))
+        err := next(ctx, cmd)
+        end(span, err)
+        return err
+    }
+}
+
+func (h tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+    return func(ctx context.Context, cmds []redis.Cmder) error {
+        names := make([]string, len(cmds))
+        for i, c := range cmds { names[i] = c.Name() }
+        ctx, span := h.start(ctx, "redis pipeline",
+            attribute.Int("db.operation.batch.size", len(cmds)),
+            attribute.String("db.operation.name", strings.Join(names, " ")))
+        err := next(ctx, cmds)
+        end(span, err)
+        return err
+    }
+}
+
+func (h tracingHook) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+    attrs = append(attrs, attribute.String("db.system.name", "redis"), attribute.String("server.address", h.addr))
+    return tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+    if err != nil && err != redis.Nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+    span.End()
+}
@@ -0,0 +1,71 @@
+package refresh
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/yourorg/search-api/internal/redisx"
+)
+
+// DeadLetter records jobs the Refresher gave up on.
+type DeadLetter interface {
+	Put(ctx context.Context, j Job, err error, attempts int) error
+}
+
+// DeadJob is one dead-letter entry.
+type DeadJob struct {
+	Job      Job       `json:"job"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// RedisDeadLetter keeps the newest MaxLen failures in a Redis list.
+type RedisDeadLetter struct {
+	Redis  *redisx.Client
+	Key    string // default "refresh:dead"
+	MaxLen int64  // default 1000
+}
+
+func (d *RedisDeadLetter) key() string {
+	if d.Key == "" {
+		return "refresh:dead"
+	}
+	return d.Key
+}
+
+func (d *RedisDeadLetter) Put(ctx context.Context, j Job, err error, attempts int) error {
+	entry := DeadJob{Job: j, Attempts: attempts, FailedAt: time.Now().UTC()}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	b, mErr := json.Marshal(entry)
+	if mErr != nil {
+		return mErr
+	}
+	maxLen := d.MaxLen
+	if maxLen <= 0 {
+		maxLen = 1000
+	}
+	return d.Redis.PushCapped(ctx, d.key(), string(b), maxLen)
+}
+
+// List returns up to n of the most recent dead-lettered jobs.
+func (d *RedisDeadLetter) List(ctx context.Context, n int64) ([]DeadJob, error) {
+	if n <= 0 {
+		n = 100
+	}
+	vals, err := d.Redis.LRange(ctx, d.key(), 0, n-1)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DeadJob, 0, len(vals))
+	for _, v := range vals {
+		var dj DeadJob
+		if json.Unmarshal([]byte(v), &dj) == nil {
+			out = append(out, dj)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,110 @@
+package refresh
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/cache"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/hydrator"
+	"github.com/yourorg/search-api/internal/redisx"
+)
+
+const (
+	defaultProvider = "rapidapi.realtor16"
+	defaultEndpoint = "search/forsale"
+)
+
+// PropertyRefresher re-resolves a property through the provider's ZIP search
+// and rewrites its resolve cache entry. Its Refresh method is the Refresher's
+// Do function.
+type PropertyRefresher struct {
+	Rapid    *attom.Client
+	Redis    *redisx.Client
+	Hydrator *hydrator.Hydrator // optional write-behind
+	// CacheTTL and StaleAfter shape the rewritten envelope.
+	CacheTTL   time.Duration
+	StaleAfter time.Duration
+}
+
+// Refresh fetches and caches the property for j. It is idempotent: a job for
+// a property whose cache entry is already fresh again is skipped. Provider
+// quota and payload mapping failures are permanent; other errors are retried.
+func (p *PropertyRefresher) Refresh(ctx context.Context, j Job) error {
+	if j.PropertyKey == "" || j.Zip == "" {
+		return Permanent(errors.New("refresh job missing property key or zip"))
+	}
+	cacheKey := cache.PropertyKey(j.PropertyKey)
+	if p.fresh(ctx, cacheKey) {
+		return nil
+	}
+	raw, err := p.Rapid.SearchByPostal(ctx, j.Zip, 20, 1, "", "")
+	if err != nil {
+		if errors.Is(err, attom.ErrDailyLimitExceeded) {
+			log.Printf("[WARN] refetch skipped due to provider daily quota: %v", err)
+			return Permanent(err)
+		}
+		return err
+	}
+	cards, err := attom.MapSearchPayloadToCards(raw)
+	if err != nil {
+		return Permanent(err)
+	}
+	ln1q, cyq, stq, _, _ := canon.Canonicalize(j.Line1, j.City, j.State, j.Zip)
+	var (
+		found attom.PropertyCard
+		ok    bool
+	)
+	for _, c := range cards {
+		// match by canonicalized address
+		ln1, cy, st2, _, _ := canon.Canonicalize(c.Address, c.City, c.State, c.Zip)
+		if ln1 == ln1q && canon.CityKey(cy) == canon.CityKey(cyq) && st2 == stq {
+			found, ok = c, true
+			break
+		}
+	}
+	if !ok {
+		return nil
+	}
+
+	// Write back to Redis with SWR envelope
+	norm := cache.Normalized{Line1: j.Line1, City: j.City, State: j.State, Zip: j.Zip}
+	env := cache.New(found, "rapidapi", norm, orDefault(p.StaleAfter, 5*time.Minute), orDefault(p.CacheTTL, time.Hour))
+	if err := cache.Put(ctx, p.Redis, cacheKey, env); err != nil {
+		return err
+	}
+
+	// Optional write-behind
+	if p.Hydrator != nil {
+		provider, endpoint := j.Provider, j.Endpoint
+		if provider == "" {
+			provider = defaultProvider
+		}
+		if endpoint == "" {
+			endpoint = defaultEndpoint
+		}
+		norm := map[string]string{"line1": j.Line1, "city": j.City, "state": j.State, "zip": j.Zip, "property_key": j.PropertyKey}
+		_ = p.Hydrator.Write(ctx, provider, endpoint, raw, norm, found)
+	}
+	return nil
+}
+
+// fresh reports whether the cache entry was refreshed since the job was
+// queued, e.g. by a concurrent resolve.
+func (p *PropertyRefresher) fresh(ctx context.Context, cacheKey string) bool {
+	env, err := cache.Get(ctx, p.Redis, cacheKey)
+	if err != nil || env == nil {
+		return false
+	}
+	return !env.Meta.Stale(time.Now())
+}
+
+func orDefault(v, def time.Duration) time.Duration {
+	if v > 0 {
+		return v
+	}
+	return def
+}
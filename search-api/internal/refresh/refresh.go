@@ -1,53 +1,325 @@
 package refresh
 
 import (
-    "context"
-    "sync"
-    "time"
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Job kinds.
+const (
+	KindProperty = ""         // re-resolve one property's cache entry
+	KindListings = "listings" // refetch one cached listings page
+	KindZip      = "zip"      // re-crawl one ZIP into the store
+)
+
+// Job is a background refresh of one property, one listings page or one
+// ZIP. A property job carries the normalized address so workers can re-run
+// the provider search without a lookup; a ZIP job only sets Zip. Jobs are
+// keyed on Key: enqueueing a key that is already queued or running is a
+// no-op.
 type Job struct {
-    PropertyKey string
+	Kind        string `json:",omitempty"`
+	PropertyKey string
+	Line1       string
+	City        string
+	State       string
+	Zip         string
+	// Provider and Endpoint hint where the property came from; empty means
+	// the default search provider.
+	Provider string
+	Endpoint string
+	// Listings is the page to refetch for KindListings jobs.
+	Listings *ListingsPage `json:",omitempty"`
+}
+
+// ListingsPage identifies a cached provider listings page by the query that
+// produced it.
+type ListingsPage struct {
+	CacheKey     string
+	Location     string // provider location: a ZIP or "City, ST"
+	PageSize     int
+	Page         int
+	Beds         int
+	Baths        int
+	MinPrice     int
+	MaxPrice     int
+	PropertyType string
+	OrderBy      string
+	// PrimaryPhoto pages skip the per-listing photo fetch and carry only
+	// each listing's primary photo.
+	PrimaryPhoto bool `json:",omitempty"`
+	// Lead refreshes a page that goes stale within it rather than only one
+	// already stale; the Warmer sets it.
+	Lead time.Duration `json:",omitempty"`
+}
+
+// Key is the dedup key: the property key, the page's cache key or the ZIP.
+func (j Job) Key() string {
+	if j.Kind == KindListings && j.Listings != nil {
+		return j.Listings.CacheKey
+	}
+	if j.Kind == KindZip {
+		return "zip:" + j.Zip
+	}
+	return j.PropertyKey
+}
+
+// Options tunes a Refresher. Zero values take the defaults noted per field.
+type Options struct {
+	Capacity    int           // queue size, default 256
+	Workers     int           // default 2
+	MaxAttempts int           // attempts per job including the first, default 3
+	BaseBackoff time.Duration // first retry delay, doubled per attempt, default 1s
+	MaxBackoff  time.Duration // default 30s
+	JobTimeout  time.Duration // per attempt, default 15s
+	// DeadLetter receives jobs that exhausted their attempts or failed
+	// permanently. Optional.
+	DeadLetter DeadLetter
+}
+
+// Stats is a snapshot of the Refresher's counters.
+type Stats struct {
+	QueueDepth   int   `json:"queue_depth"`
+	InFlight     int64 `json:"in_flight"`
+	Enqueued     int64 `json:"enqueued"`
+	Deduplicated int64 `json:"deduplicated"`
+	Dropped      int64 `json:"dropped"`
+	Succeeded    int64 `json:"succeeded"`
+	Retried      int64 `json:"retried"`
+	DeadLettered int64 `json:"dead_lettered"`
+}
+
+// Queue is a refresh queue. Refresher keeps jobs in process memory;
+// StreamQueue shares them across replicas through Redis Streams.
+type Queue interface {
+	// Enqueue reports whether j is queued, counting a job with the same
+	// key already pending; false means it was dropped.
+	Enqueue(j Job) bool
+	Stop(ctx context.Context) error
+	Stats() Stats
+}
+
+// runner executes jobs with retries and dead-lettering and keeps the
+// counters both queue implementations report.
+type runner struct {
+	Do      func(ctx context.Context, j Job) error
+	opts    Options
+	baseCtx context.Context
+	cancel  context.CancelFunc
+
+	inFlight, enqueued, deduped, dropped, succeeded, retried, dead atomic.Int64
 }
 
 type Refresher struct {
-    ch    chan Job
-    inFly sync.Map // key -> struct{}
-    Do    func(ctx context.Context, j Job)
+	*runner
+	ch    chan Job
+	inFly sync.Map // key -> struct{}
+
+	mu     sync.RWMutex // guards closed against Enqueue racing Stop
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// New starts a Refresher with default retry settings.
+func New(capacity int, workerCount int, do func(ctx context.Context, j Job) error) *Refresher {
+	return NewWithOptions(Options{Capacity: capacity, Workers: workerCount}, do)
+}
+
+// NewWithOptions starts a Refresher and its workers.
+func NewWithOptions(opts Options, do func(ctx context.Context, j Job) error) *Refresher {
+	if opts.Capacity <= 0 {
+		opts.Capacity = 256
+	}
+	r := &Refresher{runner: newRunner(opts, do), ch: make(chan Job, opts.Capacity)}
+	for i := 0; i < r.opts.Workers; i++ {
+		r.wg.Add(1)
+		go r.worker()
+	}
+	return r
 }
 
-func New(capacity int, workerCount int, do func(ctx context.Context, j Job)) *Refresher {
-    if capacity <= 0 { capacity = 256 }
-    if workerCount <= 0 { workerCount = 2 }
-    r := &Refresher{ ch: make(chan Job, capacity), Do: do }
-    for i := 0; i < workerCount; i++ {
-        go r.worker()
-    }
-    return r
+func newRunner(opts Options, do func(ctx context.Context, j Job) error) *runner {
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.JobTimeout <= 0 {
+		opts.JobTimeout = 15 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &runner{Do: do, opts: opts, baseCtx: ctx, cancel: cancel}
 }
 
-func (r *Refresher) Enqueue(j Job) {
-    if _, exists := r.inFly.LoadOrStore(j.PropertyKey, struct{}{}); exists {
-        return
-    }
-    select {
-    case r.ch <- j:
-    default:
-        // drop if saturated
-        r.inFly.Delete(j.PropertyKey)
-    }
+// Enqueue queues j unless a job with the same key is already pending.
+// Jobs are dropped (and counted) when the queue is full or stopped.
+func (r *Refresher) Enqueue(j Job) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		r.dropped.Add(1)
+		return false
+	}
+	if _, exists := r.inFly.LoadOrStore(j.Key(), struct{}{}); exists {
+		r.deduped.Add(1)
+		return true
+	}
+	select {
+	case r.ch <- j:
+		r.enqueued.Add(1)
+		return true
+	default:
+		// drop if saturated
+		r.inFly.Delete(j.Key())
+		r.dropped.Add(1)
+		log.Printf("[WARN] refresh queue full; dropped %s", j.Key())
+		return false
+	}
+}
+
+// Stop stops accepting jobs and waits for queued ones to drain. If ctx ends
+// first, in-flight attempts are cancelled and ctx's error is returned.
+func (r *Refresher) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.ch)
+	}
+	r.mu.Unlock()
+
+	return r.drain(ctx, &r.wg)
+}
+
+// drain waits for wg, cancelling in-flight attempts if ctx ends first.
+func (r *runner) drain(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Stats returns the current counters.
+func (r *Refresher) Stats() Stats {
+	return r.stats(len(r.ch))
+}
+
+func (r *runner) stats(depth int) Stats {
+	return Stats{
+		QueueDepth:   depth,
+		InFlight:     r.inFlight.Load(),
+		Enqueued:     r.enqueued.Load(),
+		Deduplicated: r.deduped.Load(),
+		Dropped:      r.dropped.Load(),
+		Succeeded:    r.succeeded.Load(),
+		Retried:      r.retried.Load(),
+		DeadLettered: r.dead.Load(),
+	}
 }
 
 func (r *Refresher) worker() {
-    for j := range r.ch {
-        ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-        func() {
-            defer func() {
-                r.inFly.Delete(j.PropertyKey)
-                cancel()
-            }()
-            if r.Do != nil { r.Do(ctx, j) }
-        }()
-    }
+	defer r.wg.Done()
+	for j := range r.ch {
+		r.inFlight.Add(1)
+		r.run(j)
+		r.inFlight.Add(-1)
+		r.inFly.Delete(j.Key())
+	}
+}
+
+// run attempts j up to MaxAttempts times with exponential backoff and
+// jitter, dead-lettering it when every attempt fails.
+func (r *runner) run(j Job) {
+	if r.Do == nil {
+		return
+	}
+	var err error
+	attempt := 0
+	for attempt < r.opts.MaxAttempts {
+		attempt++
+		ctx, cancel := context.WithTimeout(r.baseCtx, r.opts.JobTimeout)
+		err = r.Do(ctx, j)
+		cancel()
+		if err == nil {
+			r.succeeded.Add(1)
+			return
+		}
+		if isPermanent(err) || attempt == r.opts.MaxAttempts || r.baseCtx.Err() != nil {
+			break
+		}
+		r.retried.Add(1)
+		if !r.sleep(r.backoff(attempt)) {
+			break
+		}
+	}
+	r.dead.Add(1)
+	log.Printf("[WARN] refresh %s failed after %d attempt(s): %v", j.Key(), attempt, err)
+	if r.opts.DeadLetter != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if dlErr := r.opts.DeadLetter.Put(ctx, j, err, attempt); dlErr != nil {
+			log.Printf("[WARN] refresh dead-letter write failed for %s: %v", j.Key(), dlErr)
+		}
+	}
+}
+
+func (r *runner) backoff(attempt int) time.Duration {
+	d := r.opts.BaseBackoff << (attempt - 1)
+	if d <= 0 || d > r.opts.MaxBackoff {
+		d = r.opts.MaxBackoff
+	}
+	// full jitter over the upper half keeps retries spread out
+	return d/2 + rand.N(d/2+1)
+}
+
+// sleep waits d unless the Refresher is force-stopped first.
+func (r *runner) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-r.baseCtx.Done():
+		return false
+	}
 }
 
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying; the job goes straight to the
+// dead-letter list.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
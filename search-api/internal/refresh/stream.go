@@ -0,0 +1,287 @@
+package refresh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yourorg/search-api/internal/redisx"
+)
+
+// StreamOptions tunes a StreamQueue. Retry settings come from Options;
+// Capacity is unused because the stream is the queue.
+type StreamOptions struct {
+	Options
+	Stream   string        // default "refresh:jobs"
+	Group    string        // consumer group shared by all replicas, default "refresh"
+	Consumer string        // this process's name in the group, default hostname-pid
+	MaxLen   int64         // approximate stream cap, default 10000
+	DedupTTL time.Duration // how long a pending key blocks re-enqueue, default 5m
+	// ClaimIdle is how long a delivered but unacknowledged job may sit before
+	// another consumer takes it over, e.g. after a replica crash. Default 2m.
+	ClaimIdle time.Duration
+}
+
+// StreamQueue is a refresh queue shared by every API replica. Enqueue sets a
+// per-job pending key before appending to the stream, so a stale key is
+// queued once cluster-wide, and the consumer group hands each entry to a
+// single replica. Entries left unacknowledged by a dead consumer are
+// reclaimed after ClaimIdle.
+type StreamQueue struct {
+	*runner
+	rdb  *redisx.Client
+	opts StreamOptions
+
+	readCtx    context.Context
+	stopReads  context.CancelFunc
+	claimed    chan redis.XMessage
+	wg         sync.WaitGroup
+	mu         sync.RWMutex
+	closed     bool
+	lastDepth  int
+	depthMu    sync.Mutex
+	depthAsked time.Time
+}
+
+// NewStreamQueue creates the consumer group if needed and starts workers.
+func NewStreamQueue(rdb *redisx.Client, opts StreamOptions, do func(ctx context.Context, j Job) error) (*StreamQueue, error) {
+	if opts.Stream == "" {
+		opts.Stream = "refresh:jobs"
+	}
+	if opts.Group == "" {
+		opts.Group = "refresh"
+	}
+	if opts.Consumer == "" {
+		host, _ := os.Hostname()
+		opts.Consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if opts.MaxLen <= 0 {
+		opts.MaxLen = 10000
+	}
+	if opts.DedupTTL <= 0 {
+		opts.DedupTTL = 5 * time.Minute
+	}
+	if opts.ClaimIdle <= 0 {
+		opts.ClaimIdle = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rdb.Rdb.XGroupCreateMkStream(ctx, opts.Stream, opts.Group, "$").Err(); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, err
+	}
+	q := &StreamQueue{
+		runner:  newRunner(opts.Options, do),
+		rdb:     rdb,
+		opts:    opts,
+		claimed: make(chan redis.XMessage, 64),
+	}
+	q.readCtx, q.stopReads = context.WithCancel(context.Background())
+	for i := 0; i < q.runner.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	q.wg.Add(1)
+	go q.reclaimLoop()
+	return q, nil
+}
+
+func (q *StreamQueue) pendingKey(jobKey string) string {
+	return q.opts.Stream + ":pending:" + jobKey
+}
+
+// Enqueue appends j to the stream unless its key is already pending anywhere
+// in the cluster.
+func (q *StreamQueue) Enqueue(j Job) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		q.dropped.Add(1)
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ok, err := q.rdb.SetNX(ctx, q.pendingKey(j.Key()), q.opts.Consumer, q.opts.DedupTTL)
+	if err != nil {
+		q.dropped.Add(1)
+		log.Printf("[WARN] refresh stream dedup failed for %s: %v", j.Key(), err)
+		return false
+	}
+	if !ok {
+		q.deduped.Add(1)
+		return true
+	}
+	b, _ := json.Marshal(j)
+	err = q.rdb.Rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.opts.Stream,
+		MaxLen: q.opts.MaxLen,
+		Approx: true,
+		Values: map[string]any{"job": string(b)},
+	}).Err()
+	if err != nil {
+		q.rdb.Rdb.Del(ctx, q.pendingKey(j.Key()))
+		q.dropped.Add(1)
+		log.Printf("[WARN] refresh stream enqueue failed for %s: %v", j.Key(), err)
+		return false
+	}
+	q.enqueued.Add(1)
+	return true
+}
+
+// Stop stops reading new entries and waits for in-flight jobs. Entries
+// already delivered but not finished stay pending and are reclaimed by
+// another replica.
+func (q *StreamQueue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.stopReads()
+	return q.drain(ctx, &q.wg)
+}
+
+// Stats reports counters for this replica. QueueDepth is the group's
+// cluster-wide lag, refreshed at most every few seconds.
+func (q *StreamQueue) Stats() Stats {
+	return q.stats(q.depth())
+}
+
+func (q *StreamQueue) depth() int {
+	q.depthMu.Lock()
+	defer q.depthMu.Unlock()
+	if time.Since(q.depthAsked) < 5*time.Second {
+		return q.lastDepth
+	}
+	q.depthAsked = time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	groups, err := q.rdb.Rdb.XInfoGroups(ctx, q.opts.Stream).Result()
+	if err != nil {
+		return q.lastDepth
+	}
+	for _, g := range groups {
+		if g.Name == q.opts.Group {
+			q.lastDepth = int(g.Lag + g.Pending)
+		}
+	}
+	return q.lastDepth
+}
+
+func (q *StreamQueue) worker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case msg := <-q.claimed:
+			q.handle(msg)
+			continue
+		default:
+		}
+		if q.readCtx.Err() != nil {
+			return
+		}
+		streams, err := q.rdb.Rdb.XReadGroup(q.readCtx, &redis.XReadGroupArgs{
+			Group:    q.opts.Group,
+			Consumer: q.opts.Consumer,
+			Streams:  []string{q.opts.Stream, ">"},
+			Count:    1,
+			// Short blocks keep Stop responsive; a blocked read is not
+			// interrupted by cancelling readCtx.
+			Block: 2 * time.Second,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || q.readCtx.Err() != nil {
+				continue
+			}
+			log.Printf("[WARN] refresh stream read: %v", err)
+			if !q.sleepRead(time.Second) {
+				return
+			}
+			continue
+		}
+		for _, st := range streams {
+			for _, msg := range st.Messages {
+				q.handle(msg)
+			}
+		}
+	}
+}
+
+// handle runs one entry, then acknowledges it and clears its pending key so
+// the property can be queued again.
+func (q *StreamQueue) handle(msg redis.XMessage) {
+	var j Job
+	raw, _ := msg.Values["job"].(string)
+	if err := json.Unmarshal([]byte(raw), &j); err != nil {
+		log.Printf("[WARN] refresh stream dropping malformed entry %s: %v", msg.ID, err)
+	} else {
+		q.inFlight.Add(1)
+		q.run(j)
+		q.inFlight.Add(-1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := q.rdb.Rdb.XAck(ctx, q.opts.Stream, q.opts.Group, msg.ID).Err(); err != nil {
+		log.Printf("[WARN] refresh stream ack %s: %v", msg.ID, err)
+	}
+	if j.Key() != "" {
+		q.rdb.Rdb.Del(ctx, q.pendingKey(j.Key()))
+	}
+}
+
+// reclaimLoop takes over entries another consumer left idle past ClaimIdle.
+func (q *StreamQueue) reclaimLoop() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.opts.ClaimIdle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.readCtx.Done():
+			return
+		case <-ticker.C:
+		}
+		start := "0-0"
+		for {
+			msgs, next, err := q.rdb.Rdb.XAutoClaim(q.readCtx, &redis.XAutoClaimArgs{
+				Stream:   q.opts.Stream,
+				Group:    q.opts.Group,
+				Consumer: q.opts.Consumer,
+				MinIdle:  q.opts.ClaimIdle,
+				Start:    start,
+				Count:    50,
+			}).Result()
+			if err != nil {
+				if q.readCtx.Err() == nil {
+					log.Printf("[WARN] refresh stream reclaim: %v", err)
+				}
+				break
+			}
+			for _, msg := range msgs {
+				select {
+				case q.claimed <- msg:
+				case <-q.readCtx.Done():
+					return
+				}
+			}
+			if next == "0-0" || len(msgs) == 0 {
+				break
+			}
+			start = next
+		}
+	}
+}
+
+func (q *StreamQueue) sleepRead(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-q.readCtx.Done():
+		return false
+	}
+}
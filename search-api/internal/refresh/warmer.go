@@ -0,0 +1,168 @@
+package refresh
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yourorg/search-api/internal/cache"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/redisx"
+)
+
+// Warmer keeps the busiest ZIPs' provider listing pages fresh. Record counts
+// page requests per ZIP in Redis, so every replica's traffic is ranked
+// together; Run takes the top ZIPs on each tick and queues a refresh for each
+// of their pages that goes stale within Lead. Popular pages are then
+// refetched just before stale_after instead of by the first request after it.
+type Warmer struct {
+	Redis   *redisx.Client
+	Enqueue func(j Job) bool
+	// TopN ZIPs are kept warm, default 20. Demand is counted over Window,
+	// default 1h, in fixed buckets; the current and previous bucket are
+	// ranked together so a new bucket doesn't start from zero.
+	TopN   int
+	Window time.Duration
+	// Lead is how long before stale_after a page is refreshed, default 1m.
+	// Run ticks every Interval, default 30s, which should stay below Lead.
+	Lead     time.Duration
+	Interval time.Duration
+	// PagesPerZip caps the page variants warmed per ZIP, most requested
+	// first, default 5.
+	PagesPerZip int
+}
+
+const (
+	warmZipsPrefix  = "warm:zips:"
+	warmPagesPrefix = "warm:pages:"
+	warmTopKey      = "warm:top"
+	warmLockKey     = "warm:lock"
+	// warmPagesKept bounds each ZIP's page set; the least requested
+	// variants are trimmed.
+	warmPagesKept = 50
+)
+
+func (w *Warmer) topN() int             { return intOr(w.TopN, 20) }
+func (w *Warmer) window() time.Duration { return orDefault(w.Window, time.Hour) }
+func (w *Warmer) lead() time.Duration   { return orDefault(w.Lead, time.Minute) }
+func (w *Warmer) pagesPerZip() int      { return intOr(w.PagesPerZip, 5) }
+
+func (w *Warmer) interval() time.Duration {
+	return orDefault(w.Interval, 30*time.Second)
+}
+
+func (w *Warmer) bucketKey(t time.Time) string {
+	return warmZipsPrefix + strconv.FormatInt(t.Unix()/int64(w.window().Seconds()), 10)
+}
+
+// Record counts one request for a provider page. Pages for a city rather
+// than a ZIP are not tracked. It writes in the background and never blocks
+// the request.
+func (w *Warmer) Record(lp ListingsPage) {
+	if w == nil || w.Redis == nil || lp.CacheKey == "" || !canon.IsZIP(lp.Location) {
+		return
+	}
+	lp.Lead = 0
+	b, err := json.Marshal(lp)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		bucket, pages := w.bucketKey(time.Now()), warmPagesPrefix+lp.Location
+		err := w.Redis.Pipeline(ctx, func(p redis.Pipeliner) error {
+			p.ZIncrBy(ctx, bucket, 1, lp.Location)
+			p.Expire(ctx, bucket, 2*w.window())
+			p.ZIncrBy(ctx, pages, 1, string(b))
+			p.ZRemRangeByRank(ctx, pages, 0, -warmPagesKept-1)
+			p.Expire(ctx, pages, 2*w.window())
+			return nil
+		})
+		if err != nil {
+			log.Printf("[WARN] warm demand write for %s failed: %v", lp.Location, err)
+		}
+	}()
+}
+
+// Run warms pages every Interval until ctx is done. With several replicas
+// only the one holding the tick's lock warms.
+func (w *Warmer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tctx, cancel := context.WithTimeout(ctx, w.interval())
+			if n, err := w.Warm(tctx); err != nil {
+				log.Printf("[WARN] cache warm failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[INFO] cache warm queued %d page(s)", n)
+			}
+			cancel()
+		}
+	}
+}
+
+// Warm runs one pass and returns how many refreshes it queued. It returns
+// 0 without error when another replica holds this tick's lock.
+func (w *Warmer) Warm(ctx context.Context) (int, error) {
+	lock, err := w.Redis.TryLock(ctx, warmLockKey, w.interval()*9/10)
+	if err != nil || lock == nil {
+		return 0, err
+	}
+	// The lock is left to expire so no other replica warms this tick.
+	zips, err := w.TopZips(ctx)
+	if err != nil {
+		return 0, err
+	}
+	deadline := time.Now().Add(w.lead())
+	queued := 0
+	for _, zip := range zips {
+		members, err := w.Redis.Rdb.ZRevRange(ctx, warmPagesPrefix+zip, 0, int64(w.pagesPerZip()-1)).Result()
+		if err != nil {
+			return queued, err
+		}
+		for _, m := range members {
+			var lp ListingsPage
+			if json.Unmarshal([]byte(m), &lp) != nil || lp.CacheKey == "" {
+				continue
+			}
+			if page, err := cache.GetPage(ctx, w.Redis, lp.CacheKey); err == nil && page != nil && !page.Meta.Stale(deadline) {
+				continue
+			}
+			lp.Lead = w.lead()
+			w.Enqueue(Job{Kind: KindListings, Listings: &lp})
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// TopZips returns the most requested ZIPs over the current and previous
+// bucket, busiest first.
+func (w *Warmer) TopZips(ctx context.Context) ([]string, error) {
+	now := time.Now()
+	var top *redis.StringSliceCmd
+	err := w.Redis.Pipeline(ctx, func(p redis.Pipeliner) error {
+		p.ZUnionStore(ctx, warmTopKey, &redis.ZStore{Keys: []string{w.bucketKey(now), w.bucketKey(now.Add(-w.window()))}})
+		top = p.ZRevRange(ctx, warmTopKey, 0, int64(w.topN()-1))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return top.Val(), nil
+}
+
+func intOr(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
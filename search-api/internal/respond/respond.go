@@ -0,0 +1,248 @@
+// Package respond writes handler payloads in the encoding the client asked
+// for with Accept: JSON by default, MessagePack for application/x-msgpack and,
+// on endpoints that return rows of listings, CSV for text/csv.
+//
+// MessagePack bodies are the JSON document re-encoded, so field names,
+// omitted fields and timestamp formats are identical across encodings.
+package respond
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	ContentTypeMsgPack = "application/x-msgpack"
+	ContentTypeCSV     = "text/csv"
+)
+
+// Format is a response encoding.
+type Format int
+
+const (
+	JSON Format = iota
+	MsgPack
+	CSV
+)
+
+var mediaFormats = map[string]Format{
+	"application/json":        JSON,
+	"application/*":           JSON,
+	"*/*":                     JSON,
+	"application/x-msgpack":   MsgPack,
+	"application/msgpack":     MsgPack,
+	"application/vnd.msgpack": MsgPack,
+	"text/csv":                CSV,
+}
+
+// Negotiate picks the client's most preferred format from the Accept header.
+// CSV is only considered when allowCSV is set; anything unrecognised falls
+// back to JSON.
+func Negotiate(req *http.Request, allowCSV bool) Format {
+	best, bestQ := JSON, 0.0
+	for _, part := range strings.Split(req.Header.Get("Accept"), ",") {
+		media, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		f, ok := mediaFormats[strings.ToLower(strings.TrimSpace(media))]
+		if !ok || (f == CSV && !allowCSV) {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && strings.EqualFold(k, "q") {
+				if n, err := strconv.ParseFloat(v, 64); err == nil {
+					q = n
+				}
+			}
+		}
+		if q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+	return best
+}
+
+// Write encodes v as JSON or MessagePack.
+func Write(w http.ResponseWriter, req *http.Request, v any) {
+	w.Header().Add("Vary", "Accept")
+	if Negotiate(req, false) == MsgPack {
+		writeMsgPack(w, req, v)
+		return
+	}
+	render.JSON(w, req, v)
+}
+
+// Rows is Write for payloads carrying listings under key, a slice of objects
+// or a single object. With text/csv only those are written, one row each,
+// with a header of their field names.
+func Rows(w http.ResponseWriter, req *http.Request, v map[string]any, key string) {
+	w.Header().Add("Vary", "Accept")
+	switch Negotiate(req, true) {
+	case MsgPack:
+		writeMsgPack(w, req, v)
+	case CSV:
+		writeCSV(w, req, v[key])
+	default:
+		render.JSON(w, req, v)
+	}
+}
+
+func writeMsgPack(w http.ResponseWriter, req *http.Request, v any) {
+	doc, err := jsonDocument(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if err := msgpack.NewEncoder(&buf).Encode(doc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentTypeMsgPack)
+	if status, ok := req.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	_, _ = w.Write(buf.Bytes())
+}
+
+// jsonDocument round-trips v through JSON, keeping integers as integers.
+func jsonDocument(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return numbers(doc), nil
+}
+
+func numbers(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		for k, e := range t {
+			t[k] = numbers(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = numbers(e)
+		}
+	}
+	return v
+}
+
+func writeCSV(w http.ResponseWriter, req *http.Request, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var raws []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		_ = json.Unmarshal(b, &raws)
+	} else if string(b) != "null" {
+		raws = []json.RawMessage{b}
+	}
+	var header []string
+	seen := map[string]bool{}
+	rows := make([]map[string]json.RawMessage, 0, len(raws))
+	for _, raw := range raws {
+		keys, err := objectKeys(raw)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, k := range keys {
+			if !seen[k] {
+				seen[k] = true
+				header = append(header, k)
+			}
+		}
+		var row map[string]json.RawMessage
+		_ = json.Unmarshal(raw, &row)
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	if len(header) > 0 {
+		_ = cw.Write(header)
+	}
+	record := make([]string, len(header))
+	for _, row := range rows {
+		for i, k := range header {
+			record[i] = cell(row[k])
+		}
+		_ = cw.Write(record)
+	}
+	cw.Flush()
+
+	w.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+	if status, ok := req.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	_, _ = w.Write(buf.Bytes())
+}
+
+// objectKeys returns the keys of a JSON object in document order, so CSV
+// columns follow the struct field order rather than the alphabet. Shaped
+// cards are maps, which encoding/json already writes sorted.
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, t.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// cell flattens one JSON value: strings unquoted, null empty, arrays of
+// scalars joined with "|" as in the export endpoint, and anything nested
+// left as compact JSON.
+func cell(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		parts := make([]string, 0, len(list))
+		for _, e := range list {
+			if c := bytes.TrimSpace(e); len(c) > 0 && (c[0] == '{' || c[0] == '[') {
+				return string(raw)
+			}
+			parts = append(parts, cell(e))
+		}
+		return strings.Join(parts, "|")
+	}
+	return string(raw)
+}
@@ -3,27 +3,38 @@ package search
 import (
     "context"
     "log"
-    "time"
 
     "github.com/yourorg/search-api/internal/events"
 )
 
-// Indexer is a stub that consumes property.updated events and logs them.
+// Indexer is a stub that consumes events and logs them.
 // Swap this with a real OpenSearch client later.
 type Indexer struct {
     Pub events.Publisher
 }
 
 func (i *Indexer) Run(ctx context.Context) {
-    sub := i.Pub.SubscribePropertyUpdated()
+    sub, unsubscribe := i.Pub.Subscribe("indexer")
+    defer unsubscribe()
     for {
         select {
         case <-ctx.Done():
             return
-        case evt := <-sub:
+        case evt, ok := <-sub:
+            if !ok {
+                return
+            }
             // TODO: map and upsert into OpenSearch
-            log.Printf("indexer: property.updated id=%s key=%s at=%s", evt.PropertyID, evt.PropertyKey, time.Now().Format(time.RFC3339))
+            switch p := evt.Payload.(type) {
+            case events.PropertyUpdated:
+                log.Printf("indexer: %s id=%s key=%s at=%s", evt.Type, p.PropertyID, p.PropertyKey, evt.OccurredAt.Format("2006-01-02T15:04:05Z07:00"))
+            case events.ListingPriceChanged:
+                log.Printf("indexer: %s listing=%s key=%s", evt.Type, p.ListingID, p.PropertyKey)
+            case events.ListingStatusChanged:
+                log.Printf("indexer: %s listing=%s key=%s %s->%s", evt.Type, p.ListingID, p.PropertyKey, p.OldStatus, p.NewStatus)
+            case events.PhotoSetChanged:
+                log.Printf("indexer: %s listing=%s key=%s +%d -%d", evt.Type, p.ListingID, p.PropertyKey, p.Added, p.Removed)
+            }
         }
     }
 }
-
@@ -0,0 +1,243 @@
+// Package shadow vets a candidate listing provider on live traffic before it
+// is switched in as the primary. A sample of provider searches is sent to the
+// candidate in parallel; the primary's answer is always the one served, and
+// both answers are compared and kept in provider_shadow_results for the
+// /v1/admin/shadow report.
+//
+// Switching is blue/green: once the report shows the candidate agrees, point
+// PROVIDER_BASE_URL and PROVIDER_HOST at it, and the old provider can be
+// shadowed in turn until it is retired.
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/proptype"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultMaxInFlight = 8
+	// primaryWait bounds the wait for the route's answer, past the
+	// handler deadline.
+	primaryWait = time.Minute
+)
+
+// Provider sends Percent of searches to a candidate provider. A nil
+// Provider shadows nothing.
+type Provider struct {
+	// Name identifies the candidate in the report, default "candidate".
+	Name   string
+	Client *attom.Client
+	Store  *store.Store
+	// Percent of searches to shadow, 0 to 100.
+	Percent int
+	// Timeout bounds a candidate call, default 10s. MaxInFlight caps
+	// concurrent candidate calls, default 8; searches past it are not
+	// shadowed.
+	Timeout     time.Duration
+	MaxInFlight int
+
+	inFlight                                   atomic.Int64
+	sampled, skipped, recorded, failed, agreed atomic.Int64
+}
+
+// Stats counts shadowed searches since start.
+type Stats struct {
+	Sampled  int64 `json:"sampled"`
+	Skipped  int64 `json:"skipped"` // over MaxInFlight
+	Recorded int64 `json:"recorded"`
+	Agreed   int64 `json:"agreed"`
+	Failed   int64 `json:"failed"` // comparisons that could not be saved
+	InFlight int64 `json:"in_flight"`
+}
+
+// Stats returns p's counts; the server publishes them as the shadow
+// expvar.
+func (p *Provider) Stats() Stats {
+	if p == nil {
+		return Stats{}
+	}
+	return Stats{
+		Sampled:  p.sampled.Load(),
+		Skipped:  p.skipped.Load(),
+		Recorded: p.recorded.Load(),
+		Agreed:   p.agreed.Load(),
+		Failed:   p.failed.Load(),
+		InFlight: p.inFlight.Load(),
+	}
+}
+
+// Search is one provider search as a route issues it.
+type Search struct {
+	Route    string // "search" or "listings"
+	Location string
+	Params   map[string]any
+	// Fetch makes the route's provider call with c and maps the answer.
+	Fetch func(ctx context.Context, c *attom.Client) ([]attom.PropertyCard, error)
+}
+
+// Begin starts s against the candidate when it is sampled. The route calls
+// the returned func once with the primary's answer; the comparison is
+// saved when both have answered. The candidate call runs outside the
+// request, so it neither delays the response nor spends the caller's
+// budget.
+func (p *Provider) Begin(s Search) func(primary []attom.PropertyCard, err error) {
+	if p == nil || p.Client == nil || p.Store == nil || p.Percent <= 0 || rand.IntN(100) >= p.Percent {
+		return func([]attom.PropertyCard, error) {}
+	}
+	max := p.MaxInFlight
+	if max <= 0 {
+		max = defaultMaxInFlight
+	}
+	if p.inFlight.Add(1) > int64(max) {
+		p.inFlight.Add(-1)
+		p.skipped.Add(1)
+		return func([]attom.PropertyCard, error) {}
+	}
+	p.sampled.Add(1)
+
+	type answer struct {
+		cards []attom.PropertyCard
+		err   error
+	}
+	primary := make(chan answer, 1)
+	go func() {
+		defer p.inFlight.Add(-1)
+		timeout := p.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		cards, err := s.Fetch(ctx, p.Client)
+		took := time.Since(start)
+		cancel()
+
+		var a answer
+		select {
+		case a = <-primary:
+		case <-time.After(primaryWait):
+			// The route never reported its answer.
+			return
+		}
+		r := Compare(a.cards, cards)
+		r.Candidate = p.name()
+		r.Route = s.Route
+		r.Location = s.Location
+		r.Params, _ = json.Marshal(s.Params)
+		r.CandidateMs = int(took.Milliseconds())
+		if a.err != nil {
+			r.PrimaryError = a.err.Error()
+		}
+		if err != nil {
+			r.CandidateError = err.Error()
+		}
+		if err := p.Store.AddShadowResult(context.Background(), r); err != nil {
+			p.failed.Add(1)
+			log.Printf("[WARN] shadow %s: unable to save comparison for %s: %v", r.Candidate, s.Location, err)
+			return
+		}
+		p.recorded.Add(1)
+		if r.Agrees() {
+			p.agreed.Add(1)
+		}
+	}()
+	return func(cards []attom.PropertyCard, err error) {
+		select {
+		case primary <- answer{cards, err}:
+		default:
+		}
+	}
+}
+
+func (p *Provider) name() string {
+	if p.Name == "" {
+		return "candidate"
+	}
+	return p.Name
+}
+
+// Compare matches the two answers' listings by property key and lists the
+// listings only one returned and the fields the matches disagree on. Both
+// answers are kept, without photos or remarks.
+func Compare(primary, candidate []attom.PropertyCard) store.ShadowResult {
+	r := store.ShadowResult{PrimaryCount: len(primary), CandidateCount: len(candidate)}
+	r.PrimaryCards, _ = json.Marshal(compact(primary))
+	r.CandidateCards, _ = json.Marshal(compact(candidate))
+
+	byKey := make(map[string]attom.PropertyCard, len(candidate))
+	for _, c := range candidate {
+		byKey[cardKey(c)] = c
+	}
+	seen := make(map[string]bool, len(primary))
+	for _, pc := range primary {
+		k := cardKey(pc)
+		seen[k] = true
+		cc, ok := byKey[k]
+		if !ok {
+			r.OnlyPrimary = append(r.OnlyPrimary, k)
+			continue
+		}
+		r.Matched++
+		r.Mismatches = append(r.Mismatches, diff(k, pc, cc)...)
+	}
+	for _, c := range candidate {
+		if k := cardKey(c); !seen[k] {
+			r.OnlyCandidate = append(r.OnlyCandidate, k)
+		}
+	}
+	return r
+}
+
+// cardKey identifies a listing across providers by its address, falling
+// back to the provider's IDs when the address doesn't canonicalize.
+func cardKey(c attom.PropertyCard) string {
+	if _, _, _, _, pk := canon.Canonicalize(c.Address, c.City, c.State, c.Zip); pk != "" {
+		return pk
+	}
+	if c.ListingID != "" {
+		return c.ListingID
+	}
+	return c.ID
+}
+
+func diff(key string, p, c attom.PropertyCard) []store.ShadowMismatch {
+	var out []store.ShadowMismatch
+	for _, f := range []struct {
+		name string
+		p, c int
+	}{
+		{"price", p.Price, c.Price},
+		{"beds", p.Beds, c.Beds},
+		{"baths", p.Baths, c.Baths},
+		{"sqft", p.Sqft, c.Sqft},
+		{"yearBuilt", p.YearBuilt, c.YearBuilt},
+	} {
+		if f.p != f.c {
+			out = append(out, store.ShadowMismatch{Key: key, Field: f.name, Primary: strconv.Itoa(f.p), Candidate: strconv.Itoa(f.c)})
+		}
+	}
+	if pt, ct := proptype.Normalize(p.Type), proptype.Normalize(c.Type); pt != ct {
+		out = append(out, store.ShadowMismatch{Key: key, Field: "type", Primary: p.Type, Candidate: c.Type})
+	}
+	return out
+}
+
+func compact(cards []attom.PropertyCard) []attom.PropertyCard {
+	out := make([]attom.PropertyCard, len(cards))
+	for i, c := range cards {
+		c.Images, c.Description, c.Highlight = nil, "", ""
+		out[i] = c
+	}
+	return out
+}
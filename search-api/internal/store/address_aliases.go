@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourorg/search-api/internal/canon"
+)
+
+// address_aliases holds two kinds of alias. A key alias is a property key
+// computed under other canon rules than the property's own, left by a rekey
+// or by a resolve that found the property by address. An address alias is
+// an address as a provider or caller gave it, folded by canon.AddressKey,
+// which no rules change affects.
+
+// aliasedPropertyID selects the property the key in $1 was merged into or
+// is a key alias of.
+const aliasedPropertyID = `(SELECT property_id FROM property_aliases WHERE alias_key = $1
+	UNION ALL SELECT property_id FROM address_aliases WHERE kind = 'key' AND alias_key = $1
+	LIMIT 1)`
+
+// aliasedBatchPropertyID is aliasedPropertyID for the key column k.key of a
+// batch lookup over unnest(...) AS k(key).
+const aliasedBatchPropertyID = `(SELECT property_id FROM property_aliases WHERE alias_key = k.key
+	UNION ALL SELECT property_id FROM address_aliases WHERE kind = 'key' AND alias_key = k.key
+	LIMIT 1)`
+
+// PropertyKeyByAddress returns the key of the property an address alias
+// points at, or "" when the address was never seen.
+func (s *Store) PropertyKeyByAddress(ctx context.Context, addressKey string) (string, error) {
+	if s.Pool == nil {
+		return "", errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var key string
+	err := s.queryRowRead(ctx, `
+		SELECT p.property_key
+		FROM address_aliases a
+		JOIN ingest_properties p ON p.id = a.property_id
+		WHERE a.kind = 'address' AND a.alias_key = $1 AND p.deleted_at IS NULL
+	`, []any{addressKey}, &key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return key, err
+}
+
+// AddKeyAlias makes key, computed under the given rules version, an alias
+// of the property propertyKey names. A key that is itself a property's is
+// left alone.
+func (s *Store) AddKeyAlias(ctx context.Context, key, propertyKey string, version int) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO address_aliases (kind, alias_key, property_id, key_version)
+		SELECT 'key', $1, id, $3 FROM ingest_properties
+		WHERE property_key = $2 AND deleted_at IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM ingest_properties WHERE property_key = $1)
+		ON CONFLICT (kind, alias_key) DO UPDATE SET property_id = EXCLUDED.property_id, key_version = EXCLUDED.key_version
+	`, key, propertyKey, version)
+	return err
+}
+
+// Rekey outcomes.
+const (
+	RekeyUnchanged = "unchanged"
+	RekeyRenamed   = "renamed"
+	RekeyMerged    = "merged"
+)
+
+// Rekey is one property moved to the key the current canon rules give it.
+type Rekey struct {
+	OldKey     string
+	NewKey     string
+	OldVersion int
+	Outcome    string
+}
+
+// StaleKeys returns up to limit keys, after the key after, of properties
+// whose keys were computed under canon rules older than version.
+func (s *Store) StaleKeys(ctx context.Context, version int, after string, limit int) ([]string, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT property_key FROM ingest_properties
+		WHERE key_version < $1 AND property_key > $2 AND deleted_at IS NULL
+		ORDER BY property_key
+		LIMIT $3
+	`, version, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// RekeyProperty recomputes key's property key from its stored address
+// with the current canon rules. When the key is unchanged only the version
+// is bumped. Otherwise the property is renamed and the old key kept as a
+// key alias, or, when another property already has the new key, merged
+// into it, which leaves the old key as a merge alias. Either way the old
+// key keeps resolving.
+func (s *Store) RekeyProperty(ctx context.Context, key, actor string) (Rekey, error) {
+	r := Rekey{OldKey: key}
+	if s.Pool == nil {
+		return r, errors.New("nil db")
+	}
+	version := canon.KeyVersion()
+	qctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tx, err := s.Pool.Begin(qctx)
+	if err != nil {
+		return r, err
+	}
+	defer func() { _ = tx.Rollback(qctx) }()
+	if err = setAuditActorTx(qctx, tx); err != nil {
+		return r, err
+	}
+
+	var id, line1, city, state, zip string
+	err = tx.QueryRow(qctx, `
+		SELECT id, address_line1, city, state, zip, key_version FROM ingest_properties
+		WHERE property_key = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, key).Scan(&id, &line1, &city, &state, &zip, &r.OldVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r, fmt.Errorf("%w: %s", ErrPropertyNotFound, key)
+	}
+	if err != nil {
+		return r, err
+	}
+	n1, _, _, _, newKey := canon.Canonicalize(line1, city, state, zip)
+	r.NewKey = newKey
+	if newKey == "" {
+		return r, fmt.Errorf("rekey %s: stored address no longer canonicalizes", key)
+	}
+
+	if newKey == key {
+		r.Outcome = RekeyUnchanged
+		_, err = tx.Exec(qctx, `UPDATE ingest_properties SET address_line1 = $2, key_version = $3, updated_at = now() WHERE id = $1`, id, n1, version)
+		if err != nil {
+			return r, err
+		}
+		return r, tx.Commit(qctx)
+	}
+
+	var other string
+	err = tx.QueryRow(qctx, `SELECT id FROM ingest_properties WHERE property_key = $1`, newKey).Scan(&other)
+	switch {
+	case err == nil:
+		// The new rules fold this address into a property that already
+		// exists; merge it there.
+		_ = tx.Rollback(qctx)
+		cancel()
+		if _, err := s.MergeProperties(ctx, newKey, key, actor, fmt.Sprintf("rekey to canon rules version %d", version)); err != nil {
+			return r, err
+		}
+		r.Outcome = RekeyMerged
+		return r, nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return r, err
+	}
+
+	r.Outcome = RekeyRenamed
+	if _, err = tx.Exec(qctx, `
+		UPDATE ingest_properties SET property_key = $2, address_line1 = $3, key_version = $4, updated_at = now()
+		WHERE id = $1
+	`, id, newKey, n1, version); err != nil {
+		return r, err
+	}
+	if _, err = tx.Exec(qctx, `
+		INSERT INTO address_aliases (kind, alias_key, property_id, key_version) VALUES ('key', $1, $2, $3)
+		ON CONFLICT (kind, alias_key) DO UPDATE SET property_id = EXCLUDED.property_id, key_version = EXCLUDED.key_version
+	`, key, id, r.OldVersion); err != nil {
+		return r, err
+	}
+	// The new key may have been left as an alias by an earlier rekey.
+	if _, err = tx.Exec(qctx, `DELETE FROM address_aliases WHERE kind = 'key' AND alias_key = $1`, newKey); err != nil {
+		return r, err
+	}
+	if _, err = tx.Exec(qctx, `
+		UPDATE notes SET subject_id = $2 WHERE subject_kind = 'property' AND subject_id = $1
+	`, key, newKey); err != nil {
+		return r, err
+	}
+	if err = refreshListingSummariesTx(qctx, tx, []string{id}, nil); err != nil {
+		return r, err
+	}
+	return r, tx.Commit(qctx)
+}
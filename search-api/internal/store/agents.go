@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ListingAgent is an advertiser attached to a listing. It is used both as
+// upsert input and as the read model returned with listing details.
+type ListingAgent struct {
+	SourceID   string `json:"id,omitempty"`
+	Role       string `json:"role,omitempty"`
+	Name       string `json:"name"`
+	Email      string `json:"email,omitempty"`
+	Phone      string `json:"phone,omitempty"`
+	OfficeID   string `json:"officeId,omitempty"`
+	OfficeName string `json:"officeName,omitempty"`
+	BrokerName string `json:"brokerName,omitempty"`
+}
+
+// agentKey identifies an agent within a provider. Provider IDs win; otherwise
+// the name plus office is the best stable identity we have.
+func agentKey(a ListingAgent) string {
+	if a.SourceID != "" {
+		return "id:" + a.SourceID
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(a.Name)) + "|" + strings.ToLower(strings.TrimSpace(a.OfficeName))
+}
+
+// agentsJSON renders the denormalized ingest_listings.agents column.
+func agentsJSON(agents []ListingAgent) any {
+	if len(agents) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(agents)
+	if err != nil {
+		return nil
+	}
+	return json.RawMessage(b)
+}
+
+// syncListingAgentsTx upserts agents into ingest_agents and replaces the
+// listing's links in ingest_listing_agents.
+func syncListingAgentsTx(ctx context.Context, tx pgx.Tx, provider, listingUUID string, agents []ListingAgent) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM ingest_listing_agents WHERE listing_id=$1`, listingUUID)
+	for idx, a := range agents {
+		if strings.TrimSpace(a.Name) == "" {
+			continue
+		}
+		batch.Queue(`
+			WITH ag AS (
+				INSERT INTO ingest_agents (provider, agent_key, source_agent_id, name, email, phone, office_id, office_name, broker_name)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+				ON CONFLICT (provider, agent_key) DO UPDATE SET
+					name=EXCLUDED.name,
+					email=COALESCE(EXCLUDED.email, ingest_agents.email),
+					phone=COALESCE(EXCLUDED.phone, ingest_agents.phone),
+					office_id=COALESCE(EXCLUDED.office_id, ingest_agents.office_id),
+					office_name=COALESCE(EXCLUDED.office_name, ingest_agents.office_name),
+					broker_name=COALESCE(EXCLUDED.broker_name, ingest_agents.broker_name),
+					updated_at=now()
+				RETURNING id
+			)
+			INSERT INTO ingest_listing_agents (listing_id, agent_id, role, position)
+			SELECT $10, ag.id, $11, $12 FROM ag
+			ON CONFLICT (listing_id, agent_id, role) DO NOTHING
+		`,
+			provider,
+			agentKey(a),
+			nullString(a.SourceID),
+			a.Name,
+			nullString(a.Email),
+			nullString(a.Phone),
+			nullString(a.OfficeID),
+			nullString(a.OfficeName),
+			nullString(a.BrokerName),
+			listingUUID,
+			a.Role,
+			idx,
+		)
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// FetchListingDetail loads one listing by provider listing ID with its photos
+// and agents. It returns nil when the listing is unknown.
+func (s *Store) FetchListingDetail(ctx context.Context, providerListingID string) (*ListingRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var rec ListingRecord
+	err := s.queryRowRead(ctx, `
+		SELECT p.property_key, p.address_line1, p.city, p.state, p.zip,
+		       p.lat, p.lon, l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type, l.list_date,
+		       `+sqlListingFeatures+`, l.last_fetch_at, l.stale_after
+		FROM ingest_listings l
+		JOIN ingest_properties p ON p.id = l.property_id
+		WHERE l.listing_id = $1 AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY l.updated_at DESC
+		LIMIT 1
+	`, []any{providerListingID},
+		&rec.PropertyKey, &rec.AddressLine1, &rec.City, &rec.State, &rec.Zip,
+		&rec.Lat, &rec.Lon, &rec.ListingID, &rec.ListingExternalID, &rec.ListPrice, &rec.Beds, &rec.Baths, &rec.Sqft, &rec.PropertyType, &rec.ListDate,
+		&rec.Features, &rec.LastFetchAt, &rec.StaleAfter)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	photoRows, err := s.queryRead(ctx, `SELECT `+listingPhotoColumns+` FROM (`+rankedListingPhotos+` WHERE lp.listing_id = $1) lp WHERE phash_rank = 1 ORDER BY position, created_at`, rec.ListingID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.PhotoAssets, err = pgx.CollectRows(photoRows, scanListingPhoto); err != nil {
+		return nil, err
+	}
+	for _, p := range rec.PhotoAssets {
+		rec.Photos = append(rec.Photos, p.Href)
+	}
+	if rec.Agents, err = s.fetchListingAgents(ctx, rec.ListingID); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *Store) fetchListingAgents(ctx context.Context, listingUUID string) ([]ListingAgent, error) {
+	rows, err := s.queryRead(ctx, `
+		SELECT COALESCE(a.source_agent_id, ''), la.role, a.name, COALESCE(a.email, ''), COALESCE(a.phone, ''),
+		       COALESCE(a.office_id, ''), COALESCE(a.office_name, ''), COALESCE(a.broker_name, '')
+		FROM ingest_listing_agents la
+		JOIN ingest_agents a ON a.id = la.agent_id
+		WHERE la.listing_id = $1
+		ORDER BY la.position
+	`, listingUUID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ListingAgent, error) {
+		var a ListingAgent
+		err := row.Scan(&a.SourceID, &a.Role, &a.Name, &a.Email, &a.Phone, &a.OfficeID, &a.OfficeName, &a.BrokerName)
+		return a, err
+	})
+}
+
+// FetchListingAgents returns the agents on a listing by provider listing ID.
+func (s *Store) FetchListingAgents(ctx context.Context, providerListingID string) ([]ListingAgent, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var listingUUID string
+	err := s.queryRowRead(ctx, `SELECT id FROM ingest_listings WHERE listing_id = $1 AND deleted_at IS NULL ORDER BY updated_at DESC LIMIT 1`,
+		[]any{providerListingID}, &listingUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.fetchListingAgents(ctx, listingUUID)
+}
+
+// FetchListingAgentsByIDs is FetchListingAgents for many provider listing IDs
+// in one query. Listings without agents are absent from the map.
+func (s *Store) FetchListingAgentsByIDs(ctx context.Context, providerListingIDs []string) (map[string][]ListingAgent, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	out := make(map[string][]ListingAgent, len(providerListingIDs))
+	if len(providerListingIDs) == 0 {
+		return out, nil
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT l.listing_id, COALESCE(a.source_agent_id, ''), la.role, a.name, COALESCE(a.email, ''), COALESCE(a.phone, ''),
+		       COALESCE(a.office_id, ''), COALESCE(a.office_name, ''), COALESCE(a.broker_name, '')
+		FROM (
+			SELECT DISTINCT ON (listing_id) id, listing_id
+			FROM ingest_listings
+			WHERE listing_id = ANY($1::text[]) AND deleted_at IS NULL
+			ORDER BY listing_id, updated_at DESC
+		) l
+		JOIN ingest_listing_agents la ON la.listing_id = l.id
+		JOIN ingest_agents a ON a.id = la.agent_id
+		ORDER BY l.listing_id, la.position
+	`, providerListingIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var a ListingAgent
+		if err := rows.Scan(&id, &a.SourceID, &a.Role, &a.Name, &a.Email, &a.Phone, &a.OfficeID, &a.OfficeName, &a.BrokerName); err != nil {
+			return nil, err
+		}
+		out[id] = append(out[id], a)
+	}
+	return out, rows.Err()
+}
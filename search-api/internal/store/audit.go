@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrListingNotFound is returned when a soft delete or restore names an
+// unknown listing.
+var ErrListingNotFound = errors.New("listing not found")
+
+// Actor identifies who is writing: a hydrator run or an API caller. The
+// ingest_audit_log triggers record it next to every change a write makes.
+type Actor struct {
+	Name      string
+	RunID     string
+	RequestID string
+}
+
+type actorKey struct{}
+
+// WithActor attributes writes made with ctx to a.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFrom returns the actor set by WithActor, if any.
+func ActorFrom(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	return a
+}
+
+// setAuditActorTx hands the context's actor to the audit triggers for the
+// rest of tx.
+func setAuditActorTx(ctx context.Context, tx pgx.Tx) error {
+	a := ActorFrom(ctx)
+	if a == (Actor{}) {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		SELECT set_config('ingest.actor', $1, true), set_config('ingest.run_id', $2, true), set_config('ingest.request_id', $3, true)
+	`, a.Name, a.RunID, a.RequestID)
+	return err
+}
+
+// auditTriggerFunc logs inserts, deletes and changed columns of the table it
+// is attached to. Bookkeeping columns that every ingest touches are ignored
+// so a refetch that changes nothing leaves no entry.
+const auditTriggerFunc = `CREATE OR REPLACE FUNCTION ingest_audit() RETURNS trigger AS $$
+DECLARE
+    skip    TEXT[] := ARRAY['updated_at', 'last_fetch_at', 'stale_after', 'missed_cycles', 'canonical_listing_id', 'history_fetched_at', 'description_tsv'];
+    old_row JSONB := CASE WHEN TG_OP = 'INSERT' THEN NULL ELSE to_jsonb(OLD) END;
+    new_row JSONB := CASE WHEN TG_OP = 'DELETE' THEN NULL ELSE to_jsonb(NEW) END;
+    changes JSONB := '{}'::jsonb;
+    action  TEXT  := lower(TG_OP);
+    k       TEXT;
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        FOR k IN SELECT jsonb_object_keys(new_row) LOOP
+            CONTINUE WHEN k = ANY(skip) OR old_row -> k IS NOT DISTINCT FROM new_row -> k;
+            changes := changes || jsonb_build_object(k, jsonb_build_array(old_row -> k, new_row -> k));
+        END LOOP;
+        IF changes = '{}'::jsonb THEN
+            RETURN NULL;
+        END IF;
+        IF old_row ->> 'deleted_at' IS NULL AND new_row ->> 'deleted_at' IS NOT NULL THEN
+            action := 'delete';
+        ELSIF old_row ->> 'deleted_at' IS NOT NULL AND new_row ->> 'deleted_at' IS NULL THEN
+            action := 'restore';
+        END IF;
+    ELSE
+        changes := jsonb_strip_nulls(COALESCE(new_row, old_row)) - skip;
+        IF TG_OP = 'DELETE' THEN
+            action := 'purge';
+        END IF;
+    END IF;
+    INSERT INTO ingest_audit_log (table_name, row_id, action, actor, run_id, request_id, changes)
+    VALUES (TG_TABLE_NAME, (COALESCE(new_row, old_row) ->> 'id')::uuid, action,
+            NULLIF(current_setting('ingest.actor', true), ''),
+            NULLIF(current_setting('ingest.run_id', true), ''),
+            NULLIF(current_setting('ingest.request_id', true), ''),
+            changes);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;`
+
+// AuditEntry is one row of ingest_audit_log. Changes maps each changed
+// column to [old, new] for updates, or holds the row for inserts and purges.
+type AuditEntry struct {
+	ID        int64
+	Table     string
+	RowID     string
+	Action    string
+	Actor     sql.NullString
+	RunID     sql.NullString
+	RequestID sql.NullString
+	Changes   json.RawMessage
+	CreatedAt time.Time
+}
+
+// AuditLog returns the change history of a property and its listings,
+// newest first. Deleted rows are included.
+func (s *Store) AuditLog(ctx context.Context, propertyKey string, limit int) ([]AuditEntry, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		WITH prop AS (
+			SELECT p.id FROM ingest_properties p WHERE `+propertyKeyMatch+`
+		)
+		SELECT a.id, a.table_name, a.row_id, a.action, a.actor, a.run_id, a.request_id, a.changes, a.created_at
+		FROM ingest_audit_log a
+		WHERE (a.table_name = 'ingest_properties' AND a.row_id IN (SELECT id FROM prop))
+		   OR (a.table_name = 'ingest_listings' AND a.row_id IN (
+		           SELECT l.id FROM ingest_listings l WHERE l.property_id IN (SELECT id FROM prop)))
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT $2
+	`, propertyKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (AuditEntry, error) {
+		var e AuditEntry
+		var changes []byte
+		err := row.Scan(&e.ID, &e.Table, &e.RowID, &e.Action, &e.Actor, &e.RunID, &e.RequestID, &changes, &e.CreatedAt)
+		e.Changes = changes
+		return e, err
+	})
+}
+
+// DeletedRecord is a soft-deleted property or listing.
+type DeletedRecord struct {
+	Kind        string // "property" or "listing"
+	ID          string
+	PropertyKey string
+	ListingID   sql.NullString // provider listing ID, for listings
+	Address     string
+	DeletedAt   time.Time
+}
+
+// SoftDeleteProperty hides a property and, through it, its listings from
+// every read path.
+func (s *Store) SoftDeleteProperty(ctx context.Context, propertyKey string) error {
+	return s.setPropertyDeleted(ctx, propertyKey, true)
+}
+
+// RestoreProperty undoes SoftDeleteProperty.
+func (s *Store) RestoreProperty(ctx context.Context, propertyKey string) error {
+	return s.setPropertyDeleted(ctx, propertyKey, false)
+}
+
+func (s *Store) setPropertyDeleted(ctx context.Context, propertyKey string, deleted bool) error {
+	return s.auditedExec(ctx, ErrPropertyNotFound, propertyKey, true, `
+		UPDATE ingest_properties
+		SET deleted_at = CASE WHEN $2 THEN now() END, updated_at = now()
+		WHERE property_key = $1 AND (deleted_at IS NULL) = $2
+		RETURNING id
+	`, propertyKey, deleted)
+}
+
+// SoftDeleteListing hides a listing, by ingest_listings ID or provider
+// listing ID, from every read path.
+func (s *Store) SoftDeleteListing(ctx context.Context, listingID string) error {
+	return s.setListingDeleted(ctx, listingID, true)
+}
+
+// RestoreListing undoes SoftDeleteListing.
+func (s *Store) RestoreListing(ctx context.Context, listingID string) error {
+	return s.setListingDeleted(ctx, listingID, false)
+}
+
+func (s *Store) setListingDeleted(ctx context.Context, listingID string, deleted bool) error {
+	return s.auditedExec(ctx, ErrListingNotFound, listingID, false, `
+		UPDATE ingest_listings
+		SET deleted_at = CASE WHEN $2 THEN now() END, updated_at = now()
+		WHERE (id::text = $1 OR listing_id = $1) AND (deleted_at IS NULL) = $2
+		RETURNING id
+	`, listingID, deleted)
+}
+
+// auditedExec runs one attributed statement returning the IDs it changed,
+// which are properties when ofProperties is set and listings otherwise, and
+// refreshes their listing summaries. It reports notFound when the statement
+// matches no rows.
+func (s *Store) auditedExec(ctx context.Context, notFound error, what string, ofProperties bool, query string, args ...any) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err = setAuditActorTx(ctx, tx); err != nil {
+		return err
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("%w: %s", notFound, what)
+	}
+	if ofProperties {
+		err = refreshListingSummariesTx(ctx, tx, ids, nil)
+	} else {
+		err = refreshListingSummariesTx(ctx, tx, nil, ids)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeletedRecords lists soft-deleted properties and listings, most recently
+// deleted first. kind filters to "property" or "listing"; empty lists both.
+func (s *Store) DeletedRecords(ctx context.Context, kind string, limit int) ([]DeletedRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT * FROM (
+			SELECT 'property' AS kind, p.id, p.property_key, NULL::text AS listing_id, p.address_line1, p.deleted_at
+			FROM ingest_properties p
+			WHERE p.deleted_at IS NOT NULL AND $1 IN ('', 'property')
+			UNION ALL
+			SELECT 'listing', l.id, p.property_key, l.listing_id, p.address_line1, l.deleted_at
+			FROM ingest_listings l
+			JOIN ingest_properties p ON p.id = l.property_id
+			WHERE l.deleted_at IS NOT NULL AND $1 IN ('', 'listing')
+		) d
+		ORDER BY d.deleted_at DESC
+		LIMIT $2
+	`, kind, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (DeletedRecord, error) {
+		var d DeletedRecord
+		err := row.Scan(&d.Kind, &d.ID, &d.PropertyKey, &d.ListingID, &d.Address, &d.DeletedAt)
+		return d, err
+	})
+}
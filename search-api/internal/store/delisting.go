@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StatusPendingRemoval marks a listing missing from several complete
+// provider crawls of its ZIP.
+const StatusPendingRemoval = "pending_removal"
+
+// DelistedListing is a listing moved to StatusPendingRemoval.
+type DelistedListing struct {
+	ListingID   string
+	PropertyID  string
+	PropertyKey string
+	SourceID    string
+}
+
+// MarkUnseenListings records a complete crawl of zip for provider. Active
+// listings not fetched since seenSince missed the crawl: their
+// missed_cycles count goes up and, once it reaches afterCycles, they move to
+// StatusPendingRemoval. Listings the crawl did fetch were reset to zero by
+// WriteSnapshotAndUpsert. It returns the listings newly pending removal.
+func (s *Store) MarkUnseenListings(ctx context.Context, zip, provider string, seenSince time.Time, afterCycles int) ([]DelistedListing, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	if afterCycles <= 0 {
+		afterCycles = 1
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err = setAuditActorTx(ctx, tx); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, `
+		UPDATE ingest_listings l
+		SET missed_cycles = l.missed_cycles + 1,
+		    status = CASE WHEN l.missed_cycles + 1 >= $4 THEN $5 ELSE l.status END,
+		    updated_at = CASE WHEN l.missed_cycles + 1 >= $4 THEN now() ELSE l.updated_at END
+		FROM ingest_properties p
+		WHERE p.id = l.property_id
+		  AND p.zip = $1
+		  AND l.provider = $2
+		  AND l.status = 'for_sale'
+		  AND l.deleted_at IS NULL
+		  AND COALESCE(l.last_fetch_at, l.created_at) < $3
+		RETURNING l.id, l.property_id, p.property_key, l.source_id, l.status
+	`, zip, provider, seenSince, afterCycles, StatusPendingRemoval)
+	if err != nil {
+		return nil, err
+	}
+	type marked struct {
+		DelistedListing
+		status string
+	}
+	all, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (marked, error) {
+		var m marked
+		err := row.Scan(&m.ListingID, &m.PropertyID, &m.PropertyKey, &m.SourceID, &m.status)
+		return m, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []DelistedListing
+	var ids []string
+	for _, m := range all {
+		if m.status == StatusPendingRemoval {
+			out = append(out, m.DelistedListing)
+			ids = append(ids, m.ListingID)
+		}
+	}
+	// Only listings moved to pending removal changed updated_at.
+	if err = refreshListingSummariesTx(ctx, tx, nil, ids); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
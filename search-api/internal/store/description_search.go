@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/proptype"
+)
+
+// DescriptionQuery is a keyword search over listing descriptions in a ZIP
+// or a city. Keywords use web search syntax: quoted phrases ("new roof"),
+// OR, and -term to exclude.
+type DescriptionQuery struct {
+	Keywords     string
+	Zip          string
+	City         string
+	State        string
+	PropertyType string
+	MinDOM       *int
+	MaxDOM       *int
+	Features     FeatureFilter
+	Limit        int
+	Offset       int
+}
+
+// SearchListingDescriptions pages listings whose description matches the
+// keywords, best match first. Each record's Highlight holds up to two
+// excerpts with matched terms wrapped in <mark> tags; the rest of the text
+// is HTML-escaped.
+func (s *Store) SearchListingDescriptions(ctx context.Context, q DescriptionQuery) ([]ListingRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if q.Limit <= 0 {
+		q.Limit = 5
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	var cityKey string
+	if q.Zip == "" {
+		cityKey = canon.CityKey(q.City)
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		WITH q AS (SELECT websearch_to_tsquery('english', $1) AS query)
+		SELECT p.property_key, p.address_line1, p.city, p.state, p.zip,
+		       p.lat, p.lon, l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type, l.list_date,
+		       `+sqlListingFeatures+`, l.last_fetch_at, l.stale_after,
+		       ts_headline('english',
+		           replace(replace(replace(l.description, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		           q.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=8, MaxWords=25, FragmentDelimiter=" … "')
+		FROM q, ingest_properties p
+		JOIN ingest_listings l ON l.property_id = p.id
+		WHERE l.description_tsv @@ q.query
+		  AND ($2 = '' OR p.zip = $2)
+		  AND ($3 = '' OR (p.state = $3 AND p.city_key = $4))
+		  AND ($5 = '' OR l.property_type = $5)
+		  AND ($8::int IS NULL OR l.list_date <= now() - make_interval(days => $8::int))
+		  AND ($9::int IS NULL OR l.list_date > now() - make_interval(days => $9::int + 1))
+		  AND ($10::int IS NULL OR COALESCE((l.extras->>'hoa_fee')::int, 0) <= $10::int)
+		  AND ($11::int IS NULL OR (l.extras->>'garage_spaces')::int >= $11::int)
+		  AND ($12::int IS NULL OR (l.extras->>'stories')::int >= $12::int)
+		  AND ($13::boolean IS NULL OR COALESCE((l.flags->>'pool')::boolean, false) = $13::boolean)
+		  AND ($14::boolean IS NULL OR COALESCE((l.flags->>'basement')::boolean, false) = $14::boolean)
+		  AND ($15::boolean IS NULL OR COALESCE((l.flags->>'new_construction')::boolean, false) = $15::boolean)
+		  AND ($16::boolean IS NULL OR COALESCE((l.flags->>'foreclosure')::boolean, false) = $16::boolean)
+		  AND ($17::boolean IS NULL OR COALESCE((l.flags->>'price_reduced')::boolean, false) = $17::boolean)
+		  AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY ts_rank(l.description_tsv, q.query) DESC, l.updated_at DESC
+		LIMIT $6 OFFSET $7
+	`, append([]any{q.Keywords, q.Zip, strings.ToUpper(q.State), cityKey, proptype.Normalize(q.PropertyType), q.Limit, q.Offset, q.MinDOM, q.MaxDOM}, q.Features.args()...)...)
+	if err != nil {
+		return nil, err
+	}
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ListingRecord, error) {
+		var rec ListingRecord
+		err := row.Scan(&rec.PropertyKey, &rec.AddressLine1, &rec.City, &rec.State, &rec.Zip,
+			&rec.Lat, &rec.Lon, &rec.ListingID, &rec.ListingExternalID, &rec.ListPrice, &rec.Beds, &rec.Baths, &rec.Sqft, &rec.PropertyType, &rec.ListDate,
+			&rec.Features, &rec.LastFetchAt, &rec.StaleAfter, &rec.Highlight)
+		return rec, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, s.attachListingPhotos(ctx, records)
+}
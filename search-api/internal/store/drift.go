@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DriftReport is a recorded change in a provider payload's shape.
+type DriftReport struct {
+	Provider      string
+	Endpoint      string
+	Signature     string
+	UnknownFields []string
+	MissingFields []string
+	FirstSeenAt   time.Time
+	LastSeenAt    time.Time
+	SeenCount     int
+}
+
+// SaveDriftReport records a drift, collapsing repeats of the same signature
+// into one row with a running count.
+func (s *Store) SaveDriftReport(ctx context.Context, r DriftReport) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO provider_drift_reports (provider, endpoint, signature, unknown_fields, missing_fields)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, endpoint, signature) DO UPDATE
+		SET last_seen_at = now(), seen_count = provider_drift_reports.seen_count + 1
+	`, r.Provider, r.Endpoint, r.Signature, nonNilStrings(r.UnknownFields), nonNilStrings(r.MissingFields))
+	return err
+}
+
+// RecentDriftReports returns drifts seen since the given time, newest first.
+func (s *Store) RecentDriftReports(ctx context.Context, since time.Time, limit int) ([]DriftReport, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.queryRead(ctx, `
+		SELECT provider, endpoint, signature, unknown_fields, missing_fields, first_seen_at, last_seen_at, seen_count
+		FROM provider_drift_reports
+		WHERE last_seen_at >= $1
+		ORDER BY last_seen_at DESC
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (DriftReport, error) {
+		var r DriftReport
+		err := row.Scan(&r.Provider, &r.Endpoint, &r.Signature, &r.UnknownFields, &r.MissingFields, &r.FirstSeenAt, &r.LastSeenAt, &r.SeenCount)
+		return r, err
+	})
+}
+
+func nonNilStrings(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return v
+}
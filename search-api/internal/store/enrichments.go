@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// EnrichmentSubject is the location data enrichers look up a property by.
+type EnrichmentSubject struct {
+	PropertyID   string
+	PropertyKey  string
+	AddressLine1 string
+	City         string
+	State        string
+	Zip          string
+	Lat          sql.NullFloat64
+	Lon          sql.NullFloat64
+}
+
+// Enrichment is one enricher's stored result for a property.
+type Enrichment struct {
+	Enricher  string
+	Data      json.RawMessage
+	FetchedAt time.Time
+}
+
+// EnrichmentSubject loads a live property by ID. It returns nil when the
+// property is unknown or deleted.
+func (s *Store) EnrichmentSubject(ctx context.Context, propertyID string) (*EnrichmentSubject, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var sub EnrichmentSubject
+	// Read from the primary: enrichment runs right after the write.
+	err := s.Pool.QueryRow(ctx, `
+		SELECT p.id, p.property_key, p.address_line1, p.city, p.state, p.zip, p.lat, p.lon
+		FROM ingest_properties p
+		WHERE p.id = $1 AND p.deleted_at IS NULL
+	`, propertyID).Scan(&sub.PropertyID, &sub.PropertyKey, &sub.AddressLine1, &sub.City, &sub.State, &sub.Zip, &sub.Lat, &sub.Lon)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// PropertyEnrichments returns every stored enrichment of a property,
+// following merge aliases, ordered by enricher.
+func (s *Store) PropertyEnrichments(ctx context.Context, propertyKey string) ([]Enrichment, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT e.enricher, e.data, e.fetched_at
+		FROM property_enrichments e
+		JOIN ingest_properties p ON p.id = e.property_id
+		WHERE `+propertyKeyMatch+` AND p.deleted_at IS NULL
+		ORDER BY e.enricher
+	`, propertyKey)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Enrichment, error) {
+		var e Enrichment
+		var data []byte
+		err := row.Scan(&e.Enricher, &data, &e.FetchedAt)
+		e.Data = data
+		return e, err
+	})
+}
+
+// EnrichmentTimes returns when each enricher last stored a result for a
+// property.
+func (s *Store) EnrichmentTimes(ctx context.Context, propertyID string) (map[string]time.Time, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		SELECT enricher, fetched_at FROM property_enrichments WHERE property_id = $1
+	`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]time.Time{}
+	for rows.Next() {
+		var name string
+		var at time.Time
+		if err := rows.Scan(&name, &at); err != nil {
+			return nil, err
+		}
+		out[name] = at
+	}
+	return out, rows.Err()
+}
+
+// SaveEnrichment stores an enricher's result for a property, replacing the
+// previous one. The property is found by key, following merge aliases, so a
+// result for a property merged away while the enricher ran is kept on the
+// survivor; a deleted property's result is dropped.
+func (s *Store) SaveEnrichment(ctx context.Context, propertyKey, enricher string, data []byte) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO property_enrichments (property_id, enricher, data, fetched_at)
+		SELECT p.id, $2, $3, now() FROM ingest_properties p
+		WHERE `+propertyKeyMatch+` AND p.deleted_at IS NULL
+		LIMIT 1
+		ON CONFLICT (property_id, enricher) DO UPDATE
+		SET data = EXCLUDED.data, fetched_at = EXCLUDED.fetched_at
+	`, propertyKey, enricher, data)
+	return err
+}
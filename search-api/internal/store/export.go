@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const defaultExportChunk = 500
+
+// ExportQuery filters a listings export. Zero values mean no filter.
+type ExportQuery struct {
+	Zip   string
+	Since time.Time
+	// ChunkSize is the number of rows read per round trip.
+	ChunkSize int
+}
+
+// ExportRecord is a listing row for bulk export.
+type ExportRecord struct {
+	ListingRecord
+	UpdatedAt time.Time
+}
+
+// ExportListings walks listings updated at or after q.Since in
+// (updated_at, id) order, one keyset-paginated chunk at a time, and calls fn
+// for every row. Each chunk is its own query so a long export never holds a
+// transaction or a single statement open.
+func (s *Store) ExportListings(ctx context.Context, q ExportQuery, fn func(ExportRecord) error) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	chunk := q.ChunkSize
+	if chunk <= 0 {
+		chunk = defaultExportChunk
+	}
+	var (
+		cursorAt time.Time
+		cursorID string
+	)
+	if !q.Since.IsZero() {
+		cursorAt = q.Since.Add(-time.Microsecond)
+	}
+	for {
+		batch, err := s.exportChunk(ctx, q.Zip, cursorAt, cursorID, chunk)
+		if err != nil {
+			return err
+		}
+		for _, rec := range batch {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+		if len(batch) < chunk {
+			return nil
+		}
+		last := batch[len(batch)-1]
+		cursorAt, cursorID = last.UpdatedAt, last.ListingID
+	}
+}
+
+func (s *Store) exportChunk(ctx context.Context, zip string, afterAt time.Time, afterID string, limit int) ([]ExportRecord, error) {
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT p.property_key, p.address_line1, p.city, p.state, p.zip,
+		       p.lat, p.lon, l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type,
+		       l.status, l.list_date, l.updated_at
+		FROM ingest_listings l
+		JOIN ingest_properties p ON p.id = l.property_id
+		WHERE ($1 = '' OR p.zip = $1)
+		  AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+		  AND (l.updated_at, l.id) > ($2, COALESCE(NULLIF($3, '')::uuid, '00000000-0000-0000-0000-000000000000'::uuid))
+		ORDER BY l.updated_at, l.id
+		LIMIT $4
+	`, zip, afterAt, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	out, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ExportRecord, error) {
+		var rec ExportRecord
+		err := row.Scan(&rec.PropertyKey, &rec.AddressLine1, &rec.City, &rec.State, &rec.Zip,
+			&rec.Lat, &rec.Lon, &rec.ListingID, &rec.ListingExternalID, &rec.ListPrice, &rec.Beds, &rec.Baths, &rec.Sqft, &rec.PropertyType,
+			&rec.Status, &rec.ListDate, &rec.UpdatedAt)
+		return rec, err
+	})
+	if err != nil || len(out) == 0 {
+		return out, err
+	}
+	records := make([]ListingRecord, len(out))
+	for i := range out {
+		records[i] = out[i].ListingRecord
+	}
+	if err := s.attachListingPhotos(ctx, records); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Photos = records[i].Photos
+	}
+	return out, nil
+}
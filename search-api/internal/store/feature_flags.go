@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrFlagNotFound is returned when no rule is stored for a feature flag.
+var ErrFlagNotFound = errors.New("feature flag not found")
+
+// FeatureFlag is a flag's stored rollout: Percent of consumers have it on,
+// except those with an override.
+type FeatureFlag struct {
+	Name        string
+	Percent     int
+	Description string
+	Overrides   map[string]bool // consumer ID to on or off
+	UpdatedBy   string
+	UpdatedAt   time.Time
+}
+
+// FeatureFlags returns every stored flag with its overrides. A flag with
+// only overrides has Percent -1.
+func (s *Store) FeatureFlags(ctx context.Context) ([]FeatureFlag, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		SELECT n.name, coalesce(f.percent, -1), coalesce(f.description, ''), coalesce(f.updated_by, ''),
+		       coalesce(f.updated_at, 'epoch'::timestamptz),
+		       coalesce(array_agg(o.consumer ORDER BY o.consumer) FILTER (WHERE o.consumer IS NOT NULL), '{}'),
+		       coalesce(array_agg(o.enabled ORDER BY o.consumer) FILTER (WHERE o.consumer IS NOT NULL), '{}')
+		FROM (SELECT name FROM feature_flags UNION SELECT name FROM feature_flag_overrides) n
+		LEFT JOIN feature_flags f ON f.name = n.name
+		LEFT JOIN feature_flag_overrides o ON o.name = n.name
+		GROUP BY n.name, f.percent, f.description, f.updated_by, f.updated_at
+		ORDER BY n.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (FeatureFlag, error) {
+		var f FeatureFlag
+		var consumers []string
+		var enabled []bool
+		if err := row.Scan(&f.Name, &f.Percent, &f.Description, &f.UpdatedBy, &f.UpdatedAt, &consumers, &enabled); err != nil {
+			return f, err
+		}
+		f.Overrides = make(map[string]bool, len(consumers))
+		for i, c := range consumers {
+			f.Overrides[c] = enabled[i]
+		}
+		return f, nil
+	})
+}
+
+// SetFeatureFlag stores a flag's rollout percentage, 0 to 100. An empty
+// description keeps the stored one.
+func (s *Store) SetFeatureFlag(ctx context.Context, name string, percent int, description, actor string) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("feature flag %s: percent %d out of range", name, percent)
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO feature_flags (name, percent, description, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (name) DO UPDATE SET
+			percent = EXCLUDED.percent,
+			description = coalesce(nullif(EXCLUDED.description, ''), feature_flags.description),
+			updated_by = EXCLUDED.updated_by, updated_at = now()
+	`, name, percent, description, actor)
+	return err
+}
+
+// DeleteFeatureFlag removes a flag's stored rollout, leaving its overrides.
+func (s *Store) DeleteFeatureFlag(ctx context.Context, name string) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM feature_flags WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrFlagNotFound, name)
+	}
+	return nil
+}
+
+// SetFlagOverride turns a flag on or off for one consumer, whatever its
+// rollout.
+func (s *Store) SetFlagOverride(ctx context.Context, name, consumer string, enabled bool, actor string) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO feature_flag_overrides (name, consumer, enabled, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (name, consumer) DO UPDATE SET enabled = EXCLUDED.enabled, updated_by = EXCLUDED.updated_by, updated_at = now()
+	`, name, consumer, enabled, actor)
+	return err
+}
+
+// DeleteFlagOverride returns consumer to the flag's rollout.
+func (s *Store) DeleteFlagOverride(ctx context.Context, name, consumer string) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM feature_flag_overrides WHERE name = $1 AND consumer = $2`, name, consumer)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s override for %s", ErrFlagNotFound, name, consumer)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import "encoding/json"
+
+// ListingFeatures are listing details kept in JSONB rather than columns:
+// yes/no features in ingest_listings.flags and counts and amounts in
+// ingest_listings.extras. Nil fields were not reported.
+type ListingFeatures struct {
+	// extras
+	HOAFee       *int `json:"hoa_fee,omitempty"`
+	GarageSpaces *int `json:"garage_spaces,omitempty"`
+	Stories      *int `json:"stories,omitempty"`
+	// flags
+	Pool            *bool `json:"pool,omitempty"`
+	Basement        *bool `json:"basement,omitempty"`
+	NewConstruction *bool `json:"new_construction,omitempty"`
+	Foreclosure     *bool `json:"foreclosure,omitempty"`
+	PriceReduced    *bool `json:"price_reduced,omitempty"`
+}
+
+// sqlListingFeatures selects flags and extras as one object that scans into
+// ListingFeatures.
+const sqlListingFeatures = `COALESCE(l.flags, '{}'::jsonb) || COALESCE(l.extras, '{}'::jsonb)`
+
+// FeatureFilter narrows a listing page on ListingFeatures. Listings with no
+// reported HOA fee pass MaxHOA; the other bounds exclude listings that don't
+// report the feature. The boolean filters treat a missing flag as false.
+type FeatureFilter struct {
+	MaxHOA          *int
+	MinGarage       *int
+	MinStories      *int
+	Pool            *bool
+	Basement        *bool
+	NewConstruction *bool
+	Foreclosure     *bool
+	PriceReduced    *bool
+}
+
+// args are the filter's query arguments, in the order the listing queries
+// number them.
+func (f FeatureFilter) args() []any {
+	return []any{f.MaxHOA, f.MinGarage, f.MinStories, f.Pool, f.Basement, f.NewConstruction, f.Foreclosure, f.PriceReduced}
+}
+
+// flagsJSON and extrasJSON split features into the two columns; either is
+// nil when it has nothing to write, which keeps the stored value.
+func (f ListingFeatures) flagsJSON() any {
+	return featureJSON(ListingFeatures{
+		Pool: f.Pool, Basement: f.Basement,
+		NewConstruction: f.NewConstruction, Foreclosure: f.Foreclosure, PriceReduced: f.PriceReduced,
+	})
+}
+
+func (f ListingFeatures) extrasJSON() any {
+	return featureJSON(ListingFeatures{HOAFee: f.HOAFee, GarageSpaces: f.GarageSpaces, Stories: f.Stories})
+}
+
+func featureJSON(f ListingFeatures) any {
+	b, err := json.Marshal(f)
+	if err != nil || string(b) == "{}" {
+		return nil
+	}
+	return json.RawMessage(b)
+}
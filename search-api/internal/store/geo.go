@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/yourorg/search-api/internal/proptype"
+)
+
+// GeoQuery selects listings inside a bounding box and, optionally, a polygon.
+// Polygon vertices are [lon, lat] pairs; the bounding box must enclose it.
+type GeoQuery struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+	Polygon        [][2]float64
+	PropertyType   string
+	Limit          int
+	Offset         int
+}
+
+const earthRadiusMeters = 6371000.0
+
+// FetchListingsInBounds serves map viewport searches on each listing's own
+// coords, so listings without coordinates are left out. The earth_box
+// predicate lets the ll_to_earth GIST index prune candidates before the
+// exact bbox and polygon checks.
+func (s *Store) FetchListingsInBounds(ctx context.Context, q GeoQuery) ([]ListingRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	centerLat := (q.MinLat + q.MaxLat) / 2
+	centerLon := (q.MinLon + q.MaxLon) / 2
+	radius := haversineMeters(q.MinLat, q.MinLon, q.MaxLat, q.MaxLon)/2 + 1
+	var polygon any
+	if len(q.Polygon) >= 3 {
+		polygon = polygonLiteral(q.Polygon)
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT p.property_key, p.address_line1, p.city, p.state, p.zip,
+		       l.coords[1], l.coords[0], l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type, l.list_date,
+		       `+sqlListingFeatures+`
+		FROM ingest_properties p
+		JOIN ingest_listings l ON l.property_id = p.id
+		WHERE l.coords IS NOT NULL
+		  AND earth_box(ll_to_earth($5, $6), $7) @> ll_to_earth(l.coords[1], l.coords[0])
+		  AND l.coords[1] BETWEEN $1 AND $3 AND l.coords[0] BETWEEN $2 AND $4
+		  AND ($8::polygon IS NULL OR $8::polygon @> l.coords)
+		  AND ($9 = '' OR l.property_type = $9)
+		  AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY l.updated_at DESC
+		LIMIT $10 OFFSET $11
+	`, q.MinLat, q.MinLon, q.MaxLat, q.MaxLon, centerLat, centerLon, radius, polygon, proptype.Normalize(q.PropertyType), q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectListingPage(ctx, rows)
+}
+
+// polygonLiteral renders [lon, lat] vertices as a Postgres polygon literal.
+func polygonLiteral(vertices [][2]float64) string {
+	parts := make([]string, len(vertices))
+	for i, v := range vertices {
+		parts[i] = fmt.Sprintf("(%g,%g)", v[0], v[1])
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TaxAssessment is one stored tax year of a property.
+type TaxAssessment struct {
+	Year             int
+	Tax              sql.NullFloat64
+	AssessedLand     sql.NullFloat64
+	AssessedBuilding sql.NullFloat64
+	AssessedTotal    sql.NullFloat64
+	MarketTotal      sql.NullFloat64
+	Provider         string
+	FetchedAt        time.Time
+}
+
+// PropertyTransfer is one stored ownership transfer of a property.
+type PropertyTransfer struct {
+	Date      time.Time
+	Price     sql.NullFloat64
+	Event     string
+	Source    sql.NullString
+	Provider  string
+	FetchedAt time.Time
+}
+
+// PropertyHistory is a property's tax assessments, newest year first, and
+// transfers, newest first. FetchedAt is when history was last ingested.
+type PropertyHistory struct {
+	PropertyKey string
+	FetchedAt   sql.NullTime
+	Assessments []TaxAssessment
+	Transfers   []PropertyTransfer
+}
+
+// HistoryFetchedAt reports when the history of a property was last saved.
+// The time is invalid when it never was, including for unknown keys.
+func (s *Store) HistoryFetchedAt(ctx context.Context, propertyKey string) (sql.NullTime, error) {
+	var at sql.NullTime
+	if s.Pool == nil {
+		return at, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	err := s.Pool.QueryRow(ctx, `
+		SELECT p.history_fetched_at FROM ingest_properties p WHERE `+propertyKeyMatch+`
+	`, propertyKey).Scan(&at)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return at, nil
+	}
+	return at, err
+}
+
+// SavePropertyHistory upserts tax years and transfers for a property, and
+// its parcel when the tax record names one. Rows the provider no longer
+// returns are kept: public records are append-only and a shorter payload is
+// not a correction.
+func (s *Store) SavePropertyHistory(ctx context.Context, propertyKey, provider string, parcel Parcel, taxes []TaxAssessment, transfers []PropertyTransfer) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err = setAuditActorTx(ctx, tx); err != nil {
+		return err
+	}
+	propertyID, err := propertyIDByKey(ctx, tx, propertyKey)
+	if err != nil {
+		return err
+	}
+	batch := &pgx.Batch{}
+	for _, t := range taxes {
+		batch.Queue(`
+			INSERT INTO property_tax_assessments (property_id, tax_year, tax_amount, assessed_land, assessed_building, assessed_total, market_total, provider, fetched_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now())
+			ON CONFLICT (property_id, tax_year) DO UPDATE
+			SET tax_amount = EXCLUDED.tax_amount, assessed_land = EXCLUDED.assessed_land,
+			    assessed_building = EXCLUDED.assessed_building, assessed_total = EXCLUDED.assessed_total,
+			    market_total = EXCLUDED.market_total, provider = EXCLUDED.provider, fetched_at = now()
+		`, propertyID, t.Year, t.Tax, t.AssessedLand, t.AssessedBuilding, t.AssessedTotal, t.MarketTotal, provider)
+	}
+	for _, t := range transfers {
+		batch.Queue(`
+			INSERT INTO property_transfers (property_id, transfer_date, price, event, source, provider, fetched_at)
+			VALUES ($1, $2, $3, $4, $5, $6, now())
+			ON CONFLICT (property_id, transfer_date, event) DO UPDATE
+			SET price = EXCLUDED.price, source = EXCLUDED.source, provider = EXCLUDED.provider, fetched_at = now()
+		`, propertyID, t.Date, t.Price, t.Event, t.Source, provider)
+	}
+	// history_fetched_at is bookkeeping, so it does not bump updated_at.
+	batch.Queue(`
+		UPDATE ingest_properties SET history_fetched_at = now(), apn = COALESCE($2, apn), fips = COALESCE($3, fips)
+		WHERE id = $1
+	`, propertyID, parcel.apnArg(), parcel.fipsArg())
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// FetchPropertyHistory loads the stored history of a live property,
+// following merge aliases. It returns nil when the key is unknown.
+func (s *Store) FetchPropertyHistory(ctx context.Context, propertyKey string) (*PropertyHistory, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	h := PropertyHistory{Assessments: []TaxAssessment{}, Transfers: []PropertyTransfer{}}
+	var propertyID string
+	err := s.queryRowRead(ctx, `
+		SELECT p.id, p.property_key, p.history_fetched_at
+		FROM ingest_properties p
+		WHERE `+propertyKeyMatch+` AND p.deleted_at IS NULL
+	`, []any{propertyKey}, &propertyID, &h.PropertyKey, &h.FetchedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queryRead(ctx, `
+		SELECT tax_year, tax_amount, assessed_land, assessed_building, assessed_total, market_total, provider, fetched_at
+		FROM property_tax_assessments
+		WHERE property_id = $1
+		ORDER BY tax_year DESC
+	`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	h.Assessments, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (TaxAssessment, error) {
+		var t TaxAssessment
+		err := row.Scan(&t.Year, &t.Tax, &t.AssessedLand, &t.AssessedBuilding, &t.AssessedTotal, &t.MarketTotal, &t.Provider, &t.FetchedAt)
+		return t, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = s.queryRead(ctx, `
+		SELECT transfer_date, price, event, source, provider, fetched_at
+		FROM property_transfers
+		WHERE property_id = $1
+		ORDER BY transfer_date DESC
+	`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	h.Transfers, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (PropertyTransfer, error) {
+		var t PropertyTransfer
+		err := row.Scan(&t.Date, &t.Price, &t.Event, &t.Source, &t.Provider, &t.FetchedAt)
+		return t, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Hydrator run states.
+const (
+	RunRunning        = "running"
+	RunCompleted      = "completed"
+	RunQuotaExhausted = "quota_exhausted"
+	RunInterrupted    = "interrupted"
+	RunFailed         = "failed"
+)
+
+// HydratorRun summarizes one bulk hydration cycle.
+type HydratorRun struct {
+	ID                string
+	Job               string
+	Status            string
+	StartedAt         time.Time
+	FinishedAt        sql.NullTime
+	ZipsTotal         int
+	ZipsDone          int
+	ListingsPersisted int
+	Requests          int // provider calls made, i.e. quota used
+	LastError         string
+}
+
+// HydratorCheckpoint is a run's progress through one ZIP and property type.
+type HydratorCheckpoint struct {
+	RunID        string
+	Zip          string
+	PropertyType string
+	LastPage     int // last page fully persisted
+	Listings     int
+	Done         bool
+}
+
+// OpenHydratorRun resumes the job's unfinished run, if any, or starts a new
+// one. resumed reports which.
+func (s *Store) OpenHydratorRun(ctx context.Context, job string, zipsTotal int) (run HydratorRun, resumed bool, err error) {
+	if s.Pool == nil {
+		return run, false, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	row := s.Pool.QueryRow(ctx, `
+		UPDATE hydrator_runs SET status = $2, zips_total = $3, updated_at = now()
+		WHERE id = (
+			SELECT id FROM hydrator_runs
+			WHERE job = $1 AND finished_at IS NULL
+			ORDER BY started_at DESC LIMIT 1
+		)
+		RETURNING id, job, status, started_at, finished_at, zips_total, zips_done, listings_persisted, requests, COALESCE(last_error, '')
+	`, job, RunRunning, zipsTotal)
+	run, err = scanHydratorRun(row)
+	if err == nil {
+		return run, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return run, false, err
+	}
+	row = s.Pool.QueryRow(ctx, `
+		INSERT INTO hydrator_runs (job, status, zips_total)
+		VALUES ($1, $2, $3)
+		RETURNING id, job, status, started_at, finished_at, zips_total, zips_done, listings_persisted, requests, COALESCE(last_error, '')
+	`, job, RunRunning, zipsTotal)
+	run, err = scanHydratorRun(row)
+	return run, false, err
+}
+
+// FinishHydratorRun stores the run's counters and status. Completed and
+// failed runs are closed; interrupted or quota-exhausted runs stay open so
+// the next cycle resumes them.
+func (s *Store) FinishHydratorRun(ctx context.Context, r HydratorRun) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `
+		UPDATE hydrator_runs
+		SET status = $2, zips_done = $3, listings_persisted = $4, requests = $5,
+		    last_error = NULLIF($6, ''), updated_at = now(),
+		    finished_at = CASE WHEN $2 IN ('completed', 'failed') THEN now() END
+		WHERE id = $1
+	`, r.ID, r.Status, r.ZipsDone, r.ListingsPersisted, r.Requests, r.LastError)
+	return err
+}
+
+// RecentHydratorRuns returns the job's latest runs, newest first.
+func (s *Store) RecentHydratorRuns(ctx context.Context, job string, limit int) ([]HydratorRun, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.queryRead(ctx, `
+		SELECT id, job, status, started_at, finished_at, zips_total, zips_done, listings_persisted, requests, COALESCE(last_error, '')
+		FROM hydrator_runs
+		WHERE job = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`, job, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (HydratorRun, error) {
+		return scanHydratorRun(row)
+	})
+}
+
+func scanHydratorRun(row pgx.Row) (HydratorRun, error) {
+	var r HydratorRun
+	err := row.Scan(&r.ID, &r.Job, &r.Status, &r.StartedAt, &r.FinishedAt, &r.ZipsTotal, &r.ZipsDone, &r.ListingsPersisted, &r.Requests, &r.LastError)
+	return r, err
+}
+
+// HydratorCheckpoints returns a run's progress keyed by CheckpointKey.
+func (s *Store) HydratorCheckpoints(ctx context.Context, runID string) (map[string]HydratorCheckpoint, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		SELECT run_id, zip, property_type, last_page, listings, done
+		FROM hydrator_checkpoints
+		WHERE run_id = $1
+	`, runID)
+	if err != nil {
+		return nil, err
+	}
+	cps, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (HydratorCheckpoint, error) {
+		var c HydratorCheckpoint
+		err := row.Scan(&c.RunID, &c.Zip, &c.PropertyType, &c.LastPage, &c.Listings, &c.Done)
+		return c, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]HydratorCheckpoint, len(cps))
+	for _, c := range cps {
+		out[CheckpointKey(c.Zip, c.PropertyType)] = c
+	}
+	return out, nil
+}
+
+// CheckpointKey identifies a checkpoint within a run.
+func CheckpointKey(zip, propertyType string) string {
+	return zip + "|" + propertyType
+}
+
+// SaveHydratorCheckpoint records progress after a page is persisted or a
+// ZIP is finished.
+func (s *Store) SaveHydratorCheckpoint(ctx context.Context, c HydratorCheckpoint) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO hydrator_checkpoints (run_id, zip, property_type, last_page, listings, done)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (run_id, zip, property_type) DO UPDATE
+		SET last_page = EXCLUDED.last_page, listings = EXCLUDED.listings, done = EXCLUDED.done, updated_at = now()
+	`, c.RunID, c.Zip, c.PropertyType, c.LastPage, c.Listings, c.Done)
+	return err
+}
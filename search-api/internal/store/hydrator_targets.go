@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// HydratorTarget is a ZIP the bulk hydrator should cover.
+type HydratorTarget struct {
+	Zip       string
+	Priority  int // higher runs first
+	LastRunAt sql.NullTime
+	// StaleListings is the number of active listings not fetched within the
+	// stale window; set only by StaleListingZips.
+	StaleListings int
+}
+
+// HydratorTargets returns enabled targets, highest priority first and, within
+// a priority, the longest unvisited first.
+func (s *Store) HydratorTargets(ctx context.Context, limit int) ([]HydratorTarget, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT zip, priority, last_run_at
+		FROM hydrator_targets
+		WHERE enabled
+		ORDER BY priority DESC, last_run_at ASC NULLS FIRST, zip
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (HydratorTarget, error) {
+		var t HydratorTarget
+		err := row.Scan(&t.Zip, &t.Priority, &t.LastRunAt)
+		return t, err
+	})
+}
+
+// StaleListingZips returns the ZIPs with the most live for-sale listings
+// not fetched within staleAfter.
+func (s *Store) StaleListingZips(ctx context.Context, staleAfter time.Duration, limit int) ([]HydratorTarget, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.queryRead(ctx, `
+		SELECT p.zip, count(*) AS stale
+		FROM ingest_listings l
+		JOIN ingest_properties p ON p.id = l.property_id
+		WHERE l.status = 'for_sale'
+		  AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+		  AND COALESCE(l.last_fetch_at, l.updated_at) < now() - make_interval(secs => $1)
+		GROUP BY p.zip
+		ORDER BY stale DESC, p.zip
+		LIMIT $2
+	`, staleAfter.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (HydratorTarget, error) {
+		var t HydratorTarget
+		err := row.Scan(&t.Zip, &t.StaleListings)
+		return t, err
+	})
+}
+
+// MarkHydratorTargetRun stamps last_run_at on a target. ZIPs without a
+// target row are ignored.
+func (s *Store) MarkHydratorTargetRun(ctx context.Context, zip string, at time.Time) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `UPDATE hydrator_targets SET last_run_at = $2 WHERE zip = $1`, zip, at)
+	return err
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ListingSource is one provider's record of a merged listing.
+type ListingSource struct {
+	ListingID         string
+	Provider          string
+	SourceID          string
+	ListingExternalID sql.NullString
+	ListPrice         sql.NullFloat64
+	LastFetchAt       sql.NullTime
+}
+
+// MergedListing is the canonical listing for a property and status, with
+// every provider record of it (the canonical one first) in Sources.
+type MergedListing struct {
+	ListingRecord
+	Provider string
+	Sources  []ListingSource
+}
+
+// ReconcileListings links listings of the same property and status across
+// providers. Per group the canonical listing is the one from the earliest
+// provider in providerOrder (unlisted providers last), then the most recently
+// fetched; the others point at it through canonical_listing_id. Only
+// properties with a listing updated since since are reconciled; a zero since
+// covers every property. It returns the number of listings whose link changed.
+func (s *Store) ReconcileListings(ctx context.Context, providerOrder []string, since time.Time) (int64, error) {
+	if s.Pool == nil {
+		return 0, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	if providerOrder == nil {
+		providerOrder = []string{}
+	}
+	tag, err := s.Pool.Exec(ctx, `
+		WITH touched AS (
+			SELECT DISTINCT property_id FROM ingest_listings WHERE updated_at >= $2
+		), ranked AS (
+			SELECT l.id,
+			       first_value(l.id) OVER (
+			           PARTITION BY l.property_id, l.status
+			           ORDER BY array_position($1::text[], l.provider) NULLS LAST,
+			                    l.last_fetch_at DESC NULLS LAST, l.created_at, l.id
+			       ) AS canon
+			FROM ingest_listings l
+			WHERE l.property_id IN (SELECT property_id FROM touched) AND l.deleted_at IS NULL
+		)
+		UPDATE ingest_listings l
+		SET canonical_listing_id = NULLIF(r.canon, l.id)
+		FROM ranked r
+		WHERE r.id = l.id
+		  AND l.canonical_listing_id IS DISTINCT FROM NULLIF(r.canon, l.id)
+	`, providerOrder, since)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// FetchMergedListings returns a property's canonical listings, newest
+// first, each with the provider records linked to it. Listings not yet
+// reconciled are their own canonical listing.
+func (s *Store) FetchMergedListings(ctx context.Context, propertyKey string, limit int) ([]MergedListing, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT p.property_key, p.address_line1, p.city, p.state, p.zip,
+		       p.lat, p.lon, l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type,
+		       l.status, l.list_date, l.provider, l.source_id, l.last_fetch_at, l.canonical_listing_id
+		FROM ingest_properties p
+		JOIN ingest_listings l ON l.property_id = p.id
+		WHERE `+propertyKeyMatch+` AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY l.canonical_listing_id NULLS FIRST, COALESCE(l.list_date, l.created_at) DESC
+	`, propertyKey)
+	if err != nil {
+		return nil, err
+	}
+	type row struct {
+		rec       ListingRecord
+		src       ListingSource
+		canonical sql.NullString
+	}
+	all, err := pgx.CollectRows(rows, func(cr pgx.CollectableRow) (row, error) {
+		var r row
+		err := cr.Scan(&r.rec.PropertyKey, &r.rec.AddressLine1, &r.rec.City, &r.rec.State, &r.rec.Zip,
+			&r.rec.Lat, &r.rec.Lon, &r.rec.ListingID, &r.rec.ListingExternalID, &r.rec.ListPrice, &r.rec.Beds, &r.rec.Baths, &r.rec.Sqft, &r.rec.PropertyType,
+			&r.rec.Status, &r.rec.ListDate, &r.src.Provider, &r.src.SourceID, &r.src.LastFetchAt, &r.canonical)
+		r.src.ListingID = r.rec.ListingID
+		r.src.ListingExternalID = r.rec.ListingExternalID
+		r.src.ListPrice = r.rec.ListPrice
+		return r, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []MergedListing
+	byID := map[string]int{}
+	for _, r := range all {
+		if r.canonical.Valid {
+			if i, ok := byID[r.canonical.String]; ok {
+				out[i].Sources = append(out[i].Sources, r.src)
+				continue
+			}
+			// The canonical listing was not loaded; fall back to this one.
+		}
+		if len(out) >= limit {
+			continue
+		}
+		byID[r.rec.ListingID] = len(out)
+		out = append(out, MergedListing{ListingRecord: r.rec, Provider: r.src.Provider, Sources: []ListingSource{r.src}})
+	}
+	records := make([]ListingRecord, len(out))
+	for i := range out {
+		records[i] = out[i].ListingRecord
+	}
+	if err := s.attachListingPhotos(ctx, records); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].ListingRecord = records[i]
+	}
+	return out, nil
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ListingPhoto is a stored listing photo with the metadata the provider
+// sent for it.
+type ListingPhoto struct {
+	Href        string
+	Description string
+	Title       string
+	Kind        string
+	MediaType   string
+	Tags        []string
+	Position    int
+	// Room is the room type from the provider tags or the classifier.
+	Room string
+	// Primary marks the photo chosen as the listing's cover; it is also
+	// first in position.
+	Primary bool
+	// Inline marks a photo stored from a search payload before the
+	// listing's full set was fetched.
+	Inline bool
+}
+
+// InlineOnly reports whether photos are only those a search payload
+// carried, so the listing's full set has yet to be fetched.
+func InlineOnly(photos []ListingPhoto) bool {
+	return len(photos) > 0 && !slices.ContainsFunc(photos, func(p ListingPhoto) bool { return !p.Inline })
+}
+
+// ErrPhotoNotFound reports a photo href the listing does not have.
+var ErrPhotoNotFound = errors.New("photo not found")
+
+// PhotoArrangement is a curated photo order. Order lists hrefs to show
+// first, in that order; the listing's other photos follow in their current
+// order. Primary, when set, becomes the listing's primary photo. The primary
+// photo always leads.
+type PhotoArrangement struct {
+	Order   []string
+	Primary string
+}
+
+// FetchListingPhotoAssets returns a provider listing's photos in display
+// order, near-duplicates dropped like FetchListingPhotos.
+func (s *Store) FetchListingPhotoAssets(ctx context.Context, providerListingID string) ([]ListingPhoto, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT `+listingPhotoColumns+`
+		FROM ingest_listings l
+		JOIN LATERAL `+distinctListingPhotos+` lp ON true
+		WHERE l.listing_id = $1 AND l.deleted_at IS NULL AND lp.phash_rank = 1
+		ORDER BY lp.position, lp.created_at
+	`, providerListingID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanListingPhoto)
+}
+
+// listingPhotoColumns are the ListingPhoto fields of ingest_listing_photos
+// aliased lp, in scanListingPhoto order.
+const listingPhotoColumns = `lp.href, COALESCE(lp.description, ''), COALESCE(lp.title, ''), COALESCE(lp.kind, ''),
+		       COALESCE(lp.media_type, ''), COALESCE(lp.tags, '[]'::jsonb), COALESCE(lp.position, 0), COALESCE(lp.room, ''),
+		       lp.is_primary, lp.inline`
+
+func scanListingPhoto(row pgx.CollectableRow) (ListingPhoto, error) {
+	var p ListingPhoto
+	err := row.Scan(&p.Href, &p.Description, &p.Title, &p.Kind, &p.MediaType, &p.Tags, &p.Position, &p.Room, &p.Primary, &p.Inline)
+	return p, err
+}
+
+// HasTag reports whether the photo carries tag, ignoring case.
+func (p ListingPhoto) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ArrangeListingPhotos renumbers a provider listing's photos to the curated
+// order and sets its primary photo. Curated positions survive provider
+// resyncs; photos the provider adds later are placed by their provider
+// position. It returns ErrListingNotFound for an unknown listing and
+// ErrPhotoNotFound when an href is not one of the listing's photos.
+func (s *Store) ArrangeListingPhotos(ctx context.Context, providerListingID string, a PhotoArrangement) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	var listingUUID string
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM ingest_listings
+		WHERE listing_id=$1 AND deleted_at IS NULL
+		ORDER BY updated_at DESC LIMIT 1`, providerListingID).Scan(&listingUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrListingNotFound, providerListingID)
+	}
+	if err != nil {
+		return err
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT href, is_primary FROM ingest_listing_photos
+		WHERE listing_id=$1
+		ORDER BY position, created_at
+		FOR UPDATE`, listingUUID)
+	if err != nil {
+		return err
+	}
+	type current struct {
+		href    string
+		primary bool
+	}
+	photos, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (current, error) {
+		var c current
+		err := row.Scan(&c.href, &c.primary)
+		return c, err
+	})
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(photos))
+	primary := a.Primary
+	for _, p := range photos {
+		known[p.href] = true
+		if primary == "" && p.primary {
+			primary = p.href
+		}
+	}
+	for _, href := range append(slices.Clone(a.Order), a.Primary) {
+		if href != "" && !known[href] {
+			return fmt.Errorf("%w: %s", ErrPhotoNotFound, href)
+		}
+	}
+
+	order := make([]string, 0, len(photos))
+	placed := make(map[string]bool, len(photos))
+	place := func(href string) {
+		if href != "" && !placed[href] {
+			placed[href] = true
+			order = append(order, href)
+		}
+	}
+	place(primary)
+	for _, href := range a.Order {
+		place(href)
+	}
+	for _, p := range photos {
+		place(p.href)
+	}
+	// Clear the old primary first; the unique index is checked row by row.
+	if _, err = tx.Exec(ctx, `
+		UPDATE ingest_listing_photos SET is_primary = false
+		WHERE listing_id=$1 AND is_primary AND href <> $2`, listingUUID, primary); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, `
+		UPDATE ingest_listing_photos lp
+		SET position = o.ord - 1, is_primary = (lp.href = $3), curated_at = now()
+		FROM unnest($2::text[]) WITH ORDINALITY AS o(href, ord)
+		WHERE lp.listing_id = $1 AND lp.href = o.href`, listingUUID, order, primary); err != nil {
+		return err
+	}
+	if err = refreshListingSummariesTx(ctx, tx, nil, []string{listingUUID}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// listing_summaries holds one card-ready row per live listing: the listing,
+// its property's address and location, and its distinct photos, so the ZIP
+// and city listing pages are served by one query without joins or a
+// follow-up photo query. Columns are named as in ingest_properties and
+// ingest_listings so the page queries and listingOrders read it through
+// the same l alias. Every write that changes what a page shows refreshes
+// the affected rows in its own transaction with refreshListingSummariesTx.
+//
+// listingSummaryColumns are the columns a refresh writes, in the order
+// sqlListingSummarySource selects them.
+const listingSummaryColumns = `id, property_id, property_key, address_line1, city, state, zip, city_key, lat, lon,
+		listing_id, list_price, beds, baths, sqft, property_type, list_date, flags, extras,
+		primary_photo, photos, created_at, updated_at, last_fetch_at, stale_after`
+
+// sqlListingSummarySource selects the summary row of every live listing;
+// callers narrow it with further AND conditions.
+const sqlListingSummarySource = `
+		SELECT l.id, p.id AS property_id, p.property_key, p.address_line1, p.city, p.state, p.zip, p.city_key, p.lat, p.lon,
+		       l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type, l.list_date, l.flags, l.extras,
+		       ph.photos[1] AS primary_photo, ph.photos, l.created_at, l.updated_at, l.last_fetch_at, l.stale_after
+		FROM ingest_listings l
+		JOIN ingest_properties p ON p.id = l.property_id
+		LEFT JOIN LATERAL (
+			SELECT array_agg(lp.href ORDER BY lp.position, lp.created_at) AS photos
+			FROM ` + distinctListingPhotos + ` lp
+			WHERE lp.phash_rank = 1
+		) ph ON true
+		WHERE l.deleted_at IS NULL AND p.deleted_at IS NULL`
+
+// sqlBackfillListingSummaries fills listing_summaries the first time
+// Migrate creates it; later migrations leave the maintained rows alone.
+const sqlBackfillListingSummaries = `
+		INSERT INTO listing_summaries (` + listingSummaryColumns + `)` + sqlListingSummarySource + `
+		  AND NOT EXISTS (SELECT 1 FROM listing_summaries)
+		ON CONFLICT (id) DO NOTHING`
+
+// sqlRefreshListingSummaries rebuilds the summaries of the listings under
+// properties $1 and of listings $2: live ones are upserted, and rows for
+// listings that were deleted, or whose property was, are dropped.
+const sqlRefreshListingSummaries = `
+		WITH src AS (` + sqlListingSummarySource + `
+		  AND (l.property_id = ANY($1::uuid[]) OR l.id = ANY($2::uuid[]))
+		), gone AS (
+			DELETE FROM listing_summaries s
+			WHERE (s.property_id = ANY($1::uuid[]) OR s.id = ANY($2::uuid[]))
+			  AND s.id NOT IN (SELECT id FROM src)
+		)
+		INSERT INTO listing_summaries (` + listingSummaryColumns + `)
+		SELECT * FROM src
+		ON CONFLICT (id) DO UPDATE SET
+			property_id=EXCLUDED.property_id, property_key=EXCLUDED.property_key, address_line1=EXCLUDED.address_line1,
+			city=EXCLUDED.city, state=EXCLUDED.state, zip=EXCLUDED.zip, city_key=EXCLUDED.city_key, lat=EXCLUDED.lat, lon=EXCLUDED.lon,
+			listing_id=EXCLUDED.listing_id, list_price=EXCLUDED.list_price, beds=EXCLUDED.beds, baths=EXCLUDED.baths, sqft=EXCLUDED.sqft,
+			property_type=EXCLUDED.property_type, list_date=EXCLUDED.list_date, flags=EXCLUDED.flags, extras=EXCLUDED.extras,
+			primary_photo=EXCLUDED.primary_photo, photos=EXCLUDED.photos, created_at=EXCLUDED.created_at,
+			updated_at=EXCLUDED.updated_at, last_fetch_at=EXCLUDED.last_fetch_at, stale_after=EXCLUDED.stale_after, refreshed_at=now()`
+
+// refreshListingSummariesTx brings the summaries of every listing under
+// propertyIDs, and of listingIDs wherever they now live, up to date with
+// the writes tx has made so far.
+func refreshListingSummariesTx(ctx context.Context, tx pgx.Tx, propertyIDs, listingIDs []string) error {
+	if len(propertyIDs) == 0 && len(listingIDs) == 0 {
+		return nil
+	}
+	if propertyIDs == nil {
+		propertyIDs = []string{}
+	}
+	if listingIDs == nil {
+		listingIDs = []string{}
+	}
+	_, err := tx.Exec(ctx, sqlRefreshListingSummaries, propertyIDs, listingIDs)
+	return err
+}
+
+// collectListingSummaries scans rows shaped like sqlFetchListingsByPostal,
+// which carry each listing's photos.
+func collectListingSummaries(rows pgx.Rows) ([]ListingRecord, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ListingRecord, error) {
+		var rec ListingRecord
+		err := row.Scan(&rec.PropertyKey, &rec.AddressLine1, &rec.City, &rec.State, &rec.Zip,
+			&rec.Lat, &rec.Lon, &rec.ListingID, &rec.ListingExternalID, &rec.ListPrice, &rec.Beds, &rec.Baths, &rec.Sqft, &rec.PropertyType, &rec.ListDate,
+			&rec.Features, &rec.Photos, &rec.LastFetchAt, &rec.StaleAfter)
+		return rec, err
+	})
+}
@@ -0,0 +1,34 @@
+package store
+
+import "context"
+
+// Listings is the listing cache: the hydrator's writes and the reads that
+// serve listing pages, listing details and photos from them. Store
+// implements it on Postgres and Memory in process, for tests and small
+// deployments without a database. Everything else the API stores, such as
+// history, enrichments, markets and audit, needs Postgres; see Postgres.
+type Listings interface {
+	Ping(ctx context.Context) error
+	WriteSnapshotAndUpsert(ctx context.Context, in UpsertInput) (UpsertResult, error)
+	SaveDriftReport(ctx context.Context, r DriftReport) error
+	SyncListingPhotos(ctx context.Context, providerListingID string, photos []ListingPhotoInput) (PhotoDiff, error)
+	FetchListingsByPostal(ctx context.Context, postal string, limit, offset int, f ListingFilter) ([]ListingRecord, error)
+	FetchListingsByCity(ctx context.Context, city, state string, limit, offset int, f ListingFilter) ([]ListingRecord, error)
+	FetchListingsByProperty(ctx context.Context, propertyKey string, limit int) ([]ListingRecord, error)
+	FetchListingDetail(ctx context.Context, providerListingID string) (*ListingRecord, error)
+	FetchListingPhotos(ctx context.Context, providerListingID string) ([]string, error)
+	FetchListingPhotoAssets(ctx context.Context, providerListingID string) ([]ListingPhoto, error)
+	LookupPropertyKeyByListing(ctx context.Context, providerListingID string) (string, error)
+}
+
+var (
+	_ Listings = (*Store)(nil)
+	_ Listings = (*Memory)(nil)
+)
+
+// Postgres returns the Postgres store behind l, or nil when l is nil or
+// another implementation.
+func Postgres(l Listings) *Store {
+	s, _ := l.(*Store)
+	return s
+}
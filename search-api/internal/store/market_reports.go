@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MarketSegment is a property type and bedroom count (5 meaning 5 or more)
+// sold in a ZIP during a report's week.
+type MarketSegment struct {
+	PropertyType       string  `json:"property_type"`
+	Beds               *int    `json:"beds"`
+	Sold               int     `json:"sold"`
+	MedianDaysOnMarket float64 `json:"median_days_on_market"`
+}
+
+// MarketReport is one ZIP's weekly summary, built from the days of
+// market_daily_stats in the week starting WeekStart (a Monday).
+type MarketReport struct {
+	Zip         string
+	WeekStart   time.Time
+	NewListings int
+	SoldCount   int
+	// ActiveCount and MedianPrice are as of the week's last rolled-up day;
+	// PrevMedianPrice is the same for the week before.
+	ActiveCount     int
+	MedianPrice     sql.NullFloat64
+	PrevMedianPrice sql.NullFloat64
+	// Segments are the fastest-selling segments, quickest first.
+	Segments    []MarketSegment
+	GeneratedAt time.Time
+}
+
+// MarketReportWeek is a week with generated reports.
+type MarketReportWeek struct {
+	WeekStart   time.Time
+	Zips        int
+	GeneratedAt time.Time
+}
+
+// maxReportSegments is how many of a ZIP's fastest-selling segments a
+// report keeps.
+const maxReportSegments = 5
+
+// GenerateMarketReports writes the weekly report of every ZIP with daily
+// stats in the week starting weekStart. Days on market run from the list
+// date (or first sighting) to the day the listing moved to sold, its
+// status_changed_at as in the daily rollup, so later re-upserts neither
+// move the sale into another week nor lengthen it. Re-running a week
+// overwrites it.
+func (s *Store) GenerateMarketReports(ctx context.Context, weekStart time.Time) (int64, error) {
+	if s.Pool == nil {
+		return 0, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tag, err := s.Pool.Exec(ctx, `
+		WITH week AS (
+			SELECT zip, sum(new_count) AS new_listings, sum(sold_count) AS sold_count,
+			       (array_agg(active_count ORDER BY day DESC))[1] AS active_count,
+			       (array_agg(median_price ORDER BY day DESC) FILTER (WHERE median_price IS NOT NULL))[1] AS median_price
+			FROM market_daily_stats
+			WHERE day >= $1::date AND day < $1::date + 7
+			GROUP BY zip
+		), prev AS (
+			SELECT zip, (array_agg(median_price ORDER BY day DESC) FILTER (WHERE median_price IS NOT NULL))[1] AS median_price
+			FROM market_daily_stats
+			WHERE day >= $1::date - 7 AND day < $1::date
+			GROUP BY zip
+		), seg AS (
+			SELECT zip, jsonb_agg(jsonb_build_object(
+			           'property_type', property_type, 'beds', beds, 'sold', sold, 'median_days_on_market', round(dom::numeric, 1))
+			           ORDER BY dom, sold DESC) AS segments
+			FROM (
+				SELECT *, row_number() OVER (PARTITION BY zip ORDER BY dom, sold DESC) AS rank
+				FROM (
+					SELECT p.zip, coalesce(l.property_type, '') AS property_type,
+					       CASE WHEN l.beds >= 5 THEN 5 ELSE l.beds END AS beds,
+					       count(*) AS sold,
+					       percentile_cont(0.5) WITHIN GROUP (
+					           ORDER BY greatest(extract(epoch FROM l.status_changed_at - coalesce(l.list_date, l.created_at)), 0) / 86400) AS dom
+					FROM ingest_listings l
+					JOIN ingest_properties p ON p.id = l.property_id
+					WHERE l.status = 'sold' AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+					  AND l.status_changed_at >= $1::date AND l.status_changed_at < $1::date + 7
+					GROUP BY 1, 2, 3
+				) g
+			) r
+			WHERE rank <= $2
+			GROUP BY zip
+		)
+		INSERT INTO market_weekly_reports (zip, week_start, new_listings, sold_count, active_count, median_price, prev_median_price, segments, generated_at)
+		SELECT w.zip, $1::date, w.new_listings, w.sold_count, w.active_count, w.median_price, prev.median_price, coalesce(seg.segments, '[]'), now()
+		FROM week w
+		LEFT JOIN prev USING (zip)
+		LEFT JOIN seg USING (zip)
+		ON CONFLICT (week_start, zip) DO UPDATE SET
+			new_listings=EXCLUDED.new_listings, sold_count=EXCLUDED.sold_count, active_count=EXCLUDED.active_count,
+			median_price=EXCLUDED.median_price, prev_median_price=EXCLUDED.prev_median_price,
+			segments=EXCLUDED.segments, generated_at=now()
+	`, weekStart.UTC().Format("2006-01-02"), maxReportSegments)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// MarketReports returns the week's reports ordered by ZIP, or only zip's
+// when it is set.
+func (s *Store) MarketReports(ctx context.Context, weekStart time.Time, zip string) ([]MarketReport, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT zip, week_start, new_listings, sold_count, active_count, median_price, prev_median_price, segments, generated_at
+		FROM market_weekly_reports
+		WHERE week_start = $1::date AND ($2 = '' OR zip = $2)
+		ORDER BY zip
+	`, weekStart.UTC().Format("2006-01-02"), zip)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (MarketReport, error) {
+		var m MarketReport
+		var segments []byte
+		if err := row.Scan(&m.Zip, &m.WeekStart, &m.NewListings, &m.SoldCount, &m.ActiveCount, &m.MedianPrice, &m.PrevMedianPrice, &segments, &m.GeneratedAt); err != nil {
+			return m, err
+		}
+		return m, json.Unmarshal(segments, &m.Segments)
+	})
+}
+
+// MarketReportWeeks lists the most recent weeks with reports, newest first.
+func (s *Store) MarketReportWeeks(ctx context.Context, limit int) ([]MarketReportWeek, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT week_start, count(*), max(generated_at)
+		FROM market_weekly_reports
+		GROUP BY week_start
+		ORDER BY week_start DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (MarketReportWeek, error) {
+		var w MarketReportWeek
+		err := row.Scan(&w.WeekStart, &w.Zips, &w.GeneratedAt)
+		return w, err
+	})
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MarketDailyStat is one row of the per-ZIP daily rollup.
+type MarketDailyStat struct {
+	Zip         string
+	Day         time.Time
+	MedianPrice sql.NullFloat64
+	ActiveCount int
+	NewCount    int
+	SoldCount   int
+}
+
+// RollupMarketDailyStats snapshots every ZIP's market for the given day into
+// market_daily_stats. Active inventory and median price reflect the listings
+// as they stand when the rollup runs; new and sold counts are the listings
+// created or moved to sold on that day, by status_changed_at so re-upserting
+// a sold listing does not count it again. Re-running a day overwrites it.
+func (s *Store) RollupMarketDailyStats(ctx context.Context, day time.Time) (int64, error) {
+	if s.Pool == nil {
+		return 0, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tag, err := s.Pool.Exec(ctx, `
+		INSERT INTO market_daily_stats (zip, day, median_price, active_count, new_count, sold_count, computed_at)
+		SELECT p.zip, $1::date,
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY l.list_price) FILTER (WHERE l.status = 'for_sale'),
+		       count(*) FILTER (WHERE l.status = 'for_sale'),
+		       count(*) FILTER (WHERE l.created_at::date = $1::date),
+		       count(*) FILTER (WHERE l.status = 'sold' AND l.status_changed_at::date = $1::date),
+		       now()
+		FROM ingest_listings l
+		JOIN ingest_properties p ON p.id = l.property_id
+		WHERE l.deleted_at IS NULL AND p.deleted_at IS NULL
+		GROUP BY p.zip
+		ON CONFLICT (zip, day) DO UPDATE SET
+			median_price=EXCLUDED.median_price, active_count=EXCLUDED.active_count,
+			new_count=EXCLUDED.new_count, sold_count=EXCLUDED.sold_count, computed_at=now()
+	`, day.UTC().Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// FetchMarketTrends returns the daily series for a ZIP since the given day, oldest first.
+func (s *Store) FetchMarketTrends(ctx context.Context, zip string, since time.Time) ([]MarketDailyStat, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT zip, day, median_price, active_count, new_count, sold_count
+		FROM market_daily_stats
+		WHERE zip = $1 AND day >= $2::date
+		ORDER BY day
+	`, zip, since.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (MarketDailyStat, error) {
+		var m MarketDailyStat
+		err := row.Scan(&m.Zip, &m.Day, &m.MedianPrice, &m.ActiveCount, &m.NewCount, &m.SoldCount)
+		return m, err
+	})
+}
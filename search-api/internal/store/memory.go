@@ -0,0 +1,457 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/county"
+	"github.com/yourorg/search-api/internal/proptype"
+)
+
+// Memory is an in-process Listings for tests and deployments without
+// Postgres. It keeps the latest state of each listing and its photos; raw
+// snapshots, open houses and drift reports are not kept, and nothing
+// survives a restart. Property keys are matched exactly, without the aliases
+// Postgres records for merged properties. Use NewMemory to create one.
+type Memory struct {
+	// MaxListings evicts the least recently written listings past this
+	// many; zero keeps every listing.
+	MaxListings int
+
+	mu         sync.RWMutex
+	properties map[string]*memProperty // by property key
+	listings   map[string]*memListing  // by listing ID
+	sources    map[string]string       // provider listing identity -> listing ID
+}
+
+type memProperty struct {
+	id, key               string
+	address1, city, state string
+	zip, cityKey          string
+	lat, lon              sql.NullFloat64
+	parcel                Parcel
+	county                county.County
+}
+
+type memListing struct {
+	id, source   string
+	property     *memProperty
+	listingID    sql.NullString
+	status       string
+	price        sql.NullFloat64
+	beds         sql.NullInt64
+	baths        sql.NullFloat64
+	sqft         sql.NullInt64
+	propertyType sql.NullString
+	listDate     sql.NullTime
+	features     ListingFeatures
+	agents       []ListingAgent
+	photos       []ListingPhoto
+	created      time.Time
+	updated      time.Time
+}
+
+// NewMemory returns an empty Memory holding at most maxListings listings,
+// or any number when maxListings is zero.
+func NewMemory(maxListings int) *Memory {
+	return &Memory{
+		MaxListings: maxListings,
+		properties:  map[string]*memProperty{},
+		listings:    map[string]*memListing{},
+		sources:     map[string]string{},
+	}
+}
+
+func (m *Memory) Ping(ctx context.Context) error { return nil }
+
+// SaveDriftReport discards the report; drift reports are only kept in
+// Postgres.
+func (m *Memory) SaveDriftReport(ctx context.Context, r DriftReport) error { return nil }
+
+// WriteSnapshotAndUpsert records the property and listing. The raw payload
+// is not kept.
+func (m *Memory) WriteSnapshotAndUpsert(ctx context.Context, in UpsertInput) (UpsertResult, error) {
+	var res UpsertResult
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+
+	p := m.properties[in.PropertyKey]
+	if p == nil {
+		p = &memProperty{id: newMemoryID(), key: in.PropertyKey}
+		m.properties[in.PropertyKey] = p
+	}
+	p.address1, p.city, p.state, p.zip = in.Address1, in.City, in.State, in.Zip
+	p.cityKey, p.lat, p.lon = canon.CityKey(in.City), in.Lat, in.Lon
+	if apn := canon.APN(in.Parcel.APN); apn != "" {
+		p.parcel.APN = apn
+	}
+	if fips := canon.FIPS(in.Parcel.FIPS); fips != "" {
+		p.parcel.FIPS = fips
+	}
+	if fips := canon.FIPS(in.County.FIPS); fips != "" {
+		p.county = county.County{FIPS: fips, Name: in.County.Name, State: in.County.State}
+	}
+
+	source := in.Provider + "\x00" + in.SourceID + "\x00" + in.ListingID.String
+	l := m.listings[m.sources[source]]
+	if l == nil {
+		res.Inserted = true
+		l = &memListing{id: newMemoryID(), source: source, listingID: in.ListingID, created: now}
+		m.listings[l.id] = l
+		m.sources[source] = l.id
+	} else {
+		res.PrevStatus, res.PrevPrice = l.status, l.price
+	}
+	l.property = p
+	l.status, l.price, l.beds, l.baths, l.sqft = in.Status, in.ListPrice, in.Beds, in.Baths, in.Sqft
+	if in.PropertyType != "" {
+		l.propertyType = sql.NullString{String: in.PropertyType, Valid: true}
+	}
+	if in.ListDate.Valid {
+		l.listDate = in.ListDate
+	}
+	l.features = l.features.merge(in.Features)
+	if len(in.Agents) > 0 {
+		l.agents = slices.Clone(in.Agents)
+	}
+	l.updated = now
+	res.PropertyID, res.ListingID = p.id, l.id
+	if len(in.Photos) > 0 && !(in.InlinePhotos && len(l.photos) > 0) {
+		res.Photos = l.syncPhotos(in.Photos, in.InlinePhotos)
+		res.Photos.PropertyID, res.Photos.PropertyKey = p.id, p.key
+	}
+	m.evict()
+	return res, nil
+}
+
+// SyncListingPhotos replaces the photos of the most recently written listing
+// with the provider listing ID; an unknown listing is a no-op.
+func (m *Memory) SyncListingPhotos(ctx context.Context, providerListingID string, photos []ListingPhotoInput) (PhotoDiff, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.latest(providerListingID)
+	if l == nil {
+		return PhotoDiff{}, nil
+	}
+	diff := l.syncPhotos(photos, false)
+	diff.PropertyID, diff.PropertyKey = l.property.id, l.property.key
+	return diff, nil
+}
+
+func (m *Memory) FetchListingsByPostal(ctx context.Context, postal string, limit, offset int, f ListingFilter) ([]ListingRecord, error) {
+	return m.page(func(p *memProperty) bool { return p.zip == postal }, limit, offset, f), nil
+}
+
+// FetchListingsByCity matches the city on canon.CityKey, like Store.
+func (m *Memory) FetchListingsByCity(ctx context.Context, city, state string, limit, offset int, f ListingFilter) ([]ListingRecord, error) {
+	key := canon.CityKey(city)
+	return m.page(func(p *memProperty) bool { return p.cityKey == key && strings.EqualFold(p.state, state) }, limit, offset, f), nil
+}
+
+// FetchListingsByProperty returns the property's listings, newest first.
+func (m *Memory) FetchListingsByProperty(ctx context.Context, propertyKey string, limit int) ([]ListingRecord, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var matched []*memListing
+	for _, l := range m.listings {
+		if l.property.key == propertyKey {
+			matched = append(matched, l)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *memListing) int { return memCompare(SortNewest, a, b) })
+	return memRecords(matched, 0, limit), nil
+}
+
+func (m *Memory) FetchListingDetail(ctx context.Context, providerListingID string) (*ListingRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	l := m.latest(providerListingID)
+	if l == nil {
+		return nil, nil
+	}
+	rec := l.record()
+	rec.PhotoAssets = slices.Clone(l.photos)
+	return &rec, nil
+}
+
+func (m *Memory) FetchListingPhotos(ctx context.Context, providerListingID string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.latest(providerListingID); l != nil {
+		return l.photoHrefs(), nil
+	}
+	return nil, nil
+}
+
+func (m *Memory) FetchListingPhotoAssets(ctx context.Context, providerListingID string) ([]ListingPhoto, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.latest(providerListingID); l != nil {
+		return slices.Clone(l.photos), nil
+	}
+	return nil, nil
+}
+
+func (m *Memory) LookupPropertyKeyByListing(ctx context.Context, providerListingID string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.latest(providerListingID); l != nil {
+		return l.property.key, nil
+	}
+	return "", nil
+}
+
+// page filters, orders and pages listings the way the Postgres listing page
+// queries do.
+func (m *Memory) page(where func(*memProperty) bool, limit, offset int, f ListingFilter) []ListingRecord {
+	if limit <= 0 {
+		limit = 5
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	var matched []*memListing
+	for _, l := range m.listings {
+		if where(l.property) && f.matches(l, now) {
+			matched = append(matched, l)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *memListing) int { return memCompare(f.Sort, a, b) })
+	return memRecords(matched, offset, limit)
+}
+
+// latest is the most recently written listing with the provider listing
+// ID, or nil.
+func (m *Memory) latest(providerListingID string) *memListing {
+	var found *memListing
+	for _, l := range m.listings {
+		if l.listingID.Valid && l.listingID.String == providerListingID && (found == nil || l.updated.After(found.updated)) {
+			found = l
+		}
+	}
+	return found
+}
+
+// evict drops the least recently written listings past MaxListings, and
+// their properties once no listing is left on them.
+func (m *Memory) evict() {
+	for m.MaxListings > 0 && len(m.listings) > m.MaxListings {
+		var oldest *memListing
+		for _, l := range m.listings {
+			if oldest == nil || l.updated.Before(oldest.updated) {
+				oldest = l
+			}
+		}
+		delete(m.listings, oldest.id)
+		delete(m.sources, oldest.source)
+		orphan := true
+		for _, l := range m.listings {
+			if l.property == oldest.property {
+				orphan = false
+				break
+			}
+		}
+		if orphan {
+			delete(m.properties, oldest.property.key)
+		}
+	}
+}
+
+// syncPhotos replaces the listing's photos, ordered by position, and
+// reports the change like syncListingPhotosTx.
+func (l *memListing) syncPhotos(photos []ListingPhotoInput, inline bool) PhotoDiff {
+	diff := PhotoDiff{ListingID: l.id}
+	var next []ListingPhoto
+	seen := make(map[string]bool, len(photos))
+	for idx, photo := range photos {
+		if photo.Href == "" || seen[photo.Href] {
+			continue
+		}
+		seen[photo.Href] = true
+		position := photo.Position
+		if position < 0 {
+			position = idx
+		}
+		next = append(next, ListingPhoto{
+			Href: photo.Href, Description: photo.Description, Title: photo.Title, Kind: photo.Kind,
+			MediaType: photo.MediaType, Tags: slices.Clone(photo.Tags), Position: position, Room: photo.Room,
+			Primary: photo.Primary, Inline: inline,
+		})
+	}
+	slices.SortStableFunc(next, func(a, b ListingPhoto) int { return cmp.Compare(a.Position, b.Position) })
+	before := make(map[string]string, len(l.photos))
+	for _, p := range l.photos {
+		before[p.Href] = p.Room
+		if !seen[p.Href] {
+			diff.Removed++
+		}
+	}
+	for i, p := range next {
+		room, ok := before[p.Href]
+		if !ok {
+			diff.Added++
+		} else if p.Room == "" {
+			// An empty Room keeps the room already assigned.
+			next[i].Room = room
+		}
+	}
+	l.photos = next
+	diff.Count = len(l.photos)
+	return diff
+}
+
+func (l *memListing) photoHrefs() []string {
+	out := make([]string, 0, len(l.photos))
+	for _, p := range l.photos {
+		out = append(out, p.Href)
+	}
+	return out
+}
+
+func (l *memListing) record() ListingRecord {
+	p := l.property
+	return ListingRecord{
+		PropertyKey: p.key, AddressLine1: p.address1, City: p.city, State: p.state, Zip: p.zip,
+		Lat: p.lat, Lon: p.lon, ListingID: l.id, ListingExternalID: l.listingID,
+		ListPrice: l.price, Beds: l.beds, Baths: l.baths, Sqft: l.sqft, PropertyType: l.propertyType,
+		Status: l.status, Parcel: p.parcel, County: p.county, ListDate: l.listDate, Features: l.features,
+		Photos: l.photoHrefs(), Agents: slices.Clone(l.agents),
+		// Every write is a fetch; Postgres marks listings stale five minutes on.
+		LastFetchAt: sql.NullTime{Time: l.updated, Valid: true},
+		StaleAfter:  sql.NullTime{Time: l.updated.Add(5 * time.Minute), Valid: true},
+	}
+}
+
+// listedAt is when the listing came on the market, or was first written
+// when the provider gave no list date.
+func (l *memListing) listedAt() time.Time {
+	if l.listDate.Valid {
+		return l.listDate.Time
+	}
+	return l.created
+}
+
+func memRecords(listings []*memListing, offset, limit int) []ListingRecord {
+	if offset >= len(listings) {
+		return nil
+	}
+	listings = listings[offset:min(offset+limit, len(listings))]
+	out := make([]ListingRecord, len(listings))
+	for i, l := range listings {
+		out[i] = l.record()
+	}
+	return out
+}
+
+// memCompare orders listings like listingOrders, ending on the listing ID.
+func memCompare(sort ListingSort, a, b *memListing) int {
+	var c int
+	switch sort {
+	case SortPriceAsc:
+		c = nullsLast(a.price.Valid, b.price.Valid, cmp.Compare(a.price.Float64, b.price.Float64))
+	case SortPriceDesc:
+		c = nullsLast(a.price.Valid, b.price.Valid, cmp.Compare(b.price.Float64, a.price.Float64))
+	case SortNewest:
+		c = b.listedAt().Compare(a.listedAt())
+	case SortSqft:
+		c = nullsLast(a.sqft.Valid, b.sqft.Valid, cmp.Compare(b.sqft.Int64, a.sqft.Int64))
+	case SortBeds:
+		c = cmp.Or(
+			nullsLast(a.beds.Valid, b.beds.Valid, cmp.Compare(b.beds.Int64, a.beds.Int64)),
+			nullsLast(a.price.Valid, b.price.Valid, cmp.Compare(a.price.Float64, b.price.Float64)),
+		)
+	default:
+		c = b.updated.Compare(a.updated)
+	}
+	return cmp.Or(c, cmp.Compare(a.id, b.id))
+}
+
+// nullsLast is c when both values are present and otherwise orders the
+// missing one last.
+func nullsLast(aValid, bValid bool, c int) int {
+	switch {
+	case aValid && bValid:
+		return c
+	case aValid:
+		return -1
+	case bValid:
+		return 1
+	}
+	return 0
+}
+
+// matches applies the filter the way the listing page queries do.
+func (f ListingFilter) matches(l *memListing, now time.Time) bool {
+	if t := proptype.Normalize(f.PropertyType); t != "" && l.propertyType.String != t {
+		return false
+	}
+	if f.MinDOM != nil || f.MaxDOM != nil {
+		if !l.listDate.Valid {
+			return false
+		}
+		if f.MinDOM != nil && l.listDate.Time.After(now.AddDate(0, 0, -*f.MinDOM)) {
+			return false
+		}
+		if f.MaxDOM != nil && !l.listDate.Time.After(now.AddDate(0, 0, -*f.MaxDOM-1)) {
+			return false
+		}
+	}
+	ff, lf := f.Features, l.features
+	if ff.MaxHOA != nil && lf.HOAFee != nil && *lf.HOAFee > *ff.MaxHOA {
+		return false
+	}
+	if ff.MinGarage != nil && (lf.GarageSpaces == nil || *lf.GarageSpaces < *ff.MinGarage) {
+		return false
+	}
+	if ff.MinStories != nil && (lf.Stories == nil || *lf.Stories < *ff.MinStories) {
+		return false
+	}
+	for _, b := range [][2]*bool{
+		{ff.Pool, lf.Pool}, {ff.Basement, lf.Basement}, {ff.NewConstruction, lf.NewConstruction},
+		{ff.Foreclosure, lf.Foreclosure}, {ff.PriceReduced, lf.PriceReduced},
+	} {
+		if want, have := b[0], b[1]; want != nil && *want != (have != nil && *have) {
+			return false
+		}
+	}
+	return true
+}
+
+// merge overlays the fields o reports onto f, as the listing upsert merges
+// flags and extras.
+func (f ListingFeatures) merge(o ListingFeatures) ListingFeatures {
+	f.HOAFee = cmp.Or(o.HOAFee, f.HOAFee)
+	f.GarageSpaces = cmp.Or(o.GarageSpaces, f.GarageSpaces)
+	f.Stories = cmp.Or(o.Stories, f.Stories)
+	f.Pool = cmp.Or(o.Pool, f.Pool)
+	f.Basement = cmp.Or(o.Basement, f.Basement)
+	f.NewConstruction = cmp.Or(o.NewConstruction, f.NewConstruction)
+	f.Foreclosure = cmp.Or(o.Foreclosure, f.Foreclosure)
+	f.PriceReduced = cmp.Or(o.PriceReduced, f.PriceReduced)
+	return f
+}
+
+// newMemoryID returns a random version 4 UUID, the shape of the IDs
+// Postgres assigns.
+func newMemoryID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yourorg/search-api/internal/logger"
+)
+
+// queryStats is published as store_queries: per store operation, how many
+// queries ran, failed and were slow, the rows they returned or changed, and
+// their total and worst time. An operation is the exported Store method (or
+// unexported helper) that issued the query, so FetchListingsByPostal shows
+// up under its own name however many helpers it goes through.
+var queryStats sync.Map // operation -> *queryStat
+
+type queryStat struct {
+	calls       atomic.Int64
+	errors      atomic.Int64
+	slow        atomic.Int64
+	rows        atomic.Int64
+	totalMicros atomic.Int64
+	maxMicros   atomic.Int64
+}
+
+// slowQueryMicros is the slow-query log threshold; 0 disables the log.
+var slowQueryMicros atomic.Int64
+
+func init() {
+	expvar.Publish("store_queries", expvar.Func(queryStatsSnapshot))
+	slowQueryMicros.Store((500 * time.Millisecond).Microseconds())
+}
+
+// SetSlowQueryThreshold sets how long a query or batch may take before it
+// is logged with its SQL, default 500ms. Zero or less turns the log off.
+// It applies to every pool, including ones already open.
+func SetSlowQueryThreshold(d time.Duration) {
+	slowQueryMicros.Store(max(d.Microseconds(), 0))
+}
+
+// maxLoggedSQL caps the statement text of a slow-query log line.
+const maxLoggedSQL = 500
+
+type queryMetaKey struct{}
+
+// queryMeta rides the query context from the tracer's start hook to its
+// end hook.
+type queryMeta struct {
+	op    string
+	sql   string
+	start time.Time
+}
+
+func startQueryMetrics(ctx context.Context, sql string) context.Context {
+	return context.WithValue(ctx, queryMetaKey{}, queryMeta{op: callerOperation(), sql: sql, start: time.Now()})
+}
+
+func endQueryMetrics(ctx context.Context, rows int64, err error) {
+	logger.CountQuery(ctx)
+	m, ok := ctx.Value(queryMetaKey{}).(queryMeta)
+	if !ok {
+		return
+	}
+	took := time.Since(m.start)
+	st := statFor(m.op)
+	st.calls.Add(1)
+	st.rows.Add(rows)
+	st.totalMicros.Add(took.Microseconds())
+	st.observeMax(took.Microseconds())
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		st.errors.Add(1)
+	}
+	if limit := slowQueryMicros.Load(); limit > 0 && took.Microseconds() >= limit {
+		st.slow.Add(1)
+		status := "rows=" + strconv.FormatInt(rows, 10)
+		if err != nil {
+			status = "error: " + err.Error()
+		}
+		log.Printf("[WARN] slow query %s took %s (%s): %s", m.op, took.Round(time.Millisecond), status, logSQL(m.sql))
+	}
+}
+
+func (st *queryStat) observeMax(micros int64) {
+	for {
+		cur := st.maxMicros.Load()
+		if micros <= cur || st.maxMicros.CompareAndSwap(cur, micros) {
+			return
+		}
+	}
+}
+
+func statFor(op string) *queryStat {
+	if st, ok := queryStats.Load(op); ok {
+		return st.(*queryStat)
+	}
+	st, _ := queryStats.LoadOrStore(op, &queryStat{})
+	return st.(*queryStat)
+}
+
+func queryStatsSnapshot() any {
+	out := map[string]map[string]any{}
+	queryStats.Range(func(k, v any) bool {
+		st := v.(*queryStat)
+		calls, total := st.calls.Load(), st.totalMicros.Load()
+		avg := 0.0
+		if calls > 0 {
+			avg = float64(total) / float64(calls) / 1000
+		}
+		out[k.(string)] = map[string]any{
+			"calls":    calls,
+			"errors":   st.errors.Load(),
+			"slow":     st.slow.Load(),
+			"rows":     st.rows.Load(),
+			"total_ms": total / 1000,
+			"avg_ms":   avg,
+			"max_ms":   float64(st.maxMicros.Load()) / 1000,
+		}
+		return true
+	})
+	return out
+}
+
+// storeFuncPrefix is the qualified name prefix of this package's functions.
+var storeFuncPrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	slash := strings.LastIndex(name, "/")
+	return name[:slash+strings.Index(name[slash:], ".")+1]
+}()
+
+// queryHelpers issue queries on behalf of their caller, which names the
+// operation instead.
+var queryHelpers = map[string]bool{
+	"queryRead": true, "queryRowRead": true, "queryPrepared": true,
+	"startQueryMetrics": true, "callerOperation": true,
+	"queryTracer.TraceQueryStart": true, "queryTracer.TraceBatchStart": true,
+}
+
+// callerOperation names the store function that issued the query being
+// traced: the innermost caller in this package that isn't a query helper,
+// without receiver or closure suffixes.
+func callerOperation() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		f, more := frames.Next()
+		if name, ok := strings.CutPrefix(f.Function, storeFuncPrefix); ok {
+			name = strings.TrimPrefix(name, "(*Store).")
+			if i := strings.Index(name, ".func"); i > 0 {
+				name = name[:i]
+			}
+			if !queryHelpers[name] {
+				return name
+			}
+		}
+		if !more {
+			return "other"
+		}
+	}
+}
+
+func logSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxLoggedSQL {
+		return sql[:maxLoggedSQL] + "…"
+	}
+	return sql
+}
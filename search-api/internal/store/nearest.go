@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// NearbyProperty is a stored property near a point.
+type NearbyProperty struct {
+	PropertyKey    string
+	AddressLine1   string
+	City           string
+	State          string
+	Zip            string
+	DistanceMeters float64
+}
+
+// NearestProperty returns the stored property closest to lat/lon within
+// radiusMeters, or nil when there is none.
+func (s *Store) NearestProperty(ctx context.Context, lat, lon, radiusMeters float64) (*NearbyProperty, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var p NearbyProperty
+	err := s.queryRowRead(ctx, `
+		SELECT property_key, address_line1, city, state, zip, dist
+		FROM (
+			SELECT p.property_key, p.address_line1, p.city, p.state, p.zip,
+			       earth_distance(ll_to_earth($1, $2), ll_to_earth(p.lat, p.lon)) AS dist
+			FROM ingest_properties p
+			WHERE earth_box(ll_to_earth($1, $2), $3) @> ll_to_earth(p.lat, p.lon)
+			  AND p.deleted_at IS NULL
+		) near
+		WHERE dist <= $3
+		ORDER BY dist
+		LIMIT 1
+	`, []any{lat, lon, radiusMeters}, &p.PropertyKey, &p.AddressLine1, &p.City, &p.State, &p.Zip, &p.DistanceMeters)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
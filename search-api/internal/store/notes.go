@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Note subjects.
+const (
+	NoteProperty = "property"
+	NoteListing  = "listing"
+)
+
+// ErrNoteNotFound reports a note ID the owner has no note under.
+var ErrNoteNotFound = errors.New("note not found")
+
+// Note is an internal annotation on a property or listing. Notes belong to
+// the API key or token subject that wrote them and are only visible to it.
+type Note struct {
+	ID     string
+	Owner  string
+	Kind   string // NoteProperty or NoteListing
+	Target string // property key or provider listing ID
+	Body   string
+	Labels []string
+	// Author is the person who wrote the note, when several share a key;
+	// it defaults to the owner.
+	Author    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+const noteColumns = `id::text, owner, subject_kind, subject_id, body, labels, author, created_at, updated_at`
+
+func scanNote(row pgx.CollectableRow) (Note, error) {
+	var n Note
+	err := row.Scan(&n.ID, &n.Owner, &n.Kind, &n.Target, &n.Body, &n.Labels, &n.Author, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+// AddNote stores n and returns it with its ID and timestamps. It returns
+// ErrPropertyNotFound or ErrListingNotFound when the target does not exist.
+func (s *Store) AddNote(ctx context.Context, n Note) (Note, error) {
+	if s.Pool == nil {
+		return Note{}, errors.New("nil db")
+	}
+	var exists string
+	var notFound error
+	switch n.Kind {
+	case NoteProperty:
+		exists, notFound = `SELECT 1 FROM ingest_properties WHERE property_key = $3 AND deleted_at IS NULL`, ErrPropertyNotFound
+	case NoteListing:
+		exists, notFound = `SELECT 1 FROM ingest_listings WHERE listing_id = $3 AND deleted_at IS NULL`, ErrListingNotFound
+	default:
+		return Note{}, fmt.Errorf("unknown note kind %q", n.Kind)
+	}
+	if n.Labels == nil {
+		n.Labels = []string{}
+	}
+	if n.Author == "" {
+		n.Author = n.Owner
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		INSERT INTO notes (owner, subject_kind, subject_id, body, labels, author)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE EXISTS (`+exists+`)
+		RETURNING `+noteColumns, n.Owner, n.Kind, n.Target, n.Body, n.Labels, n.Author)
+	if err != nil {
+		return Note{}, err
+	}
+	out, err := pgx.CollectExactlyOneRow(rows, scanNote)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Note{}, fmt.Errorf("%w: %s", notFound, n.Target)
+	}
+	return out, err
+}
+
+// Notes returns owner's notes on one target, oldest first.
+func (s *Store) Notes(ctx context.Context, owner, kind, target string) ([]Note, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT `+noteColumns+` FROM notes
+		WHERE owner = $1 AND subject_kind = $2 AND subject_id = $3
+		ORDER BY created_at, id
+	`, owner, kind, target)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanNote)
+}
+
+// UpdateNote replaces the body and labels of one of owner's notes; a nil
+// body or labels leaves that field as it is.
+func (s *Store) UpdateNote(ctx context.Context, owner, id string, body *string, labels []string) (Note, error) {
+	if s.Pool == nil {
+		return Note{}, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		UPDATE notes SET body = COALESCE($3, body), labels = COALESCE($4, labels), updated_at = now()
+		WHERE owner = $1 AND id::text = $2
+		RETURNING `+noteColumns, owner, id, body, labels)
+	if err != nil {
+		return Note{}, err
+	}
+	n, err := pgx.CollectExactlyOneRow(rows, scanNote)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Note{}, fmt.Errorf("%w: %s", ErrNoteNotFound, id)
+	}
+	return n, err
+}
+
+// DeleteNote deletes one of owner's notes.
+func (s *Store) DeleteNote(ctx context.Context, owner, id string) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM notes WHERE owner = $1 AND id::text = $2`, owner, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrNoteNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// OpenHouse is a showing window for a listing.
+type OpenHouse struct {
+	Start       time.Time
+	End         time.Time
+	Description string
+}
+
+// UpcomingOpenHouse is an open house joined with the listing it belongs to.
+type UpcomingOpenHouse struct {
+	OpenHouse
+	ListingID    string
+	PropertyKey  string
+	AddressLine1 string
+	City         string
+	State        string
+	Zip          string
+}
+
+// syncOpenHousesTx upserts the listing's open houses keyed by start time and
+// drops any the provider no longer reports.
+func syncOpenHousesTx(ctx context.Context, tx pgx.Tx, listingUUID string, events []OpenHouse) error {
+	batch := &pgx.Batch{}
+	starts := make([]time.Time, 0, len(events))
+	for _, ev := range events {
+		if ev.Start.IsZero() {
+			continue
+		}
+		end := ev.End
+		if end.Before(ev.Start) {
+			end = ev.Start
+		}
+		starts = append(starts, ev.Start)
+		batch.Queue(`
+			INSERT INTO ingest_open_houses (listing_id, start_at, end_at, description)
+			VALUES ($1,$2,$3,$4)
+			ON CONFLICT (listing_id, start_at)
+			DO UPDATE SET end_at=EXCLUDED.end_at, description=EXCLUDED.description, updated_at=now()
+		`, listingUUID, ev.Start, end, nullString(ev.Description))
+	}
+	batch.Queue(`DELETE FROM ingest_open_houses WHERE listing_id=$1 AND NOT (start_at = ANY($2::timestamptz[]))`, listingUUID, starts)
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// FetchOpenHouses returns the open houses for a provider listing ID ordered by
+// start time. Past events are included only when includePast is set.
+func (s *Store) FetchOpenHouses(ctx context.Context, providerListingID string, includePast bool) ([]OpenHouse, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT oh.start_at, oh.end_at, COALESCE(oh.description, '')
+		FROM ingest_listings l
+		JOIN ingest_open_houses oh ON oh.listing_id = l.id
+		WHERE l.listing_id = $1 AND l.deleted_at IS NULL AND ($2 OR oh.end_at >= now())
+		ORDER BY oh.start_at
+	`, providerListingID, includePast)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (OpenHouse, error) {
+		var oh OpenHouse
+		err := row.Scan(&oh.Start, &oh.End, &oh.Description)
+		return oh, err
+	})
+}
+
+// FetchOpenHousesByIDs is FetchOpenHouses for many provider listing IDs in
+// one query. Listings without open houses are absent from the map.
+func (s *Store) FetchOpenHousesByIDs(ctx context.Context, providerListingIDs []string, includePast bool) (map[string][]OpenHouse, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	out := make(map[string][]OpenHouse, len(providerListingIDs))
+	if len(providerListingIDs) == 0 {
+		return out, nil
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT l.listing_id, oh.start_at, oh.end_at, COALESCE(oh.description, '')
+		FROM ingest_listings l
+		JOIN ingest_open_houses oh ON oh.listing_id = l.id
+		WHERE l.listing_id = ANY($1::text[]) AND l.deleted_at IS NULL AND ($2 OR oh.end_at >= now())
+		ORDER BY l.listing_id, oh.start_at
+	`, providerListingIDs, includePast)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var oh OpenHouse
+		if err := rows.Scan(&id, &oh.Start, &oh.End, &oh.Description); err != nil {
+			return nil, err
+		}
+		out[id] = append(out[id], oh)
+	}
+	return out, rows.Err()
+}
+
+// FetchUpcomingOpenHousesByPostal lists open houses in a ZIP that end after
+// from and start before until.
+func (s *Store) FetchUpcomingOpenHousesByPostal(ctx context.Context, postal string, from, until time.Time, limit int) ([]UpcomingOpenHouse, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT oh.start_at, oh.end_at, COALESCE(oh.description, ''),
+		       COALESCE(l.listing_id, l.source_id), p.property_key, p.address_line1, p.city, p.state, p.zip
+		FROM ingest_open_houses oh
+		JOIN ingest_listings l ON l.id = oh.listing_id
+		JOIN ingest_properties p ON p.id = l.property_id
+		WHERE p.zip = $1 AND oh.end_at >= $2 AND oh.start_at < $3
+		  AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY oh.start_at
+		LIMIT $4
+	`, postal, from, until, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (UpcomingOpenHouse, error) {
+		var u UpcomingOpenHouse
+		err := row.Scan(&u.Start, &u.End, &u.Description, &u.ListingID, &u.PropertyKey, &u.AddressLine1, &u.City, &u.State, &u.Zip)
+		return u, err
+	})
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/county"
+)
+
+// Parcel identifies the land a property sits on: the assessor parcel number
+// and the FIPS code of the county that assigned it. APNs are only unique
+// within a county. Empty fields are unknown.
+type Parcel struct {
+	APN  string
+	FIPS string
+}
+
+// apnArg and fipsArg are the normalized parcel fields as query arguments;
+// an unknown field is NULL so COALESCE keeps the stored value.
+func (p Parcel) apnArg() any  { return nullString(canon.APN(p.APN)) }
+func (p Parcel) fipsArg() any { return nullString(canon.FIPS(p.FIPS)) }
+
+// countyFIPSArg and countyNameArg are a county as query arguments; without
+// a FIPS code both are NULL so the stored county is kept.
+func countyFIPSArg(c county.County) any { return nullString(canon.FIPS(c.FIPS)) }
+func countyNameArg(c county.County) any {
+	if canon.FIPS(c.FIPS) == "" {
+		return nullString("")
+	}
+	return nullString(c.Name)
+}
+
+// ParcelProperty is a stored property recorded on a parcel.
+type ParcelProperty struct {
+	PropertyKey string
+	FIPS        string
+}
+
+// maxParcelProperties bounds LookupPropertiesByAPN; more matches than this
+// are not a lookup anyone can act on.
+const maxParcelProperties = 10
+
+// LookupPropertiesByAPN returns the live properties recorded on an APN,
+// most recently updated first. fips narrows the match to one county; when
+// it is empty the same number may match properties in several counties.
+func (s *Store) LookupPropertiesByAPN(ctx context.Context, apn, fips string) ([]ParcelProperty, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	apn = canon.APN(apn)
+	if apn == "" {
+		return nil, nil
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT p.property_key, COALESCE(p.fips, '')
+		FROM ingest_properties p
+		WHERE p.apn = $1 AND ($2 = '' OR p.fips = $2) AND p.deleted_at IS NULL
+		ORDER BY p.updated_at DESC
+		LIMIT $3
+	`, apn, canon.FIPS(fips), maxParcelProperties)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ParcelProperty
+	for rows.Next() {
+		var p ParcelProperty
+		if err := rows.Scan(&p.PropertyKey, &p.FIPS); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PhotoRef identifies a stored photo for background processing.
+type PhotoRef struct {
+	ID   string
+	Href string
+}
+
+// PhotoReuse is another listing that shares photos with the listing asked
+// about, by perceptual hash.
+type PhotoReuse struct {
+	ListingID    string
+	PropertyKey  string
+	Provider     string
+	Status       string
+	SameProperty bool
+	SharedPhotos int
+	FirstSeen    time.Time
+	Deleted      bool
+}
+
+// PhotosToHash returns up to limit photos that have no perceptual hash yet,
+// oldest first. Photos whose last attempt failed are retried after a week.
+func (s *Store) PhotosToHash(ctx context.Context, limit int) ([]PhotoRef, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		SELECT id, href FROM ingest_listing_photos
+		WHERE phash IS NULL AND (phash_at IS NULL OR phash_at < now() - interval '7 days')
+		ORDER BY created_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (PhotoRef, error) {
+		var p PhotoRef
+		err := row.Scan(&p.ID, &p.Href)
+		return p, err
+	})
+}
+
+// SavePhotoHash records the perceptual hash of a photo. ok=false records a
+// failed download or decode so the photo is not retried straight away.
+func (s *Store) SavePhotoHash(ctx context.Context, photoID string, hash uint64, ok bool) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var phash *int64
+	if ok {
+		v := int64(hash)
+		phash = &v
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	// A new hash can hide the photo as a duplicate, so the listing's
+	// summary is refreshed with it.
+	var listingID string
+	err = tx.QueryRow(ctx, `UPDATE ingest_listing_photos SET phash=$2, phash_at=now() WHERE id=$1 RETURNING listing_id`, photoID, phash).Scan(&listingID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = refreshListingSummariesTx(ctx, tx, nil, []string{listingID}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// FetchPhotoReuse lists other listings carrying any of the same images as a
+// provider listing, most shared photos first. Listings of other providers
+// with the same listing ID are the same listing and are left out. Deleted
+// listings are included since reuse from an old listing is the usual case.
+func (s *Store) FetchPhotoReuse(ctx context.Context, providerListingID string, limit int) ([]PhotoReuse, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT COALESCE(ol.listing_id, ol.source_id), op.property_key, ol.provider, ol.status,
+		       ol.property_id = l.property_id, count(DISTINCT other.phash), ol.created_at, ol.deleted_at IS NOT NULL
+		FROM ingest_listings l
+		JOIN ingest_listing_photos lp ON lp.listing_id = l.id AND lp.phash IS NOT NULL
+		JOIN ingest_listing_photos other ON other.phash = lp.phash AND other.listing_id <> l.id
+		JOIN ingest_listings ol ON ol.id = other.listing_id AND ol.listing_id IS DISTINCT FROM l.listing_id
+		JOIN ingest_properties op ON op.id = ol.property_id
+		WHERE l.id = (
+			SELECT id FROM ingest_listings WHERE listing_id = $1 AND deleted_at IS NULL ORDER BY updated_at DESC LIMIT 1
+		)
+		GROUP BY ol.id, op.property_key, l.property_id
+		ORDER BY count(DISTINCT other.phash) DESC, ol.created_at
+		LIMIT $2
+	`, providerListingID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (PhotoReuse, error) {
+		var r PhotoReuse
+		err := row.Scan(&r.ListingID, &r.PropertyKey, &r.Provider, &r.Status, &r.SameProperty,
+			&r.SharedPhotos, &r.FirstSeen, &r.Deleted)
+		return r, err
+	})
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PhotosToClassify returns up to limit photos with no room type that the
+// classifier has not looked at in the last week, oldest first.
+func (s *Store) PhotosToClassify(ctx context.Context, limit int) ([]PhotoRef, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		SELECT id, href FROM ingest_listing_photos
+		WHERE room IS NULL AND (room_at IS NULL OR room_at < now() - interval '7 days')
+		ORDER BY created_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (PhotoRef, error) {
+		var p PhotoRef
+		err := row.Scan(&p.ID, &p.Href)
+		return p, err
+	})
+}
+
+// SavePhotoRoom records the classifier's room type for a photo. An empty
+// room records that the classifier had no answer so the photo is not sent
+// again straight away.
+func (s *Store) SavePhotoRoom(ctx context.Context, photoID, room string) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `
+		UPDATE ingest_listing_photos
+		SET room=$2, room_source=CASE WHEN $2::text IS NOT NULL THEN 'model' END, room_at=now()
+		WHERE id=$1 AND room IS NULL
+	`, photoID, nullString(room))
+	return err
+}
+
+// ListingPhotoRooms maps each classified photo href of a provider listing to
+// its room type. Unclassified photos are absent.
+func (s *Store) ListingPhotoRooms(ctx context.Context, providerListingID string) (map[string]string, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT lp.href, lp.room
+		FROM ingest_listings l
+		JOIN ingest_listing_photos lp ON lp.listing_id = l.id
+		WHERE l.listing_id = $1 AND l.deleted_at IS NULL AND lp.room IS NOT NULL
+	`, providerListingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]string)
+	for rows.Next() {
+		var href, room string
+		if err := rows.Scan(&href, &room); err != nil {
+			return nil, err
+		}
+		out[href] = room
+	}
+	return out, rows.Err()
+}
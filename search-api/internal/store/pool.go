@@ -0,0 +1,114 @@
+package store
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourorg/search-api/internal/env"
+)
+
+// PoolConfig sizes the connection pools of a Store; replicas get the same
+// settings as the primary. Zero fields keep the defaults: 10 connections,
+// 1 kept open, 30m lifetime, 30m idle time, the DSN's statement_timeout or
+// 15s, and a 10s client-side bound per store call.
+type PoolConfig struct {
+	MaxConns         int32
+	MinConns         int32
+	MaxConnLifetime  time.Duration
+	MaxConnIdleTime  time.Duration
+	StatementTimeout time.Duration
+	QueryTimeout     time.Duration
+}
+
+// PoolConfigFromEnv reads the pool settings:
+//
+//	PG_MAX_CONNS              connections per pool
+//	PG_MIN_CONNS              connections kept open when idle
+//	PG_MAX_CONN_LIFETIME      recycle connections after this long, e.g. 30m
+//	PG_MAX_CONN_IDLE_TIME     close idle connections after this long
+//	PG_STATEMENT_TIMEOUT      server-side statement_timeout, e.g. 15s
+//	PG_QUERY_TIMEOUT          client-side bound on each store call
+//
+// Deployments sharing one database should size PG_MAX_CONNS so the API and
+// hydrator together stay under the server's max_connections.
+func PoolConfigFromEnv() PoolConfig {
+	return PoolConfig{
+		MaxConns:         int32(min(max(env.GetInt("PG_MAX_CONNS", 0), 0), math.MaxInt32)),
+		MinConns:         int32(min(max(env.GetInt("PG_MIN_CONNS", 0), 0), math.MaxInt32)),
+		MaxConnLifetime:  max(env.GetDuration("PG_MAX_CONN_LIFETIME", 0), 0),
+		MaxConnIdleTime:  max(env.GetDuration("PG_MAX_CONN_IDLE_TIME", 0), 0),
+		StatementTimeout: max(env.GetDuration("PG_STATEMENT_TIMEOUT", 0), 0),
+		QueryTimeout:     max(env.GetDuration("PG_QUERY_TIMEOUT", 0), 0),
+	}
+}
+
+func (c PoolConfig) apply(cfg *pgxpool.Config) {
+	cfg.MaxConns = 10
+	if c.MaxConns > 0 {
+		cfg.MaxConns = c.MaxConns
+	}
+	cfg.MinConns = 1
+	if c.MinConns > 0 {
+		cfg.MinConns = min(c.MinConns, cfg.MaxConns)
+	}
+	cfg.MaxConnLifetime = 30 * time.Minute
+	if c.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = c.MaxConnIdleTime
+	}
+	switch _, inDSN := cfg.ConnConfig.RuntimeParams["statement_timeout"]; {
+	case c.StatementTimeout > 0:
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10)
+	case !inDSN:
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(defaultStatementTimeout.Milliseconds(), 10)
+	}
+}
+
+// PoolStat is a snapshot of one connection pool.
+type PoolStat struct {
+	MaxConns      int32 `json:"max_conns"`
+	TotalConns    int32 `json:"total_conns"`
+	IdleConns     int32 `json:"idle_conns"`
+	AcquiredConns int32 `json:"acquired_conns"`
+	Acquires      int64 `json:"acquires"`
+	// WaitedAcquires had to wait for a connection; a share of Acquires
+	// that keeps growing means the pool is too small.
+	WaitedAcquires   int64   `json:"waited_acquires"`
+	CanceledAcquires int64   `json:"canceled_acquires"`
+	AcquireWaitMS    float64 `json:"acquire_wait_ms"`
+}
+
+func poolStat(p *pgxpool.Pool) PoolStat {
+	st := p.Stat()
+	return PoolStat{
+		MaxConns:         st.MaxConns(),
+		TotalConns:       st.TotalConns(),
+		IdleConns:        st.IdleConns(),
+		AcquiredConns:    st.AcquiredConns(),
+		Acquires:         st.AcquireCount(),
+		WaitedAcquires:   st.EmptyAcquireCount(),
+		CanceledAcquires: st.CanceledAcquireCount(),
+		AcquireWaitMS:    float64(st.AcquireDuration().Microseconds()) / 1000,
+	}
+}
+
+// PoolStats reports the primary pool as "primary" and replicas as
+// "replica0", "replica1", ... in the order their DSNs were given.
+func (s *Store) PoolStats() map[string]PoolStat {
+	out := map[string]PoolStat{}
+	if s == nil || s.Pool == nil {
+		return out
+	}
+	out["primary"] = poolStat(s.Pool)
+	if s.replicas != nil {
+		for i, r := range s.replicas.members {
+			out["replica"+strconv.Itoa(i)] = poolStat(r.db)
+		}
+	}
+	return out
+}
@@ -11,23 +11,177 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/county"
+	"github.com/yourorg/search-api/internal/proptype"
 )
 
-type Store struct{ DB *sql.DB }
+const (
+	// defaultStatementTimeout is applied server-side to every session.
+	defaultStatementTimeout = 15 * time.Second
+	// defaultQueryTimeout bounds each store call client-side.
+	defaultQueryTimeout = 10 * time.Second
 
-func Open(dsn string) (*Store, error) {
-	db, err := sql.Open("pgx", dsn)
+	stmtFetchListingsByPostal = "fetch_listings_by_postal"
+	stmtFetchListingsByCity   = "fetch_listings_by_city"
+	stmtFetchPhotosByListings = "fetch_photos_by_listings"
+)
+
+const sqlFetchListingsByPostal = `
+		SELECT l.property_key, l.address_line1, l.city, l.state, l.zip,
+		       l.lat, l.lon, l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type, l.list_date,
+		       ` + sqlListingFeatures + `, l.photos, l.last_fetch_at, l.stale_after
+		FROM listing_summaries l
+		WHERE l.zip = $1 AND ($4 = '' OR l.property_type = $4)
+		  AND ($5::int IS NULL OR l.list_date <= now() - make_interval(days => $5::int))
+		  AND ($6::int IS NULL OR l.list_date > now() - make_interval(days => $6::int + 1))
+		  AND ($7::int IS NULL OR COALESCE((l.extras->>'hoa_fee')::int, 0) <= $7::int)
+		  AND ($8::int IS NULL OR (l.extras->>'garage_spaces')::int >= $8::int)
+		  AND ($9::int IS NULL OR (l.extras->>'stories')::int >= $9::int)
+		  AND ($10::boolean IS NULL OR COALESCE((l.flags->>'pool')::boolean, false) = $10::boolean)
+		  AND ($11::boolean IS NULL OR COALESCE((l.flags->>'basement')::boolean, false) = $11::boolean)
+		  AND ($12::boolean IS NULL OR COALESCE((l.flags->>'new_construction')::boolean, false) = $12::boolean)
+		  AND ($13::boolean IS NULL OR COALESCE((l.flags->>'foreclosure')::boolean, false) = $13::boolean)
+		  AND ($14::boolean IS NULL OR COALESCE((l.flags->>'price_reduced')::boolean, false) = $14::boolean)
+		ORDER BY %s
+		LIMIT $2 OFFSET $3`
+
+const sqlFetchListingsByCity = `
+		SELECT l.property_key, l.address_line1, l.city, l.state, l.zip,
+		       l.lat, l.lon, l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type, l.list_date,
+		       ` + sqlListingFeatures + `, l.photos, l.last_fetch_at, l.stale_after
+		FROM listing_summaries l
+		WHERE l.state = $1 AND l.city_key = $2 AND ($5 = '' OR l.property_type = $5)
+		  AND ($6::int IS NULL OR l.list_date <= now() - make_interval(days => $6::int))
+		  AND ($7::int IS NULL OR l.list_date > now() - make_interval(days => $7::int + 1))
+		  AND ($8::int IS NULL OR COALESCE((l.extras->>'hoa_fee')::int, 0) <= $8::int)
+		  AND ($9::int IS NULL OR (l.extras->>'garage_spaces')::int >= $9::int)
+		  AND ($10::int IS NULL OR (l.extras->>'stories')::int >= $10::int)
+		  AND ($11::boolean IS NULL OR COALESCE((l.flags->>'pool')::boolean, false) = $11::boolean)
+		  AND ($12::boolean IS NULL OR COALESCE((l.flags->>'basement')::boolean, false) = $12::boolean)
+		  AND ($13::boolean IS NULL OR COALESCE((l.flags->>'new_construction')::boolean, false) = $13::boolean)
+		  AND ($14::boolean IS NULL OR COALESCE((l.flags->>'foreclosure')::boolean, false) = $14::boolean)
+		  AND ($15::boolean IS NULL OR COALESCE((l.flags->>'price_reduced')::boolean, false) = $15::boolean)
+		ORDER BY %s
+		LIMIT $3 OFFSET $4`
+
+// ListingSort orders a database-served listing page.
+type ListingSort string
+
+const (
+	SortUpdated   ListingSort = ""           // most recently refreshed first
+	SortPriceAsc  ListingSort = "price_asc"  // cheapest first
+	SortPriceDesc ListingSort = "price_desc" // most expensive first
+	SortNewest    ListingSort = "newest"     // most recently listed first
+	SortSqft      ListingSort = "sqft"       // largest first
+	SortBeds      ListingSort = "beds"       // most bedrooms first
+)
+
+// listingOrders is the ORDER BY for each sort. Every order ends on l.id so
+// pages never overlap when the sort column ties.
+var listingOrders = map[ListingSort]string{
+	SortUpdated:   "l.updated_at DESC, l.id",
+	SortPriceAsc:  "l.list_price ASC NULLS LAST, l.id",
+	SortPriceDesc: "l.list_price DESC NULLS LAST, l.id",
+	SortNewest:    "COALESCE(l.list_date, l.created_at) DESC, l.id",
+	SortSqft:      "l.sqft DESC NULLS LAST, l.id",
+	SortBeds:      "l.beds DESC NULLS LAST, l.list_price ASC NULLS LAST, l.id",
+}
+
+// listingPageQuery returns the statement name and SQL of a listing page
+// query in the given order. Unknown sorts fall back to SortUpdated.
+func listingPageQuery(stmt, query string, sort ListingSort) (string, string) {
+	order, ok := listingOrders[sort]
+	if !ok {
+		sort, order = SortUpdated, listingOrders[SortUpdated]
+	}
+	if sort != SortUpdated {
+		stmt += "_" + string(sort)
+	}
+	return stmt, fmt.Sprintf(query, order)
+}
+
+// rankedListingPhotos ranks ingest_listing_photos for dropping same-image
+// duplicates: within a listing, only the first photo by position of each
+// perceptual hash has phash_rank 1. Unhashed photos always do. Callers add
+// a WHERE on lp.listing_id so only the listings asked for are ranked, not
+// the whole table.
+const rankedListingPhotos = `
+		SELECT lp.*, row_number() OVER (
+			PARTITION BY lp.listing_id, COALESCE(lp.phash::text, lp.href) ORDER BY lp.position, lp.created_at
+		) AS phash_rank
+		FROM ingest_listing_photos lp`
+
+// distinctListingPhotos is rankedListingPhotos for the listing l of a
+// LATERAL join.
+const distinctListingPhotos = `(` + rankedListingPhotos + ` WHERE lp.listing_id = l.id)`
+
+const sqlFetchPhotosByListings = `
+		SELECT listing_id, href FROM (` + rankedListingPhotos + ` WHERE lp.listing_id = ANY($1::uuid[])) lp
+		WHERE phash_rank = 1
+		ORDER BY listing_id, position`
+
+type Store struct {
+	Pool     *pgxpool.Pool
+	replicas *replicaSet
+	// QueryTimeout bounds each store call; zero uses defaultQueryTimeout.
+	QueryTimeout time.Duration
+}
+
+func Open(dsn string, pool PoolConfig) (*Store, error) {
+	p, err := openPool(dsn, pool)
 	if err != nil {
 		return nil, err
 	}
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(30 * time.Minute)
-	return &Store{DB: db}, nil
+	return &Store{Pool: p, QueryTimeout: pool.QueryTimeout}, nil
 }
 
-func (s *Store) Ping(ctx context.Context) error { return s.DB.PingContext(ctx) }
+func openPool(dsn string, pool PoolConfig) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	pool.apply(cfg)
+	cfg.AfterConnect = prepareHotStatements
+	cfg.ConnConfig.Tracer = queryTracer{}
+	return pgxpool.NewWithConfig(context.Background(), cfg)
+}
+
+// prepareHotStatements prepares the listing page queries on every new
+// connection. Preparation is best-effort because the tables may not exist
+// until Migrate has run; queryPrepared falls back to the raw SQL text.
+func prepareHotStatements(ctx context.Context, conn *pgx.Conn) error {
+	for sort := range listingOrders {
+		for _, q := range [][2]string{
+			{stmtFetchListingsByPostal, sqlFetchListingsByPostal},
+			{stmtFetchListingsByCity, sqlFetchListingsByCity},
+		} {
+			name, query := listingPageQuery(q[0], q[1], sort)
+			_, _ = conn.Prepare(ctx, name, query)
+		}
+	}
+	_, _ = conn.Prepare(ctx, stmtFetchPhotosByListings, sqlFetchPhotosByListings)
+	return nil
+}
+
+func (s *Store) Ping(ctx context.Context) error { return s.Pool.Ping(ctx) }
+
+// Close releases the primary and any replica pools.
+func (s *Store) Close() {
+	s.replicas.close()
+	s.Pool.Close()
+}
+
+// queryCtx derives the per-call deadline for a store operation.
+func (s *Store) queryCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := s.QueryTimeout
+	if timeout <= 0 {
+		timeout = defaultQueryTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
 
 func (s *Store) Migrate(ctx context.Context) error {
 	stmts := []string{
@@ -49,6 +203,12 @@ func (s *Store) Migrate(ctx context.Context) error {
             stale_after     TIMESTAMPTZ
         );`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS ux_ingest_properties_property_key ON ingest_properties(property_key);`,
+		`ALTER TABLE ingest_properties ADD COLUMN IF NOT EXISTS city_key TEXT;`,
+		// Mirrors canon.CityKey for rows written before city_key existed.
+		`UPDATE ingest_properties SET city_key = regexp_replace(regexp_replace(regexp_replace(regexp_replace(city,
+            '\mSAINTE\M', 'STE', 'g'), '\mSAINT\M', 'ST', 'g'), '\mFORT\M', 'FT', 'g'), '\mMOUNT\M', 'MT', 'g')
+         WHERE city_key IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_properties_state_city_key ON ingest_properties(state, city_key);`,
 		`CREATE INDEX IF NOT EXISTS idx_ingest_properties_geo ON ingest_properties USING GIST (ll_to_earth(lat, lon));`,
 		`CREATE TABLE IF NOT EXISTS ingest_listings (
             id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -107,15 +267,39 @@ func (s *Store) Migrate(ctx context.Context) error {
 		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS kind TEXT;`,
 		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS title TEXT;`,
 		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS position INTEGER;`,
+		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS phash BIGINT;`,
+		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS phash_at TIMESTAMPTZ;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listphotos_phash ON ingest_listing_photos(phash) WHERE phash IS NOT NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listphotos_unhashed ON ingest_listing_photos(created_at) WHERE phash IS NULL;`,
+		`ALTER TABLE ingest_listings ADD COLUMN IF NOT EXISTS description TEXT;`,
+		`ALTER TABLE ingest_listings ADD COLUMN IF NOT EXISTS description_tsv tsvector
+            GENERATED ALWAYS AS (to_tsvector('english', COALESCE(description, ''))) STORED;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_description_tsv ON ingest_listings USING GIN (description_tsv);`,
+		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS room TEXT;`,
+		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS room_source TEXT;`,
+		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS room_at TIMESTAMPTZ;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listphotos_unclassified ON ingest_listing_photos(created_at) WHERE room IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_property_type ON ingest_listings(property_type) WHERE property_type IS NOT NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_sort_price ON ingest_listings(list_price, id) WHERE deleted_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_sort_sqft ON ingest_listings(sqft DESC NULLS LAST, id) WHERE deleted_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_sort_beds ON ingest_listings(beds DESC NULLS LAST, list_price, id) WHERE deleted_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_sort_newest ON ingest_listings((COALESCE(list_date, created_at)) DESC, id) WHERE deleted_at IS NULL;`,
+		// Listing flag filters; the expressions match the page queries'.
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_flag_new_construction ON ingest_listings((COALESCE((flags->>'new_construction')::boolean, false))) WHERE deleted_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_flag_foreclosure ON ingest_listings((COALESCE((flags->>'foreclosure')::boolean, false))) WHERE deleted_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_flag_price_reduced ON ingest_listings((COALESCE((flags->>'price_reduced')::boolean, false))) WHERE deleted_at IS NULL;`,
 		`CREATE TABLE IF NOT EXISTS ingest_provider_raw_snapshots (
-            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            id UUID NOT NULL DEFAULT gen_random_uuid(),
             provider       TEXT NOT NULL,
             endpoint       TEXT NOT NULL,
             external_id    TEXT,
             payload        JSONB NOT NULL,
             fetched_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
-            payload_sha256 TEXT NOT NULL
-        );`,
+            payload_sha256 TEXT NOT NULL,
+            PRIMARY KEY (id, fetched_at)
+        ) PARTITION BY RANGE (fetched_at);`,
+		sqlPartitionRawSnapshots,
+		`CREATE TABLE IF NOT EXISTS ingest_provider_raw_snapshots_default PARTITION OF ingest_provider_raw_snapshots DEFAULT;`,
 		`CREATE INDEX IF NOT EXISTS idx_ingest_snapshots_provider ON ingest_provider_raw_snapshots(provider, endpoint, fetched_at DESC);`,
 		`CREATE INDEX IF NOT EXISTS idx_ingest_snapshots_external ON ingest_provider_raw_snapshots(provider, external_id);`,
 		`CREATE TABLE IF NOT EXISTS ingest_hydrate_jobs (
@@ -133,13 +317,416 @@ func (s *Store) Migrate(ctx context.Context) error {
             updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
         );`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS ux_ingest_jobs_idem ON ingest_hydrate_jobs(idempotency_key);`,
+		`CREATE TABLE IF NOT EXISTS ingest_agents (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            provider        TEXT NOT NULL,
+            agent_key       TEXT NOT NULL,
+            source_agent_id TEXT,
+            name            TEXT NOT NULL,
+            email           TEXT,
+            phone           TEXT,
+            office_id       TEXT,
+            office_name     TEXT,
+            broker_name     TEXT,
+            created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+            updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS ux_ingest_agents_provider_key ON ingest_agents(provider, agent_key);`,
+		`CREATE TABLE IF NOT EXISTS ingest_listing_agents (
+            listing_id UUID NOT NULL REFERENCES ingest_listings(id) ON DELETE CASCADE,
+            agent_id   UUID NOT NULL REFERENCES ingest_agents(id) ON DELETE CASCADE,
+            role       TEXT NOT NULL DEFAULT '',
+            position   INTEGER NOT NULL DEFAULT 0,
+            PRIMARY KEY (listing_id, agent_id, role)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listing_agents_agent ON ingest_listing_agents(agent_id);`,
+		`CREATE TABLE IF NOT EXISTS ingest_open_houses (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            listing_id  UUID NOT NULL REFERENCES ingest_listings(id) ON DELETE CASCADE,
+            start_at    TIMESTAMPTZ NOT NULL,
+            end_at      TIMESTAMPTZ NOT NULL,
+            description TEXT,
+            created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+            updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS ux_ingest_open_houses_listing_start ON ingest_open_houses(listing_id, start_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_open_houses_start ON ingest_open_houses(start_at);`,
+		`CREATE TABLE IF NOT EXISTS market_daily_stats (
+            zip           TEXT NOT NULL,
+            day           DATE NOT NULL,
+            median_price  NUMERIC,
+            active_count  INTEGER NOT NULL DEFAULT 0,
+            new_count     INTEGER NOT NULL DEFAULT 0,
+            sold_count    INTEGER NOT NULL DEFAULT 0,
+            computed_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (zip, day)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_market_daily_stats_day ON market_daily_stats(day);`,
+		`CREATE TABLE IF NOT EXISTS property_valuations (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            property_id  UUID NOT NULL REFERENCES ingest_properties(id) ON DELETE CASCADE,
+            provider     TEXT NOT NULL,
+            value        NUMERIC NOT NULL,
+            value_low    NUMERIC,
+            value_high   NUMERIC,
+            confidence   NUMERIC,
+            comps_count  INTEGER,
+            valued_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_property_valuations_property ON property_valuations(property_id, valued_at DESC);`,
+		`CREATE TABLE IF NOT EXISTS provider_drift_reports (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            provider        TEXT NOT NULL,
+            endpoint        TEXT NOT NULL,
+            signature       TEXT NOT NULL,
+            unknown_fields  TEXT[] NOT NULL DEFAULT '{}',
+            missing_fields  TEXT[] NOT NULL DEFAULT '{}',
+            first_seen_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+            last_seen_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+            seen_count      INTEGER NOT NULL DEFAULT 1
+        );`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS ux_provider_drift_signature ON provider_drift_reports(provider, endpoint, signature);`,
+		`CREATE TABLE IF NOT EXISTS hydrator_runs (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            job                 TEXT NOT NULL,
+            status              TEXT NOT NULL,
+            started_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+            updated_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+            finished_at         TIMESTAMPTZ,
+            zips_total          INTEGER NOT NULL DEFAULT 0,
+            zips_done           INTEGER NOT NULL DEFAULT 0,
+            listings_persisted  INTEGER NOT NULL DEFAULT 0,
+            requests            INTEGER NOT NULL DEFAULT 0,
+            last_error          TEXT
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_hydrator_runs_job ON hydrator_runs(job, started_at DESC);`,
+		`CREATE TABLE IF NOT EXISTS hydrator_checkpoints (
+            run_id        UUID NOT NULL REFERENCES hydrator_runs(id) ON DELETE CASCADE,
+            zip           TEXT NOT NULL,
+            property_type TEXT NOT NULL DEFAULT '',
+            last_page     INTEGER NOT NULL DEFAULT 0,
+            listings      INTEGER NOT NULL DEFAULT 0,
+            done          BOOLEAN NOT NULL DEFAULT false,
+            updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (run_id, zip, property_type)
+        );`,
+		`CREATE TABLE IF NOT EXISTS hydrator_targets (
+            zip          TEXT PRIMARY KEY,
+            priority     INTEGER NOT NULL DEFAULT 0,
+            enabled      BOOLEAN NOT NULL DEFAULT true,
+            last_run_at  TIMESTAMPTZ,
+            notes        TEXT,
+            created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_hydrator_targets_order ON hydrator_targets(priority DESC, last_run_at ASC NULLS FIRST) WHERE enabled;`,
+		`CREATE TABLE IF NOT EXISTS zip_demand (
+            zip       TEXT NOT NULL,
+            hour      TIMESTAMPTZ NOT NULL,
+            requests  INTEGER NOT NULL DEFAULT 0,
+            PRIMARY KEY (zip, hour)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_properties_zip ON ingest_properties(zip);`,
+		// Consecutive complete ZIP crawls a listing was absent from; see MarkUnseenListings.
+		`ALTER TABLE ingest_listings ADD COLUMN IF NOT EXISTS missed_cycles INTEGER NOT NULL DEFAULT 0;`,
+		// Set on cross-provider duplicates; NULL on canonical listings. See ReconcileListings.
+		`ALTER TABLE ingest_listings ADD COLUMN IF NOT EXISTS canonical_listing_id UUID REFERENCES ingest_listings(id) ON DELETE SET NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_canonical ON ingest_listings(canonical_listing_id) WHERE canonical_listing_id IS NOT NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_updated ON ingest_listings(updated_at);`,
+		`CREATE TABLE IF NOT EXISTS property_merges (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            op              TEXT NOT NULL,
+            target_key      TEXT NOT NULL,
+            source_key      TEXT NOT NULL,
+            listing_ids     UUID[] NOT NULL DEFAULT '{}',
+            source_snapshot JSONB,
+            actor           TEXT NOT NULL DEFAULT '',
+            reason          TEXT NOT NULL DEFAULT '',
+            created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_property_merges_target ON property_merges(target_key, created_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_property_merges_source ON property_merges(source_key, created_at DESC);`,
+		// Keys of merged-away properties; ingests and lookups by an alias land on property_id.
+		`CREATE TABLE IF NOT EXISTS property_aliases (
+            alias_key    TEXT PRIMARY KEY,
+            property_id  UUID NOT NULL REFERENCES ingest_properties(id) ON DELETE CASCADE,
+            merge_id     UUID REFERENCES property_merges(id) ON DELETE SET NULL,
+            created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_property_aliases_property ON property_aliases(property_id);`,
+		// Set on listings moved by SplitProperty so upserts keep them where they were put.
+		`ALTER TABLE ingest_listings ADD COLUMN IF NOT EXISTS property_pinned BOOLEAN NOT NULL DEFAULT false;`,
+		// Soft deletes: read paths skip rows with deleted_at set.
+		`ALTER TABLE ingest_properties ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+		`ALTER TABLE ingest_listings ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+		`CREATE TABLE IF NOT EXISTS ingest_audit_log (
+            id          BIGSERIAL PRIMARY KEY,
+            table_name  TEXT NOT NULL,
+            row_id      UUID NOT NULL,
+            action      TEXT NOT NULL,
+            actor       TEXT,
+            run_id      TEXT,
+            request_id  TEXT,
+            changes     JSONB NOT NULL DEFAULT '{}',
+            created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_audit_log_row ON ingest_audit_log(table_name, row_id, created_at DESC);`,
+		// Change feed order; see ChangeCursor. Entries from before the feed
+		// existed get txid 0 and sort first.
+		`ALTER TABLE ingest_audit_log ADD COLUMN IF NOT EXISTS txid BIGINT NOT NULL DEFAULT 0;`,
+		`ALTER TABLE ingest_audit_log ALTER COLUMN txid SET DEFAULT txid_current();`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_audit_log_feed ON ingest_audit_log(txid, id);`,
+		auditTriggerFunc,
+		`DROP TRIGGER IF EXISTS trg_ingest_properties_audit ON ingest_properties;`,
+		`CREATE TRIGGER trg_ingest_properties_audit AFTER INSERT OR UPDATE OR DELETE ON ingest_properties
+            FOR EACH ROW EXECUTE FUNCTION ingest_audit();`,
+		`DROP TRIGGER IF EXISTS trg_ingest_listings_audit ON ingest_listings;`,
+		`CREATE TRIGGER trg_ingest_listings_audit AFTER INSERT OR UPDATE OR DELETE ON ingest_listings
+            FOR EACH ROW EXECUTE FUNCTION ingest_audit();`,
+		`CREATE TABLE IF NOT EXISTS property_enrichments (
+            property_id UUID NOT NULL REFERENCES ingest_properties(id) ON DELETE CASCADE,
+            enricher    TEXT NOT NULL,
+            data        JSONB NOT NULL,
+            fetched_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (property_id, enricher)
+        );`,
+		`CREATE TABLE IF NOT EXISTS property_tax_assessments (
+            property_id       UUID NOT NULL REFERENCES ingest_properties(id) ON DELETE CASCADE,
+            tax_year          INTEGER NOT NULL,
+            tax_amount        NUMERIC,
+            assessed_land     NUMERIC,
+            assessed_building NUMERIC,
+            assessed_total    NUMERIC,
+            market_total      NUMERIC,
+            provider          TEXT NOT NULL,
+            fetched_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (property_id, tax_year)
+        );`,
+		`CREATE TABLE IF NOT EXISTS property_transfers (
+            id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            property_id   UUID NOT NULL REFERENCES ingest_properties(id) ON DELETE CASCADE,
+            transfer_date DATE NOT NULL,
+            price         NUMERIC,
+            event         TEXT NOT NULL,
+            source        TEXT,
+            provider      TEXT NOT NULL,
+            fetched_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+            UNIQUE (property_id, transfer_date, event)
+        );`,
+		`ALTER TABLE ingest_properties ADD COLUMN IF NOT EXISTS history_fetched_at TIMESTAMPTZ;`,
+		`ALTER TABLE ingest_properties ADD COLUMN IF NOT EXISTS apn TEXT;`,
+		`ALTER TABLE ingest_properties ADD COLUMN IF NOT EXISTS fips TEXT;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_properties_apn ON ingest_properties(apn, fips) WHERE apn IS NOT NULL;`,
+		`CREATE TABLE IF NOT EXISTS listing_summaries (
+            id            UUID PRIMARY KEY REFERENCES ingest_listings(id) ON DELETE CASCADE,
+            property_id   UUID NOT NULL,
+            property_key  TEXT NOT NULL,
+            address_line1 TEXT NOT NULL,
+            city          TEXT NOT NULL,
+            state         TEXT NOT NULL,
+            zip           TEXT NOT NULL,
+            city_key      TEXT,
+            lat           DOUBLE PRECISION,
+            lon           DOUBLE PRECISION,
+            listing_id    TEXT,
+            list_price    NUMERIC,
+            beds          SMALLINT,
+            baths         NUMERIC,
+            sqft          INTEGER,
+            property_type TEXT,
+            list_date     TIMESTAMPTZ,
+            flags         JSONB,
+            extras        JSONB,
+            primary_photo TEXT,
+            photos        TEXT[],
+            created_at    TIMESTAMPTZ NOT NULL,
+            updated_at    TIMESTAMPTZ NOT NULL,
+            last_fetch_at TIMESTAMPTZ,
+            stale_after   TIMESTAMPTZ,
+            refreshed_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_listing_summaries_property ON listing_summaries(property_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_listing_summaries_zip ON listing_summaries(zip, updated_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_listing_summaries_city ON listing_summaries(state, city_key, updated_at DESC);`,
+		sqlBackfillListingSummaries,
+		// coords is the listing's own [lon, lat]; rows written before it was
+		// populated take their property's.
+		`UPDATE ingest_listings l SET coords = point(p.lon, p.lat)
+         FROM ingest_properties p
+         WHERE p.id = l.property_id AND l.coords IS NULL AND p.lat IS NOT NULL AND p.lon IS NOT NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_geo ON ingest_listings USING GIST (ll_to_earth(coords[1], coords[0])) WHERE coords IS NOT NULL;`,
+		// Per-consumer API usage, rolled up hourly; see AddUsage.
+		`CREATE TABLE IF NOT EXISTS api_usage (
+            consumer       TEXT NOT NULL,
+            hour           TIMESTAMPTZ NOT NULL,
+            requests       BIGINT NOT NULL DEFAULT 0,
+            provider_calls BIGINT NOT NULL DEFAULT 0,
+            cache_hits     BIGINT NOT NULL DEFAULT 0,
+            cache_misses   BIGINT NOT NULL DEFAULT 0,
+            provider_reads BIGINT NOT NULL DEFAULT 0,
+            errors         BIGINT NOT NULL DEFAULT 0,
+            PRIMARY KEY (consumer, hour)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_api_usage_hour ON api_usage(hour);`,
+		// Curated photo order: curated_at pins position against provider
+		// resyncs, and a listing has at most one primary photo.
+		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS is_primary BOOLEAN NOT NULL DEFAULT false;`,
+		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS curated_at TIMESTAMPTZ;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS ux_ingest_listphotos_primary ON ingest_listing_photos(listing_id) WHERE is_primary;`,
+		// Internal notes on properties and listings, per owning key or
+		// token subject; see AddNote.
+		`CREATE TABLE IF NOT EXISTS notes (
+            id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            owner        TEXT NOT NULL,
+            subject_kind TEXT NOT NULL,
+            subject_id   TEXT NOT NULL,
+            body         TEXT NOT NULL DEFAULT '',
+            labels       TEXT[] NOT NULL DEFAULT '{}',
+            author       TEXT NOT NULL,
+            created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+            updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_notes_subject ON notes(owner, subject_kind, subject_id, created_at);`,
+		// Saved searches and their email digests; see DigestListings and
+		// ClaimDigest. The digests table is the send log that keeps a
+		// window from being mailed twice.
+		`CREATE TABLE IF NOT EXISTS saved_searches (
+            id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            owner          TEXT NOT NULL,
+            name           TEXT NOT NULL DEFAULT '',
+            email          TEXT NOT NULL,
+            criteria       JSONB NOT NULL,
+            frequency      TEXT NOT NULL,
+            digested_until TIMESTAMPTZ NOT NULL DEFAULT now(),
+            next_digest_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+            created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+            updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_saved_searches_owner ON saved_searches(owner, created_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_saved_searches_due ON saved_searches(next_digest_at) WHERE frequency <> 'off';`,
+		`CREATE TABLE IF NOT EXISTS saved_search_digests (
+            saved_search_id UUID NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+            window_start    TIMESTAMPTZ NOT NULL,
+            window_end      TIMESTAMPTZ NOT NULL,
+            status          TEXT NOT NULL,
+            listings        INTEGER NOT NULL DEFAULT 0,
+            attempts        INTEGER NOT NULL DEFAULT 0,
+            error           TEXT,
+            sent_at         TIMESTAMPTZ,
+            created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+            updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (saved_search_id, window_start)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_audit_log_table_time ON ingest_audit_log(table_name, created_at);`,
+		`CREATE TABLE IF NOT EXISTS market_weekly_reports (
+            zip               TEXT NOT NULL,
+            week_start        DATE NOT NULL,
+            new_listings      INTEGER NOT NULL DEFAULT 0,
+            sold_count        INTEGER NOT NULL DEFAULT 0,
+            active_count      INTEGER NOT NULL DEFAULT 0,
+            median_price      NUMERIC,
+            prev_median_price NUMERIC,
+            segments          JSONB NOT NULL DEFAULT '[]',
+            generated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (week_start, zip)
+        );`,
+		// Hourly search rollup for analytics; see AddSearchStats.
+		`CREATE TABLE IF NOT EXISTS search_analytics (
+            hour          TIMESTAMPTZ NOT NULL,
+            route         TEXT NOT NULL,
+            zip           TEXT NOT NULL DEFAULT '',
+            city          TEXT NOT NULL DEFAULT '',
+            property_type TEXT NOT NULL DEFAULT '',
+            order_by      TEXT NOT NULL DEFAULT '',
+            filters       TEXT NOT NULL DEFAULT '',
+            cache         TEXT NOT NULL DEFAULT '',
+            searches      BIGINT NOT NULL DEFAULT 0,
+            results       BIGINT NOT NULL DEFAULT 0,
+            zero_results  BIGINT NOT NULL DEFAULT 0,
+            PRIMARY KEY (hour, route, zip, city, property_type, order_by, filters, cache)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_search_analytics_zip ON search_analytics(zip, hour);`,
+		// Feature flag rollouts and per-consumer overrides; see package flags.
+		`CREATE TABLE IF NOT EXISTS feature_flags (
+            name        TEXT PRIMARY KEY,
+            percent     INTEGER NOT NULL CHECK (percent BETWEEN 0 AND 100),
+            description TEXT NOT NULL DEFAULT '',
+            updated_by  TEXT NOT NULL DEFAULT '',
+            updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE TABLE IF NOT EXISTS feature_flag_overrides (
+            name       TEXT NOT NULL,
+            consumer   TEXT NOT NULL,
+            enabled    BOOLEAN NOT NULL,
+            updated_by TEXT NOT NULL DEFAULT '',
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (name, consumer)
+        );`,
+		// Searches answered by both the primary provider and a shadow
+		// candidate; see package shadow.
+		`CREATE TABLE IF NOT EXISTS provider_shadow_results (
+            id              BIGSERIAL PRIMARY KEY,
+            candidate       TEXT NOT NULL,
+            route           TEXT NOT NULL,
+            location        TEXT NOT NULL,
+            params          JSONB NOT NULL DEFAULT '{}',
+            primary_count   INTEGER NOT NULL DEFAULT 0,
+            candidate_count INTEGER NOT NULL DEFAULT 0,
+            matched         INTEGER NOT NULL DEFAULT 0,
+            only_primary    TEXT[] NOT NULL DEFAULT '{}',
+            only_candidate  TEXT[] NOT NULL DEFAULT '{}',
+            mismatches      JSONB NOT NULL DEFAULT '[]',
+            agrees          BOOLEAN NOT NULL,
+            primary_error   TEXT NOT NULL DEFAULT '',
+            candidate_error TEXT NOT NULL DEFAULT '',
+            candidate_ms    INTEGER NOT NULL DEFAULT 0,
+            primary_cards   JSONB,
+            candidate_cards JSONB,
+            created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_provider_shadow_results_time ON provider_shadow_results(created_at DESC);`,
+		// The canon rules version each property key was computed with.
+		`ALTER TABLE ingest_properties ADD COLUMN IF NOT EXISTS key_version SMALLINT NOT NULL DEFAULT 1;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_properties_key_version ON ingest_properties(key_version);`,
+		// Keys from earlier canon rules and addresses as given, mapped to the
+		// property they now belong to. key_version is the version an old
+		// key was computed under; null for addresses.
+		`CREATE TABLE IF NOT EXISTS address_aliases (
+            kind         TEXT NOT NULL,
+            alias_key    TEXT NOT NULL,
+            property_id  UUID NOT NULL REFERENCES ingest_properties(id) ON DELETE CASCADE,
+            key_version  SMALLINT,
+            created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (kind, alias_key)
+        );`,
+		`CREATE INDEX IF NOT EXISTS idx_address_aliases_property ON address_aliases(property_id);`,
+		// The county a property is grouped under. Unlike the parcel's fips
+		// it is derived from the ZIP when the provider sends no county.
+		`ALTER TABLE ingest_properties ADD COLUMN IF NOT EXISTS county_fips TEXT;`,
+		`ALTER TABLE ingest_properties ADD COLUMN IF NOT EXISTS county_name TEXT;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_properties_county ON ingest_properties(county_fips) WHERE county_fips IS NOT NULL;`,
+		// Listing freshness, served on database pages. Rows summarized before
+		// the columns existed are filled from their listings.
+		`ALTER TABLE listing_summaries ADD COLUMN IF NOT EXISTS last_fetch_at TIMESTAMPTZ;`,
+		`ALTER TABLE listing_summaries ADD COLUMN IF NOT EXISTS stale_after TIMESTAMPTZ;`,
+		`UPDATE listing_summaries s SET last_fetch_at = l.last_fetch_at, stale_after = l.stale_after
+		 FROM ingest_listings l
+		 WHERE l.id = s.id AND s.last_fetch_at IS NULL AND l.last_fetch_at IS NOT NULL;`,
+		// Photos stored from a search payload, before the listing's full
+		// set was fetched; see UpsertInput.InlinePhotos.
+		`ALTER TABLE ingest_listing_photos ADD COLUMN IF NOT EXISTS inline BOOLEAN NOT NULL DEFAULT false;`,
+		// When a listing last changed status, so a sale is dated once rather
+		// than by every re-upsert. Rows from before the column take their
+		// last update.
+		`ALTER TABLE ingest_listings ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ;`,
+		`UPDATE ingest_listings SET status_changed_at = updated_at WHERE status_changed_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_ingest_listings_sold ON ingest_listings(status_changed_at) WHERE status = 'sold';`,
 	}
 	for _, q := range stmts {
-		if _, err := s.DB.ExecContext(ctx, q); err != nil {
+		if _, err := s.Pool.Exec(ctx, q); err != nil {
 			return err
 		}
 	}
-	return nil
+	return s.EnsureSnapshotPartitions(ctx, time.Now(), snapshotMonthsAhead)
 }
 
 type ListingPhotoInput struct {
@@ -150,6 +737,12 @@ type ListingPhotoInput struct {
 	MediaType   string
 	Tags        []string
 	Position    int
+	// Room is the normalized room type from the provider tags, if any. An
+	// empty Room keeps a room already assigned by the classifier.
+	Room string
+	// Primary marks the provider's cover photo. It only applies to a newly
+	// stored photo; a stored photo keeps its primary flag.
+	Primary bool
 }
 type UpsertInput struct {
 	PropertyKey string
@@ -159,6 +752,14 @@ type UpsertInput struct {
 	Zip         string
 	Lat         sql.NullFloat64
 	Lon         sql.NullFloat64
+	// AddressKey is the address as received, folded by canon.AddressKey;
+	// it is kept as an address alias of the property.
+	AddressKey string
+	// Parcel is merged into the property; empty fields keep what is stored.
+	Parcel Parcel
+	// County is the county the property is grouped under; an empty FIPS
+	// keeps the stored county.
+	County county.County
 	// Listing bits
 	Provider  string
 	SourceID  string
@@ -168,18 +769,57 @@ type UpsertInput struct {
 	Beds      sql.NullInt64
 	Baths     sql.NullFloat64
 	Sqft      sql.NullInt64
-	Photos    []ListingPhotoInput
+	// ListDate is when the listing went on the market; null keeps the
+	// stored date.
+	ListDate sql.NullTime
+	// PropertyType is a proptype value; empty keeps the stored type.
+	PropertyType string
+	// Description is the listing remarks; empty keeps the stored text.
+	Description string
+	// Features are merged into the stored flags and extras; nil fields
+	// keep what is stored.
+	Features ListingFeatures
+	Photos   []ListingPhotoInput
+	// InlinePhotos marks Photos as the few a search payload carries rather
+	// than the listing's full set. They are stored only when the listing
+	// has no photos, so they never trim a full set, and are marked
+	// ListingPhoto.Inline until the full set replaces them.
+	InlinePhotos bool
+	Agents       []ListingAgent
+	// OpenHouses replaces the listing's schedule when non-nil.
+	OpenHouses []OpenHouse
 	// Raw snapshot
 	Endpoint    string
 	ExternalID  string
 	PayloadJSON []byte
+	// SkipSnapshot is set when replaying a stored snapshot, which must not
+	// be recorded a second time.
+	SkipSnapshot bool
 }
 
 type UpsertResult struct {
 	PropertyID string
 	ListingID  string
+	// Inserted is set when the listing is new; otherwise PrevStatus and
+	// PrevPrice hold its values before the write.
+	Inserted   bool
+	PrevStatus string
+	PrevPrice  sql.NullFloat64
+	Photos     PhotoDiff
+}
+
+// PhotoDiff counts how a photo sync changed a listing's stored photo URLs.
+type PhotoDiff struct {
+	ListingID   string
+	PropertyID  string
+	PropertyKey string
+	Added       int
+	Removed     int
+	Count       int
 }
 
+func (d PhotoDiff) Changed() bool { return d.Added > 0 || d.Removed > 0 }
+
 type ListingRecord struct {
 	PropertyKey       string
 	AddressLine1      string
@@ -195,221 +835,356 @@ type ListingRecord struct {
 	Baths             sql.NullFloat64
 	Sqft              sql.NullInt64
 	PropertyType      sql.NullString
-	Photos            []string
+	// Status is only loaded by FetchListingsByProperty and ExportListings.
+	Status string
+	// Parcel and County are only loaded by FetchListingsByProperty.
+	Parcel   Parcel
+	County   county.County
+	ListDate sql.NullTime
+	// LastFetchAt and StaleAfter are the listing's freshness; only loaded
+	// by the ZIP and city pages, FetchListingDetail, FetchListingsByProperty
+	// and SearchListingDescriptions.
+	LastFetchAt sql.NullTime
+	StaleAfter  sql.NullTime
+	Features    ListingFeatures
+	Photos      []string
+	// PhotoAssets are Photos with their metadata; only loaded by
+	// FetchListingDetail.
+	PhotoAssets []ListingPhoto
+	Agents      []ListingAgent
+	// Highlight is only set by SearchListingDescriptions.
+	Highlight string
 }
 
 func (s *Store) WriteSnapshotAndUpsert(ctx context.Context, in UpsertInput) (UpsertResult, error) {
 	var res UpsertResult
-	if s.DB == nil {
+	if s.Pool == nil {
 		return res, errors.New("nil db")
 	}
-	tx, err := s.DB.BeginTx(ctx, nil)
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tx, err := s.Pool.Begin(ctx)
 	if err != nil {
 		return res, err
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err = setAuditActorTx(ctx, tx); err != nil {
+		return res, err
+	}
+
+	// A key merged into another property ingests into that property and
+	// leaves its address alone.
+	err = tx.QueryRow(ctx, `
+        UPDATE ingest_properties p SET apn=COALESCE($2, p.apn), fips=COALESCE($3, p.fips), county_fips=COALESCE($4, p.county_fips), county_name=COALESCE($5, p.county_name), updated_at=now(), last_fetch_at=now(), stale_after=now() + interval '5 minutes'
+        WHERE p.id = `+aliasedPropertyID+`
+        RETURNING p.id`, in.PropertyKey, in.Parcel.apnArg(), in.Parcel.fipsArg(), countyFIPSArg(in.County), countyNameArg(in.County)).Scan(&res.PropertyID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return res, err
+	}
 
 	// ingest_properties upsert
-	err = tx.QueryRowContext(ctx, `
-        INSERT INTO ingest_properties (property_key, address_line1, city, state, zip, lat, lon, last_fetch_at, stale_after)
-        VALUES ($1,$2,$3,$4,$5,$6,$7, now(), now() + interval '5 minutes')
+	if res.PropertyID == "" {
+		err = tx.QueryRow(ctx, `
+        INSERT INTO ingest_properties (property_key, address_line1, city, state, zip, lat, lon, city_key, apn, fips, key_version, county_fips, county_name, last_fetch_at, stale_after)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13, now(), now() + interval '5 minutes')
         ON CONFLICT (property_key)
-        DO UPDATE SET address_line1=EXCLUDED.address_line1, city=EXCLUDED.city, state=EXCLUDED.state, zip=EXCLUDED.zip, lat=EXCLUDED.lat, lon=EXCLUDED.lon, updated_at=now(), last_fetch_at=now(), stale_after=now() + interval '5 minutes'
+        DO UPDATE SET address_line1=EXCLUDED.address_line1, city=EXCLUDED.city, state=EXCLUDED.state, zip=EXCLUDED.zip, lat=EXCLUDED.lat, lon=EXCLUDED.lon, city_key=EXCLUDED.city_key, apn=COALESCE(EXCLUDED.apn, ingest_properties.apn), fips=COALESCE(EXCLUDED.fips, ingest_properties.fips), key_version=EXCLUDED.key_version, county_fips=COALESCE(EXCLUDED.county_fips, ingest_properties.county_fips), county_name=COALESCE(EXCLUDED.county_name, ingest_properties.county_name), updated_at=now(), last_fetch_at=now(), stale_after=now() + interval '5 minutes'
         RETURNING id`,
-		in.PropertyKey, in.Address1, in.City, in.State, in.Zip, in.Lat, in.Lon,
-	).Scan(&res.PropertyID)
-	if err != nil {
+			in.PropertyKey, in.Address1, in.City, in.State, in.Zip, in.Lat, in.Lon, canon.CityKey(in.City), in.Parcel.apnArg(), in.Parcel.fipsArg(), canon.KeyVersion(), countyFIPSArg(in.County), countyNameArg(in.County),
+		).Scan(&res.PropertyID)
+		if err != nil {
+			return res, err
+		}
+	}
+	if in.AddressKey != "" {
+		_, err = tx.Exec(ctx, `
+        INSERT INTO address_aliases (kind, alias_key, property_id) VALUES ('address', $1, $2)
+        ON CONFLICT (kind, alias_key) DO UPDATE SET property_id = EXCLUDED.property_id
+        WHERE address_aliases.property_id <> EXCLUDED.property_id`, in.AddressKey, res.PropertyID)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+        SELECT status, list_price FROM ingest_listings
+        WHERE provider=$1 AND source_id=$2 AND listing_id IS NOT DISTINCT FROM $3
+        FOR UPDATE`, in.Provider, in.SourceID, in.ListingID).Scan(&res.PrevStatus, &res.PrevPrice)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		res.Inserted = true
+	case err != nil:
 		return res, err
 	}
 
 	// ingest_listings upsert
-	err = tx.QueryRowContext(ctx, `
-        INSERT INTO ingest_listings (property_id, provider, source_id, listing_id, status, list_price, beds, baths, sqft, coords, last_fetch_at, stale_after)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9, NULL, now(), now() + interval '5 minutes')
+	err = tx.QueryRow(ctx, `
+        INSERT INTO ingest_listings (property_id, provider, source_id, listing_id, status, list_price, beds, baths, sqft, agents, description, property_type, list_date, flags, extras, coords, last_fetch_at, stale_after, status_changed_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15, point($17::float8, $16::float8), now(), now() + interval '5 minutes', now())
         ON CONFLICT (provider, source_id, listing_id)
-        DO UPDATE SET property_id=EXCLUDED.property_id, status=EXCLUDED.status, list_price=EXCLUDED.list_price, beds=EXCLUDED.beds, baths=EXCLUDED.baths, sqft=EXCLUDED.sqft, updated_at=now(), last_fetch_at=now(), stale_after=now() + interval '5 minutes'
+        DO UPDATE SET property_id=CASE WHEN ingest_listings.property_pinned THEN ingest_listings.property_id ELSE EXCLUDED.property_id END, status=EXCLUDED.status, status_changed_at=CASE WHEN ingest_listings.status IS DISTINCT FROM EXCLUDED.status THEN now() ELSE ingest_listings.status_changed_at END, list_price=EXCLUDED.list_price, beds=EXCLUDED.beds, baths=EXCLUDED.baths, sqft=EXCLUDED.sqft, agents=COALESCE(EXCLUDED.agents, ingest_listings.agents), description=COALESCE(EXCLUDED.description, ingest_listings.description), property_type=COALESCE(EXCLUDED.property_type, ingest_listings.property_type), list_date=COALESCE(EXCLUDED.list_date, ingest_listings.list_date), flags=COALESCE(ingest_listings.flags, '{}'::jsonb) || COALESCE(EXCLUDED.flags, '{}'::jsonb), extras=COALESCE(ingest_listings.extras, '{}'::jsonb) || COALESCE(EXCLUDED.extras, '{}'::jsonb), coords=COALESCE(EXCLUDED.coords, ingest_listings.coords), updated_at=now(), last_fetch_at=now(), stale_after=now() + interval '5 minutes', missed_cycles=0
         RETURNING id`,
-		res.PropertyID, in.Provider, in.SourceID, in.ListingID, in.Status, in.ListPrice, in.Beds, in.Baths, in.Sqft,
+		res.PropertyID, in.Provider, in.SourceID, in.ListingID, in.Status, in.ListPrice, in.Beds, in.Baths, in.Sqft, agentsJSON(in.Agents), nullString(in.Description), nullString(in.PropertyType), in.ListDate, in.Features.flagsJSON(), in.Features.extrasJSON(), in.Lat, in.Lon,
 	).Scan(&res.ListingID)
 	if err != nil {
 		return res, err
 	}
 
-	if len(in.Photos) > 0 {
-		if err = replaceListingPhotosTx(ctx, tx, res.ListingID, in.Photos); err != nil {
+	if len(in.Agents) > 0 {
+		if err = syncListingAgentsTx(ctx, tx, in.Provider, res.ListingID, in.Agents); err != nil {
+			return res, err
+		}
+	}
+
+	if in.OpenHouses != nil {
+		if err = syncOpenHousesTx(ctx, tx, res.ListingID, in.OpenHouses); err != nil {
+			return res, err
+		}
+	}
+
+	syncPhotos := len(in.Photos) > 0
+	if syncPhotos && in.InlinePhotos {
+		var stored bool
+		if err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ingest_listing_photos WHERE listing_id=$1)`, res.ListingID).Scan(&stored); err != nil {
+			return res, err
+		}
+		syncPhotos = !stored
+	}
+	if syncPhotos {
+		if res.Photos, err = syncListingPhotosTx(ctx, tx, res.ListingID, in.Photos, in.InlinePhotos); err != nil {
 			return res, err
 		}
+		res.Photos.PropertyID, res.Photos.PropertyKey = res.PropertyID, in.PropertyKey
+	}
+
+	if err = refreshListingSummariesTx(ctx, tx, []string{res.PropertyID}, []string{res.ListingID}); err != nil {
+		return res, err
 	}
 
 	// raw snapshot for ingestion audit
-	sum := sha256.Sum256(in.PayloadJSON)
-	sha := hex.EncodeToString(sum[:])
-	if _, err = tx.ExecContext(ctx, `
+	if !in.SkipSnapshot {
+		sum := sha256.Sum256(in.PayloadJSON)
+		sha := hex.EncodeToString(sum[:])
+		if _, err = tx.Exec(ctx, `
         INSERT INTO ingest_provider_raw_snapshots (provider, endpoint, external_id, payload, payload_sha256)
         VALUES ($1,$2,$3,$4,$5)
-    `, in.Provider, in.Endpoint, in.ExternalID, string(in.PayloadJSON), sha); err != nil {
-		return res, err
+    `, in.Provider, in.Endpoint, in.ExternalID, jsonbOrNull(in.PayloadJSON), sha); err != nil {
+			return res, err
+		}
 	}
 
-	err = tx.Commit()
+	err = tx.Commit(ctx)
 	if err != nil {
 		return res, err
 	}
 	return res, nil
 }
 
-func (s *Store) FetchListingsByPostal(ctx context.Context, postal string, limit, offset int, propertyType string) ([]ListingRecord, error) {
-	if s.DB == nil {
-		return nil, errors.New("nil db")
+// ListingFilter narrows and orders a listing page. Zero values don't filter.
+type ListingFilter struct {
+	PropertyType string
+	Sort         ListingSort
+	// MinDOM and MaxDOM bound days on market. Listings without a list date
+	// are excluded when either is set.
+	MinDOM   *int
+	MaxDOM   *int
+	Features FeatureFilter
+}
+
+func (s *Store) FetchListingsByPostal(ctx context.Context, postal string, limit, offset int, f ListingFilter) ([]ListingRecord, error) {
+	if limit <= 0 {
+		limit = 5
 	}
+	if offset < 0 {
+		offset = 0
+	}
+	stmt, query := listingPageQuery(stmtFetchListingsByPostal, sqlFetchListingsByPostal, f.Sort)
+	args := append([]any{postal, limit, offset, proptype.Normalize(f.PropertyType), f.MinDOM, f.MaxDOM}, f.Features.args()...)
+	return s.fetchListingPage(ctx, stmt, query, args...)
+}
+
+// FetchListingsByCity pages listings for a city/state. The city is matched on
+// canon.CityKey so "Saint Paul" and "St. Paul" resolve to the same rows.
+func (s *Store) FetchListingsByCity(ctx context.Context, city, state string, limit, offset int, f ListingFilter) ([]ListingRecord, error) {
 	if limit <= 0 {
 		limit = 5
 	}
 	if offset < 0 {
 		offset = 0
 	}
-	args := []any{postal, limit, offset}
-	query := strings.Builder{}
-	query.WriteString(`
-		SELECT p.property_key, p.address_line1, p.city, p.state, p.zip,
-		       p.lat, p.lon, l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type
-		FROM ingest_properties p
-		JOIN ingest_listings l ON l.property_id = p.id
-		WHERE p.zip = $1
-	`)
-	if propertyType != "" {
-		query.WriteString(" AND l.property_type = $4")
-		args = append(args, propertyType)
-	}
-	query.WriteString(`
-		ORDER BY l.updated_at DESC
-		LIMIT $2 OFFSET $3
-	`)
-	rows, err := s.DB.QueryContext(ctx, query.String(), args...)
+	stmt, query := listingPageQuery(stmtFetchListingsByCity, sqlFetchListingsByCity, f.Sort)
+	args := append([]any{strings.ToUpper(state), canon.CityKey(city), limit, offset, proptype.Normalize(f.PropertyType), f.MinDOM, f.MaxDOM}, f.Features.args()...)
+	return s.fetchListingPage(ctx, stmt, query, args...)
+}
+
+// fetchListingPage runs one of the prepared listing page queries, which read
+// listing_summaries and so carry photos already.
+func (s *Store) fetchListingPage(ctx context.Context, stmt, query string, args ...any) ([]ListingRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryPrepared(ctx, stmt, query, args...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var records []ListingRecord
-	for rows.Next() {
+	return collectListingSummaries(rows)
+}
+
+// collectListingPage scans rows shaped like FetchListingsInBounds' and
+// attaches each listing's photos with one follow-up query.
+func (s *Store) collectListingPage(ctx context.Context, rows pgx.Rows) ([]ListingRecord, error) {
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ListingRecord, error) {
 		var rec ListingRecord
-		if err := rows.Scan(&rec.PropertyKey, &rec.AddressLine1, &rec.City, &rec.State, &rec.Zip,
-			&rec.Lat, &rec.Lon, &rec.ListingID, &rec.ListingExternalID, &rec.ListPrice, &rec.Beds, &rec.Baths, &rec.Sqft, &rec.PropertyType); err != nil {
-			return nil, err
-		}
-		records = append(records, rec)
-	}
-	if err := rows.Err(); err != nil {
+		err := row.Scan(&rec.PropertyKey, &rec.AddressLine1, &rec.City, &rec.State, &rec.Zip,
+			&rec.Lat, &rec.Lon, &rec.ListingID, &rec.ListingExternalID, &rec.ListPrice, &rec.Beds, &rec.Baths, &rec.Sqft, &rec.PropertyType, &rec.ListDate,
+			&rec.Features)
+		return rec, err
+	})
+	if err != nil {
 		return nil, err
 	}
+	return records, s.attachListingPhotos(ctx, records)
+}
+
+// attachListingPhotos fills Photos for every record with one query.
+func (s *Store) attachListingPhotos(ctx context.Context, records []ListingRecord) error {
 	if len(records) == 0 {
-		return records, nil
+		return nil
 	}
-	placeholders := make([]string, len(records))
-	photoArgs := make([]any, len(records))
+	ids := make([]string, len(records))
 	for i, rec := range records {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
-		photoArgs[i] = rec.ListingID
+		ids[i] = rec.ListingID
 	}
-	photoRows, err := s.DB.QueryContext(ctx,
-		`SELECT listing_id, href FROM ingest_listing_photos WHERE listing_id IN (`+strings.Join(placeholders, ",")+`) ORDER BY listing_id, position`,
-		photoArgs...,
-	)
+	photoRows, err := s.queryPrepared(ctx, stmtFetchPhotosByListings, sqlFetchPhotosByListings, ids)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer photoRows.Close()
 	photosByListing := make(map[string][]string)
 	for photoRows.Next() {
 		var listingID, href string
 		if err := photoRows.Scan(&listingID, &href); err != nil {
-			return nil, err
+			return err
 		}
 		photosByListing[listingID] = append(photosByListing[listingID], href)
 	}
 	if err := photoRows.Err(); err != nil {
-		return nil, err
+		return err
 	}
 	for i := range records {
 		records[i].Photos = photosByListing[records[i].ListingID]
 	}
-	return records, nil
+	return nil
 }
 
 func (s *Store) FetchListingPhotos(ctx context.Context, providerListingID string) ([]string, error) {
-	if s.DB == nil {
+	if s.Pool == nil {
 		return nil, errors.New("nil db")
 	}
-	rows, err := s.DB.QueryContext(ctx, `
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
 		SELECT lp.href
 		FROM ingest_listings l
-		JOIN ingest_listing_photos lp ON lp.listing_id = l.id
-		WHERE l.listing_id = $1
+		JOIN LATERAL `+distinctListingPhotos+` lp ON true
+		WHERE l.listing_id = $1 AND l.deleted_at IS NULL AND lp.phash_rank = 1
 		ORDER BY lp.position, lp.created_at
 	`, providerListingID)
 	if err != nil {
 		return nil, err
 	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// FetchListingPhotosByIDs is FetchListingPhotos for many provider listing IDs
+// in one query. Listings without photos are absent from the map.
+func (s *Store) FetchListingPhotosByIDs(ctx context.Context, providerListingIDs []string) (map[string][]string, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	out := make(map[string][]string, len(providerListingIDs))
+	if len(providerListingIDs) == 0 {
+		return out, nil
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT l.listing_id, lp.href
+		FROM ingest_listings l
+		JOIN LATERAL `+distinctListingPhotos+` lp ON true
+		WHERE l.listing_id = ANY($1::text[]) AND l.deleted_at IS NULL AND lp.phash_rank = 1
+		ORDER BY l.listing_id, lp.position, lp.created_at
+	`, providerListingIDs)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
-	var photos []string
 	for rows.Next() {
-		var href string
-		if err := rows.Scan(&href); err != nil {
+		var id, href string
+		if err := rows.Scan(&id, &href); err != nil {
 			return nil, err
 		}
-		photos = append(photos, href)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		out[id] = append(out[id], href)
 	}
-	return photos, nil
+	return out, rows.Err()
 }
 
-func (s *Store) ReplaceListingPhotos(ctx context.Context, providerListingID string, photos []ListingPhotoInput) error {
-	if s.DB == nil {
-		return errors.New("nil db")
+// SyncListingPhotos diffs photos against what is stored for the listing,
+// preserving photo IDs for hrefs that are still present.
+// It returns how the stored set changed; an unknown listing is a no-op.
+func (s *Store) SyncListingPhotos(ctx context.Context, providerListingID string, photos []ListingPhotoInput) (PhotoDiff, error) {
+	var diff PhotoDiff
+	if s.Pool == nil {
+		return diff, errors.New("nil db")
 	}
-	var listingUUID string
-	err := s.DB.QueryRowContext(ctx, `SELECT id FROM ingest_listings WHERE listing_id=$1 ORDER BY updated_at DESC LIMIT 1`, providerListingID).Scan(&listingUUID)
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var listingUUID, propertyID, propertyKey string
+	err := s.Pool.QueryRow(ctx, `
+		SELECT l.id, p.id, p.property_key
+		FROM ingest_listings l
+		JOIN ingest_properties p ON p.id = l.property_id
+		WHERE l.listing_id=$1
+		ORDER BY l.updated_at DESC LIMIT 1`, providerListingID).Scan(&listingUUID, &propertyID, &propertyKey)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil
+		if errors.Is(err, pgx.ErrNoRows) {
+			return diff, nil
 		}
-		return err
+		return diff, err
 	}
-	tx, err := s.DB.BeginTx(ctx, nil)
+	tx, err := s.Pool.Begin(ctx)
 	if err != nil {
-		return err
+		return diff, err
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
-	if err = replaceListingPhotosTx(ctx, tx, listingUUID, photos); err != nil {
-		return err
+	defer func() { _ = tx.Rollback(ctx) }()
+	if diff, err = syncListingPhotosTx(ctx, tx, listingUUID, photos, false); err != nil {
+		return diff, err
+	}
+	if err = refreshListingSummariesTx(ctx, tx, nil, []string{listingUUID}); err != nil {
+		return diff, err
 	}
-	return tx.Commit()
+	diff.PropertyID, diff.PropertyKey = propertyID, propertyKey
+	return diff, tx.Commit(ctx)
 }
 
 func (s *Store) LookupPropertyKeyByListing(ctx context.Context, providerListingID string) (string, error) {
-	if s.DB == nil {
+	if s.Pool == nil {
 		return "", errors.New("nil db")
 	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
 	var propertyKey string
-	err := s.DB.QueryRowContext(ctx, `
+	err := s.queryRowRead(ctx, `
 		SELECT p.property_key
 		FROM ingest_listings l
 		JOIN ingest_properties p ON p.id = l.property_id
-		WHERE l.listing_id = $1
+		WHERE l.listing_id = $1 AND l.deleted_at IS NULL AND p.deleted_at IS NULL
 		ORDER BY l.updated_at DESC
 		LIMIT 1
-	`, providerListingID).Scan(&propertyKey)
-	if errors.Is(err, sql.ErrNoRows) {
+	`, []any{providerListingID}, &propertyKey)
+	if errors.Is(err, pgx.ErrNoRows) {
 		return "", nil
 	}
 	if err != nil {
@@ -418,57 +1193,119 @@ func (s *Store) LookupPropertyKeyByListing(ctx context.Context, providerListingI
 	return propertyKey, nil
 }
 
-func replaceListingPhotosTx(ctx context.Context, tx *sql.Tx, listingUUID string, photos []ListingPhotoInput) error {
-	if _, err := tx.ExecContext(ctx, `DELETE FROM ingest_listing_photos WHERE listing_id=$1`, listingUUID); err != nil {
-		return err
+// sqlUpsertListingPhotos writes a listing's whole photo set in one
+// statement: $1 is the listing, $2-$10 are parallel arrays, one element per
+// photo, unnested into rows, and $11 marks the set as inline. Tags arrive
+// as a JSON array per photo; photos whose row changed get their tag rows
+// brought in line with it. Curated photos keep their position.
+const sqlUpsertListingPhotos = `
+		WITH input AS (
+			SELECT * FROM unnest($2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::int[], $9::text[], $10::bool[])
+				AS i(href, description, media_type, kind, tags, title, position, room, is_primary)
+		), up AS (
+			INSERT INTO ingest_listing_photos (listing_id, href, description, media_type, kind, tags, title, position,
+			                                   room, room_source, room_at, is_primary, inline)
+			SELECT $1, i.href, i.description, i.media_type, i.kind, i.tags::jsonb, i.title, i.position,
+			       i.room, CASE WHEN i.room IS NOT NULL THEN 'tags' END, CASE WHEN i.room IS NOT NULL THEN now() END,
+			       i.is_primary AND NOT EXISTS (SELECT 1 FROM ingest_listing_photos p WHERE p.listing_id = $1 AND p.is_primary),
+			       $11::bool
+			FROM input i
+			ON CONFLICT (listing_id, href) DO UPDATE SET
+				description=EXCLUDED.description, media_type=EXCLUDED.media_type, kind=EXCLUDED.kind,
+				tags=EXCLUDED.tags, title=EXCLUDED.title, inline=EXCLUDED.inline,
+				position=CASE WHEN ingest_listing_photos.curated_at IS NULL THEN EXCLUDED.position ELSE ingest_listing_photos.position END,
+				room=COALESCE(EXCLUDED.room, ingest_listing_photos.room),
+				room_source=COALESCE(EXCLUDED.room_source, ingest_listing_photos.room_source),
+				room_at=COALESCE(EXCLUDED.room_at, ingest_listing_photos.room_at)
+			WHERE (ingest_listing_photos.description, ingest_listing_photos.media_type, ingest_listing_photos.kind,
+			       ingest_listing_photos.tags, ingest_listing_photos.title, ingest_listing_photos.position)
+			      IS DISTINCT FROM
+			      (EXCLUDED.description, EXCLUDED.media_type, EXCLUDED.kind, EXCLUDED.tags, EXCLUDED.title,
+			       CASE WHEN ingest_listing_photos.curated_at IS NULL THEN EXCLUDED.position ELSE ingest_listing_photos.position END)
+			   OR (EXCLUDED.room IS NOT NULL AND ingest_listing_photos.room IS DISTINCT FROM EXCLUDED.room)
+			   OR ingest_listing_photos.inline <> EXCLUDED.inline
+			RETURNING id, href, tags
+		), labels AS (
+			SELECT up.id, t.label
+			FROM up, jsonb_array_elements_text(COALESCE(up.tags, '[]'::jsonb)) AS t(label)
+			WHERE t.label <> ''
+		), stale_tags AS (
+			DELETE FROM ingest_listing_photo_tags t USING up
+			WHERE t.photo_id = up.id AND NOT EXISTS (SELECT 1 FROM labels l WHERE l.id = up.id AND l.label = t.label)
+		)
+		INSERT INTO ingest_listing_photo_tags (photo_id, label)
+		SELECT DISTINCT id, label FROM labels
+		ON CONFLICT (photo_id, label) DO NOTHING`
+
+// syncListingPhotosTx reconciles the stored photo set for a listing with the
+// incoming one: new hrefs are inserted, existing rows keep their IDs and are
+// only rewritten when metadata or position changed, and hrefs no longer
+// present are deleted. The set is written as one multi-row upsert plus one
+// delete, sent together, however many photos the listing has. inline marks
+// the set as a search payload's rather than the listing's full set.
+func syncListingPhotosTx(ctx context.Context, tx pgx.Tx, listingUUID string, photos []ListingPhotoInput, inline bool) (PhotoDiff, error) {
+	diff := PhotoDiff{ListingID: listingUUID}
+	rows, err := tx.Query(ctx, `SELECT href FROM ingest_listing_photos WHERE listing_id=$1`, listingUUID)
+	if err != nil {
+		return diff, err
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return diff, err
+	}
+	before := make(map[string]struct{}, len(existing))
+	for _, href := range existing {
+		before[href] = struct{}{}
 	}
+	seen := make(map[string]struct{}, len(photos))
+	keep := make([]string, 0, len(photos))
+	var descriptions, mediaTypes, kinds, tags, titles, rooms []*string
+	var positions []int32
+	var primaries []bool
+	primarySeen := false
 	for idx, photo := range photos {
 		if photo.Href == "" {
 			continue
 		}
+		if _, dup := seen[photo.Href]; dup {
+			continue
+		}
+		seen[photo.Href] = struct{}{}
+		keep = append(keep, photo.Href)
+		if _, ok := before[photo.Href]; !ok {
+			diff.Added++
+		}
 		position := photo.Position
 		if position < 0 {
 			position = idx
 		}
-		var tagsJSON any
+		var tagsJSON string
 		if len(photo.Tags) > 0 {
 			b, err := json.Marshal(photo.Tags)
 			if err != nil {
-				return err
-			}
-			tagsJSON = b
-		}
-		var photoID string
-		if err := tx.QueryRowContext(ctx, `
-			INSERT INTO ingest_listing_photos (listing_id, href, description, media_type, kind, tags, title, position)
-			VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
-			RETURNING id
-		`,
-			listingUUID,
-			photo.Href,
-			nullString(photo.Description),
-			nullString(photo.MediaType),
-			nullString(photo.Kind),
-			tagsJSON,
-			nullString(photo.Title),
-			position,
-		).Scan(&photoID); err != nil {
-			return err
-		}
-		for _, label := range photo.Tags {
-			if label == "" {
-				continue
-			}
-			if _, err := tx.ExecContext(ctx, `
-				INSERT INTO ingest_listing_photo_tags (photo_id, label)
-				VALUES ($1,$2)
-				ON CONFLICT (photo_id, label) DO NOTHING
-			`, photoID, label); err != nil {
-				return err
+				return diff, err
 			}
+			tagsJSON = string(b)
 		}
+		descriptions = append(descriptions, optString(photo.Description))
+		mediaTypes = append(mediaTypes, optString(photo.MediaType))
+		kinds = append(kinds, optString(photo.Kind))
+		tags = append(tags, optString(tagsJSON))
+		titles = append(titles, optString(photo.Title))
+		positions = append(positions, int32(position))
+		rooms = append(rooms, optString(photo.Room))
+		// The unique index allows one primary photo per listing.
+		primaries = append(primaries, photo.Primary && !primarySeen)
+		primarySeen = primarySeen || photo.Primary
 	}
-	return nil
+	batch := &pgx.Batch{}
+	if len(keep) > 0 {
+		batch.Queue(sqlUpsertListingPhotos, listingUUID, keep, descriptions, mediaTypes, kinds, tags, titles, positions, rooms, primaries, inline)
+	}
+	batch.Queue(`DELETE FROM ingest_listing_photos WHERE listing_id=$1 AND NOT (href = ANY($2::text[]))`, listingUUID, keep)
+	diff.Count = len(keep)
+	diff.Removed = len(existing) - (len(keep) - diff.Added)
+	return diff, tx.SendBatch(ctx, batch).Close()
 }
 
 func nullString(v string) sql.NullString {
@@ -477,3 +1314,20 @@ func nullString(v string) sql.NullString {
 	}
 	return sql.NullString{String: v, Valid: true}
 }
+
+// optString is nullString for array parameters, whose NULL elements are nil.
+func optString(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// jsonbOrNull passes raw JSON through to a JSONB parameter, mapping empty
+// input to SQL NULL rather than an invalid empty document.
+func jsonbOrNull(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return json.RawMessage(b)
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PropertyRecord is a canonical property row without its listings.
+type PropertyRecord struct {
+	PropertyKey  string
+	AddressLine1 string
+	City         string
+	State        string
+	Zip          string
+	Lat          sql.NullFloat64
+	Lon          sql.NullFloat64
+	UpdatedAt    time.Time
+}
+
+// propertyKeyMatch matches ingest_properties p by the key in $1 or by an
+// alias left when that key was merged into p or p was rekeyed.
+const propertyKeyMatch = `(p.property_key = $1 OR p.id = ` + aliasedPropertyID + `)`
+
+// FetchProperty loads a property by key, following merge and rekey aliases.
+// It returns nil when the key is unknown.
+func (s *Store) FetchProperty(ctx context.Context, propertyKey string) (*PropertyRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var p PropertyRecord
+	err := s.queryRowRead(ctx, `
+		SELECT property_key, address_line1, city, state, zip, lat, lon, updated_at
+		FROM ingest_properties
+		WHERE (property_key = $1 OR id = `+aliasedPropertyID+`)
+		  AND deleted_at IS NULL
+		ORDER BY property_key = $1 DESC
+		LIMIT 1
+	`, []any{propertyKey}, &p.PropertyKey, &p.AddressLine1, &p.City, &p.State, &p.Zip, &p.Lat, &p.Lon, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// FetchProperties is FetchProperty for many keys in one query. The map is
+// keyed by the requested key; unknown keys are absent.
+func (s *Store) FetchProperties(ctx context.Context, propertyKeys []string) (map[string]*PropertyRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	out := make(map[string]*PropertyRecord, len(propertyKeys))
+	if len(propertyKeys) == 0 {
+		return out, nil
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT k.key, p.property_key, p.address_line1, p.city, p.state, p.zip, p.lat, p.lon, p.updated_at
+		FROM unnest($1::text[]) AS k(key)
+		JOIN LATERAL (
+			SELECT property_key, address_line1, city, state, zip, lat, lon, updated_at
+			FROM ingest_properties
+			WHERE (property_key = k.key OR id = `+aliasedBatchPropertyID+`)
+			  AND deleted_at IS NULL
+			ORDER BY property_key = k.key DESC
+			LIMIT 1
+		) p ON true
+	`, propertyKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var p PropertyRecord
+		if err := rows.Scan(&key, &p.PropertyKey, &p.AddressLine1, &p.City, &p.State, &p.Zip, &p.Lat, &p.Lon, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out[key] = &p
+	}
+	return out, rows.Err()
+}
+
+// FetchListingsByProperty returns every listing recorded for a property,
+// newest first, with status, list date and photos. Past listings are kept so
+// callers can show a listing history.
+func (s *Store) FetchListingsByProperty(ctx context.Context, propertyKey string, limit int) ([]ListingRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT p.property_key, p.address_line1, p.city, p.state, p.zip,
+		       p.lat, p.lon, l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type,
+		       l.status, l.list_date, COALESCE(p.apn, ''), COALESCE(p.fips, ''),
+		       COALESCE(p.county_fips, ''), COALESCE(p.county_name, ''), l.last_fetch_at, l.stale_after
+		FROM ingest_properties p
+		JOIN ingest_listings l ON l.property_id = p.id
+		WHERE `+propertyKeyMatch+` AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY COALESCE(l.list_date, l.created_at) DESC
+		LIMIT $2
+	`, propertyKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ListingRecord, error) {
+		var rec ListingRecord
+		err := row.Scan(&rec.PropertyKey, &rec.AddressLine1, &rec.City, &rec.State, &rec.Zip,
+			&rec.Lat, &rec.Lon, &rec.ListingID, &rec.ListingExternalID, &rec.ListPrice, &rec.Beds, &rec.Baths, &rec.Sqft, &rec.PropertyType,
+			&rec.Status, &rec.ListDate, &rec.Parcel.APN, &rec.Parcel.FIPS,
+			&rec.County.FIPS, &rec.County.Name, &rec.LastFetchAt, &rec.StaleAfter)
+		return rec, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, s.attachListingPhotos(ctx, records)
+}
+
+// FetchListingsByProperties is FetchListingsByProperty for many keys in one
+// query, returning up to limit listings per requested key.
+func (s *Store) FetchListingsByProperties(ctx context.Context, propertyKeys []string, limit int) (map[string][]ListingRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	out := make(map[string][]ListingRecord, len(propertyKeys))
+	if len(propertyKeys) == 0 {
+		return out, nil
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT k.key, r.*
+		FROM unnest($1::text[]) AS k(key)
+		JOIN LATERAL (
+			SELECT p.property_key, p.address_line1, p.city, p.state, p.zip,
+			       p.lat, p.lon, l.id, l.listing_id, l.list_price, l.beds, l.baths, l.sqft, l.property_type,
+			       l.status, l.list_date, COALESCE(p.apn, '') AS apn, COALESCE(p.fips, '') AS fips,
+			       COALESCE(p.county_fips, '') AS county_fips, COALESCE(p.county_name, '') AS county_name,
+			       l.last_fetch_at, l.stale_after
+			FROM ingest_properties p
+			JOIN ingest_listings l ON l.property_id = p.id
+			WHERE (p.property_key = k.key OR p.id = `+aliasedBatchPropertyID+`)
+			  AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+			ORDER BY COALESCE(l.list_date, l.created_at) DESC
+			LIMIT $2
+		) r ON true
+	`, propertyKeys, limit)
+	if err != nil {
+		return nil, err
+	}
+	type keyed struct {
+		key string
+		rec ListingRecord
+	}
+	all, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (keyed, error) {
+		var k keyed
+		rec := &k.rec
+		err := row.Scan(&k.key, &rec.PropertyKey, &rec.AddressLine1, &rec.City, &rec.State, &rec.Zip,
+			&rec.Lat, &rec.Lon, &rec.ListingID, &rec.ListingExternalID, &rec.ListPrice, &rec.Beds, &rec.Baths, &rec.Sqft, &rec.PropertyType,
+			&rec.Status, &rec.ListDate, &rec.Parcel.APN, &rec.Parcel.FIPS,
+			&rec.County.FIPS, &rec.County.Name, &rec.LastFetchAt, &rec.StaleAfter)
+		return k, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]ListingRecord, len(all))
+	for i, k := range all {
+		records[i] = k.rec
+	}
+	if err := s.attachListingPhotos(ctx, records); err != nil {
+		return nil, err
+	}
+	for i, k := range all {
+		out[k.key] = append(out[k.key], records[i])
+	}
+	return out, nil
+}
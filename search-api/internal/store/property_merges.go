@@ -0,0 +1,307 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourorg/search-api/internal/canon"
+)
+
+const (
+	MergeOpMerge = "merge"
+	MergeOpSplit = "split"
+)
+
+var (
+	// ErrPropertyNotFound is returned when a merge or split names an unknown
+	// property key.
+	ErrPropertyNotFound = errors.New("property not found")
+	// ErrInvalidMerge is returned for a merge or split that would not change
+	// anything, such as merging a property into itself.
+	ErrInvalidMerge = errors.New("invalid merge")
+)
+
+// PropertyMerge is one row of the property_merges audit table. For a merge,
+// SourceKey's listings moved to TargetKey and SourceKey became an alias of
+// it; for a split, ListingIDs moved from SourceKey to TargetKey.
+type PropertyMerge struct {
+	ID         string
+	Op         string
+	TargetKey  string
+	SourceKey  string
+	ListingIDs []string
+	// Source is the merged-away property row as JSON; empty for splits.
+	Source    json.RawMessage
+	Actor     string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// SplitInput moves some of a property's listings to the property at a
+// corrected address, creating it when needed.
+type SplitInput struct {
+	PropertyKey string
+	// ListingIDs are ingest_listings IDs or provider listing IDs.
+	ListingIDs []string
+	NewKey     string
+	Address1   string
+	City       string
+	State      string
+	Zip        string
+	Actor      string
+	Reason     string
+}
+
+// propertyIDByKey resolves key, following aliases left by earlier merges
+// and rekeys.
+func propertyIDByKey(ctx context.Context, tx pgx.Tx, key string) (string, error) {
+	var id string
+	err := tx.QueryRow(ctx, `
+		SELECT id FROM ingest_properties WHERE property_key = $1
+		UNION ALL
+		SELECT property_id FROM property_aliases WHERE alias_key = $1
+		UNION ALL
+		SELECT property_id FROM address_aliases WHERE kind = 'key' AND alias_key = $1
+		LIMIT 1
+	`, key).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", ErrPropertyNotFound, key)
+	}
+	return id, err
+}
+
+// MergeProperties folds sourceKey into targetKey: listings, valuations,
+// enrichments and notes move to the target, sourceKey (and any aliases of it) become
+// aliases of the target so later ingests land there, and the source row is
+// deleted after being recorded in the audit row.
+func (s *Store) MergeProperties(ctx context.Context, targetKey, sourceKey, actor, reason string) (PropertyMerge, error) {
+	m := PropertyMerge{Op: MergeOpMerge, TargetKey: targetKey, SourceKey: sourceKey, Actor: actor, Reason: reason}
+	if s.Pool == nil {
+		return m, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return m, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err = setAuditActorTx(ctx, tx); err != nil {
+		return m, err
+	}
+
+	targetID, err := propertyIDByKey(ctx, tx, targetKey)
+	if err != nil {
+		return m, err
+	}
+	var sourceID string
+	err = tx.QueryRow(ctx, `
+		SELECT id, to_jsonb(p) FROM ingest_properties p WHERE property_key = $1 FOR UPDATE
+	`, sourceKey).Scan(&sourceID, &m.Source)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return m, fmt.Errorf("%w: %s", ErrPropertyNotFound, sourceKey)
+	}
+	if err != nil {
+		return m, err
+	}
+	if sourceID == targetID {
+		return m, fmt.Errorf("%w: %s is already %s", ErrInvalidMerge, sourceKey, targetKey)
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE ingest_listings
+		SET property_id = $1, canonical_listing_id = NULL, updated_at = now()
+		WHERE property_id = $2
+		RETURNING id
+	`, targetID, sourceID)
+	if err != nil {
+		return m, err
+	}
+	m.ListingIDs, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return m, err
+	}
+	if _, err = tx.Exec(ctx, `UPDATE property_valuations SET property_id = $1 WHERE property_id = $2`, targetID, sourceID); err != nil {
+		return m, err
+	}
+	// Tax years and transfers the target already has win; the rest move.
+	if _, err = tx.Exec(ctx, `
+		UPDATE property_tax_assessments a SET property_id = $1
+		WHERE a.property_id = $2
+		  AND NOT EXISTS (SELECT 1 FROM property_tax_assessments t WHERE t.property_id = $1 AND t.tax_year = a.tax_year)
+	`, targetID, sourceID); err != nil {
+		return m, err
+	}
+	if _, err = tx.Exec(ctx, `
+		UPDATE property_transfers x SET property_id = $1
+		WHERE x.property_id = $2
+		  AND NOT EXISTS (SELECT 1 FROM property_transfers t
+		                  WHERE t.property_id = $1 AND t.transfer_date = x.transfer_date AND t.event = x.event)
+	`, targetID, sourceID); err != nil {
+		return m, err
+	}
+	// Enrichments move too, the newer result winning where both have run
+	// the same enricher; the delete below would otherwise cascade to them.
+	if _, err = tx.Exec(ctx, `
+		INSERT INTO property_enrichments (property_id, enricher, data, fetched_at)
+		SELECT $1, enricher, data, fetched_at FROM property_enrichments WHERE property_id = $2
+		ON CONFLICT (property_id, enricher) DO UPDATE
+		SET data = EXCLUDED.data, fetched_at = EXCLUDED.fetched_at
+		WHERE property_enrichments.fetched_at < EXCLUDED.fetched_at
+	`, targetID, sourceID); err != nil {
+		return m, err
+	}
+	if _, err = tx.Exec(ctx, `UPDATE property_aliases SET property_id = $1 WHERE property_id = $2`, targetID, sourceID); err != nil {
+		return m, err
+	}
+	if _, err = tx.Exec(ctx, `UPDATE address_aliases SET property_id = $1 WHERE property_id = $2`, targetID, sourceID); err != nil {
+		return m, err
+	}
+	// Notes name their property by key; targetKey may itself be an alias.
+	if _, err = tx.Exec(ctx, `
+		UPDATE notes SET subject_id = (SELECT property_key FROM ingest_properties WHERE id = $1)
+		WHERE subject_kind = 'property' AND subject_id = $2
+	`, targetID, sourceKey); err != nil {
+		return m, err
+	}
+	if err = insertPropertyMergeTx(ctx, tx, &m); err != nil {
+		return m, err
+	}
+	if _, err = tx.Exec(ctx, `
+		INSERT INTO property_aliases (alias_key, property_id, merge_id) VALUES ($1, $2, $3)
+		ON CONFLICT (alias_key) DO UPDATE SET property_id = EXCLUDED.property_id, merge_id = EXCLUDED.merge_id, created_at = now()
+	`, sourceKey, targetID, m.ID); err != nil {
+		return m, err
+	}
+	if err = refreshListingSummariesTx(ctx, tx, []string{targetID}, m.ListingIDs); err != nil {
+		return m, err
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM ingest_properties WHERE id = $1`, sourceID); err != nil {
+		return m, err
+	}
+	return m, tx.Commit(ctx)
+}
+
+// SplitProperty moves in.ListingIDs off in.PropertyKey onto in.NewKey. The
+// moved listings are pinned so later ingests, which still canonicalize to
+// the old key, do not move them back. An alias for NewKey left by an
+// earlier merge is dropped, which undoes that merge for the moved listings.
+func (s *Store) SplitProperty(ctx context.Context, in SplitInput) (PropertyMerge, error) {
+	m := PropertyMerge{Op: MergeOpSplit, TargetKey: in.NewKey, SourceKey: in.PropertyKey, Actor: in.Actor, Reason: in.Reason}
+	if s.Pool == nil {
+		return m, errors.New("nil db")
+	}
+	if len(in.ListingIDs) == 0 {
+		return m, fmt.Errorf("%w: no listings to split", ErrInvalidMerge)
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return m, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err = setAuditActorTx(ctx, tx); err != nil {
+		return m, err
+	}
+
+	sourceID, err := propertyIDByKey(ctx, tx, in.PropertyKey)
+	if err != nil {
+		return m, err
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM property_aliases WHERE alias_key = $1`, in.NewKey); err != nil {
+		return m, err
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM address_aliases WHERE kind = 'key' AND alias_key = $1`, in.NewKey); err != nil {
+		return m, err
+	}
+	var targetID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ingest_properties (property_key, address_line1, city, state, zip, city_key, key_version)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (property_key) DO UPDATE SET updated_at = now(), deleted_at = NULL
+		RETURNING id
+	`, in.NewKey, in.Address1, in.City, in.State, in.Zip, canon.CityKey(in.City), canon.KeyVersion()).Scan(&targetID)
+	if err != nil {
+		return m, err
+	}
+	if targetID == sourceID {
+		return m, fmt.Errorf("%w: %s is already %s", ErrInvalidMerge, in.NewKey, in.PropertyKey)
+	}
+	rows, err := tx.Query(ctx, `
+		UPDATE ingest_listings
+		SET property_id = $1, property_pinned = true, canonical_listing_id = NULL, updated_at = now()
+		WHERE property_id = $2 AND (id::text = ANY($3) OR listing_id = ANY($3))
+		RETURNING id
+	`, targetID, sourceID, in.ListingIDs)
+	if err != nil {
+		return m, err
+	}
+	m.ListingIDs, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return m, err
+	}
+	if len(m.ListingIDs) < len(in.ListingIDs) {
+		return m, fmt.Errorf("%w: only %d of %d listing(s) belong to %s", ErrInvalidMerge, len(m.ListingIDs), len(in.ListingIDs), in.PropertyKey)
+	}
+	// Listings left behind may point at a moved canonical listing.
+	if _, err = tx.Exec(ctx, `
+		UPDATE ingest_listings SET canonical_listing_id = NULL, updated_at = now()
+		WHERE canonical_listing_id = ANY($1::uuid[])
+	`, m.ListingIDs); err != nil {
+		return m, err
+	}
+	if err = refreshListingSummariesTx(ctx, tx, []string{sourceID, targetID}, nil); err != nil {
+		return m, err
+	}
+	if err = insertPropertyMergeTx(ctx, tx, &m); err != nil {
+		return m, err
+	}
+	return m, tx.Commit(ctx)
+}
+
+func insertPropertyMergeTx(ctx context.Context, tx pgx.Tx, m *PropertyMerge) error {
+	var source any
+	if len(m.Source) > 0 {
+		source = m.Source
+	}
+	return tx.QueryRow(ctx, `
+		INSERT INTO property_merges (op, target_key, source_key, listing_ids, source_snapshot, actor, reason)
+		VALUES ($1,$2,$3,COALESCE($4::uuid[], '{}'),$5,$6,$7)
+		RETURNING id, created_at
+	`, m.Op, m.TargetKey, m.SourceKey, m.ListingIDs, source, m.Actor, m.Reason).Scan(&m.ID, &m.CreatedAt)
+}
+
+// PropertyMerges returns the merge and split operations involving
+// propertyKey, newest first.
+func (s *Store) PropertyMerges(ctx context.Context, propertyKey string, limit int) ([]PropertyMerge, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT id, op, target_key, source_key, listing_ids::text[], source_snapshot, actor, reason, created_at
+		FROM property_merges
+		WHERE target_key = $1 OR source_key = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, propertyKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (PropertyMerge, error) {
+		var m PropertyMerge
+		var source []byte
+		err := row.Scan(&m.ID, &m.Op, &m.TargetKey, &m.SourceKey, &m.ListingIDs, &source, &m.Actor, &m.Reason, &m.CreatedAt)
+		m.Source = source
+		return m, err
+	})
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const replicaHealthInterval = 10 * time.Second
+
+// replica is a read-only connection pool plus its last known health.
+type replica struct {
+	db      *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// replicaSet round-robins reads across healthy replicas.
+type replicaSet struct {
+	members []*replica
+	next    atomic.Uint64
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// OpenWithReplicas opens the primary plus read replicas. FetchListings* and
+// Lookup* reads are routed to a healthy replica and fall back to the primary
+// when none are available; all writes go to the primary.
+func OpenWithReplicas(primaryDSN string, pool PoolConfig, replicaDSNs ...string) (*Store, error) {
+	s, err := Open(primaryDSN, pool)
+	if err != nil {
+		return nil, err
+	}
+	if len(replicaDSNs) == 0 {
+		return s, nil
+	}
+	set := &replicaSet{stop: make(chan struct{})}
+	for _, dsn := range replicaDSNs {
+		if dsn == "" {
+			continue
+		}
+		db, err := openPool(dsn, pool)
+		if err != nil {
+			set.close()
+			s.Pool.Close()
+			return nil, err
+		}
+		r := &replica{db: db}
+		r.healthy.Store(true)
+		set.members = append(set.members, r)
+	}
+	if len(set.members) == 0 {
+		return s, nil
+	}
+	s.replicas = set
+	go set.healthLoop()
+	return s, nil
+}
+
+// pick returns the next healthy replica, or nil if there is none.
+func (rs *replicaSet) pick() *replica {
+	if rs == nil || len(rs.members) == 0 {
+		return nil
+	}
+	n := uint64(len(rs.members))
+	start := rs.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		r := rs.members[(start+i)%n]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+func (rs *replicaSet) healthLoop() {
+	ticker := time.NewTicker(replicaHealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rs.stop:
+			return
+		case <-ticker.C:
+			for _, r := range rs.members {
+				rs.check(r)
+			}
+		}
+	}
+}
+
+func (rs *replicaSet) check(r *replica) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := r.db.Ping(ctx)
+	was := r.healthy.Swap(err == nil)
+	if was && err != nil {
+		log.Printf("[WARN] store replica marked unhealthy: %v", err)
+	} else if !was && err == nil {
+		log.Printf("[INFO] store replica recovered")
+	}
+}
+
+func (rs *replicaSet) markDown(r *replica, err error) {
+	if r.healthy.Swap(false) {
+		log.Printf("[WARN] store replica marked unhealthy after query error: %v", err)
+	}
+}
+
+func (rs *replicaSet) close() {
+	if rs == nil {
+		return
+	}
+	rs.once.Do(func() { close(rs.stop) })
+	for _, r := range rs.members {
+		r.db.Close()
+	}
+}
+
+// queryRead runs a read query on a replica, failing over to the primary if
+// the replica errors for reasons other than the caller's context.
+func (s *Store) queryRead(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	if r := s.replicas.pick(); r != nil {
+		rows, err := r.db.Query(ctx, query, args...)
+		if err == nil {
+			return rows, nil
+		}
+		if ctx.Err() != nil || isServerError(err) {
+			return nil, err
+		}
+		s.replicas.markDown(r, err)
+	}
+	return s.Pool.Query(ctx, query, args...)
+}
+
+// queryPrepared runs a read through a statement prepared in
+// prepareHotStatements, re-issuing the raw SQL if the connection predates
+// the schema and never got the prepared statement.
+func (s *Store) queryPrepared(ctx context.Context, name, query string, args ...any) (pgx.Rows, error) {
+	rows, err := s.queryRead(ctx, name, args...)
+	if err == nil {
+		return rows, nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42601" {
+		return s.queryRead(ctx, query, args...)
+	}
+	return nil, err
+}
+
+// isServerError reports whether Postgres answered with an error, meaning the
+// connection itself is healthy and failing over would not help.
+func isServerError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr)
+}
+
+// queryRowRead is the single-row variant of queryRead; dest is scanned in place.
+func (s *Store) queryRowRead(ctx context.Context, query string, args []any, dest ...any) error {
+	if r := s.replicas.pick(); r != nil {
+		err := r.db.QueryRow(ctx, query, args...).Scan(dest...)
+		if err == nil || errors.Is(err, pgx.ErrNoRows) || ctx.Err() != nil || isServerError(err) {
+			return err
+		}
+		s.replicas.markDown(r, err)
+	}
+	return s.Pool.QueryRow(ctx, query, args...).Scan(dest...)
+}
@@ -0,0 +1,330 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/proptype"
+)
+
+// Digest frequencies of a saved search.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+	DigestOff    = "off"
+)
+
+// DigestInterval is how often a frequency sends, or zero for DigestOff.
+func DigestInterval(frequency string) time.Duration {
+	switch frequency {
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// ErrSavedSearchNotFound reports a saved search ID the owner has none under.
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// SavedSearchCriteria select the listings a saved search follows: a ZIP, or
+// a city and state, and optional filters. Zero filters match anything.
+type SavedSearchCriteria struct {
+	PostalCode   string `json:"postalcode,omitempty"`
+	City         string `json:"city,omitempty"`
+	State        string `json:"state,omitempty"`
+	PropertyType string `json:"property_type,omitempty"`
+	MinPrice     int    `json:"minprice,omitempty"`
+	MaxPrice     int    `json:"maxprice,omitempty"`
+	Beds         int    `json:"beds,omitempty"`
+	Baths        int    `json:"baths,omitempty"`
+}
+
+// SavedSearch is a search a caller follows by email digest. Like notes,
+// saved searches belong to the API key or token subject that made them.
+type SavedSearch struct {
+	ID        string
+	Owner     string
+	Name      string
+	Email     string
+	Criteria  SavedSearchCriteria
+	Frequency string
+	CreatedAt time.Time
+	// DigestedUntil is the end of the last window a digest covered;
+	// listings changed after it go in the next one.
+	DigestedUntil time.Time
+	NextDigestAt  time.Time
+}
+
+const savedSearchColumns = `id::text, owner, name, email, criteria, frequency, created_at, digested_until, next_digest_at`
+
+func scanSavedSearch(row pgx.CollectableRow) (SavedSearch, error) {
+	var s SavedSearch
+	err := row.Scan(&s.ID, &s.Owner, &s.Name, &s.Email, &s.Criteria, &s.Frequency, &s.CreatedAt, &s.DigestedUntil, &s.NextDigestAt)
+	return s, err
+}
+
+// CreateSavedSearch stores ss; its first digest covers listings changed
+// from now until one interval later.
+func (s *Store) CreateSavedSearch(ctx context.Context, ss SavedSearch) (SavedSearch, error) {
+	if s.Pool == nil {
+		return SavedSearch{}, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		INSERT INTO saved_searches (owner, name, email, criteria, frequency, digested_until, next_digest_at)
+		VALUES ($1, $2, $3, $4, $5, now(), now() + make_interval(secs => $6))
+		RETURNING `+savedSearchColumns,
+		ss.Owner, ss.Name, ss.Email, ss.Criteria, ss.Frequency, DigestInterval(ss.Frequency).Seconds())
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	return pgx.CollectExactlyOneRow(rows, scanSavedSearch)
+}
+
+// SavedSearches returns owner's saved searches, oldest first.
+func (s *Store) SavedSearches(ctx context.Context, owner string) ([]SavedSearch, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT `+savedSearchColumns+` FROM saved_searches WHERE owner = $1 ORDER BY created_at, id
+	`, owner)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanSavedSearch)
+}
+
+// SavedSearchUpdate changes a saved search's name, email or frequency; nil
+// fields are left as they are.
+type SavedSearchUpdate struct {
+	Name      *string
+	Email     *string
+	Frequency *string
+}
+
+// UpdateSavedSearch applies u to one of owner's saved searches. A new
+// frequency reschedules the next digest one interval after the last;
+// turning digests back on starts the next window now rather than covering
+// the time they were off.
+func (s *Store) UpdateSavedSearch(ctx context.Context, owner, id string, u SavedSearchUpdate) (SavedSearch, error) {
+	if s.Pool == nil {
+		return SavedSearch{}, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var interval *float64
+	if u.Frequency != nil {
+		secs := DigestInterval(*u.Frequency).Seconds()
+		interval = &secs
+	}
+	rows, err := s.Pool.Query(ctx, `
+		UPDATE saved_searches SET
+			name = COALESCE($3, name),
+			email = COALESCE($4, email),
+			frequency = COALESCE($5, frequency),
+			digested_until = CASE WHEN frequency = 'off' AND $6::float8 IS NOT NULL THEN now() ELSE digested_until END,
+			next_digest_at = CASE
+				WHEN $6::float8 IS NULL THEN next_digest_at
+				WHEN frequency = 'off' THEN now() + make_interval(secs => $6)
+				ELSE digested_until + make_interval(secs => $6)
+			END,
+			updated_at = now()
+		WHERE owner = $1 AND id::text = $2
+		RETURNING `+savedSearchColumns, owner, id, u.Name, u.Email, u.Frequency, interval)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	ss, err := pgx.CollectExactlyOneRow(rows, scanSavedSearch)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return SavedSearch{}, fmt.Errorf("%w: %s", ErrSavedSearchNotFound, id)
+	}
+	return ss, err
+}
+
+// DeleteSavedSearch deletes one of owner's saved searches and its digest
+// history.
+func (s *Store) DeleteSavedSearch(ctx context.Context, owner, id string) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM saved_searches WHERE owner = $1 AND id::text = $2`, owner, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrSavedSearchNotFound, id)
+	}
+	return nil
+}
+
+// DueSavedSearches returns up to limit saved searches whose next digest is
+// due at now, longest overdue first.
+func (s *Store) DueSavedSearches(ctx context.Context, now time.Time, limit int) ([]SavedSearch, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+savedSearchColumns+` FROM saved_searches
+		WHERE frequency <> 'off' AND next_digest_at <= $1
+		ORDER BY next_digest_at
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanSavedSearch)
+}
+
+// DigestListing is a listing that appeared or changed price or status in a
+// digest window.
+type DigestListing struct {
+	ListingID     string
+	PropertyKey   string
+	Address       string
+	City          string
+	State         string
+	Zip           string
+	Status        string
+	Price         *float64
+	Beds          *int
+	Baths         *float64
+	Sqft          *int
+	New           bool
+	PriceChanged  bool
+	StatusChanged bool
+}
+
+// DigestListings returns up to limit live listings matching c that were
+// added, restored or changed price or status in (since, until], new ones
+// first, and how many matched in all. Changes are read from the ingest
+// audit log.
+func (s *Store) DigestListings(ctx context.Context, c SavedSearchCriteria, since, until time.Time, limit int) ([]DigestListing, int, error) {
+	if s.Pool == nil {
+		return nil, 0, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		WITH touched AS (
+			SELECT a.row_id,
+			       bool_or(a.action IN ('insert', 'restore')) AS is_new,
+			       bool_or(a.changes ? 'list_price') AS price_changed,
+			       bool_or(a.changes ? 'status') AS status_changed
+			FROM ingest_audit_log a
+			WHERE a.table_name = 'ingest_listings' AND a.created_at > $1 AND a.created_at <= $2
+			  AND (a.action IN ('insert', 'restore') OR a.changes ?| ARRAY['list_price', 'status'])
+			GROUP BY a.row_id
+		)
+		SELECT l.listing_id, p.property_key, p.address_line1, p.city, p.state, p.zip, l.status,
+		       l.list_price::float8, l.beds::int, l.baths::float8, l.sqft, t.is_new, t.price_changed, t.status_changed,
+		       count(*) OVER () AS total
+		FROM touched t
+		JOIN ingest_listings l ON l.id = t.row_id
+		JOIN ingest_properties p ON p.id = l.property_id
+		WHERE l.deleted_at IS NULL AND p.deleted_at IS NULL AND l.canonical_listing_id IS NULL AND l.listing_id IS NOT NULL
+		  AND ($3 = '' OR p.zip = $3)
+		  AND ($4 = '' OR (p.state = $4 AND p.city_key = $5))
+		  AND ($6 = '' OR l.property_type = $6)
+		  AND ($7 = 0 OR l.list_price >= $7)
+		  AND ($8 = 0 OR l.list_price <= $8)
+		  AND ($9 = 0 OR l.beds >= $9)
+		  AND ($10 = 0 OR l.baths >= $10)
+		ORDER BY t.is_new DESC, l.list_price NULLS LAST, l.id
+		LIMIT $11
+	`, since, until, c.PostalCode, strings.ToUpper(c.State), canon.CityKey(c.City), proptype.Normalize(c.PropertyType),
+		c.MinPrice, c.MaxPrice, c.Beds, c.Baths, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	listings, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (DigestListing, error) {
+		var d DigestListing
+		err := row.Scan(&d.ListingID, &d.PropertyKey, &d.Address, &d.City, &d.State, &d.Zip, &d.Status,
+			&d.Price, &d.Beds, &d.Baths, &d.Sqft, &d.New, &d.PriceChanged, &d.StatusChanged, &total)
+		return d, err
+	})
+	return listings, total, err
+}
+
+// ClaimDigest records that the digest of ss's window starting at
+// ss.DigestedUntil is being sent, and reports false when another worker
+// holds it or it was already sent. A failed attempt, or one stuck sending
+// for longer than stale, can be claimed again.
+func (s *Store) ClaimDigest(ctx context.Context, ss SavedSearch, until time.Time, stale time.Duration) (bool, error) {
+	if s.Pool == nil {
+		return false, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tag, err := s.Pool.Exec(ctx, `
+		INSERT INTO saved_search_digests (saved_search_id, window_start, window_end, status, attempts)
+		VALUES ($1::uuid, $2, $3, 'sending', 1)
+		ON CONFLICT (saved_search_id, window_start) DO UPDATE SET
+			window_end = EXCLUDED.window_end, status = 'sending', error = NULL,
+			attempts = saved_search_digests.attempts + 1, updated_at = now()
+		WHERE saved_search_digests.status = 'failed'
+		   OR (saved_search_digests.status = 'sending' AND saved_search_digests.updated_at < now() - make_interval(secs => $4))
+	`, ss.ID, ss.DigestedUntil, until, stale.Seconds())
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// FinishDigest records the outcome of a claimed digest. On success, with
+// status sent or empty, the saved search moves on to the next window; on
+// failure it is retried after retry.
+func (s *Store) FinishDigest(ctx context.Context, ss SavedSearch, until time.Time, listings int, sendErr error, retry time.Duration) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	status, errMsg := "sent", ""
+	switch {
+	case sendErr != nil:
+		status, errMsg = "failed", sendErr.Error()
+	case listings == 0:
+		status = "empty"
+	}
+	if _, err = tx.Exec(ctx, `
+		UPDATE saved_search_digests
+		SET status = $3, listings = $4, error = NULLIF($5, ''), updated_at = now(),
+		    sent_at = CASE WHEN $3 = 'sent' THEN now() END
+		WHERE saved_search_id = $1::uuid AND window_start = $2
+	`, ss.ID, ss.DigestedUntil, status, listings, errMsg); err != nil {
+		return err
+	}
+	if sendErr != nil {
+		_, err = tx.Exec(ctx, `UPDATE saved_searches SET next_digest_at = now() + make_interval(secs => $2) WHERE id::text = $1`, ss.ID, retry.Seconds())
+	} else {
+		_, err = tx.Exec(ctx, `
+			UPDATE saved_searches SET digested_until = $2, next_digest_at = $2 + make_interval(secs => $3)
+			WHERE id::text = $1
+		`, ss.ID, until, DigestInterval(ss.Frequency).Seconds())
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SearchKey groups searches in the hourly search_analytics rollup. It holds
+// no caller identity: only where and how the searches searched.
+type SearchKey struct {
+	Hour         time.Time
+	Route        string
+	Zip          string
+	City         string
+	PropertyType string
+	OrderBy      string
+	Filters      string // other filter names, sorted and comma-separated
+	Cache        string
+}
+
+// SearchCounts are the searches in one group and what they returned.
+type SearchCounts struct {
+	Searches    int64
+	Results     int64
+	ZeroResults int64
+}
+
+// Add adds o's counts to c.
+func (c *SearchCounts) Add(o SearchCounts) {
+	c.Searches += o.Searches
+	c.Results += o.Results
+	c.ZeroResults += o.ZeroResults
+}
+
+// SearchStatRow is one group's counts.
+type SearchStatRow struct {
+	SearchKey
+	SearchCounts
+}
+
+// AddSearchStats adds rows to the hourly search_analytics rollup; each Hour
+// is truncated to its hour.
+func (s *Store) AddSearchStats(ctx context.Context, rows []SearchStatRow) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var (
+		hours                               []time.Time
+		routes, zips, cities, types, orders []string
+		filters, caches                     []string
+		searches, results, zeros            []int64
+	)
+	for _, r := range rows {
+		hours = append(hours, r.Hour.UTC().Truncate(time.Hour))
+		routes = append(routes, r.Route)
+		zips = append(zips, r.Zip)
+		cities = append(cities, r.City)
+		types = append(types, r.PropertyType)
+		orders = append(orders, r.OrderBy)
+		filters = append(filters, r.Filters)
+		caches = append(caches, r.Cache)
+		searches = append(searches, r.Searches)
+		results = append(results, r.Results)
+		zeros = append(zeros, r.ZeroResults)
+	}
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO search_analytics (hour, route, zip, city, property_type, order_by, filters, cache, searches, results, zero_results)
+		SELECT * FROM unnest($1::timestamptz[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::text[], $9::bigint[], $10::bigint[], $11::bigint[])
+		ON CONFLICT (hour, route, zip, city, property_type, order_by, filters, cache) DO UPDATE SET
+			searches     = search_analytics.searches + EXCLUDED.searches,
+			results      = search_analytics.results + EXCLUDED.results,
+			zero_results = search_analytics.zero_results + EXCLUDED.zero_results
+	`, hours, routes, zips, cities, types, orders, filters, caches, searches, results, zeros)
+	return err
+}
+
+// Orders for SearchAnalyticsQuery.Sort.
+const (
+	SearchSortSearches    = "searches"     // most searched
+	SearchSortZeroResults = "zero_results" // most searches that found nothing
+	SearchSortProvider    = "provider"     // most searches answered by the provider
+)
+
+// SearchAnalyticsQuery selects search_analytics rows in [From, To), of one
+// route when Route is set.
+type SearchAnalyticsQuery struct {
+	From, To time.Time
+	Route    string
+	Sort     string
+	Limit    int
+}
+
+// ZipSearchStats is a ZIP's searches over a query's range, split by how the
+// cache answered them.
+type ZipSearchStats struct {
+	Zip string
+	SearchCounts
+	CacheHits     int64 // fresh or stale from the Redis cache
+	CacheMisses   int64 // served from the database
+	ProviderReads int64 // fetched from the provider
+}
+
+// TopSearchZips returns the ZIPs searched over the range, ordered by q.Sort.
+func (s *Store) TopSearchZips(ctx context.Context, q SearchAnalyticsQuery) ([]ZipSearchStats, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	order := map[string]string{
+		"":                    "searches",
+		SearchSortSearches:    "searches",
+		SearchSortZeroResults: "zero_results",
+		SearchSortProvider:    "provider_reads",
+	}[q.Sort]
+	if order == "" {
+		return nil, fmt.Errorf("unknown search analytics sort %q", q.Sort)
+	}
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT zip, sum(searches) AS searches, sum(results), sum(zero_results) AS zero_results,
+		       coalesce(sum(searches) FILTER (WHERE cache IN ('hit', 'stale')), 0),
+		       coalesce(sum(searches) FILTER (WHERE cache = 'miss'), 0),
+		       coalesce(sum(searches) FILTER (WHERE cache = 'provider'), 0) AS provider_reads
+		FROM search_analytics
+		WHERE hour >= $1 AND hour < $2 AND zip <> '' AND ($3 = '' OR route = $3)
+		GROUP BY zip
+		ORDER BY `+order+` DESC, searches DESC, zip
+		LIMIT $4
+	`, q.From.UTC(), q.To.UTC(), q.Route, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ZipSearchStats, error) {
+		var z ZipSearchStats
+		err := row.Scan(&z.Zip, &z.Searches, &z.Results, &z.ZeroResults, &z.CacheHits, &z.CacheMisses, &z.ProviderReads)
+		return z, err
+	})
+}
+
+// SearchFilterStat is how often one value of a search dimension was used:
+// a property_type, an orderby, a filter name or a city.
+type SearchFilterStat struct {
+	Dimension string
+	Value     string
+	SearchCounts
+}
+
+// SearchFilterStats returns usage of each property type, order, filter and
+// city searched over the range, most searched first within each dimension.
+// Searches that left a dimension unset are not counted in it.
+func (s *Store) SearchFilterStats(ctx context.Context, q SearchAnalyticsQuery) ([]SearchFilterStat, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		WITH a AS (
+			SELECT * FROM search_analytics
+			WHERE hour >= $1 AND hour < $2 AND ($3 = '' OR route = $3)
+		), d AS (
+			SELECT 'property_type' AS dimension, property_type AS value, searches, results, zero_results FROM a
+			UNION ALL
+			SELECT 'orderby', order_by, searches, results, zero_results FROM a
+			UNION ALL
+			SELECT 'filter', f, searches, results, zero_results FROM a, unnest(string_to_array(filters, ',')) AS f
+			UNION ALL
+			SELECT 'city', city, searches, results, zero_results FROM a
+		)
+		SELECT dimension, value, searches, results, zero_results FROM (
+			SELECT dimension, value, sum(searches) AS searches, sum(results) AS results, sum(zero_results) AS zero_results,
+			       row_number() OVER (PARTITION BY dimension ORDER BY sum(searches) DESC, value) AS rank
+			FROM d
+			WHERE value <> ''
+			GROUP BY dimension, value
+		) ranked
+		WHERE rank <= $4
+		ORDER BY dimension, rank
+	`, q.From.UTC(), q.To.UTC(), q.Route, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (SearchFilterStat, error) {
+		var f SearchFilterStat
+		err := row.Scan(&f.Dimension, &f.Value, &f.Searches, &f.Results, &f.ZeroResults)
+		return f, err
+	})
+}
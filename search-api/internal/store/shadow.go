@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrShadowResultNotFound is returned when no shadow comparison has the ID.
+var ErrShadowResultNotFound = errors.New("shadow result not found")
+
+// ShadowMismatch is one field a listing both providers returned disagrees
+// on.
+type ShadowMismatch struct {
+	Key       string `json:"key"`
+	Field     string `json:"field"`
+	Primary   string `json:"primary"`
+	Candidate string `json:"candidate"`
+}
+
+// ShadowResult is one search sent to both the primary provider and a
+// shadow candidate. Listings are matched by property key; OnlyPrimary and
+// OnlyCandidate hold the keys one side returned and the other didn't.
+type ShadowResult struct {
+	ID             int64
+	Candidate      string
+	Route          string // "search" or "listings"
+	Location       string
+	Params         json.RawMessage
+	PrimaryCount   int
+	CandidateCount int
+	Matched        int
+	OnlyPrimary    []string
+	OnlyCandidate  []string
+	Mismatches     []ShadowMismatch
+	PrimaryError   string
+	CandidateError string
+	CandidateMs    int
+	CreatedAt      time.Time
+	// The answers compared, loaded by ShadowResult only.
+	PrimaryCards   json.RawMessage
+	CandidateCards json.RawMessage
+}
+
+// Agrees reports whether both providers answered with the same listings
+// and no field disagreed.
+func (r ShadowResult) Agrees() bool {
+	return r.PrimaryError == "" && r.CandidateError == "" &&
+		len(r.OnlyPrimary) == 0 && len(r.OnlyCandidate) == 0 && len(r.Mismatches) == 0
+}
+
+// AddShadowResult records a shadow comparison.
+func (s *Store) AddShadowResult(ctx context.Context, r ShadowResult) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	mismatches, err := json.Marshal(r.Mismatches)
+	if err != nil {
+		return err
+	}
+	params := r.Params
+	if len(params) == 0 {
+		params = json.RawMessage(`{}`)
+	}
+	_, err = s.Pool.Exec(ctx, `
+		INSERT INTO provider_shadow_results
+			(candidate, route, location, params, primary_count, candidate_count, matched,
+			 only_primary, only_candidate, mismatches, agrees, primary_error, candidate_error,
+			 candidate_ms, primary_cards, candidate_cards)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`, r.Candidate, r.Route, r.Location, params, r.PrimaryCount, r.CandidateCount, r.Matched,
+		nonNilStrings(r.OnlyPrimary), nonNilStrings(r.OnlyCandidate), mismatches, r.Agrees(),
+		r.PrimaryError, r.CandidateError, r.CandidateMs, nullJSON(r.PrimaryCards), nullJSON(r.CandidateCards))
+	return err
+}
+
+func nullJSON(v json.RawMessage) any {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// ShadowQuery selects shadow comparisons created in [From, To), optionally
+// for one candidate and route. Disagreements keeps those that don't agree.
+type ShadowQuery struct {
+	From, To      time.Time
+	Candidate     string
+	Route         string
+	Disagreements bool
+	Limit         int
+}
+
+const shadowResultCols = `id, candidate, route, location, params, primary_count, candidate_count, matched,
+	only_primary, only_candidate, mismatches, primary_error, candidate_error, candidate_ms, created_at`
+
+func scanShadowResult(row pgx.CollectableRow, extra ...any) (ShadowResult, error) {
+	var r ShadowResult
+	var mismatches []byte
+	dest := append([]any{&r.ID, &r.Candidate, &r.Route, &r.Location, &r.Params, &r.PrimaryCount, &r.CandidateCount, &r.Matched,
+		&r.OnlyPrimary, &r.OnlyCandidate, &mismatches, &r.PrimaryError, &r.CandidateError, &r.CandidateMs, &r.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(mismatches, &r.Mismatches); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// ShadowResults returns comparisons matching q, newest first, without the
+// answers compared.
+func (s *Store) ShadowResults(ctx context.Context, q ShadowQuery) ([]ShadowResult, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT `+shadowResultCols+`
+		FROM provider_shadow_results
+		WHERE created_at >= $1 AND created_at < $2
+		  AND ($3 = '' OR candidate = $3)
+		  AND ($4 = '' OR route = $4)
+		  AND (NOT $5 OR NOT agrees)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $6
+	`, q.From, q.To, q.Candidate, q.Route, q.Disagreements, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ShadowResult, error) {
+		return scanShadowResult(row)
+	})
+}
+
+// ShadowResult returns one comparison with both providers' answers.
+func (s *Store) ShadowResult(ctx context.Context, id int64) (ShadowResult, error) {
+	if s.Pool == nil {
+		return ShadowResult{}, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT `+shadowResultCols+`, coalesce(primary_cards, 'null'), coalesce(candidate_cards, 'null')
+		FROM provider_shadow_results
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return ShadowResult{}, err
+	}
+	r, err := pgx.CollectExactlyOneRow(rows, func(row pgx.CollectableRow) (ShadowResult, error) {
+		var primary, candidate []byte
+		r, err := scanShadowResult(row, &primary, &candidate)
+		r.PrimaryCards, r.CandidateCards = primary, candidate
+		return r, err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ShadowResult{}, fmt.Errorf("%w: %d", ErrShadowResultNotFound, id)
+	}
+	return r, err
+}
+
+// ShadowSummary totals one candidate's comparisons on one route. Fields
+// counts mismatches by field.
+type ShadowSummary struct {
+	Candidate       string
+	Route           string
+	Comparisons     int64
+	Agreed          int64
+	PrimaryErrors   int64
+	CandidateErrors int64
+	OnlyPrimary     int64
+	OnlyCandidate   int64
+	Matched         int64
+	Mismatches      int64
+	Fields          map[string]int64
+	MedianMs        int
+}
+
+// ShadowSummaries totals the comparisons in [q.From, q.To) by candidate
+// and route, optionally for one of each.
+func (s *Store) ShadowSummaries(ctx context.Context, q ShadowQuery) ([]ShadowSummary, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		WITH r AS (
+			SELECT * FROM provider_shadow_results
+			WHERE created_at >= $1 AND created_at < $2
+			  AND ($3 = '' OR candidate = $3)
+			  AND ($4 = '' OR route = $4)
+		), f AS (
+			SELECT candidate, route, m->>'field' AS field, count(*) AS n
+			FROM r, jsonb_array_elements(r.mismatches) m
+			GROUP BY 1, 2, 3
+		)
+		SELECT r.candidate, r.route, count(*),
+		       count(*) FILTER (WHERE agrees),
+		       count(*) FILTER (WHERE primary_error <> ''),
+		       count(*) FILTER (WHERE candidate_error <> ''),
+		       coalesce(sum(cardinality(only_primary)), 0),
+		       coalesce(sum(cardinality(only_candidate)), 0),
+		       coalesce(sum(matched), 0),
+		       coalesce(sum(jsonb_array_length(mismatches)), 0),
+		       coalesce(percentile_cont(0.5) WITHIN GROUP (ORDER BY candidate_ms), 0)::int,
+		       coalesce((SELECT jsonb_object_agg(f.field, f.n) FROM f
+		                 WHERE f.candidate = r.candidate AND f.route = r.route), '{}')
+		FROM r
+		GROUP BY r.candidate, r.route
+		ORDER BY r.candidate, r.route
+	`, q.From, q.To, q.Candidate, q.Route)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ShadowSummary, error) {
+		var sum ShadowSummary
+		var fields []byte
+		if err := row.Scan(&sum.Candidate, &sum.Route, &sum.Comparisons, &sum.Agreed, &sum.PrimaryErrors, &sum.CandidateErrors,
+			&sum.OnlyPrimary, &sum.OnlyCandidate, &sum.Matched, &sum.Mismatches, &sum.MedianMs, &fields); err != nil {
+			return sum, err
+		}
+		return sum, json.Unmarshal(fields, &sum.Fields)
+	})
+}
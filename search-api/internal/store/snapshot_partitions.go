@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ingest_provider_raw_snapshots is range-partitioned by fetched_at month so
+// retention drops whole partitions instead of deleting rows. A table created
+// before partitioning is attached as ingest_provider_raw_snapshots_legacy,
+// covering everything up to the month after the migration ran. Rows that
+// land outside every monthly partition go to the default partition.
+const (
+	snapshotsTable        = "ingest_provider_raw_snapshots"
+	snapshotsDefaultTable = snapshotsTable + "_default"
+	// snapshotMonthsAhead is how many months past the current one Migrate
+	// creates partitions for.
+	snapshotMonthsAhead = 2
+)
+
+// sqlPartitionRawSnapshots converts an unpartitioned snapshots table in
+// place. The old table keeps its rows and becomes the legacy partition; its
+// indexes are renamed so the partitioned table can take the original names.
+const sqlPartitionRawSnapshots = `
+DO $$
+BEGIN
+	IF (SELECT relkind FROM pg_class WHERE oid = to_regclass('ingest_provider_raw_snapshots')) = 'r' THEN
+		ALTER TABLE ingest_provider_raw_snapshots RENAME TO ingest_provider_raw_snapshots_legacy;
+		ALTER TABLE ingest_provider_raw_snapshots_legacy RENAME CONSTRAINT ingest_provider_raw_snapshots_pkey TO ingest_provider_raw_snapshots_legacy_pkey;
+		ALTER INDEX IF EXISTS idx_ingest_snapshots_provider RENAME TO idx_ingest_snapshots_legacy_provider;
+		ALTER INDEX IF EXISTS idx_ingest_snapshots_external RENAME TO idx_ingest_snapshots_legacy_external;
+		CREATE TABLE ingest_provider_raw_snapshots (
+			LIKE ingest_provider_raw_snapshots_legacy INCLUDING DEFAULTS,
+			PRIMARY KEY (id, fetched_at)
+		) PARTITION BY RANGE (fetched_at);
+		CREATE INDEX idx_ingest_snapshots_provider ON ingest_provider_raw_snapshots(provider, endpoint, fetched_at DESC);
+		CREATE INDEX idx_ingest_snapshots_external ON ingest_provider_raw_snapshots(provider, external_id);
+		EXECUTE format('ALTER TABLE ingest_provider_raw_snapshots ATTACH PARTITION ingest_provider_raw_snapshots_legacy FOR VALUES FROM (MINVALUE) TO (%L)',
+			date_trunc('month', now()) + interval '1 month');
+	END IF;
+END $$;`
+
+// SnapshotPartition is one partition of the raw snapshots table. Until is
+// the exclusive upper bound of its fetched_at range.
+type SnapshotPartition struct {
+	Name  string
+	Until time.Time
+}
+
+// snapshotPartitionName names the partition holding month's snapshots.
+func snapshotPartitionName(month time.Time) string {
+	return snapshotsTable + month.UTC().Format("_y2006m01")
+}
+
+// EnsureSnapshotPartitions creates the monthly partitions for the month of
+// from and the months following it. A month already covered, by its own
+// partition or the legacy one, is left alone; so is a month whose rows
+// already went to the default partition, which keeps them until
+// TrimDefaultSnapshots removes them.
+func (s *Store) EnsureSnapshotPartitions(ctx context.Context, from time.Time, months int) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	start := time.Date(from.UTC().Year(), from.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= months; i++ {
+		lo, hi := start.AddDate(0, i, 0), start.AddDate(0, i+1, 0)
+		_, err := s.Pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+pgx.Identifier{snapshotPartitionName(lo)}.Sanitize()+
+			` PARTITION OF `+snapshotsTable+` FOR VALUES FROM ('`+lo.Format(time.RFC3339)+`') TO ('`+hi.Format(time.RFC3339)+`')`)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && (pgErr.Code == "42P17" || pgErr.Code == "23514") {
+			// 42P17: overlaps an existing partition; 23514: the default
+			// partition already holds rows for the month.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpiredSnapshotPartitions lists the bounded partitions holding only
+// snapshots fetched before cutoff, oldest first.
+func (s *Store) ExpiredSnapshotPartitions(ctx context.Context, cutoff time.Time) ([]SnapshotPartition, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.Pool.Query(ctx, `
+		SELECT name, until FROM (
+			SELECT c.relname AS name,
+			       (regexp_match(pg_get_expr(c.relpartbound, c.oid), 'TO \(''([^'']+)''\)'))[1]::timestamptz AS until
+			FROM pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			WHERE i.inhparent = to_regclass($1)
+		) p
+		WHERE until <= $2
+		ORDER BY until`, snapshotsTable, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (SnapshotPartition, error) {
+		var p SnapshotPartition
+		err := row.Scan(&p.Name, &p.Until)
+		return p, err
+	})
+}
+
+// CopySnapshotPartition writes every snapshot in the named partition to w as
+// one JSON object per line and returns how many it wrote. It runs without
+// the store's query timeout or the server's statement_timeout, since a
+// partition can hold a month of payloads; ctx bounds it instead.
+func (s *Store) CopySnapshotPartition(ctx context.Context, name string, w io.Writer) (int64, error) {
+	return s.copySnapshots(ctx, w, `SELECT to_jsonb(s)::text FROM `+pgx.Identifier{name}.Sanitize()+` s`)
+}
+
+// CopyDefaultSnapshots writes the snapshots fetched before cutoff in the
+// default partition to w like CopySnapshotPartition, so they can be archived
+// before TrimDefaultSnapshots deletes them.
+func (s *Store) CopyDefaultSnapshots(ctx context.Context, cutoff time.Time, w io.Writer) (int64, error) {
+	return s.copySnapshots(ctx, w, `SELECT to_jsonb(s)::text FROM `+snapshotsDefaultTable+` s WHERE fetched_at < $1`, cutoff)
+}
+
+func (s *Store) copySnapshots(ctx context.Context, w io.Writer, query string, args ...any) (int64, error) {
+	if s.Pool == nil {
+		return 0, errors.New("nil db")
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if _, err := tx.Exec(ctx, `SET LOCAL statement_timeout = 0`); err != nil {
+		return 0, err
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int64
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return n, err
+		}
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// DropSnapshotPartition drops one partition and the snapshots in it.
+func (s *Store) DropSnapshotPartition(ctx context.Context, name string) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	if name == snapshotsDefaultTable {
+		return errors.New("the default snapshot partition cannot be dropped")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	_, err := s.Pool.Exec(ctx, `DROP TABLE `+pgx.Identifier{name}.Sanitize())
+	return err
+}
+
+// TrimDefaultSnapshots deletes snapshots fetched before cutoff from the
+// default partition, which retention can't drop.
+func (s *Store) TrimDefaultSnapshots(ctx context.Context, cutoff time.Time) (int64, error) {
+	if s.Pool == nil {
+		return 0, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM `+snapshotsDefaultTable+` WHERE fetched_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
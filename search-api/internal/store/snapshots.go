@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RawSnapshot is one stored provider payload. ExternalID is the provider ID
+// of the card the payload was stored for.
+type RawSnapshot struct {
+	ID         string
+	Provider   string
+	Endpoint   string
+	ExternalID string
+	Payload    []byte
+	FetchedAt  time.Time
+}
+
+// SnapshotFilter narrows RawSnapshots. Empty fields match everything.
+type SnapshotFilter struct {
+	Provider   string
+	Endpoint   string
+	ExternalID string
+	Since      time.Time
+	Limit      int // default 100
+}
+
+// RawSnapshots returns the latest stored payload of each card matching f,
+// most recently fetched first.
+func (s *Store) RawSnapshots(ctx context.Context, f SnapshotFilter) ([]RawSnapshot, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT * FROM (
+			SELECT DISTINCT ON (provider, endpoint, external_id)
+			       id, provider, endpoint, external_id, payload, fetched_at
+			FROM ingest_provider_raw_snapshots
+			WHERE external_id IS NOT NULL
+			  AND ($1 = '' OR provider = $1)
+			  AND ($2 = '' OR endpoint = $2)
+			  AND ($3 = '' OR external_id = $3)
+			  AND fetched_at >= $4
+			ORDER BY provider, endpoint, external_id, fetched_at DESC
+		) s
+		ORDER BY s.fetched_at DESC
+		LIMIT $5
+	`, f.Provider, f.Endpoint, f.ExternalID, f.Since, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (RawSnapshot, error) {
+		var r RawSnapshot
+		err := row.Scan(&r.ID, &r.Provider, &r.Endpoint, &r.ExternalID, &r.Payload, &r.FetchedAt)
+		return r, err
+	})
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yourorg/search-api/internal/tracing"
+)
+
+// maxTracedSQL caps the statement text attached to a span.
+const maxTracedSQL = 2048
+
+// queryTracer opens a span around every query and batch the pool runs and
+// records it in store_queries.
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return startQuerySpan(startQueryMetrics(ctx, data.SQL), data.SQL)
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.response.returned_rows", data.CommandTag.RowsAffected()))
+	endQuerySpan(span, data.Err)
+	endQueryMetrics(ctx, data.CommandTag.RowsAffected(), data.Err)
+}
+
+func (queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx = startQueryMetrics(ctx, "BATCH of "+strconv.Itoa(data.Batch.Len()))
+	ctx, _ = tracing.Tracer().Start(ctx, "db BATCH", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.Int("db.operation.batch.size", data.Batch.Len()),
+		))
+	return ctx
+}
+
+func (queryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if data.Err != nil {
+		trace.SpanFromContext(ctx).AddEvent("query failed", trace.WithAttributes(
+			attribute.String("db.query.text", truncateSQL(data.SQL)),
+			attribute.String("error.message", data.Err.Error()),
+		))
+	}
+}
+
+func (queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endQuerySpan(trace.SpanFromContext(ctx), data.Err)
+	endQueryMetrics(ctx, 0, data.Err)
+}
+
+func startQuerySpan(ctx context.Context, sql string) context.Context {
+	ctx, _ = tracing.Tracer().Start(ctx, "db "+queryOperation(sql), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.query.text", truncateSQL(sql)),
+		))
+	return ctx
+}
+
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryOperation names a span after the statement's leading keyword, or the
+// prepared statement name when sql is one.
+func queryOperation(sql string) string {
+	f := strings.Fields(sql)
+	if len(f) == 0 {
+		return "QUERY"
+	}
+	if !strings.ContainsAny(sql, " \t\n") {
+		return f[0]
+	}
+	return strings.ToUpper(f[0])
+}
+
+func truncateSQL(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if len(sql) > maxTracedSQL {
+		return sql[:maxTracedSQL]
+	}
+	return sql
+}
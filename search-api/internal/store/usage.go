@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UsageCounts are one consumer's API usage in one bucket. The cache counts
+// split the requests that reported a cache result; Errors are 5xx answers.
+type UsageCounts struct {
+	Requests      int64
+	ProviderCalls int64
+	CacheHits     int64 // fresh or stale from the Redis cache
+	CacheMisses   int64 // served from the database
+	ProviderReads int64 // fetched from the provider
+	Errors        int64
+}
+
+// Add adds o's counts to c.
+func (c *UsageCounts) Add(o UsageCounts) {
+	c.Requests += o.Requests
+	c.ProviderCalls += o.ProviderCalls
+	c.CacheHits += o.CacheHits
+	c.CacheMisses += o.CacheMisses
+	c.ProviderReads += o.ProviderReads
+	c.Errors += o.Errors
+}
+
+// UsageRow is a consumer's usage in the bucket starting at Bucket.
+type UsageRow struct {
+	Consumer string
+	Bucket   time.Time
+	UsageCounts
+}
+
+// AddUsage adds rows to the hourly api_usage rollup; each Bucket is
+// truncated to its hour.
+func (s *Store) AddUsage(ctx context.Context, rows []UsageRow) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	var (
+		consumers                     []string
+		hours                         []time.Time
+		requests, calls, hits, misses []int64
+		reads, errs                   []int64
+	)
+	for _, r := range rows {
+		consumers = append(consumers, r.Consumer)
+		hours = append(hours, r.Bucket.UTC().Truncate(time.Hour))
+		requests = append(requests, r.Requests)
+		calls = append(calls, r.ProviderCalls)
+		hits = append(hits, r.CacheHits)
+		misses = append(misses, r.CacheMisses)
+		reads = append(reads, r.ProviderReads)
+		errs = append(errs, r.Errors)
+	}
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO api_usage (consumer, hour, requests, provider_calls, cache_hits, cache_misses, provider_reads, errors)
+		SELECT * FROM unnest($1::text[], $2::timestamptz[], $3::bigint[], $4::bigint[], $5::bigint[], $6::bigint[], $7::bigint[], $8::bigint[])
+		ON CONFLICT (consumer, hour) DO UPDATE SET
+			requests       = api_usage.requests + EXCLUDED.requests,
+			provider_calls = api_usage.provider_calls + EXCLUDED.provider_calls,
+			cache_hits     = api_usage.cache_hits + EXCLUDED.cache_hits,
+			cache_misses   = api_usage.cache_misses + EXCLUDED.cache_misses,
+			provider_reads = api_usage.provider_reads + EXCLUDED.provider_reads,
+			errors         = api_usage.errors + EXCLUDED.errors
+	`, consumers, hours, requests, calls, hits, misses, reads, errs)
+	return err
+}
+
+// UsageQuery selects api_usage rows in [From, To). Day sums the hours of
+// each UTC day; Consumer, when set, keeps one consumer.
+type UsageQuery struct {
+	From, To time.Time
+	Consumer string
+	Day      bool
+}
+
+// Usage returns the usage rollup by consumer and bucket, oldest first.
+func (s *Store) Usage(ctx context.Context, q UsageQuery) ([]UsageRow, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	unit := "hour"
+	if q.Day {
+		unit = "day"
+	}
+	rows, err := s.queryRead(ctx, `
+		SELECT consumer, date_trunc($1, hour AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket,
+		       sum(requests), sum(provider_calls), sum(cache_hits), sum(cache_misses), sum(provider_reads), sum(errors)
+		FROM api_usage
+		WHERE hour >= $2 AND hour < $3 AND ($4 = '' OR consumer = $4)
+		GROUP BY consumer, bucket
+		ORDER BY bucket, consumer
+	`, unit, q.From.UTC(), q.To.UTC(), q.Consumer)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (UsageRow, error) {
+		var u UsageRow
+		err := row.Scan(&u.Consumer, &u.Bucket, &u.Requests, &u.ProviderCalls, &u.CacheHits, &u.CacheMisses, &u.ProviderReads, &u.Errors)
+		return u, err
+	})
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Valuation is a persisted price estimate for a property.
+type Valuation struct {
+	PropertyKey string
+	Provider    string
+	Value       float64
+	Low         sql.NullFloat64
+	High        sql.NullFloat64
+	Confidence  sql.NullFloat64
+	CompsCount  sql.NullInt64
+	ValuedAt    time.Time
+}
+
+// Comparable is a listing near a subject property used by comps-based models.
+type Comparable struct {
+	PropertyKey string
+	Price       float64
+	Sqft        int
+	Beds        int
+	Sold        bool // sold in the last year
+}
+
+// SaveValuation appends a valuation for the property identified by PropertyKey.
+func (s *Store) SaveValuation(ctx context.Context, v Valuation) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	tag, err := s.Pool.Exec(ctx, `
+		INSERT INTO property_valuations (property_id, provider, value, value_low, value_high, confidence, comps_count, valued_at)
+		SELECT id, $2, $3, $4, $5, $6, $7, $8 FROM ingest_properties WHERE property_key = $1
+	`, v.PropertyKey, v.Provider, v.Value, v.Low, v.High, v.Confidence, v.CompsCount, v.ValuedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("valuation: unknown property " + v.PropertyKey)
+	}
+	return nil
+}
+
+// LatestValuation returns the newest valuation for a property from the given
+// provider that is no older than maxAge, or nil.
+func (s *Store) LatestValuation(ctx context.Context, propertyKey, provider string, maxAge time.Duration) (*Valuation, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	v := Valuation{PropertyKey: propertyKey}
+	err := s.queryRowRead(ctx, `
+		SELECT v.provider, v.value, v.value_low, v.value_high, v.confidence, v.comps_count, v.valued_at
+		FROM property_valuations v
+		JOIN ingest_properties p ON p.id = v.property_id
+		WHERE p.property_key = $1 AND p.deleted_at IS NULL AND v.provider = $2 AND v.valued_at >= $3
+		ORDER BY v.valued_at DESC
+		LIMIT 1
+	`, []any{propertyKey, provider, time.Now().Add(-maxAge)},
+		&v.Provider, &v.Value, &v.Low, &v.High, &v.Confidence, &v.CompsCount, &v.ValuedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// FetchComparables returns priced listings in the subject's ZIP with similar
+// bedroom counts, listings sold in the last year first and then nearest
+// (when coordinates are known) and most recently updated. Listings are
+// stored as sold when the provider reports them so, which the bulk crawl
+// checks for listings that leave the for-sale search.
+func (s *Store) FetchComparables(ctx context.Context, propertyKey, zip string, beds int, lat, lon sql.NullFloat64, limit int) ([]Comparable, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	if limit <= 0 {
+		limit = 25
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT p.property_key, l.list_price::float8, COALESCE(l.sqft, 0), COALESCE(l.beds, 0),
+		       l.status = 'sold' AND l.status_changed_at >= now() - interval '1 year'
+		FROM ingest_listings l
+		JOIN ingest_properties p ON p.id = l.property_id
+		WHERE p.zip = $1 AND p.property_key <> $2 AND l.list_price > 0
+		  AND l.deleted_at IS NULL AND p.deleted_at IS NULL
+		  AND ($3 = 0 OR l.beds BETWEEN $3 - 1 AND $3 + 1)
+		ORDER BY 5 DESC,
+		         earth_distance(ll_to_earth(l.coords[1], l.coords[0]), ll_to_earth($4, $5)) NULLS LAST,
+		         l.updated_at DESC
+		LIMIT $6
+	`, zip, propertyKey, beds, lat, lon, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Comparable, error) {
+		var c Comparable
+		err := row.Scan(&c.PropertyKey, &c.Price, &c.Sqft, &c.Beds, &c.Sold)
+		return c, err
+	})
+}
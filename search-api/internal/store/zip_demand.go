@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RecordZipDemand adds API lookup counts per ZIP to the given hour's bucket.
+func (s *Store) RecordZipDemand(ctx context.Context, hour time.Time, counts map[string]int) error {
+	if s.Pool == nil {
+		return errors.New("nil db")
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	zips := make([]string, 0, len(counts))
+	hits := make([]int32, 0, len(counts))
+	for z, n := range counts {
+		zips = append(zips, z)
+		hits = append(hits, int32(n))
+	}
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO zip_demand (zip, hour, requests)
+		SELECT t.zip, $1, t.n FROM unnest($2::text[], $3::int[]) AS t(zip, n)
+		ON CONFLICT (zip, hour) DO UPDATE SET requests = zip_demand.requests + EXCLUDED.requests
+	`, hour.UTC().Truncate(time.Hour), zips, hits)
+	return err
+}
+
+// ZipActivity is what the hydrator scheduler knows about a ZIP.
+type ZipActivity struct {
+	Zip         string
+	LastFetchAt sql.NullTime // newest listing fetch in the ZIP
+	Demand      int          // API lookups since the requested time
+}
+
+// FetchZipActivity returns freshness and demand for each of zips. ZIPs
+// without listings have an invalid LastFetchAt.
+func (s *Store) FetchZipActivity(ctx context.Context, zips []string, since time.Time) (map[string]ZipActivity, error) {
+	if s.Pool == nil {
+		return nil, errors.New("nil db")
+	}
+	ctx, cancel := s.queryCtx(ctx)
+	defer cancel()
+	rows, err := s.queryRead(ctx, `
+		SELECT z.zip,
+		       (SELECT max(l.last_fetch_at)
+		          FROM ingest_properties p
+		          JOIN ingest_listings l ON l.property_id = p.id
+		         WHERE p.zip = z.zip),
+		       COALESCE((SELECT sum(d.requests) FROM zip_demand d WHERE d.zip = z.zip AND d.hour >= $2), 0)
+		FROM unnest($1::text[]) AS z(zip)
+	`, zips, since.UTC().Truncate(time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	acts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ZipActivity, error) {
+		var a ZipActivity
+		err := row.Scan(&a.Zip, &a.LastFetchAt, &a.Demand)
+		return a, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]ZipActivity, len(acts))
+	for _, a := range acts {
+		out[a.Zip] = a
+	}
+	return out, nil
+}
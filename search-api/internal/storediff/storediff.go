@@ -0,0 +1,230 @@
+// Package storediff compares the listings the store serves for a ZIP with
+// the ones the provider lists for it now, to audit hydration: listings the
+// store is missing, listings it still serves as for sale after the provider
+// dropped them, and listings whose status, price or facts have drifted.
+// The admin route GET /v1/admin/diff/zips/{zip} and `propctl diff` both run
+// it.
+package storediff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/canon"
+	"github.com/yourorg/search-api/internal/proptype"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+const (
+	defaultPageSize = 50
+	defaultMaxPages = 5
+	// statusForSale is the status hydration gives the listings the
+	// provider's for-sale search returns.
+	statusForSale = "for_sale"
+)
+
+// ErrStore wraps failures reading the store; other errors are the
+// provider's.
+var ErrStore = errors.New("store read failed")
+
+// Differ compares a ZIP's store listings with the provider's for-sale
+// search, reading at most MaxPages pages of PageSize, default 5 of 50.
+type Differ struct {
+	Store    *store.Store
+	Client   *attom.Client
+	PageSize int
+	MaxPages int
+}
+
+// Listing is a listing one side has and the other doesn't. Status and
+// UpdatedAt are the store's.
+type Listing struct {
+	PropertyKey string     `json:"propertyKey"`
+	ListingID   string     `json:"listingId,omitempty"`
+	Address     string     `json:"address"`
+	Price       int        `json:"price"`
+	Status      string     `json:"status,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty" doc:"When the store last updated the listing"`
+}
+
+// Mismatch is a field the store and provider disagree on for a listing
+// both have.
+type Mismatch struct {
+	PropertyKey string    `json:"propertyKey"`
+	ListingID   string    `json:"listingId,omitempty"`
+	Field       string    `json:"field" doc:"status, price, beds, baths, sqft or type"`
+	Store       string    `json:"store"`
+	Provider    string    `json:"provider"`
+	UpdatedAt   time.Time `json:"updatedAt" doc:"When the store last updated the listing"`
+}
+
+// Report is one ZIP's diff.
+type Report struct {
+	Zip       string    `json:"zip"`
+	CheckedAt time.Time `json:"checkedAt"`
+	// Complete is false when the provider had more pages than were read;
+	// Unlisted is then left empty, since the unread pages may hold them.
+	Complete      bool `json:"complete" doc:"Whether every provider page was read; unlisted is only reported when it was"`
+	ProviderPages int  `json:"providerPages"`
+	ProviderCount int  `json:"providerCount"`
+	StoreCount    int  `json:"storeCount" doc:"Live store listings in the ZIP, any status"`
+	Matched       int  `json:"matched"`
+	// Missing are listed by the provider but not in the store; Unlisted
+	// are for sale in the store but no longer listed by the provider.
+	Missing    []Listing  `json:"missing"`
+	Unlisted   []Listing  `json:"unlisted"`
+	Mismatches []Mismatch `json:"mismatches"`
+	// OldestUpdate is the least recently updated store listing the
+	// provider still lists.
+	OldestUpdate *time.Time `json:"oldestUpdate,omitempty"`
+}
+
+// Agrees reports whether the store and provider agree on every listing.
+func (r Report) Agrees() bool {
+	return len(r.Missing) == 0 && len(r.Unlisted) == 0 && len(r.Mismatches) == 0
+}
+
+// Diff reads the ZIP from both sides and compares them. Provider errors
+// are returned as is, so callers can tell quota and upstream failures
+// apart.
+func (d *Differ) Diff(ctx context.Context, zip string) (Report, error) {
+	if d.Store == nil || d.Client == nil {
+		return Report{}, errors.New("storediff: store and provider client are required")
+	}
+	r := Report{Zip: zip, CheckedAt: time.Now().UTC(), Missing: []Listing{}, Unlisted: []Listing{}, Mismatches: []Mismatch{}}
+	cards, complete, pages, err := d.providerListings(ctx, zip)
+	if err != nil {
+		return r, err
+	}
+	r.Complete, r.ProviderPages, r.ProviderCount = complete, pages, len(cards)
+
+	stored := map[string]store.ExportRecord{}
+	err = d.Store.ExportListings(ctx, store.ExportQuery{Zip: zip}, func(rec store.ExportRecord) error {
+		// A property relisted keeps its older listings; compare the newest.
+		if prev, ok := stored[rec.PropertyKey]; !ok || rec.UpdatedAt.After(prev.UpdatedAt) {
+			stored[rec.PropertyKey] = rec
+		}
+		return nil
+	})
+	if err != nil {
+		return r, fmt.Errorf("%w: %v", ErrStore, err)
+	}
+	r.StoreCount = len(stored)
+
+	listed := make(map[string]bool, len(cards))
+	for _, c := range cards {
+		key := cardKey(c)
+		if listed[key] {
+			continue
+		}
+		listed[key] = true
+		rec, ok := stored[key]
+		if !ok {
+			r.Missing = append(r.Missing, Listing{PropertyKey: key, ListingID: c.ListingID, Address: c.Address, Price: c.Price})
+			continue
+		}
+		r.Matched++
+		if r.OldestUpdate == nil || rec.UpdatedAt.Before(*r.OldestUpdate) {
+			at := rec.UpdatedAt
+			r.OldestUpdate = &at
+		}
+		r.Mismatches = append(r.Mismatches, compare(rec, c)...)
+	}
+	if r.Complete {
+		for key, rec := range stored {
+			if listed[key] || rec.Status != statusForSale {
+				continue
+			}
+			at := rec.UpdatedAt
+			r.Unlisted = append(r.Unlisted, Listing{
+				PropertyKey: key, ListingID: rec.ListingExternalID.String, Address: rec.AddressLine1,
+				Price: recordPrice(rec.ListingRecord), Status: rec.Status, UpdatedAt: &at,
+			})
+		}
+		sort.Slice(r.Unlisted, func(i, j int) bool { return r.Unlisted[i].PropertyKey < r.Unlisted[j].PropertyKey })
+	}
+	return r, nil
+}
+
+// providerListings pages through the provider's for-sale search for zip.
+func (d *Differ) providerListings(ctx context.Context, zip string) (cards []attom.PropertyCard, complete bool, pages int, err error) {
+	size, max := d.PageSize, d.MaxPages
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	if max <= 0 {
+		max = defaultMaxPages
+	}
+	for page := 1; page <= max; page++ {
+		raw, err := d.Client.SearchListingsByPostal(ctx, zip, size, page, 0, 0, 0, 0, "", "")
+		if err != nil {
+			return nil, false, pages, err
+		}
+		pages++
+		got, err := attom.MapListingPayloadToCards(raw)
+		if err != nil {
+			return nil, false, pages, fmt.Errorf("map provider page %d: %w", page, err)
+		}
+		cards = append(cards, got...)
+		if len(got) < size {
+			return cards, true, pages, nil
+		}
+	}
+	return cards, false, pages, nil
+}
+
+// cardKey is the card's property key as the store computes it.
+func cardKey(c attom.PropertyCard) string {
+	if _, _, _, _, pk := canon.Canonicalize(c.Address, c.City, c.State, c.Zip); pk != "" {
+		return pk
+	}
+	return c.PropertyID
+}
+
+func recordPrice(rec store.ListingRecord) int {
+	if !rec.ListPrice.Valid {
+		return 0
+	}
+	return int(math.Round(rec.ListPrice.Float64))
+}
+
+func compare(rec store.ExportRecord, c attom.PropertyCard) []Mismatch {
+	var out []Mismatch
+	add := func(field, st, pv string) {
+		out = append(out, Mismatch{
+			PropertyKey: rec.PropertyKey, ListingID: rec.ListingExternalID.String,
+			Field: field, Store: st, Provider: pv, UpdatedAt: rec.UpdatedAt,
+		})
+	}
+	if rec.Status != statusForSale {
+		add("status", rec.Status, statusForSale)
+	}
+	ints := []struct {
+		field  string
+		st     int
+		set    bool
+		listed int
+	}{
+		{"price", recordPrice(rec.ListingRecord), rec.ListPrice.Valid, c.Price},
+		{"beds", int(rec.Beds.Int64), rec.Beds.Valid, c.Beds},
+		{"baths", int(math.Round(rec.Baths.Float64)), rec.Baths.Valid, c.Baths},
+		{"sqft", int(rec.Sqft.Int64), rec.Sqft.Valid, c.Sqft},
+	}
+	for _, f := range ints {
+		// The provider sends 0 for facts it doesn't have; so does a
+		// card built from a row without them.
+		if (f.set || f.listed != 0) && f.st != f.listed {
+			add(f.field, strconv.Itoa(f.st), strconv.Itoa(f.listed))
+		}
+	}
+	if pt := proptype.ForStorage(c.Type); pt != "" && pt != rec.PropertyType.String {
+		add("type", rec.PropertyType.String, c.Type)
+	}
+	return out
+}
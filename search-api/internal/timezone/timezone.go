@@ -0,0 +1,91 @@
+// Package timezone derives a property's local time zone from its state and
+// ZIP. Most states lie in one zone; for those that are split, the ZIP's
+// three-digit prefix picks the zone where the line falls between prefixes.
+// Counties split within a prefix get the state's main zone.
+package timezone
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	// Zone data is embedded so lookups don't depend on the host's zoneinfo.
+	_ "time/tzdata"
+)
+
+var states = map[string]string{
+	"AL": "America/Chicago", "AK": "America/Anchorage", "AZ": "America/Phoenix", "AR": "America/Chicago",
+	"CA": "America/Los_Angeles", "CO": "America/Denver", "CT": "America/New_York", "DE": "America/New_York",
+	"DC": "America/New_York", "FL": "America/New_York", "GA": "America/New_York", "HI": "Pacific/Honolulu",
+	"ID": "America/Boise", "IL": "America/Chicago", "IN": "America/Indiana/Indianapolis", "IA": "America/Chicago",
+	"KS": "America/Chicago", "KY": "America/New_York", "LA": "America/Chicago", "ME": "America/New_York",
+	"MD": "America/New_York", "MA": "America/New_York", "MI": "America/Detroit", "MN": "America/Chicago",
+	"MS": "America/Chicago", "MO": "America/Chicago", "MT": "America/Denver", "NE": "America/Chicago",
+	"NV": "America/Los_Angeles", "NH": "America/New_York", "NJ": "America/New_York", "NM": "America/Denver",
+	"NY": "America/New_York", "NC": "America/New_York", "ND": "America/Chicago", "OH": "America/New_York",
+	"OK": "America/Chicago", "OR": "America/Los_Angeles", "PA": "America/New_York", "RI": "America/New_York",
+	"SC": "America/New_York", "SD": "America/Chicago", "TN": "America/Chicago", "TX": "America/Chicago",
+	"UT": "America/Denver", "VT": "America/New_York", "VA": "America/New_York", "WA": "America/Los_Angeles",
+	"WV": "America/New_York", "WI": "America/Chicago", "WY": "America/Denver",
+	"PR": "America/Puerto_Rico", "VI": "America/St_Thomas", "GU": "Pacific/Guam", "AS": "Pacific/Pago_Pago",
+	"MP": "Pacific/Saipan",
+}
+
+// prefixes overrides the state's zone for ZIP prefixes on the other side
+// of a zone line.
+var prefixes = map[string]string{
+	// Florida panhandle west of the Apalachicola.
+	"324": "America/Chicago", "325": "America/Chicago",
+	// El Paso.
+	"798": "America/Denver", "799": "America/Denver", "885": "America/Denver",
+	// East Tennessee.
+	"373": "America/New_York", "374": "America/New_York", "376": "America/New_York",
+	"377": "America/New_York", "378": "America/New_York", "379": "America/New_York",
+	// Western Kentucky.
+	"420": "America/Chicago", "421": "America/Chicago", "422": "America/Chicago",
+	"423": "America/Chicago", "424": "America/Chicago",
+	// Northwest and southwest Indiana.
+	"463": "America/Chicago", "464": "America/Chicago", "476": "America/Chicago", "477": "America/Chicago",
+	// Southwest North Dakota, western South Dakota and the Nebraska panhandle.
+	"586": "America/Denver", "577": "America/Denver", "693": "America/Denver",
+	// North Idaho.
+	"835": "America/Los_Angeles", "838": "America/Los_Angeles",
+	// Malheur County, Oregon.
+	"979": "America/Boise",
+}
+
+var (
+	mu    sync.Mutex
+	zones = map[string]*time.Location{}
+)
+
+// Name returns the IANA name of the zone an address in state and zip lies
+// in, or "" for an unknown state.
+func Name(state, zip string) string {
+	if zip = strings.TrimSpace(zip); len(zip) >= 3 {
+		if name, ok := prefixes[zip[:3]]; ok {
+			return name
+		}
+	}
+	return states[strings.ToUpper(strings.TrimSpace(state))]
+}
+
+// For returns the zone an address in state and zip lies in, or nil for an
+// unknown state.
+func For(state, zip string) *time.Location {
+	name := Name(state, zip)
+	if name == "" {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if loc, ok := zones[name]; ok {
+		return loc
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc = nil
+	}
+	zones[name] = loc
+	return loc
+}
@@ -0,0 +1,66 @@
+// Package tracing configures OpenTelemetry for the API and the hydrator.
+//
+// OTEL_TRACES_EXPORTER picks the exporter: "otlp" sends spans over OTLP/HTTP
+// to OTEL_EXPORTER_OTLP_ENDPOINT, "stdout" prints them, and "none" (the
+// default) keeps tracing off. Sampling follows the standard
+// OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG variables and the service
+// name follows OTEL_SERVICE_NAME. W3C traceparent and baggage headers are
+// propagated either way.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/yourorg/search-api"
+
+// Setup installs the global tracer provider and propagator. The returned
+// shutdown flushes buffered spans and must be called before exit.
+func Setup(ctx context.Context, service string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	noop := func(context.Context) error { return nil }
+
+	var exp sdktrace.SpanExporter
+	switch kind := os.Getenv("OTEL_TRACES_EXPORTER"); kind {
+	case "", "none":
+		return noop, nil
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return noop, fmt.Errorf("tracing: unknown OTEL_TRACES_EXPORTER %q", kind)
+	}
+	if err != nil {
+		return noop, err
+	}
+
+	// Detectors apply in order, so OTEL_SERVICE_NAME wins over service.
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(service)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return noop, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the tracer all of this module's spans are started from.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
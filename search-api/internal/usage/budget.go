@@ -0,0 +1,165 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/redisx"
+)
+
+// Budget caps a consumer's provider-triggering requests per UTC day and
+// calendar month; zero leaves that period uncapped. A request counts once
+// however many provider calls it makes.
+type Budget struct {
+	Daily   int
+	Monthly int
+}
+
+// Budgets enforces per-consumer budgets. Requests over budget are refused
+// only when they would call the provider, so cached answers keep flowing.
+type Budgets struct {
+	// Consumers maps consumer IDs (key:name, jwt:subject or anonymous) to
+	// their budget; others get Default.
+	Consumers map[string]Budget
+	Default   Budget
+	// Redis shares the counters across replicas; nil counts per process,
+	// as does a Redis outage.
+	Redis *redisx.Client
+
+	mu    sync.Mutex
+	local map[string]int
+}
+
+func (b *Budgets) budgetFor(consumer string) Budget {
+	if bud, ok := b.Consumers[consumer]; ok {
+		return bud
+	}
+	return b.Default
+}
+
+// Enabled reports whether any consumer has a budget.
+func (b *Budgets) Enabled() bool {
+	if b == nil {
+		return false
+	}
+	if b.Default != (Budget{}) {
+		return true
+	}
+	for _, bud := range b.Consumers {
+		if bud != (Budget{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware charges the caller's budget on the request's first provider
+// call, refusing it with 429 once the budget is spent.
+func (b *Budgets) Middleware(next http.Handler) http.Handler {
+	if !b.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consumer := Anonymous
+		if p, ok := auth.FromContext(r.Context()); ok {
+			consumer = p.ID()
+		}
+		bud := b.budgetFor(consumer)
+		if bud == (Budget{}) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var once sync.Once
+		var refused error
+		ctx := attom.WithAdmission(r.Context(), func(ctx context.Context) error {
+			once.Do(func() { refused = b.charge(ctx, consumer, bud, time.Now().UTC()) })
+			return refused
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// charge counts one request against consumer's budget, or returns the
+// error refusing it when a period's budget is spent.
+func (b *Budgets) charge(ctx context.Context, consumer string, bud Budget, now time.Time) error {
+	dayReset := now.Truncate(24*time.Hour).AddDate(0, 0, 1)
+	monthReset := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	dayKey := "budget:" + consumer + ":d:" + now.Format("20060102")
+	monthKey := "budget:" + consumer + ":m:" + now.Format("200601")
+
+	day, month, err := b.incr(ctx, dayKey, monthKey, dayReset, monthReset, 1)
+	if err != nil {
+		log.Printf("[WARN] consumer budget: redis unavailable, counting in process: %v", err)
+		day, month = b.incrLocal(dayKey, monthKey, 1)
+	}
+	var period string
+	var limit int
+	var reset time.Time
+	switch {
+	case bud.Daily > 0 && day > bud.Daily:
+		period, limit, reset = "daily", bud.Daily, dayReset
+	case bud.Monthly > 0 && month > bud.Monthly:
+		period, limit, reset = "monthly", bud.Monthly, monthReset
+	default:
+		return nil
+	}
+	// Refused requests don't spend the budget.
+	if err == nil {
+		_, _, _ = b.incr(ctx, dayKey, monthKey, dayReset, monthReset, -1)
+	} else {
+		b.incrLocal(dayKey, monthKey, -1)
+	}
+	return apierror.New(http.StatusTooManyRequests, "consumer_budget_exceeded",
+		fmt.Sprintf("%s provider request budget of %d spent; cached results are still served", period, limit)).
+		With("budget", period).With("limit", limit).With("reset_at", reset.Format(time.RFC3339)).
+		WithRetryAfter(reset.Sub(now))
+}
+
+func (b *Budgets) incr(ctx context.Context, dayKey, monthKey string, dayReset, monthReset time.Time, by int64) (day, month int, err error) {
+	if b.Redis == nil {
+		day, month = b.incrLocal(dayKey, monthKey, int(by))
+		return day, month, nil
+	}
+	var d, m *redis.IntCmd
+	err = b.Redis.Pipeline(ctx, func(p redis.Pipeliner) error {
+		d = p.IncrBy(ctx, dayKey, by)
+		p.ExpireAt(ctx, dayKey, dayReset.Add(time.Hour))
+		m = p.IncrBy(ctx, monthKey, by)
+		p.ExpireAt(ctx, monthKey, monthReset.Add(time.Hour))
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(d.Val()), int(m.Val()), nil
+}
+
+// incrLocal counts in process, dropping earlier periods' counters when
+// the day turns.
+func (b *Budgets) incrLocal(dayKey, monthKey string, by int) (day, month int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.local[dayKey]; !ok {
+		today, thisMonth := dayKey[strings.LastIndex(dayKey, ":"):], monthKey[strings.LastIndex(monthKey, ":"):]
+		for k := range b.local {
+			if !strings.HasSuffix(k, today) && !strings.HasSuffix(k, thisMonth) {
+				delete(b.local, k)
+			}
+		}
+	}
+	if b.local == nil {
+		b.local = map[string]int{}
+	}
+	b.local[dayKey] += by
+	b.local[monthKey] += by
+	return b.local[dayKey], b.local[monthKey]
+}
@@ -0,0 +1,127 @@
+// Package usage accounts API requests to the consumers that made them: how
+// many requests each API key or token subject sent, how many provider calls
+// they caused and how often the cache answered, rolled up hourly in
+// Postgres so provider cost can be attributed.
+package usage
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourorg/search-api/internal/logger"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// Anonymous is the consumer of requests made without credentials.
+const Anonymous = "anonymous"
+
+type bucket struct {
+	consumer string
+	hour     time.Time
+}
+
+// Recorder counts requests in memory and flushes them to the api_usage
+// rollup. Its Record method is an access log OnRequest hook.
+type Recorder struct {
+	Store *store.Store
+
+	mu     sync.Mutex
+	counts map[bucket]*store.UsageCounts
+}
+
+// Record counts one finished request.
+func (r *Recorder) Record(e logger.Entry) {
+	if r == nil {
+		return
+	}
+	consumer := e.Consumer
+	if consumer == "" {
+		consumer = Anonymous
+	}
+	c := store.UsageCounts{Requests: 1, ProviderCalls: e.ProviderCalls}
+	switch e.Cache {
+	case logger.CacheHit, logger.CacheStale:
+		c.CacheHits = 1
+	case logger.CacheMiss:
+		c.CacheMisses = 1
+	case logger.CacheProvider:
+		c.ProviderReads = 1
+	}
+	if e.Status >= 500 {
+		c.Errors = 1
+	}
+	k := bucket{consumer: consumer, hour: time.Now().UTC().Truncate(time.Hour)}
+	r.mu.Lock()
+	if r.counts == nil {
+		r.counts = make(map[bucket]*store.UsageCounts)
+	}
+	if r.counts[k] == nil {
+		r.counts[k] = &store.UsageCounts{}
+	}
+	r.counts[k].Add(c)
+	r.mu.Unlock()
+}
+
+// Flush writes pending counts. Counts that fail to write are kept for the
+// next flush, so a database outage delays usage rather than losing it.
+func (r *Recorder) Flush(ctx context.Context) {
+	if r == nil || r.Store == nil {
+		return
+	}
+	r.mu.Lock()
+	counts := r.counts
+	r.counts = nil
+	r.mu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+	rows := make([]store.UsageRow, 0, len(counts))
+	for k, c := range counts {
+		rows = append(rows, store.UsageRow{Consumer: k.consumer, Bucket: k.hour, UsageCounts: *c})
+	}
+	if err := r.Store.AddUsage(ctx, rows); err != nil {
+		log.Printf("[WARN] usage flush (%d bucket(s)) failed, will retry: %v", len(rows), err)
+		r.mu.Lock()
+		if r.counts == nil {
+			r.counts = make(map[bucket]*store.UsageCounts)
+		}
+		for k, c := range counts {
+			if r.counts[k] == nil {
+				r.counts[k] = &store.UsageCounts{}
+			}
+			r.counts[k].Add(*c)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// Run flushes every interval until ctx is done.
+func (r *Recorder) Run(ctx context.Context, every time.Duration) {
+	if every <= 0 {
+		every = time.Minute
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			r.Flush(fctx)
+			cancel()
+		}
+	}
+}
+
+// HitRatio is the share of requests with a cache result that the cache
+// answered, or 0 when none reported one.
+func HitRatio(c store.UsageCounts) float64 {
+	total := c.CacheHits + c.CacheMisses + c.ProviderReads
+	if total == 0 {
+		return 0
+	}
+	return float64(c.CacheHits) / float64(total)
+}
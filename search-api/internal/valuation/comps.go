@@ -0,0 +1,90 @@
+package valuation
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yourorg/search-api/internal/store"
+)
+
+const minComps = 3
+
+// CompsValuer estimates value from comparable listings in our own data:
+// the median price per square foot of nearby similar homes, scaled to the
+// subject's size. Without a subject size it falls back to median price.
+type CompsValuer struct {
+	Store *store.Store
+	Limit int
+}
+
+func (c *CompsValuer) Name() string { return "comps.v1" }
+
+func (c *CompsValuer) Estimate(ctx context.Context, subject Subject) (Estimate, error) {
+	if c.Store == nil || subject.Zip == "" {
+		return Estimate{}, ErrInsufficientData
+	}
+	comps, err := c.Store.FetchComparables(ctx, subject.PropertyKey, subject.Zip, subject.Beds,
+		nullCoord(subject.Lat), nullCoord(subject.Lon), c.Limit)
+	if err != nil {
+		return Estimate{}, err
+	}
+	var samples []float64
+	for _, comp := range comps {
+		if subject.Sqft > 0 {
+			if comp.Sqft > 0 {
+				samples = append(samples, comp.Price/float64(comp.Sqft))
+			}
+			continue
+		}
+		samples = append(samples, comp.Price)
+	}
+	if len(samples) < minComps {
+		return Estimate{}, ErrInsufficientData
+	}
+	sort.Float64s(samples)
+	scale := 1.0
+	if subject.Sqft > 0 {
+		scale = float64(subject.Sqft)
+	}
+	median := quantile(samples, 0.5)
+	p25, p75 := quantile(samples, 0.25), quantile(samples, 0.75)
+
+	// Confidence grows with sample size and shrinks with spread.
+	spread := 1.0
+	if median > 0 {
+		spread = math.Min(1, (p75-p25)/median)
+	}
+	confidence := math.Min(1, float64(len(samples))/10) * (1 - spread)
+
+	return Estimate{
+		Provider:   c.Name(),
+		Value:      math.Round(median * scale),
+		Low:        math.Round(p25 * scale),
+		High:       math.Round(p75 * scale),
+		Confidence: math.Round(confidence*100) / 100,
+		CompsCount: len(samples),
+		ValuedAt:   time.Now(),
+	}, nil
+}
+
+// quantile interpolates linearly over sorted values.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	if lo == hi {
+		return sorted[lo]
+	}
+	frac := pos - float64(lo)
+	return sorted[lo]*(1-frac) + sorted[hi]*frac
+}
+
+func nullCoord(v float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: v, Valid: v != 0}
+}
@@ -0,0 +1,59 @@
+package valuation
+
+import (
+	"math"
+	"sort"
+
+	"github.com/yourorg/search-api/attom"
+)
+
+// RentEstimate is a monthly market rent with its interquartile range.
+type RentEstimate struct {
+	Rent       float64
+	Low        float64
+	High       float64
+	Confidence float64
+	CompsCount int
+}
+
+// EstimateRent estimates a subject's monthly rent from for-rent comparables
+// the way CompsValuer estimates value: the median rent per square foot of
+// comparables within a bedroom of the subject, scaled to its size, or the
+// median rent when the subject's size is unknown.
+func EstimateRent(subject Subject, comps []attom.RentalListing) (RentEstimate, error) {
+	var samples []float64
+	for _, c := range comps {
+		if subject.Beds > 0 && c.Beds > 0 && (c.Beds < subject.Beds-1 || c.Beds > subject.Beds+1) {
+			continue
+		}
+		if subject.Sqft > 0 {
+			if c.Sqft > 0 {
+				samples = append(samples, float64(c.Rent)/float64(c.Sqft))
+			}
+			continue
+		}
+		samples = append(samples, float64(c.Rent))
+	}
+	if len(samples) < minComps {
+		return RentEstimate{}, ErrInsufficientData
+	}
+	sort.Float64s(samples)
+	scale := 1.0
+	if subject.Sqft > 0 {
+		scale = float64(subject.Sqft)
+	}
+	median := quantile(samples, 0.5)
+	p25, p75 := quantile(samples, 0.25), quantile(samples, 0.75)
+	spread := 1.0
+	if median > 0 {
+		spread = math.Min(1, (p75-p25)/median)
+	}
+	confidence := math.Min(1, float64(len(samples))/10) * (1 - spread)
+	return RentEstimate{
+		Rent:       math.Round(median * scale),
+		Low:        math.Round(p25 * scale),
+		High:       math.Round(p75 * scale),
+		Confidence: math.Round(confidence*100) / 100,
+		CompsCount: len(samples),
+	}, nil
+}
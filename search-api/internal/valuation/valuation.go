@@ -0,0 +1,154 @@
+package valuation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/store"
+)
+
+// ErrInsufficientData is returned when a model cannot produce an estimate.
+var ErrInsufficientData = errors.New("valuation: insufficient data")
+
+// Subject describes the property being valued.
+type Subject struct {
+	PropertyKey string
+	Zip         string
+	Beds        int
+	Baths       int
+	Sqft        int
+	Lat         float64
+	Lon         float64
+}
+
+// Estimate is a point estimate with an optional range and 0..1 confidence.
+type Estimate struct {
+	Provider   string
+	Value      float64
+	Low        float64
+	High       float64
+	Confidence float64
+	CompsCount int
+	ValuedAt   time.Time
+}
+
+// Valuer is implemented by each price-estimate source.
+type Valuer interface {
+	Name() string
+	Estimate(ctx context.Context, subject Subject) (Estimate, error)
+}
+
+// Service fronts a Valuer with the property_valuations table so repeated
+// detail reads within MaxAge reuse the stored estimate.
+type Service struct {
+	Valuer Valuer
+	Store  *store.Store
+	MaxAge time.Duration
+}
+
+// Get returns a cached estimate if fresh, otherwise computes and persists one.
+// A nil estimate with nil error means the model had too little data.
+func (s *Service) Get(ctx context.Context, subject Subject) (*Estimate, error) {
+	if s == nil || s.Valuer == nil || subject.PropertyKey == "" {
+		return nil, nil
+	}
+	maxAge := s.MaxAge
+	if maxAge <= 0 {
+		maxAge = 24 * time.Hour
+	}
+	if s.Store != nil {
+		v, err := s.Store.LatestValuation(ctx, subject.PropertyKey, s.Valuer.Name(), maxAge)
+		if err != nil {
+			log.Printf("[WARN] valuation lookup failed for %s: %v", subject.PropertyKey, err)
+		} else if v != nil {
+			return fromRecord(v), nil
+		}
+	}
+	est, err := s.Valuer.Estimate(ctx, subject)
+	if errors.Is(err, ErrInsufficientData) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if est.ValuedAt.IsZero() {
+		est.ValuedAt = time.Now()
+	}
+	if s.Store != nil {
+		if err := s.Store.SaveValuation(ctx, toRecord(subject.PropertyKey, est)); err != nil {
+			log.Printf("[WARN] unable to persist valuation for %s: %v", subject.PropertyKey, err)
+		}
+	}
+	return &est, nil
+}
+
+// SubjectFromCard builds a valuation subject from a property card.
+func SubjectFromCard(propertyKey string, card attom.PropertyCard) Subject {
+	return Subject{
+		PropertyKey: propertyKey,
+		Zip:         card.Zip,
+		Beds:        card.Beds,
+		Baths:       card.Baths,
+		Sqft:        card.Sqft,
+		Lat:         card.Coords[1],
+		Lon:         card.Coords[0],
+	}
+}
+
+// SubjectFromRecord builds a valuation subject from a stored listing.
+func SubjectFromRecord(rec store.ListingRecord) Subject {
+	return Subject{
+		PropertyKey: rec.PropertyKey,
+		Zip:         rec.Zip,
+		Beds:        int(rec.Beds.Int64),
+		Baths:       int(rec.Baths.Float64),
+		Sqft:        int(rec.Sqft.Int64),
+		Lat:         rec.Lat.Float64,
+		Lon:         rec.Lon.Float64,
+	}
+}
+
+// CardValue converts an estimate to the API representation.
+func (e *Estimate) CardValue() *attom.EstimatedValue {
+	if e == nil {
+		return nil
+	}
+	return &attom.EstimatedValue{
+		Value:      int(math.Round(e.Value)),
+		Low:        int(math.Round(e.Low)),
+		High:       int(math.Round(e.High)),
+		Confidence: e.Confidence,
+		Provider:   e.Provider,
+		ValuedAt:   e.ValuedAt,
+	}
+}
+
+func fromRecord(v *store.Valuation) *Estimate {
+	return &Estimate{
+		Provider:   v.Provider,
+		Value:      v.Value,
+		Low:        v.Low.Float64,
+		High:       v.High.Float64,
+		Confidence: v.Confidence.Float64,
+		CompsCount: int(v.CompsCount.Int64),
+		ValuedAt:   v.ValuedAt,
+	}
+}
+
+func toRecord(propertyKey string, e Estimate) store.Valuation {
+	return store.Valuation{
+		PropertyKey: propertyKey,
+		Provider:    e.Provider,
+		Value:       e.Value,
+		Low:         sql.NullFloat64{Float64: e.Low, Valid: e.Low > 0},
+		High:        sql.NullFloat64{Float64: e.High, Valid: e.High > 0},
+		Confidence:  sql.NullFloat64{Float64: e.Confidence, Valid: true},
+		CompsCount:  sql.NullInt64{Int64: int64(e.CompsCount), Valid: true},
+		ValuedAt:    e.ValuedAt,
+	}
+}
@@ -0,0 +1,470 @@
+package serverpkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourorg/search-api/attom"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/env"
+	"github.com/yourorg/search-api/internal/flags"
+	"github.com/yourorg/search-api/internal/redisx"
+	"github.com/yourorg/search-api/internal/shadow"
+	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/usage"
+)
+
+// Config configures a Server. ConfigFromEnv reads it from the environment
+// variables the search-api command documents in docker-compose.yml; zero
+// fields take the defaults noted on them.
+type Config struct {
+	// Addr is the address Start listens on, default ":4002".
+	Addr string
+
+	// ProviderKey is the RapidAPI key. It may be empty when ProviderOffline
+	// is set.
+	ProviderKey string
+	// ProviderOptions configure the provider client: budgets, retries,
+	// fixtures and recordings. See attom.OptionsFromEnv.
+	ProviderOptions []attom.Option
+	// ProviderOffline reports that ProviderOptions serve fixtures or
+	// recordings instead of RapidAPI.
+	ProviderOffline bool
+
+	RedisAddr     string // default 127.0.0.1:6379
+	RedisPassword string
+	RedisDB       int
+
+	// PostgresDSN enables the Postgres store, migrated by New; reads go to
+	// PostgresReplicaDSNs when set. Pool sizes and timeouts come from the
+	// PG_* variables read by store.PoolConfigFromEnv.
+	PostgresDSN         string
+	PostgresReplicaDSNs []string
+	// SlowQueryThreshold logs store queries slower than it, default 500ms.
+	SlowQueryThreshold time.Duration
+	// MemoryStore caches listings in process when there is no PostgresDSN,
+	// keeping at most MemoryStoreMaxListings, default 10000.
+	MemoryStore            bool
+	MemoryStoreMaxListings int
+
+	// HTTP server limits. The timeouts default to 5s to read headers, 30s
+	// to read the request, 60s to write the response and 120s idle between
+	// keep-alive requests; MaxHeaderBytes defaults to 64 KiB.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// HandlerTimeout is the deadline on each request's work, default 30s;
+	// it should stay below WriteTimeout. Exports are exempt.
+	HandlerTimeout time.Duration
+	// MaxBodyBytes caps request bodies, default 1 MiB; batch resolves get
+	// MaxBatchBodyBytes, default 4 MiB.
+	MaxBodyBytes      int64
+	MaxBatchBodyBytes int64
+
+	// AccessLogSampleRate is the fraction of requests written to the access
+	// log, from 0 to 1; ConfigFromEnv defaults it to 1. Server errors and
+	// requests slower than AccessLogSlow are always written, so 0 writes
+	// only those.
+	AccessLogSampleRate float64
+	AccessLogSlow       time.Duration
+
+	// CORSOrigins may call the API from a browser: exact origins, subdomain
+	// wildcards such as https://*.example.com, or "*". None, the default,
+	// sends no CORS headers. CORSHeaders are the request headers allowed
+	// and CORSMaxAge caches preflights, default 10m. CORSCredentials allows
+	// cookies and HTTP auth; it cannot be combined with "*" in CORSOrigins
+	// or CORSAdminOrigins.
+	CORSOrigins     []string
+	CORSHeaders     []string
+	CORSMaxAge      time.Duration
+	CORSCredentials bool
+	// CORSAdminOrigins may call the /v1/admin routes, default none; the
+	// CORSOrigins do not apply there.
+	CORSAdminOrigins []string
+
+	// AdminToken enables the /v1/admin routes.
+	AdminToken string
+
+	// APIKeys identify callers by X-API-Key or bearer token; with them or
+	// OIDCIssuer set, invalid credentials are refused and AuthRequired also
+	// refuses anonymous callers. Routes beyond search and resolve need the
+	// export, hydrate or admin scope.
+	APIKeys      []APIKey
+	AuthRequired bool
+	// OIDCIssuer accepts JWTs from that OpenID Connect provider issued for
+	// OIDCAudience, which it requires. OIDCJWKSURL skips discovery;
+	// OIDCScopeMap maps the provider's scopes to API scopes or roles, and
+	// without it tokens grant no scopes.
+	OIDCIssuer   string
+	OIDCAudience string
+	OIDCJWKSURL  string
+	OIDCScopeMap map[string]string
+
+	// ConsumerBudgets cap provider-triggering requests per consumer: an API
+	// key as key:name, a token subject as jwt:subject, or anonymous. Other
+	// consumers get DefaultConsumerBudget. Requests over budget get 429
+	// when they would call the provider; cached answers are still served.
+	ConsumerBudgets       map[string]ConsumerBudget
+	DefaultConsumerBudget ConsumerBudget
+
+	// Flags sets feature flag rollout percentages by name, below those
+	// stored through /v1/admin/flags. See flags.ParseEnv for FLAGS.
+	Flags map[string]int
+
+	// ShadowProviderURL, or ShadowProviderSandboxDir for fixtures, enables
+	// shadow traffic to a candidate listing provider named ShadowProviderName:
+	// ShadowPercent of provider searches also go to it, bounded by
+	// ShadowTimeout, default 10s, and both answers are kept for
+	// /v1/admin/shadow. The candidate uses ShadowProviderHost and
+	// ShadowProviderKey, which defaults to ProviderKey. Needs PostgresDSN.
+	ShadowProviderName       string
+	ShadowProviderURL        string
+	ShadowProviderHost       string
+	ShadowProviderKey        string
+	ShadowProviderSandboxDir string
+	ShadowPercent            int
+	ShadowTimeout            time.Duration
+
+	// EventsBuffer is each event subscriber's buffer, default 256.
+	// EventsRedisChannel mirrors events to Redis for `propctl events tail`.
+	EventsBuffer       int
+	EventsRedisChannel string
+	// Indexer feeds events to the search indexer.
+	Indexer bool
+
+	// RefreshQueue is "memory", the default, or "redis" to share one queue
+	// across replicas. The other refresh settings default to 256 queued
+	// jobs, 2 workers and 3 attempts.
+	RefreshQueue       string
+	RefreshQueueSize   int
+	RefreshWorkers     int
+	RefreshMaxAttempts int
+
+	// CacheWarmTopZips > 0 refreshes the busiest ZIPs' pages CacheWarmLead,
+	// default 1m, before they go stale, checking every CacheWarmInterval,
+	// default 30s.
+	CacheWarmTopZips  int
+	CacheWarmLead     time.Duration
+	CacheWarmInterval time.Duration
+	// DualReadStaleAfter is how long after its listings were fetched a
+	// database page has its ZIP re-crawled under the dual-read flag; a ZIP
+	// is crawled at most once per DualReadZipCooldown, default 6h.
+	DualReadStaleAfter  time.Duration
+	DualReadZipCooldown time.Duration
+	// ListingsPhotoBudget caps the provider photo calls for one listings
+	// page, default 10; ListingsStoredPhotosOnly makes none, leaving cards
+	// with stored or search payload images.
+	ListingsPhotoBudget      int
+	ListingsStoredPhotosOnly bool
+
+	// Resolve batch limits and the ?wait=true timeout; zero uses the
+	// resolve handler's defaults.
+	ResolveBatchMaxItems    int
+	ResolveBatchFetchBudget int
+	ResolveWaitTimeout      time.Duration
+	// AddressVerifyStrict refuses to resolve addresses the address
+	// verifier (ADDRESS_VERIFY) can't confirm as deliverable.
+	AddressVerifyStrict bool
+
+	Hooks Hooks
+}
+
+// ConsumerBudget caps a consumer's provider-triggering requests per UTC
+// day and calendar month; zero leaves a period uncapped.
+type ConsumerBudget struct {
+	Daily   int
+	Monthly int
+}
+
+// APIKey is a static API key. Scopes name scopes or auth.Roles, such as
+// "operator", and default to "read"; "admin" grants everything.
+type APIKey struct {
+	Name   string
+	Secret string
+	Scopes []string
+}
+
+// ConfigFromEnv reads the search-api command's environment. It does not
+// require RAPIDAPI_KEY; callers check ProviderKey against ProviderOffline.
+func ConfigFromEnv() Config {
+	opts, offline := attom.OptionsFromEnv()
+	return Config{
+		Addr:                     ":" + strconv.Itoa(env.GetInt("PORT", 4002)),
+		ProviderKey:              os.Getenv("RAPIDAPI_KEY"),
+		ProviderOptions:          opts,
+		ProviderOffline:          offline,
+		RedisAddr:                env.Get("REDIS_ADDR", "127.0.0.1:6379"),
+		RedisPassword:            env.Get("REDIS_PASSWORD", ""),
+		RedisDB:                  env.GetInt("REDIS_DB", 0),
+		PostgresDSN:              os.Getenv("PG_DSN"),
+		PostgresReplicaDSNs:      splitDSNs(os.Getenv("PG_REPLICA_DSNS")),
+		SlowQueryThreshold:       time.Duration(env.GetInt("PG_SLOW_QUERY_MS", 500)) * time.Millisecond,
+		MemoryStore:              env.Get("STORE_BACKEND", "") == "memory",
+		MemoryStoreMaxListings:   env.GetInt("MEMORY_STORE_MAX_LISTINGS", 10000),
+		ReadHeaderTimeout:        time.Duration(env.GetInt("HTTP_READ_HEADER_TIMEOUT_SECONDS", 5)) * time.Second,
+		ReadTimeout:              time.Duration(env.GetInt("HTTP_READ_TIMEOUT_SECONDS", 30)) * time.Second,
+		WriteTimeout:             time.Duration(env.GetInt("HTTP_WRITE_TIMEOUT_SECONDS", 60)) * time.Second,
+		IdleTimeout:              time.Duration(env.GetInt("HTTP_IDLE_TIMEOUT_SECONDS", 120)) * time.Second,
+		MaxHeaderBytes:           env.GetInt("HTTP_MAX_HEADER_BYTES", 64<<10),
+		HandlerTimeout:           time.Duration(env.GetInt("HTTP_HANDLER_TIMEOUT_SECONDS", 30)) * time.Second,
+		MaxBodyBytes:             int64(env.GetInt("HTTP_MAX_BODY_BYTES", 1<<20)),
+		MaxBatchBodyBytes:        int64(env.GetInt("HTTP_MAX_BATCH_BODY_BYTES", 4<<20)),
+		AccessLogSampleRate:      env.GetFloat("ACCESS_LOG_SAMPLE_RATE", 1),
+		AccessLogSlow:            time.Duration(env.GetInt("ACCESS_LOG_SLOW_MS", 1000)) * time.Millisecond,
+		CORSOrigins:              splitDSNs(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		CORSHeaders:              splitDSNs(os.Getenv("CORS_ALLOWED_HEADERS")),
+		CORSMaxAge:               time.Duration(env.GetInt("CORS_MAX_AGE_SECONDS", 600)) * time.Second,
+		CORSCredentials:          os.Getenv("CORS_ALLOW_CREDENTIALS") == "1",
+		CORSAdminOrigins:         splitDSNs(os.Getenv("CORS_ADMIN_ORIGINS")),
+		AdminToken:               os.Getenv("ADMIN_TOKEN"),
+		APIKeys:                  parseAPIKeys(os.Getenv("API_KEYS")),
+		AuthRequired:             os.Getenv("AUTH_REQUIRED") == "1",
+		OIDCIssuer:               os.Getenv("OIDC_ISSUER"),
+		OIDCAudience:             os.Getenv("OIDC_AUDIENCE"),
+		OIDCJWKSURL:              os.Getenv("OIDC_JWKS_URL"),
+		OIDCScopeMap:             parseScopeMap(os.Getenv("OIDC_SCOPE_MAP")),
+		ConsumerBudgets:          parseConsumerBudgets(os.Getenv("CONSUMER_BUDGETS")),
+		DefaultConsumerBudget:    parseConsumerBudget(os.Getenv("CONSUMER_BUDGET_DEFAULT")),
+		Flags:                    flags.ParseEnv(os.Getenv("FLAGS")),
+		ShadowProviderName:       env.Get("SHADOW_PROVIDER_NAME", "candidate"),
+		ShadowProviderURL:        os.Getenv("SHADOW_PROVIDER_BASE_URL"),
+		ShadowProviderHost:       os.Getenv("SHADOW_PROVIDER_HOST"),
+		ShadowProviderKey:        os.Getenv("SHADOW_PROVIDER_KEY"),
+		ShadowProviderSandboxDir: os.Getenv("SHADOW_PROVIDER_SANDBOX_DIR"),
+		ShadowPercent:            env.GetInt("SHADOW_PERCENT", 0),
+		ShadowTimeout:            time.Duration(env.GetInt("SHADOW_TIMEOUT_SECONDS", 10)) * time.Second,
+		EventsBuffer:             env.GetInt("EVENTS_BUFFER", 256),
+		EventsRedisChannel:       os.Getenv("EVENTS_REDIS_CHANNEL"),
+		Indexer:                  os.Getenv("ENABLE_INDEXER") == "1",
+		RefreshQueue:             env.Get("REFRESH_QUEUE", "memory"),
+		RefreshQueueSize:         env.GetInt("REFRESH_QUEUE_SIZE", 256),
+		RefreshWorkers:           env.GetInt("REFRESH_WORKERS", 2),
+		RefreshMaxAttempts:       env.GetInt("REFRESH_MAX_ATTEMPTS", 3),
+		CacheWarmTopZips:         env.GetInt("CACHE_WARM_TOP_ZIPS", 0),
+		CacheWarmLead:            time.Duration(env.GetInt("CACHE_WARM_LEAD_SECONDS", 60)) * time.Second,
+		CacheWarmInterval:        time.Duration(env.GetInt("CACHE_WARM_INTERVAL_SECONDS", 30)) * time.Second,
+		DualReadStaleAfter:       time.Duration(env.GetInt("DUAL_READ_STALE_MINUTES", 60)) * time.Minute,
+		DualReadZipCooldown:      time.Duration(env.GetInt("DUAL_READ_ZIP_COOLDOWN_MINUTES", 360)) * time.Minute,
+		ListingsPhotoBudget:      env.GetInt("LISTINGS_PHOTO_BUDGET", 10),
+		ListingsStoredPhotosOnly: os.Getenv("LISTINGS_STORED_PHOTOS_ONLY") == "1",
+		ResolveBatchMaxItems:     env.GetInt("RESOLVE_BATCH_MAX_ITEMS", 50),
+		ResolveBatchFetchBudget:  env.GetInt("RESOLVE_BATCH_FETCH_BUDGET", 10),
+		ResolveWaitTimeout:       time.Duration(env.GetInt("RESOLVE_WAIT_TIMEOUT_SECONDS", 10)) * time.Second,
+		AddressVerifyStrict:      os.Getenv("ADDRESS_VERIFY_STRICT") == "1",
+	}
+}
+
+func (c Config) withDefaults() Config {
+	if c.Addr == "" {
+		c.Addr = ":4002"
+	}
+	if c.RedisAddr == "" {
+		c.RedisAddr = "127.0.0.1:6379"
+	}
+	if c.ReadHeaderTimeout <= 0 {
+		c.ReadHeaderTimeout = 5 * time.Second
+	}
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = 30 * time.Second
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 60 * time.Second
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 120 * time.Second
+	}
+	if c.MaxHeaderBytes <= 0 {
+		c.MaxHeaderBytes = 64 << 10
+	}
+	if c.HandlerTimeout <= 0 {
+		c.HandlerTimeout = 30 * time.Second
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+	if c.MaxBatchBodyBytes <= 0 {
+		c.MaxBatchBodyBytes = 4 << 20
+	}
+	if c.SlowQueryThreshold <= 0 {
+		c.SlowQueryThreshold = 500 * time.Millisecond
+	}
+	if c.MemoryStoreMaxListings <= 0 {
+		c.MemoryStoreMaxListings = 10000
+	}
+	if c.EventsBuffer <= 0 {
+		c.EventsBuffer = 256
+	}
+	if c.RefreshQueueSize <= 0 {
+		c.RefreshQueueSize = 256
+	}
+	if c.RefreshWorkers <= 0 {
+		c.RefreshWorkers = 2
+	}
+	if c.RefreshMaxAttempts <= 0 {
+		c.RefreshMaxAttempts = 3
+	}
+	if c.CacheWarmLead <= 0 {
+		c.CacheWarmLead = time.Minute
+	}
+	if c.CacheWarmInterval <= 0 {
+		c.CacheWarmInterval = 30 * time.Second
+	}
+	return c
+}
+
+// Validate reports settings that are out of range or would leave the
+// server insecure, which the search-api command refuses to start with.
+func (c Config) Validate() error {
+	if c.OIDCIssuer != "" && c.OIDCAudience == "" {
+		return errors.New("OIDC_ISSUER needs OIDC_AUDIENCE: without it tokens the issuer signs for any client are accepted")
+	}
+	if !(c.AccessLogSampleRate >= 0 && c.AccessLogSampleRate <= 1) {
+		return fmt.Errorf("ACCESS_LOG_SAMPLE_RATE must be between 0 and 1, got %v", c.AccessLogSampleRate)
+	}
+	if c.CORSCredentials && (slices.Contains(c.CORSOrigins, "*") || slices.Contains(c.CORSAdminOrigins, "*")) {
+		return errors.New(`CORS_ALLOW_CREDENTIALS cannot be combined with "*" origins: any site could make credentialed calls`)
+	}
+	return nil
+}
+
+// authenticator builds the request authenticator. With no API keys and no
+// OIDC issuer it only recognises the AdminToken.
+func (c Config) authenticator() *auth.Authenticator {
+	a := &auth.Authenticator{
+		AdminToken: c.AdminToken,
+		Required:   c.AuthRequired,
+		Public:     map[string]bool{"/health": true, "/openapi.json": true, "/docs": true},
+	}
+	for _, k := range c.APIKeys {
+		a.Keys = append(a.Keys, auth.Key{Name: k.Name, Secret: k.Secret, Scopes: k.Scopes})
+	}
+	if c.OIDCIssuer != "" {
+		a.OIDC = &auth.Verifier{Issuer: c.OIDCIssuer, Audience: c.OIDCAudience, JWKSURL: c.OIDCJWKSURL, ScopeMap: c.OIDCScopeMap}
+	}
+	return a
+}
+
+// budgets builds the per-consumer budget middleware, counting in rdb.
+// shadowProvider is the candidate provider shadowing searches into st, or
+// nil when shadow traffic is off.
+func (c Config) shadowProvider(st *store.Store) *shadow.Provider {
+	if st == nil || c.ShadowPercent <= 0 || (c.ShadowProviderURL == "" && c.ShadowProviderSandboxDir == "") {
+		return nil
+	}
+	var opts []attom.Option
+	if c.ShadowProviderURL != "" {
+		opts = append(opts, attom.WithBaseURL(c.ShadowProviderURL))
+	}
+	if c.ShadowProviderHost != "" {
+		opts = append(opts, attom.WithHost(c.ShadowProviderHost))
+	}
+	if c.ShadowProviderSandboxDir != "" {
+		opts = append(opts, attom.WithSandbox(c.ShadowProviderSandboxDir))
+	}
+	key := c.ShadowProviderKey
+	if key == "" {
+		key = c.ProviderKey
+	}
+	return &shadow.Provider{
+		Name:    c.ShadowProviderName,
+		Client:  attom.NewClient(key, opts...),
+		Store:   st,
+		Percent: min(c.ShadowPercent, 100),
+		Timeout: c.ShadowTimeout,
+	}
+}
+
+func (c Config) budgets(rdb *redisx.Client) *usage.Budgets {
+	b := &usage.Budgets{
+		Default: usage.Budget(c.DefaultConsumerBudget),
+		Redis:   rdb,
+	}
+	for consumer, cb := range c.ConsumerBudgets {
+		if b.Consumers == nil {
+			b.Consumers = map[string]usage.Budget{}
+		}
+		b.Consumers[consumer] = usage.Budget(cb)
+	}
+	return b
+}
+
+// parseAPIKeys reads API_KEYS: comma-separated name:secret pairs, each
+// optionally followed by :scopes separated by spaces, e.g.
+// "mobile:s3cret,ops:t0ken:read admin".
+func parseAPIKeys(v string) []APIKey {
+	var keys []APIKey
+	for _, part := range splitDSNs(v) {
+		fields := strings.SplitN(part, ":", 3)
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+		k := APIKey{Name: fields[0], Secret: fields[1]}
+		if len(fields) == 3 {
+			k.Scopes = strings.Fields(fields[2])
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// parseScopeMap reads OIDC_SCOPE_MAP: comma-separated provider=api scope
+// pairs, e.g. "search.read=read,search.admin=admin". Empty maps nothing.
+func parseScopeMap(v string) map[string]string {
+	var m map[string]string
+	for _, part := range splitDSNs(v) {
+		from, to, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		if m == nil {
+			m = map[string]string{}
+		}
+		m[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+	return m
+}
+
+// parseConsumerBudgets reads CONSUMER_BUDGETS: comma-separated
+// consumer=daily/monthly entries, e.g. "key:mobile=1000/20000,anonymous=50/0".
+func parseConsumerBudgets(v string) map[string]ConsumerBudget {
+	var m map[string]ConsumerBudget
+	for _, part := range splitDSNs(v) {
+		consumer, budget, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(consumer) == "" {
+			continue
+		}
+		if m == nil {
+			m = map[string]ConsumerBudget{}
+		}
+		m[strings.TrimSpace(consumer)] = parseConsumerBudget(budget)
+	}
+	return m
+}
+
+// parseConsumerBudget reads "daily/monthly"; a missing or invalid part is
+// uncapped.
+func parseConsumerBudget(v string) ConsumerBudget {
+	daily, monthly, _ := strings.Cut(strings.TrimSpace(v), "/")
+	d, _ := strconv.Atoi(strings.TrimSpace(daily))
+	m, _ := strconv.Atoi(strings.TrimSpace(monthly))
+	return ConsumerBudget{Daily: max(d, 0), Monthly: max(m, 0)}
+}
+
+// splitDSNs parses a comma-separated list, such as replica DSNs or CORS
+// origins.
+func splitDSNs(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,181 @@
+package serverpkg
+
+import (
+	"context"
+	"expvar"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/httprate"
+	"github.com/go-chi/render"
+	"github.com/yourorg/search-api/attom"
+	httpapi "github.com/yourorg/search-api/http"
+	"github.com/yourorg/search-api/http/gql"
+	"github.com/yourorg/search-api/http/openapi"
+	httpv1 "github.com/yourorg/search-api/http/v1"
+	"github.com/yourorg/search-api/internal/apierror"
+	"github.com/yourorg/search-api/internal/auth"
+	"github.com/yourorg/search-api/internal/cors"
+	"github.com/yourorg/search-api/internal/flags"
+	"github.com/yourorg/search-api/internal/logger"
+	"github.com/yourorg/search-api/internal/payment"
+	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/tracing"
+)
+
+// streamingRoutes may run past HandlerTimeout and WriteTimeout; they are
+// bounded by the client instead.
+var streamingRoutes = map[string]bool{
+	"/v1/export/listings": true,
+}
+
+// buildRouter mounts the API's routes. Config.AdminToken enables the
+// /v1/admin routes, which take their CORS origins from CORSAdminOrigins.
+// Handlers evaluate feature flags from fl.
+func buildRouter(cfg Config, listingClient *attom.Client, deps httpv1.ResolveDeps, listings httpapi.ListingsDeps, fl *flags.Set) chi.Router {
+	authn := cfg.authenticator()
+	r := chi.NewRouter()
+	r.Use(tracing.Middleware)
+	r.Use(cors.Middleware(cors.Policy{
+		Origins:     cfg.CORSOrigins,
+		Headers:     cfg.CORSHeaders,
+		MaxAge:      cfg.CORSMaxAge,
+		Credentials: cfg.CORSCredentials,
+	}, map[string]cors.Policy{
+		"/v1/admin": {
+			Origins:     cfg.CORSAdminOrigins,
+			Methods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+			Headers:     cfg.CORSHeaders,
+			MaxAge:      cfg.CORSMaxAge,
+			Credentials: cfg.CORSCredentials,
+		},
+	}))
+	r.Use(authn.Middleware)
+	r.Use(fl.Middleware)
+	r.Use(limitBody(cfg.MaxBodyBytes, map[string]int64{
+		"/v1/properties/resolve:batch": cfg.MaxBatchBodyBytes,
+	}))
+	r.Use(handlerDeadline(cfg.HandlerTimeout))
+	r.Use(interactiveProvider)
+	r.Use(auditActor)
+	r.Use(cfg.budgets(deps.Redis).Middleware)
+	r.Use(httprate.Limit(100, 1*time.Minute, // protect upstream quota
+		httprate.WithKeyFuncs(httprate.KeyByIP),
+		httprate.WithLimitHandler(func(w http.ResponseWriter, r *http.Request) {
+			apierror.Write(w, r, apierror.New(http.StatusTooManyRequests, "rate_limited", "too many requests").WithRetryAfter(time.Minute))
+		}),
+	))
+	r.Use(render.SetContentType(render.ContentTypeJSON))
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{"ok":true}`)) })
+	// Runtime and store stats are operator-only, like /v1/admin.
+	r.With(auth.Require(auth.ScopeAdmin)).Handle("/debug/vars", expvar.Handler())
+
+	var storeRef *store.Store
+	if deps.Hydrator != nil {
+		storeRef = store.Postgres(deps.Hydrator.Store)
+	}
+	// Read routes; the others declare their own scope when registered.
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Require(auth.ScopeRead))
+		httpapi.RegisterSearch(r, httpapi.SearchDeps{Hydrator: deps.Hydrator, ListingsClient: listingClient, Shadow: listings.Shadow})
+		httpapi.RegisterGeoSearch(r, httpapi.GeoSearchDeps{Store: storeRef})
+		httpapi.RegisterListings(r, listings)
+
+		// v1 resolve endpoint with Redis + SWR
+		httpv1.RegisterResolve(r, deps)
+		httpv1.RegisterOpenHouses(r, httpv1.OpenHousesDeps{Store: storeRef})
+		httpv1.RegisterProperties(r, httpv1.PropertiesDeps{Store: storeRef})
+		httpv1.RegisterPhotos(r, httpv1.PhotosDeps{Store: storeRef})
+		httpv1.RegisterPayments(r, httpv1.PaymentDeps{Store: storeRef, Defaults: payment.AssumptionsFromEnv()})
+		httpv1.RegisterRentEstimates(r, httpv1.RentDeps{Store: storeRef, Rapid: listingClient, Redis: deps.Redis, Valuation: deps.Valuation,
+			Defaults: payment.AssumptionsFromEnv(), Rental: payment.RentalAssumptionsFromEnv()})
+		httpv1.RegisterMarkets(r, httpv1.MarketsDeps{Store: storeRef})
+		httpv1.RegisterReports(r, httpv1.ReportsDeps{Store: storeRef})
+		gql.Register(r, &gql.Resolver{Store: storeRef, Redis: deps.Redis, Valuation: deps.Valuation})
+	})
+	httpapi.RegisterHydrate(r, httpapi.HydrateDeps{})
+	httpv1.RegisterExport(r, httpv1.ExportDeps{Store: storeRef})
+	httpv1.RegisterChanges(r, httpv1.ChangesDeps{Store: storeRef})
+	httpv1.RegisterNotes(r, httpv1.NotesDeps{Store: storeRef})
+	httpv1.RegisterSavedSearches(r, httpv1.SavedSearchesDeps{Store: storeRef})
+	httpv1.RegisterAdmin(r, httpv1.AdminDeps{Store: storeRef, Flags: fl, Provider: listingClient, Enabled: cfg.AdminToken != "" || authn.Enabled()})
+
+	// API reference for client SDK generation
+	openapi.Register(r)
+
+	return r
+}
+
+// limitBody caps request bodies at max bytes, or the route's own cap in
+// routes. A declared length over the cap is refused at once; otherwise the
+// read fails when it passes the cap and the handler answers body_too_large.
+func limitBody(max int64, routes map[string]int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := max
+			if n, ok := routes[r.URL.Path]; ok {
+				limit = n
+			}
+			if limit > 0 && r.Body != nil && r.Body != http.NoBody {
+				if r.ContentLength > limit {
+					apierror.Write(w, r, apierror.BodyTooLarge(limit))
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handlerDeadline bounds each request's context by d, so store queries and
+// provider calls give up and the handler answers 504. Streaming routes
+// instead have their write deadline lifted.
+func handlerDeadline(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if streamingRoutes[r.URL.Path] {
+				_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+				next.ServeHTTP(w, r)
+				return
+			}
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// interactiveProvider gives provider calls made while serving a request
+// the interactive timeout and retries; work the request hands to the
+// refresh queue or a bulk job runs with the bulk profile instead.
+func interactiveProvider(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(attom.WithRequestOptions(r.Context(), attom.InteractiveRequests)))
+	})
+}
+
+// auditActor attributes store writes made while serving a request to the
+// caller in the ingest audit log, and the request itself to the
+// authenticated caller in the access log and usage rollup.
+func auditActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(caller); err == nil {
+			caller = host
+		}
+		if p, ok := auth.FromContext(r.Context()); ok {
+			caller = p.ID()
+			logger.SetConsumer(r.Context(), caller)
+		}
+		ctx := store.WithActor(r.Context(), store.Actor{Name: "api:" + caller, RequestID: middleware.GetReqID(r.Context())})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
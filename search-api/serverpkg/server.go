@@ -0,0 +1,402 @@
+// Package serverpkg runs the search API inside another Go program. New wires
+// the provider client, Redis, the optional store, the refresh queue and the
+// HTTP routes from a Config; Start serves them and Shutdown drains them. The
+// search-api command is ConfigFromEnv, New and Start, with Shutdown on
+// SIGINT/SIGTERM.
+//
+// An embedding service can add its own routes and middleware before Start,
+// or mount Handler in its own server and never call Start.
+package serverpkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/yourorg/search-api/attom"
+	httpapi "github.com/yourorg/search-api/http"
+	httpv1 "github.com/yourorg/search-api/http/v1"
+	"github.com/yourorg/search-api/internal/addrverify"
+	"github.com/yourorg/search-api/internal/analytics"
+	"github.com/yourorg/search-api/internal/enrichment"
+	"github.com/yourorg/search-api/internal/events"
+	"github.com/yourorg/search-api/internal/flags"
+	"github.com/yourorg/search-api/internal/geocode"
+	"github.com/yourorg/search-api/internal/hydrator"
+	"github.com/yourorg/search-api/internal/logger"
+	"github.com/yourorg/search-api/internal/redisx"
+	"github.com/yourorg/search-api/internal/refresh"
+	"github.com/yourorg/search-api/internal/search"
+	"github.com/yourorg/search-api/internal/store"
+	"github.com/yourorg/search-api/internal/usage"
+	"github.com/yourorg/search-api/internal/valuation"
+)
+
+// Hooks let an embedding service follow the server's lifecycle and events.
+// Every hook is optional.
+type Hooks struct {
+	// OnStart runs once Start is listening, with the bound address.
+	OnStart func(addr net.Addr)
+	// OnShutdown runs after HTTP requests have drained and before queued
+	// refreshes are.
+	OnShutdown func(ctx context.Context)
+	// OnEvent receives every property and listing event. Events are
+	// dropped, not queued without bound, while OnEvent falls behind.
+	OnEvent func(Event)
+}
+
+// Event is a property or listing event: Type is one of property.updated,
+// property.resolved, listing.price_changed, listing.status_changed or
+// listing.photos_changed, and Payload its JSON body, the same envelope
+// EVENTS_REDIS_CHANNEL carries.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Server is the search API. Create one with New.
+type Server struct {
+	cfg        Config
+	router     chi.Router
+	middleware []func(http.Handler) http.Handler
+
+	rdb      *redisx.Client
+	pgStore  *store.Store
+	hydr     *hydrator.Hydrator
+	usage    *usage.Recorder
+	searches *analytics.Recorder
+	flags    *flags.Set
+	ref      refresh.Queue
+	stopWork context.CancelFunc
+
+	mu  sync.Mutex
+	srv *http.Server
+}
+
+// publishOnce publishes the first Server's stats on /debug/vars; expvar
+// names are process-wide.
+var publishOnce sync.Once
+
+// New wires a Server from cfg. Redis and Postgres being unreachable is
+// logged, not fatal, as in the search-api command; requests then fall back
+// to the provider. Background work starts at once and stops on Shutdown.
+func New(cfg Config) *Server {
+	cfg = cfg.withDefaults()
+	s := &Server{cfg: cfg}
+	workCtx, stopWork := context.WithCancel(context.Background())
+	s.stopWork = stopWork
+
+	s.rdb = redisx.New(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	if err := s.rdb.Ping(context.TODO()); err != nil {
+		log.Printf("warning: redis ping failed: %v", err)
+	}
+
+	// Optional Postgres + events + indexer
+	store.SetSlowQueryThreshold(cfg.SlowQueryThreshold)
+	if cfg.PostgresDSN != "" {
+		st, err := store.OpenWithReplicas(cfg.PostgresDSN, store.PoolConfigFromEnv(), cfg.PostgresReplicaDSNs...)
+		if err != nil {
+			log.Printf("postgres open error: %v", err)
+		} else {
+			s.pgStore = st
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_ = st.Ping(ctx)
+			_ = st.Migrate(ctx)
+			cancel()
+		}
+	}
+	pub := events.NewInMemory(cfg.EventsBuffer)
+	if cfg.EventsRedisChannel != "" {
+		go events.Forward(workCtx, pub, s.rdb, cfg.EventsRedisChannel)
+	}
+	if cfg.Indexer {
+		go (&search.Indexer{Pub: pub}).Run(workCtx)
+	}
+	if cfg.Hooks.OnEvent != nil {
+		go forwardEvents(workCtx, pub, cfg.Hooks.OnEvent)
+	}
+	s.flags = &flags.Set{Store: s.pgStore, Redis: s.rdb, Env: cfg.Flags}
+	go s.flags.Run(workCtx)
+	providerOpts := cfg.ProviderOptions
+	var valuer *valuation.Service
+	if s.pgStore != nil {
+		s.hydr = &hydrator.Hydrator{Store: s.pgStore, Pub: pub, Demand: &hydrator.DemandRecorder{Store: s.pgStore}}
+		go s.hydr.Demand.Run(workCtx, time.Minute)
+		s.usage = &usage.Recorder{Store: s.pgStore}
+		go s.usage.Run(workCtx, time.Minute)
+		s.searches = &analytics.Recorder{Store: s.pgStore}
+		go s.searches.Run(workCtx, time.Minute)
+		if enricher := (&enrichment.Service{Store: s.pgStore, Redis: s.rdb, Sources: enrichment.SourcesFromEnv()}); enricher.Enabled() {
+			go enricher.Run(workCtx, pub)
+		}
+		valuer = &valuation.Service{Valuer: &valuation.CompsValuer{Store: s.pgStore}, Store: s.pgStore, MaxAge: 24 * time.Hour}
+		providerOpts = append(providerOpts, attom.WithDriftHook(s.hydr.RecordDrift))
+	} else if cfg.MemoryStore {
+		// Lite mode: listings are cached in process so pages and photos can
+		// be served from the store without Postgres. Routes that need
+		// Postgres still answer store_unavailable.
+		s.hydr = &hydrator.Hydrator{Store: store.NewMemory(cfg.MemoryStoreMaxListings), Pub: pub}
+		providerOpts = append(providerOpts, attom.WithDriftHook(s.hydr.RecordDrift))
+	}
+	listingClient := attom.NewClient(cfg.ProviderKey, providerOpts...)
+
+	// Background refresher: resolves stale keys via RapidAPI and writes back into Redis
+	refreshOpts := refresh.Options{
+		Capacity:    cfg.RefreshQueueSize,
+		Workers:     cfg.RefreshWorkers,
+		MaxAttempts: cfg.RefreshMaxAttempts,
+		DeadLetter:  &refresh.RedisDeadLetter{Redis: s.rdb},
+	}
+	propertyRefresh := (&refresh.PropertyRefresher{
+		Rapid:      listingClient,
+		Redis:      s.rdb,
+		Hydrator:   s.hydr,
+		CacheTTL:   time.Hour,
+		StaleAfter: 5 * time.Minute,
+	}).Refresh
+	candidate := cfg.shadowProvider(s.pgStore)
+	listings := httpapi.ListingsDeps{
+		Hydrator:         s.hydr,
+		Store:            s.pgStore,
+		ListingsClient:   listingClient,
+		Shadow:           candidate,
+		Valuation:        valuer,
+		Redis:            s.rdb,
+		PageTTL:          time.Hour,
+		PageStaleAfter:   5 * time.Minute,
+		ZipStaleAfter:    cfg.DualReadStaleAfter,
+		ZipCrawlCooldown: cfg.DualReadZipCooldown,
+
+		PhotoBudget:      cfg.ListingsPhotoBudget,
+		StoredPhotosOnly: cfg.ListingsStoredPhotosOnly,
+	}
+	// Refreshes run behind a cached response, so they yield quota to
+	// interactive calls like bulk hydration does.
+	refreshDo := func(ctx context.Context, j refresh.Job) error {
+		ctx = attom.WithPriority(ctx, attom.PriorityBulk)
+		ctx = attom.WithRequestOptions(ctx, attom.BulkRequests)
+		ctx = store.WithActor(ctx, store.Actor{Name: "refresh"})
+		if j.Kind == refresh.KindListings && j.Listings != nil {
+			return listings.RefreshListingsPage(ctx, *j.Listings)
+		}
+		if j.Kind == refresh.KindZip {
+			return refreshZip(ctx, listingClient, s.hydr, j.Zip)
+		}
+		return propertyRefresh(ctx, j)
+	}
+	if cfg.RefreshQueue == "redis" {
+		sq, err := refresh.NewStreamQueue(s.rdb, refresh.StreamOptions{Options: refreshOpts}, refreshDo)
+		if err != nil {
+			log.Printf("[WARN] redis refresh queue unavailable, using in-memory queue: %v", err)
+		} else {
+			s.ref = sq
+		}
+	}
+	if s.ref == nil {
+		s.ref = refresh.NewWithOptions(refreshOpts, refreshDo)
+	}
+	ref := s.ref
+	listings.Refetch = func(p refresh.ListingsPage) {
+		ref.Enqueue(refresh.Job{Kind: refresh.KindListings, Listings: &p})
+	}
+	if s.pgStore != nil {
+		listings.RefreshZip = func(zip string) bool {
+			return ref.Enqueue(refresh.Job{Kind: refresh.KindZip, Zip: zip})
+		}
+	}
+	if cfg.CacheWarmTopZips > 0 {
+		listings.Warmer = &refresh.Warmer{
+			Redis:    s.rdb,
+			Enqueue:  ref.Enqueue,
+			TopN:     cfg.CacheWarmTopZips,
+			Lead:     cfg.CacheWarmLead,
+			Interval: cfg.CacheWarmInterval,
+		}
+		go listings.Warmer.Run(workCtx)
+	}
+
+	publishOnce.Do(func() {
+		expvar.Publish("store_pool", expvar.Func(func() any { return s.pgStore.PoolStats() }))
+		expvar.Publish("events", expvar.Func(func() any { return pub.Stats() }))
+		expvar.Publish("provider_budgets", expvar.Func(func() any { return listingClient.BudgetUsage() }))
+		expvar.Publish("refresh", expvar.Func(func() any { return ref.Stats() }))
+		expvar.Publish("shadow", expvar.Func(func() any { return candidate.Stats() }))
+	})
+
+	deps := httpv1.ResolveDeps{
+		Redis: s.rdb,
+		Rapid: listingClient,
+		Refetch: func(pk, line1, city, state, zip string) {
+			ref.Enqueue(refresh.Job{PropertyKey: pk, Line1: line1, City: city, State: state, Zip: zip})
+		},
+		CacheTTL:    time.Hour,
+		StaleAfter:  5 * time.Minute,
+		NegativeTTL: 60 * time.Second,
+		Hydrator:    s.hydr,
+		Valuation:   valuer,
+		Geocoder:    geocode.FromEnv(),
+
+		Verifier:            addrverify.FromEnv(s.rdb),
+		RejectUndeliverable: cfg.AddressVerifyStrict,
+
+		BatchMaxItems:    cfg.ResolveBatchMaxItems,
+		BatchFetchBudget: cfg.ResolveBatchFetchBudget,
+		WaitTimeout:      cfg.ResolveWaitTimeout,
+	}
+	s.router = buildRouter(cfg, listingClient, deps, listings, s.flags)
+	return s
+}
+
+// refreshZip re-crawls one ZIP for dual-read the way the hydrator's bulk
+// job does. Each ZIP checkpoints as its own run, since the refresh workers
+// crawl several at once. Delisting is left to the hydrator, which counts
+// its own complete cycles.
+func refreshZip(ctx context.Context, client *attom.Client, hydr *hydrator.Hydrator, zip string) error {
+	job := &hydrator.BulkJob{
+		Client:   client,
+		Hydrator: hydr,
+		Config: hydrator.BulkConfig{
+			Name:              "dual-read:" + zip,
+			Targets:           hydrator.TargetsStatic,
+			Zips:              []string{zip},
+			DelistAfterCycles: -1,
+		},
+	}
+	return job.RunOnce(ctx)
+}
+
+// forwardEvents hands every event on pub to fn until ctx is done.
+func forwardEvents(ctx context.Context, pub events.Publisher, fn func(Event)) {
+	sub, unsubscribe := pub.Subscribe("hooks")
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-sub:
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(evt.Payload)
+			if err != nil {
+				log.Printf("[WARN] events: marshal %s: %v", evt.Type, err)
+				continue
+			}
+			fn(Event{ID: evt.ID, Type: string(evt.Type), Version: evt.Version, OccurredAt: evt.OccurredAt, Payload: payload})
+		}
+	}
+}
+
+// Handle registers h for every method on pattern, in chi's pattern syntax,
+// behind the server's middleware. Register routes before Start.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.router.Handle(pattern, h)
+}
+
+// HandleFunc is Handle for a function.
+func (s *Server) HandleFunc(pattern string, h http.HandlerFunc) {
+	s.router.HandleFunc(pattern, h)
+}
+
+// Method registers h for one HTTP method on pattern.
+func (s *Server) Method(method, pattern string, h http.Handler) {
+	s.router.Method(method, pattern, h)
+}
+
+// Use wraps every route, built in or registered, in mw. Middleware runs in
+// the order added, after request logging and before the server's own
+// middleware. Add it before Start or Handler.
+func (s *Server) Use(mw ...func(http.Handler) http.Handler) {
+	s.middleware = append(s.middleware, mw...)
+}
+
+// Handler is the server's complete HTTP handler, for mounting in another
+// server instead of calling Start.
+func (s *Server) Handler() http.Handler {
+	var h http.Handler = s.router
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		h = s.middleware[i](h)
+	}
+	onRequest := func(e logger.Entry) {
+		s.usage.Record(e)
+		s.searches.Record(e)
+	}
+	// The request ID is assigned outside the access log so its line carries it.
+	h = logger.AccessLog{SampleRate: s.cfg.AccessLogSampleRate, Slow: s.cfg.AccessLogSlow, OnRequest: onRequest}.Middleware(h)
+	return middleware.RequestID(h)
+}
+
+// Start listens on Config.Addr and serves in the background. It returns
+// once the listener is bound, after running Hooks.OnStart.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.srv != nil {
+		return errors.New("server already started")
+	}
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: s.cfg.ReadHeaderTimeout,
+		ReadTimeout:       s.cfg.ReadTimeout,
+		WriteTimeout:      s.cfg.WriteTimeout,
+		IdleTimeout:       s.cfg.IdleTimeout,
+		MaxHeaderBytes:    s.cfg.MaxHeaderBytes,
+	}
+	s.srv = srv
+	go func() {
+		log.Printf("search-api listening on %s", ln.Addr())
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[ERROR] search-api serve: %v", err)
+		}
+	}()
+	if s.cfg.Hooks.OnStart != nil {
+		s.cfg.Hooks.OnStart(ln.Addr())
+	}
+	return nil
+}
+
+// Shutdown drains HTTP requests and queued refreshes, flushes demand and
+// usage counts and stops background work, within ctx. The Server can't be reused.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var errs []error
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.cfg.Hooks.OnShutdown != nil {
+		s.cfg.Hooks.OnShutdown(ctx)
+	}
+	if err := s.ref.Stop(ctx); err != nil {
+		log.Printf("[WARN] refresh drain incomplete: %v (%+v)", err, s.ref.Stats())
+		errs = append(errs, err)
+	}
+	if s.hydr != nil {
+		s.hydr.Demand.Flush(ctx)
+	}
+	s.usage.Flush(ctx)
+	s.searches.Flush(ctx)
+	s.stopWork()
+	if s.pgStore != nil {
+		s.pgStore.Close()
+	}
+	return errors.Join(errs...)
+}